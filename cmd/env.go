@@ -23,6 +23,7 @@ import (
 
 var envList = []string{
 	localdata.EnvNameHome,
+	localdata.EnvNameWorkspace,
 	localdata.EnvNameSSHPassPrompt,
 	localdata.EnvNameSSHPath,
 	localdata.EnvNameSCPPath,
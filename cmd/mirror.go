@@ -34,9 +34,9 @@ import (
 	"github.com/pingcap/tiup/pkg/localdata"
 	"github.com/pingcap/tiup/pkg/repository"
 	"github.com/pingcap/tiup/pkg/repository/model"
-	ru "github.com/pingcap/tiup/pkg/repository/utils"
 	"github.com/pingcap/tiup/pkg/repository/v1manifest"
 	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/pingcap/tiup/server/rotate"
 	"github.com/spf13/cobra"
@@ -67,6 +67,7 @@ of components or the repository itself.`,
 		newMirrorGenkeyCmd(),
 		newMirrorCloneCmd(),
 		newMirrorMergeCmd(),
+		newMirrorVerifyCmd(),
 		newMirrorPublishCmd(),
 		newMirrorShowCmd(),
 		newMirrorSetCmd(),
@@ -164,15 +165,20 @@ func newMirrorShowCmd() *cobra.Command {
 // the `mirror set` sub command
 func newMirrorSetCmd() *cobra.Command {
 	var (
-		root  string
-		reset bool
+		root   string
+		reset  bool
+		tlsOpt localdata.MirrorTLS
 	)
 	cmd := &cobra.Command{
 		Use:   "set <mirror-addr>",
 		Short: "Set mirror address",
 		Long: `Set mirror address, the address could be an URL or a path to the repository
 directory. Relative paths will not be expanded, so absolute paths are recommended.
-The root manifest in $TIUP_HOME will be replaced with the one in given repository automatically.`,
+The root manifest in $TIUP_HOME will be replaced with the one in given repository automatically.
+
+For HTTPS mirrors with an internal CA, the CA bundle, the pinned fingerprint of
+the server certificate and the client certificate could be specified, they are
+saved in the profile and used for all accesses to the mirror.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			teleCommand = cmd.CommandPath()
 			if !reset && len(args) != 1 {
@@ -195,6 +201,22 @@ The root manifest in $TIUP_HOME will be replaced with the one in given repositor
 			}
 
 			profile := localdata.InitProfile()
+			if tlsOpt != (localdata.MirrorTLS{}) {
+				if !strings.HasPrefix(addr, "https") {
+					return perrs.New("the TLS settings are only supported for HTTPS mirrors")
+				}
+				settings := tlsOpt
+				if err := settings.Abs(); err != nil {
+					return err
+				}
+				if _, err := settings.TLSConfig(); err != nil {
+					return err
+				}
+				if profile.Config.MirrorTLS == nil {
+					profile.Config.MirrorTLS = make(map[string]*localdata.MirrorTLS)
+				}
+				profile.Config.MirrorTLS[addr] = &settings
+			}
 			if err := profile.ResetMirror(addr, root); err != nil {
 				log.Errorf("Failed to set mirror: %s\n", err.Error())
 				return err
@@ -205,6 +227,10 @@ The root manifest in $TIUP_HOME will be replaced with the one in given repositor
 	}
 	cmd.Flags().StringVarP(&root, "root", "r", root, "Specify the path of `root.json`")
 	cmd.Flags().BoolVar(&reset, "reset", false, "Reset mirror to use the default address.")
+	cmd.Flags().StringVar(&tlsOpt.CACert, "ca-cert", "", "The CA bundle to verify the certificate of the HTTPS mirror")
+	cmd.Flags().StringVar(&tlsOpt.Fingerprint, "fingerprint", "", "The pinned SHA256 fingerprint of the certificate of the HTTPS mirror")
+	cmd.Flags().StringVar(&tlsOpt.ClientCert, "client-cert", "", "The client certificate to access the HTTPS mirror")
+	cmd.Flags().StringVar(&tlsOpt.ClientKey, "client-key", "", "The private key of the client certificate")
 
 	return cmd
 }
@@ -526,18 +552,7 @@ func loadPrivKey(privPath string) (*v1manifest.KeyInfo, error) {
 	}
 
 	// Get the private key
-	f, err := os.Open(privPath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	ki := v1manifest.KeyInfo{}
-	if err := json.NewDecoder(f).Decode(&ki); err != nil {
-		return nil, perrs.Annotate(err, "decode key")
-	}
-
-	return &ki, nil
+	return repository.LoadPrivateKey(privPath)
 }
 
 func loadPrivKeys(keysDir string) (map[string]*v1manifest.KeyInfo, error) {
@@ -590,6 +605,8 @@ func newMirrorPublishCmd() *cobra.Command {
 	desc := ""
 	standalone := false
 	hidden := false
+	var urls []string
+	requires := v1manifest.HostRequirements{}
 
 	cmd := &cobra.Command{
 		Use:   "publish <comp-name> <version> <tarball> <entry>",
@@ -607,57 +624,40 @@ func newMirrorPublishCmd() *cobra.Command {
 				flagSet.Insert(f.Name)
 			})
 
-			if err := validatePlatform(goos, goarch); err != nil {
-				return err
-			}
-
-			hashes, length, err := ru.HashFile(tarpath)
+			key, err := loadPrivKey(privPath)
 			if err != nil {
 				return err
 			}
-			fmt.Printf("uploading %s with %d bytes, sha256: %v ...\n",
-				tarpath, length, hashes[v1manifest.SHA256])
 
-			tarfile, err := os.Open(tarpath)
-			if err != nil {
-				return perrs.Annotatef(err, "open tarball: %s", tarpath)
-			}
-			defer tarfile.Close()
-
-			publishInfo := &model.PublishInfo{
-				ComponentData: &model.TarInfo{Reader: tarfile, Name: fmt.Sprintf("%s-%s-%s-%s.tar.gz", component, version, goos, goarch)},
+			var hostRequires *v1manifest.HostRequirements
+			if requires != (v1manifest.HostRequirements{}) {
+				if requires.Libc != "" && requires.Libc != "glibc" && requires.Libc != "musl" {
+					return perrs.Errorf("unsupported libc %s, only glibc and musl are supported", requires.Libc)
+				}
+				hostRequires = &requires
 			}
 
-			var reqErr error
-			pubErr := utils.Retry(func() error {
-				err := doPublish(component, version, entry, desc,
-					publishInfo, hashes, length,
-					standalone, hidden, privPath,
-					goos, goarch, flagSet,
-				)
-				if err != nil {
-					// retry if the error is manifest too old or validation failed
-					if err == repository.ErrManifestTooOld ||
-						errors.Is(perrs.Cause(err), utils.ErrValidateChecksum) ||
-						strings.Contains(err.Error(), "INVALID TARBALL") {
-						fmt.Printf("server returned an error: %s, retry...\n", err)
-						if _, ferr := tarfile.Seek(0, 0); ferr != nil { // reset the reader
-							return ferr
-						}
-						return err // return err to trigger next retry
-					}
-					reqErr = err // keep the error info
-				}
-				return nil // return nil to end the retry loop
-			}, utils.RetryOption{
-				Attempts: 10,
-				Delay:    time.Second * 2,
-				Timeout:  time.Minute * 10,
+			env := environment.GlobalEnv()
+			result, err := env.V1Repository().Publisher().Publish(tarpath, repository.PublishOptions{
+				Component:   component,
+				Version:     version,
+				Entry:       entry,
+				Description: desc,
+				OS:          goos,
+				Arch:        goarch,
+				Standalone:  standalone,
+				Hidden:      hidden,
+				Key:         key,
+				URLs:        urls,
+				Requires:    hostRequires,
 			})
-			if reqErr != nil {
-				return reqErr
+			if err != nil {
+				return err
+			}
+			if !result.Created && (flagSet.Exist("standalone") || flagSet.Exist("hide")) {
+				fmt.Println("This is not a new component, --standalone and --hide flag were omitted")
 			}
-			return pubErr
+			return nil
 		},
 	}
 
@@ -667,62 +667,13 @@ func newMirrorPublishCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&desc, "desc", "", desc, "description of the component")
 	cmd.Flags().BoolVarP(&standalone, "standalone", "", standalone, "can this component run directly")
 	cmd.Flags().BoolVarP(&hidden, "hide", "", hidden, "is this component invisible on listing")
+	cmd.Flags().StringSliceVar(&urls, "url", nil, "additional locations of the tarball tried before the mirror, e.g. an object storage or CDN address")
+	cmd.Flags().StringVar(&requires.Libc, "libc", "", "the C library the binaries are linked with, glibc or musl")
+	cmd.Flags().StringVar(&requires.MinLibcVersion, "min-libc-version", "", "the minimum version of the C library required on the hosts, e.g. 2.28")
+	cmd.Flags().StringVar(&requires.MinKernelVersion, "min-kernel-version", "", "the minimum version of the kernel required on the hosts, e.g. 3.10")
 	return cmd
 }
 
-func doPublish(
-	component, version, entry, desc string,
-	publishInfo *model.PublishInfo,
-	hashes map[string]string, length int64,
-	standalone, hidden bool,
-	privPath, goos, goarch string,
-	flagSet set.StringSet,
-) error {
-	env := environment.GlobalEnv()
-	env.V1Repository().PurgeTimestamp()
-	m, err := env.V1Repository().FetchComponentManifest(component, true)
-	if err != nil {
-		if perrs.Cause(err) == repository.ErrUnknownComponent {
-			fmt.Printf("Creating component %s\n", component)
-			publishInfo.Stand = &standalone
-			publishInfo.Hide = &hidden
-		} else {
-			return err
-		}
-	} else if flagSet.Exist("standalone") || flagSet.Exist("hide") {
-		fmt.Println("This is not a new component, --standalone and --hide flag will be omitted")
-	}
-
-	m = repository.UpdateManifestForPublish(m, component, version, entry, goos, goarch, desc, v1manifest.FileHash{
-		Hashes: hashes,
-		Length: uint(length),
-	})
-
-	manifest, err := sign(privPath, m)
-	if err != nil {
-		return err
-	}
-
-	return env.V1Repository().Mirror().Publish(manifest, publishInfo)
-}
-
-func validatePlatform(goos, goarch string) error {
-	// Only support any/any, don't support linux/any, any/amd64 .etc.
-	if goos == "any" && goarch == "any" {
-		return nil
-	}
-
-	switch goos + "/" + goarch {
-	case "linux/amd64",
-		"linux/arm64",
-		"darwin/amd64",
-		"darwin/arm64":
-		return nil
-	default:
-		return perrs.Errorf("platform %s/%s not supported", goos, goarch)
-	}
-}
-
 // the `mirror genkey` sub command
 func newMirrorGenkeyCmd() *cobra.Command {
 	var (
@@ -897,7 +848,11 @@ func newMirrorMergeCmd() *cobra.Command {
 
 			sourceMirrors := []repository.Mirror{}
 			for _, source := range sources {
-				sourceMirror := repository.NewMirror(source, repository.MirrorOptions{})
+				tlsCfg, err := env.Profile().Config.MirrorTLSConfig(source)
+				if err != nil {
+					return perrs.Annotatef(err, "invalid TLS settings of mirror(%s)", source)
+				}
+				sourceMirror := repository.NewMirror(source, repository.MirrorOptions{TLSConfig: tlsCfg})
 				if err := sourceMirror.Open(); err != nil {
 					return err
 				}
@@ -918,6 +873,93 @@ func newMirrorMergeCmd() *cobra.Command {
 	return cmd
 }
 
+// the `mirror verify` sub command
+func newMirrorVerifyCmd() *cobra.Command {
+	var (
+		format        string
+		expiryWarning time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "verify [mirror-dir]",
+		Short: "Verify the integrity of a local mirror",
+		Long: `Verify the integrity of a local mirror, which is the current mirror if not specified.
+It checks the signature chain from the first root manifest to every component manifest,
+the hash and length of every tarball, the expiry of manifests, and reports the files
+not referenced by any manifest. It exits with a non-zero code if any error is found.`,
+		Example: `  tiup mirror verify /path/to/mirror                      # Verify the mirror
+  tiup mirror verify /path/to/mirror --format json        # Output the report in JSON
+  tiup mirror verify --expiry-warning 720h                # Warn the manifests expiring in 30 days`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teleCommand = cmd.CommandPath()
+			if len(args) > 1 {
+				return cmd.Help()
+			}
+
+			dir := environment.Mirror()
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			if strings.HasPrefix(dir, "http") {
+				return perrs.Errorf("only local mirror can be verified, the mirror is %s", dir)
+			}
+
+			report, err := repository.VerifyMirror(dir, repository.VerifyOptions{ExpiryWarning: expiryWarning})
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				printVerifyReport(report)
+			}
+
+			if !report.OK() {
+				return perrs.Errorf("found %d errors in mirror %s", len(report.Errors), dir)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "default", "The format of output, available values are [default, json]")
+	cmd.Flags().DurationVar(&expiryWarning, "expiry-warning", 7*24*time.Hour, "Warn the manifests expiring in the duration")
+
+	return cmd
+}
+
+func printVerifyReport(report *repository.VerifyReport) {
+	fmt.Printf("Mirror: %s\n", report.Mirror)
+	fmt.Printf("Root version: %d, components: %d, tarballs: %d\n", report.RootVersion, report.Components, report.Tarballs)
+
+	printIssues := func(title string, issues []repository.VerifyIssue, c *color.Color) {
+		if len(issues) == 0 {
+			return
+		}
+		fmt.Printf("\n%s:\n", title)
+		table := [][]string{{"File", "Message"}}
+		for _, issue := range issues {
+			table = append(table, []string{issue.File, c.Sprint(issue.Message)})
+		}
+		tui.PrintTable(table, true)
+	}
+	printIssues("Errors", report.Errors, color.New(color.FgRed))
+	printIssues("Warnings", report.Warnings, color.New(color.FgYellow))
+
+	if len(report.Orphans) > 0 {
+		fmt.Printf("\nFiles not referenced by any manifest:\n")
+		for _, f := range report.Orphans {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+	if report.OK() {
+		fmt.Printf("\n%s\n", color.GreenString("Mirror verified successfully"))
+	}
+}
+
 // the `mirror clone` sub command
 func newMirrorCloneCmd() *cobra.Command {
 	var (
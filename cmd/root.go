@@ -71,6 +71,7 @@ the latest stable version will be downloaded from the repository.`,
   $ tiup update --all                  # Update all installed components to the latest version
   $ tiup update --nightly              # Update all installed components to the nightly version
   $ tiup update --self                 # Update the "tiup" to the latest version
+  $ tiup self rollback                 # Restore the "tiup" replaced by the last update
   $ tiup list                          # Fetch the latest supported components list
   $ tiup status                        # Display all running/terminated instances
   $ tiup clean <name>                  # Clean the data of running/terminated instance (Kill process if it's running)
@@ -225,6 +226,8 @@ the latest stable version will be downloaded from the repository.`,
 		newTelemetryCmd(),
 		newEnvCmd(),
 		newHistoryCmd(),
+		newSelfCmd(),
+		newWorkspaceCmd(),
 	)
 }
 
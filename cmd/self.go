@@ -0,0 +1,91 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+func newSelfCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self",
+		Short: "Manage the version of tiup itself",
+		Long: `Manage the version of tiup itself. Use 'tiup update --self' to update
+tiup, the previous version is kept for rolling back.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teleCommand = cmd.CommandPath()
+			env := environment.GlobalEnv()
+			fmt.Printf("Current version: %s\n", version.NewTiUPVersion().SemVer())
+			if pinned := env.Profile().Config.TiUPVersion; pinned != "" {
+				fmt.Printf("Pinned version:  %s\n", pinned)
+			} else {
+				fmt.Println("Pinned version:  none (update to the latest)")
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "rollback",
+			Short: "Restore the tiup replaced by the last update",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				teleCommand = cmd.CommandPath()
+				env := environment.GlobalEnv()
+				if err := checkTiUPBinary(env); err != nil {
+					return err
+				}
+				if err := env.SelfRollback(); err != nil {
+					return err
+				}
+				fmt.Println("Rolled back successfully!")
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "pin <version>",
+			Short: "Pin the version of tiup installed by 'tiup update --self'",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				teleCommand = cmd.CommandPath()
+				if len(args) != 1 {
+					return cmd.Help()
+				}
+				env := environment.GlobalEnv()
+				if err := env.PinTiUPVersion(args[0]); err != nil {
+					return err
+				}
+				fmt.Printf("Pinned tiup to %s, run 'tiup update --self' to apply it\n", args[0])
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "unpin",
+			Short: "Remove the pinned version of tiup",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				teleCommand = cmd.CommandPath()
+				env := environment.GlobalEnv()
+				if err := env.PinTiUPVersion(""); err != nil {
+					return err
+				}
+				fmt.Println("tiup will be updated to the latest version")
+				return nil
+			},
+		},
+	)
+	return cmd
+}
@@ -16,17 +16,25 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/environment"
 	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/utils"
+	gops "github.com/shirou/gopsutil/process"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 func newUninstallCmd() *cobra.Command {
-	var all, self bool
+	var all, self, force, unused bool
+	var olderThan string
 	cmdUnInst := &cobra.Command{
 		Use:   "uninstall <component1>:<version>",
 		Short: "Uninstall components or versions of a component",
@@ -36,6 +44,9 @@ components or versions which are installed. You can uninstall multiple
 components or multiple versions of a component at once. The --self flag
 which is used to uninstall tiup.
 
+The versions used by the clusters managed by tiup-cluster and tiup-dm, or by
+running processes (e.g. playgrounds) are not removed unless --force is given.
+
   # Uninstall tiup
   tiup uninstall --self
 
@@ -46,7 +57,10 @@ which is used to uninstall tiup.
   tiup uninstall tidb --all
 
   # Uninstall all installed components
-  tiup uninstall --all`,
+  tiup uninstall --all
+
+  # Uninstall the versions not in use and installed more than 90 days ago
+  tiup uninstall --unused --older-than 90d`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			teleCommand = cmd.CommandPath()
 			env := environment.GlobalEnv()
@@ -61,10 +75,37 @@ which is used to uninstall tiup.
 				fmt.Printf("Uninstalled TiUP successfully! (User data reserved, you can delete '%s' manually if you confirm userdata useless)\n", env.Profile().Root())
 				return nil
 			}
+
+			var refs []componentRef
+			if !force {
+				var err error
+				if refs, err = collectComponentRefs(env); err != nil {
+					return err
+				}
+			}
 			switch {
+			case unused:
+				if len(args) > 0 || all {
+					return cmd.Help()
+				}
+				var age time.Duration
+				if olderThan != "" {
+					var err error
+					if age, err = utils.ParseDuration(olderThan); err != nil {
+						return err
+					}
+				}
+				return removeUnusedComponents(env, refs, age)
 			case len(args) > 0:
-				return removeComponents(env, args, all)
+				return removeComponents(env, args, all, refs)
 			case len(args) == 0 && all:
+				if len(refs) > 0 {
+					installed, err := env.Profile().InstalledComponents()
+					if err != nil {
+						return err
+					}
+					return removeComponents(env, installed, all, refs)
+				}
 				if err := os.RemoveAll(env.LocalPath(localdata.ComponentParentDir)); err != nil {
 					return errors.Trace(err)
 				}
@@ -77,12 +118,194 @@ which is used to uninstall tiup.
 	}
 	cmdUnInst.Flags().BoolVar(&all, "all", false, "Remove all components or versions.")
 	cmdUnInst.Flags().BoolVar(&self, "self", false, "Uninstall tiup and clean all local data")
+	cmdUnInst.Flags().BoolVar(&force, "force", false, "Remove the versions even if they are in use")
+	cmdUnInst.Flags().BoolVar(&unused, "unused", false, "Remove all versions not in use")
+	cmdUnInst.Flags().StringVar(&olderThan, "older-than", "", "Only remove the versions installed before the duration with --unused, e.g. 90d")
 	return cmdUnInst
 }
 
-func removeComponents(env *environment.Environment, specs []string, all bool) error {
+// componentRef is a reference to the installed components
+type componentRef struct {
+	// component is empty if all components of the version are referenced
+	component string
+	// version is empty if all versions of the component are referenced
+	version string
+	by      string
+}
+
+func (r componentRef) match(component, version string) bool {
+	return (r.component == "" || r.component == component) &&
+		(r.version == "" || r.version == version)
+}
+
+// collectComponentRefs finds the installed components in use, including the
+// components of running processes and the versions of the managed clusters
+func collectComponentRefs(env *environment.Environment) ([]componentRef, error) {
+	var refs []componentRef
+
+	compDir := env.LocalPath(localdata.ComponentParentDir)
+	procs, err := gops.Processes()
+	if err != nil {
+		return nil, errors.Annotate(err, "list processes")
+	}
+	for _, proc := range procs {
+		exe, err := proc.Exe()
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(compDir, exe)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		parts := strings.Split(rel, string(filepath.Separator))
+		if len(parts) < 3 {
+			continue
+		}
+		refs = append(refs, componentRef{
+			component: parts[0],
+			version:   parts[1],
+			by:        fmt.Sprintf("running process %d", proc.Pid),
+		})
+	}
+
+	for _, kind := range []string{"cluster", "dm"} {
+		clusterDir := env.LocalPath(localdata.StorageParentDir, kind, spec.TiUPClusterDir)
+		entries, err := os.ReadDir(clusterDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Trace(err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			by := fmt.Sprintf("%s %s", kind, entry.Name())
+			data, err := os.ReadFile(filepath.Join(clusterDir, entry.Name(), "meta.yaml"))
+			if err != nil {
+				continue
+			}
+			if spec.IsEncryptedData(data) {
+				// the component managing the cluster is always in use
+				refs = append(refs, componentRef{component: kind, by: by})
+				fmt.Printf("The meta of %s is encrypted, the versions used by it are not checked\n", by)
+				continue
+			}
+			clusterRefs, err := metaComponentRefs(kind, data, by)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, clusterRefs...)
+		}
+	}
+	return refs, nil
+}
+
+// the version keys in the meta of the clusters of each kind, and the
+// components of the instances in the topology sections
+var clusterKinds = map[string]struct {
+	versionKey string
+	components map[string]string
+}{
+	"cluster": {
+		versionKey: "tidb_version",
+		components: map[string]string{
+			"tidb_servers":         spec.ComponentTiDB,
+			"tikv_servers":         spec.ComponentTiKV,
+			"tiflash_servers":      spec.ComponentTiFlash,
+			"pd_servers":           spec.ComponentPD,
+			"pump_servers":         spec.ComponentPump,
+			"drainer_servers":      spec.ComponentDrainer,
+			"cdc_servers":          spec.ComponentCDC,
+			"monitoring_servers":   spec.ComponentPrometheus,
+			"grafana_servers":      spec.ComponentGrafana,
+			"alertmanager_servers": spec.ComponentAlertmanager,
+		},
+	},
+	"dm": {
+		versionKey: "dm_version",
+		components: map[string]string{
+			"master_servers":       spec.ComponentDMMaster,
+			"worker_servers":       spec.ComponentDMWorker,
+			"monitoring_servers":   spec.ComponentPrometheus,
+			"grafana_servers":      spec.ComponentGrafana,
+			"alertmanager_servers": spec.ComponentAlertmanager,
+		},
+	},
+}
+
+// metaComponentRefs returns the references of a cluster of kind to the
+// components, by the meta of it: the version of the component managing it
+// that updated the meta last time, and the components of the instances at
+// the version of the cluster
+func metaComponentRefs(kind string, data []byte, by string) ([]componentRef, error) {
+	meta := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, errors.Annotatef(err, "parse meta of %s", by)
+	}
+
+	// e.g. "v1.10.0 tiup\nGo Version: go1.18", all versions are referenced
+	// if it's not recorded by the legacy versions
+	opsVer := ""
+	if v, ok := meta["last_ops_ver"].(string); ok && len(strings.Fields(v)) > 0 {
+		opsVer = "v" + strings.TrimPrefix(strings.Fields(v)[0], "v")
+	}
+	refs := []componentRef{{component: kind, version: opsVer, by: by}}
+
+	version, _ := meta[clusterKinds[kind].versionKey].(string)
+	topo, _ := meta["topology"].(map[interface{}]interface{})
+	if version == "" || topo == nil {
+		return refs, nil
+	}
+	components := set.NewStringSet()
+	for section, component := range clusterKinds[kind].components {
+		if instances, ok := topo[section].([]interface{}); !ok || len(instances) == 0 {
+			continue
+		}
+		// the third components are not bound to the version of the cluster
+		if spec.TiDBComponentVersion(component, version) != "" {
+			components.Insert(component)
+		}
+	}
+	sorted := components.Slice()
+	sort.Strings(sorted)
+	for _, component := range sorted {
+		refs = append(refs, componentRef{component: component, version: version, by: by})
+	}
+	return refs, nil
+}
+
+// installedTarget is an installed version of a component to be removed
+type installedTarget struct {
+	component string
+	version   string
+}
+
+// checkComponentRefs returns the error if any target is in use
+func checkComponentRefs(targets []installedTarget, refs []componentRef) error {
+	var used []string
+	for _, t := range targets {
+		for _, ref := range refs {
+			if ref.match(t.component, t.version) {
+				used = append(used, fmt.Sprintf("%s:%s (used by %s)", t.component, t.version, ref.by))
+				break
+			}
+		}
+	}
+	if len(used) == 0 {
+		return nil
+	}
+	return errors.Errorf("the following versions are in use, use --force to remove them anyway:\n  %s",
+		strings.Join(used, "\n  "))
+}
+
+func removeComponents(env *environment.Environment, specs []string, all bool, refs []componentRef) error {
+	// all targets are checked before removing any of them
+	var removed []string
+	var paths []string
+	var targets []installedTarget
 	for _, spec := range specs {
-		paths := []string{}
 		if strings.Contains(spec, ":") {
 			parts := strings.SplitN(spec, ":", 2)
 			// after this version is deleted, component will have no version left. delete the whole component dir directly
@@ -90,16 +313,21 @@ func removeComponents(env *environment.Environment, specs []string, all bool) er
 			if err != nil {
 				return errors.Trace(err)
 			}
+			versions := 0
 			if parts[1] == utils.NightlyVersionAlias {
 				for _, fi := range dir {
 					if utils.Version(fi.Name()).IsNightly() {
 						paths = append(paths, env.LocalPath(localdata.ComponentParentDir, parts[0], fi.Name()))
+						targets = append(targets, installedTarget{parts[0], fi.Name()})
+						versions++
 					}
 				}
 			} else {
 				paths = append(paths, env.LocalPath(localdata.ComponentParentDir, parts[0], parts[1]))
+				targets = append(targets, installedTarget{parts[0], parts[1]})
+				versions++
 			}
-			if len(dir)-len(paths) < 1 {
+			if len(dir)-versions < 1 {
 				paths = append(paths, env.LocalPath(localdata.ComponentParentDir, parts[0]))
 			}
 		} else {
@@ -108,14 +336,75 @@ func removeComponents(env *environment.Environment, specs []string, all bool) er
 				continue
 			}
 			paths = append(paths, env.LocalPath(localdata.ComponentParentDir, spec))
+			versions, err := env.Profile().InstalledVersions(spec)
+			if err != nil {
+				return err
+			}
+			for _, v := range versions {
+				targets = append(targets, installedTarget{spec, v})
+			}
+			// the components managing clusters are referenced by any version
+			for _, ref := range refs {
+				if ref.version == "" && ref.component == spec {
+					targets = append(targets, installedTarget{spec, ""})
+					break
+				}
+			}
+		}
+		removed = append(removed, spec)
+	}
+	if err := checkComponentRefs(targets, refs); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			return errors.Trace(err)
 		}
-		for _, path := range paths {
+	}
+	for _, spec := range removed {
+		fmt.Printf("Uninstalled component `%s` successfully!\n", spec)
+	}
+	return nil
+}
+
+// removeUnusedComponents removes the versions not referenced and installed
+// before the age
+func removeUnusedComponents(env *environment.Environment, refs []componentRef, age time.Duration) error {
+	components, err := env.Profile().InstalledComponents()
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(-age)
+	removed := 0
+	for _, component := range components {
+		versions, err := env.Profile().InstalledVersions(component)
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			path := env.LocalPath(localdata.ComponentParentDir, component, version)
+			if age > 0 {
+				fi, err := os.Stat(path)
+				if err != nil || fi.ModTime().After(deadline) {
+					continue
+				}
+			}
+			if checkComponentRefs([]installedTarget{{component, version}}, refs) != nil {
+				continue
+			}
 			if err := os.RemoveAll(path); err != nil {
 				return errors.Trace(err)
 			}
+			removed++
+			fmt.Printf("Uninstalled component `%s:%s` successfully!\n", component, version)
 		}
-
-		fmt.Printf("Uninstalled component `%s` successfully!\n", spec)
+		if remains, err := os.ReadDir(env.LocalPath(localdata.ComponentParentDir, component)); err == nil && len(remains) == 0 {
+			_ = os.Remove(env.LocalPath(localdata.ComponentParentDir, component))
+		}
+	}
+	if removed == 0 {
+		fmt.Println("No unused version found")
 	}
 	return nil
 }
@@ -0,0 +1,66 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetaComponentRefs(t *testing.T) {
+	refs, err := metaComponentRefs("cluster", []byte(`
+user: tidb
+tidb_version: v6.1.0
+last_ops_ver: |-
+  1.10.2 tiup
+  Go Version: go1.18
+topology:
+  pd_servers:
+    - host: 172.16.5.140
+  tikv_servers:
+    - host: 172.16.5.140
+  cdc_servers: []
+  alertmanager_servers:
+    - host: 172.16.5.140
+`), "cluster test")
+	assert.Nil(t, err)
+	assert.Equal(t, []componentRef{
+		{component: "cluster", version: "v1.10.2", by: "cluster test"},
+		{component: "pd", version: "v6.1.0", by: "cluster test"},
+		{component: "tikv", version: "v6.1.0", by: "cluster test"},
+	}, refs)
+
+	// the other versions of tiup-cluster and the other components are not used
+	assert.Nil(t, checkComponentRefs([]installedTarget{
+		{"cluster", "v1.9.0"},
+		{"tidb", "v6.1.0"},
+		{"tikv", "v5.4.0"},
+	}, refs))
+	assert.NotNil(t, checkComponentRefs([]installedTarget{{"tikv", "v6.1.0"}}, refs))
+
+	// all versions are referenced if the version managing it is unknown
+	refs, err = metaComponentRefs("dm", []byte(`
+dm_version: v6.1.0
+topology:
+  master_servers:
+    - host: 172.16.5.140
+`), "dm test")
+	assert.Nil(t, err)
+	assert.Equal(t, []componentRef{
+		{component: "dm", by: "dm test"},
+		{component: "dm-master", version: "v6.1.0", by: "dm test"},
+	}, refs)
+	assert.NotNil(t, checkComponentRefs([]installedTarget{{"dm", "v1.9.0"}}, refs))
+}
@@ -34,7 +34,9 @@ installed locally. Use <component>:<version> to update to the specified
 version. Components will be ignored if the latest version has already been 
 installed locally, but you can use --force explicitly to overwrite an 
 existing installation. Use --self which is used to update TiUP to the 
-latest version. All other flags will be ignored if the flag --self is given.
+latest version, or the version pinned by 'tiup self pin'. The previous
+version is kept and could be restored by 'tiup self rollback'. All other
+flags will be ignored if the flag --self is given.
 
   $ tiup update --all                     # Update all components to the latest stable version
   $ tiup update --nightly --all           # Update all components to the latest nightly version
@@ -51,29 +53,7 @@ latest version. All other flags will be ignored if the flag --self is given.
 				if err := checkTiUPBinary(env); err != nil {
 					return err
 				}
-
-				originFile := env.LocalPath("bin", "tiup")
-				renameFile := env.LocalPath("bin", "tiup.tmp")
-				if err := os.Rename(originFile, renameFile); err != nil {
-					fmt.Printf("Backup of `%s` to `%s` failed.\n", originFile, renameFile)
-					return err
-				}
-
-				var err error
-				defer func() {
-					if err != nil || utils.IsNotExist(originFile) {
-						if err := os.Rename(renameFile, originFile); err != nil {
-							fmt.Printf("Please rename `%s` to `%s` manually.\n", renameFile, originFile)
-						}
-					} else {
-						if err := os.Remove(renameFile); err != nil {
-							fmt.Printf("Please delete `%s` manually.\n", renameFile)
-						}
-					}
-				}()
-
-				err = env.SelfUpdate()
-				if err != nil {
+				if err := env.SelfUpdate(); err != nil {
 					return err
 				}
 			}
@@ -0,0 +1,224 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+func newWorkspaceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workspace <command>",
+		Short: "Manage the isolated workspaces of tiup",
+		Long: `A workspace is a profile of tiup with its own mirror, installed components
+and data of components (e.g. clusters), so multiple projects or CI jobs on one
+host don't share the mutable state. The workspace in use is decided by (in order):
+
+  1. the TIUP_HOME environment variable, which disables the named workspaces
+  2. the TIUP_WORKSPACE environment variable
+  3. the .tiup-workspace file in the working directory or its parents
+  4. the workspace switched to by 'tiup workspace switch'`,
+		Example: `  $ tiup workspace create ci --mirror /path/to/mirror # Create a workspace with its own mirror
+  $ tiup workspace switch ci --local                 # Use the workspace in the current directory
+  $ TIUP_WORKSPACE=ci tiup cluster list              # Use the workspace in one command`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newWorkspaceCreateCmd(),
+		newWorkspaceListCmd(),
+		newWorkspaceSwitchCmd(),
+		newWorkspaceCurrentCmd(),
+		newWorkspaceRemoveCmd(),
+	)
+	return cmd
+}
+
+func newWorkspaceCreateCmd() *cobra.Command {
+	var mirror, root string
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a workspace",
+		Long: `Create a workspace, the mirror of the current workspace is used if
+the mirror is not specified.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teleCommand = cmd.CommandPath()
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			from := environment.GlobalEnv().Profile()
+			if mirror != "" {
+				from = nil
+			}
+			profile, err := localdata.CreateWorkspace(args[0], from)
+			if err != nil {
+				return err
+			}
+			if mirror != "" {
+				if err := profile.ResetMirror(mirror, root); err != nil {
+					_ = localdata.RemoveWorkspace(args[0])
+					return err
+				}
+			}
+			fmt.Printf("Workspace %s created in %s\n", args[0], profile.Root())
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&mirror, "mirror", "", "The mirror used by the workspace")
+	cmd.Flags().StringVarP(&root, "root", "r", "", "Specify the path of root.json of the mirror")
+	return cmd
+}
+
+func newWorkspaceListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all workspaces",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teleCommand = cmd.CommandPath()
+			current, err := localdata.CurrentWorkspace()
+			if err != nil {
+				return err
+			}
+			names, err := localdata.ListWorkspaces()
+			if err != nil {
+				return err
+			}
+
+			table := [][]string{{"Name", "Mirror", "Path"}}
+			for _, name := range names {
+				root := localdata.WorkspaceRoot(name)
+				cfg, err := localdata.InitConfig(root)
+				if err != nil {
+					return err
+				}
+				mirror := cfg.Mirror
+				if mirror == "" {
+					mirror = "-"
+				}
+				if name == current.Name {
+					name += " (current)"
+				}
+				table = append(table, []string{name, mirror, root})
+			}
+			tui.PrintTable(table, true)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newWorkspaceSwitchCmd() *cobra.Command {
+	var local bool
+	cmd := &cobra.Command{
+		Use:   "switch <name>",
+		Short: "Switch to a workspace",
+		Long: `Switch to a workspace for the current user, or for the current
+directory and its sub directories if --local is specified. The workspace
+switched to is ignored if TIUP_HOME or TIUP_WORKSPACE is set.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teleCommand = cmd.CommandPath()
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			name := args[0]
+
+			if local {
+				wd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				if err := localdata.WriteWorkspaceFile(wd, name); err != nil {
+					return err
+				}
+				fmt.Printf("Workspace %s is used in %s\n", name, wd)
+				return nil
+			}
+
+			if err := localdata.SwitchWorkspace(name); err != nil {
+				return err
+			}
+			fmt.Printf("Switched to workspace %s\n", name)
+			if current, err := localdata.CurrentWorkspace(); err == nil && current.Name != name {
+				fmt.Printf("Note: workspace %s is still used here as specified by %s\n", current.Name, current.Source)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&local, "local", false, "Write the .tiup-workspace file in the current directory instead")
+	return cmd
+}
+
+func newWorkspaceCurrentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "current",
+		Short: "Show the workspace in use",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teleCommand = cmd.CommandPath()
+			current, err := localdata.CurrentWorkspace()
+			if err != nil {
+				return err
+			}
+			name := current.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Printf("Name:   %s\n", name)
+			fmt.Printf("Path:   %s\n", current.Root)
+			fmt.Printf("Source: %s\n", current.Source)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newWorkspaceRemoveCmd() *cobra.Command {
+	var skipConfirm bool
+	cmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a workspace and all data in it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teleCommand = cmd.CommandPath()
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			name := args[0]
+
+			if current, err := localdata.CurrentWorkspace(); err == nil && current.Name == name {
+				return fmt.Errorf("workspace %s is in use (specified by %s)", name, current.Source)
+			}
+			if !skipConfirm {
+				if err := tui.PromptForConfirmOrAbortError(
+					"All components and data in workspace %s will be removed, do you want to continue? [y/N]: ", name); err != nil {
+					return err
+				}
+			}
+			if err := localdata.RemoveWorkspace(name); err != nil {
+				return err
+			}
+			fmt.Printf("Workspace %s removed\n", name)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip the confirmation")
+	return cmd
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tunnel is a local port forwarded to the remote address through a bastion
+type tunnel struct {
+	cmd       *exec.Cmd
+	localAddr string
+}
+
+// openTunnel forwards a free local port to target through the bastion host
+// by the ssh client of the system, so the ssh config and agent of the user
+// are respected.
+func openTunnel(bastion, target string) (*tunnel, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	localAddr := l.Addr().String()
+	l.Close()
+
+	args := []string{
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-L", fmt.Sprintf("%s:%s", localAddr, target),
+	}
+	dest := bastion
+	// ssh does not accept port in the destination
+	if i := strings.LastIndex(bastion, ":"); i > 0 && !strings.Contains(bastion[i:], "]") {
+		if _, err := strconv.Atoi(bastion[i+1:]); err == nil {
+			args = append(args, "-p", bastion[i+1:])
+			dest = bastion[:i]
+		}
+	}
+	args = append(args, dest)
+
+	cmd := exec.Command("ssh", args...)
+	// let the user answer the prompts of ssh such as password
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't start ssh to bastion %s: %s", bastion, err.Error())
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	timeout := time.After(time.Minute)
+	for {
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("ssh to bastion %s exited: %v", bastion, err)
+		case <-timeout:
+			_ = cmd.Process.Kill()
+			return nil, fmt.Errorf("timeout waiting for tunnel through bastion %s", bastion)
+		case <-time.After(200 * time.Millisecond):
+		}
+		if conn, err := net.DialTimeout("tcp", localAddr, time.Second); err == nil {
+			conn.Close()
+			return &tunnel{cmd: cmd, localAddr: localAddr}, nil
+		}
+	}
+}
+
+// Close stops the tunnel
+func (t *tunnel) Close() {
+	if t.cmd.Process != nil {
+		_ = t.cmd.Process.Kill()
+	}
+}
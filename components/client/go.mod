@@ -4,9 +4,11 @@ go 1.16
 
 require (
 	github.com/gizak/termui/v3 v3.1.0
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/shirou/gopsutil v3.21.11+incompatible
 	github.com/spf13/cobra v1.3.0
 	github.com/tklauser/go-sysconf v0.3.9 // indirect
 	github.com/xo/usql v0.9.5
 	github.com/yusufpapurcu/wmi v1.2.2 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
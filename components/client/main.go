@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/url"
 	"os"
 	"os/user"
 	"path"
@@ -52,36 +53,51 @@ func main() {
 }
 
 func execute() error {
+	var bastion string
+
 	rootCmd := &cobra.Command{
-		Use:          "tiup client",
-		Short:        "Connect a TiDB cluster in your local host",
+		Use:   "tiup client [playground|profile|cluster]",
+		Short: "Connect a TiDB cluster in your local host",
+		Long: `Connect a TiDB cluster, the target could be the tag of a running playground,
+the name of a saved connection profile or the name of a cluster managed by
+tiup-cluster, the certificates of TLS enabled clusters are used automatically.
+
+Examples:
+  $ tiup client                                        # Choose a running playground to connect
+  $ tiup client prod-cluster                           # Connect the cluster managed by tiup-cluster
+  $ tiup client prod-cluster --bastion admin@jumphost  # Connect the cluster through a bastion host
+  $ tiup client profile add dev --host 10.0.1.1 --port 4000 --user root
+  $ tiup client dev                                    # Connect with the saved profile`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			target := ""
 			if len(args) > 0 {
 				target = args[0]
 			}
-			return connect(target)
+			return connect(target, bastion)
 		},
 	}
+	rootCmd.Flags().StringVar(&bastion, "bastion", "", "Proxy the connection through the bastion host by ssh, in the format of user@host[:port]")
+
+	rootCmd.AddCommand(newProfileCmd())
 
 	return rootCmd.Execute()
 }
 
-func connect(target string) error {
+func connect(target, bastion string) error {
 	tiupHome := os.Getenv(EnvNameHome)
 	if tiupHome == "" {
 		return fmt.Errorf("env variable %s not set, are you running client out of tiup?", EnvNameHome)
 	}
 	endpoints, err := scanEndpoint(tiupHome)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("error on read files: %s", err.Error())
 	}
-	if len(endpoints) == 0 {
-		return fmt.Errorf("It seems no playground is running, execute `tiup playground` to start one")
-	}
 	var ep *endpoint
 	if target == "" {
+		if len(endpoints) == 0 {
+			return fmt.Errorf("It seems no playground is running, execute `tiup playground` to start one")
+		}
 		if ep = selectEndpoint(endpoints); ep == nil {
 			os.Exit(0)
 		}
@@ -92,9 +108,54 @@ func connect(target string) error {
 			}
 		}
 		if ep == nil {
-			return fmt.Errorf("specified instance %s not found, maybe it's not alive now, execute `tiup status` to see instance list", target)
+			p, err := findProfile(tiupHome, target)
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return fmt.Errorf("specified instance %s not found, maybe it's not alive now, execute `tiup status` to see instance list", target)
+			}
+			if bastion != "" {
+				p.Bastion = bastion
+			}
+			return connectProfile(p)
+		}
+	}
+	return open(ep.dsn)
+}
+
+// findProfile looks up the saved profiles and then the clusters managed by
+// tiup-cluster for the target.
+func findProfile(tiupHome, target string) (*profile, error) {
+	profiles, err := loadProfiles(tiupHome)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := profiles[target]; ok {
+		return p, nil
+	}
+	return clusterProfile(tiupHome, target)
+}
+
+func connectProfile(p *profile) error {
+	addr := p.addr()
+	if p.Bastion != "" {
+		t, err := openTunnel(p.Bastion, addr)
+		if err != nil {
+			return err
 		}
+		defer t.Close()
+		addr = t.localAddr
 	}
+
+	dsn, err := p.dsn(addr)
+	if err != nil {
+		return err
+	}
+	return open(dsn)
+}
+
+func open(dsn string) error {
 	u, err := user.Current()
 	if err != nil {
 		return fmt.Errorf("can't get current user: %s", err.Error())
@@ -104,8 +165,12 @@ func connect(target string) error {
 		return fmt.Errorf("can't open history file: %s", err.Error())
 	}
 	h := handler.New(l, u, os.Getenv(EnvNameInstanceDataDir), true)
-	if err = h.Open(context.TODO(), ep.dsn); err != nil {
-		return fmt.Errorf("can't open connection to %s: %s", ep.dsn, err.Error())
+	if err = h.Open(context.TODO(), dsn); err != nil {
+		// never show the password in the DSN
+		if u, perr := url.Parse(dsn); perr == nil {
+			dsn = u.Redacted()
+		}
+		return fmt.Errorf("can't open connection to %s: %s", dsn, err.Error())
 	}
 	if err = h.Run(); err != io.EOF {
 		return err
@@ -0,0 +1,375 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// EnvNameComponentDataDir represents the working directory of specific component
+	EnvNameComponentDataDir = "TIUP_COMPONENT_DATA_DIR"
+	// StorageParentDir represent the parent directory of running component
+	StorageParentDir = "storage"
+	// ProfilesFilename is the file to store connection profiles
+	ProfilesFilename = "profiles.json"
+
+	// layout of the cluster component storage
+	clusterComponentDir = "cluster"
+	clusterDir          = "clusters"
+	clusterMetaFilename = "meta.yaml"
+	clusterTLSDir       = "tls"
+	clusterTLSCACert    = "ca.crt"
+	clusterTLSCert      = "client.crt"
+	clusterTLSKey       = "client.pem"
+)
+
+// profile is a named set of connection parameters
+type profile struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	Database string `json:"database,omitempty"`
+	CA       string `json:"ca,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Key      string `json:"key,omitempty"`
+	// Bastion is the ssh destination (user@host[:port]) to proxy the connection through
+	Bastion string `json:"bastion,omitempty"`
+}
+
+// profilesPath returns the path of the file storing the profiles
+func profilesPath(tiupHome string) string {
+	if dir := os.Getenv(EnvNameComponentDataDir); dir != "" {
+		return path.Join(dir, ProfilesFilename)
+	}
+	return path.Join(tiupHome, StorageParentDir, "client", ProfilesFilename)
+}
+
+func loadProfiles(tiupHome string) (map[string]*profile, error) {
+	profiles := make(map[string]*profile)
+
+	data, err := os.ReadFile(profilesPath(tiupHome))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("can't parse profiles: %s", err.Error())
+	}
+	return profiles, nil
+}
+
+func saveProfiles(tiupHome string, profiles map[string]*profile) error {
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+	fname := profilesPath(tiupHome)
+	if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+		return err
+	}
+	// the file may contain passwords
+	return os.WriteFile(fname, data, 0600)
+}
+
+// sortedProfiles returns the profiles ordered by name
+func sortedProfiles(profiles map[string]*profile) []*profile {
+	result := make([]*profile, 0, len(profiles))
+	for _, p := range profiles {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// clusterMeta is the part of the meta of a managed cluster needed to connect it
+type clusterMeta struct {
+	Topology struct {
+		Global struct {
+			TLSEnabled bool `yaml:"enable_tls,omitempty"`
+		} `yaml:"global,omitempty"`
+		TiDBServers []struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+		} `yaml:"tidb_servers"`
+	} `yaml:"topology"`
+}
+
+// clusterProfile builds a profile for the cluster managed by tiup-cluster,
+// the certificates are picked from the meta dir if TLS is enabled. It returns
+// nil if there is no cluster with the name.
+func clusterProfile(tiupHome, name string) (*profile, error) {
+	dir := path.Join(tiupHome, StorageParentDir, clusterComponentDir, clusterDir, name)
+	data, err := os.ReadFile(path.Join(dir, clusterMetaFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var meta clusterMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("can't parse meta of cluster %s: %s", name, err.Error())
+	}
+	if len(meta.Topology.TiDBServers) == 0 {
+		return nil, fmt.Errorf("there is no TiDB server in cluster %s", name)
+	}
+
+	db := meta.Topology.TiDBServers[0]
+	p := &profile{
+		Name: name,
+		Host: db.Host,
+		Port: db.Port,
+		User: "root",
+	}
+	if p.Port == 0 {
+		p.Port = 4000
+	}
+	if meta.Topology.Global.TLSEnabled {
+		p.CA = path.Join(dir, clusterTLSDir, clusterTLSCACert)
+		p.Cert = path.Join(dir, clusterTLSDir, clusterTLSCert)
+		p.Key = path.Join(dir, clusterTLSDir, clusterTLSKey)
+	}
+	return p, nil
+}
+
+// tlsConfig loads the certificates of the profile, nil is returned if TLS
+// is not configured.
+func (p *profile) tlsConfig() (*tls.Config, error) {
+	if p.CA == "" && p.Cert == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName: p.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if p.CA != "" {
+		caCert, err := os.ReadFile(p.CA)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA certificate: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("invalid CA certificate %s", p.CA)
+		}
+		cfg.RootCAs = pool
+	}
+	if p.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(p.Cert, p.Key)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %s", err.Error())
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// dsn builds the usql DSN of the profile connecting to the addr, which is
+// different from the address of the profile when proxied by a bastion.
+func (p *profile) dsn(addr string) (string, error) {
+	u := &url.URL{
+		Scheme: "mysql",
+		Host:   addr,
+		Path:   "/" + p.Database,
+	}
+	user := p.User
+	if user == "" {
+		user = "root"
+	}
+	if p.Password != "" {
+		u.User = url.UserPassword(user, p.Password)
+	} else {
+		u.User = url.User(user)
+	}
+
+	cfg, err := p.tlsConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg != nil {
+		key := "tiup-" + p.Name
+		if err := mysql.RegisterTLSConfig(key, cfg); err != nil {
+			return "", err
+		}
+		u.RawQuery = url.Values{"tls": []string{key}}.Encode()
+	}
+	return u.String(), nil
+}
+
+// addr returns the address of the TiDB server in the profile
+func (p *profile) addr() string {
+	return net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+}
+
+func newProfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage the connection profiles",
+	}
+
+	cmd.AddCommand(
+		newProfileAddCmd(),
+		newProfileListCmd(),
+		newProfileRemoveCmd(),
+	)
+	return cmd
+}
+
+func newProfileAddCmd() *cobra.Command {
+	p := &profile{}
+	var fromCluster string
+
+	cmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add or overwrite a connection profile",
+		Example: `  tiup client profile add dev --host 10.0.1.1 --port 4000 --user root
+  tiup client profile add prod --from-cluster prod-cluster --bastion admin@jumphost`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			tiupHome := os.Getenv(EnvNameHome)
+			if tiupHome == "" {
+				return fmt.Errorf("env variable %s not set, are you running client out of tiup?", EnvNameHome)
+			}
+
+			if fromCluster != "" {
+				cp, err := clusterProfile(tiupHome, fromCluster)
+				if err != nil {
+					return err
+				}
+				if cp == nil {
+					return fmt.Errorf("cluster %s not found", fromCluster)
+				}
+				// flags specified explicitly take precedence over the cluster meta
+				flags := cmd.Flags()
+				if !flags.Changed("host") {
+					p.Host = cp.Host
+				}
+				if !flags.Changed("port") {
+					p.Port = cp.Port
+				}
+				if !flags.Changed("ca") {
+					p.CA = cp.CA
+				}
+				if !flags.Changed("cert") {
+					p.Cert = cp.Cert
+				}
+				if !flags.Changed("key") {
+					p.Key = cp.Key
+				}
+			}
+			if p.Host == "" {
+				return fmt.Errorf("the host of the profile must be specified")
+			}
+			if (p.Cert == "") != (p.Key == "") {
+				return fmt.Errorf("the certificate and key of client must be specified together")
+			}
+			p.Name = args[0]
+
+			profiles, err := loadProfiles(tiupHome)
+			if err != nil {
+				return err
+			}
+			profiles[p.Name] = p
+			if err := saveProfiles(tiupHome, profiles); err != nil {
+				return err
+			}
+			fmt.Printf("Profile %s saved\n", p.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&p.Host, "host", "", "Host of the TiDB server")
+	cmd.Flags().IntVar(&p.Port, "port", 4000, "Port of the TiDB server")
+	cmd.Flags().StringVarP(&p.User, "user", "u", "root", "User to login")
+	cmd.Flags().StringVarP(&p.Password, "password", "p", "", "Password of the user, it's stored in plain text")
+	cmd.Flags().StringVarP(&p.Database, "database", "D", "", "Default database")
+	cmd.Flags().StringVar(&p.CA, "ca", "", "Path of the CA certificate to verify the server")
+	cmd.Flags().StringVar(&p.Cert, "cert", "", "Path of the client certificate")
+	cmd.Flags().StringVar(&p.Key, "key", "", "Path of the client private key")
+	cmd.Flags().StringVar(&p.Bastion, "bastion", "", "Proxy the connection through the bastion host by ssh, in the format of user@host[:port]")
+	cmd.Flags().StringVar(&fromCluster, "from-cluster", "", "Fill the address and certificates from the cluster managed by tiup-cluster")
+
+	return cmd
+}
+
+func newProfileListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all connection profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := loadProfiles(os.Getenv(EnvNameHome))
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Name\tAddress\tUser\tTLS\tBastion")
+			for _, p := range sortedProfiles(profiles) {
+				bastion := p.Bastion
+				if bastion == "" {
+					bastion = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%s\n", p.Name, p.addr(), p.User, p.CA != "" || p.Cert != "", bastion)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newProfileRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a connection profile",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			tiupHome := os.Getenv(EnvNameHome)
+			profiles, err := loadProfiles(tiupHome)
+			if err != nil {
+				return err
+			}
+			if _, ok := profiles[args[0]]; !ok {
+				return fmt.Errorf("profile %s not found", args[0])
+			}
+			delete(profiles, args[0])
+			return saveProfiles(tiupHome, profiles)
+		},
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+func newAdminCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Administrate the database of a TiDB cluster",
+	}
+
+	cmd.AddCommand(newSetPasswordCmd())
+	return cmd
+}
+
+func newSetPasswordCmd() *cobra.Command {
+	var (
+		opt    manager.SetPasswordOptions
+		prompt bool
+	)
+	cmd := &cobra.Command{
+		Use:   "set-password <cluster-name>",
+		Short: "Set or rotate the password of the root or operator account",
+		Long: `Set or rotate the password of the root account, or the operator account used by
+tiup for its own SQL needs, which is created with limited privileges if it does
+not exist. The current root password is read from the credential store of the
+cluster, and the new password is stored in it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			if prompt {
+				opt.Password = tui.PromptForPassword("New password of %s: ", opt.User)
+				if opt.Password == "" {
+					return perrs.New("the password can't be empty")
+				}
+			}
+			return cm.SetPassword(clusterName, opt, skipConfirm)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opt.User, "user", "root", "The account to set password for, root or operator")
+	cmd.Flags().BoolVar(&prompt, "prompt", false, "Input the new password instead of generating a random one")
+	cmd.Flags().BoolVar(&opt.AskCurrent, "ask-current", false, "Input the current root password instead of reading it from the credential store")
+
+	return cmd
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"crypto/tls"
+	"path/filepath"
+
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newApplyCmd() *cobra.Command {
+	opt := manager.ApplyOptions{
+		Deploy: manager.DeployOptions{
+			IdentityFile: filepath.Join(utils.UserHome(), ".ssh", "id_rsa"),
+		},
+	}
+	cmd := &cobra.Command{
+		Use:   "apply <cluster-name> <desired.yaml>",
+		Short: "Converge a cluster to a desired state",
+		Long: `Converge a cluster to the desired state declared in a file, which contains
+the version and the topology of the cluster:
+
+  version: v6.1.0
+  topology:
+    <the topology of the cluster>
+
+The desired state is compared with the cluster, the new instances are scaled
+out, the instances absent in the desired state are scaled in, then the changed
+configs are reloaded, or the cluster is upgraded if the version is changed.
+The version is not managed if it's omitted.`,
+		Example: `  $ tiup cluster apply prod prod.yaml --dry-run
+  $ tiup cluster apply prod prod.yaml`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			scale := func(b *task.Builder, imetadata spec.Metadata, gOpt operator.Options, tlsCfg *tls.Config) {
+				scaleInTask(clusterName, b, imetadata, gOpt, tlsCfg)
+			}
+			return cm.Apply(clusterName, args[1], opt, postScaleOutHook, final, scale, skipConfirm, gOpt)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			case 1:
+				return nil, cobra.ShellCompDirectiveDefault
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&opt.DryRun, "dry-run", false, "Only print the plan to converge the cluster")
+	cmd.Flags().StringVarP(&opt.Deploy.User, "user", "u", utils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.Deploy.IdentityFile, "identity_file", "i", opt.Deploy.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().BoolVarP(&opt.Deploy.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVarP(&opt.Deploy.NoLabels, "no-labels", "", false, "Don't check TiKV labels")
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders, also for TiCDC drain one capture")
+	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
+
+	return cmd
+}
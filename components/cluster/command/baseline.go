@@ -0,0 +1,60 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newBaselineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "Record and compare the hardware baseline of a cluster",
+		Long: `Run a short micro-benchmark suite (fsync latency in the data directories,
+network RTT between hosts and CPU) on the hosts of a cluster. The result
+recorded is kept in the meta of the cluster, and later results are compared
+with it to find out if the hardware is degraded.`,
+	}
+
+	recordCmd := &cobra.Command{
+		Use:   "record <cluster-name>",
+		Short: "Record the current result as the baseline",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			clusterReport.ID = scrubClusterName(args[0])
+			teleCommand = append(teleCommand, scrubClusterName(args[0]))
+
+			return cm.RecordBaseline(args[0], gOpt)
+		},
+	}
+
+	compareCmd := &cobra.Command{
+		Use:   "compare <cluster-name>",
+		Short: "Compare the current result with the baseline",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			clusterReport.ID = scrubClusterName(args[0])
+			teleCommand = append(teleCommand, scrubClusterName(args[0]))
+
+			return cm.CompareBaseline(args[0], gOpt)
+		},
+	}
+
+	cmd.AddCommand(recordCmd, compareCmd)
+	return cmd
+}
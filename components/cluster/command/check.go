@@ -15,6 +15,7 @@ package command
 
 import (
 	"path"
+	"time"
 
 	"github.com/pingcap/tiup/pkg/cluster/manager"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -54,6 +55,9 @@ it will check the new instances `,
 				}
 				scaleOutTopo = args[1]
 			}
+			if opt.Opr.EnableBandwidth {
+				opt.Opr.EnableNetwork = true
+			}
 
 			return cm.CheckCluster(args[0], scaleOutTopo, opt, gOpt)
 		},
@@ -68,9 +72,16 @@ it will check the new instances `,
 	cmd.Flags().BoolVar(&opt.Opr.EnableCPU, "enable-cpu", false, "Enable CPU thread count check")
 	cmd.Flags().BoolVar(&opt.Opr.EnableMem, "enable-mem", false, "Enable memory size check")
 	cmd.Flags().BoolVar(&opt.Opr.EnableDisk, "enable-disk", false, "Enable disk IO (fio) check")
-	cmd.Flags().BoolVar(&opt.ApplyFix, "apply", false, "Try to fix failed checks")
+	cmd.Flags().BoolVar(&opt.Opr.EnableNetwork, "enable-network", false, "Enable the network latency check between TiKV and PD hosts")
+	cmd.Flags().BoolVar(&opt.Opr.EnableBandwidth, "enable-bandwidth", false, "Also measure the bandwidth between TiKV and PD hosts with iperf3, implies --enable-network")
+	cmd.Flags().IntVar(&opt.Opr.NetworkMaxPairs, "network-max-pairs", 64, "The max pairs of hosts measured in the network check, pairs are sampled in big clusters, 0 for all pairs")
+	cmd.Flags().DurationVar(&opt.Opr.NetworkMaxRTT, "network-max-rtt", 2*time.Millisecond, "The max RTT allowed between TiKV and PD hosts")
+	cmd.Flags().IntVar(&opt.Opr.NetworkMinMbps, "network-min-bandwidth", 1000, "The min bandwidth allowed between TiKV and PD hosts, in Mbits/s")
+	cmd.Flags().BoolVar(&opt.CloudLabels, "cloud-labels", false, "Verify the location labels of PD, TiKV and TiFlash with the cloud metadata (AWS/GCP/Azure) of hosts")
+	cmd.Flags().BoolVar(&opt.ApplyFix, "apply", false, "Try to fix failed checks, the suggested NUMA bindings are written back to the topology")
 	cmd.Flags().BoolVar(&opt.ExistCluster, "cluster", false, "Check existing cluster, the input is a cluster name.")
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "api-timeout", 10, "Timeout in seconds when querying PD APIs.")
+	cmd.Flags().StringVar(&opt.ReportTo, "report-to", "", "Push the check report in JSON to the results server: an http(s) endpoint, an http(s) prefix ending with '/' (e.g. an object storage bucket) or a local directory. The bearer token is read from "+manager.EnvCheckReportToken)
 
 	return cmd
 }
@@ -0,0 +1,75 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configs rendered for the instances",
+	}
+
+	cmd.AddCommand(newConfigHistoryCmd())
+	return cmd
+}
+
+func newConfigHistoryCmd() *cobra.Command {
+	var showDiff bool
+	cmd := &cobra.Command{
+		Use:   "history <cluster-name>",
+		Short: "Show the generations of the configs rendered for the instances",
+		Long: fmt.Sprintf(`Show the generations of the configs rendered for the instances.
+
+A new generation is kept each time the configs of an instance are changed
+by deploy, scale-out, upgrade or reload, the last %d generations are kept
+for each instance. The config files (conf/*.toml) can be rolled back to a
+generation with 'reload --to-generation <generation>', the run scripts are
+not kept in the history and are rendered from the topology as usual.`, spec.ConfigHistoryLimit),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			if err := validRoles(gOpt.Roles); err != nil {
+				return err
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.ConfigHistory(clusterName, gOpt, showDiff)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only show the config history of specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only show the config history of specified nodes")
+	cmd.Flags().BoolVar(&showDiff, "diff", false, "Show the diffs between the generations")
+
+	return cmd
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/spf13/cobra"
+)
+
+func newDashboardCmd() *cobra.Command {
+	opt := manager.DashboardOptions{}
+	var statusTimeout uint64
+
+	cmd := &cobra.Command{
+		Use:   "dashboard <cluster-name>",
+		Short: "Print or open the URL of TiDB Dashboard",
+		Long: `Print or open the URL of TiDB Dashboard. TiDB Dashboard is moved to one of
+the PD hosts allowed by global.dashboard.pd_hosts of the topology if it's
+running on another one. A session can be issued so the URL signs in
+automatically, and a local port can be forwarded to the dashboard through SSH
+if it's unreachable from here, e.g. behind a bastion specified by --ssh-proxy-host.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			opt.StatusTimeout = time.Second * time.Duration(statusTimeout)
+			return cm.Dashboard(clusterName, opt, gOpt)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&opt.Open, "open", false, "Open the URL in the default browser")
+	cmd.Flags().BoolVar(&opt.Session, "session", false, "Sign in and issue a session shared by the URL")
+	cmd.Flags().StringVar(&opt.User, "user", "root", "The SQL user to sign in TiDB Dashboard, used with --session")
+	cmd.Flags().DurationVar(&opt.SessionTTL, "session-ttl", 3*time.Hour, "The time the issued session expires after, used with --session")
+	cmd.Flags().BoolVar(&opt.ReadOnly, "read-only", false, "Revoke the write privileges of the issued session, used with --session")
+	cmd.Flags().IntVar(&opt.ForwardPort, "forward", 0, "Forward the local port to TiDB Dashboard through SSH, 0 means no forwarding")
+	cmd.Flags().Uint64Var(&statusTimeout, "status-timeout", 10, "Timeout in seconds when requesting PD and TiDB Dashboard")
+
+	return cmd
+}
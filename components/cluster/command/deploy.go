@@ -37,6 +37,7 @@ func newDeploy() *cobra.Command {
 	opt := manager.DeployOptions{
 		IdentityFile: path.Join(utils.UserHome(), ".ssh", "id_rsa"),
 	}
+	recordBaseline := false
 	cmd := &cobra.Command{
 		Use:          "deploy <cluster-name> <version> <topology.yaml>",
 		Short:        "Deploy a cluster for production",
@@ -65,7 +66,17 @@ func newDeploy() *cobra.Command {
 				teleTopology = string(data)
 			}
 
-			return cm.Deploy(clusterName, version, topoFile, opt, postDeployHook, skipConfirm, gOpt)
+			if err := cm.Deploy(clusterName, version, topoFile, opt, postDeployHook, skipConfirm, gOpt); err != nil {
+				return err
+			}
+			// the cluster is deployed anyway, the baseline could be recorded later
+			if recordBaseline {
+				if err := cm.RecordBaseline(clusterName, gOpt); err != nil {
+					log.Warnf("Failed to record the hardware baseline, please retry with `%s baseline record %s`: %s",
+						tui.OsArgs0(), clusterName, err)
+				}
+			}
+			return nil
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			switch len(args) {
@@ -83,6 +94,9 @@ func newDeploy() *cobra.Command {
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result of components")
 	cmd.Flags().BoolVarP(&opt.NoLabels, "no-labels", "", false, "Don't check TiKV labels")
+	cmd.Flags().BoolVar(&opt.IgnoreFsCheck, "ignore-fs-check", false, "Don't check the filesystems of the data dirs of TiKV, TiFlash, PD and TiCDC")
+	cmd.Flags().StringVar(&opt.CloudLabels, "cloud-labels", "", "Infer the location labels of PD, TiKV and TiFlash from the cloud metadata (AWS/GCP/Azure) of hosts, 'fill' to set the missing labels, 'verify' to only check them")
+	cmd.Flags().BoolVar(&recordBaseline, "baseline", false, "Run micro benchmarks on the hosts after deploying and record the result as the hardware baseline")
 
 	return cmd
 }
@@ -17,7 +17,6 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/manager"
@@ -72,7 +71,7 @@ func newDisplayCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				return cm.DisplayDashboardInfo(clusterName, time.Second*time.Duration(gOpt.APITimeout), tlsCfg)
+				return cm.DisplayDashboardInfo(clusterName, tlsCfg, gOpt)
 			}
 			if showTiKVLabels {
 				return cm.DisplayTiKVLabels(clusterName, gOpt)
@@ -92,10 +91,13 @@ func newDisplayCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only display specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only display specified nodes")
 	cmd.Flags().BoolVar(&gOpt.ShowUptime, "uptime", false, "Display with uptime")
+	cmd.Flags().BoolVar(&gOpt.ShowDetail, "detail", false, "Display details of components, such as changefeeds of TiCDC, disk usage and recent restarts of instances")
+	cmd.Flags().Uint64Var(&gOpt.DiskFullHorizon, "disk-full-horizon", 30, "Warn if the disk of an instance is projected to be full within the days, used with --detail")
 	cmd.Flags().BoolVar(&showDashboardOnly, "dashboard", false, "Only display TiDB Dashboard information")
 	cmd.Flags().BoolVar(&showVersionOnly, "version", false, "Only display TiDB cluster version")
 	cmd.Flags().BoolVar(&showTiKVLabels, "labels", false, "Only display labels of specified TiKV role or nodes")
 	cmd.Flags().Uint64Var(&statusTimeout, "status-timeout", 10, "Timeout in seconds when getting node status")
+	cmd.Flags().BoolVar(&gOpt.SkipUnreachable, "skip-unreachable", false, "Skip and quarantine unreachable hosts, they could be caught up later with the reconcile command")
 
 	return cmd
 }
@@ -0,0 +1,63 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/spf13/cobra"
+)
+
+func newExportInventoryCmd() *cobra.Command {
+	var (
+		format   string
+		file     string
+		noDetect bool
+	)
+	cmd := &cobra.Command{
+		Use:   "export-inventory <cluster-name>",
+		Short: "Export the inventory of the instances for the CMDB and asset systems",
+		Long: `Export the records of the instances of a cluster, including the hosts, roles,
+versions, ports, dirs, labels, tags and OS/arch, in CSV or JSON for the CMDB and
+asset systems. The versions reported by the running instances are detected and
+exported along with the ones in the meta, unless --no-detect is specified.`,
+		Example: `  tiup cluster export-inventory test-cluster --format csv -o inventory.csv
+  tiup cluster export-inventory test-cluster --format json --no-detect`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.ExportInventory(clusterName, format, file, gOpt, !noDetect)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", manager.InventoryFormatCSV, "The format of the inventory: csv or json")
+	cmd.Flags().StringVarP(&file, "out", "o", "", "The path of the file exported, the inventory is printed if it's not specified")
+	cmd.Flags().BoolVar(&noDetect, "no-detect", false, "Don't detect the versions reported by the running instances")
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "api-timeout", 10, "Timeout in seconds when detecting the versions of the instances")
+
+	return cmd
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newExportMetaCmd() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "export-meta <cluster-name>",
+		Short: "Export the meta of a cluster for handover to another control machine",
+		Long: `Export the meta, keys, certificates and audit logs of a cluster to a tarball,
+which could be imported on another control machine with import-meta. The hashes
+of the files are recorded in the tarball and verified when importing.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			if file == "" {
+				file = clusterName + "-meta.tgz"
+			}
+			return cm.ExportClusterMeta(clusterName, file)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "out", "o", "", "The path of the tarball exported (default \"<cluster-name>-meta.tgz\")")
+
+	return cmd
+}
+
+func newImportMetaCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "import-meta <file>",
+		Short: "Import the meta of a cluster exported by export-meta",
+		Long: `Import the meta of a cluster exported by export-meta. The tarball is rejected
+if any file in it is corrupted, or a different meta of the cluster exists, or
+the cluster conflicts with the other clusters, unless --force is specified.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			return cm.ImportClusterMeta(args[0], force, skipConfirm)
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Override the existing meta of the cluster and ignore the conflicts")
+
+	return cmd
+}
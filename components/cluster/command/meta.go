@@ -17,13 +17,14 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/spf13/cobra"
 )
 
 func newMetaCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "meta",
-		Short: "backup/restore meta information",
+		Short: "backup/restore/encrypt meta information",
 	}
 
 	var filePath string
@@ -58,8 +59,36 @@ func newMetaCmd() *cobra.Command {
 		},
 	}
 
+	var metaEncryptCmd = &cobra.Command{
+		Use:   "encrypt <cluster-name>",
+		Short: "encrypt topology, private keys and certificates of cluster",
+		Long: fmt.Sprintf(`Encrypt topology, private keys and certificates of cluster at rest.
+The key is read from the file specified by %s, or the passphrase
+specified by %s, which must be set for all the later operations
+on the cluster.`, spec.EnvStorageKeyFile, spec.EnvStoragePassphrase),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please input cluster-name")
+			}
+			return cm.EncryptClusterMeta(args[0])
+		},
+	}
+
+	var metaDecryptCmd = &cobra.Command{
+		Use:   "decrypt <cluster-name>",
+		Short: "decrypt meta information of cluster encrypted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please input cluster-name")
+			}
+			return cm.DecryptClusterMeta(args[0])
+		},
+	}
+
 	cmd.AddCommand(metaBackupCmd)
 	cmd.AddCommand(metaRestoreCmd)
+	cmd.AddCommand(metaEncryptCmd)
+	cmd.AddCommand(metaDecryptCmd)
 
 	return cmd
 }
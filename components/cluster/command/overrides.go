@@ -0,0 +1,138 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newOverridesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "overrides",
+		Short: "Manage the temporary flag overrides of instances",
+		Long: `Manage the temporary command line flag overrides of instances, e.g. enabling
+a debug flag on one TiKV. The overrides are tracked in the meta of the cluster,
+and removed by the first reload after they expire.`,
+	}
+
+	cmd.AddCommand(
+		newOverridesSetCmd(),
+		newOverridesListCmd(),
+		newOverridesClearCmd(),
+	)
+	return cmd
+}
+
+func newOverridesSetCmd() *cobra.Command {
+	var (
+		node  string
+		flags []string
+		ttl   time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:     "set <cluster-name>",
+		Short:   "Override the flags of an instance and reload it",
+		Example: `  tiup cluster overrides set mycluster -N 172.16.5.140:20160 --flag="--log-level=debug" --ttl 2h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.SetRuntimeOverride(clusterName, node, flags, ttl, gOpt, skipConfirm)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&node, "node", "N", "", "The node to override the flags of")
+	cmd.Flags().StringArrayVar(&flags, "flag", nil, "The flag appended to the command line of the instance, can be specified multiple times")
+	cmd.Flags().DurationVar(&ttl, "ttl", 24*time.Hour, "The time the override expires after, 0 means never expire")
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders, also for TiCDC drain one capture")
+	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
+	_ = cmd.MarkFlagRequired("node")
+	_ = cmd.MarkFlagRequired("flag")
+
+	return cmd
+}
+
+func newOverridesListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list <cluster-name>",
+		Short: "List the flag overrides of the instances",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.ListRuntimeOverrides(clusterName)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	return cmd
+}
+
+func newOverridesClearCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clear <cluster-name>",
+		Short: "Remove the flag overrides and reload the instances",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.ClearRuntimeOverrides(clusterName, gOpt.Nodes, gOpt, skipConfirm)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only clear the overrides of the specified nodes")
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders, also for TiCDC drain one capture")
+
+	return cmd
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/checkpoint"
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+func newRecoverCmd() *cobra.Command {
+	var (
+		opt                          manager.RecoverOptions
+		resume, rollback, forceClear bool
+	)
+	cmd := &cobra.Command{
+		Use:   "recover <cluster-name>",
+		Short: "Recover a cluster locked by an interrupted operation",
+		Long: `Recover a cluster locked by an interrupted operation.
+
+The operation in progress is shown, and it can be recovered in one of the ways:
+  --resume       replay the operation with the completed steps skipped
+  --rollback     restore the meta to the one before the operation, the
+                 changes already made on the hosts are not reverted
+  --force-clear  clear the lock as is, it's recorded in the audit log
+
+You are asked to choose one if none is specified. The operations still running
+on the control machine can only be recovered with --force.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			for action, chosen := range map[string]bool{
+				manager.RecoverResume:     resume,
+				manager.RecoverRollback:   rollback,
+				manager.RecoverForceClear: forceClear,
+			} {
+				if !chosen {
+					continue
+				}
+				if opt.Action != "" {
+					return errors.New("only one of --resume, --rollback and --force-clear can be specified")
+				}
+				opt.Action = action
+			}
+
+			opArgs, auditFile, err := cm.RecoverOperation(clusterName, opt, skipConfirm)
+			if err != nil || opArgs == nil {
+				return err
+			}
+			if len(opArgs) < 2 {
+				return errors.Errorf("unknown command of the operation: %v", opArgs)
+			}
+			if !checkpoint.HasCheckPoint() {
+				if err := checkpoint.SetCheckPoint(auditFile); err != nil {
+					return errors.Annotate(err, "set checkpoint failed")
+				}
+			}
+			log.Infof("Resuming `%s %s`", tui.OsArgs0(), strings.Join(opArgs[1:], " "))
+			rootCmd.SetArgs(opArgs[1:])
+			return rootCmd.Execute()
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&resume, "resume", false, "Resume the interrupted operation with the completed steps skipped")
+	cmd.Flags().BoolVar(&rollback, "rollback", false, "Restore the meta to the one before the interrupted operation")
+	cmd.Flags().BoolVar(&forceClear, "force-clear", false, "Clear the lock of the interrupted operation as is")
+	cmd.Flags().BoolVar(&opt.Force, "force", false, "Recover the operation even if the process holding the lock is alive")
+
+	return cmd
+}
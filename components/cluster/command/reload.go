@@ -55,6 +55,10 @@ func newReloadCmd() *cobra.Command {
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders, also for TiCDC drain one capture")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
 	cmd.Flags().BoolVar(&skipRestart, "skip-restart", false, "Only refresh configuration to remote and do not restart services")
+	cmd.Flags().BoolVar(&gOpt.SkipUnreachable, "skip-unreachable", false, "Skip and quarantine unreachable hosts, they could be caught up later with the reconcile command")
+	cmd.Flags().BoolVar(&gOpt.ZoneAware, "zone-aware", false, "Restart the instances of PD, TiKV and TiFlash zone by zone, the zones are read from the labels of the instances or the other ones on their hosts")
+	cmd.Flags().StringVar(&gOpt.ZoneLabel, "zone-label", spec.CloudLabelZone, "The label name of zones used by --zone-aware")
+	cmd.Flags().IntVar(&gOpt.ConfigGeneration, "to-generation", 0, "Roll the config files (conf/*.toml) back to the generation kept in the config history instead of rendering them from the topology, the run scripts are still rendered from the topology")
 
 	return cmd
 }
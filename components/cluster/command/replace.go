@@ -0,0 +1,83 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newReplaceCmd() *cobra.Command {
+	opt := manager.ReplaceOptions{
+		Deploy: manager.DeployOptions{
+			IdentityFile: filepath.Join(utils.UserHome(), ".ssh", "id_rsa"),
+		},
+	}
+	cmd := &cobra.Command{
+		Use:   "replace <cluster-name>",
+		Short: "Replace an instance with a new one on another host",
+		Long: `Replace an instance with a new one on another host, e.g. for hardware swaps.
+The new instance is deployed with the same spec as the old one, and the old one
+is scaled in after the new one is up and, for TiKV, has taken over its share of
+regions. The progress is recorded, the replacement can be resumed with --resume
+if it's interrupted.`,
+		Example: `  $ tiup cluster replace prod -N 172.16.5.1:20160 --with 172.16.5.10
+  $ tiup cluster replace prod --resume`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			scale := func(b *task.Builder, imetadata spec.Metadata, gOpt operator.Options, tlsCfg *tls.Config) {
+				scaleInTask(clusterName, b, imetadata, gOpt, tlsCfg)
+			}
+			return cm.Replace(clusterName, opt, postScaleOutHook, final, scale, skipConfirm, gOpt)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&opt.Node, "node", "N", "", "The node to be replaced")
+	cmd.Flags().StringVar(&opt.NewHost, "with", "", "The host of the new instance")
+	cmd.Flags().DurationVar(&opt.WaitTimeout, "wait-timeout", 2*time.Hour, "Timeout waiting for the new instance to converge before scaling in the old one")
+	cmd.Flags().BoolVar(&opt.Resume, "resume", false, "Resume the replacement in progress")
+	cmd.Flags().BoolVar(&opt.Abort, "abort", false, "Discard the record of the replacement in progress, the instances are kept as is")
+	cmd.Flags().StringVarP(&opt.Deploy.User, "user", "u", utils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.Deploy.IdentityFile, "identity_file", "i", opt.Deploy.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().BoolVarP(&opt.Deploy.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVarP(&opt.Deploy.NoLabels, "no-labels", "", false, "Don't check TiKV labels")
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders, also for TiCDC drain one capture")
+	cmd.Flags().Uint64Var(&gOpt.StoreRemovalTimeout, "store-removal-timeout", 86400, "Timeout in seconds waiting for the old TiKV store to become tombstone")
+
+	return cmd
+}
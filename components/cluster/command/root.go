@@ -26,6 +26,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/manager"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -104,8 +105,23 @@ func init() {
 			// populate logger
 			log.SetDisplayModeFromString(gOpt.DisplayMode)
 
+			if gOpt.Viewer {
+				if err := checkViewerCommand(cmd); err != nil {
+					return err
+				}
+			}
+
 			var err error
 			var env *tiupmeta.Environment
+			if gOpt.SSHType == executor.SSHTypeSimulate {
+				clusterName := ""
+				if len(args) > 0 {
+					clusterName = args[0]
+				}
+				if err := startSimulation(clusterName); err != nil {
+					return err
+				}
+			}
 			if err = spec.Initialize("cluster"); err != nil {
 				return err
 			}
@@ -127,7 +143,6 @@ func init() {
 			tiupmeta.SetGlobalEnv(env)
 
 			teleCommand = getParentNames(cmd)
-
 			if gOpt.NativeSSH {
 				gOpt.SSHType = executor.SSHTypeSystem
 				log.Infof(
@@ -163,9 +178,12 @@ func init() {
 	// the value of wait-timeout is also used for `systemctl` commands, as the default timeout of systemd for
 	// start/stop operations is 90s, the default value of this argument is better be longer than that
 	rootCmd.PersistentFlags().Uint64Var(&gOpt.OptTimeout, "wait-timeout", 120, "Timeout in seconds to wait for an operation to complete, ignored for operations that don't fit.")
+	rootCmd.PersistentFlags().Uint64Var(&gOpt.RequestTimeout, "request-timeout", 0, "Timeout in seconds of each request to the APIs of the components like PD and TiCDC, the built-in timeouts are used if it's 0.")
+	rootCmd.PersistentFlags().IntVar(&gOpt.DiagnoseLines, "diagnose-lines", 100, "The count of log and journal lines harvested to the control machine from the instances failed to start, 0 to disable.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
 	rootCmd.PersistentFlags().BoolVar(&gOpt.NativeSSH, "native-ssh", gOpt.NativeSSH, "(EXPERIMENTAL) Use the native SSH client installed on local system instead of the build-in one.")
-	rootCmd.PersistentFlags().StringVar((*string)(&gOpt.SSHType), "ssh", "", "(EXPERIMENTAL) The executor type: 'builtin', 'system', 'none'.")
+	rootCmd.PersistentFlags().StringVar((*string)(&gOpt.SSHType), "ssh", "", "(EXPERIMENTAL) The executor type: 'builtin', 'system', 'none', 'simulate'.")
+	rootCmd.PersistentFlags().StringVar(&snapshotFile, "snapshot", "", "(EXPERIMENTAL) The snapshot of recorded SSH and API responses used by --ssh=simulate")
 	rootCmd.PersistentFlags().IntVarP(&gOpt.Concurrency, "concurrency", "c", 5, "max number of parallel tasks allowed")
 	rootCmd.PersistentFlags().StringVar(&gOpt.DisplayMode, "format", "default", "(EXPERIMENTAL) The format of output, available values are [default, json]")
 	rootCmd.PersistentFlags().StringVar(&gOpt.SSHProxyHost, "ssh-proxy-host", "", "The SSH proxy host used to connect to remote host.")
@@ -174,6 +192,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&gOpt.SSHProxyIdentity, "ssh-proxy-identity-file", path.Join(utils.UserHome(), ".ssh", "id_rsa"), "The identity file used to login the proxy host.")
 	rootCmd.PersistentFlags().BoolVar(&gOpt.SSHProxyUsePassword, "ssh-proxy-use-password", false, "Use password to login the proxy host.")
 	rootCmd.PersistentFlags().Uint64Var(&gOpt.SSHProxyTimeout, "ssh-proxy-timeout", 5, "Timeout in seconds to connect the proxy host via SSH, ignored for operations that don't need an SSH connection.")
+	rootCmd.PersistentFlags().BoolVar(&gOpt.Viewer, "viewer", false, fmt.Sprintf("Run in the read-only viewer mode, only %s are allowed and the private key of the cluster is never used.", viewerCommandList()))
+	rootCmd.PersistentFlags().StringVar(&gOpt.ViewerUser, "viewer-user", utils.CurrentUser(), "The user name to login the hosts with read-only privileges in the viewer mode.")
+	rootCmd.PersistentFlags().StringVar(&gOpt.ViewerIdentityFile, "viewer-identity-file", "", "The identity file of the viewer user, only the status APIs are queried in the viewer mode if it's not set.")
 	_ = rootCmd.PersistentFlags().MarkHidden("native-ssh")
 	_ = rootCmd.PersistentFlags().MarkHidden("ssh-proxy-host")
 	_ = rootCmd.PersistentFlags().MarkHidden("ssh-proxy-user")
@@ -214,6 +235,24 @@ func init() {
 		newTemplateCmd(),
 		newTLSCmd(),
 		newMetaCmd(),
+		newReconcileCmd(),
+		newCDCCmd(),
+		newRenderScriptsCmd(),
+		newOverridesCmd(),
+		newDashboardCmd(),
+		newTunnelCmd(),
+		newBaselineCmd(),
+		newReplaceCmd(),
+		newApplyCmd(),
+		newExportMetaCmd(),
+		newImportMetaCmd(),
+		newAdminCmd(),
+		newLogLevelCmd(),
+		newRecoverCmd(),
+		newTopCmd(),
+		newConfigCmd(),
+		newServiceStatusCmd(),
+		newExportInventoryCmd(),
 	)
 }
 
@@ -295,11 +334,24 @@ func Execute() {
 	start := time.Now()
 	code := 0
 	err := rootCmd.Execute()
+	// write back the changes to the encrypted clusters and clean up the decrypted files
+	if tidbSpec != nil {
+		if lockErr := tidbSpec.LockAll(); lockErr != nil && err == nil {
+			err = lockErr
+		}
+	}
+	finishSimulation()
 	if err != nil {
 		code = 1
 	}
 
 	zap.L().Info("Execute command finished", zap.Int("code", code), zap.Error(err))
+	api.LogCallStats()
+	if environment.DebugMode {
+		for _, s := range api.GetCallStats() {
+			log.Debugf("API %s: %d calls, %d failed, avg %s, max %s", s.Endpoint, s.Count, s.Failures, s.Avg(), s.Max)
+		}
+	}
 
 	if reportEnabled {
 		f := func() {
@@ -36,16 +36,7 @@ func newScaleInCmd() *cobra.Command {
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 
 			scale := func(b *task.Builder, imetadata spec.Metadata, tlsCfg *tls.Config) {
-				metadata := imetadata.(*spec.ClusterMeta)
-
-				nodes := gOpt.Nodes
-				if !gOpt.Force {
-					nodes = operator.AsyncNodes(metadata.Topology, nodes, false)
-				}
-
-				b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, gOpt, tlsCfg).
-					UpdateMeta(clusterName, metadata, nodes).
-					UpdateTopology(clusterName, tidbSpec.Path(clusterName), metadata, nodes)
+				scaleInTask(clusterName, b, imetadata, gOpt, tlsCfg)
 			}
 
 			return cm.ScaleIn(clusterName, skipConfirm, gOpt, scale)
@@ -63,8 +54,24 @@ func newScaleInCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Specify the nodes (required)")
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders, also for TiCDC drain one capture")
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force just try stop and destroy instance before removing the instance from topo")
+	cmd.Flags().BoolVar(&gOpt.ParallelStoreRemoval, "parallel-store-removal", false, "Remove all the specified TiKV stores at the same time instead of in batches by location labels")
+	cmd.Flags().Uint64Var(&gOpt.StoreRemovalTimeout, "store-removal-timeout", 86400, "Timeout in seconds waiting for a batch of TiKV stores to become tombstone before removing the next batch")
 
 	_ = cmd.MarkFlagRequired("node")
 
 	return cmd
 }
+
+// scaleInTask builds the task of scaling in the nodes specified in gOpt
+func scaleInTask(clusterName string, b *task.Builder, imetadata spec.Metadata, gOpt operator.Options, tlsCfg *tls.Config) {
+	metadata := imetadata.(*spec.ClusterMeta)
+
+	nodes := gOpt.Nodes
+	if !gOpt.Force {
+		nodes = operator.AsyncNodes(metadata.Topology, nodes, false)
+	}
+
+	b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, gOpt, tlsCfg).
+		UpdateMeta(clusterName, metadata, nodes).
+		UpdateTopology(clusterName, tidbSpec.Path(clusterName), metadata, nodes)
+}
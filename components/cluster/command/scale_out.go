@@ -93,6 +93,9 @@ func newScaleOutCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&opt.IdentityFile, "identity_file", "i", opt.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.NoLabels, "no-labels", "", false, "Don't check TiKV labels")
+	cmd.Flags().BoolVar(&opt.IgnoreFsCheck, "ignore-fs-check", false, "Don't check the filesystems of the data dirs of TiKV, TiFlash, PD and TiCDC")
+	cmd.Flags().StringVar(&opt.CloudLabels, "cloud-labels", "", "Infer the location labels of the new PD, TiKV and TiFlash from the cloud metadata (AWS/GCP/Azure) of hosts, 'fill' to set the missing labels, 'verify' to only check them")
+	cmd.Flags().BoolVar(&opt.Inherit, "inherit", false, "Inherit the fields not set for the new instances from the first existing instance of the same role, the resolved spec is shown before applying")
 	cmd.Flags().BoolVarP(&opt.Stage1, "stage1", "", false, "Don't start the new instance after scale-out, need to manually execute cluster scale-out --stage2")
 	cmd.Flags().BoolVarP(&opt.Stage2, "stage2", "", false, "Start the new instance and init config after scale-out --stage1")
 
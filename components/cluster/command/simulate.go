@@ -0,0 +1,96 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/simulate"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var (
+	snapshotFile string             // the snapshot of responses used by --ssh=simulate
+	snapshot     *simulate.Snapshot // the snapshot in use
+	simulateDir  string             // the copy of the profile modified by the simulation
+)
+
+// startSimulation loads the snapshot and points the profile to a temporary
+// directory with a copy of the meta of the cluster operated, so the clusters
+// are not changed by the simulation, it must be called before the profile is
+// initialized
+func startSimulation(clusterName string) error {
+	if snapshotFile == "" {
+		return perrs.New("the snapshot must be specified by --snapshot for --ssh=simulate")
+	}
+	var err error
+	if snapshot, err = simulate.Load(snapshotFile); err != nil {
+		return err
+	}
+
+	profileDir, err := spec.ResolveProfileDir("cluster")
+	if err != nil {
+		return err
+	}
+	// the temporary directory is only accessible by the user
+	if simulateDir, err = os.MkdirTemp("", "tiup-cluster-simulate"); err != nil {
+		return perrs.AddStack(err)
+	}
+	// the profile is a subdir, so the host registry beside it is not shared
+	// with the real one either
+	simulateProfile := filepath.Join(simulateDir, "cluster")
+	src := spec.NewSpec(filepath.Join(profileDir, spec.TiUPClusterDir), nil)
+	dst := spec.NewSpec(filepath.Join(simulateProfile, spec.TiUPClusterDir), nil)
+	if clusterName != "" && utils.IsExist(src.MetaPath(clusterName)) {
+		if err := os.MkdirAll(filepath.Dir(dst.MetaPath(clusterName)), 0700); err != nil {
+			return perrs.AddStack(err)
+		}
+		if err := utils.Copy(src.MetaPath(clusterName), dst.MetaPath(clusterName)); err != nil {
+			return perrs.Annotatef(err, "failed to copy the meta of cluster %s for simulation", clusterName)
+		}
+	}
+	if err := os.Setenv(localdata.EnvNameComponentDataDir, simulateProfile); err != nil {
+		return perrs.AddStack(err)
+	}
+
+	simulate.Enable(snapshot)
+	log.Warnf("Simulating with the snapshot %s, no host is connected and the clusters are not changed", snapshotFile)
+	return nil
+}
+
+// finishSimulation prints the calls made in the simulation and removes the
+// copy of the profile
+func finishSimulation() {
+	if snapshot == nil {
+		return
+	}
+	defer os.RemoveAll(simulateDir)
+
+	counts, unmatched := snapshot.Summary()
+	fmt.Printf("\nSimulation finished: %d SSH commands, %d transfers, %d API requests\n",
+		counts["ssh"], counts["transfer"], counts["http"])
+	if len(unmatched) == 0 {
+		return
+	}
+	fmt.Printf("%s\n", color.YellowString("%d calls have no recorded response:", len(unmatched)))
+	for _, c := range unmatched {
+		fmt.Printf("  [%s] %s: %s\n", c.Kind, c.Target, c.Detail)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartSimulation(t *testing.T) {
+	profile := t.TempDir()
+	t.Setenv(localdata.EnvNameComponentDataDir, profile)
+	clusters := spec.NewSpec(filepath.Join(profile, spec.TiUPClusterDir), nil)
+	for _, name := range []string{"test1", "test2"} {
+		require.Nil(t, os.MkdirAll(clusters.Path(name, "ssh"), 0700))
+		require.Nil(t, os.WriteFile(clusters.MetaPath(name), []byte("user: tidb\n"), 0644))
+		require.Nil(t, os.WriteFile(clusters.Path(name, "ssh", "id_rsa"), []byte("key"), 0600))
+	}
+	snapshotFile = filepath.Join(t.TempDir(), "snapshot.yaml")
+	require.Nil(t, os.WriteFile(snapshotFile, []byte("ssh: []\n"), 0644))
+	t.Cleanup(func() {
+		finishSimulation()
+		snapshot, snapshotFile = nil, ""
+		executor.SetSimulator(nil)
+		utils.SetHTTPTransport(nil)
+	})
+
+	require.Nil(t, startSimulation("test1"))
+	fi, err := os.Stat(simulateDir)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+
+	// only the meta of the cluster operated is copied
+	simulated := os.Getenv(localdata.EnvNameComponentDataDir)
+	assert.Equal(t, filepath.Join(simulateDir, "cluster"), simulated)
+	copied := spec.NewSpec(filepath.Join(simulated, spec.TiUPClusterDir), nil)
+	data, err := os.ReadFile(copied.MetaPath("test1"))
+	assert.Nil(t, err)
+	assert.Equal(t, "user: tidb\n", string(data))
+	assert.True(t, utils.IsNotExist(copied.Path("test1", "ssh")))
+	assert.True(t, utils.IsNotExist(copied.Path("test2")))
+}
@@ -21,8 +21,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
-	"github.com/pingcap/tiup/pkg/crypto/rand"
-	"github.com/pingcap/tiup/pkg/proxy"
+	"github.com/pingcap/tiup/pkg/tui"
 	"github.com/spf13/cobra"
 
 	// for sql/driver
@@ -64,9 +63,9 @@ func newStartCmd() *cobra.Command {
 
 			// init password
 			if initPasswd {
-				pwd, err := initPassword(clusterName)
+				pwd, err := cm.InitSQLUsers(clusterName)
 				if err != nil {
-					log.Errorf("Failed to set root password of TiDB database to '%s'", pwd)
+					log.Errorf("Failed to set root password of TiDB database")
 					if strings.Contains(strings.ToLower(err.Error()), "error 1045") {
 						log.Errorf("Initializing is only working when the root password is empty")
 						log.Errorf(color.YellowString("Did you already set root password before?"))
@@ -75,8 +74,7 @@ func newStartCmd() *cobra.Command {
 				}
 				log.Warnf("The root password of TiDB database has been changed.")
 				fmt.Printf("The new password is: '%s'.\n", color.HiYellowString(pwd)) // use fmt to avoid printing to audit log
-				log.Warnf("Copy and record it to somewhere safe, %s, and will not be stored.", color.HiRedString("it is only displayed once"))
-				log.Warnf("The generated password %s.", color.HiRedString("can NOT be get and shown again"))
+				log.Infof("The password is stored in the credential store of the cluster, it could be rotated with `%s admin set-password %s`.", tui.OsArgs0(), clusterName)
 			}
 			return nil
 		},
@@ -90,7 +88,7 @@ func newStartCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().BoolVar(&initPasswd, "init", false, "Initialize a secure root password for the database")
+	cmd.Flags().BoolVar(&initPasswd, "init", false, "Initialize a secure root password and the operator account for the database, the credentials are stored in the credential store")
 	cmd.Flags().BoolVar(&restoreLeader, "restore-leaders", false, "Allow leaders to be scheduled to stores after start")
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only start specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only start specified nodes")
@@ -100,47 +98,6 @@ func newStartCmd() *cobra.Command {
 	return cmd
 }
 
-func initPassword(clusterName string) (string, error) {
-	metadata, err := spec.ClusterMetadata(clusterName)
-	if err != nil {
-		return "", err
-	}
-	tcpProxy := proxy.GetTCPProxy()
-
-	// generate password
-	pwd, err := rand.Password(18)
-	if err != nil {
-		return pwd, err
-	}
-
-	var lastErr error
-	for _, spec := range metadata.Topology.TiDBServers {
-		spec := spec
-		endpoint := fmt.Sprintf("%s:%d", spec.Host, spec.Port)
-		if tcpProxy != nil {
-			closeC := tcpProxy.Run([]string{endpoint})
-			defer tcpProxy.Close(closeC)
-			endpoint = tcpProxy.GetEndpoints()[0]
-		}
-		db, err := createDB(endpoint)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-		defer db.Close()
-
-		sqlStr := fmt.Sprintf("SET PASSWORD FOR 'root'@'%%' = '%s'; FLUSH PRIVILEGES;", pwd)
-		_, err = db.Exec(sqlStr)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-		return pwd, nil
-	}
-
-	return pwd, lastErr
-}
-
 func createDB(endpoint string) (db *sql.DB, err error) {
 	dsn := fmt.Sprintf("root:@tcp(%s)/?charset=utf8mb4,utf8&multiStatements=true", endpoint)
 	db, err = sql.Open("mysql", dsn)
@@ -134,5 +134,44 @@ func newTemplateCmd() *cobra.Command {
 	cmd.Flags().StringSliceVar(&localOpt.GrafanaServers, "grafana-servers", []string{"127.0.0.1"}, "List of grafana servers")
 	cmd.Flags().StringSliceVar(&localOpt.AlertManagerServers, "alertmanager-servers", nil, "List of alermanager servers")
 
+	cmd.AddCommand(newTemplateExportCmd())
+	return cmd
+}
+
+func newTemplateExportCmd() *cobra.Command {
+	force := false
+
+	cmd := &cobra.Command{
+		Use:   "export <cluster-name> [template...]",
+		Short: "Export the config and script templates to customize them for the cluster",
+		Long: `Export the config and script templates to the templates directory of the
+cluster. The templates in the directory take precedence over the ones embedded
+in tiup-cluster, so the modifications survive upgrading tiup-cluster. All
+templates are exported if none is specified, e.g.:
+
+  tiup cluster template export mycluster scripts/run_cdc.sh.tpl systemd/system.service.tpl`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.ExportTemplates(clusterName, args[1:], force)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite the templates already exported")
+
 	return cmd
 }
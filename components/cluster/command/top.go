@@ -0,0 +1,63 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/spf13/cobra"
+)
+
+func newTopCmd() *cobra.Command {
+	opt := manager.TopOptions{}
+	cmd := &cobra.Command{
+		Use:   "top <cluster-name>",
+		Short: "Show the resource usage of the instances in a live table",
+		Long: `Show the CPU, memory and disk I/O usage of the processes of the instances, and
+the network traffic of their hosts, in a table refreshed periodically and sorted
+by the usage, to find the hot nodes quickly without Grafana. The usage is read
+from /proc of the hosts through SSH.`,
+		Example: `  tiup cluster top test-cluster
+  tiup cluster top test-cluster -R tikv --sort disk --limit 10`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.Top(clusterName, opt, gOpt)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only show the instances of specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only show the specified nodes")
+	cmd.Flags().DurationVar(&opt.Interval, "interval", 3*time.Second, "The interval to refresh the usage")
+	cmd.Flags().StringVar(&opt.SortBy, "sort", manager.TopSortCPU, "Sort the instances by: cpu, mem, disk or net")
+	cmd.Flags().IntVar(&opt.Count, "count", 0, "Exit after refreshing the given times, 0 to refresh until Ctrl+C")
+	cmd.Flags().IntVar(&opt.Limit, "limit", 0, "Only show the given number of instances using the most, 0 to show all")
+
+	return cmd
+}
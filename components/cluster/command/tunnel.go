@@ -0,0 +1,62 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newTunnelCmd() *cobra.Command {
+	var targets []string
+
+	cmd := &cobra.Command{
+		Use:   "tunnel <cluster-name>",
+		Short: "Forward local ports to the web UIs and APIs of components through SSH",
+		Long: `Forward local ports to the web UIs and APIs of components through SSH, and
+through the bastion specified by --ssh-proxy-host if any, so they can be
+accessed from where the cluster network is unreachable. The ports are
+forwarded until interrupted.
+
+If TLS is enabled, the APIs of PD, TiCDC, Pump, Drainer and TiDB Dashboard
+are proxied as plain HTTP on 127.0.0.1 with the client certificate of the
+cluster, the other ports are forwarded as is.`,
+		Example: `  tiup cluster tunnel mycluster -N grafana -N dashboard
+  tiup cluster tunnel mycluster -N pd,172.16.5.140:9090 --ssh-proxy-host bastion`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.Tunnel(clusterName, targets, gOpt)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&targets, "target", "N", nil, "The targets to forward to, a component name (e.g. grafana, pd), a node or dashboard")
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "status-timeout", 10, "Timeout in seconds when requesting PD for TiDB Dashboard")
+	_ = cmd.MarkFlagRequired("target")
+
+	return cmd
+}
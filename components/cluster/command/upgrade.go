@@ -14,17 +14,24 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 func newUpgradeCmd() *cobra.Command {
 	offlineMode := false
+	showHooks := false
 
 	cmd := &cobra.Command{
 		Use:   "upgrade <cluster-name> <version>",
 		Short: "Upgrade a specified TiDB cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if showHooks && len(args) >= 1 {
+				clusterReport.ID = scrubClusterName(args[0])
+				teleCommand = append(teleCommand, scrubClusterName(args[0]))
+				return cm.ShowRestartHooks(args[0], gOpt)
+			}
 			if len(args) != 2 {
 				return cmd.Help()
 			}
@@ -53,6 +60,12 @@ func newUpgradeCmd() *cobra.Command {
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders, also for TiCDC drain one capture")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
 	cmd.Flags().BoolVarP(&offlineMode, "offline", "", false, "Upgrade a stopped cluster")
+	cmd.Flags().BoolVar(&gOpt.SkipUnreachable, "skip-unreachable", false, "Skip and quarantine unreachable hosts, they could be caught up later with the reconcile command")
+	cmd.Flags().BoolVar(&gOpt.SkipUpgradeHooks, "skip-hooks", false, "Skip the SQL hooks of the versions upgraded across, they are recorded as skipped")
+	cmd.Flags().BoolVar(&gOpt.ZoneAware, "zone-aware", false, "Restart the instances of PD, TiKV and TiFlash zone by zone, the zones are read from the labels of the instances or the other ones on their hosts")
+	cmd.Flags().StringVar(&gOpt.ZoneLabel, "zone-label", spec.CloudLabelZone, "The label name of zones used by --zone-aware")
+	cmd.Flags().BoolVar(&showHooks, "show-hooks", false, "Only list the graceful hooks run when restarting the instances, e.g. evicting the leaders, and their timeouts, the version is not required")
+	cmd.Flags().StringSliceVar(&gOpt.DisabledRestartHooks, "disable-hook", nil, "The graceful hooks not to run when restarting the instances, available values are [resign-leader, drain, health, ready], prefix with the component to disable it for the component only, e.g. tikv:resign-leader")
 
 	return cmd
 }
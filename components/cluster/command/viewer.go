@@ -0,0 +1,63 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/spf13/cobra"
+)
+
+// viewerCommands are the commands allowed in the viewer mode, none of them
+// changes the cluster or needs its private key
+var viewerCommands = []string{
+	"display",
+	"show-config",
+	"audit",
+	"check",
+	"top",
+	"service-status",
+	"list",
+}
+
+// viewerAllowed are the names of the commands allowed in the viewer mode,
+// including the ones not touching the cluster at all
+var viewerAllowed = set.NewStringSet(append([]string{
+	"help",
+	"completion",
+	"__complete",
+}, viewerCommands...)...)
+
+// viewerCommandList returns the commands allowed in the viewer mode in
+// text, e.g. "display, audit and list"
+func viewerCommandList() string {
+	last := len(viewerCommands) - 1
+	return strings.Join(viewerCommands[:last], ", ") + " and " + viewerCommands[last]
+}
+
+// checkViewerCommand rejects the commands not allowed in the viewer mode
+func checkViewerCommand(cmd *cobra.Command) error {
+	names := getParentNames(cmd)[1:]
+	if len(names) == 0 {
+		return nil
+	}
+	// the subcommands of audit clean up the logs
+	if viewerAllowed.Exist(names[0]) && (names[0] != "audit" || len(names) == 1) {
+		return nil
+	}
+	return perrs.Errorf("`%s` is not allowed in the viewer mode, only %s are allowed",
+		strings.Join(names, " "), viewerCommandList())
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCheckViewerCommand(t *testing.T) {
+	root := &cobra.Command{Use: "tiup-cluster"}
+	audit := &cobra.Command{Use: "audit"}
+	audit.AddCommand(&cobra.Command{Use: "cleanup"})
+	root.AddCommand(
+		&cobra.Command{Use: "display"},
+		&cobra.Command{Use: "top"},
+		&cobra.Command{Use: "service-status"},
+		&cobra.Command{Use: "start"},
+		audit,
+	)
+
+	tests := []struct {
+		args    []string
+		allowed bool
+	}{
+		{[]string{"display"}, true},
+		{[]string{"audit"}, true},
+		{[]string{"top"}, true},
+		{[]string{"service-status"}, true},
+		{[]string{"start"}, false},
+		{[]string{"audit", "cleanup"}, false},
+	}
+	for _, test := range tests {
+		cmd, _, err := root.Find(test.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkViewerCommand(cmd); (err == nil) != test.allowed {
+			t.Fatalf("expected %v allowed: %v, got error %v", test.args, test.allowed, err)
+		}
+	}
+}
+
+func TestViewerCommandList(t *testing.T) {
+	list := viewerCommandList()
+	if list != "display, show-config, audit, check, top, service-status and list" {
+		t.Fatalf("unexpected viewer commands: %s", list)
+	}
+
+	// the rejected commands are told what are allowed
+	root := &cobra.Command{Use: "tiup-cluster"}
+	start := &cobra.Command{Use: "start"}
+	root.AddCommand(start)
+	err := checkViewerCommand(start)
+	if err == nil || !strings.Contains(err.Error(), list) {
+		t.Fatalf("expected the allowed commands in the error, got %v", err)
+	}
+}
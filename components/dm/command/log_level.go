@@ -0,0 +1,67 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/spf13/cobra"
+)
+
+func newLogLevelCmd() *cobra.Command {
+	opt := manager.LogLevelOptions{}
+	cmd := &cobra.Command{
+		Use:   "log-level <cluster-name>",
+		Short: "Change the log level of instances temporarily",
+		Long: `Change the log level of DM master and worker instances temporarily, they are
+restarted with the level set by a runtime override, and restarted again without
+it after the duration, or on Ctrl+C or the terminal being closed. The changes
+are recorded in the meta, use --revert to restore them if the command is killed
+before reverting, or they are reverted by the next run after the duration.`,
+		Example: `  tiup dm log-level test-dm -R dm-worker --set debug --duration 30m
+  tiup dm log-level test-dm --revert`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			if opt.Level == "" && !opt.Revert {
+				return cmd.Help()
+			}
+			if err := validRoles(gOpt.Roles); err != nil {
+				return err
+			}
+
+			clusterName := args[0]
+
+			return cm.LogLevel(clusterName, opt, gOpt)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return shellCompGetClusterName(cm, toComplete)
+			default:
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only change the log level of specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only change the log level of specified nodes")
+	cmd.Flags().StringVar(&opt.Level, "set", "", "The log level to set: debug, info, warn or error")
+	cmd.Flags().DurationVar(&opt.Duration, "duration", 30*time.Minute, "Revert the log levels after the duration, 0 to keep them")
+	cmd.Flags().BoolVar(&opt.Revert, "revert", false, "Revert the log levels recorded by an interrupted run")
+
+	return cmd
+}
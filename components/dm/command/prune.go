@@ -14,6 +14,7 @@
 package command
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -76,8 +77,9 @@ func clearOutDatedEtcdInfo(clusterName string, metadata *spec.Metadata, opt oper
 	if err != nil {
 		return err
 	}
-	dmMasterClient := api.NewDMMasterClient(topo.GetMasterList(), 10*time.Second, tlsCfg)
-	registeredMasters, registeredWorkers, err := dmMasterClient.GetRegisteredMembers()
+	ctx := api.WithRequestTimeout(context.Background(), time.Duration(opt.RequestTimeout)*time.Second)
+	dmMasterClient := api.NewDMMasterClient(topo.GetMasterList(), api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
+	registeredMasters, registeredWorkers, err := dmMasterClient.GetRegisteredMembers(ctx)
 	if err != nil {
 		return err
 	}
@@ -102,7 +104,7 @@ func clearOutDatedEtcdInfo(clusterName string, metadata *spec.Metadata, opt oper
 		master := master
 		wg.Add(1)
 		go func() {
-			errCh <- dmMasterClient.OfflineMaster(master, nil)
+			errCh <- dmMasterClient.OfflineMaster(ctx, master, nil)
 			wg.Done()
 		}()
 	}
@@ -110,7 +112,7 @@ func clearOutDatedEtcdInfo(clusterName string, metadata *spec.Metadata, opt oper
 		worker := worker
 		wg.Add(1)
 		go func() {
-			errCh <- dmMasterClient.OfflineWorker(worker, nil)
+			errCh <- dmMasterClient.OfflineWorker(ctx, worker, nil)
 			wg.Done()
 		}()
 	}
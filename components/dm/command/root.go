@@ -96,7 +96,6 @@ please backup your data before process.`,
 				return err
 			}
 			tiupmeta.SetGlobalEnv(env)
-
 			if gOpt.NativeSSH {
 				gOpt.SSHType = executor.SSHTypeSystem
 				zap.L().Info("System ssh client will be used",
@@ -128,6 +127,8 @@ please backup your data before process.`,
 
 	rootCmd.PersistentFlags().Uint64Var(&gOpt.SSHTimeout, "ssh-timeout", 5, "Timeout in seconds to connect host via SSH, ignored for operations that don't need an SSH connection.")
 	rootCmd.PersistentFlags().Uint64Var(&gOpt.OptTimeout, "wait-timeout", 120, "Timeout in seconds to wait for an operation to complete, ignored for operations that don't fit.")
+	rootCmd.PersistentFlags().Uint64Var(&gOpt.RequestTimeout, "request-timeout", 0, "Timeout in seconds of each request to the APIs of the components like DM master, the built-in timeouts are used if it's 0.")
+	rootCmd.PersistentFlags().IntVar(&gOpt.DiagnoseLines, "diagnose-lines", 100, "The count of log and journal lines harvested to the control machine from the instances failed to start, 0 to disable.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
 	rootCmd.PersistentFlags().BoolVar(&gOpt.NativeSSH, "native-ssh", gOpt.NativeSSH, "Use the SSH client installed on local system instead of the build-in one.")
 	rootCmd.PersistentFlags().StringVar((*string)(&gOpt.SSHType), "ssh", "", "The executor type: 'builtin', 'system', 'none'")
@@ -170,6 +171,8 @@ please backup your data before process.`,
 		newReplayCmd(),
 		newTemplateCmd(),
 		newMetaCmd(),
+		newRecoverCmd(),
+		newLogLevelCmd(),
 	)
 }
 
@@ -235,6 +238,12 @@ func Execute() {
 
 	code := 0
 	err := rootCmd.Execute()
+	// write back the changes to the encrypted clusters and clean up the decrypted files
+	if dmspec != nil {
+		if lockErr := dmspec.LockAll(); lockErr != nil && err == nil {
+			err = lockErr
+		}
+	}
 	if err != nil {
 		code = 1
 	}
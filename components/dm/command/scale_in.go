@@ -134,7 +134,7 @@ func ScaleInDMCluster(
 		return errors.New("cannot find available dm-master instance")
 	}
 
-	dmMasterClient = api.NewDMMasterClient(dmMasterEndpoint, 10*time.Second, tlsCfg)
+	dmMasterClient = api.NewDMMasterClient(dmMasterEndpoint, api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
 
 	noAgentHosts := set.NewStringSet()
 	topo.IterInstance(func(inst dm.Instance) {
@@ -166,13 +166,13 @@ func ScaleInDMCluster(
 			switch component.Name() {
 			case dm.ComponentDMMaster:
 				name := instance.(*dm.MasterInstance).Name
-				err := dmMasterClient.OfflineMaster(name, nil)
+				err := dmMasterClient.OfflineMaster(ctx, name, nil)
 				if err != nil {
 					return err
 				}
 			case dm.ComponentDMWorker:
 				name := instance.(*dm.WorkerInstance).Name
-				err := dmMasterClient.OfflineWorker(name, nil)
+				err := dmMasterClient.OfflineWorker(ctx, name, nil)
 				if err != nil {
 					return err
 				}
@@ -19,11 +19,15 @@ import (
 
 func newUpgradeCmd() *cobra.Command {
 	offlineMode := false
+	showHooks := false
 
 	cmd := &cobra.Command{
 		Use:   "upgrade <cluster-name> <version>",
 		Short: "Upgrade a specified DM cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if showHooks && len(args) >= 1 {
+				return cm.ShowRestartHooks(args[0], gOpt)
+			}
 			if len(args) != 2 {
 				return cmd.Help()
 			}
@@ -41,6 +45,8 @@ func newUpgradeCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&offlineMode, "offline", "", false, "Upgrade a stopped cluster")
+	cmd.Flags().BoolVar(&showHooks, "show-hooks", false, "Only list the graceful hooks run when restarting the instances, e.g. evicting the leaders, and their timeouts, the version is not required")
+	cmd.Flags().StringSliceVar(&gOpt.DisabledRestartHooks, "disable-hook", nil, "The graceful hooks not to run when restarting the instances, available values are [resign-leader, drain, health, ready], prefix with the component to disable it for the component only, e.g. tikv:resign-leader")
 
 	return cmd
 }
@@ -14,9 +14,7 @@
 package spec
 
 import (
-	"fmt"
 	"path/filepath"
-	"reflect"
 
 	cspec "github.com/pingcap/tiup/pkg/cluster/spec"
 )
@@ -28,6 +26,16 @@ type Metadata struct {
 	User    string `yaml:"user"`       // the user to run and manage cluster on remote
 	Version string `yaml:"dm_version"` // the version of TiDB cluster
 	// EnableFirewall bool   `yaml:"firewall"`
+	// hosts skipped by previous operations because they were unreachable
+	QuarantinedHosts []string `yaml:"quarantined_hosts,omitempty"`
+	// temporary flag overrides of the instances
+	RuntimeOverrides []cspec.RuntimeOverride `yaml:"runtime_overrides,omitempty"`
+	// the log levels changed temporarily at runtime
+	LogLevelChanges []cspec.LogLevelChange `yaml:"log_level_changes,omitempty"`
+	// the samples of the disk usage of the data dirs
+	DiskUsages []cspec.DiskUsageHistory `yaml:"disk_usages,omitempty"`
+	// the restarts of the instances
+	Restarts []cspec.RestartHistory `yaml:"restarts,omitempty"`
 
 	Topology *Specification `yaml:"topology"`
 }
@@ -50,13 +58,14 @@ func (m *Metadata) GetTopology() cspec.Topology {
 }
 
 // SetTopology implements Metadata interface.
-func (m *Metadata) SetTopology(topo cspec.Topology) {
-	dmTopo, ok := topo.(*Specification)
-	if !ok {
-		panic(fmt.Sprintln("wrong type: ", reflect.TypeOf(topo)))
+func (m *Metadata) SetTopology(topo cspec.Topology) error {
+	dmTopo, err := AsDMTopology(topo)
+	if err != nil {
+		return err
 	}
 
 	m.Topology = dmTopo
+	return nil
 }
 
 // GetBaseMeta implements Metadata interface.
@@ -64,6 +73,12 @@ func (m *Metadata) GetBaseMeta() *cspec.BaseMeta {
 	return &cspec.BaseMeta{
 		Version: m.Version,
 		User:    m.User,
+
+		QuarantinedHosts: &m.QuarantinedHosts,
+		RuntimeOverrides: &m.RuntimeOverrides,
+		LogLevelChanges:  &m.LogLevelChanges,
+		DiskUsages:       &m.DiskUsages,
+		Restarts:         &m.Restarts,
 	}
 }
 
@@ -74,7 +89,7 @@ func GetSpecManager() *cspec.SpecManager {
 			return &Metadata{
 				Topology: new(Specification),
 			}
-		})
+		}).SetHostRegistry(cspec.NewHostRegistry(cspec.HostRegistryPath(), "dm"))
 	}
 	return specManager
 }
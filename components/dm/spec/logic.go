@@ -22,10 +22,14 @@ import (
 	"sync"
 	"time"
 
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/utils"
 )
 
 // Components names supported by TiUP
@@ -132,7 +136,7 @@ func (i *MasterInstance) InitConfig(
 	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).WithPeerPort(spec.PeerPort).AppendEndpoints(i.topo.Endpoints(deployUser)...).WithV1SourcePath(spec.V1SourcePath)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_dm-master_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_dm-master.sh")
@@ -191,6 +195,82 @@ func (i *MasterInstance) setTLSConfig(ctx context.Context, enableTLS bool, confi
 	return configs, nil
 }
 
+var _ spec.RollingUpdateInstance = &MasterInstance{}
+
+// API implements RollingUpdateInstance interface.
+func (i *MasterInstance) API(topo spec.Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) (spec.ComponentAPI, error) {
+	dmTopo, err := AsDMTopology(topo)
+	if err != nil {
+		return nil, err
+	}
+	return &masterAPI{
+		ins:               i,
+		topo:              dmTopo,
+		apiTimeoutSeconds: apiTimeoutSeconds,
+		tlsCfg:            tlsCfg,
+	}, nil
+}
+
+// masterAPI transfers the leader before restarting a DM master instance
+type masterAPI struct {
+	spec.NopComponentAPI
+	ins               *MasterInstance
+	topo              *Specification
+	apiTimeoutSeconds int
+	tlsCfg            *tls.Config
+}
+
+// RestartHooks implements RestartHookDescriber interface.
+func (a *masterAPI) RestartHooks() []spec.RestartHook {
+	timeout := time.Second * time.Duration(a.apiTimeoutSeconds)
+	var hooks []spec.RestartHook
+	if len(a.topo.Masters) > 1 {
+		hooks = append(hooks, spec.RestartHook{Name: spec.RestartHookResignLeader, Action: "transfer the DM master leader if it's the leader", Timeout: timeout})
+	}
+	return append(hooks, spec.RestartHook{Name: spec.RestartHookHealth, Action: "wait for the DM master to be active", Timeout: 2 * timeout})
+}
+
+// ResignLeader implements ComponentAPI interface.
+func (a *masterAPI) ResignLeader(ctx context.Context) error {
+	if len(a.topo.Masters) <= 1 {
+		return nil
+	}
+
+	timeout := time.Second * time.Duration(a.apiTimeoutSeconds)
+	client := api.NewDMMasterClient(a.topo.GetMasterList(), api.RequestTimeout(ctx, timeout), a.tlsCfg)
+	_, _, isLeader, err := client.GetMaster(ctx, a.ins.Name)
+	if err != nil {
+		return perrs.Annotatef(err, "failed to get DM master leader %s", a.ins.GetHost())
+	}
+	if !isLeader {
+		return nil
+	}
+	if err := client.EvictDMMasterLeader(ctx, &utils.RetryOption{Timeout: timeout, Delay: 2 * time.Second}); err != nil {
+		return perrs.Annotatef(err, "failed to evict DM master leader %s", a.ins.GetHost())
+	}
+	return nil
+}
+
+// Health implements ComponentAPI interface.
+func (a *masterAPI) Health(ctx context.Context) error {
+	timeout := time.Second * time.Duration(a.apiTimeoutSeconds)
+	client := api.NewDMMasterClient(a.topo.GetMasterList(), api.RequestTimeout(ctx, timeout), a.tlsCfg)
+	err := utils.Wait(ctx, func() error {
+		_, isActive, isLeader, err := client.GetMaster(ctx, a.ins.Name)
+		if err != nil {
+			return err
+		}
+		if !isActive && !isLeader {
+			return perrs.Errorf("DM master %s is not active", a.ins.Name)
+		}
+		return nil
+	}, utils.WaitOption{Timeout: 2 * timeout, Interval: 2 * time.Second})
+	if err != nil {
+		return perrs.Annotatef(err, "failed to start DM master %s", a.ins.GetHost())
+	}
+	return nil
+}
+
 // ScaleConfig deploy temporary config on scaling
 func (i *MasterInstance) ScaleConfig(
 	ctx context.Context,
@@ -218,7 +298,7 @@ func (i *MasterInstance) ScaleConfig(
 	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).WithPeerPort(spec.PeerPort).AppendEndpoints(c.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_dm-master_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 
@@ -308,7 +388,7 @@ func (i *WorkerInstance) InitConfig(
 		paths.Log,
 	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(i.topo.Endpoints(deployUser)...)
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_dm-worker_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_dm-worker.sh")
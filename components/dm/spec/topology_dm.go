@@ -141,14 +141,14 @@ type MasterSpec struct {
 }
 
 // Status queries current status of the instance
-func (s *MasterSpec) Status(_ context.Context, timeout time.Duration, tlsCfg *tls.Config, _ ...string) string {
+func (s *MasterSpec) Status(ctx context.Context, timeout time.Duration, tlsCfg *tls.Config, _ ...string) string {
 	if timeout < time.Second {
 		timeout = statusQueryTimeout
 	}
 
 	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
-	dc := api.NewDMMasterClient([]string{addr}, timeout, tlsCfg)
-	isFound, isActive, isLeader, err := dc.GetMaster(s.Name)
+	dc := api.NewDMMasterClient([]string{addr}, api.RequestTimeout(ctx, timeout), tlsCfg)
+	isFound, isActive, isLeader, err := dc.GetMaster(ctx, s.Name)
 	if err != nil {
 		return "Down"
 	}
@@ -211,7 +211,7 @@ type WorkerSpec struct {
 }
 
 // Status queries current status of the instance
-func (s *WorkerSpec) Status(_ context.Context, timeout time.Duration, tlsCfg *tls.Config, masterList ...string) string {
+func (s *WorkerSpec) Status(ctx context.Context, timeout time.Duration, tlsCfg *tls.Config, masterList ...string) string {
 	if len(masterList) < 1 {
 		return "N/A"
 	}
@@ -219,8 +219,8 @@ func (s *WorkerSpec) Status(_ context.Context, timeout time.Duration, tlsCfg *tl
 	if timeout < time.Second {
 		timeout = statusQueryTimeout
 	}
-	dc := api.NewDMMasterClient(masterList, timeout, tlsCfg)
-	stage, err := dc.GetWorker(s.Name)
+	dc := api.NewDMMasterClient(masterList, api.RequestTimeout(ctx, timeout), tlsCfg)
+	stage, err := dc.GetWorker(ctx, s.Name)
 	if err != nil {
 		return "Down"
 	}
@@ -674,14 +674,24 @@ func (s *Specification) NewPart() spec.Topology {
 	}
 }
 
+// AsDMTopology converts the topology to the DM topology, an error is returned
+// instead of panic if it's not a DM topology.
+func AsDMTopology(topo spec.Topology) (*Specification, error) {
+	dmTopo, ok := topo.(*Specification)
+	if !ok || dmTopo == nil {
+		return nil, spec.NewTopologyTypeMismatchError(spec.TopoTypeDM, topo)
+	}
+	return dmTopo, nil
+}
+
 // MergeTopo implements ScaleOutTopology interface.
-func (s *Specification) MergeTopo(rhs spec.Topology) spec.Topology {
-	other, ok := rhs.(*Specification)
-	if !ok {
-		panic("topo should be DM Topology")
+func (s *Specification) MergeTopo(rhs spec.Topology) (spec.Topology, error) {
+	other, err := AsDMTopology(rhs)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.Merge(other)
+	return s.Merge(other), nil
 }
 
 // GetMasterList returns a list of Master API hosts of the current cluster
@@ -18,6 +18,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/joomcode/errorx"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/stretchr/testify/assert"
@@ -313,7 +314,10 @@ func merge4test(base, scale string) (*Specification, error) {
 		return nil, err
 	}
 
-	mergedTopo := baseTopo.MergeTopo(scaleTopo)
+	mergedTopo, err := baseTopo.MergeTopo(scaleTopo)
+	if err != nil {
+		return nil, err
+	}
 	if err := mergedTopo.Validate(); err != nil {
 		return nil, err
 	}
@@ -546,3 +550,18 @@ monitored:
 		assert.Equal(t, "test-deploy", topo.MonitoredOptions.DeployDir)
 	})
 }
+
+func TestTopologyTypeMismatch(t *testing.T) {
+	topo := &Specification{}
+	_, err := topo.MergeTopo(&spec.Specification{})
+	assert.True(t, errorx.IsOfType(err, spec.ErrTopologyTypeMismatch))
+	merged, err := topo.MergeTopo(topo.NewPart())
+	assert.Nil(t, err)
+	assert.IsType(t, topo, merged)
+
+	meta := &Metadata{}
+	err = meta.SetTopology(&spec.Specification{})
+	assert.True(t, errorx.IsOfType(err, spec.ErrTopologyTypeMismatch))
+	assert.Nil(t, meta.SetTopology(topo))
+	assert.Equal(t, topo, meta.Topology)
+}
@@ -35,6 +35,8 @@ const (
 	ScaleInCommandType  CommandType = "scale-in"
 	ScaleOutCommandType CommandType = "scale-out"
 	DisplayCommandType  CommandType = "display"
+
+	UpgradeComponentCommandType CommandType = "upgrade-component"
 )
 
 // Command send to Playground.
@@ -154,6 +156,28 @@ func newDisplay() *cobra.Command {
 	return cmd
 }
 
+func newUpgradeComponent() *cobra.Command {
+	var binPath string
+
+	cmd := &cobra.Command{
+		Use:     "upgrade-component <component>",
+		Short:   "Restart all instances of a component with a new binary",
+		Example: "tiup playground upgrade-component tidb --binpath ./bin/tidb-server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 || binPath == "" {
+				return cmd.Help()
+			}
+
+			return upgradeComponent(args[0], binPath)
+		},
+		Hidden: false,
+	}
+
+	cmd.Flags().StringVar(&binPath, "binpath", "", "path of the new binary of the component")
+
+	return cmd
+}
+
 func scaleIn(pids []int) error {
 	port, err := targetTag()
 	if err != nil {
@@ -201,6 +225,34 @@ func display(args []string) error {
 	return sendCommandsAndPrintResult([]Command{c}, addr)
 }
 
+func upgradeComponent(componentID, binPath string) error {
+	port, err := targetTag()
+	if err != nil {
+		return err
+	}
+
+	// the playground may run in a different working directory, so always
+	// send the absolute path of the binary.
+	binPath, err = getAbsolutePath(binPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(binPath); err != nil {
+		return errors.Annotatef(err, "cannot access binary %s", binPath)
+	}
+
+	c := Command{
+		CommandType: UpgradeComponentCommandType,
+		ComponentID: componentID,
+		Config: instance.Config{
+			BinPath: binPath,
+		},
+	}
+
+	addr := "127.0.0.1:" + strconv.Itoa(port)
+	return sendCommandsAndPrintResult([]Command{c}, addr)
+}
+
 func sendCommandsAndPrintResult(cmds []Command, addr string) error {
 	for _, cmd := range cmds {
 		data, err := json.Marshal(&cmd)
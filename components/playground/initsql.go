@@ -0,0 +1,106 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// initSQLFiles returns the SQL files to execute in the order of their names,
+// path could be either a directory containing *.sql files or a single file.
+func initSQLFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".sql") {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// execInitSQL executes the init SQL files against the TiDB listening on dbAddr,
+// TiDB may be able to accept connections before the schema is fully loaded, so
+// it keeps retrying a simple query until TiDB is ready before executing them.
+func execInitSQL(dbAddr, path string, timeout int) error {
+	files, err := initSQLFiles(path)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	cli, err := sql.Open("mysql", fmt.Sprintf("root:@tcp(%s)/?multiStatements=true", dbAddr))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer cli.Close()
+
+	if timeout <= 0 {
+		timeout = 60
+	}
+	err = utils.Wait(context.Background(), func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		_, err := cli.ExecContext(ctx, "SELECT COUNT(*) FROM information_schema.schemata")
+		return err
+	}, utils.WaitOption{
+		Interval: time.Second,
+		Timeout:  time.Second * time.Duration(timeout),
+	})
+	if err != nil {
+		return errors.Annotatef(err, "TiDB %s is not ready to execute init SQL", dbAddr)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			continue
+		}
+		fmt.Printf("Executing init SQL file %s\n", file)
+		if _, err := cli.Exec(string(data)); err != nil {
+			return errors.Annotatef(err, "failed to execute init SQL file %s", file)
+		}
+	}
+	fmt.Println(color.GreenString("Init SQL executed successfully"))
+	return nil
+}
@@ -58,6 +58,12 @@ type Instance interface {
 	// Wait Should only call this if the instance is started successfully.
 	// The implementation should be safe to call Wait multi times.
 	Wait() error
+	// SetBinPath replace the binary used by the next Start.
+	SetBinPath(binPath string)
+}
+
+func (inst *instance) SetBinPath(binPath string) {
+	inst.BinPath = binPath
 }
 
 func (inst *instance) StatusAddrs() (addrs []string) {
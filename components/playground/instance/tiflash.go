@@ -115,7 +115,7 @@ func (inst *TiFlashInstance) Start(ctx context.Context, version utils.Version) e
 	if err != nil {
 		return err
 	}
-	if err = pdClient.UpdateScheduleConfig(bytes.NewBuffer(lowSpaceRatio)); err != nil {
+	if err = pdClient.UpdateScheduleConfig(ctx, bytes.NewBuffer(lowSpaceRatio)); err != nil {
 		return err
 	}
 	// Update maxReplicas before placement rules so that it would not be overwritten
@@ -125,7 +125,7 @@ func (inst *TiFlashInstance) Start(ctx context.Context, version utils.Version) e
 	if err != nil {
 		return err
 	}
-	if err = pdClient.UpdateReplicateConfig(bytes.NewBuffer(maxReplicas)); err != nil {
+	if err = pdClient.UpdateReplicateConfig(ctx, bytes.NewBuffer(maxReplicas)); err != nil {
 		return err
 	}
 	// Set enable-placement-rules to allow TiFlash work properly
@@ -135,7 +135,7 @@ func (inst *TiFlashInstance) Start(ctx context.Context, version utils.Version) e
 	if err != nil {
 		return err
 	}
-	if err = pdClient.UpdateReplicateConfig(bytes.NewBuffer(enablePlacementRules)); err != nil {
+	if err = pdClient.UpdateReplicateConfig(ctx, bytes.NewBuffer(enablePlacementRules)); err != nil {
 		return err
 	}
 
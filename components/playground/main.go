@@ -64,6 +64,7 @@ type BootOptions struct {
 	Drainer instance.Config `yaml:"drainer"`
 	Host    string          `yaml:"host"`
 	Monitor bool            `yaml:"monitor"`
+	InitSQL string          `yaml:"init_sql"`
 }
 
 var (
@@ -72,6 +73,7 @@ var (
 	playgroundReport *telemetry.PlaygroundReport
 	options          = &BootOptions{}
 	tag              string
+	waitTimeout      int
 	deleteWhenExit   bool
 	tiupDataDir      string
 	dataDir          string
@@ -120,6 +122,9 @@ const (
 	ticdcBinpath   = "ticdc.binpath"
 	pumpBinpath    = "pump.binpath"
 	drainerBinpath = "drainer.binpath"
+
+	// init sql scripts
+	initSQL = "init-sql"
 )
 
 func installIfMissing(component, version string) error {
@@ -162,10 +167,7 @@ Examples:
 		},
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			tiupDataDir = os.Getenv(localdata.EnvNameInstanceDataDir)
-			tiupHome := os.Getenv(localdata.EnvNameHome)
-			if tiupHome == "" {
-				tiupHome, _ = getAbsolutePath(filepath.Join("~", localdata.ProfileDirName))
-			}
+			tiupHome, _ := tiupHomeDir()
 			switch {
 			case tag != "":
 				dataDir = filepath.Join(tiupHome, localdata.DataParentDir, tag)
@@ -285,6 +287,29 @@ If you'd like to use a TiDB version other than %s, cancel and retry with the fol
 				options.Version = version.String()
 			}
 
+			// the report of last run is meaningless now
+			_ = os.Remove(filepath.Join(dataDir, readinessFile))
+
+			var deadline time.Time
+			if waitTimeout > 0 {
+				deadline = time.Now().Add(time.Second * time.Duration(waitTimeout))
+				// bootCluster may block for a long time, e.g. waiting for TiDB up
+				// without limit, so quit directly if it's not done in time.
+				timer := time.AfterFunc(time.Until(deadline), func() {
+					if atomic.LoadUint32(&booted) == 1 {
+						return
+					}
+					fmt.Println(color.RedString("Error: Playground is not ready after %ds", waitTimeout))
+					atomic.StoreInt32(&p.curSig, int32(syscall.SIGKILL))
+					cancel()
+					time.AfterFunc(time.Second, func() {
+						removeData()
+						os.Exit(1)
+					})
+				})
+				defer timer.Stop()
+			}
+
 			bootErr := p.bootCluster(ctx, env, options)
 			if bootErr != nil {
 				// always kill all process started and wait before quit.
@@ -296,6 +321,17 @@ If you'd like to use a TiDB version other than %s, cancel and retry with the fol
 
 			atomic.StoreUint32(&booted, 1)
 
+			if waitTimeout > 0 {
+				if err := p.waitReady(time.Until(deadline)); err != nil {
+					atomic.StoreInt32(&p.curSig, int32(syscall.SIGKILL))
+					p.terminate(syscall.SIGKILL)
+					_ = p.wait()
+					return err
+				}
+			}
+			logIfErr(p.dumpReadiness())
+			defer os.Remove(filepath.Join(dataDir, readinessFile))
+
 			waitErr := p.wait()
 			if waitErr != nil {
 				return waitErr
@@ -324,6 +360,7 @@ If you'd like to use a TiDB version other than %s, cancel and retry with the fol
 
 	rootCmd.Flags().Int(dbTimeout, defaultOptions.TiDB.UpTimeout, "TiDB max wait time in seconds for starting, 0 means no limit")
 	rootCmd.Flags().Int(tiflashTimeout, defaultOptions.TiFlash.UpTimeout, "TiFlash max wait time in seconds for starting, 0 means no limit")
+	rootCmd.Flags().IntVar(&waitTimeout, "wait-timeout", 0, "Max wait time in seconds for all instances to be ready, exit with non-zero code if not ready in time, 0 means no limit")
 
 	rootCmd.Flags().String(clusterHost, defaultOptions.Host, "Playground cluster host")
 	rootCmd.Flags().String(dbHost, defaultOptions.TiDB.Host, "Playground TiDB host. If not provided, TiDB will still use `host` flag as its host")
@@ -347,9 +384,13 @@ If you'd like to use a TiDB version other than %s, cancel and retry with the fol
 	rootCmd.Flags().String(pumpBinpath, defaultOptions.Pump.BinPath, "Pump instance binary path")
 	rootCmd.Flags().String(drainerBinpath, defaultOptions.Drainer.BinPath, "Drainer instance binary path")
 
+	rootCmd.Flags().String(initSQL, defaultOptions.InitSQL, "SQL file or directory of *.sql files to execute once TiDB is ready")
+
 	rootCmd.AddCommand(newDisplay())
 	rootCmd.AddCommand(newScaleOut())
 	rootCmd.AddCommand(newScaleIn())
+	rootCmd.AddCommand(newUpgradeComponent())
+	rootCmd.AddCommand(newSnapshot())
 
 	return rootCmd.Execute()
 }
@@ -470,6 +511,9 @@ func populateOpt(flagSet *pflag.FlagSet) (err error) {
 				return
 			}
 
+		case initSQL:
+			options.InitSQL = flag.Value.String()
+
 		case clusterHost:
 			options.Host = flag.Value.String()
 		case dbHost:
@@ -532,7 +576,7 @@ func checkDB(dbAddr string, timeout int) bool {
 func checkStoreStatus(pdClient *api.PDClient, storeAddr string, timeout int) bool {
 	if timeout > 0 {
 		for i := 0; i < timeout; i++ {
-			if up, err := pdClient.IsUp(storeAddr); err == nil && up {
+			if up, err := pdClient.IsUp(apiContext(), storeAddr); err == nil && up {
 				return true
 			}
 			time.Sleep(time.Second)
@@ -540,7 +584,7 @@ func checkStoreStatus(pdClient *api.PDClient, storeAddr string, timeout int) boo
 		return false
 	}
 	for {
-		if up, err := pdClient.IsUp(storeAddr); err == nil && up {
+		if up, err := pdClient.IsUp(apiContext(), storeAddr); err == nil && up {
 			return true
 		}
 		time.Sleep(time.Second)
@@ -558,6 +602,14 @@ func hasDashboard(pdAddr string) bool {
 }
 
 // getAbsolutePath returns the absolute path
+// tiupHomeDir returns the home dir of tiup
+func tiupHomeDir() (string, error) {
+	if tiupHome := os.Getenv(localdata.EnvNameHome); tiupHome != "" {
+		return tiupHome, nil
+	}
+	return getAbsolutePath(filepath.Join("~", localdata.ProfileDirName))
+}
+
 func getAbsolutePath(path string) (string, error) {
 	if path == "" {
 		return "", nil
@@ -70,6 +70,9 @@ type Playground struct {
 
 	idAlloc        map[string]int
 	instanceWaiter errgroup.Group
+	// the pids of the processes stopped on purpose, e.g. to be restarted
+	// with a new binary, their quitting is not an error
+	expectedStops sync.Map
 
 	// not nil iff we start the exec.Cmd successfully.
 	// we should and can safely call wait() to make sure the process quit
@@ -146,17 +149,20 @@ func (p *Playground) pdClient() *api.PDClient {
 		addrs = append(addrs, inst.Addr())
 	}
 
-	return api.NewPDClient(
-		context.WithValue(context.TODO(), logprinter.ContextKeyLogger, log),
-		addrs, 10*time.Second, nil,
-	)
+	return api.NewPDClient(apiContext(), addrs, 10*time.Second, nil)
+}
+
+// apiContext returns the context to request the APIs of the instances, it
+// carries the logger
+func apiContext() context.Context {
+	return context.WithValue(context.TODO(), logprinter.ContextKeyLogger, log)
 }
 
 func (p *Playground) killKVIfTombstone(inst *instance.TiKVInstance) {
 	defer logIfErr(p.renderSDFile())
 
 	for {
-		tombstone, err := p.pdClient().IsTombStone(inst.Addr())
+		tombstone, err := p.pdClient().IsTombStone(apiContext(), inst.Addr())
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -229,7 +235,7 @@ func (p *Playground) killTiFlashIfTombstone(inst *instance.TiFlashInstance) {
 	defer logIfErr(p.renderSDFile())
 
 	for {
-		tombstone, err := p.pdClient().IsTombStone(inst.Addr())
+		tombstone, err := p.pdClient().IsTombStone(apiContext(), inst.Addr())
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -276,7 +282,7 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 		for i := 0; i < len(p.pds); i++ {
 			if p.pds[i].Pid() == pid {
 				inst := p.pds[i]
-				err := p.pdClient().DelPD(inst.Name(), timeoutOpt)
+				err := p.pdClient().DelPD(apiContext(), inst.Name(), timeoutOpt)
 				if err != nil {
 					return err
 				}
@@ -287,7 +293,7 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 		for i := 0; i < len(p.tikvs); i++ {
 			if p.tikvs[i].Pid() == pid {
 				inst := p.tikvs[i]
-				err := p.pdClient().DelStore(inst.Addr(), timeoutOpt)
+				err := p.pdClient().DelStore(apiContext(), inst.Addr(), timeoutOpt)
 				if err != nil {
 					return err
 				}
@@ -313,7 +319,7 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 		for i := 0; i < len(p.tiflashs); i++ {
 			if p.tiflashs[i].Pid() == pid {
 				inst := p.tiflashs[i]
-				err := p.pdClient().DelStore(inst.Addr(), timeoutOpt)
+				err := p.pdClient().DelStore(apiContext(), inst.Addr(), timeoutOpt)
 				if err != nil {
 					return err
 				}
@@ -433,9 +439,25 @@ func (p *Playground) startInstance(ctx context.Context, inst instance.Instance)
 }
 
 func (p *Playground) addWaitInstance(inst instance.Instance) {
-	p.startedInstances = append(p.startedInstances, inst)
+	// the instances restarted are started already
+	started := false
+	for _, e := range p.startedInstances {
+		if e == inst {
+			started = true
+			break
+		}
+	}
+	if !started {
+		p.startedInstances = append(p.startedInstances, inst)
+	}
+
+	pid := inst.Pid()
 	p.instanceWaiter.Go(func() error {
 		err := inst.Wait()
+		if _, ok := p.expectedStops.LoadAndDelete(pid); ok {
+			fmt.Printf("%s(%d) stopped to restart\n", inst.Component(), pid)
+			return nil
+		}
 		if err != nil && atomic.LoadInt32(&p.curSig) == 0 {
 			fmt.Print(color.RedString("%s quit: %s\n", inst.Component(), err.Error()))
 			if lines, _ := utils.TailN(inst.LogFile(), 10); len(lines) > 0 {
@@ -485,6 +507,64 @@ func (p *Playground) handleScaleOut(w io.Writer, cmd *Command) error {
 	return nil
 }
 
+// handleUpgradeComponent restarts the instances of one component with a new
+// binary one by one, the next instance is restarted only after the previous
+// one is up again.
+func (p *Playground) handleUpgradeComponent(w io.Writer, cmd *Command) error {
+	var insts []instance.Instance
+	_ = p.WalkInstances(func(cid string, ins instance.Instance) error {
+		if cid == cmd.ComponentID {
+			insts = append(insts, ins)
+		}
+		return nil
+	})
+	if len(insts) == 0 {
+		fmt.Fprintf(w, "no instance of component: %s\n", cmd.ComponentID)
+		return nil
+	}
+
+	ctx := context.WithValue(context.TODO(), logprinter.ContextKeyLogger, log)
+	for _, ins := range insts {
+		pid := ins.Pid()
+		fmt.Fprintf(w, "Restarting %s(%d) with %s\n", ins.Component(), pid, cmd.BinPath)
+
+		p.expectedStops.Store(pid, struct{}{})
+		if err := syscall.Kill(pid, syscall.SIGQUIT); err != nil {
+			p.expectedStops.Delete(pid)
+			return errors.AddStack(err)
+		}
+		timer := time.AfterFunc(forceKillAfterDuration, func() {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		})
+		_ = ins.Wait()
+		timer.Stop()
+
+		ins.SetBinPath(cmd.BinPath)
+		if err := p.startInstance(ctx, ins); err != nil {
+			return err
+		}
+
+		up := true
+		switch inst := ins.(type) {
+		case *instance.TiDBInstance:
+			up = checkDB(inst.Addr(), p.bootOptions.TiDB.UpTimeout)
+		case *instance.TiKVInstance:
+			up = checkStoreStatus(p.pdClient(), inst.Addr(), 60)
+		case *instance.TiFlashInstance:
+			up = checkStoreStatus(p.pdClient(), inst.Addr(), p.bootOptions.TiFlash.UpTimeout)
+		}
+		if !up {
+			return errors.Errorf("%s(%d) failed to up after restart, check detail log from: %s",
+				ins.Component(), ins.Pid(), ins.LogFile())
+		}
+	}
+
+	logIfErr(p.renderSDFile())
+
+	fmt.Fprintf(w, "upgrade %s success\n", cmd.ComponentID)
+	return nil
+}
+
 func (p *Playground) handleCommand(cmd *Command, w io.Writer) error {
 	fmt.Printf("receive command: %s\n", cmd.CommandType)
 	switch cmd.CommandType {
@@ -494,6 +574,8 @@ func (p *Playground) handleCommand(cmd *Command, w io.Writer) error {
 		return p.handleScaleIn(w, cmd.PID)
 	case ScaleOutCommandType:
 		return p.handleScaleOut(w, cmd)
+	case UpgradeComponentCommandType:
+		return p.handleUpgradeComponent(w, cmd)
 	}
 
 	return nil
@@ -501,6 +583,7 @@ func (p *Playground) handleCommand(cmd *Command, w io.Writer) error {
 
 func (p *Playground) listenAndServeHTTP() error {
 	http.HandleFunc("/command", p.commandHandler)
+	http.HandleFunc("/ready", p.readinessHandler)
 	return http.ListenAndServe(":"+strconv.Itoa(p.port), nil)
 }
 
@@ -766,6 +849,17 @@ func (p *Playground) waitAllTiFlashUp() {
 }
 
 func (p *Playground) bootCluster(ctx context.Context, env *environment.Environment, options *BootOptions) error {
+	if options.InitSQL != "" {
+		path, err := getAbsolutePath(options.InitSQL)
+		if err != nil {
+			return errors.Annotatef(err, "cannot eval absolute directory: %s", options.InitSQL)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return errors.Annotatef(err, "cannot access init SQL: %s", options.InitSQL)
+		}
+		options.InitSQL = path
+	}
+
 	for _, cfg := range []*instance.Config{
 		&options.PD,
 		&options.TiDB,
@@ -888,6 +982,12 @@ func (p *Playground) bootCluster(ctx context.Context, env *environment.Environme
 		p.tiflashs = started
 		p.waitAllTiFlashUp()
 
+		if options.InitSQL != "" {
+			if err := execInitSQL(succ[0], options.InitSQL, options.TiDB.UpTimeout); err != nil {
+				return err
+			}
+		}
+
 		fmt.Println(color.GreenString("CLUSTER START SUCCESSFULLY, Enjoy it ^-^"))
 		for _, dbAddr := range succ {
 			ss := strings.Split(dbAddr, ":")
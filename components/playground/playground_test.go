@@ -40,3 +40,53 @@ func TestPlaygroundAbsDir(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, filepath.Join(u.HomeDir, "c/d/e"), c)
 }
+
+func TestCopySnapshot(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "snap")
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(src, "tikv-0", "data", "db"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(src, "tikv-0", "data", "db", "000001.sst"), []byte("sst"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(src, "tikv-0", "data", "db", "MANIFEST-000001"), []byte("manifest"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(src, "port"), []byte("9527"), 0644))
+
+	assert.Nil(t, copySnapshot(src, dst))
+
+	data, err := os.ReadFile(filepath.Join(dst, "tikv-0", "data", "db", "000001.sst"))
+	assert.Nil(t, err)
+	assert.Equal(t, "sst", string(data))
+	data, err = os.ReadFile(filepath.Join(dst, "tikv-0", "data", "db", "MANIFEST-000001"))
+	assert.Nil(t, err)
+	assert.Equal(t, "manifest", string(data))
+	_, err = os.Stat(filepath.Join(dst, "port"))
+	assert.True(t, os.IsNotExist(err))
+
+	// files may be appended must not share the inode with the snapshot
+	srcInfo, err := os.Stat(filepath.Join(src, "tikv-0", "data", "db", "MANIFEST-000001"))
+	assert.Nil(t, err)
+	dstInfo, err := os.Stat(filepath.Join(dst, "tikv-0", "data", "db", "MANIFEST-000001"))
+	assert.Nil(t, err)
+	assert.False(t, os.SameFile(srcInfo, dstInfo))
+}
+
+func TestInitSQLFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"02-data.sql", "01-schema.SQL", "README.md"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "sub.sql"), 0755))
+
+	files, err := initSQLFiles(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "01-schema.SQL"),
+		filepath.Join(dir, "02-data.sql"),
+	}, files)
+
+	files, err = initSQLFiles(filepath.Join(dir, "02-data.sql"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "02-data.sql")}, files)
+
+	_, err = initSQLFiles(filepath.Join(dir, "not-exist"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,176 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+// status of an instance in the readiness report
+const (
+	instanceStatusUp   = "up"
+	instanceStatusDown = "down"
+)
+
+// readinessFile is the file in the data dir the readiness report is dumped to
+const readinessFile = "readiness.json"
+
+// InstanceReadiness is the readiness of an instance of the playground
+type InstanceReadiness struct {
+	Component   string   `json:"component"`
+	PID         int      `json:"pid"`
+	Addr        string   `json:"addr,omitempty"`
+	StatusAddrs []string `json:"status_addrs,omitempty"`
+	Status      string   `json:"status"`
+}
+
+// Readiness is the machine readable report of whether the playground is ready
+type Readiness struct {
+	Ready     bool                `json:"ready"`
+	Version   string              `json:"version"`
+	Port      int                 `json:"port"`
+	Instances []InstanceReadiness `json:"instances"`
+}
+
+// readiness checks every instance of the playground, the playground is ready
+// only if all of them are up and serving.
+func (p *Playground) readiness() *Readiness {
+	r := &Readiness{
+		Ready: true,
+		Port:  p.port,
+	}
+	if p.bootOptions != nil {
+		r.Version = p.bootOptions.Version
+	}
+
+	_ = p.WalkInstances(func(cid string, ins instance.Instance) error {
+		ir := InstanceReadiness{
+			Component:   cid,
+			PID:         ins.Pid(),
+			StatusAddrs: ins.StatusAddrs(),
+			Status:      instanceStatusDown,
+		}
+		if a, ok := ins.(interface{ Addr() string }); ok {
+			ir.Addr = a.Addr()
+		}
+		if p.instanceUp(cid, ins, ir.Addr) {
+			ir.Status = instanceStatusUp
+		} else {
+			r.Ready = false
+		}
+		r.Instances = append(r.Instances, ir)
+		return nil
+	})
+	if len(r.Instances) == 0 {
+		r.Ready = false
+	}
+
+	return r
+}
+
+func (p *Playground) instanceUp(cid string, ins instance.Instance, addr string) bool {
+	// the process quit
+	if err := syscall.Kill(ins.Pid(), 0); err != nil {
+		return false
+	}
+
+	switch cid {
+	case spec.ComponentTiDB:
+		return tryConnect(fmt.Sprintf("root:@tcp(%s)/", addr)) == nil
+	case spec.ComponentTiKV, spec.ComponentTiFlash:
+		up, err := p.pdClient().IsUp(apiContext(), addr)
+		return err == nil && up
+	}
+
+	if addr == "" {
+		addrs := ins.StatusAddrs()
+		if len(addrs) == 0 {
+			return true
+		}
+		addr = addrs[0]
+	}
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// waitReady waits until all instances of the playground are ready.
+func (p *Playground) waitReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		// the playground is quitting
+		if atomic.LoadInt32(&p.curSig) != 0 {
+			return nil
+		}
+		r := p.readiness()
+		if r.Ready {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			var down []string
+			for _, ins := range r.Instances {
+				if ins.Status != instanceStatusUp {
+					down = append(down, fmt.Sprintf("%s(%d)", ins.Component, ins.PID))
+				}
+			}
+			return errors.Errorf("playground is not ready after %s, instances not ready: %v", timeout, down)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// dumpReadiness writes the readiness report to the data dir of the playground.
+func (p *Playground) dumpReadiness() error {
+	data, err := json.MarshalIndent(p.readiness(), "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return os.WriteFile(filepath.Join(p.dataDir, readinessFile), data, 0644)
+}
+
+func (p *Playground) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := p.readiness()
+	data, err := json.Marshal(report)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// files in the data dir of a playground that should not be included in a snapshot
+var snapshotExcludes = map[string]struct{}{
+	"port": {},
+	"dsn":  {},
+}
+
+func newSnapshot() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save or load the data of a stopped playground",
+		Long: `Save the data directories of a stopped playground as a named snapshot, and
+restore them later, the playground must be started with the same tag and topology
+to reuse the restored data.
+
+Examples:
+  $ tiup playground --tag fixture --db 1 --kv 1 --pd 1  # Start playground and load data, then stop it
+  $ tiup playground snapshot save base --tag fixture    # Save the data of playground 'fixture' as 'base'
+  $ tiup playground snapshot load base --tag fixture    # Restore the data of playground 'fixture' from 'base'
+  $ tiup playground --tag fixture --db 1 --kv 1 --pd 1  # Start the playground with restored data`,
+		Hidden: false,
+		// the snapshots are taken from the data of an existing playground,
+		// unlike the root command, no data dir is created for a new one
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			tiupDataDir = os.Getenv(localdata.EnvNameInstanceDataDir)
+			switch {
+			case tag != "":
+				tiupHome, err := tiupHomeDir()
+				if err != nil {
+					return err
+				}
+				dataDir = filepath.Join(tiupHome, localdata.DataParentDir, tag)
+			case tiupDataDir != "":
+				dataDir = tiupDataDir
+				tag = filepath.Base(dataDir)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		newSnapshotSave(),
+		newSnapshotLoad(),
+		newSnapshotList(),
+	)
+
+	return cmd
+}
+
+func newSnapshotSave() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "save <name>",
+		Short: "Save the data of a stopped playground as a snapshot",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			if err := checkSnapshotTarget(); err != nil {
+				return err
+			}
+
+			snapDir, err := snapshotPath(args[0])
+			if err != nil {
+				return err
+			}
+			if utils.IsExist(snapDir) {
+				return errors.Errorf("snapshot %s already exists", args[0])
+			}
+
+			if err := copySnapshot(dataDir, snapDir); err != nil {
+				_ = os.RemoveAll(snapDir)
+				return err
+			}
+			fmt.Println(color.GreenString("Snapshot %s of playground %s saved", args[0], tag))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&tag, "tag", "T", "", "Tag of the playground to save")
+
+	return cmd
+}
+
+func newSnapshotLoad() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "load <name>",
+		Short: "Restore the data of a stopped playground from a snapshot",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			if err := checkSnapshotTarget(); err != nil {
+				return err
+			}
+
+			snapDir, err := snapshotPath(args[0])
+			if err != nil {
+				return err
+			}
+			if !utils.IsExist(snapDir) {
+				return errors.Errorf("snapshot %s not found", args[0])
+			}
+
+			// clean the current data but keep the files describing the playground itself
+			entries, err := os.ReadDir(dataDir)
+			if err != nil {
+				return errors.AddStack(err)
+			}
+			for _, entry := range entries {
+				if _, ok := snapshotExcludes[entry.Name()]; ok {
+					continue
+				}
+				if err := os.RemoveAll(filepath.Join(dataDir, entry.Name())); err != nil {
+					return errors.AddStack(err)
+				}
+			}
+
+			if err := copySnapshot(snapDir, dataDir); err != nil {
+				return err
+			}
+			fmt.Println(color.GreenString("Playground %s restored from snapshot %s", tag, args[0]))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&tag, "tag", "T", "", "Tag of the playground to restore")
+
+	return cmd
+}
+
+func newSnapshotList() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all saved snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := snapshotPath("")
+			if err != nil {
+				return err
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return errors.AddStack(err)
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					fmt.Println(entry.Name())
+				}
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// checkSnapshotTarget makes sure the data dir belongs to a named playground
+// which is not running at the moment.
+func checkSnapshotTarget() error {
+	if tag == "" || deleteWhenExit {
+		return errors.New("the tag of playground must be specified by --tag")
+	}
+
+	port, err := loadPort(dataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("playground %s not found", tag)
+		}
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	if err == nil {
+		conn.Close()
+		return errors.Errorf("playground %s is still running, stop it first", tag)
+	}
+	return nil
+}
+
+// snapshotPath returns the directory to store the snapshot with the name
+func snapshotPath(name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", errors.Errorf("invalid snapshot name: %s", name)
+	}
+
+	base := os.Getenv(localdata.EnvNameComponentDataDir)
+	if base == "" {
+		tiupHome, err := tiupHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(tiupHome, localdata.StorageParentDir, "playground")
+	}
+	return filepath.Join(base, "snapshots", name), nil
+}
+
+// copySnapshot copies the data from src to dst. SST files are never modified
+// after being written, so they are hard linked when possible to make the
+// snapshot cheap, other files are always copied as they may be appended later.
+func copySnapshot(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		if _, ok := snapshotExcludes[rel]; ok {
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return errors.AddStack(err)
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			// sockets, pipes and so on are meaningless in a snapshot
+			return nil
+		}
+
+		if strings.HasSuffix(info.Name(), ".sst") {
+			if err := os.Link(path, target); err == nil {
+				return nil
+			}
+		}
+		return utils.Copy(path, target)
+	})
+}
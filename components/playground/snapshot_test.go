@@ -0,0 +1,45 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotNoDataDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(localdata.EnvNameHome, home)
+	t.Setenv(localdata.EnvNameInstanceDataDir, "")
+	t.Setenv(localdata.EnvNameComponentDataDir, "")
+	defer func() { tag, dataDir, deleteWhenExit = "", "", false }()
+
+	cmd := newSnapshot()
+	cmd.SetArgs([]string{"list"})
+	require.NoError(t, cmd.Execute())
+	assert.Empty(t, dataDir)
+	assert.NoDirExists(t, filepath.Join(home, localdata.DataParentDir))
+
+	// the data dir of the playground is resolved by the tag, but not created
+	cmd = newSnapshot()
+	cmd.SetArgs([]string{"save", "base", "--tag", "fixture"})
+	assert.ErrorContains(t, cmd.Execute(), "playground fixture not found")
+	assert.Equal(t, filepath.Join(home, localdata.DataParentDir, "fixture"), dataDir)
+	assert.False(t, deleteWhenExit)
+	assert.NoDirExists(t, filepath.Join(home, localdata.DataParentDir))
+}
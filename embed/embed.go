@@ -2,6 +2,10 @@ package embed
 
 import (
 	goembed "embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 //go:embed templates
@@ -12,6 +16,38 @@ func ReadTemplate(path string) ([]byte, error) {
 	return embededFiles.ReadFile(path)
 }
 
+// ReadTemplateFrom read the template file in dir, the directory containing
+// the modified templates of a cluster, and falls back to the embedded one if
+// it's not modified. The path of a template in dir is relative to the
+// templates dir, e.g. {dir}/scripts/run_cdc.sh.tpl overrides
+// templates/scripts/run_cdc.sh.tpl. An empty dir reads the embedded one.
+func ReadTemplateFrom(dir, path string) ([]byte, error) {
+	if dir != "" && strings.HasPrefix(path, "templates/") {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(path, "templates/"))))
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return ReadTemplate(path)
+}
+
+// ListTemplates returns the paths of all embedded templates relative to the
+// templates dir, e.g. scripts/run_cdc.sh.tpl
+func ListTemplates() ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(embededFiles, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		paths = append(paths, strings.TrimPrefix(path, "templates/"))
+		return nil
+	})
+	return paths, err
+}
+
 //go:embed examples
 var embedExamples goembed.FS
 
@@ -19,3 +55,26 @@ var embedExamples goembed.FS
 func ReadExample(path string) ([]byte, error) {
 	return embedExamples.ReadFile(path)
 }
+
+//go:embed upgrade_hooks
+var embedUpgradeHooks goembed.FS
+
+// ListUpgradeHooks returns the paths of all embedded upgrade hooks relative to
+// the upgrade_hooks dir, e.g. v6.1.0/001-adjust-variables.sql
+func ListUpgradeHooks() ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(embedUpgradeHooks, "upgrade_hooks", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".sql") {
+			return err
+		}
+		paths = append(paths, strings.TrimPrefix(path, "upgrade_hooks/"))
+		return nil
+	})
+	return paths, err
+}
+
+// ReadUpgradeHook read an upgrade hook, the path is relative to the
+// upgrade_hooks dir
+func ReadUpgradeHook(path string) ([]byte, error) {
+	return embedUpgradeHooks.ReadFile("upgrade_hooks/" + path)
+}
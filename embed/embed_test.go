@@ -72,3 +72,41 @@ func (s *embedSuite) TestCanReadExamples(c *check.C) {
 		c.Assert(embedData, check.BytesEquals, data)
 	}
 }
+
+func (s *embedSuite) TestTemplateOverride(c *check.C) {
+	dir := c.MkDir()
+
+	fp := "templates/scripts/run_cdc.sh.tpl"
+	embedData, err := ReadTemplate(fp)
+	c.Assert(err, check.IsNil)
+
+	// the embedded template is used if it's not overridden
+	data, err := ReadTemplateFrom(dir, fp)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.BytesEquals, embedData)
+
+	c.Assert(os.MkdirAll(filepath.Join(dir, "scripts"), 0755), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "scripts", "run_cdc.sh.tpl"), []byte("custom"), 0644), check.IsNil)
+	data, err = ReadTemplateFrom(dir, fp)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "custom")
+
+	// the override of one cluster doesn't affect the others
+	data, err = ReadTemplateFrom("", fp)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.BytesEquals, embedData)
+
+	data, err = ReadTemplate(fp)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.BytesEquals, embedData)
+
+	paths, err := ListTemplates()
+	c.Assert(err, check.IsNil)
+	found := false
+	for _, p := range paths {
+		if p == "scripts/run_cdc.sh.tpl" {
+			found = true
+		}
+	}
+	c.Assert(found, check.IsTrue)
+}
@@ -25,6 +25,9 @@ LimitSTACK=10485760
 AmbientCapabilities=CAP_NET_RAW
 {{- end}}
 User={{.User}}
+{{- if .TimeZone}}
+Environment="TZ={{.TimeZone}}"
+{{- end}}
 ExecStart=/bin/bash -c '{{.DeployDir}}/scripts/run_{{.ServiceName}}.sh'
 {{- if eq .ServiceName "prometheus"}}
 ExecReload=/bin/bash -c 'kill -HUP $MAINPID $(pidof {{.DeployDir}}/bin/ng-monitoring-server)'
@@ -0,0 +1,4 @@
+-- The dynamic privileges are introduced in v5.1.0, reload the privileges on
+-- all the TiDB instances, so the grants migrated by the bootstrap of the new
+-- version take effect on the instances which are not the owner.
+FLUSH PRIVILEGES;
@@ -148,6 +148,11 @@ func NewCheckPoint(r io.Reader) (*CheckPoint, error) {
 	return &cp, nil
 }
 
+// Points returns the points recorded in the audit file in order
+func (c *CheckPoint) Points() []map[string]interface{} {
+	return c.points
+}
+
 // Acquire get point from checkpoints
 func (c *CheckPoint) acquire(ctx context.Context, fs FieldSet, fn string, point map[string]interface{}) *Point {
 	acquired := ctx.Value(semKey).(*semaphore.Weighted).TryAcquire(1)
@@ -58,12 +58,7 @@ func NewBinlogClient(pdEndpoints []string, timeout time.Duration, tlsConfig *tls
 }
 
 func (c *BinlogClient) getURL(addr string) string {
-	scheme := "http"
-	if c.tls != nil {
-		scheme = "https"
-	}
-
-	return fmt.Sprintf("%s://%s", scheme, addr)
+	return URL(c.tls != nil, addr)
 }
 
 func (c *BinlogClient) getOfflineURL(addr string, nodeID string) string {
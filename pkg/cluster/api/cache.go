@@ -0,0 +1,130 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultCacheTTL is the default time the responses of status queries are cached
+const DefaultCacheTTL = 2 * time.Second
+
+type cacheEntry struct {
+	data   []byte
+	expire time.Time
+}
+
+// responseCache caches the successful responses of the read-only APIs for a
+// short time, and merges the identical requests in flight into one, so the
+// status queries issued for every instance in one operation do not hammer
+// the server.
+type responseCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	group   singleflight.Group
+}
+
+type responseCacheKey struct{}
+
+// WithCacheContext returns a context with which the PD and TiCDC clients
+// cache the responses of the status queries for ttl. The cache is scoped to
+// the operation run with the context, and it's dropped by the changes made
+// through the clients with it, or by InvalidateCache.
+func WithCacheContext(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, responseCacheKey{}, newResponseCache(ttl))
+}
+
+// InvalidateCache drops the responses cached in ctx, it must be called after
+// the state of the cluster is changed not through the API clients, e.g. an
+// instance is restarted.
+func InvalidateCache(ctx context.Context) {
+	cacheFromContext(ctx).invalidate()
+}
+
+// cacheFromContext returns the cache in ctx, nil if the responses are not cached
+func cacheFromContext(ctx context.Context) *responseCache {
+	c, _ := ctx.Value(responseCacheKey{}).(*responseCache)
+	return c
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// cacheKey identifies a request by the endpoints it is sent to, the order of
+// them doesn't matter as any of them returns the same response
+func cacheKey(endpoints []string) string {
+	sorted := append([]string(nil), endpoints...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// get returns the cached response of the key if it is not expired, otherwise
+// calls f to refresh it. The cache is bypassed if it's nil or the ttl is not
+// positive.
+func (c *responseCache) get(key string, f func() ([]byte, error)) ([]byte, error) {
+	if c == nil || c.ttl <= 0 {
+		return f()
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expire) {
+		return e.data, nil
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		data, err := f()
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		c.mu.Lock()
+		for k, e := range c.entries {
+			if now.After(e.expire) {
+				delete(c.entries, k)
+			}
+		}
+		c.entries[key] = cacheEntry{data: data, expire: now.Add(c.ttl)}
+		c.mu.Unlock()
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// invalidate drops all cached responses, it must be called after the state
+// of the cluster is changed.
+func (c *responseCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.mu.Unlock()
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	c := newResponseCache(time.Minute)
+	var called int32
+	f := func() ([]byte, error) {
+		atomic.AddInt32(&called, 1)
+		time.Sleep(10 * time.Millisecond)
+		return []byte("stores"), nil
+	}
+
+	// identical requests in flight are merged
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := c.get("pd", f)
+			assert.Nil(t, err)
+			assert.Equal(t, "stores", string(data))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&called))
+
+	// cached
+	_, err := c.get("pd", f)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&called))
+
+	// invalidated
+	c.invalidate()
+	_, err = c.get("pd", f)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&called))
+
+	// the cache is bypassed
+	var bypassed *responseCache
+	_, err = bypassed.get("pd", f)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&called))
+
+	// errors are not cached
+	_, err = c.get("cdc", func() ([]byte, error) {
+		return nil, errors.New("down")
+	})
+	assert.NotNil(t, err)
+	data, err := c.get("cdc", func() ([]byte, error) {
+		return []byte("captures"), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "captures", string(data))
+}
+
+func TestCacheContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, cacheFromContext(ctx))
+	InvalidateCache(ctx)
+
+	// the cache is scoped to the context
+	ctx1 := WithCacheContext(ctx, DefaultCacheTTL)
+	ctx2 := WithCacheContext(ctx, DefaultCacheTTL)
+	assert.NotNil(t, cacheFromContext(ctx1))
+	assert.NotSame(t, cacheFromContext(ctx1), cacheFromContext(ctx2))
+	_, err := cacheFromContext(ctx1).get(cacheKey([]string{"pd1", "pd2"}), func() ([]byte, error) {
+		return []byte("stores"), nil
+	})
+	assert.Nil(t, err)
+	// the order of the endpoints doesn't matter
+	data, err := cacheFromContext(ctx1).get(cacheKey([]string{"pd2", "pd1"}), func() ([]byte, error) {
+		return nil, errors.New("not cached")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "stores", string(data))
+	_, err = cacheFromContext(ctx2).get(cacheKey([]string{"pd1", "pd2"}), func() ([]byte, error) {
+		return nil, errors.New("not cached")
+	})
+	assert.NotNil(t, err)
+
+	InvalidateCache(ctx1)
+	_, err = cacheFromContext(ctx1).get(cacheKey([]string{"pd1", "pd2"}), func() ([]byte, error) {
+		return nil, errors.New("not cached")
+	})
+	assert.NotNil(t, err)
+}
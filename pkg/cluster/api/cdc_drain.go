@@ -0,0 +1,117 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// drainStallTimeout is how long a drain makes no progress before it's
+// regarded as stalled
+var drainStallTimeout = 30 * time.Second
+
+// DrainProgress is the progress of draining a capture
+type DrainProgress struct {
+	CaptureID         string
+	InitialTableCount int           // the tables on the capture when the drain started
+	CurrentTableCount int           // the tables still on the capture
+	Elapsed           time.Duration // since the drain started
+	Rate              float64       // tables transferred per second
+	ETA               time.Duration // 0 if it can't be estimated yet
+	Stalled           bool          // no table is transferred in drainStallTimeout
+}
+
+// Transferred returns the count of the tables transferred
+func (p *DrainProgress) Transferred() int {
+	return p.InitialTableCount - p.CurrentTableCount
+}
+
+// String implements the fmt.Stringer interface
+func (p *DrainProgress) String() string {
+	msg := fmt.Sprintf("Still waiting for %d tables to transfer, %d/%d transferred in %s",
+		p.CurrentTableCount, p.Transferred(), p.InitialTableCount, p.Elapsed.Round(time.Second))
+	switch {
+	case p.Stalled:
+		msg += fmt.Sprintf(", no table transferred in the last %s, the drain may be stuck", drainStallTimeout)
+	case p.ETA > 0:
+		msg += fmt.Sprintf(", %.2f tables/s, ETA %s", p.Rate, p.ETA.Round(time.Second))
+	}
+	return msg
+}
+
+// MarshalJSON implements the json.Marshaler interface, the durations are in seconds
+func (p *DrainProgress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		CaptureID         string  `json:"capture_id"`
+		InitialTableCount int     `json:"initial_table_count"`
+		CurrentTableCount int     `json:"current_table_count"`
+		ElapsedSeconds    float64 `json:"elapsed_seconds"`
+		Rate              float64 `json:"tables_per_second"`
+		ETASeconds        float64 `json:"eta_seconds,omitempty"`
+		Stalled           bool    `json:"stalled"`
+	}{
+		CaptureID:         p.CaptureID,
+		InitialTableCount: p.InitialTableCount,
+		CurrentTableCount: p.CurrentTableCount,
+		ElapsedSeconds:    p.Elapsed.Seconds(),
+		Rate:              p.Rate,
+		ETASeconds:        p.ETA.Seconds(),
+		Stalled:           p.Stalled,
+	})
+}
+
+// drainEstimator estimates the progress of a drain from the table counts
+// polled from the owner
+type drainEstimator struct {
+	captureID    string
+	started      bool
+	start        time.Time
+	lastProgress time.Time
+	initial      int
+	last         int
+}
+
+// update records the table count polled at now and returns the progress
+func (e *drainEstimator) update(count int, now time.Time) *DrainProgress {
+	if !e.started {
+		e.started = true
+		e.start, e.lastProgress = now, now
+		e.initial, e.last = count, count
+	}
+	if count < e.last {
+		e.lastProgress = now
+	}
+	e.last = count
+	// the tables created during the drain are scheduled to the capture too
+	if count > e.initial {
+		e.initial = count
+	}
+
+	p := &DrainProgress{
+		CaptureID:         e.captureID,
+		InitialTableCount: e.initial,
+		CurrentTableCount: count,
+		Elapsed:           now.Sub(e.start),
+		Stalled:           now.Sub(e.lastProgress) >= drainStallTimeout,
+	}
+	if p.Elapsed > 0 {
+		p.Rate = float64(p.Transferred()) / p.Elapsed.Seconds()
+	}
+	if p.Rate > 0 && !p.Stalled {
+		p.ETA = time.Duration(float64(count) / p.Rate * float64(time.Second))
+	}
+	return p
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainEstimator(t *testing.T) {
+	now := time.Unix(1000, 0)
+	e := &drainEstimator{captureID: "capture-1"}
+
+	p := e.update(40, now)
+	assert.Equal(t, 40, p.InitialTableCount)
+	assert.Equal(t, 0, p.Transferred())
+	assert.Zero(t, p.ETA)
+	assert.False(t, p.Stalled)
+
+	p = e.update(30, now.Add(5*time.Second))
+	assert.Equal(t, 10, p.Transferred())
+	assert.Equal(t, 2.0, p.Rate)
+	assert.Equal(t, 15*time.Second, p.ETA)
+	assert.Equal(t, "Still waiting for 30 tables to transfer, 10/40 transferred in 5s, 2.00 tables/s, ETA 15s", p.String())
+
+	// no progress within the stall timeout
+	p = e.update(30, now.Add(5*time.Second+drainStallTimeout))
+	assert.True(t, p.Stalled)
+	assert.Zero(t, p.ETA)
+	assert.Contains(t, p.String(), "the drain may be stuck")
+
+	// tables created during the drain
+	p = e.update(45, now.Add(40*time.Second))
+	assert.Equal(t, 45, p.InitialTableCount)
+	assert.Zero(t, p.Rate)
+
+	p = e.update(0, now.Add(50*time.Second))
+	assert.False(t, p.Stalled)
+	data, err := json.Marshal(p)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"capture_id":"capture-1","initial_table_count":45,"current_table_count":0,
+		"elapsed_seconds":50,"tables_per_second":0.9,"stalled":false}`, string(data))
+}
@@ -29,29 +29,35 @@ import (
 
 // CDCOpenAPIClient is client for access TiCDC Open API
 type CDCOpenAPIClient struct {
-	urls   []string
-	client *utils.HTTPClient
-	ctx    context.Context
+	urls    []string
+	client  *utils.HTTPClient
+	timeout time.Duration
+
+	drainObserver func(*DrainProgress)
 }
 
-// NewCDCOpenAPIClient return a `CDCOpenAPIClient`
-func NewCDCOpenAPIClient(ctx context.Context, addresses []string, timeout time.Duration, tlsConfig *tls.Config) *CDCOpenAPIClient {
-	httpPrefix := "http"
-	if tlsConfig != nil {
-		httpPrefix = "https"
-	}
+// NewCDCOpenAPIClient return a `CDCOpenAPIClient`, the context is passed to
+// each call of the client
+func NewCDCOpenAPIClient(addresses []string, timeout time.Duration, tlsConfig *tls.Config) *CDCOpenAPIClient {
 	urls := make([]string, 0, len(addresses))
 	for _, addr := range addresses {
-		urls = append(urls, fmt.Sprintf("%s://%s", httpPrefix, addr))
+		urls = append(urls, URL(tlsConfig != nil, addr))
 	}
 
 	return &CDCOpenAPIClient{
-		urls:   urls,
-		client: utils.NewHTTPClient(timeout, tlsConfig),
-		ctx:    ctx,
+		urls:    urls,
+		client:  ApplyExtraHeaders(utils.NewHTTPClient(timeout, verifiedTLSConfig(tlsConfig))),
+		timeout: timeout,
 	}
 }
 
+// WithDrainObserver sets the func called with the progress of each poll
+// while draining a capture
+func (c *CDCOpenAPIClient) WithDrainObserver(observer func(*DrainProgress)) *CDCOpenAPIClient {
+	c.drainObserver = observer
+	return c
+}
+
 func (c *CDCOpenAPIClient) getEndpoints(api string) (endpoints []string) {
 	for _, url := range c.urls {
 		endpoints = append(endpoints, fmt.Sprintf("%s/%s", url, api))
@@ -59,7 +65,16 @@ func (c *CDCOpenAPIClient) getEndpoints(api string) (endpoints []string) {
 	return endpoints
 }
 
-func drainCapture(client *CDCOpenAPIClient, target string) (int, error) {
+// RetryTimeout returns the timeout to retry a query, it's long enough for
+// a few requests to the slow servers
+func (c *CDCOpenAPIClient) RetryTimeout() time.Duration {
+	if timeout := 2 * c.timeout; timeout > 10*time.Second {
+		return timeout
+	}
+	return 10 * time.Second
+}
+
+func drainCapture(ctx context.Context, client *CDCOpenAPIClient, target string) (int, error) {
 	api := "api/v1/captures/drain"
 	endpoints := client.getEndpoints(api)
 
@@ -73,30 +88,30 @@ func drainCapture(client *CDCOpenAPIClient, target string) (int, error) {
 
 	var resp DrainCaptureResp
 	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		data, statusCode, err := client.client.Put(client.ctx, endpoint, bytes.NewReader(body))
+		data, statusCode, err := client.client.Put(ctx, endpoint, bytes.NewReader(body))
 		if err != nil {
 			switch statusCode {
 			case http.StatusNotFound:
 				// old version cdc does not support `DrainCapture`, return nil to trigger hard restart.
-				client.l().Debugf("cdc drain capture does not support, ignore it, target: %s, err: %+v", target, err)
+				client.l(ctx).Debugf("cdc drain capture does not support, ignore it, target: %s, err: %+v", target, err)
 				return data, nil
 			case http.StatusServiceUnavailable:
 				// cdc is not ready to accept request, return error to trigger retry.
-				client.l().Debugf("cdc drain capture meet service unavailable, retry it, target: %s, err: %+v", target, err)
+				client.l(ctx).Debugf("cdc drain capture meet service unavailable, retry it, target: %s, err: %+v", target, err)
 				return data, err
 			default:
 			}
 			// match https://github.com/pingcap/tiflow/blob/e3d0d9d23b77c7884b70016ddbd8030ffeb95dfd/pkg/errors/cdc_errors.go#L55-L57
 			if bytes.Contains(data, []byte("CDC:ErrSchedulerRequestFailed")) {
-				client.l().Debugf("cdc drain capture failed, data: %s, err: %+v", data, err)
+				client.l(ctx).Debugf("cdc drain capture failed, data: %s, err: %+v", data, err)
 				return data, nil
 			}
 			// match https://github.com/pingcap/tiflow/blob/e3d0d9d23b77c7884b70016ddbd8030ffeb95dfd/pkg/errors/cdc_errors.go#L51-L54
 			if bytes.Contains(data, []byte("CDC:ErrCaptureNotExist")) {
-				client.l().Debugf("cdc drain capture failed, data: %s, err: %+v", data, err)
+				client.l(ctx).Debugf("cdc drain capture failed, data: %s, err: %+v", data, err)
 				return data, nil
 			}
-			client.l().Debugf("cdc drain capture failed, data: %s, statusCode: %d, err: %+v", data, statusCode, err)
+			client.l(ctx).Debugf("cdc drain capture failed, data: %s, statusCode: %d, err: %+v", data, statusCode, err)
 			return data, err
 		}
 		return data, json.Unmarshal(data, &resp)
@@ -105,36 +120,46 @@ func drainCapture(client *CDCOpenAPIClient, target string) (int, error) {
 }
 
 // DrainCapture request cdc owner move all tables on the target capture to other captures.
-func (c *CDCOpenAPIClient) DrainCapture(target string, apiTimeoutSeconds int) error {
+func (c *CDCOpenAPIClient) DrainCapture(ctx context.Context, target string, apiTimeoutSeconds int) error {
 	start := time.Now()
-	err := utils.Retry(func() error {
-		count, err := drainCapture(c, target)
+	estimator := &drainEstimator{captureID: target}
+	err := utils.Wait(ctx, func() error {
+		count, err := drainCapture(ctx, c, target)
 		if err != nil {
 			return err
 		}
+		progress := estimator.update(count, time.Now())
+		// the progress is displayed by the observer if it's set
+		if c.drainObserver != nil {
+			c.drainObserver(progress)
+		} else if count != 0 {
+			c.l(ctx).Infof("\t %s", progress)
+		}
 		if count == 0 {
 			return nil
 		}
-		c.l().Infof("\t Still waiting for %d tables to transfer...", count)
 		return fmt.Errorf("drain capture not finished yet, target: %s, count: %d", target, count)
-	}, utils.RetryOption{
-		Delay:   1 * time.Second,
-		Timeout: time.Duration(apiTimeoutSeconds) * time.Second,
+	}, utils.WaitOption{
+		Timeout:     time.Duration(apiTimeoutSeconds) * time.Second,
+		Interval:    time.Second,
+		MaxInterval: time.Second * 5,
 	})
 
-	c.l().Debugf("cdc drain capture finished, target: %s, elapsed: %+v", target, time.Since(start))
+	cacheFromContext(ctx).invalidate()
+
+	c.l(ctx).Debugf("cdc drain capture finished, target: %s, elapsed: %+v", target, time.Since(start))
 	return err
 }
 
 // ResignOwner resign the cdc owner, and wait for a new owner be found
-func (c *CDCOpenAPIClient) ResignOwner() error {
+func (c *CDCOpenAPIClient) ResignOwner(ctx context.Context) error {
 	api := "api/v1/owner/resign"
 	endpoints := c.getEndpoints(api)
 	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, statusCode, err := c.client.PostWithStatusCode(c.ctx, endpoint, nil)
+		body, statusCode, err := c.client.PostWithStatusCode(ctx, endpoint, nil)
 		if err != nil {
 			if statusCode == http.StatusNotFound {
-				c.l().Debugf("resign owner does not found, ignore it, err: %+v", err)
+				c.l(ctx).Debugf("resign owner does not found, ignore it, err: %+v", err)
 				return body, nil
 			}
 			return body, err
@@ -145,19 +170,20 @@ func (c *CDCOpenAPIClient) ResignOwner() error {
 	if err != nil {
 		return err
 	}
+	cacheFromContext(ctx).invalidate()
 
-	owner, err := c.GetOwner()
+	owner, err := c.GetOwner(ctx)
 	if err != nil {
 		return err
 	}
 
-	c.l().Debugf("cdc resign owner successfully, and new owner found, owner: %+v", owner)
+	c.l(ctx).Debugf("cdc resign owner successfully, and new owner found, owner: %+v", owner)
 	return nil
 }
 
 // GetOwner return the cdc owner capture information
-func (c *CDCOpenAPIClient) GetOwner() (*Capture, error) {
-	captures, err := c.GetAllCaptures()
+func (c *CDCOpenAPIClient) GetOwner(ctx context.Context) (*Capture, error) {
+	captures, err := c.GetAllCaptures(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -171,8 +197,8 @@ func (c *CDCOpenAPIClient) GetOwner() (*Capture, error) {
 }
 
 // GetCaptureByAddr return the capture information by the address
-func (c *CDCOpenAPIClient) GetCaptureByAddr(addr string) (*Capture, error) {
-	captures, err := c.GetAllCaptures()
+func (c *CDCOpenAPIClient) GetCaptureByAddr(ctx context.Context, addr string) (*Capture, error) {
+	captures, err := c.GetAllCaptures(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -187,46 +213,50 @@ func (c *CDCOpenAPIClient) GetCaptureByAddr(addr string) (*Capture, error) {
 }
 
 // GetAllCaptures return all captures instantaneously
-func (c *CDCOpenAPIClient) GetAllCaptures() (result []*Capture, err error) {
-	err = utils.Retry(func() error {
-		result, err = getAllCaptures(c)
+func (c *CDCOpenAPIClient) GetAllCaptures(ctx context.Context) (result []*Capture, err error) {
+	err = utils.Wait(ctx, func() error {
+		result, err = getAllCaptures(ctx, c)
 		if err != nil {
 			return err
 		}
 		return nil
-	}, utils.RetryOption{
-		Timeout: 10 * time.Second,
+	}, utils.WaitOption{
+		Timeout: c.RetryTimeout(),
 	})
 	return result, err
 }
 
-func getAllCaptures(client *CDCOpenAPIClient) ([]*Capture, error) {
+func getAllCaptures(ctx context.Context, client *CDCOpenAPIClient) ([]*Capture, error) {
 	api := "api/v1/captures"
 	endpoints := client.getEndpoints(api)
 
-	var response []*Capture
-
-	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, statusCode, err := client.client.GetWithStatusCode(client.ctx, endpoint)
-		if err != nil {
-			if statusCode == http.StatusNotFound {
-				// old version cdc does not support open api, also the stopped cdc instance
-				// return nil to trigger hard restart
-				client.l().Debugf("get all captures not support, ignore it, err: %+v", err)
-				return body, nil
+	data, err := cacheFromContext(ctx).get(cacheKey(endpoints), func() ([]byte, error) {
+		return tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+			body, statusCode, err := client.client.GetWithStatusCode(ctx, endpoint)
+			if err != nil {
+				if statusCode == http.StatusNotFound {
+					// old version cdc does not support open api, also the stopped cdc instance
+					// return nil to trigger hard restart
+					client.l(ctx).Debugf("get all captures not support, ignore it, err: %+v", err)
+					return nil, nil
+				}
+				return body, err
 			}
-			return body, err
-		}
 
-		return body, json.Unmarshal(body, &response)
+			return body, json.Unmarshal(body, &[]*Capture{})
+		})
 	})
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
 
-	return response, err
+	var response []*Capture
+	return response, json.Unmarshal(data, &response)
 }
 
 // IsCaptureAlive return error if the capture is not alive
-func (c *CDCOpenAPIClient) IsCaptureAlive() error {
-	status, err := c.GetStatus()
+func (c *CDCOpenAPIClient) IsCaptureAlive(ctx context.Context) error {
+	status, err := c.GetStatus(ctx)
 	if err != nil {
 		return err
 	}
@@ -237,16 +267,18 @@ func (c *CDCOpenAPIClient) IsCaptureAlive() error {
 }
 
 // GetStatus return the status of the TiCDC server.
-func (c *CDCOpenAPIClient) GetStatus() (result ServerStatus, err error) {
+func (c *CDCOpenAPIClient) GetStatus(ctx context.Context) (result ServerStatus, err error) {
 	api := "api/v1/status"
 	// client should only have address to the target cdc server, not all cdc servers.
 	endpoints := c.getEndpoints(api)
 
-	err = utils.Retry(func() error {
-		data, statusCode, err := c.client.GetWithStatusCode(c.ctx, endpoints[0])
+	err = utils.Wait(ctx, func() error {
+		start := time.Now()
+		data, statusCode, err := c.client.GetWithStatusCode(ctx, endpoints[0])
+		apiMetrics.record(endpoints[0], time.Since(start), err)
 		if err != nil {
 			if statusCode == http.StatusNotFound {
-				c.l().Debugf("capture server status api not support, ignore it, err: %+v", err)
+				c.l(ctx).Debugf("capture server status api not support, ignore it, err: %+v", err)
 				return nil
 			}
 			err = json.Unmarshal(data, &result)
@@ -259,15 +291,62 @@ func (c *CDCOpenAPIClient) GetStatus() (result ServerStatus, err error) {
 			return errors.New("capture status is not alive, retry it")
 		}
 		return nil
-	}, utils.RetryOption{
-		Timeout: 10 * time.Second,
+	}, utils.WaitOption{
+		Timeout: c.RetryTimeout(),
 	})
 
 	return result, err
 }
 
-func (c *CDCOpenAPIClient) l() *logprinter.Logger {
-	return c.ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+// CreateChangefeed creates a changefeed by the owner of the cdc cluster
+func (c *CDCOpenAPIClient) CreateChangefeed(ctx context.Context, cfg *ChangefeedConfig) error {
+	api := "api/v1/changefeeds"
+	endpoints := c.getEndpoints(api)
+
+	body, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		data, statusCode, err := c.client.PostWithStatusCode(ctx, endpoint, bytes.NewReader(body))
+		if err != nil {
+			if statusCode == http.StatusNotFound {
+				// the open api is not supported, no need to try other captures
+				return data, errors.Errorf("the cdc server does not support creating changefeed by open api, err: %s", err)
+			}
+			c.l(ctx).Debugf("create changefeed failed, data: %s, statusCode: %d, err: %+v", data, statusCode, err)
+			return data, err
+		}
+		return data, nil
+	})
+	return err
+}
+
+// GetAllChangefeeds return the common information of all changefeeds
+func (c *CDCOpenAPIClient) GetAllChangefeeds(ctx context.Context) ([]*ChangefeedCommonInfo, error) {
+	api := "api/v1/changefeeds"
+	endpoints := c.getEndpoints(api)
+
+	body, err := tryURLsHedged(ctx, endpoints, hedgeFromContext(ctx), func(ctx context.Context, endpoint string) ([]byte, error) {
+		return c.client.Get(ctx, endpoint)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response []*ChangefeedCommonInfo
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return response, nil
+}
+
+func (c *CDCOpenAPIClient) l(ctx context.Context) *logprinter.Logger {
+	if logger, ok := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger); ok {
+		return logger
+	}
+	return logprinter.NewLogger("")
 }
 
 // Liveness is the liveness status of a capture.
@@ -306,3 +385,31 @@ type DrainCaptureRequest struct {
 type DrainCaptureResp struct {
 	CurrentTableCount int `json:"current_table_count"`
 }
+
+// ChangefeedConfig is request for creating a changefeed
+type ChangefeedConfig struct {
+	ID                    string   `json:"changefeed_id"`
+	StartTS               uint64   `json:"start_ts,omitempty"`
+	TargetTS              uint64   `json:"target_ts,omitempty"`
+	SinkURI               string   `json:"sink_uri"`
+	ForceReplicate        bool     `json:"force_replicate,omitempty"`
+	IgnoreIneligibleTable bool     `json:"ignore_ineligible_table,omitempty"`
+	FilterRules           []string `json:"filter_rules,omitempty"`
+}
+
+// RunningError is the error a changefeed meets
+type RunningError struct {
+	Addr    string `json:"addr"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ChangefeedCommonInfo holds the common information of a changefeed
+type ChangefeedCommonInfo struct {
+	Namespace      string        `json:"namespace,omitempty"`
+	ID             string        `json:"id"`
+	FeedState      string        `json:"state"`
+	CheckpointTSO  uint64        `json:"checkpoint_tso"`
+	CheckpointTime string        `json:"checkpoint_time"`
+	RunningError   *RunningError `json:"error"`
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/api/vcr"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCDCChangefeedsReplay(t *testing.T) {
+	r := vcr.Use(t, "testdata/cdc_changefeeds.yaml")
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, logprinter.NewLogger(""))
+
+	client := NewCDCOpenAPIClient([]string{"172.16.5.1:8300"}, 10*time.Second, nil)
+	assert.Nil(t, client.IsCaptureAlive(ctx))
+
+	err := client.CreateChangefeed(ctx, &ChangefeedConfig{
+		ID:          "feed-1",
+		SinkURI:     "blackhole://",
+		FilterRules: []string{"test.*"},
+	})
+	assert.Nil(t, err)
+
+	feeds, err := client.GetAllChangefeeds(ctx)
+	assert.Nil(t, err)
+	if r.Mode() == vcr.ModeRecord {
+		return
+	}
+	assert.Len(t, feeds, 1)
+	assert.Equal(t, "feed-1", feeds[0].ID)
+	assert.Equal(t, "normal", feeds[0].FeedState)
+	assert.Nil(t, feeds[0].RunningError)
+	assert.Empty(t, r.Unused())
+}
@@ -54,17 +54,13 @@ func NewDMMasterClient(addrs []string, timeout time.Duration, tlsConfig *tls.Con
 	return &DMMasterClient{
 		addrs:      addrs,
 		tlsEnabled: enableTLS,
-		httpClient: utils.NewHTTPClient(timeout, tlsConfig),
+		httpClient: ApplyExtraHeaders(utils.NewHTTPClient(timeout, tlsConfig)),
 	}
 }
 
 // GetURL builds the the client URL of DMClient
 func (dm *DMMasterClient) GetURL(addr string) string {
-	httpPrefix := "http"
-	if dm.tlsEnabled {
-		httpPrefix = "https"
-	}
-	return fmt.Sprintf("%s://%s", httpPrefix, addr)
+	return URL(dm.tlsEnabled, addr)
 }
 
 func (dm *DMMasterClient) getEndpoints(cmd string) (endpoints []string) {
@@ -76,33 +72,35 @@ func (dm *DMMasterClient) getEndpoints(cmd string) (endpoints []string) {
 	return
 }
 
-func (dm *DMMasterClient) getMember(endpoints []string) (*dmpb.ListMemberResponse, error) {
+func (dm *DMMasterClient) getMember(ctx context.Context, endpoints []string) (*dmpb.ListMemberResponse, error) {
 	resp := &dmpb.ListMemberResponse{}
-	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := dm.httpClient.Get(context.TODO(), endpoint)
+	// the response is parsed by each request as they may be hedged
+	body, err := tryURLsHedged(ctx, endpoints, hedgeFromContext(ctx), func(ctx context.Context, endpoint string) ([]byte, error) {
+		body, err := dm.httpClient.Get(ctx, endpoint)
 		if err != nil {
 			return body, err
 		}
 
-		err = jsonpb.Unmarshal(strings.NewReader(string(body)), resp)
-
-		if err != nil {
+		resp := &dmpb.ListMemberResponse{}
+		if err := jsonpb.Unmarshal(strings.NewReader(string(body)), resp); err != nil {
 			return body, err
 		}
-
 		if !resp.Result {
 			return body, errors.New("dm-master get members failed: " + resp.Msg)
 		}
 
 		return body, nil
 	})
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	return resp, jsonpb.Unmarshal(strings.NewReader(string(body)), resp)
 }
 
-func (dm *DMMasterClient) deleteMember(endpoints []string) (*dmpb.OfflineMemberResponse, error) {
+func (dm *DMMasterClient) deleteMember(ctx context.Context, endpoints []string) (*dmpb.OfflineMemberResponse, error) {
 	resp := &dmpb.OfflineMemberResponse{}
 	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, statusCode, err := dm.httpClient.Delete(context.TODO(), endpoint, nil)
+		body, statusCode, err := dm.httpClient.Delete(ctx, endpoint, nil)
 
 		if statusCode == 404 || bytes.Contains(body, []byte("not exists")) {
 			zap.L().Debug("member to offline does not exist, ignore.")
@@ -129,10 +127,10 @@ func (dm *DMMasterClient) deleteMember(endpoints []string) (*dmpb.OfflineMemberR
 
 // GetMaster returns the dm master leader
 // returns isFound, isActive, isLeader, error
-func (dm *DMMasterClient) GetMaster(name string) (isFound bool, isActive bool, isLeader bool, err error) {
+func (dm *DMMasterClient) GetMaster(ctx context.Context, name string) (isFound bool, isActive bool, isLeader bool, err error) {
 	query := "?leader=true&master=true&names=" + name
 	endpoints := dm.getEndpoints(dmMembersURI + query)
-	memberResp, err := dm.getMember(endpoints)
+	memberResp, err := dm.getMember(ctx, endpoints)
 
 	if err != nil {
 		zap.L().Error("get dm master status failed", zap.Error(err))
@@ -159,10 +157,10 @@ func (dm *DMMasterClient) GetMaster(name string) (isFound bool, isActive bool, i
 
 // GetWorker returns the dm worker status
 // returns (worker stage, error). If worker stage is "", that means this worker is in cluster
-func (dm *DMMasterClient) GetWorker(name string) (string, error) {
+func (dm *DMMasterClient) GetWorker(ctx context.Context, name string) (string, error) {
 	query := "?worker=true&names=" + name
 	endpoints := dm.getEndpoints(dmMembersURI + query)
-	memberResp, err := dm.getMember(endpoints)
+	memberResp, err := dm.getMember(ctx, endpoints)
 
 	if err != nil {
 		zap.L().Error("get dm worker status failed", zap.Error(err))
@@ -187,7 +185,7 @@ func (dm *DMMasterClient) GetWorker(name string) (string, error) {
 }
 
 // GetLeader gets leader of dm cluster
-func (dm *DMMasterClient) GetLeader(retryOpt *utils.RetryOption) (string, error) {
+func (dm *DMMasterClient) GetLeader(ctx context.Context, retryOpt *utils.RetryOption) (string, error) {
 	query := "?leader=true"
 	endpoints := dm.getEndpoints(dmMembersURI + query)
 
@@ -200,10 +198,10 @@ func (dm *DMMasterClient) GetLeader(retryOpt *utils.RetryOption) (string, error)
 		err        error
 	)
 
-	if err := utils.Retry(func() error {
-		memberResp, err = dm.getMember(endpoints)
+	if err := utils.Wait(ctx, func() error {
+		memberResp, err = dm.getMember(ctx, endpoints)
 		return err
-	}, *retryOpt); err != nil {
+	}, utils.WaitOptionFromRetry(*retryOpt)); err != nil {
 		return "", err
 	}
 
@@ -217,10 +215,10 @@ func (dm *DMMasterClient) GetLeader(retryOpt *utils.RetryOption) (string, error)
 }
 
 // GetRegisteredMembers gets all registerer members of dm cluster
-func (dm *DMMasterClient) GetRegisteredMembers() ([]string, []string, error) {
+func (dm *DMMasterClient) GetRegisteredMembers(ctx context.Context) ([]string, []string, error) {
 	query := "?master=true&worker=true"
 	endpoints := dm.getEndpoints(dmMembersURI + query)
-	memberResp, err := dm.getMember(endpoints)
+	memberResp, err := dm.getMember(ctx, endpoints)
 
 	var (
 		registeredMasters []string
@@ -248,35 +246,35 @@ func (dm *DMMasterClient) GetRegisteredMembers() ([]string, []string, error) {
 }
 
 // EvictDMMasterLeader evicts the dm master leader
-func (dm *DMMasterClient) EvictDMMasterLeader(retryOpt *utils.RetryOption) error {
+func (dm *DMMasterClient) EvictDMMasterLeader(ctx context.Context, retryOpt *utils.RetryOption) error {
 	return nil
 }
 
 // OfflineMember offlines the member of dm cluster
-func (dm *DMMasterClient) OfflineMember(query string, retryOpt *utils.RetryOption) error {
+func (dm *DMMasterClient) OfflineMember(ctx context.Context, query string, retryOpt *utils.RetryOption) error {
 	endpoints := dm.getEndpoints(dmMembersURI + query)
 
 	if retryOpt == nil {
 		retryOpt = defaultRetryOpt
 	}
 
-	if err := utils.Retry(func() error {
-		_, err := dm.deleteMember(endpoints)
+	if err := utils.Wait(ctx, func() error {
+		_, err := dm.deleteMember(ctx, endpoints)
 		return err
-	}, *retryOpt); err != nil {
+	}, utils.WaitOptionFromRetry(*retryOpt)); err != nil {
 		return fmt.Errorf("error offline member %s, %v", query, err)
 	}
 	return nil
 }
 
 // OfflineWorker offlines the dm worker
-func (dm *DMMasterClient) OfflineWorker(name string, retryOpt *utils.RetryOption) error {
+func (dm *DMMasterClient) OfflineWorker(ctx context.Context, name string, retryOpt *utils.RetryOption) error {
 	query := "/worker/" + name
-	return dm.OfflineMember(query, retryOpt)
+	return dm.OfflineMember(ctx, query, retryOpt)
 }
 
 // OfflineMaster offlines the dm master
-func (dm *DMMasterClient) OfflineMaster(name string, retryOpt *utils.RetryOption) error {
+func (dm *DMMasterClient) OfflineMaster(ctx context.Context, name string, retryOpt *utils.RetryOption) error {
 	query := "/master/" + name
-	return dm.OfflineMember(query, retryOpt)
+	return dm.OfflineMember(ctx, query, retryOpt)
 }
@@ -0,0 +1,74 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// ExtraHeaders is the headers attached to the requests sent by the component
+// API clients, it's used when the component APIs are behind an authenticating
+// proxy. The headers are only sent to the hosts of the cluster over HTTPS, so
+// that the credentials are never sent in plain text or to the endpoints out of
+// the cluster.
+type ExtraHeaders struct {
+	header http.Header
+	hosts  set.StringSet
+}
+
+// NewExtraHeaders returns the ExtraHeaders sent to the hosts, the bearer token
+// is added as the Authorization header if it is not empty. It returns nil if
+// there is no header to send.
+func NewExtraHeaders(headers map[string]string, token string, hosts []string) *ExtraHeaders {
+	h := http.Header{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	if token != "" {
+		h.Set("Authorization", "Bearer "+token)
+	}
+	if len(h) == 0 || len(hosts) == 0 {
+		return nil
+	}
+	return &ExtraHeaders{header: h, hosts: set.NewStringSet(hosts...)}
+}
+
+type extraHeadersKey struct{}
+
+// WithExtraHeaders returns a context in which the requests of the component
+// API clients are sent with the headers, a nil headers sends none
+func WithExtraHeaders(ctx context.Context, headers *ExtraHeaders) context.Context {
+	return context.WithValue(ctx, extraHeadersKey{}, headers)
+}
+
+// extraHeadersOf returns the extra headers of the request to u in ctx
+func extraHeadersOf(ctx context.Context, u *url.URL) http.Header {
+	h, _ := ctx.Value(extraHeadersKey{}).(*ExtraHeaders)
+	if h == nil || u.Scheme != SchemeHTTPS || !h.hosts.Exist(u.Hostname()) {
+		return nil
+	}
+	return h.header
+}
+
+// ApplyExtraHeaders makes the client send the extra headers in the context of
+// the requests, see WithExtraHeaders, and returns it
+func ApplyExtraHeaders(c *utils.HTTPClient) *utils.HTTPClient {
+	c.SetContextHeader(extraHeadersOf)
+	return c
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtraHeaders(t *testing.T) {
+	var got http.Header
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	})
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	ctx := WithExtraHeaders(context.Background(),
+		NewExtraHeaders(map[string]string{"X-Proxy-User": "tiup"}, "secret", []string{"127.0.0.1"}))
+	assert.Nil(t, NewExtraHeaders(nil, "", []string{"127.0.0.1"}))
+
+	c := ApplyExtraHeaders(utils.NewHTTPClient(time.Second, &tls.Config{InsecureSkipVerify: true}))
+	_, _, err := c.PostWithStatusCode(ctx, server.URL, strings.NewReader("{}"))
+	assert.Nil(t, err)
+	assert.Equal(t, "tiup", got.Get("X-Proxy-User"))
+	assert.Equal(t, "Bearer secret", got.Get("Authorization"))
+	assert.Equal(t, "application/json", got.Get("Content-Type"))
+
+	_, _, err = c.Delete(ctx, server.URL, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer secret", got.Get("Authorization"))
+
+	// the headers are scoped to the context, e.g. the one of another cluster
+	_, _, err = c.Delete(context.Background(), server.URL, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, got.Get("Authorization"))
+
+	// the headers are not sent to the hosts out of the cluster
+	_, _, err = c.Delete(ctx, strings.Replace(server.URL, "127.0.0.1", "localhost", 1), nil)
+	assert.Nil(t, err)
+	assert.Empty(t, got.Get("Authorization"))
+
+	// the headers are not sent in plain text
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	_, _, err = c.PostWithStatusCode(ctx, plain.URL, strings.NewReader("{}"))
+	assert.Nil(t, err)
+	assert.Empty(t, got.Get("Authorization"))
+	assert.Equal(t, "application/json", got.Get("Content-Type"))
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+)
+
+// HedgeOption controls the hedged requests to multiple endpoints. The next
+// endpoint is requested if none of the in-flight requests responds within
+// Delay, and at most Parallelism requests are in flight at the same time.
+type HedgeOption struct {
+	Delay       time.Duration
+	Parallelism int
+}
+
+// DefaultHedgeOption is a reasonable hedge option for the control-plane APIs
+var DefaultHedgeOption = HedgeOption{
+	Delay:       300 * time.Millisecond,
+	Parallelism: 3,
+}
+
+type hedgeKey struct{}
+
+// WithHedgeContext returns a context with which the read-only requests of the
+// PD, TiCDC and DM-master clients are hedged by opt, the requests changing the
+// cluster are never hedged.
+func WithHedgeContext(ctx context.Context, opt HedgeOption) context.Context {
+	return context.WithValue(ctx, hedgeKey{}, &opt)
+}
+
+// WithoutHedge returns a context with which the requests are not hedged, it
+// opts out the calls not safe to be sent to the endpoints in parallel.
+func WithoutHedge(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hedgeKey{}, (*HedgeOption)(nil))
+}
+
+// hedgeFromContext returns the hedge option in ctx, nil if it's not hedged
+func hedgeFromContext(ctx context.Context) *HedgeOption {
+	opt, _ := ctx.Value(hedgeKey{}).(*HedgeOption)
+	return opt
+}
+
+// tryURLsHedged is like tryURLs, but requests the endpoints in parallel if
+// the previous ones are slow, the first successful response is returned and
+// the others are canceled. It falls back to tryURLs if hedging is disabled.
+//
+// The f may be called concurrently, so it must not modify shared state.
+func tryURLsHedged(
+	ctx context.Context,
+	endpoints []string,
+	opt *HedgeOption,
+	f func(ctx context.Context, endpoint string) ([]byte, error),
+) ([]byte, error) {
+	if opt == nil || opt.Parallelism <= 1 || len(endpoints) <= 1 {
+		return tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+			return f(ctx, endpoint)
+		})
+	}
+
+	urls := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		urls = append(urls, u.String())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	// buffered so the canceled requests never block
+	results := make(chan result, len(urls))
+
+	next, inflight := 0, 0
+	launch := func() {
+		endpoint := urls[next]
+		next++
+		inflight++
+		go func() {
+			data, err := timedCall(endpoint, func(endpoint string) ([]byte, error) {
+				return f(ctx, endpoint)
+			})
+			results <- result{data, err}
+		}()
+	}
+
+	timer := time.NewTimer(opt.Delay)
+	defer timer.Stop()
+
+	var lastErr error
+	launch()
+	for inflight > 0 {
+		select {
+		case r := <-results:
+			inflight--
+			if r.err == nil {
+				return r.data, nil
+			}
+			lastErr = r.err
+			// the endpoint is down, no need to wait for the delay
+			if next < len(urls) {
+				launch()
+			}
+		case <-timer.C:
+			if next < len(urls) && inflight < opt.Parallelism {
+				launch()
+			}
+			timer.Reset(opt.Delay)
+		}
+	}
+
+	return nil, perrs.Errorf("no endpoint available, the last err was: %s", lastErr)
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTryURLsHedged(t *testing.T) {
+	opt := &HedgeOption{Delay: 10 * time.Millisecond, Parallelism: 2}
+	endpoints := []string{"http://slow", "http://fast", "http://unused"}
+
+	// the slow endpoint is hedged by the next one
+	var called int32
+	data, err := tryURLsHedged(context.Background(), endpoints, opt, func(ctx context.Context, endpoint string) ([]byte, error) {
+		atomic.AddInt32(&called, 1)
+		if endpoint == "http://slow" {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return []byte("slow"), nil
+			}
+		}
+		return []byte(endpoint), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://fast", string(data))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&called))
+
+	// the failed endpoint is skipped without waiting for the delay
+	opt.Delay = time.Hour
+	data, err = tryURLsHedged(context.Background(), endpoints, opt, func(ctx context.Context, endpoint string) ([]byte, error) {
+		if endpoint == "http://slow" {
+			return nil, errors.New("down")
+		}
+		return []byte(endpoint), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://fast", string(data))
+
+	// all endpoints failed
+	_, err = tryURLsHedged(context.Background(), endpoints, opt, func(ctx context.Context, endpoint string) ([]byte, error) {
+		return nil, errors.New("down")
+	})
+	assert.NotNil(t, err)
+}
+
+func TestHedgeContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, hedgeFromContext(ctx))
+	ctx = WithHedgeContext(ctx, DefaultHedgeOption)
+	assert.Equal(t, &DefaultHedgeOption, hedgeFromContext(ctx))
+	// opted out by the calls not safe to hedge
+	assert.Nil(t, hedgeFromContext(WithoutHedge(ctx)))
+}
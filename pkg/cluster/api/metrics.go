@@ -0,0 +1,131 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SlowCallThreshold is the duration above which an API call is logged as slow
+var SlowCallThreshold = 3 * time.Second
+
+// CallStats is the statistics of the API calls to an endpoint
+type CallStats struct {
+	Endpoint string        `json:"endpoint"`
+	Count    int           `json:"count"`
+	Failures int           `json:"failures"`
+	Total    time.Duration `json:"total"`
+	Max      time.Duration `json:"max"`
+}
+
+// Avg returns the average latency of the calls
+func (s CallStats) Avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+type callMetrics struct {
+	mu    sync.Mutex
+	stats map[string]*CallStats
+}
+
+var apiMetrics = &callMetrics{
+	stats: make(map[string]*CallStats),
+}
+
+// metricsKey strips the query of the endpoint, so the calls with different
+// parameters are counted together
+func metricsKey(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	u.RawQuery = ""
+	return u.String()
+}
+
+func (m *callMetrics) record(endpoint string, d time.Duration, err error) {
+	key := metricsKey(endpoint)
+
+	m.mu.Lock()
+	s, ok := m.stats[key]
+	if !ok {
+		s = &CallStats{Endpoint: key}
+		m.stats[key] = s
+	}
+	s.Count++
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+	if err != nil {
+		s.Failures++
+	}
+	m.mu.Unlock()
+
+	if d >= SlowCallThreshold {
+		zap.L().Warn("Slow API call",
+			zap.String("endpoint", endpoint),
+			zap.Duration("duration", d),
+			zap.Error(err),
+		)
+	}
+}
+
+// timedCall calls f to the endpoint and records the metrics of the call
+func timedCall(endpoint string, f func(endpoint string) ([]byte, error)) ([]byte, error) {
+	start := time.Now()
+	data, err := f(endpoint)
+	apiMetrics.record(endpoint, time.Since(start), err)
+	return data, err
+}
+
+// GetCallStats returns the statistics of the API calls since the process
+// started, the endpoints spent the most time come first.
+func GetCallStats() []CallStats {
+	apiMetrics.mu.Lock()
+	defer apiMetrics.mu.Unlock()
+
+	result := make([]CallStats, 0, len(apiMetrics.stats))
+	for _, s := range apiMetrics.stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Endpoint < result[j].Endpoint
+	})
+	return result
+}
+
+// LogCallStats writes the summary of the API calls to the log
+func LogCallStats() {
+	for _, s := range GetCallStats() {
+		zap.L().Info("API call stats",
+			zap.String("endpoint", s.Endpoint),
+			zap.Int("count", s.Count),
+			zap.Int("failures", s.Failures),
+			zap.Duration("avg", s.Avg()),
+			zap.Duration("max", s.Max),
+		)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallMetrics(t *testing.T) {
+	m := &callMetrics{stats: make(map[string]*CallStats)}
+	m.record("http://pd:2379/pd/api/v1/stores?state=0", time.Second, nil)
+	m.record("http://pd:2379/pd/api/v1/stores?state=1", 3*time.Second, errors.New("timeout"))
+	m.record("http://cdc:8300/api/v1/captures", time.Second, nil)
+
+	s := m.stats["http://pd:2379/pd/api/v1/stores"]
+	assert.NotNil(t, s)
+	assert.Equal(t, 2, s.Count)
+	assert.Equal(t, 1, s.Failures)
+	assert.Equal(t, 3*time.Second, s.Max)
+	assert.Equal(t, 2*time.Second, s.Avg())
+	assert.Equal(t, 1, m.stats["http://cdc:8300/api/v1/captures"].Count)
+}
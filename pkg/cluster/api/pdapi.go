@@ -42,7 +42,6 @@ type PDClient struct {
 	addrs      []string
 	tlsEnabled bool
 	httpClient *utils.HTTPClient
-	ctx        context.Context
 }
 
 // LabelInfo represents an instance label info
@@ -58,8 +57,8 @@ type LabelInfo struct {
 	Labels    string `json:"labels"`
 }
 
-// NewPDClient returns a new PDClient, the context must have
-// a *logprinter.Logger as value of "logger"
+// NewPDClient returns a new PDClient, the logger in the context of each
+// request is used if any
 func NewPDClient(
 	ctx context.Context,
 	addrs []string,
@@ -71,30 +70,36 @@ func NewPDClient(
 		enableTLS = true
 	}
 
-	if _, ok := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger); !ok {
-		panic("the context must have logger inside")
-	}
-
 	cli := &PDClient{
 		addrs:      addrs,
 		tlsEnabled: enableTLS,
-		httpClient: utils.NewHTTPClient(timeout, tlsConfig),
-		ctx:        ctx,
+		httpClient: ApplyExtraHeaders(utils.NewHTTPClient(timeout, verifiedTLSConfig(tlsConfig))),
 	}
 
-	cli.tryIdentifyVersion()
+	cli.tryIdentifyVersion(ctx)
 	return cli
 }
 
-func (pc *PDClient) l() *logprinter.Logger {
-	return pc.ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+// get requests the endpoints with GET method and returns the first successful
+// response, the requests are hedged if enabled in ctx.
+func (pc *PDClient) get(ctx context.Context, endpoints []string) ([]byte, error) {
+	return tryURLsHedged(ctx, endpoints, hedgeFromContext(ctx), func(ctx context.Context, endpoint string) ([]byte, error) {
+		return pc.httpClient.Get(ctx, endpoint)
+	})
 }
 
-func (pc *PDClient) tryIdentifyVersion() {
+func (pc *PDClient) l(ctx context.Context) *logprinter.Logger {
+	if logger, ok := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger); ok {
+		return logger
+	}
+	return logprinter.NewLogger("")
+}
+
+func (pc *PDClient) tryIdentifyVersion(ctx context.Context) {
 	endpoints := pc.getEndpoints(pdVersionURI)
 	response := map[string]string{}
 	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
+		body, err := pc.httpClient.Get(ctx, endpoint)
 		if err != nil {
 			return body, err
 		}
@@ -106,13 +111,15 @@ func (pc *PDClient) tryIdentifyVersion() {
 	}
 }
 
+// Version returns the version of PD identified when the client is created,
+// it's empty if the version is not identified
+func (pc *PDClient) Version() string {
+	return pc.version
+}
+
 // GetURL builds the client URL of PDClient
 func (pc *PDClient) GetURL(addr string) string {
-	httpPrefix := "http"
-	if pc.tlsEnabled {
-		httpPrefix = "https"
-	}
-	return fmt.Sprintf("%s://%s", httpPrefix, addr)
+	return URL(pc.tlsEnabled, addr)
 }
 
 const (
@@ -129,6 +136,7 @@ var (
 	pdConfigReplicate    = "pd/api/v1/config/replicate"
 	pdReplicationModeURI = "pd/api/v1/config/replication-mode"
 	pdRulesURI           = "pd/api/v1/config/rules"
+	pdRuleURI            = "pd/api/v1/config/rule"
 	pdConfigSchedule     = "pd/api/v1/config/schedule"
 	pdLeaderURI          = "pd/api/v1/leader"
 	pdLeaderTransferURI  = "pd/api/v1/leader/transfer"
@@ -138,6 +146,8 @@ var (
 	pdStoresURI          = "pd/api/v1/stores"
 	pdStoresLimitURI     = "pd/api/v1/stores/limit"
 	pdRegionsCheckURI    = "pd/api/v1/regions/check"
+	pdRegionsStoreURI    = "pd/api/v1/regions/store"
+	pdGCSafePointURI     = "pd/api/v1/gc/safepoint"
 )
 
 func tryURLs(endpoints []string, f func(endpoint string) ([]byte, error)) ([]byte, error) {
@@ -156,7 +166,7 @@ func tryURLs(endpoints []string, f func(endpoint string) ([]byte, error)) ([]byt
 
 		endpoint = u.String()
 
-		bytes, err = f(endpoint)
+		bytes, err = timedCall(endpoint, f)
 		if err != nil {
 			continue
 		}
@@ -178,44 +188,30 @@ func (pc *PDClient) getEndpoints(uri string) (endpoints []string) {
 }
 
 // CheckHealth checks the health of PD node
-func (pc *PDClient) CheckHealth() error {
+func (pc *PDClient) CheckHealth(ctx context.Context) error {
 	endpoints := pc.getEndpoints(pdPingURI)
 
-	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
-		if err != nil {
-			return body, err
-		}
-
-		return body, nil
-	})
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := pc.get(ctx, endpoints)
+	return err
 }
 
 // GetStores queries the stores info from PD server
-func (pc *PDClient) GetStores() (*StoresInfo, error) {
+func (pc *PDClient) GetStores(ctx context.Context) (*StoresInfo, error) {
 	// Return all stores
 	query := "?state=0&state=1&state=2"
 	endpoints := pc.getEndpoints(pdStoresURI + query)
 
 	storesInfo := StoresInfo{}
 
-	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
-		if err != nil {
-			return body, err
-		}
-
-		return body, json.Unmarshal(body, &storesInfo)
+	body, err := cacheFromContext(ctx).get(cacheKey(endpoints), func() ([]byte, error) {
+		return pc.get(ctx, endpoints)
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(body, &storesInfo); err != nil {
+		return nil, perrs.AddStack(err)
+	}
 
 	// Desc sorting the store list, we assume the store with largest ID is the
 	// latest one.
@@ -228,8 +224,8 @@ func (pc *PDClient) GetStores() (*StoresInfo, error) {
 }
 
 // GetCurrentStore gets the current store info of a given host
-func (pc *PDClient) GetCurrentStore(addr string) (*StoreInfo, error) {
-	stores, err := pc.GetStores()
+func (pc *PDClient) GetCurrentStore(ctx context.Context, addr string) (*StoreInfo, error) {
+	stores, err := pc.GetStores(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -269,7 +265,7 @@ func (pc *PDClient) GetCurrentStore(addr string) (*StoreInfo, error) {
 }
 
 // WaitLeader wait until there's a leader or timeout.
-func (pc *PDClient) WaitLeader(retryOpt *utils.RetryOption) error {
+func (pc *PDClient) WaitLeader(ctx context.Context, retryOpt *utils.RetryOption) error {
 	if retryOpt == nil {
 		retryOpt = &utils.RetryOption{
 			Delay:   time.Second * 1,
@@ -277,94 +273,96 @@ func (pc *PDClient) WaitLeader(retryOpt *utils.RetryOption) error {
 		}
 	}
 
-	if err := utils.Retry(func() error {
-		_, err := pc.GetLeader()
+	if err := utils.Wait(ctx, func() error {
+		_, err := pc.GetLeader(ctx)
 		if err == nil {
 			return nil
 		}
 
 		// return error by default, to make the retry work
-		pc.l().Debugf("Still waitting for the PD leader to be elected")
+		pc.l(ctx).Debugf("Still waitting for the PD leader to be elected")
 		return perrs.New("still waitting for the PD leader to be elected")
-	}, *retryOpt); err != nil {
+	}, utils.WaitOptionFromRetry(*retryOpt)); err != nil {
 		return fmt.Errorf("error getting PD leader, %v", err)
 	}
 	return nil
 }
 
 // GetLeader queries the leader node of PD cluster
-func (pc *PDClient) GetLeader() (*pdpb.Member, error) {
+func (pc *PDClient) GetLeader(ctx context.Context) (*pdpb.Member, error) {
 	endpoints := pc.getEndpoints(pdLeaderURI)
 
 	leader := pdpb.Member{}
 
-	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
-		if err != nil {
-			return body, err
-		}
-
-		return body, json.Unmarshal(body, &leader)
-	})
-
+	body, err := pc.get(ctx, endpoints)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(body, &leader); err != nil {
+		return nil, perrs.AddStack(err)
+	}
 
 	return &leader, nil
 }
 
 // GetMembers queries for member list from the PD server
-func (pc *PDClient) GetMembers() (*pdpb.GetMembersResponse, error) {
+func (pc *PDClient) GetMembers(ctx context.Context) (*pdpb.GetMembersResponse, error) {
 	endpoints := pc.getEndpoints(pdMembersURI)
 	members := pdpb.GetMembersResponse{}
 
-	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
-		if err != nil {
-			return body, err
-		}
-
-		return body, json.Unmarshal(body, &members)
-	})
-
+	body, err := pc.get(ctx, endpoints)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, perrs.AddStack(err)
+	}
 
 	return &members, nil
 }
 
 // GetConfig returns all PD configs
-func (pc *PDClient) GetConfig() (map[string]interface{}, error) {
+func (pc *PDClient) GetConfig(ctx context.Context) (map[string]interface{}, error) {
 	endpoints := pc.getEndpoints(pdConfigURI)
 
 	// We don't use the `github.com/tikv/pd/server/config` directly because
 	// there is compatible issue: https://github.com/pingcap/tiup/issues/637
 	pdConfig := map[string]interface{}{}
 
-	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
-		if err != nil {
-			return body, err
-		}
-
-		return body, json.Unmarshal(body, &pdConfig)
-	})
+	body, err := pc.get(ctx, endpoints)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(body, &pdConfig); err != nil {
+		return nil, perrs.AddStack(err)
+	}
 
 	return flatten.Flatten(pdConfig, "", flatten.DotStyle)
 }
 
+// GetGCSafePoint returns the GC safe points of the cluster
+func (pc *PDClient) GetGCSafePoint(ctx context.Context) (*GCSafePoint, error) {
+	endpoints := pc.getEndpoints(pdGCSafePointURI)
+
+	safePoint := GCSafePoint{}
+	body, err := pc.get(ctx, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &safePoint); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+
+	return &safePoint, nil
+}
+
 // GetClusterID return cluster ID
-func (pc *PDClient) GetClusterID() (uint64, error) {
+func (pc *PDClient) GetClusterID(ctx context.Context) (uint64, error) {
 	endpoints := pc.getEndpoints(pdClusterIDURI)
 	var clusterID map[string]interface{}
 
 	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
+		body, err := pc.httpClient.Get(ctx, endpoint)
 		if err != nil {
 			return body, err
 		}
@@ -382,8 +380,8 @@ func (pc *PDClient) GetClusterID() (uint64, error) {
 }
 
 // GetDashboardAddress get the PD node address which runs dashboard
-func (pc *PDClient) GetDashboardAddress() (string, error) {
-	cfg, err := pc.GetConfig()
+func (pc *PDClient) GetDashboardAddress(ctx context.Context) (string, error) {
+	cfg, err := pc.GetConfig(ctx)
 	if err != nil {
 		return "", perrs.AddStack(err)
 	}
@@ -395,16 +393,29 @@ func (pc *PDClient) GetDashboardAddress() (string, error) {
 	return addr, nil
 }
 
+// SetDashboardAddress sets the address of the PD running TiDB Dashboard,
+// the address is in the form of scheme://host:port
+func (pc *PDClient) SetDashboardAddress(ctx context.Context, addr string) error {
+	body, err := json.Marshal(map[string]interface{}{"pd-server.dashboard-address": addr})
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	pc.l(ctx).Debugf("setting dashboard address: %s", addr)
+	err = pc.updateConfig(ctx, pdConfigURI, bytes.NewBuffer(body))
+	cacheFromContext(ctx).invalidate()
+	return err
+}
+
 // EvictPDLeader evicts the PD leader
-func (pc *PDClient) EvictPDLeader(retryOpt *utils.RetryOption) error {
+func (pc *PDClient) EvictPDLeader(ctx context.Context, retryOpt *utils.RetryOption) error {
 	// get current members
-	members, err := pc.GetMembers()
+	members, err := pc.GetMembers(ctx)
 	if err != nil {
 		return err
 	}
 
 	if len(members.Members) == 1 {
-		pc.l().Warnf("Only 1 member in the PD cluster, skip leader evicting")
+		pc.l(ctx).Warnf("Only 1 member in the PD cluster, skip leader evicting")
 		return nil
 	}
 
@@ -413,7 +424,7 @@ func (pc *PDClient) EvictPDLeader(retryOpt *utils.RetryOption) error {
 	endpoints := pc.getEndpoints(cmd)
 
 	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Post(pc.ctx, endpoint, nil)
+		body, err := pc.httpClient.Post(ctx, endpoint, nil)
 		if err != nil {
 			return body, err
 		}
@@ -431,8 +442,8 @@ func (pc *PDClient) EvictPDLeader(retryOpt *utils.RetryOption) error {
 			Timeout: time.Second * 300,
 		}
 	}
-	if err := utils.Retry(func() error {
-		currLeader, err := pc.GetLeader()
+	if err := utils.Wait(ctx, func() error {
+		currLeader, err := pc.GetLeader(ctx)
 		if err != nil {
 			return err
 		}
@@ -443,9 +454,9 @@ func (pc *PDClient) EvictPDLeader(retryOpt *utils.RetryOption) error {
 		}
 
 		// return error by default, to make the retry work
-		pc.l().Debugf("Still waitting for the PD leader to transfer")
+		pc.l(ctx).Debugf("Still waitting for the PD leader to transfer")
 		return perrs.New("still waitting for the PD leader to transfer")
-	}, *retryOpt); err != nil {
+	}, utils.WaitOptionFromRetry(*retryOpt)); err != nil {
 		return fmt.Errorf("error evicting PD leader, %v", err)
 	}
 	return nil
@@ -459,9 +470,9 @@ type pdSchedulerRequest struct {
 
 // EvictStoreLeader evicts the store leaders
 // The host parameter should be in format of IP:Port, that matches store's address
-func (pc *PDClient) EvictStoreLeader(host string, retryOpt *utils.RetryOption, countLeader func(string) (int, error)) error {
+func (pc *PDClient) EvictStoreLeader(ctx context.Context, host string, retryOpt *utils.RetryOption, countLeader func(string) (int, error)) error {
 	// get info of current stores
-	latestStore, err := pc.GetCurrentStore(host)
+	latestStore, err := pc.GetCurrentStore(ctx, host)
 	if err != nil {
 		if errors.Is(err, ErrNoStore) {
 			return nil
@@ -479,7 +490,7 @@ func (pc *PDClient) EvictStoreLeader(host string, retryOpt *utils.RetryOption, c
 		return nil
 	}
 
-	pc.l().Infof("\tEvicting %d leaders from store %s...", leaderCount, latestStore.Store.Address)
+	pc.l(ctx).Infof("\tEvicting %d leaders from store %s...", leaderCount, latestStore.Store.Address)
 
 	// set scheduler for stores
 	scheduler, err := json.Marshal(pdSchedulerRequest{
@@ -493,11 +504,12 @@ func (pc *PDClient) EvictStoreLeader(host string, retryOpt *utils.RetryOption, c
 	endpoints := pc.getEndpoints(pdSchedulersURI)
 
 	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		return pc.httpClient.Post(pc.ctx, endpoint, bytes.NewBuffer(scheduler))
+		return pc.httpClient.Post(ctx, endpoint, bytes.NewBuffer(scheduler))
 	})
 	if err != nil {
 		return err
 	}
+	cacheFromContext(ctx).invalidate()
 
 	// wait for the transfer to complete
 	if retryOpt == nil {
@@ -506,8 +518,8 @@ func (pc *PDClient) EvictStoreLeader(host string, retryOpt *utils.RetryOption, c
 			Timeout: time.Second * 600,
 		}
 	}
-	if err := utils.Retry(func() error {
-		currStore, err := pc.GetCurrentStore(host)
+	if err := utils.Wait(ctx, func() error {
+		currStore, err := pc.GetCurrentStore(ctx, host)
 		if err != nil {
 			if errors.Is(err, ErrNoStore) {
 				return nil
@@ -522,14 +534,14 @@ func (pc *PDClient) EvictStoreLeader(host string, retryOpt *utils.RetryOption, c
 		if leaderCount == 0 {
 			return nil
 		}
-		pc.l().Infof(
+		pc.l(ctx).Infof(
 			"\t  Still waitting for %d store leaders to transfer...",
 			leaderCount,
 		)
 
 		// return error by default, to make the retry work
 		return perrs.New("still waiting for the store leaders to transfer")
-	}, *retryOpt); err != nil {
+	}, utils.WaitOptionFromRetry(*retryOpt)); err != nil {
 		return fmt.Errorf("error evicting store leader from %s, %v", host, err)
 	}
 	return nil
@@ -537,9 +549,9 @@ func (pc *PDClient) EvictStoreLeader(host string, retryOpt *utils.RetryOption, c
 
 // RemoveStoreEvict removes a store leader evict scheduler, which allows following
 // leaders to be transffered to it again.
-func (pc *PDClient) RemoveStoreEvict(host string) error {
+func (pc *PDClient) RemoveStoreEvict(ctx context.Context, host string) error {
 	// get info of current stores
-	latestStore, err := pc.GetCurrentStore(host)
+	latestStore, err := pc.GetCurrentStore(ctx, host)
 	if err != nil {
 		return err
 	}
@@ -552,9 +564,9 @@ func (pc *PDClient) RemoveStoreEvict(host string) error {
 	)
 	endpoints := pc.getEndpoints(cmd)
 
-	logger := pc.l()
+	logger := pc.l(ctx)
 	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, statusCode, err := pc.httpClient.Delete(pc.ctx, endpoint, nil)
+		body, statusCode, err := pc.httpClient.Delete(ctx, endpoint, nil)
 		if err != nil {
 			if statusCode == http.StatusNotFound || bytes.Contains(body, []byte("scheduler not found")) {
 				logger.Debugf("Store leader evicting scheduler does not exist, ignore.")
@@ -568,15 +580,16 @@ func (pc *PDClient) RemoveStoreEvict(host string) error {
 	if err != nil {
 		return err
 	}
+	cacheFromContext(ctx).invalidate()
 
 	logger.Debugf("Removed store leader evicting scheduler from %s.", latestStore.Store.Address)
 	return nil
 }
 
 // DelPD deletes a PD node from the cluster, name is the Name of the PD member
-func (pc *PDClient) DelPD(name string, retryOpt *utils.RetryOption) error {
+func (pc *PDClient) DelPD(ctx context.Context, name string, retryOpt *utils.RetryOption) error {
 	// get current members
-	members, err := pc.GetMembers()
+	members, err := pc.GetMembers(ctx)
 	if err != nil {
 		return err
 	}
@@ -588,9 +601,9 @@ func (pc *PDClient) DelPD(name string, retryOpt *utils.RetryOption) error {
 	cmd := fmt.Sprintf("%s/name/%s", pdMembersURI, name)
 	endpoints := pc.getEndpoints(cmd)
 
-	logger := pc.l()
+	logger := pc.l(ctx)
 	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, statusCode, err := pc.httpClient.Delete(pc.ctx, endpoint, nil)
+		body, statusCode, err := pc.httpClient.Delete(ctx, endpoint, nil)
 		if err != nil {
 			if statusCode == http.StatusNotFound || bytes.Contains(body, []byte("not found, pd")) {
 				logger.Debugf("PD node does not exist, ignore: %s", body)
@@ -612,8 +625,8 @@ func (pc *PDClient) DelPD(name string, retryOpt *utils.RetryOption) error {
 			Timeout: time.Second * 60,
 		}
 	}
-	if err := utils.Retry(func() error {
-		currMembers, err := pc.GetMembers()
+	if err := utils.Wait(ctx, func() error {
+		currMembers, err := pc.GetMembers(ctx)
 		if err != nil {
 			return err
 		}
@@ -626,15 +639,15 @@ func (pc *PDClient) DelPD(name string, retryOpt *utils.RetryOption) error {
 		}
 
 		return nil
-	}, *retryOpt); err != nil {
+	}, utils.WaitOptionFromRetry(*retryOpt)); err != nil {
 		return fmt.Errorf("error deleting PD node, %v", err)
 	}
 	return nil
 }
 
-func (pc *PDClient) isSameState(host string, state metapb.StoreState) (bool, error) {
+func (pc *PDClient) isSameState(ctx context.Context, host string, state metapb.StoreState) (bool, error) {
 	// get info of current stores
-	storeInfo, err := pc.GetCurrentStore(host)
+	storeInfo, err := pc.GetCurrentStore(ctx, host)
 	if err != nil {
 		return false, err
 	}
@@ -648,21 +661,21 @@ func (pc *PDClient) isSameState(host string, state metapb.StoreState) (bool, err
 
 // IsTombStone check if the node is Tombstone.
 // The host parameter should be in format of IP:Port, that matches store's address
-func (pc *PDClient) IsTombStone(host string) (bool, error) {
-	return pc.isSameState(host, metapb.StoreState_Tombstone)
+func (pc *PDClient) IsTombStone(ctx context.Context, host string) (bool, error) {
+	return pc.isSameState(ctx, host, metapb.StoreState_Tombstone)
 }
 
 // IsUp check if the node is Up state.
 // The host parameter should be in format of IP:Port, that matches store's address
-func (pc *PDClient) IsUp(host string) (bool, error) {
-	return pc.isSameState(host, metapb.StoreState_Up)
+func (pc *PDClient) IsUp(ctx context.Context, host string) (bool, error) {
+	return pc.isSameState(ctx, host, metapb.StoreState_Up)
 }
 
 // DelStore deletes stores from a (TiKV) host
 // The host parameter should be in format of IP:Port, that matches store's address
-func (pc *PDClient) DelStore(host string, retryOpt *utils.RetryOption) error {
+func (pc *PDClient) DelStore(ctx context.Context, host string, retryOpt *utils.RetryOption) error {
 	// get info of current stores
-	storeInfo, err := pc.GetCurrentStore(host)
+	storeInfo, err := pc.GetCurrentStore(ctx, host)
 	if err != nil {
 		if errors.Is(err, ErrNoStore) {
 			return nil
@@ -676,9 +689,9 @@ func (pc *PDClient) DelStore(host string, retryOpt *utils.RetryOption) error {
 	cmd := fmt.Sprintf("%s/%d", pdStoreURI, storeID)
 	endpoints := pc.getEndpoints(cmd)
 
-	logger := pc.l()
+	logger := pc.l(ctx)
 	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, statusCode, err := pc.httpClient.Delete(pc.ctx, endpoint, nil)
+		body, statusCode, err := pc.httpClient.Delete(ctx, endpoint, nil)
 		if err != nil {
 			if statusCode == http.StatusNotFound || bytes.Contains(body, []byte("not found")) {
 				logger.Debugf("store %d %s does not exist, ignore: %s", storeID, host, body)
@@ -692,6 +705,7 @@ func (pc *PDClient) DelStore(host string, retryOpt *utils.RetryOption) error {
 	if err != nil {
 		return err
 	}
+	cacheFromContext(ctx).invalidate()
 
 	// wait for the deletion to complete
 	if retryOpt == nil {
@@ -700,8 +714,8 @@ func (pc *PDClient) DelStore(host string, retryOpt *utils.RetryOption) error {
 			Timeout: time.Second * 60,
 		}
 	}
-	if err := utils.Retry(func() error {
-		currStore, err := pc.GetCurrentStore(host)
+	if err := utils.Wait(ctx, func() error {
+		currStore, err := pc.GetCurrentStore(ctx, host)
 		if err != nil {
 			// the store does not exist anymore, just ignore and skip
 			if errors.Is(err, ErrNoStore) {
@@ -722,51 +736,58 @@ func (pc *PDClient) DelStore(host string, retryOpt *utils.RetryOption) error {
 		}
 
 		return nil
-	}, *retryOpt); err != nil {
+	}, utils.WaitOptionFromRetry(*retryOpt)); err != nil {
 		return fmt.Errorf("error deleting store, %v", err)
 	}
 	return nil
 }
 
-func (pc *PDClient) updateConfig(url string, body io.Reader) error {
+func (pc *PDClient) updateConfig(ctx context.Context, url string, body io.Reader) error {
 	endpoints := pc.getEndpoints(url)
 	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		return pc.httpClient.Post(pc.ctx, endpoint, body)
+		return pc.httpClient.Post(ctx, endpoint, body)
 	})
 	return err
 }
 
 // UpdateReplicateConfig updates the PD replication config
-func (pc *PDClient) UpdateReplicateConfig(body io.Reader) error {
-	return pc.updateConfig(pdConfigReplicate, body)
+func (pc *PDClient) UpdateReplicateConfig(ctx context.Context, body io.Reader) error {
+	return pc.updateConfig(ctx, pdConfigReplicate, body)
 }
 
 // GetReplicateConfig gets the PD replication config
-func (pc *PDClient) GetReplicateConfig() ([]byte, error) {
+func (pc *PDClient) GetReplicateConfig(ctx context.Context) ([]byte, error) {
 	endpoints := pc.getEndpoints(pdConfigReplicate)
-	return tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		return pc.httpClient.Get(pc.ctx, endpoint)
-	})
+	return pc.get(ctx, endpoints)
 }
 
-// GetLocationLabels gets the replication.location-labels config from pd server
-func (pc *PDClient) GetLocationLabels() ([]string, bool, error) {
-	config, err := pc.GetReplicateConfig()
+// GetReplicationConfig gets the parsed replication config from pd server
+func (pc *PDClient) GetReplicationConfig(ctx context.Context) (*PDReplicationConfig, error) {
+	config, err := pc.GetReplicateConfig(ctx)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	rc := PDReplicationConfig{}
-	if err := json.Unmarshal(config, &rc); err != nil {
-		return nil, false, perrs.Annotatef(err, "unmarshal replication config: %s", string(config))
+	rc := &PDReplicationConfig{}
+	if err := json.Unmarshal(config, rc); err != nil {
+		return nil, perrs.Annotatef(err, "unmarshal replication config: %s", string(config))
+	}
+	return rc, nil
+}
+
+// GetLocationLabels gets the replication.location-labels config from pd server
+func (pc *PDClient) GetLocationLabels(ctx context.Context) ([]string, bool, error) {
+	rc, err := pc.GetReplicationConfig(ctx)
+	if err != nil {
+		return nil, false, err
 	}
 
 	return rc.LocationLabels, rc.EnablePlacementRules, nil
 }
 
-// GetTiKVLabels implements TiKVLabelProvider
-func (pc *PDClient) GetTiKVLabels() (map[string]map[string]string, []map[string]LabelInfo, error) {
-	r, err := pc.GetStores()
+// GetTiKVLabels returns the labels of the TiKV stores
+func (pc *PDClient) GetTiKVLabels(ctx context.Context) (map[string]map[string]string, []map[string]LabelInfo, error) {
+	r, err := pc.GetStores(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -811,19 +832,63 @@ func (pc *PDClient) GetTiKVLabels() (map[string]map[string]string, []map[string]
 	return locationLabels, storeInfo, nil
 }
 
+// GetPlacementRules gets the placement rules of the group
+func (pc *PDClient) GetPlacementRules(ctx context.Context, group string) ([]*PlacementRule, error) {
+	endpoints := pc.getEndpoints(fmt.Sprintf("%s/group/%s", pdRulesURI, group))
+
+	var rules []*PlacementRule
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, err := pc.httpClient.Get(ctx, endpoint)
+		if err != nil {
+			return body, err
+		}
+		return body, json.Unmarshal(body, &rules)
+	})
+	return rules, err
+}
+
+// SetPlacementRule creates or updates a placement rule, it has the same
+// effect as `pd-ctl config placement-rules save`
+func (pc *PDClient) SetPlacementRule(ctx context.Context, rule *PlacementRule) error {
+	body, err := json.Marshal(rule)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	pc.l(ctx).Debugf("setting placement rule %s/%s", rule.GroupID, rule.ID)
+	return pc.updateConfig(ctx, pdRuleURI, bytes.NewBuffer(body))
+}
+
+// DeletePlacementRule deletes a placement rule, it's ignored if the rule
+// does not exist
+func (pc *PDClient) DeletePlacementRule(ctx context.Context, group, id string) error {
+	endpoints := pc.getEndpoints(fmt.Sprintf("%s/%s/%s", pdRuleURI, group, id))
+
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, statusCode, err := pc.httpClient.Delete(ctx, endpoint, nil)
+		if err != nil && statusCode != http.StatusNotFound {
+			return body, err
+		}
+		return body, nil
+	})
+	if err == nil {
+		pc.l(ctx).Debugf("Delete placement rule %s/%s success", group, id)
+	}
+	return err
+}
+
 // UpdateScheduleConfig updates the PD schedule config
-func (pc *PDClient) UpdateScheduleConfig(body io.Reader) error {
-	return pc.updateConfig(pdConfigSchedule, body)
+func (pc *PDClient) UpdateScheduleConfig(ctx context.Context, body io.Reader) error {
+	return pc.updateConfig(ctx, pdConfigSchedule, body)
 }
 
 // CheckRegion queries for the region with specific status
-func (pc *PDClient) CheckRegion(state string) (*RegionsInfo, error) {
+func (pc *PDClient) CheckRegion(ctx context.Context, state string) (*RegionsInfo, error) {
 	uri := pdRegionsCheckURI + "/" + state
 	endpoints := pc.getEndpoints(uri)
 	regionsInfo := RegionsInfo{}
 
 	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
-		body, err := pc.httpClient.Get(pc.ctx, endpoint)
+		body, err := pc.httpClient.Get(ctx, endpoint)
 		if err != nil {
 			return body, err
 		}
@@ -833,9 +898,24 @@ func (pc *PDClient) CheckRegion(state string) (*RegionsInfo, error) {
 	return &regionsInfo, err
 }
 
+// GetStoreRegions queries for the regions having a peer on the store
+func (pc *PDClient) GetStoreRegions(ctx context.Context, storeID uint64) (*RegionsInfo, error) {
+	endpoints := pc.getEndpoints(fmt.Sprintf("%s/%d", pdRegionsStoreURI, storeID))
+	regionsInfo := RegionsInfo{}
+
+	body, err := pc.get(ctx, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &regionsInfo); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	return &regionsInfo, nil
+}
+
 // SetReplicationConfig sets a config key value of PD replication, it has the
 // same effect as `pd-ctl config set key value`
-func (pc *PDClient) SetReplicationConfig(key string, value int) error {
+func (pc *PDClient) SetReplicationConfig(ctx context.Context, key string, value int) error {
 	// Only support for pd version >= v4.0.0
 	if pc.version == "" || semver.Compare(pc.version, "v4.0.0") < 0 {
 		return nil
@@ -846,13 +926,13 @@ func (pc *PDClient) SetReplicationConfig(key string, value int) error {
 	if err != nil {
 		return err
 	}
-	pc.l().Debugf("setting replication config: %s=%d", key, value)
-	return pc.updateConfig(pdReplicationModeURI, bytes.NewBuffer(body))
+	pc.l(ctx).Debugf("setting replication config: %s=%d", key, value)
+	return pc.updateConfig(ctx, pdReplicationModeURI, bytes.NewBuffer(body))
 }
 
 // SetAllStoreLimits sets store for all stores and types, it has the same effect
 // as `pd-ctl store limit all value`
-func (pc *PDClient) SetAllStoreLimits(value int) error {
+func (pc *PDClient) SetAllStoreLimits(ctx context.Context, value int) error {
 	// Only support for pd version >= v4.0.0
 	if pc.version == "" || semver.Compare(pc.version, "v4.0.0") < 0 {
 		return nil
@@ -863,6 +943,6 @@ func (pc *PDClient) SetAllStoreLimits(value int) error {
 	if err != nil {
 		return err
 	}
-	pc.l().Debugf("setting store limit: %d", value)
-	return pc.updateConfig(pdStoresLimitURI, bytes.NewBuffer(body))
+	pc.l(ctx).Debugf("setting store limit: %d", value)
+	return pc.updateConfig(ctx, pdStoresLimitURI, bytes.NewBuffer(body))
 }
@@ -0,0 +1,46 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/api/vcr"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPDEvictLeaderReplay(t *testing.T) {
+	r := vcr.Use(t, "testdata/pd_evict_leader.yaml")
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, logprinter.NewLogger(""))
+
+	pc := NewPDClient(ctx, []string{"172.16.5.1:2379"}, 10*time.Second, nil)
+	assert.Equal(t, "v5.4.0", pc.version)
+
+	// the leader is still pd-1 at the first poll, and transferred to pd-2
+	// at the second one
+	err := pc.EvictPDLeader(ctx, &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: 10 * time.Second})
+	assert.Nil(t, err)
+	if r.Mode() == vcr.ModeRecord {
+		return
+	}
+
+	// all recorded interactions are replayed, the requests after fail
+	assert.Empty(t, r.Unused())
+	_, err = pc.GetLeader(ctx)
+	assert.NotNil(t, err)
+}
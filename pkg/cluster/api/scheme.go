@@ -0,0 +1,142 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// the schemes of the HTTP APIs
+const (
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+)
+
+// Scheme returns the scheme of the HTTP APIs, https if TLS is enabled
+func Scheme(tlsEnabled bool) string {
+	if tlsEnabled {
+		return SchemeHTTPS
+	}
+	return SchemeHTTP
+}
+
+// URL returns the URL of the address with the scheme
+func URL(tlsEnabled bool, addr string) string {
+	return fmt.Sprintf("%s://%s", Scheme(tlsEnabled), addr)
+}
+
+// EndpointResolver resolves the schemes and the TLS configs to access the
+// HTTP APIs of the components, the components served without certs in a TLS
+// enabled cluster are overridden to http.
+type EndpointResolver struct {
+	tlsCfg    *tls.Config
+	overrides map[string]string // component -> scheme
+}
+
+// NewEndpointResolver returns an EndpointResolver of the cluster TLS config,
+// nil if TLS is not enabled
+func NewEndpointResolver(tlsCfg *tls.Config) *EndpointResolver {
+	return &EndpointResolver{
+		tlsCfg:    tlsCfg,
+		overrides: make(map[string]string),
+	}
+}
+
+// WithScheme overrides the scheme of the component
+func (r *EndpointResolver) WithScheme(component, scheme string) *EndpointResolver {
+	r.overrides[component] = scheme
+	return r
+}
+
+// Scheme returns the scheme of the HTTP APIs of the component
+func (r *EndpointResolver) Scheme(component string) string {
+	if scheme, ok := r.overrides[component]; ok {
+		return scheme
+	}
+	return Scheme(r.tlsCfg != nil)
+}
+
+// URL returns the URL of the address of the component
+func (r *EndpointResolver) URL(component, addr string) string {
+	return fmt.Sprintf("%s://%s", r.Scheme(component), addr)
+}
+
+// TLSConfig returns the TLS config to access the component, nil if it's
+// served over http. The certificate of the server is verified to be issued
+// for the address dialed, so the mismatches are told with the SANs found.
+func (r *EndpointResolver) TLSConfig(component string) *tls.Config {
+	if r.tlsCfg == nil || r.Scheme(component) != SchemeHTTPS {
+		return nil
+	}
+	if r.tlsCfg.InsecureSkipVerify {
+		return r.tlsCfg
+	}
+	cfg := r.tlsCfg.Clone()
+	roots := cfg.RootCAs
+	// the chain and the SANs are verified by VerifyConnection instead
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("no certificate is provided by the server")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+			return err
+		}
+		if cs.ServerName == "" {
+			return nil
+		}
+		return VerifyCertAddrs(cs.PeerCertificates[0], cs.ServerName)
+	}
+	return cfg
+}
+
+// verifiedTLSConfig returns the TLS config of the clients of the component
+// APIs, the certificate of the server is verified to be issued for the
+// address dialed, see EndpointResolver.TLSConfig
+func verifiedTLSConfig(tlsCfg *tls.Config) *tls.Config {
+	return NewEndpointResolver(tlsCfg).TLSConfig("")
+}
+
+// VerifyCertAddrs checks the SANs of the certificate cover the hosts of the
+// addresses
+func VerifyCertAddrs(cert *x509.Certificate, addrs ...string) error {
+	for _, addr := range addrs {
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		if err := cert.VerifyHostname(host); err != nil {
+			sans := append([]string{}, cert.DNSNames...)
+			for _, ip := range cert.IPAddresses {
+				sans = append(sans, ip.String())
+			}
+			return errors.Errorf("the certificate %s is issued for %s, not %s, the server may be deployed without the certificate of its own",
+				cert.Subject.CommonName, strings.Join(sans, ", "), host)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/crypto"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointResolver(t *testing.T) {
+	r := NewEndpointResolver(nil).WithScheme("grafana", SchemeHTTP)
+	assert.Equal(t, "http://10.0.0.1:2379", r.URL("pd", "10.0.0.1:2379"))
+	assert.Nil(t, r.TLSConfig("pd"))
+
+	tlsCfg := &tls.Config{}
+	r = NewEndpointResolver(tlsCfg).WithScheme("grafana", SchemeHTTP)
+	assert.Equal(t, "https://10.0.0.1:2379", r.URL("pd", "10.0.0.1:2379"))
+	assert.Equal(t, "http://10.0.0.1:3000", r.URL("grafana", "10.0.0.1:3000"))
+	assert.Nil(t, r.TLSConfig("grafana"))
+
+	cfg := r.TLSConfig("pd")
+	assert.NotNil(t, cfg)
+	assert.True(t, cfg.InsecureSkipVerify)
+	assert.NotNil(t, cfg.VerifyConnection)
+	// the TLS config of the cluster is not changed
+	assert.False(t, tlsCfg.InsecureSkipVerify)
+}
+
+func TestVerifyCertAddrs(t *testing.T) {
+	ca, err := crypto.NewCA("test")
+	assert.Nil(t, err)
+	key, err := crypto.NewKeyPair(crypto.KeyTypeRSA, crypto.KeySchemeRSASSAPSSSHA256)
+	assert.Nil(t, err)
+	csr, err := key.CSR("pd", "pd", []string{"localhost", "pd-0"}, []string{"127.0.0.1", "10.0.0.1"})
+	assert.Nil(t, err)
+	der, err := ca.Sign(csr)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	assert.Nil(t, VerifyCertAddrs(cert, "10.0.0.1:2379", "pd-0:2379", "localhost"))
+	err = VerifyCertAddrs(cert, "10.0.0.1:2379", "10.0.0.2:2379")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is issued for localhost, pd-0, 127.0.0.1, 10.0.0.1, not 10.0.0.2")
+}
+
+func TestClientsVerifyCertAddrs(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	tlsCfg := &tls.Config{RootCAs: roots}
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	assert.Nil(t, err)
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, logprinter.NewLogger(""))
+
+	// the certificate of the test server is issued for 127.0.0.1 but not localhost
+	cdc := NewCDCOpenAPIClient([]string{"127.0.0.1:" + port, "localhost:" + port}, time.Second, tlsCfg)
+	_, err = cdc.client.Get(ctx, cdc.urls[0])
+	assert.Nil(t, err)
+	_, err = cdc.client.Get(ctx, cdc.urls[1])
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is issued for")
+
+	// the logger is optional in the context
+	pc := NewPDClient(context.Background(), []string{"localhost:" + port}, time.Second, tlsCfg)
+	_, err = pc.httpClient.Get(ctx, pc.GetURL("localhost:"+port))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is issued for")
+}
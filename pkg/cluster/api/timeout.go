@@ -0,0 +1,41 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"time"
+)
+
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout returns a context with which the component API clients
+// created use timeout for each request, it's used when the control planes of
+// the components are slow to respond but healthy. The built-in timeouts of
+// the callers are used if it's not positive.
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// RequestTimeout returns the timeout set by WithRequestTimeout in ctx, or def
+// if it's not set
+func RequestTimeout(ctx context.Context, def time.Duration) time.Duration {
+	if timeout, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		return timeout
+	}
+	return def
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, 5*time.Second, RequestTimeout(ctx, 5*time.Second))
+
+	timeoutCtx := WithRequestTimeout(ctx, 30*time.Second)
+	assert.Equal(t, 30*time.Second, RequestTimeout(timeoutCtx, 5*time.Second))
+
+	// the retries of the queries last for a few requests at least
+	client := NewCDCOpenAPIClient([]string{"127.0.0.1:8300"}, RequestTimeout(timeoutCtx, 5*time.Second), nil)
+	assert.Equal(t, 60*time.Second, client.RetryTimeout())
+
+	assert.Equal(t, 5*time.Second, RequestTimeout(WithRequestTimeout(ctx, -time.Second), 5*time.Second))
+	assert.Equal(t, 10*time.Second, NewCDCOpenAPIClient(nil, time.Second, nil).RetryTimeout())
+}
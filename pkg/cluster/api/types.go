@@ -52,6 +52,28 @@ type PDReplicationConfig struct {
 	IsolationLevel string `toml:"isolation-level" json:"isolation-level"`
 }
 
+// PlacementRule is a placement rule of PD, the fields not used are omitted.
+type PlacementRule struct {
+	GroupID          string            `json:"group_id"`
+	ID               string            `json:"id"`
+	Index            int               `json:"index,omitempty"`
+	Override         bool              `json:"override,omitempty"`
+	StartKeyHex      string            `json:"start_key"`
+	EndKeyHex        string            `json:"end_key"`
+	Role             string            `json:"role"`
+	Count            int               `json:"count"`
+	LabelConstraints []LabelConstraint `json:"label_constraints,omitempty"`
+	LocationLabels   []string          `json:"location_labels,omitempty"`
+}
+
+// LabelConstraint is a constraint of the labels of stores in a placement rule,
+// the op is one of in, notIn, exists and notExists.
+type LabelConstraint struct {
+	Key    string   `json:"key"`
+	Op     string   `json:"op"`
+	Values []string `json:"values,omitempty"`
+}
+
 // MetaStore contains meta information about a store.
 type MetaStore struct {
 	*metapb.Store
@@ -124,3 +146,31 @@ type RegionsInfo struct {
 	Count   int           `json:"count"`
 	Regions []*RegionInfo `json:"regions"`
 }
+
+// ServiceSafePoint is the safe point of a service, such as gc_worker or ticdc
+type ServiceSafePoint struct {
+	ServiceID string `json:"service_id"`
+	ExpiredAt int64  `json:"expired_at"`
+	SafePoint uint64 `json:"safe_point"`
+}
+
+// GCSafePoint is the response of the gc safepoint API of PD
+type GCSafePoint struct {
+	ServiceGCSafePoints []*ServiceSafePoint `json:"service_gc_safe_points"`
+	GCSafePoint         uint64              `json:"gc_safe_point"`
+}
+
+// SafePoint returns the safe point of GC, data older than it may have been
+// garbage collected. Old versions of PD don't report gc_safe_point, the safe
+// point of the GC worker is used instead.
+func (s *GCSafePoint) SafePoint() uint64 {
+	if s.GCSafePoint > 0 {
+		return s.GCSafePoint
+	}
+	for _, sp := range s.ServiceGCSafePoints {
+		if sp.ServiceID == "gc_worker" {
+			return sp.SafePoint
+		}
+	}
+	return 0
+}
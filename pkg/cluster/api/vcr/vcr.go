@@ -0,0 +1,248 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vcr records the exchanges of the component API clients with a real
+// cluster into a cassette, and replays them in the unit tests, so the flows
+// made of many requests can be covered without live clusters.
+//
+// A test replays the cassette with
+//
+//	r := vcr.Use(t, "testdata/pd_evict_leader.yaml")
+//	pdClient := api.NewPDClient(ctx, addrs, timeout, nil)
+//	...
+//	assert.Empty(t, r.Unused())
+//
+// and the cassette is (re-)recorded from the cluster the test points to if
+// the environment variable TIUP_API_RECORD is set.
+package vcr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvNameRecord is the environment variable to record the cassettes from
+// real clusters instead of replaying them
+const EnvNameRecord = "TIUP_API_RECORD"
+
+// Mode is the mode of a recorder
+type Mode int
+
+// Modes of recorder
+const (
+	ModeReplay Mode = iota
+	ModeRecord
+)
+
+// Interaction is a request and the response to it
+type Interaction struct {
+	Method      string `yaml:"method"`
+	URL         string `yaml:"url"`
+	RequestBody string `yaml:"request_body,omitempty"`
+	Status      int    `yaml:"status"`
+	Body        string `yaml:"body,omitempty"`
+}
+
+// Cassette is the interactions recorded in order
+type Cassette struct {
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Recorder is a http.RoundTripper records the interactions to the cassette
+// or replays them from it.
+//
+// In replay mode, a request is served by the first interaction not replayed
+// yet with the same method, URL and request body, so the same request polled
+// many times gets the responses in the order recorded. The requests matching
+// no interaction fail.
+type Recorder struct {
+	mode     Mode
+	file     string
+	next     http.RoundTripper
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+}
+
+var _ http.RoundTripper = &Recorder{}
+
+// New returns a recorder of the cassette file, the cassette is loaded in
+// replay mode. In record mode, the requests are sent with next, or
+// http.DefaultTransport if it's nil.
+func New(file string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	r := &Recorder{mode: mode, file: file, next: next}
+	if r.next == nil {
+		r.next = http.DefaultTransport
+	}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, perrs.Annotatef(err, "failed to read cassette %s", file)
+	}
+	if err := yaml.UnmarshalStrict(data, &r.cassette); err != nil {
+		return nil, perrs.Annotatef(err, "invalid cassette %s", file)
+	}
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Use makes all HTTP clients created in the test use the recorder of the
+// cassette file. The mode is record if EnvNameRecord is set, and the
+// cassette is saved when the test finishes.
+func Use(t testing.TB, file string) *Recorder {
+	t.Helper()
+	mode := ModeReplay
+	if os.Getenv(EnvNameRecord) != "" {
+		mode = ModeRecord
+	}
+	r, err := New(file, mode, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utils.SetHTTPTransport(r)
+	t.Cleanup(func() {
+		utils.SetHTTPTransport(nil)
+		if mode != ModeRecord {
+			return
+		}
+		if err := r.Save(); err != nil {
+			t.Errorf("failed to save cassette: %s", err)
+		}
+	})
+	return r
+}
+
+// Mode returns the mode of the recorder
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Interactions returns the interactions of the cassette
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Unused returns the interactions not replayed, which usually means the flow
+// tested changed since the cassette was recorded
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, replayed := range r.replayed {
+		if !replayed {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// Save writes the interactions recorded to the cassette file
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	data, err := yaml.Marshal(r.cassette)
+	r.mu.Unlock()
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := utils.CreateDir(filepath.Dir(r.file)); err != nil {
+		return err
+	}
+	return perrs.AddStack(os.WriteFile(r.file, data, 0644))
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	if r.mode == ModeRecord {
+		return r.record(req, reqBody)
+	}
+	return r.replay(req, reqBody)
+}
+
+func (r *Recorder) record(req *http.Request, reqBody []byte) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		// the transport errors are not reproducible, the test should
+		// simulate them in other ways
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(reqBody),
+		Status:      resp.StatusCode,
+		Body:        string(body),
+	})
+	r.replayed = append(r.replayed, true)
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, reqBody []byte) (*http.Response, error) {
+	url := req.URL.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, it := range r.cassette.Interactions {
+		if r.replayed[i] || it.Method != req.Method || it.URL != url || it.RequestBody != string(reqBody) {
+			continue
+		}
+		r.replayed[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", it.Status, http.StatusText(it.Status)),
+			StatusCode:    it.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header),
+			Body:          io.NopCloser(bytes.NewBufferString(it.Body)),
+			ContentLength: int64(len(it.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, perrs.Errorf("no interaction recorded in %s for %s %s", r.file, req.Method, url)
+}
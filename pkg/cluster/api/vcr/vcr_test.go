@@ -0,0 +1,90 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vcr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	polled := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/state":
+			polled++
+			if polled == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			_, _ = w.Write([]byte(strings.Repeat("x", polled)))
+		case "/drain":
+			body, _ := io.ReadAll(req.Body)
+			_, _ = w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "cassette.yaml")
+	r, err := New(file, ModeRecord, nil)
+	assert.Nil(t, err)
+	client := &http.Client{Transport: r}
+	for _, want := range []string{"x", "xx"} {
+		resp, err := client.Get(server.URL + "/state")
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, want, string(body))
+	}
+	resp, err := client.Post(server.URL+"/drain", "application/json", strings.NewReader(`{"store":1}`))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Nil(t, r.Save())
+	assert.Len(t, r.Interactions(), 3)
+
+	// the server is gone, the responses are replayed in the order recorded
+	server.Close()
+	r, err = New(file, ModeReplay, nil)
+	assert.Nil(t, err)
+	client = &http.Client{Transport: r}
+	resp, err = client.Post(server.URL+"/drain", "application/json", strings.NewReader(`{"store":1}`))
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, `{"store":1}`, string(body))
+	assert.Len(t, r.Unused(), 2)
+
+	resp, err = client.Get(server.URL + "/state")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp, err = client.Get(server.URL + "/state")
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "xx", string(body))
+	assert.Empty(t, r.Unused())
+
+	// the request body doesn't match, and the interactions are used up
+	_, err = client.Post(server.URL+"/drain", "application/json", strings.NewReader(`{"store":2}`))
+	assert.NotNil(t, err)
+	_, err = client.Get(server.URL + "/state")
+	assert.NotNil(t, err)
+}
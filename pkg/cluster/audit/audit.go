@@ -144,8 +144,7 @@ func GetAuditList(dir string) ([]Item, error) {
 	return auditList, nil
 }
 
-// OutputAuditLog outputs audit log.
-func OutputAuditLog(dir, fileSuffix string, data []byte) error {
+func newAuditID(fileSuffix string) string {
 	auditID := base52.Encode(time.Now().UnixNano() + rand.Int63n(1000))
 	if customID := os.Getenv(EnvNameAuditID); customID != "" {
 		auditID = fmt.Sprintf("%s_%s", auditID, customID)
@@ -153,16 +152,24 @@ func OutputAuditLog(dir, fileSuffix string, data []byte) error {
 	if fileSuffix != "" {
 		auditID = fmt.Sprintf("%s_%s", auditID, fileSuffix)
 	}
+	return auditID
+}
+
+// CommandLine returns the first line of the audit log of the command
+func CommandLine(args []string) string {
+	return strings.Join(encodeCommandArgs(args), " ") + "\n"
+}
 
-	fname := filepath.Join(dir, auditID)
+// OutputAuditLog outputs audit log.
+func OutputAuditLog(dir, fileSuffix string, data []byte) error {
+	fname := filepath.Join(dir, newAuditID(fileSuffix))
 	f, err := os.Create(fname)
 	if err != nil {
 		return errors.Annotate(err, "create audit log")
 	}
 	defer f.Close()
 
-	args := encodeCommandArgs(os.Args)
-	if _, err := f.Write([]byte(strings.Join(args, " ") + "\n")); err != nil {
+	if _, err := f.Write([]byte(CommandLine(os.Args))); err != nil {
 		return errors.Annotate(err, "write audit log")
 	}
 	if _, err := f.Write(data); err != nil {
@@ -171,6 +178,16 @@ func OutputAuditLog(dir, fileSuffix string, data []byte) error {
 	return nil
 }
 
+// ImportAuditLog saves the data starting with the command line as a new
+// audit log, and returns the audit ID of it
+func ImportAuditLog(dir string, data []byte) (string, error) {
+	auditID := newAuditID("")
+	if err := os.WriteFile(filepath.Join(dir, auditID), data, 0644); err != nil {
+		return "", errors.Annotate(err, "write audit log")
+	}
+	return auditID, nil
+}
+
 // ShowAuditLog show the audit with the specified auditID
 func ShowAuditLog(dir string, auditID string) error {
 	path := filepath.Join(dir, auditID)
@@ -38,8 +38,14 @@ type repositoryT struct {
 // NewRepository returns repository
 func NewRepository(os, arch string) (Repository, error) {
 	profile := localdata.InitProfile()
-	mirror := repository.NewMirror(environment.Mirror(), repository.MirrorOptions{
-		Progress: repository.DisableProgress{},
+	mirrorAddr := environment.Mirror()
+	tlsCfg, err := profile.Config.MirrorTLSConfig(mirrorAddr)
+	if err != nil {
+		return nil, err
+	}
+	mirror := repository.NewMirror(mirrorAddr, repository.MirrorOptions{
+		Progress:  repository.DisableProgress{},
+		TLSConfig: tlsCfg,
 	})
 	if err := mirror.Open(); err != nil {
 		return nil, err
@@ -87,6 +87,28 @@ func New(ctx context.Context, limit int, logger *logprinter.Logger) context.Cont
 		concurrency = limit
 	}
 
+	inner := &Context{
+		mutex: sync.RWMutex{},
+		Ev:    NewEventBus(),
+		exec: struct {
+			executors    map[string]Executor
+			stdouts      map[string][]byte
+			stderrs      map[string][]byte
+			checkResults map[string][]interface{}
+		}{
+			executors:    make(map[string]Executor),
+			stdouts:      make(map[string][]byte),
+			stderrs:      make(map[string][]byte),
+			checkResults: make(map[string][]interface{}),
+		},
+		Concurrency: concurrency, // default to CPU count
+	}
+	if logger != nil {
+		inner.Ev.Subscribe(EventDrainProgress, func(instance string, progress interface{}) {
+			logger.Eventf(string(EventDrainProgress), progress, "\t %s: %s", instance, progress)
+		})
+	}
+
 	return context.WithValue(
 		context.WithValue(
 			checkpoint.NewContext(ctx),
@@ -94,22 +116,7 @@ func New(ctx context.Context, limit int, logger *logprinter.Logger) context.Cont
 			logger,
 		),
 		ctxKey,
-		&Context{
-			mutex: sync.RWMutex{},
-			Ev:    NewEventBus(),
-			exec: struct {
-				executors    map[string]Executor
-				stdouts      map[string][]byte
-				stderrs      map[string][]byte
-				checkResults map[string][]interface{}
-			}{
-				executors:    make(map[string]Executor),
-				stdouts:      make(map[string][]byte),
-				stderrs:      make(map[string][]byte),
-				checkResults: make(map[string][]interface{}),
-			},
-			Concurrency: concurrency, // default to CPU count
-		},
+		inner,
 	)
 }
 
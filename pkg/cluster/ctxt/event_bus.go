@@ -35,6 +35,8 @@ const (
 	EventTaskFinish EventKind = "task_finish"
 	// EventTaskProgress is emitted when a task has made some progress.
 	EventTaskProgress EventKind = "task_progress"
+	// EventDrainProgress is emitted when draining an instance has made some progress.
+	EventDrainProgress EventKind = "drain_progress"
 )
 
 // NewEventBus creates a new EventBus.
@@ -62,6 +64,12 @@ func (ev *EventBus) PublishTaskProgress(task fmt.Stringer, progress string) {
 	ev.eventBus.Publish(string(EventTaskProgress), task, progress)
 }
 
+// PublishDrainProgress publishes a DrainProgress event of the instance.
+func (ev *EventBus) PublishDrainProgress(instance string, progress interface{}) {
+	zap.L().Debug("DrainProgress", zap.String("instance", instance), zap.Any("progress", progress))
+	ev.eventBus.Publish(string(EventDrainProgress), instance, progress)
+}
+
 // Subscribe subscribes events.
 func (ev *EventBus) Subscribe(eventName EventKind, handler interface{}) {
 	err := ev.eventBus.Subscribe(string(eventName), handler)
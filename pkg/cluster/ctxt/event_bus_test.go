@@ -0,0 +1,47 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctxt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainProgressEvent(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := logprinter.NewLogger("json")
+	logger.SetStdout(out)
+
+	ctx := New(context.Background(), 0, logger)
+	GetInner(ctx).Ev.PublishDrainProgress("172.16.5.140:8300", map[string]int{"tables": 3})
+
+	var event struct {
+		Event   string         `json:"event"`
+		Payload map[string]int `json:"payload"`
+	}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &event))
+	assert.Equal(t, string(EventDrainProgress), event.Event)
+	assert.Equal(t, 3, event.Payload["tables"])
+
+	// the progress is printed as the message in the other modes
+	out.Reset()
+	logger.SetDisplayMode(logprinter.DisplayModePlain)
+	GetInner(ctx).Ev.PublishDrainProgress("172.16.5.140:8300", "1/3 tables transferred")
+	assert.Equal(t, "\t 172.16.5.140:8300: 1/3 tables transferred\n", out.String())
+}
@@ -43,6 +43,10 @@ var (
 	// SSHTypeNone is the type of local executor (no ssh will be used)
 	SSHTypeNone SSHType = "none"
 
+	// SSHTypeSimulate is the type of simulate executor, the commands are not
+	// executed but answered by the simulator
+	SSHTypeSimulate SSHType = "simulate"
+
 	executeDefaultTimeout = time.Second * 60
 
 	// This command will be execute once the NativeSSHExecutor is created.
@@ -54,6 +58,11 @@ var (
 	defaultSSHAuthorizedKeys = "~/.ssh/authorized_keys"
 )
 
+// UseSSH returns if the executors of the type connect to the hosts with SSH
+func (t SSHType) UseSSH() bool {
+	return t != SSHTypeNone && t != SSHTypeSimulate
+}
+
 // New create a new Executor
 func New(etype SSHType, sudo bool, c SSHConfig) (ctxt.Executor, error) {
 	if etype == "" {
@@ -111,6 +120,14 @@ func New(etype SSHType, sudo bool, c SSHConfig) (ctxt.Executor, error) {
 			Locale: "C",
 		}
 		executor = e
+	case SSHTypeSimulate:
+		if simulator == nil {
+			return nil, errors.New("no simulator set for the simulate executor")
+		}
+		executor = &SimulateExecutor{
+			Config:    &c,
+			Simulator: simulator,
+		}
 	default:
 		return nil, errors.Errorf("unregistered executor: %s", etype)
 	}
@@ -0,0 +1,46 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+)
+
+// Factory creates the executor connecting to the host of the config, it
+// replaces New for the programs embedding the cluster operations, e.g. to
+// connect the hosts through their own transport.
+type Factory func(etype SSHType, sudo bool, c SSHConfig) (ctxt.Executor, error)
+
+type factoryKey struct{}
+
+// WithFactory returns a copy of ctx in which the executors are created by f
+func WithFactory(ctx context.Context, f Factory) context.Context {
+	return context.WithValue(ctx, factoryKey{}, f)
+}
+
+// NewFromContext creates the executor with the factory in ctx, or with New
+// if there is no one
+func NewFromContext(ctx context.Context, etype SSHType, sudo bool, c SSHConfig) (ctxt.Executor, error) {
+	f, ok := ctx.Value(factoryKey{}).(Factory)
+	if !ok || f == nil {
+		return New(etype, sudo, c)
+	}
+	e, err := f(etype, sudo, c)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckPointExecutor{e, &c}, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSimulator struct{}
+
+func (fakeSimulator) Execute(host, cmd string, sudo bool) ([]byte, []byte, error) {
+	return []byte(host + ":" + cmd), nil, nil
+}
+
+func (fakeSimulator) Transfer(host, src, dst string, download bool) error {
+	return nil
+}
+
+func TestNewFromContext(t *testing.T) {
+	var hosts []string
+	factory := func(etype SSHType, sudo bool, c SSHConfig) (ctxt.Executor, error) {
+		if c.Host == "" {
+			return nil, errors.New("no host")
+		}
+		hosts = append(hosts, c.Host)
+		return &SimulateExecutor{Config: &c, Simulator: fakeSimulator{}}, nil
+	}
+	ctx := WithFactory(ctxt.New(context.Background(), 0, logprinter.NewLogger("")), factory)
+
+	e, err := NewFromContext(ctx, SSHTypeBuiltin, false, SSHConfig{Host: "10.0.0.1"})
+	require.Nil(t, err)
+	stdout, _, err := e.Execute(ctx, "ls", false)
+	require.Nil(t, err)
+	require.Equal(t, "10.0.0.1:ls", string(stdout))
+	require.Equal(t, []string{"10.0.0.1"}, hosts)
+
+	_, err = NewFromContext(ctx, SSHTypeBuiltin, false, SSHConfig{})
+	require.NotNil(t, err)
+
+	// New is used without the factory
+	_, err = NewFromContext(context.Background(), SSHTypeSimulate, false, SSHConfig{Host: "10.0.0.1"})
+	require.NotNil(t, err)
+}
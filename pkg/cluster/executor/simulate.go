@@ -0,0 +1,54 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+)
+
+// Simulator answers the commands and transfers of the simulate executor
+type Simulator interface {
+	Execute(host, cmd string, sudo bool) (stdout []byte, stderr []byte, err error)
+	Transfer(host, src, dst string, download bool) error
+}
+
+// the simulator used by the simulate executors
+var simulator Simulator
+
+// SetSimulator sets the simulator of the executors with SSHTypeSimulate
+func SetSimulator(s Simulator) {
+	simulator = s
+}
+
+// SimulateExecutor doesn't connect to the host, the commands and transfers
+// are answered by the simulator
+type SimulateExecutor struct {
+	Config    *SSHConfig
+	Simulator Simulator
+}
+
+var _ ctxt.Executor = &SimulateExecutor{}
+
+// Execute implements Executor interface.
+func (e *SimulateExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return e.Simulator.Execute(e.Config.Host, cmd, sudo)
+}
+
+// Transfer implements Executer interface.
+func (e *SimulateExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	return e.Simulator.Transfer(e.Config.Host, src, dst, download)
+}
@@ -0,0 +1,195 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// ApplyOptions contains the options of applying a desired state
+type ApplyOptions struct {
+	DryRun bool // only print the plan
+	Deploy DeployOptions
+}
+
+// Apply converges the cluster to the desired state in file. The new
+// instances are scaled out, the instances absent in the desired state are
+// scaled in, then the changed specs are saved and reloaded, or the cluster
+// is upgraded if the version is changed.
+func (m *Manager) Apply(
+	name string,
+	file string,
+	opt ApplyOptions,
+	afterDeploy func(b *task.Builder, newPart spec.Topology, gOpt operator.Options),
+	final func(b *task.Builder, name string, meta spec.Metadata, gOpt operator.Options),
+	scale func(b *task.Builder, metadata spec.Metadata, gOpt operator.Options, tlsCfg *tls.Config),
+	skipConfirm bool,
+	gOpt operator.Options,
+) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	// the stages share the lock, so no operation could run in between
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+
+	desired := m.newTopology()
+	version, err := spec.ParseDesiredState(file, desired)
+	if err != nil {
+		return err
+	}
+	if version != "" {
+		if version, err = utils.FmtVer(version); err != nil {
+			return err
+		}
+	}
+	plan, err := spec.PlanApply(metadata.GetTopology(), desired, m.newTopology)
+	if err != nil {
+		return err
+	}
+	if err := utils.ValidateSpecDiff(plan.Current, plan.Target); err != nil {
+		return perrs.Annotate(err, "the desired state can't be applied")
+	}
+
+	current := metadata.GetBaseMeta().Version
+	upgrade := version != "" && version != current
+	if plan.Converged() && !upgrade {
+		m.logger.Infof("Cluster `%s` is already in the desired state", name)
+		return nil
+	}
+	printApplyPlan(m.stdout, name, current, version, upgrade, plan)
+	if opt.DryRun {
+		return nil
+	}
+	if !skipConfirm {
+		if err := m.prompter.PromptForConfirmOrAbortError("Do you want to apply the plan? [y/N]: "); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.NewNodes) > 0 {
+		f, err := os.CreateTemp("", "tiup-apply-*.yaml")
+		if err != nil {
+			return perrs.AddStack(err)
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.Write(plan.ScaleOut); err != nil {
+			f.Close()
+			return perrs.AddStack(err)
+		}
+		if err := f.Close(); err != nil {
+			return perrs.AddStack(err)
+		}
+		if err := m.ScaleOut(name, f.Name(), afterDeploy, final, opt.Deploy, true, gOpt); err != nil {
+			return perrs.Annotate(err, "failed to scale out the new instances")
+		}
+	}
+
+	if len(plan.ScaleIn) > 0 {
+		scaleOpt := gOpt
+		scaleOpt.Nodes = plan.ScaleIn
+		if err := m.ScaleIn(name, true, scaleOpt, func(b *task.Builder, metadata spec.Metadata, tlsCfg *tls.Config) {
+			scale(b, metadata, scaleOpt, tlsCfg)
+		}); err != nil {
+			return perrs.Annotate(err, "failed to scale in the instances absent in the desired state")
+		}
+	}
+
+	if len(plan.Changes) > 0 {
+		// plan again as the new instances are appended to the topology
+		if metadata, err = m.meta(name); err != nil {
+			return err
+		}
+		if plan, err = spec.PlanApply(metadata.GetTopology(), desired, m.newTopology); err != nil {
+			return err
+		}
+		if err := metadata.SetTopology(plan.Target); err != nil {
+			return err
+		}
+		if err := m.specManager.SaveMeta(name, metadata); err != nil {
+			return perrs.Annotate(err, "failed to save meta")
+		}
+	}
+
+	reloadOpt := gOpt
+	reloadOpt.Roles = nil
+	reloadOpt.Nodes = nil
+	switch {
+	case upgrade:
+		// the configs are refreshed by upgrading
+		if err := m.Upgrade(name, version, gOpt, true, false); err != nil {
+			return err
+		}
+	case plan.ReloadAll:
+		if err := m.Reload(name, reloadOpt, false, true); err != nil {
+			return err
+		}
+	case len(plan.Reload) > 0:
+		reloadOpt.Nodes = plan.Reload
+		if err := m.Reload(name, reloadOpt, false, true); err != nil {
+			return err
+		}
+	}
+
+	m.logger.Infof("Applied the desired state to cluster `%s` successfully", name)
+	return nil
+}
+
+func (m *Manager) newTopology() spec.Topology {
+	return m.specManager.NewMetadata().GetTopology()
+}
+
+func printApplyPlan(w io.Writer, name, current, version string, upgrade bool, plan *spec.ApplyPlan) {
+	fmt.Fprintf(w, "Plan to converge cluster %s:\n", color.HiYellowString(name))
+	if len(plan.NewNodes) > 0 {
+		fmt.Fprintf(w, "%s scale out: %s\n", color.GreenString("+"), strings.Join(plan.NewNodes, ", "))
+	}
+	if len(plan.ScaleIn) > 0 {
+		fmt.Fprintf(w, "%s scale in: %s\n", color.RedString("-"), strings.Join(plan.ScaleIn, ", "))
+	}
+	if len(plan.Changes) > 0 {
+		fmt.Fprintf(w, "%s change:\n", color.YellowString("~"))
+		for _, c := range plan.Changes {
+			fmt.Fprintf(w, "    %s\n", c)
+		}
+	}
+	switch {
+	case upgrade:
+		fmt.Fprintf(w, "%s upgrade: %s -> %s\n", color.YellowString("~"), current, version)
+	case plan.ReloadAll:
+		fmt.Fprintf(w, "%s reload: all instances\n", color.YellowString("~"))
+	case len(plan.Reload) > 0:
+		fmt.Fprintf(w, "%s reload: %s\n", color.YellowString("~"), strings.Join(plan.Reload, ", "))
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// baselineDegradation is the ratio of degradation highlighted in comparison
+const baselineDegradation = 0.2
+
+// RecordBaseline runs the micro benchmarks on the hosts of the cluster and
+// saves the result as the baseline in the meta
+func (m *Manager) RecordBaseline(name string, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	meta, ok := metadata.(*spec.ClusterMeta)
+	if !ok {
+		return perrs.New("baseline is not supported by the cluster")
+	}
+
+	record, err := m.runBaseline(name, metadata, gOpt)
+	if err != nil {
+		return err
+	}
+	meta.Baseline = record
+	if err := m.specManager.SaveMeta(name, meta); err != nil {
+		return err
+	}
+	m.logger.Infof("Baseline of %d hosts recorded for cluster %s", len(record.Hosts), name)
+	return nil
+}
+
+// CompareBaseline runs the micro benchmarks and compares the result with the
+// baseline recorded
+func (m *Manager) CompareBaseline(name string, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	meta, ok := metadata.(*spec.ClusterMeta)
+	if !ok || meta.Baseline == nil {
+		return perrs.Errorf("no baseline recorded for cluster %s, run `baseline record %s` first", name, name)
+	}
+
+	current, err := m.runBaseline(name, metadata, gOpt)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(m.stdout, "Baseline recorded at: %s\n", meta.Baseline.Time.Local().Format(time.RFC3339))
+	table := [][]string{{"Host", "Metric", "Baseline", "Current", "Degradation"}}
+	for _, d := range spec.CompareBaseline(meta.Baseline, current) {
+		change := fmt.Sprintf("%+.1f%%", d.Change*100)
+		if d.Change >= baselineDegradation {
+			change = color.HiRedString(change)
+		}
+		table = append(table, []string{
+			d.Host,
+			d.Metric,
+			fmt.Sprintf("%.2f", d.Baseline),
+			fmt.Sprintf("%.2f", d.Current),
+			change,
+		})
+	}
+	tui.FprintTable(m.stdout, table, true)
+	return nil
+}
+
+// runBaseline runs the micro benchmarks on every host of the cluster, the
+// fsync latency is measured in the data dirs on the host, or the deploy dir
+// of the first instance if there is no data dir on the host
+func (m *Manager) runBaseline(name string, metadata spec.Metadata, gOpt operator.Options) (*spec.BaselineRecord, error) {
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+	deployUser := topo.BaseTopo().GlobalOptions.User
+
+	dataDirs := make(map[string]set.StringSet)
+	deployDirs := make(map[string]string)
+	topo.IterInstance(func(inst spec.Instance) {
+		host := inst.GetHost()
+		if _, ok := deployDirs[host]; !ok {
+			deployDirs[host] = inst.DeployDir()
+			dataDirs[host] = set.NewStringSet()
+		}
+		for _, dir := range spec.MultiDirAbs(deployUser, inst.DataDir()) {
+			dataDirs[host].Insert(dir)
+		}
+	})
+	hosts := make([]string, 0, len(deployDirs))
+	hostDirs := make(map[string][]string)
+	for host := range deployDirs {
+		hosts = append(hosts, host)
+		hostDirs[host] = dataDirs[host].Slice()
+		if len(hostDirs[host]) == 0 {
+			hostDirs[host] = []string{deployDirs[host]}
+		}
+		sort.Strings(hostDirs[host])
+	}
+	sort.Strings(hosts)
+
+	var shellTasks []*task.StepDisplay
+	for _, host := range hosts {
+		peers := make([]string, 0, len(hosts)-1)
+		for _, peer := range hosts {
+			if peer != host {
+				peers = append(peers, peer)
+			}
+		}
+		shellTasks = append(shellTasks, task.NewBuilder(m.logger).
+			Shell(host, spec.BaselineScript(hostDirs[host], peers), host, false).
+			BuildAsStep(fmt.Sprintf("  - Benchmark %s", host)))
+	}
+
+	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
+	if err != nil {
+		return nil, err
+	}
+	t := b.ParallelStep("+ Run micro benchmarks", false, shellTasks...).Build()
+
+	ctx := ctxt.New(
+		m.baseContext(name, gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return nil, err
+		}
+		return nil, perrs.Trace(err)
+	}
+
+	record := &spec.BaselineRecord{
+		Time:  time.Now(),
+		Hosts: make(map[string]*spec.HostBaseline),
+	}
+	for _, host := range hosts {
+		stdout, _, ok := ctxt.GetInner(ctx).GetOutputs(host)
+		if !ok {
+			return nil, perrs.Errorf("no benchmark result found for %s", host)
+		}
+		hb, err := spec.ParseHostBaseline(string(stdout))
+		if err != nil {
+			return nil, perrs.Annotatef(err, "benchmark on %s", host)
+		}
+		record.Hosts[host] = hb
+	}
+	return record, nil
+}
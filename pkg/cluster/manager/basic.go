@@ -24,6 +24,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -70,7 +71,7 @@ func (m *Manager) EnableCluster(name string, gOpt operator.Options, isEnable boo
 	t := b.Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -100,6 +101,12 @@ func (m *Manager) StartCluster(name string, gOpt operator.Options, restoreLeader
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return err
@@ -121,6 +128,18 @@ func (m *Manager) StartCluster(name string, gOpt operator.Options, restoreLeader
 	b.Func("StartCluster", func(ctx context.Context) error {
 		return operator.Start(ctx, topo, gOpt, restoreLeader, tlsCfg)
 	})
+	if cluster, ok := topo.(*spec.Specification); ok && len(cluster.GlobalOptions.Dashboard.PDHosts) > 0 {
+		b.Func("EnsureDashboardPlacement", func(ctx context.Context) error {
+			m.ensureDashboardPlacement(ctx, cluster, tlsCfg, gOpt)
+			return nil
+		})
+	}
+	if cluster, ok := topo.(*spec.Specification); ok && len(cluster.GlobalOptions.ReadOnlyZones) > 0 {
+		b.Func("EnsureReadOnlyZoneRules", func(ctx context.Context) error {
+			m.ensureReadOnlyZoneRules(ctx, cluster, tlsCfg, gOpt)
+			return nil
+		})
+	}
 
 	for _, f := range fn {
 		f(b, metadata)
@@ -129,7 +148,7 @@ func (m *Manager) StartCluster(name string, gOpt operator.Options, restoreLeader
 	t := b.Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -141,6 +160,7 @@ func (m *Manager) StartCluster(name string, gOpt operator.Options, restoreLeader
 		return perrs.Trace(err)
 	}
 
+	m.recordRestarts(name, metadata, restartReasonStart, gOpt)
 	m.logger.Infof("Started cluster `%s` successfully", name)
 	return nil
 }
@@ -157,6 +177,12 @@ func (m *Manager) StopCluster(
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return err
@@ -171,7 +197,7 @@ func (m *Manager) StopCluster(
 	}
 
 	if !skipConfirm {
-		if err := tui.PromptForConfirmOrAbortError(
+		if err := m.prompter.PromptForConfirmOrAbortError(
 			fmt.Sprintf("Will stop the cluster %s with nodes: %s, roles: %s.\nDo you want to continue? [y/N]:",
 				color.HiYellowString(name),
 				color.HiRedString(strings.Join(gOpt.Nodes, ",")),
@@ -194,7 +220,7 @@ func (m *Manager) StopCluster(
 		Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -217,6 +243,12 @@ func (m *Manager) RestartCluster(name string, gOpt operator.Options, skipConfirm
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return err
@@ -231,7 +263,7 @@ func (m *Manager) RestartCluster(name string, gOpt operator.Options, skipConfirm
 	}
 
 	if !skipConfirm {
-		if err := tui.PromptForConfirmOrAbortError(
+		if err := m.prompter.PromptForConfirmOrAbortError(
 			fmt.Sprintf("Will restart the cluster %s with nodes: %s roles: %s.\nCluster will be unavailable\nDo you want to continue? [y/N]:",
 				color.HiYellowString(name),
 				color.HiYellowString(strings.Join(gOpt.Nodes, ",")),
@@ -252,11 +284,13 @@ func (m *Manager) RestartCluster(name string, gOpt operator.Options, skipConfirm
 		}).
 		Build()
 
-	ctx := ctxt.New(
-		context.Background(),
+	// the status queried by the restart hooks of each instance is cached,
+	// and dropped once an instance is stopped or started
+	ctx := api.WithCacheContext(ctxt.New(
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
-	)
+	), api.DefaultCacheTTL)
 	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
@@ -265,6 +299,7 @@ func (m *Manager) RestartCluster(name string, gOpt operator.Options, skipConfirm
 		return perrs.Trace(err)
 	}
 
+	m.recordRestarts(name, metadata, "restart", gOpt)
 	m.logger.Infof("Restarted cluster `%s` successfully", name)
 	return nil
 }
@@ -289,6 +324,17 @@ func getMonitorHosts(topo spec.Topology) (map[string]hostInfo, set.StringSet) {
 		}
 	})
 
+	// the extra hosts only running the monitoring agents
+	for _, h := range spec.MonitoredHosts(topo) {
+		if _, found := uniqueHosts[h.Host]; !found {
+			uniqueHosts[h.Host] = hostInfo{
+				ssh:  h.SSHPort,
+				os:   h.OS,
+				arch: h.Arch,
+			}
+		}
+	}
+
 	return uniqueHosts, noAgentHosts
 }
 
@@ -358,7 +404,7 @@ func (m *Manager) RestoreClusterMeta(clusterName, filePath string, skipConfirm b
 	}
 	m.logger.Warnf(fmt.Sprintf("the given tarball was last modified at %s", color.HiYellowString(fi.ModTime().Format(time.RFC3339))))
 	if !skipConfirm {
-		if err := tui.PromptForAnswerOrAbortError(
+		if err := m.prompter.PromptForAnswerOrAbortError(
 			"Yes, I know my cluster meta will be be overridden.",
 			fmt.Sprintf("This operation will override topology file and other meta file of %s cluster %s .",
 				m.sysName,
@@ -383,3 +429,42 @@ func (m *Manager) RestoreClusterMeta(clusterName, filePath string, skipConfirm b
 	}
 	return err
 }
+
+// EncryptClusterMeta encrypts the topology, private keys and certificates in
+// the meta directory of the cluster, they are decrypted transparently with the
+// key specified by environment variables when operating the cluster.
+func (m *Manager) EncryptClusterMeta(clusterName string) error {
+	if _, err := m.meta(clusterName); err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return err
+	}
+	if m.specManager.IsEncrypted(clusterName) {
+		m.logger.Infof("meta of cluster %s is already encrypted", clusterName)
+		return nil
+	}
+	if err := m.specManager.EncryptStorage(clusterName); err != nil {
+		return err
+	}
+	m.logger.Infof("encrypted meta of cluster %s successfully", clusterName)
+	return nil
+}
+
+// DecryptClusterMeta decrypts the meta directory of the cluster encrypted by
+// EncryptClusterMeta
+func (m *Manager) DecryptClusterMeta(clusterName string) error {
+	if _, err := m.meta(clusterName); err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return err
+	}
+	if !m.specManager.IsEncrypted(clusterName) {
+		m.logger.Infof("meta of cluster %s is not encrypted", clusterName)
+		return nil
+	}
+	// write back the runtime files first, they may be newer than the storage
+	if err := m.specManager.Lock(clusterName); err != nil {
+		return err
+	}
+	if err := m.specManager.DecryptStorage(clusterName); err != nil {
+		return err
+	}
+	m.logger.Infof("decrypted meta of cluster %s successfully", clusterName)
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -99,15 +100,22 @@ func buildScaleOutTask(
 		return nil, err
 	}
 
+	etcHosts, err := spec.EtcHostsEntries(mergedTopo)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize the environments
 	initializedHosts := set.NewStringSet()
 	metadata.GetTopology().IterInstance(func(instance spec.Instance) {
 		initializedHosts.Insert(instance.GetHost())
 	})
+	for _, h := range spec.MonitoredHosts(metadata.GetTopology()) {
+		initializedHosts.Insert(h.Host)
+	}
 	// uninitializedHosts are hosts which haven't been initialized yet
 	uninitializedHosts := make(map[string]hostInfo) // host -> ssh-port, os, arch
-	newPart.IterInstance(func(instance spec.Instance) {
-		host := instance.GetHost()
+	initHost := func(host string, info hostInfo) {
 		if initializedHosts.Exist(host) {
 			return
 		}
@@ -115,11 +123,7 @@ func buildScaleOutTask(
 			return
 		}
 
-		uninitializedHosts[host] = hostInfo{
-			ssh:  instance.GetSSHPort(),
-			os:   instance.OS(),
-			arch: instance.Arch(),
-		}
+		uninitializedHosts[host] = info
 
 		var dirs []string
 		globalOptions := metadata.GetTopology().BaseTopo().GlobalOptions
@@ -134,8 +138,8 @@ func buildScaleOutTask(
 
 		t := task.NewBuilder(m.logger).
 			RootSSH(
-				instance.GetHost(),
-				instance.GetSSHPort(),
+				host,
+				info.ssh,
 				opt.User,
 				s.Password,
 				s.IdentityFile,
@@ -152,11 +156,28 @@ func buildScaleOutTask(
 				gOpt.SSHType,
 				globalOptions.SSHType,
 			).
-			EnvInit(instance.GetHost(), base.User, base.Group, opt.SkipCreateUser || globalOptions.User == opt.User).
-			Mkdir(globalOptions.User, instance.GetHost(), dirs...).
+			EnvInit(host, base.User, base.Group, opt.SkipCreateUser || globalOptions.User == opt.User, globalOptions.DeployUser.ForHost(host)).
+			HostState(host, globalOptions.HostState).
+			EtcHosts(host, name, etcHosts).
+			Mkdir(globalOptions.User, host, dirs...).
 			BuildAsStep(fmt.Sprintf("  - Initialized host %s ", host))
 		envInitTasks = append(envInitTasks, t)
+	}
+	newPart.IterInstance(func(instance spec.Instance) {
+		initHost(instance.GetHost(), hostInfo{
+			ssh:  instance.GetSSHPort(),
+			os:   instance.OS(),
+			arch: instance.Arch(),
+		})
 	})
+	// the extra hosts only running the monitoring agents
+	for _, h := range spec.MonitoredHosts(newPart) {
+		initHost(h.Host, hostInfo{
+			ssh:  h.SSHPort,
+			os:   h.OS,
+			arch: h.Arch,
+		})
+	}
 
 	// Download missing component
 	downloadCompTasks = buildDownloadCompTasks(
@@ -324,6 +345,8 @@ func buildScaleOutTask(
 		builder.
 			ParallelStep("+ Download TiDB components", gOpt.Force, downloadCompTasks...).
 			ParallelStep("+ Initialize target host environments", gOpt.Force, envInitTasks...).
+			ParallelStep("+ Update /etc/hosts of existing hosts", gOpt.Force,
+				buildEtcHostsTasks(m, name, initializedHosts.Slice(), etcHosts)...).
 			ParallelStep("+ Deploy TiDB instance", gOpt.Force, deployCompTasks...).
 			ParallelStep("+ Copy certificate to remote host", gOpt.Force, certificateTasks...).
 			ParallelStep("+ Generate scale-out config", gOpt.Force, scaleOutConfigTasks...).
@@ -335,7 +358,9 @@ func buildScaleOutTask(
 	}
 
 	builder.Func("Save meta", func(_ context.Context) error {
-		metadata.SetTopology(mergedTopo)
+		if err := metadata.SetTopology(mergedTopo); err != nil {
+			return err
+		}
 		return m.specManager.SaveMeta(name, metadata)
 	})
 
@@ -375,6 +400,23 @@ func buildScaleOutTask(
 	return builder.Build(), nil
 }
 
+// buildEtcHostsTasks updates the entries of the cluster in /etc/hosts on the
+// hosts, no task is generated if the entries are not managed, i.e. nil
+func buildEtcHostsTasks(m *Manager, name string, hosts []string, entries map[string]string) []*task.StepDisplay {
+	if entries == nil {
+		return nil
+	}
+
+	sort.Strings(hosts)
+	tasks := make([]*task.StepDisplay, 0, len(hosts))
+	for _, host := range hosts {
+		tasks = append(tasks, task.NewBuilder(m.logger).
+			EtcHosts(host, name, entries).
+			BuildAsStep(fmt.Sprintf("  - Update /etc/hosts on %s", host)))
+	}
+	return tasks
+}
+
 // buildScaleConfigTasks  generates certificate for instance and transfers it to the server
 func buildScaleConfigTasks(
 	m *Manager,
@@ -405,9 +447,10 @@ func buildScaleConfigTasks(
 				inst,
 				base.User,
 				meta.DirPaths{
-					Deploy: deployDir,
-					Data:   dataDirs,
-					Log:    logDir,
+					Deploy:    deployDir,
+					Data:      dataDirs,
+					Log:       logDir,
+					Templates: m.specManager.Path(name, spec.TemplateDirName),
 				},
 			).BuildAsStep(fmt.Sprintf("  - Generate scale-out config %s -> %s", inst.ComponentName(), inst.ID()))
 		scaleConfigTasks = append(scaleConfigTasks, t)
@@ -599,10 +642,11 @@ func buildInitMonitoredConfigTasks(
 					globalOptions.User,
 					globalOptions.TLSEnabled,
 					meta.DirPaths{
-						Deploy: deployDir,
-						Data:   []string{dataDir},
-						Log:    logDir,
-						Cache:  specManager.Path(name, spec.TempConfigPath),
+						Deploy:    deployDir,
+						Data:      []string{dataDir},
+						Log:       logDir,
+						Cache:     specManager.Path(name, spec.TempConfigPath),
+						Templates: specManager.Path(name, spec.TemplateDirName),
 					},
 				).
 				BuildAsStep(fmt.Sprintf("  - Generate config %s -> %s", comp, host))
@@ -664,10 +708,11 @@ func buildInitConfigTasks(
 				base.User,
 				gOpt.IgnoreConfigCheck,
 				meta.DirPaths{
-					Deploy: deployDir,
-					Data:   dataDirs,
-					Log:    logDir,
-					Cache:  m.specManager.Path(name, spec.TempConfigPath),
+					Deploy:    deployDir,
+					Data:      dataDirs,
+					Log:       logDir,
+					Cache:     m.specManager.Path(name, spec.TempConfigPath),
+					Templates: m.specManager.Path(name, spec.TemplateDirName),
 				},
 			).
 			BuildAsStep(fmt.Sprintf("  - Generate config %s -> %s", compName, instance.ID()))
@@ -0,0 +1,325 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alessio/shellescape"
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+)
+
+// ChangefeedOptions are the options to create a changefeed
+type ChangefeedOptions struct {
+	api.ChangefeedConfig
+	SkipSinkCheck bool // do not probe the sink from the cdc node
+}
+
+// ChangefeedInfo is the replication status of a changefeed
+type ChangefeedInfo struct {
+	ID         string `json:"id"`
+	State      string `json:"state"`
+	Checkpoint string `json:"checkpoint"`
+	Lag        string `json:"lag"`
+	Error      string `json:"error,omitempty"`
+}
+
+// the physical part of a TSO is the milliseconds since unix epoch
+const tsoPhysicalShiftBits = 18
+
+// tsoTime returns the physical time of a TSO
+func tsoTime(ts uint64) time.Time {
+	ms := int64(ts >> tsoPhysicalShiftBits)
+	return time.Unix(ms/1e3, (ms%1e3)*1e6)
+}
+
+// sinkHostPattern matches the host names could be probed
+var sinkHostPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9\-_.]*[A-Za-z0-9])?$`)
+
+// splitSinkURI separates the authority from the sink URI, the kafka sink
+// supports multiple brokers separated by comma in the authority which is
+// rejected by url.Parse, the rest is parsed with the authority stripped
+func splitSinkURI(sinkURI string) (string, *url.URL, error) {
+	idx := strings.Index(sinkURI, "://")
+	if idx < 0 {
+		return "", nil, perrs.Errorf("invalid sink uri %s", sinkURI)
+	}
+	scheme, rest := sinkURI[:idx], sinkURI[idx+len("://"):]
+	authority := rest
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority = rest[:i]
+	}
+	rest = rest[len(authority):]
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		authority = authority[i+1:]
+	}
+
+	u, err := url.Parse(scheme + "://" + rest)
+	if err != nil {
+		return "", nil, perrs.Annotatef(err, "invalid sink uri %s", sinkURI)
+	}
+	return authority, u, nil
+}
+
+// validateProbeAddr checks the address is a plain host and port, as it is
+// passed to the shell of the cdc node
+func validateProbeAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return perrs.Annotatef(err, "invalid address %s in sink uri", addr)
+	}
+	if net.ParseIP(host) == nil && !sinkHostPattern.MatchString(host) {
+		return perrs.Errorf("invalid host %s in sink uri", host)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return perrs.Errorf("invalid port %s in sink uri", port)
+	}
+	return nil
+}
+
+// sinkProbeAddrs parses the sink URI and returns the addresses need to be
+// reachable from the cdc node.
+func sinkProbeAddrs(sinkURI string) ([]string, error) {
+	hosts, u, err := splitSinkURI(sinkURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaultPort string
+	switch strings.ToLower(u.Scheme) {
+	case "mysql", "mysql+ssl", "tidb", "tidb+ssl":
+		defaultPort = "3306"
+	case "kafka", "kafka+ssl":
+		// kafka sink supports multiple brokers separated by comma
+		defaultPort = "9092"
+	case "pulsar", "pulsar+ssl":
+		defaultPort = "6650"
+	case "s3", "gcs", "gs", "azblob", "azure":
+		// the bucket is only reachable through the endpoint if it is customized
+		endpoint := u.Query().Get("endpoint")
+		if endpoint == "" {
+			return nil, nil
+		}
+		eu, err := url.Parse(endpoint)
+		if err != nil || eu.Host == "" {
+			return nil, perrs.Errorf("invalid endpoint %s in sink uri", endpoint)
+		}
+		hosts = eu.Host
+		defaultPort = "80"
+		if eu.Scheme == "https" {
+			defaultPort = "443"
+		}
+	case "blackhole", "file", "local", "noop":
+		return nil, nil
+	default:
+		return nil, perrs.Errorf("unsupported sink scheme '%s' in sink uri %s", u.Scheme, sinkURI)
+	}
+
+	if hosts == "" {
+		return nil, perrs.Errorf("no host specified in sink uri %s", sinkURI)
+	}
+
+	var addrs []string
+	for _, h := range strings.Split(hosts, ",") {
+		if h == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(strings.Trim(h, "[]"), defaultPort)
+		}
+		if err := validateProbeAddr(h); err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, h)
+	}
+	return addrs, nil
+}
+
+// CreateChangefeed validates the options and creates a changefeed in the cluster
+func (m *Manager) CreateChangefeed(name string, opt ChangefeedOptions, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo, ok := metadata.GetTopology().(*spec.Specification)
+	if !ok {
+		return perrs.Errorf("cluster %s does not support TiCDC", name)
+	}
+	if len(topo.CDCServers) == 0 {
+		return perrs.Errorf("there is no TiCDC server in cluster %s", name)
+	}
+	if opt.ID == "" {
+		return perrs.New("the changefeed id must be specified")
+	}
+	if opt.TargetTS != 0 && opt.StartTS != 0 && opt.TargetTS <= opt.StartTS {
+		return perrs.Errorf("target-ts %d must be larger than start-ts %d", opt.TargetTS, opt.StartTS)
+	}
+
+	addrs, err := sinkProbeAddrs(opt.SinkURI)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+	apiCtx := m.apiContext(name, gOpt)
+	timeout := api.RequestTimeout(apiCtx, time.Second*time.Duration(gOpt.APITimeout))
+
+	// data before the GC safe point may have been deleted, the changefeed
+	// starting from it would fail to catch up
+	if opt.StartTS != 0 {
+		pdClient := api.NewPDClient(apiCtx, topo.GetPDList(), timeout, tlsCfg)
+		sp, err := pdClient.GetGCSafePoint(apiCtx)
+		if err != nil {
+			return perrs.Annotate(err, "failed to get GC safe point")
+		}
+		if safePoint := sp.SafePoint(); opt.StartTS < safePoint {
+			return perrs.Errorf("start-ts %d (%s) is earlier than the GC safe point %d (%s), the data may have been garbage collected",
+				opt.StartTS, tsoTime(opt.StartTS).Format(time.RFC3339),
+				safePoint, tsoTime(safePoint).Format(time.RFC3339))
+		}
+	}
+
+	if !opt.SkipSinkCheck && len(addrs) > 0 {
+		if err := m.probeSink(name, topo, metadata.GetBaseMeta().User, addrs, gOpt); err != nil {
+			return err
+		}
+	}
+
+	client := api.NewCDCOpenAPIClient(topo.GetCDCList(), api.RequestTimeout(apiCtx, timeout), tlsCfg)
+	if err := client.CreateChangefeed(apiCtx, &opt.ChangefeedConfig); err != nil {
+		return perrs.Annotatef(err, "failed to create changefeed %s", opt.ID)
+	}
+
+	m.logger.Infof("Changefeed %s is created successfully", color.YellowString(opt.ID))
+	return nil
+}
+
+// probeSink checks the sink addresses are reachable from the first cdc node,
+// the changefeed would be created but keep failing otherwise.
+func (m *Manager) probeSink(name string, topo *spec.Specification, user string, addrs []string, gOpt operator.Options) error {
+	cdc := topo.CDCServers[0]
+	m.logger.Infof("Probing the sink from TiCDC node %s", color.CyanString(cdc.Host))
+
+	probeTasks := make([]task.Task, 0, len(addrs))
+	for _, addr := range addrs {
+		if err := validateProbeAddr(addr); err != nil {
+			return err
+		}
+		host, port, _ := net.SplitHostPort(addr)
+		// the command always succeed, the result is reported by the output
+		probe := shellescape.Quote(fmt.Sprintf("</dev/tcp/%s/%s", host, port))
+		cmd := fmt.Sprintf(`timeout 5 bash -c %s >/dev/null 2>&1 && echo ok || echo failed`, probe)
+		probeTasks = append(probeTasks,
+			task.NewBuilder(m.logger).
+				Shell(cdc.Host, cmd, "probe-sink-"+addr, false).
+				Build())
+	}
+
+	b, err := m.sshTaskBuilder(name, topo, user, gOpt)
+	if err != nil {
+		return err
+	}
+	t := b.Parallel(false, probeTasks...).Build()
+
+	ctx := ctxt.New(
+		m.baseContext(name, gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	var unreachable []string
+	for _, addr := range addrs {
+		stdout, _, ok := ctxt.GetInner(ctx).GetOutputs("probe-sink-" + addr)
+		if !ok || strings.TrimSpace(string(stdout)) != "ok" {
+			unreachable = append(unreachable, addr)
+		}
+	}
+	if len(unreachable) > 0 {
+		return perrs.Errorf("sink %s is not reachable from TiCDC node %s, use --skip-sink-check to skip this check",
+			strings.Join(unreachable, ","), cdc.Host)
+	}
+	return nil
+}
+
+// getChangefeedInfos queries the changefeeds of the cluster, the lag is the
+// duration between now and the checkpoint of each changefeed.
+func (m *Manager) getChangefeedInfos(apiCtx context.Context, topo *spec.Specification, tlsCfg *tls.Config, timeout time.Duration) ([]ChangefeedInfo, error) {
+	if len(topo.CDCServers) == 0 {
+		return nil, nil
+	}
+
+	client := api.NewCDCOpenAPIClient(topo.GetCDCList(), api.RequestTimeout(apiCtx, timeout), tlsCfg)
+	changefeeds, err := client.GetAllChangefeeds(apiCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]ChangefeedInfo, 0, len(changefeeds))
+	for _, cf := range changefeeds {
+		info := ChangefeedInfo{
+			ID:         cf.ID,
+			State:      cf.FeedState,
+			Checkpoint: "-",
+			Lag:        "-",
+		}
+		if cf.Namespace != "" && cf.Namespace != "default" {
+			info.ID = cf.Namespace + "/" + cf.ID
+		}
+		if cf.CheckpointTSO > 0 {
+			checkpoint := tsoTime(cf.CheckpointTSO)
+			info.Checkpoint = checkpoint.Format("2006-01-02 15:04:05")
+			info.Lag = now.Sub(checkpoint).Truncate(time.Second).String()
+		}
+		if cf.RunningError != nil {
+			info.Error = cf.RunningError.Message
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
@@ -14,10 +14,14 @@
 package manager
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,13 +30,13 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
 	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/tui"
+	"gopkg.in/yaml.v3"
 )
 
 // CheckOptions contains the options for check command
@@ -43,17 +47,29 @@ type CheckOptions struct {
 	Opr          *operator.CheckOptions
 	ApplyFix     bool // try to apply fixes of failed checks
 	ExistCluster bool // check an exist cluster
+	CloudLabels  bool // verify the TiKV labels with the cloud metadata
+	// ReportTo is the destination of the check report, see pushCheckReport
+	ReportTo string
+
+	numaBindings map[string]string // instance id -> suggested numa_node
+	results      []HostCheckResult
 }
 
 // CheckCluster check cluster before deploying or upgrading
 func (m *Manager) CheckCluster(clusterOrTopoName, scaleoutTopo string, opt CheckOptions, gOpt operator.Options) error {
 	var topo spec.Specification
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(clusterOrTopoName, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
 	var currTopo *spec.Specification
+	var metadata *spec.ClusterMeta
+	topoFile := ""
+
+	if gOpt.Viewer && opt.ApplyFix {
+		return perrs.New("the fixes of failed checks can't be applied in the viewer mode")
+	}
 
 	if opt.ExistCluster { // check for existing cluster
 		clusterName := clusterOrTopoName
@@ -67,12 +83,13 @@ func (m *Manager) CheckCluster(clusterOrTopoName, scaleoutTopo string, opt Check
 			return perrs.Errorf("cluster %s does not exist", clusterName)
 		}
 
-		metadata, err := spec.ClusterMetadata(clusterName)
+		metadata, err = spec.ClusterMetadata(clusterName)
 		if err != nil {
 			return err
 		}
 
 		if scaleoutTopo != "" {
+			topoFile = scaleoutTopo
 			currTopo = metadata.Topology
 			// complete global configuration
 			topo.GlobalOptions = currTopo.GlobalOptions
@@ -89,15 +106,23 @@ func (m *Manager) CheckCluster(clusterOrTopoName, scaleoutTopo string, opt Check
 			opt.ExistCluster = false
 		} else {
 			opt.IdentityFile = m.specManager.Path(clusterName, "ssh", "id_rsa")
-			topo = *metadata.Topology
 			opt.User = metadata.User
+			if gOpt.Viewer {
+				// the hosts are checked with the read-only credentials
+				if gOpt.ViewerIdentityFile == "" {
+					return perrs.New("checking the hosts of a cluster requires SSH access, please specify --viewer-identity-file in the viewer mode")
+				}
+				opt.IdentityFile = gOpt.ViewerIdentityFile
+				opt.User = gOpt.ViewerUser
+			}
+			topo = *metadata.Topology
 		}
 
 		topo.AdjustByVersion(metadata.Version)
 	} else { // check before cluster is deployed
-		topoFileName := clusterOrTopoName
+		topoFile = clusterOrTopoName
 
-		if err := spec.ParseTopologyYaml(topoFileName, &topo); err != nil {
+		if err := spec.ParseTopologyYaml(topoFile, &topo); err != nil {
 			return err
 		}
 		spec.ExpandRelativeDir(&topo)
@@ -111,7 +136,7 @@ func (m *Manager) CheckCluster(clusterOrTopoName, scaleoutTopo string, opt Check
 		sshConnProps  *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 	)
-	if gOpt.SSHType != executor.SSHTypeNone {
+	if gOpt.SSHType.UseSSH() {
 		var err error
 		if sshConnProps, err = tui.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword); err != nil {
 			return err
@@ -129,7 +154,10 @@ func (m *Manager) CheckCluster(clusterOrTopoName, scaleoutTopo string, opt Check
 
 	// Abort scale out operation if the merged topology is invalid
 	if currTopo != nil && scaleoutTopo != "" {
-		mergedTopo := currTopo.MergeTopo(&topo)
+		mergedTopo, err := spec.MergeTopology(currTopo, &topo)
+		if err != nil {
+			return err
+		}
 		if err := mergedTopo.Validate(); err != nil {
 			return err
 		}
@@ -138,10 +166,35 @@ func (m *Manager) CheckCluster(clusterOrTopoName, scaleoutTopo string, opt Check
 		}
 	}
 
-	if err := checkSystemInfo(ctx, sshConnProps, sshProxyProps, &topo, &gOpt, &opt); err != nil {
+	if err := m.checkSystemInfo(ctx, sshConnProps, sshProxyProps, &topo, &gOpt, &opt); err != nil {
 		return err
 	}
 
+	if opt.ReportTo != "" {
+		report := newCheckReport(opt.results)
+		if metadata != nil {
+			report.Cluster = clusterOrTopoName
+			report.ClusterVersion = metadata.Version
+		}
+		report.Topology = topoFile
+		dest, err := pushCheckReport(ctx, report, opt.ReportTo)
+		if err != nil {
+			return err
+		}
+		m.logger.Infof("Check report %s is pushed to %s", report.ID, dest)
+	}
+
+	if len(opt.numaBindings) > 0 {
+		if topoFile != "" {
+			if err := writeNUMABindings(topoFile, opt.numaBindings); err != nil {
+				return err
+			}
+			m.logger.Infof("Suggested NUMA bindings are written to %s", topoFile)
+		} else if err := m.applyNUMABindings(clusterOrTopoName, opt.numaBindings, gOpt); err != nil {
+			return err
+		}
+	}
+
 	if !opt.ExistCluster {
 		return nil
 	}
@@ -151,6 +204,74 @@ func (m *Manager) CheckCluster(clusterOrTopoName, scaleoutTopo string, opt Check
 	return m.checkRegionsInfo(clusterOrTopoName, &topo, &gOpt)
 }
 
+// refreshNUMABindings regenerates the run scripts of the instances bound to
+// the suggested NUMA nodes, the bindings take effect after restarting them
+// applyNUMABindings saves the NUMA bindings suggested to the topology of the
+// cluster and refreshes the configs of the instances bound, the meta is
+// re-read under the operation lock as the check may take a while
+func (m *Manager) applyNUMABindings(name string, bindings map[string]string, gOpt operator.Options) error {
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := spec.ClusterMetadata(name)
+	if err != nil {
+		return err
+	}
+	metadata.Topology.IterInstance(func(inst spec.Instance) {
+		if numa, ok := bindings[inst.ID()]; ok {
+			inst.SetNumaNode(numa)
+		}
+	})
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+	m.logger.Infof("Suggested NUMA bindings are saved to the topology of %s", name)
+	return m.refreshNUMABindings(name, metadata, bindings, gOpt)
+}
+
+func (m *Manager) refreshNUMABindings(name string, metadata *spec.ClusterMeta, bindings map[string]string, gOpt operator.Options) error {
+	topo := metadata.Topology
+	var skipped, bound []string
+	topo.IterInstance(func(inst spec.Instance) {
+		if _, ok := bindings[inst.ID()]; ok {
+			bound = append(bound, inst.ID())
+		} else {
+			skipped = append(skipped, inst.ID())
+		}
+	})
+	if len(bound) == 0 {
+		return nil
+	}
+
+	refreshTasks, _ := buildInitConfigTasks(m, name, topo, metadata.GetBaseMeta(), gOpt, skipped)
+	b, err := m.sshTaskBuilder(name, topo, metadata.User, gOpt)
+	if err != nil {
+		return err
+	}
+	t := b.ParallelStep("+ Refresh instance configs", gOpt.Force, refreshTasks...).Build()
+
+	ctx := ctxt.New(
+		m.baseContext(name, gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	sort.Strings(bound)
+	m.logger.Infof("The NUMA bindings take effect after restarting the instances, please use `%s restart %s -N %s`",
+		tui.OsArgs0(), name, strings.Join(bound, ","))
+	return nil
+}
+
 // HostCheckResult represents the check result of each node
 type HostCheckResult struct {
 	Node    string `json:"node"`
@@ -160,7 +281,7 @@ type HostCheckResult struct {
 }
 
 // checkSystemInfo performs series of checks and tests of the deploy server
-func checkSystemInfo(
+func (m *Manager) checkSystemInfo(
 	ctx context.Context,
 	s, p *tui.SSHConnectionProps,
 	topo *spec.Specification,
@@ -392,6 +513,20 @@ func checkSystemInfo(
 						task.CheckTypePackage,
 						topo,
 						opt.Opr,
+					).
+					// check for NUMA binding
+					Shell(
+						inst.GetHost(),
+						"numactl --hardware 2>/dev/null || true",
+						"",
+						false,
+					).
+					CheckSys(
+						inst.GetHost(),
+						"",
+						task.CheckTypeNUMA,
+						topo,
+						opt.Opr,
 					)
 
 				if !opt.ExistCluster {
@@ -494,7 +629,24 @@ func checkSystemInfo(
 		applyFixTasks = append(applyFixTasks, tf.BuildAsStep(fmt.Sprintf("  - Applying changes on %s", host)))
 	}
 
+	if opt.Opr.EnableNetwork {
+		netResults, err := checkNetworkMatrix(ctx, s, p, topo, gOpt, opt)
+		if err != nil {
+			return err
+		}
+		checkResults = append(checkResults, netResults...)
+	}
+
+	if opt.CloudLabels {
+		labelResults, err := checkCloudLabels(ctx, s, p, topo, gOpt, opt)
+		if err != nil {
+			return err
+		}
+		checkResults = append(checkResults, labelResults...)
+	}
+
 	checkResults = deduplicateCheckResult(checkResults)
+	opt.results = checkResults
 
 	if gOpt.DisplayMode == "json" {
 		checkResultStruct := make([]HostCheckResult, 0)
@@ -513,13 +665,13 @@ func checkSystemInfo(
 		if err != nil {
 			return err
 		}
-		fmt.Println(string(data))
+		fmt.Fprintln(m.stdout, string(data))
 	} else {
 		resLines := formatHostCheckResults(checkResults)
 		checkResultTable = append(checkResultTable, resLines...)
 		// print check results *before* trying to applying checks
 		// FIXME: add fix result to output, and display the table after fixing
-		tui.PrintTable(checkResultTable, true)
+		tui.FprintTable(m.stdout, checkResultTable, true)
 	}
 
 	if opt.ApplyFix {
@@ -563,7 +715,7 @@ func handleCheckResults(ctx context.Context, host string, opt *CheckOptions, t *
 				items = append(items, item)
 				continue
 			}
-			msg, err := fixFailedChecks(host, r, t)
+			msg, err := fixFailedChecks(host, r, t, opt)
 			if err != nil {
 				ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger).
 					Debugf("%s: fail to apply fix to %s (%s)", host, r.Name, err)
@@ -605,7 +757,7 @@ func formatHostCheckResults(results []HostCheckResult) [][]string {
 }
 
 // fixFailedChecks tries to automatically apply changes to fix failed checks
-func fixFailedChecks(host string, res *operator.CheckResult, t *task.Builder) (string, error) {
+func fixFailedChecks(host string, res *operator.CheckResult, t *task.Builder, opt *CheckOptions) (string, error) {
 	msg := ""
 	switch res.Name {
 	case operator.CheckNameSysService:
@@ -657,6 +809,35 @@ func fixFailedChecks(host string, res *operator.CheckResult, t *task.Builder) (s
 			"", true,
 		)
 		msg = "will try to disable swap, please also check /etc/fstab manually"
+	case operator.CheckNameHostState:
+		var state spec.HostState
+		fields := strings.Fields(res.Msg)
+		switch {
+		case res.Msg == "disable_swap":
+			state.DisableSwap = true
+		case res.Msg == "disable_thp":
+			state.DisableTHP = true
+		case len(fields) == 2 && fields[0] == "swappiness":
+			val, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return "", fmt.Errorf("can not set swappiness, %s", res.Msg)
+			}
+			state.Swappiness = &val
+		default:
+			return "", fmt.Errorf("can not repair host state, %s", res)
+		}
+		t.HostState(host, state)
+		msg = fmt.Sprintf("will try to %s persistently", color.HiBlueString(res.Msg))
+	case operator.CheckNameNUMA:
+		binding := res.NUMABinding
+		if binding == nil {
+			return "", fmt.Errorf("can not set numa_node, %s", res)
+		}
+		if opt.numaBindings == nil {
+			opt.numaBindings = make(map[string]string)
+		}
+		opt.numaBindings[binding.Instance] = binding.Node
+		msg = fmt.Sprintf("will set '%s' for %s in the topology", color.HiBlueString("numa_node: "+binding.Node), binding.Instance)
 	default:
 		msg = fmt.Sprintf("%s, auto fixing not supported", res)
 	}
@@ -671,10 +852,11 @@ func (m *Manager) checkRegionsInfo(clusterName string, topo *spec.Specification,
 	if err != nil {
 		return err
 	}
+	ctx := m.apiContext(clusterName, *gOpt)
 	pdClient := api.NewPDClient(
-		context.WithValue(context.TODO(), logprinter.ContextKeyLogger, m.logger),
+		ctx,
 		topo.GetPDList(),
-		time.Second*time.Duration(gOpt.APITimeout),
+		api.RequestTimeout(ctx, time.Second*time.Duration(gOpt.APITimeout)),
 		tlsConfig,
 	)
 
@@ -683,7 +865,7 @@ func (m *Manager) checkRegionsInfo(clusterName string, topo *spec.Specification,
 		"miss-peer",
 		"pending-peer",
 	} {
-		rInfo, err := pdClient.CheckRegion(state)
+		rInfo, err := pdClient.CheckRegion(ctx, state)
 		if err != nil {
 			return err
 		}
@@ -703,7 +885,7 @@ func (m *Manager) checkRegionsInfo(clusterName string, topo *spec.Specification,
 	return nil
 }
 
-// checkConflict checks cluster conflict
+// checkConflict checks cluster conflict and the policies of the site
 func checkConflict(m *Manager, clusterName string, topo spec.Topology) error {
 	clusterList, err := m.specManager.GetAllClusters()
 	if err != nil {
@@ -713,8 +895,24 @@ func checkConflict(m *Manager, clusterName string, topo spec.Topology) error {
 	if err := spec.CheckClusterPortConflict(clusterList, clusterName, topo); err != nil {
 		return err
 	}
-	err = spec.CheckClusterDirConflict(clusterList, clusterName, topo)
-	return err
+	if err := spec.CheckClusterDirConflict(clusterList, clusterName, topo); err != nil {
+		return err
+	}
+
+	// the clusters of the other kinds managed on this control machine, e.g.
+	// the TiDB clusters for tiup-dm, are checked through the host registry
+	registry := m.specManager.HostRegistry()
+	if err := registry.Sync(clusterList); err != nil {
+		return err
+	}
+	entries, err := registry.ForeignEntries()
+	if err != nil {
+		return err
+	}
+	if err := spec.CheckHostRegistryConflict(entries, clusterName, topo); err != nil {
+		return err
+	}
+	return spec.CheckPolicies(topo)
 }
 
 // deduplicateCheckResult deduplicate check results
@@ -751,3 +949,72 @@ func deduplicateCheckResult(checkResults []HostCheckResult) (uniqueResults []Hos
 	}
 	return
 }
+
+// writeNUMABindings sets the numa_node of the TiKV instances in the topology
+// file, the other content of the file including comments are kept as is.
+func writeNUMABindings(fname string, bindings map[string]string) error {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+
+	// the instances are identified with the defaults of the topology applied,
+	// e.g. the global ports, the entries are in the same order as the nodes
+	var topo spec.Specification
+	if err := spec.ParseTopologyYaml(fname, &topo); err != nil {
+		return err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return perrs.Annotatef(err, "failed to parse topology file %s", fname)
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	servers := yamlMapValue(root.Content[0], "tikv_servers")
+	if servers == nil || servers.Kind != yaml.SequenceNode {
+		return nil
+	}
+	if len(servers.Content) != len(topo.TiKVServers) {
+		return perrs.Errorf("failed to locate the TiKV servers in topology file %s", fname)
+	}
+	for i, server := range servers.Content {
+		kv := topo.TiKVServers[i]
+		numa, ok := bindings[fmt.Sprintf("%s:%d", kv.Host, kv.Port)]
+		if !ok {
+			continue
+		}
+		if v := yamlMapValue(server, "numa_node"); v != nil {
+			v.Value = numa
+			v.Tag = "!!str"
+			v.Style = yaml.DoubleQuotedStyle
+			continue
+		}
+		server.Content = append(server.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "numa_node"},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: numa, Style: yaml.DoubleQuotedStyle},
+		)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(os.WriteFile(fname, buf.Bytes(), 0644))
+}
+
+// yamlMapValue returns the value of the key in a yaml mapping node
+func yamlMapValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
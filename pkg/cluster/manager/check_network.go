@@ -0,0 +1,233 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// the port of the iperf3 server started for bandwidth tests
+const iperfPort = 5299
+
+// hostPair is a pair of hosts measured in the network matrix
+type hostPair struct {
+	src string
+	dst string
+}
+
+// sampleHostPairs returns the pairs of hosts to measure, all pairs are
+// measured if the count is not more than maxPairs. Otherwise every host is
+// paired with the next one to cover all hosts, and the others are sampled
+// randomly.
+func sampleHostPairs(hosts []string, maxPairs int, r *rand.Rand) []hostPair {
+	var all []hostPair
+	for i := 0; i < len(hosts); i++ {
+		for j := i + 1; j < len(hosts); j++ {
+			all = append(all, hostPair{hosts[i], hosts[j]})
+		}
+	}
+	if maxPairs <= 0 || len(all) <= maxPairs {
+		return all
+	}
+
+	picked := make(map[hostPair]bool)
+	var pairs []hostPair
+	pick := func(p hostPair) {
+		if p.src > p.dst {
+			p.src, p.dst = p.dst, p.src
+		}
+		if !picked[p] {
+			picked[p] = true
+			pairs = append(pairs, p)
+		}
+	}
+	for i := range hosts {
+		pick(hostPair{hosts[i], hosts[(i+1)%len(hosts)]})
+	}
+	r.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+	for _, p := range all {
+		if len(pairs) >= maxPairs {
+			break
+		}
+		pick(p)
+	}
+	return pairs
+}
+
+// parseRTT parses the average RTT from the summary of ping, e.g.
+// rtt min/avg/max/mdev = 0.040/0.050/0.060/0.010 ms
+func parseRTT(output string) (time.Duration, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "rtt") && !strings.HasPrefix(line, "round-trip") {
+			continue
+		}
+		fields := strings.Split(line, "/")
+		if len(fields) < 5 {
+			return 0, false
+		}
+		avg, err := strconv.ParseFloat(strings.TrimSpace(fields[4]), 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(avg * float64(time.Millisecond)), true
+	}
+	return 0, false
+}
+
+// checkNetworkMatrix measures the RTT and optionally the bandwidth between
+// the TiKV and PD hosts, the pairs exceeding the thresholds are failed
+func checkNetworkMatrix(
+	ctx context.Context,
+	s, p *tui.SSHConnectionProps,
+	topo *spec.Specification,
+	gOpt *operator.Options,
+	opt *CheckOptions,
+) ([]HostCheckResult, error) {
+	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+
+	sshPorts := make(map[string]int)
+	for _, inst := range topo.TiKVServers {
+		sshPorts[inst.Host] = inst.SSHPort
+	}
+	for _, inst := range topo.PDServers {
+		sshPorts[inst.Host] = inst.SSHPort
+	}
+	hosts := make([]string, 0, len(sshPorts))
+	for host := range sshPorts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	if len(hosts) < 2 {
+		return nil, nil
+	}
+
+	pairs := sampleHostPairs(hosts, opt.Opr.NetworkMaxPairs, rand.New(rand.NewSource(time.Now().UnixNano())))
+	sshTask := func(host string) *task.Builder {
+		return task.NewBuilder(logger).
+			RootSSH(
+				host,
+				sshPorts[host],
+				opt.User,
+				s.Password,
+				s.IdentityFile,
+				s.IdentityFilePassphrase,
+				gOpt.SSHTimeout,
+				gOpt.OptTimeout,
+				gOpt.SSHProxyHost,
+				gOpt.SSHProxyPort,
+				gOpt.SSHProxyUser,
+				p.Password,
+				p.IdentityFile,
+				p.IdentityFilePassphrase,
+				gOpt.SSHProxyTimeout,
+				gOpt.SSHType,
+				topo.GlobalOptions.SSHType,
+			)
+	}
+
+	// RTT of the pairs from the same host are measured in one step
+	peers := make(map[string][]string)
+	for _, pair := range pairs {
+		peers[pair.src] = append(peers[pair.src], pair.dst)
+	}
+	var rttTasks []*task.StepDisplay
+	for src, dsts := range peers {
+		tb := sshTask(src)
+		for _, dst := range dsts {
+			tb = tb.Shell(src, fmt.Sprintf("ping -c 5 -i 0.2 -q -W 1 %s", dst), "rtt:"+src+"->"+dst, false)
+		}
+		rttTasks = append(rttTasks, tb.BuildAsStep(fmt.Sprintf("  - Measuring RTT from %s", src)))
+	}
+	// the errors of ping are reported as the results
+	_ = task.NewBuilder(logger).
+		ParallelStep("+ Measure RTT between TiKV and PD hosts", true, rttTasks...).
+		Build().
+		Execute(ctx)
+
+	var results []HostCheckResult
+	for _, pair := range pairs {
+		result := HostCheckResult{Node: pair.src, Name: operator.CheckNameNetMatrix}
+		stdout, _, _ := ctxt.GetInner(ctx).GetOutputs("rtt:" + pair.src + "->" + pair.dst)
+		rtt, ok := parseRTT(string(stdout))
+		switch {
+		case !ok:
+			result.Status = "Fail"
+			result.Message = fmt.Sprintf("%s is unreachable by ping", pair.dst)
+		case opt.Opr.NetworkMaxRTT > 0 && rtt > opt.Opr.NetworkMaxRTT:
+			result.Status = "Fail"
+			result.Message = fmt.Sprintf("RTT to %s is %s, exceeds %s", pair.dst, rtt, opt.Opr.NetworkMaxRTT)
+		default:
+			result.Status = "Pass"
+			result.Message = fmt.Sprintf("RTT to %s is %s", pair.dst, rtt)
+		}
+		results = append(results, result)
+	}
+
+	if !opt.Opr.EnableBandwidth {
+		return results, nil
+	}
+
+	// the bandwidth is measured pair by pair to avoid the tests interfering
+	// with each other
+	for _, pair := range pairs {
+		id := "bw:" + pair.src + "->" + pair.dst
+		t := task.NewBuilder(logger).
+			Serial(
+				sshTask(pair.dst).
+					Shell(pair.dst, fmt.Sprintf("nohup timeout 30 iperf3 -s -1 -p %d >/dev/null 2>&1 &", iperfPort), "", false).
+					Build(),
+				sshTask(pair.src).
+					Shell(pair.src, fmt.Sprintf("sleep 1; iperf3 -c %s -p %d -t 3 -f m | awk '/receiver/{print $7}'", pair.dst, iperfPort), id, false).
+					Build(),
+			).
+			BuildAsStep(fmt.Sprintf("  - Measuring bandwidth from %s to %s", pair.src, pair.dst))
+
+		result := HostCheckResult{Node: pair.src, Name: operator.CheckNameNetMatrix}
+		if err := task.NewBuilder(logger).ParallelStep("+ Measure bandwidth", false, t).Build().Execute(ctx); err != nil {
+			result.Status = "Warn"
+			result.Message = fmt.Sprintf("failed to measure bandwidth to %s, is iperf3 installed? %s", pair.dst, perrs.Cause(err))
+			results = append(results, result)
+			continue
+		}
+		stdout, _, _ := ctxt.GetInner(ctx).GetOutputs(id)
+		mbps, err := strconv.ParseFloat(strings.TrimSpace(string(stdout)), 64)
+		switch {
+		case err != nil:
+			result.Status = "Warn"
+			result.Message = fmt.Sprintf("failed to measure bandwidth to %s, is iperf3 installed?", pair.dst)
+		case opt.Opr.NetworkMinMbps > 0 && mbps < float64(opt.Opr.NetworkMinMbps):
+			result.Status = "Fail"
+			result.Message = fmt.Sprintf("bandwidth to %s is %.0f Mbits/s, lower than %d Mbits/s", pair.dst, mbps, opt.Opr.NetworkMinMbps)
+		default:
+			result.Status = "Pass"
+			result.Message = fmt.Sprintf("bandwidth to %s is %.0f Mbits/s", pair.dst, mbps)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
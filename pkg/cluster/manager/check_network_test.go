@@ -0,0 +1,60 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleHostPairs(t *testing.T) {
+	hosts := []string{"h1", "h2", "h3", "h4", "h5", "h6"}
+
+	pairs := sampleHostPairs(hosts, 0, rand.New(rand.NewSource(1)))
+	assert.Len(t, pairs, 15)
+	pairs = sampleHostPairs(hosts, 20, rand.New(rand.NewSource(1)))
+	assert.Len(t, pairs, 15)
+
+	pairs = sampleHostPairs(hosts, 8, rand.New(rand.NewSource(1)))
+	assert.Len(t, pairs, 8)
+	covered := make(map[string]bool)
+	seen := make(map[hostPair]bool)
+	for _, p := range pairs {
+		assert.Less(t, p.src, p.dst)
+		assert.False(t, seen[p])
+		seen[p] = true
+		covered[p.src] = true
+		covered[p.dst] = true
+	}
+	assert.Len(t, covered, len(hosts))
+}
+
+func TestParseRTT(t *testing.T) {
+	rtt, ok := parseRTT(`PING 10.0.1.2 (10.0.1.2) 56(84) bytes of data.
+
+--- 10.0.1.2 ping statistics ---
+5 packets transmitted, 5 received, 0% packet loss, time 801ms
+rtt min/avg/max/mdev = 0.101/0.250/0.402/0.110 ms
+`)
+	assert.True(t, ok)
+	assert.Equal(t, 250*time.Microsecond, rtt)
+
+	_, ok = parseRTT(`--- 10.0.1.3 ping statistics ---
+5 packets transmitted, 0 received, 100% packet loss, time 4099ms
+`)
+	assert.False(t, ok)
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/pingcap/tiup/pkg/version"
+)
+
+// CheckReportSchemaVersion is the version of the schema of CheckReport, it's
+// bumped only on incompatible changes, new fields may be added at any time
+const CheckReportSchemaVersion = 1
+
+// EnvCheckReportToken is the bearer token sent to the results server
+const EnvCheckReportToken = "TIUP_CHECK_REPORT_TOKEN"
+
+// CheckReport is the result of a check pushed to the results server, which
+// collects the reports of multiple control machines and clusters
+type CheckReport struct {
+	SchemaVersion  int               `json:"schema_version"`
+	ID             string            `json:"id"`
+	Time           time.Time         `json:"time"`
+	ControlMachine string            `json:"control_machine"`
+	User           string            `json:"user"`
+	TiUPVersion    string            `json:"tiup_version"`
+	Cluster        string            `json:"cluster,omitempty"`
+	ClusterVersion string            `json:"cluster_version,omitempty"`
+	Topology       string            `json:"topology,omitempty"`
+	Summary        CheckSummary      `json:"summary"`
+	Results        []HostCheckResult `json:"results"`
+}
+
+// CheckSummary is the count of check results by status
+type CheckSummary struct {
+	Hosts int `json:"hosts"`
+	Pass  int `json:"pass"`
+	Warn  int `json:"warn"`
+	Fail  int `json:"fail"`
+}
+
+// newCheckReport builds the report of the check results
+func newCheckReport(results []HostCheckResult) *CheckReport {
+	hostname, _ := os.Hostname()
+	report := &CheckReport{
+		SchemaVersion:  CheckReportSchemaVersion,
+		ID:             uuid.New().String(),
+		Time:           time.Now().UTC(),
+		ControlMachine: hostname,
+		User:           utils.CurrentUser(),
+		TiUPVersion:    version.NewTiUPVersion().SemVer(),
+		Results:        results,
+	}
+
+	hosts := make(map[string]struct{})
+	for _, r := range results {
+		hosts[r.Node] = struct{}{}
+		switch r.Status {
+		case "Warn":
+			report.Summary.Warn++
+		case "Fail":
+			report.Summary.Fail++
+		default:
+			report.Summary.Pass++
+		}
+	}
+	report.Summary.Hosts = len(hosts)
+	return report
+}
+
+// objectName returns the name of the report in a directory or object
+// storage prefix, the reports of a cluster are kept in time order
+func (r *CheckReport) objectName() string {
+	name := r.Cluster
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(r.Topology), filepath.Ext(r.Topology))
+	}
+	return fmt.Sprintf("%s/%s-%s.json", name, r.Time.Format("20060102T150405Z"), r.ID[:8])
+}
+
+// pushCheckReport sends the report to the destination, which could be:
+//   - an http(s) endpoint, the report is POSTed to it
+//   - an http(s) prefix ending with '/', e.g. an object storage bucket, the
+//     report is PUT as <prefix><cluster>/<time>-<id>.json to keep the history
+//   - a local directory, the report is written as <dir>/<cluster>/<time>-<id>.json
+func pushCheckReport(ctx context.Context, report *CheckReport, dest string) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", perrs.AddStack(err)
+	}
+
+	if !strings.HasPrefix(dest, "http://") && !strings.HasPrefix(dest, "https://") {
+		path := filepath.Join(strings.TrimPrefix(dest, "file://"), report.objectName())
+		if err := utils.CreateDir(filepath.Dir(path)); err != nil {
+			return "", err
+		}
+		return path, perrs.AddStack(os.WriteFile(path, data, 0644))
+	}
+
+	client := utils.NewHTTPClient(30*time.Second, nil)
+	if token := os.Getenv(EnvCheckReportToken); token != "" {
+		client.SetRequestHeader("Authorization", "Bearer "+token)
+	}
+	if strings.HasSuffix(dest, "/") {
+		url := dest + report.objectName()
+		if _, _, err := client.Put(ctx, url, bytes.NewReader(data)); err != nil {
+			return "", perrs.Annotatef(err, "failed to upload check report to %s", url)
+		}
+		return url, nil
+	}
+	if _, err := client.Post(ctx, dest, bytes.NewReader(data)); err != nil {
+		return "", perrs.Annotatef(err, "failed to push check report to %s", dest)
+	}
+	return dest, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushCheckReport(t *testing.T) {
+	report := newCheckReport([]HostCheckResult{
+		{Node: "10.0.1.1", Name: "os-version", Status: "Pass"},
+		{Node: "10.0.1.1", Name: "swap", Status: "Warn"},
+		{Node: "10.0.1.2", Name: "thp", Status: "Fail"},
+	})
+	report.Cluster = "test"
+	assert.Equal(t, CheckSummary{Hosts: 2, Pass: 1, Warn: 1, Fail: 1}, report.Summary)
+
+	// local directory
+	dir := t.TempDir()
+	path, err := pushCheckReport(context.Background(), report, dir)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(path, filepath.Join(dir, "test")+"/"))
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	var saved CheckReport
+	assert.Nil(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, report.ID, saved.ID)
+	assert.Equal(t, CheckReportSchemaVersion, saved.SchemaVersion)
+
+	// endpoint and prefix
+	var methods, paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	_, err = pushCheckReport(context.Background(), report, server.URL+"/api/checks")
+	assert.Nil(t, err)
+	_, err = pushCheckReport(context.Background(), report, server.URL+"/bucket/checks/")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"POST", "PUT"}, methods)
+	assert.Equal(t, "/api/checks", paths[0])
+	assert.Equal(t, "/bucket/checks/"+report.objectName(), paths[1])
+}
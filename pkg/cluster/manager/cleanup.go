@@ -27,9 +27,7 @@ import (
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
-	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/set"
-	"github.com/pingcap/tiup/pkg/tui"
 )
 
 // CleanCluster cleans the cluster without destroying it
@@ -43,6 +41,12 @@ func (m *Manager) CleanCluster(name string, gOpt operator.Options, cleanOpt oper
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil {
 		return err
@@ -60,7 +64,7 @@ func (m *Manager) CleanCluster(name string, gOpt operator.Options, cleanOpt oper
 		cleanOpt.CleanupData, cleanOpt.CleanupLog, false, cleanOpt.CleanupAuditLog, cleanOpt.RetainDataRoles, cleanOpt.RetainDataNodes)
 
 	if !skipConfirm {
-		if err := cleanupConfirm(m.logger, name, m.sysName, base.Version, cleanOpt, delFileMap); err != nil {
+		if err := m.cleanupConfirm(name, m.sysName, base.Version, cleanOpt, delFileMap); err != nil {
 			return err
 		}
 	}
@@ -87,7 +91,7 @@ func (m *Manager) CleanCluster(name string, gOpt operator.Options, cleanOpt oper
 		Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -104,10 +108,10 @@ func (m *Manager) CleanCluster(name string, gOpt operator.Options, cleanOpt oper
 }
 
 // checkConfirm
-func cleanupConfirm(logger *logprinter.Logger, clusterName, sysName, version string, cleanOpt operator.Options, delFileMap map[string]set.StringSet) error {
-	logger.Warnf("The clean operation will %s %s %s cluster `%s`",
+func (m *Manager) cleanupConfirm(clusterName, sysName, version string, cleanOpt operator.Options, delFileMap map[string]set.StringSet) error {
+	m.logger.Warnf("The clean operation will %s %s %s cluster `%s`",
 		color.HiYellowString("stop"), sysName, version, color.HiYellowString(clusterName))
-	if err := tui.PromptForConfirmOrAbortError("Do you want to continue? [y/N]:"); err != nil {
+	if err := m.prompter.PromptForConfirmOrAbortError("Do you want to continue? [y/N]:"); err != nil {
 		return err
 	}
 
@@ -125,11 +129,11 @@ func cleanupConfirm(logger *logprinter.Logger, clusterName, sysName, version str
 		}
 	}
 
-	logger.Warnf("Clean the clutser %s's%s.\nNodes will be ignored: %s\nRoles will be ignored: %s\nFiles to be deleted are: %s",
+	m.logger.Warnf("Clean the clutser %s's%s.\nNodes will be ignored: %s\nRoles will be ignored: %s\nFiles to be deleted are: %s",
 		color.HiYellowString(clusterName), cleanTarget(cleanOpt), cleanOpt.RetainDataNodes,
 		cleanOpt.RetainDataRoles,
 		delFileList)
-	return tui.PromptForConfirmOrAbortError("Do you want to continue? [y/N]:")
+	return m.prompter.PromptForConfirmOrAbortError("Do you want to continue? [y/N]:")
 }
 
 func cleanTarget(cleanOpt operator.Options) string {
@@ -0,0 +1,187 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// the modes of inferring the labels from the cloud metadata
+const (
+	CloudLabelsFill   = "fill"
+	CloudLabelsVerify = "verify"
+)
+
+var errCloudLabelsMismatch = errNSDeploy.NewType("cloud_labels_mismatch", utils.ErrTraitPreCheck)
+
+// detectCloudLocations queries the cloud metadata on the hosts of the PD,
+// TiKV and TiFlash instances, the hosts which are not cloud instances are
+// absent in the result
+func detectCloudLocations(
+	ctx context.Context,
+	s, p *tui.SSHConnectionProps,
+	topo *spec.Specification,
+	gOpt *operator.Options,
+	user string,
+) (map[string]*spec.CloudLocation, error) {
+	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+
+	sshPorts := spec.CloudLabeledHosts(topo)
+
+	var detectTasks []*task.StepDisplay
+	for host, port := range sshPorts {
+		t := task.NewBuilder(logger).
+			RootSSH(
+				host,
+				port,
+				user,
+				s.Password,
+				s.IdentityFile,
+				s.IdentityFilePassphrase,
+				gOpt.SSHTimeout,
+				gOpt.OptTimeout,
+				gOpt.SSHProxyHost,
+				gOpt.SSHProxyPort,
+				gOpt.SSHProxyUser,
+				p.Password,
+				p.IdentityFile,
+				p.IdentityFilePassphrase,
+				gOpt.SSHProxyTimeout,
+				gOpt.SSHType,
+				topo.GlobalOptions.SSHType,
+			).
+			Shell(host, spec.CloudMetadataScript, "cloud:"+host, false).
+			BuildAsStep(fmt.Sprintf("  - Querying cloud metadata on %s", host))
+		detectTasks = append(detectTasks, t)
+	}
+
+	t := task.NewBuilder(logger).
+		ParallelStep("+ Detect locations from cloud metadata", false, detectTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		return nil, perrs.Annotate(err, "failed to query cloud metadata of hosts")
+	}
+
+	locations := make(map[string]*spec.CloudLocation)
+	for host := range sshPorts {
+		stdout, _, _ := ctxt.GetInner(ctx).GetOutputs("cloud:" + host)
+		loc, err := spec.ParseCloudLocation(string(stdout))
+		if err != nil {
+			return nil, perrs.Annotatef(err, "cloud metadata of %s", host)
+		}
+		if loc == nil {
+			logger.Warnf("%s is not a cloud instance, skip inferring its labels", host)
+			continue
+		}
+		locations[host] = loc
+	}
+	return locations, nil
+}
+
+// inferCloudLabels fills or verifies the labels in locLabels of the PD, TiKV
+// and TiFlash instances with the cloud metadata of the hosts, see
+// spec.InferCloudLabels
+func (m *Manager) inferCloudLabels(
+	s, p *tui.SSHConnectionProps,
+	topo *spec.Specification,
+	locLabels []string,
+	mode string,
+	gOpt *operator.Options,
+	user string,
+) error {
+	if mode != CloudLabelsFill && mode != CloudLabelsVerify {
+		return perrs.Errorf("unknown mode of cloud labels: %s, it should be %s or %s", mode, CloudLabelsFill, CloudLabelsVerify)
+	}
+	if len(locLabels) == 0 {
+		m.logger.Warnf("The location labels of PD are not set, skip inferring the labels from the cloud metadata")
+		return nil
+	}
+
+	ctx := ctxt.New(
+		m.baseContext("", *gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	locations, err := detectCloudLocations(ctx, s, p, topo, gOpt, user)
+	if err != nil {
+		return err
+	}
+	issues := spec.InferCloudLabels(topo, locLabels, locations, mode == CloudLabelsFill)
+	if len(issues) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		msgs = append(msgs, issue.String())
+	}
+	return errCloudLabelsMismatch.
+		New("The labels of the instances don't match the cloud metadata:\n  %s", strings.Join(msgs, "\n  ")).
+		WithProperty(tui.SuggestionFromString("Please fix the labels in the topology, or remove them and use '--cloud-labels=fill' to set them from the cloud metadata."))
+}
+
+// checkCloudLabels verifies the labels of the PD, TiKV and TiFlash instances
+// with the cloud metadata as a part of the check
+func checkCloudLabels(
+	ctx context.Context,
+	s, p *tui.SSHConnectionProps,
+	topo *spec.Specification,
+	gOpt *operator.Options,
+	opt *CheckOptions,
+) ([]HostCheckResult, error) {
+	locations, err := detectCloudLocations(ctx, s, p, topo, gOpt, opt.User)
+	if err != nil {
+		return nil, err
+	}
+	locLabels, err := topo.LocationLabels()
+	if err != nil {
+		return nil, err
+	}
+	issues := spec.InferCloudLabels(topo, locLabels, locations, false)
+
+	var results []HostCheckResult
+	failed := make(map[string]bool)
+	for _, issue := range issues {
+		failed[issue.Host] = true
+		results = append(results, HostCheckResult{
+			Node:    issue.Host,
+			Name:    operator.CheckNameCloudLabels,
+			Status:  "Fail",
+			Message: issue.String(),
+		})
+	}
+	for host, loc := range locations {
+		if failed[host] {
+			continue
+		}
+		results = append(results, HostCheckResult{
+			Node:    host,
+			Name:    operator.CheckNameCloudLabels,
+			Status:  "Pass",
+			Message: fmt.Sprintf("labels match the location in %s: %s/%s", loc.Provider, loc.Region, loc.Zone),
+		})
+	}
+	return results, nil
+}
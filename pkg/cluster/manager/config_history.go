@@ -0,0 +1,209 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// filterInstances returns the instances of topo matching the roles and
+// nodes of gOpt
+func filterInstances(topo spec.Topology, gOpt operator.Options) []spec.Instance {
+	filterRoles := set.NewStringSet(gOpt.Roles...)
+	filterNodes := set.NewStringSet(gOpt.Nodes...)
+	var instances []spec.Instance
+	topo.IterInstance(func(inst spec.Instance) {
+		if (len(filterRoles) > 0 && !filterRoles.Exist(inst.Role())) ||
+			(len(filterNodes) > 0 && !filterNodes.Exist(inst.ID())) {
+			return
+		}
+		instances = append(instances, inst)
+	})
+	return instances
+}
+
+// configLines splits the content of a config file into lines
+func configLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// countChangedLines returns the count of lines added and removed from the
+// config files of prev to the ones of cur
+func countChangedLines(prev, cur *spec.ConfigGeneration) (added, removed int) {
+	names := set.NewStringSet(cur.FileNames()...)
+	if prev != nil {
+		names.Join(set.NewStringSet(prev.FileNames()...))
+	}
+	for name := range names {
+		before := make(map[string]int)
+		if prev != nil {
+			for _, line := range configLines(prev.Files[name]) {
+				before[line]++
+			}
+		}
+		for _, line := range configLines(cur.Files[name]) {
+			if before[line] > 0 {
+				before[line]--
+				continue
+			}
+			added++
+		}
+		for _, n := range before {
+			removed += n
+		}
+	}
+	return added, removed
+}
+
+// ConfigHistory shows the generations of the configs rendered for the
+// instances of the cluster, and the diffs between them if showDiff is set
+func (m *Manager) ConfigHistory(name string, gOpt operator.Options, showDiff bool) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+
+	instances := filterInstances(metadata.GetTopology(), gOpt)
+	if len(instances) == 0 {
+		return perrs.New("no instance matches the roles and nodes specified")
+	}
+
+	type configDiff struct {
+		inst      spec.Instance
+		prev, cur *spec.ConfigGeneration
+	}
+	rows := [][]string{{"ID", "Role", "Generation", "Time", "Files", "Changes"}}
+	var diffs []configDiff
+	for _, inst := range instances {
+		history, err := m.specManager.ConfigGenerations(name, inst)
+		if err != nil {
+			return err
+		}
+		for i, g := range history {
+			var prev *spec.ConfigGeneration
+			if i > 0 {
+				prev = history[i-1]
+			}
+			added, removed := countChangedLines(prev, g)
+			gen := strconv.Itoa(g.Generation)
+			if i == len(history)-1 {
+				gen += " (latest)"
+			}
+			rows = append(rows, []string{
+				inst.ID(),
+				inst.Role(),
+				gen,
+				g.Time.Format(time.RFC3339),
+				strings.Join(g.FileNames(), ","),
+				fmt.Sprintf("+%d -%d", added, removed),
+			})
+			if showDiff && prev != nil {
+				diffs = append(diffs, configDiff{inst, prev, g})
+			}
+		}
+	}
+	if len(rows) == 1 {
+		m.logger.Infof("No config history of the instances is kept, it's recorded since the configs are refreshed next time")
+		return nil
+	}
+
+	fmt.Fprintf(m.stdout, "Cluster: %s\n", color.HiYellowString(name))
+	tui.FprintTable(m.stdout, rows, true)
+
+	for _, d := range diffs {
+		for _, fname := range d.cur.FileNames() {
+			if string(d.prev.Files[fname]) == string(d.cur.Files[fname]) {
+				continue
+			}
+			fmt.Fprintf(m.stdout, "\n%s %s: generation %d -> %d\n",
+				color.CyanString(d.inst.ID()), fname, d.prev.Generation, d.cur.Generation)
+			utils.ShowDiff(string(d.prev.Files[fname]), string(d.cur.Files[fname]), m.stdout)
+		}
+	}
+	return nil
+}
+
+// buildRollbackConfigTasks builds the tasks to transfer the configs kept in
+// the generation gen of the config history to the instances matching the roles
+// and nodes of gOpt. The configs rolled back are saved as a new generation.
+func buildRollbackConfigTasks(
+	m *Manager,
+	name string,
+	topo spec.Topology,
+	base *spec.BaseMeta,
+	gOpt operator.Options,
+	gen int,
+	nodes []string,
+) ([]*task.StepDisplay, error) {
+	var tasks []*task.StepDisplay
+	deletedNodes := set.NewStringSet(nodes...)
+	for _, inst := range filterInstances(topo, gOpt) {
+		if deletedNodes.Exist(inst.ID()) {
+			continue
+		}
+		if history, err := m.specManager.ConfigGenerations(name, inst); err != nil {
+			return nil, err
+		} else if len(history) == 0 {
+			// components without config files, e.g. monitors
+			continue
+		}
+		g, err := m.specManager.ConfigGeneration(name, inst, gen)
+		if err != nil {
+			if errx := errorx.Cast(err); errx != nil {
+				return nil, errx.WithProperty(tui.SuggestionFromFormat(
+					"Please check the generations kept with '%s config history %s -N %s'",
+					tui.OsArgs0(), name, inst.ID()))
+			}
+			return nil, err
+		}
+
+		inst := inst
+		confDir := filepath.Join(spec.Abs(base.User, inst.DeployDir()), "conf")
+		tb := task.NewBuilder(m.logger)
+		for _, fname := range g.FileNames() {
+			tb.CopyFile(m.specManager.ConfigGenerationFile(name, inst, gen, fname), filepath.Join(confDir, fname), inst.GetHost(), false, 0, false)
+		}
+		tb.Func(fmt.Sprintf("SaveConfigGeneration: %s", inst.ID()), func(ctx context.Context) error {
+			_, err := m.specManager.SaveConfigGeneration(name, inst, g.Files)
+			return err
+		})
+		tasks = append(tasks, tb.BuildAsStep(fmt.Sprintf("  - Roll back config %s -> generation %d", inst.ID(), gen)))
+	}
+	if len(tasks) == 0 {
+		return nil, perrs.Errorf("no config history of the instances matching the roles and nodes is kept")
+	}
+	return tasks, nil
+}
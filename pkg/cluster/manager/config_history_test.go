@@ -0,0 +1,42 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountChangedLines(t *testing.T) {
+	first := &spec.ConfigGeneration{Files: map[string][]byte{
+		"tiflash.toml": []byte("a = 1\nb = 2\n"),
+	}}
+	added, removed := countChangedLines(nil, first)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 0, removed)
+
+	second := &spec.ConfigGeneration{Files: map[string][]byte{
+		"tiflash.toml":         []byte("a = 1\nb = 3\n"),
+		"tiflash-learner.toml": []byte("c = 1"),
+	}}
+	added, removed = countChangedLines(first, second)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 1, removed)
+
+	added, removed = countChangedLines(second, first)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 2, removed)
+}
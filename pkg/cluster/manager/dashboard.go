@@ -0,0 +1,227 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/crypto"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// DashboardOptions contains the options of the dashboard command
+type DashboardOptions struct {
+	Open          bool          // open the URL in the browser
+	Session       bool          // sign in and issue a session shared by the URL
+	User          string        // the SQL user to sign in
+	SessionTTL    time.Duration // the time the shared session expires after
+	ReadOnly      bool          // revoke the write privileges of the shared session
+	ForwardPort   int           // forward the local port to the dashboard via SSH if not 0
+	StatusTimeout time.Duration // timeout of the requests to the dashboard
+}
+
+// ensureDashboardPlacement moves TiDB Dashboard to a PD allowed to run it,
+// the failure is only warned as the cluster works without the dashboard.
+func (m *Manager) ensureDashboardPlacement(ctx context.Context, cluster *spec.Specification, tlsCfg *tls.Config, gOpt operator.Options) {
+	ctx = context.WithValue(ctx, logprinter.ContextKeyLogger, m.logger)
+	before, _ := cluster.GetDashboardAddress(ctx, tlsCfg, time.Second*time.Duration(gOpt.APITimeout), cluster.GetPDList()...)
+	after, err := cluster.EnsureDashboardPlacement(ctx, tlsCfg, time.Second*time.Duration(gOpt.APITimeout), cluster.GetPDList()...)
+	if err != nil {
+		m.logger.Warnf("Failed to place TiDB Dashboard on %v: %s", cluster.GlobalOptions.Dashboard.PDHosts, err)
+		return
+	}
+	if before != after {
+		m.logger.Infof("TiDB Dashboard is moved from %s to %s", before, after)
+	}
+}
+
+// Dashboard prints the URL of TiDB Dashboard, optionally with a freshly
+// issued session, and forwards a local port to it through SSH.
+func (m *Manager) Dashboard(name string, opt DashboardOptions, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	cluster, err := spec.AsClusterTopology(metadata.GetTopology())
+	if err != nil {
+		return err
+	}
+	tlsCfg, err := cluster.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	ctx := m.apiContext(name, gOpt)
+	addr, err := cluster.EnsureDashboardPlacement(ctx, tlsCfg, opt.StatusTimeout, cluster.GetPDList()...)
+	if err != nil {
+		return perrs.Annotate(err, "failed to retrieve TiDB Dashboard instance from PD")
+	}
+	switch addr {
+	case "", "auto":
+		return perrs.New("TiDB Dashboard is not initialized, please start PD and try again")
+	case "none":
+		return perrs.New("TiDB Dashboard is disabled")
+	}
+
+	client := utils.NewHTTPClient(opt.StatusTimeout, tlsCfg)
+	baseURL := api.URL(tlsCfg != nil, addr) + "/dashboard/"
+
+	// the dashboard may be unreachable from here, e.g. behind a bastion
+	reachable := true
+	if _, err := client.Get(ctx, baseURL); err != nil {
+		reachable = false
+		if opt.ForwardPort == 0 {
+			m.logger.Warnf("TiDB Dashboard %s is unreachable: %s", addr, err)
+			m.logger.Warnf("Use --forward to access it through SSH port forwarding")
+		}
+	}
+
+	fragment := ""
+	if opt.Session {
+		if !reachable {
+			return perrs.Errorf("cannot issue a session as TiDB Dashboard %s is unreachable", addr)
+		}
+		code, err := issueDashboardSession(ctx, client, baseURL, opt)
+		if err != nil {
+			return err
+		}
+		fragment = "#/signin?code=" + code
+	}
+
+	if tlsCfg != nil {
+		fmt.Fprintln(m.stdout,
+			"Client certificate:",
+			color.CyanString(m.specManager.Path(name, spec.TLSCertKeyDir, spec.PFXClientCert)),
+		)
+		fmt.Fprintln(m.stdout,
+			"Certificate password:",
+			color.CyanString(crypto.PKCS12Password),
+		)
+	}
+
+	if opt.ForwardPort == 0 {
+		dashboardURL := baseURL + fragment
+		fmt.Fprintln(m.stdout, "Dashboard URL:", color.CyanString(dashboardURL))
+		if opt.Open {
+			openBrowser(m.logger, dashboardURL)
+		}
+		return nil
+	}
+
+	dashboardURL := fmt.Sprintf("%s/dashboard/%s", api.URL(tlsCfg != nil, fmt.Sprintf("127.0.0.1:%d", opt.ForwardPort)), fragment)
+	fmt.Fprintln(m.stdout, "Dashboard URL:", color.CyanString(dashboardURL))
+	cmd, err := m.dashboardForwardCmd(name, metadata.GetBaseMeta().User, cluster, addr, opt.ForwardPort, gOpt)
+	if err != nil {
+		return err
+	}
+	if opt.Open {
+		// the browser is opened before the port is forwarded, it may need refreshing
+		openBrowser(m.logger, dashboardURL)
+	}
+	m.logger.Infof("Forwarding 127.0.0.1:%d to %s, press Ctrl+C to stop", opt.ForwardPort, addr)
+	return perrs.AddStack(cmd.Run())
+}
+
+// issueDashboardSession signs in TiDB Dashboard and returns the code to
+// share the session, so the URL with the code signs in automatically.
+func issueDashboardSession(ctx context.Context, client *utils.HTTPClient, baseURL string, opt DashboardOptions) (string, error) {
+	password := tui.ContextPrompter(ctx).PromptForPassword("Input the password of SQL user %s: ", opt.User)
+	body, err := json.Marshal(map[string]interface{}{
+		"type":     0, // sign in with SQL user
+		"username": opt.User,
+		"password": password,
+	})
+	if err != nil {
+		return "", perrs.AddStack(err)
+	}
+	data, err := client.Post(ctx, baseURL+"api/user/login", bytes.NewBuffer(body))
+	if err != nil {
+		return "", perrs.Annotate(err, "failed to sign in TiDB Dashboard")
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(data, &login); err != nil {
+		return "", perrs.AddStack(err)
+	}
+
+	body, err = json.Marshal(map[string]interface{}{
+		"expire_in_sec":     int64(opt.SessionTTL / time.Second),
+		"revoke_write_priv": opt.ReadOnly,
+	})
+	if err != nil {
+		return "", perrs.AddStack(err)
+	}
+	client.SetRequestHeader("Authorization", "Bearer "+login.Token)
+	data, err = client.Post(ctx, baseURL+"api/user/share/code", bytes.NewBuffer(body))
+	if err != nil {
+		return "", perrs.Annotate(err, "failed to issue a session of TiDB Dashboard")
+	}
+	var share struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(data, &share); err != nil {
+		return "", perrs.AddStack(err)
+	}
+	return share.Code, nil
+}
+
+// dashboardForwardCmd builds the ssh command forwarding the local port to
+// the dashboard, through the SSH proxy if it's specified.
+func (m *Manager) dashboardForwardCmd(name, user string, cluster *spec.Specification, addr string, localPort int, gOpt operator.Options) (*exec.Cmd, error) {
+	for _, pd := range cluster.PDServers {
+		if addr == fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort) {
+			return m.sshForwardCmd(name, user, pd.Host, pd.SSHPort, []portForward{{
+				Host:       pd.Host,
+				RemotePort: pd.ClientPort,
+				LocalPort:  localPort,
+			}}, gOpt), nil
+		}
+	}
+	return nil, perrs.Errorf("TiDB Dashboard %s is not running on any PD of the cluster", addr)
+}
+
+// openBrowser opens the URL in the default browser, the failure is ignored
+// as the URL is printed anyway.
+func openBrowser(logger *logprinter.Logger, url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		logger.Debugf("failed to open the browser: %s", err)
+	}
+}
@@ -14,7 +14,6 @@
 package manager
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -26,7 +25,6 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
@@ -45,6 +43,9 @@ type DeployOptions struct {
 	IdentityFile   string // path to the private key file
 	UsePassword    bool   // use password instead of identity file for ssh connection
 	NoLabels       bool   // don't check labels for TiKV instance
+	CloudLabels    string // fill or verify the labels with the cloud metadata
+	IgnoreFsCheck  bool   // don't check the filesystems of data dirs
+	Inherit        bool   // inherit the unset fields from existing instances when scaling out
 	Stage1         bool   // don't start the new instance, just deploy
 	Stage2         bool   // start instances and init Config after stage1
 }
@@ -117,7 +118,11 @@ func (m *Manager) Deploy(
 
 	if topo, ok := topo.(*spec.Specification); ok {
 		topo.AdjustByVersion(clusterVersion)
-		if !opt.NoLabels {
+		if err := topo.ValidateReadOnlyZones(); err != nil {
+			return err
+		}
+		// the labels are checked after inferred from the cloud metadata
+		if !opt.NoLabels && opt.CloudLabels == "" {
 			// Check if TiKV's label set correctly
 			lbs, err := topo.LocationLabels()
 			if err != nil {
@@ -137,7 +142,7 @@ func (m *Manager) Deploy(
 		sshConnProps  *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 	)
-	if gOpt.SSHType != executor.SSHTypeNone {
+	if gOpt.SSHType.UseSSH() {
 		var err error
 		if sshConnProps, err = tui.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword); err != nil {
 			return err
@@ -153,6 +158,43 @@ func (m *Manager) Deploy(
 		return err
 	}
 
+	if err := m.checkHostRequirements(sshConnProps, sshProxyProps, topo, clusterVersion, &gOpt, opt.User); err != nil {
+		return err
+	}
+
+	if !opt.IgnoreFsCheck {
+		if err := m.checkDataFilesystems(sshConnProps, sshProxyProps, topo, nil, &gOpt, opt.User); err != nil {
+			return err
+		}
+	}
+
+	if topo, ok := topo.(*spec.Specification); ok && opt.CloudLabels != "" {
+		var lbs []string
+		var err error
+		if opt.CloudLabels == CloudLabelsFill {
+			// PD uses the labels inferred if its location labels are not set
+			lbs, err = spec.FillCloudLocationLabels(topo)
+		} else {
+			lbs, err = topo.LocationLabels()
+		}
+		if err != nil {
+			return err
+		}
+		if err := m.inferCloudLabels(sshConnProps, sshProxyProps, topo, lbs, opt.CloudLabels, &gOpt, opt.User); err != nil {
+			return err
+		}
+		if !opt.NoLabels {
+			if err := spec.CheckTiKVLabels(lbs, topo); err != nil {
+				return perrs.Errorf("check TiKV label failed, please fix that before continue:\n%s", err)
+			}
+		}
+	}
+
+	etcHosts, err := spec.EtcHostsEntries(topo)
+	if err != nil {
+		return err
+	}
+
 	if !skipConfirm && strings.ToLower(gOpt.DisplayMode) != "json" {
 		if err := m.confirmTopology(name, clusterVersion, topo, set.NewStringSet()); err != nil {
 			return err
@@ -235,7 +277,9 @@ func (m *Manager) Deploy(
 				gOpt.SSHType,
 				globalOptions.SSHType,
 			).
-			EnvInit(host, globalOptions.User, globalOptions.Group, opt.SkipCreateUser || globalOptions.User == opt.User).
+			EnvInit(host, globalOptions.User, globalOptions.Group, opt.SkipCreateUser || globalOptions.User == opt.User, globalOptions.DeployUser.ForHost(host)).
+			HostState(host, globalOptions.HostState).
+			EtcHosts(host, name, etcHosts).
 			Mkdir(globalOptions.User, host, dirs...).
 			BuildAsStep(fmt.Sprintf("  - Prepare %s:%d", host, hostInfo.ssh))
 		envInitTasks = append(envInitTasks, t)
@@ -380,7 +424,7 @@ func (m *Manager) Deploy(
 	t := builder.Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
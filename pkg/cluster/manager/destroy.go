@@ -53,7 +53,7 @@ func (m *Manager) DestroyCluster(name string, gOpt operator.Options, destroyOpt
 
 	if !skipConfirm {
 		m.logger.Warnf(color.HiRedString(tui.ASCIIArtWarning))
-		if err := tui.PromptForAnswerOrAbortError(
+		if err := m.prompter.PromptForAnswerOrAbortError(
 			"Yes, I know my cluster and data will be deleted.",
 			fmt.Sprintf("This operation will destroy %s %s cluster %s and its data.",
 				m.sysName,
@@ -86,7 +86,7 @@ func (m *Manager) DestroyCluster(name string, gOpt operator.Options, destroyOpt
 		Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -141,7 +141,7 @@ func (m *Manager) DestroyTombstone(
 	}
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -158,7 +158,7 @@ func (m *Manager) DestroyTombstone(
 	t := b.
 		Func("FindTomestoneNodes", func(ctx context.Context) (err error) {
 			if !skipConfirm {
-				err = tui.PromptForConfirmOrAbortError(
+				err = m.prompter.PromptForConfirmOrAbortError(
 					fmt.Sprintf("%s\nDo you confirm this action? [y/N]:",
 						color.HiYellowString("Will destroy these nodes: %v", nodes)),
 				)
@@ -0,0 +1,221 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alessio/shellescape"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+const (
+	// the minimal interval between two samples of a data dir
+	diskSampleInterval = time.Hour
+	// a week of hourly samples, they are kept in the meta
+	maxDiskSamples = 168
+)
+
+// dataDirUsage is the disk usage of the filesystem of a data dir
+type dataDirUsage struct {
+	dir   string
+	used  uint64
+	total uint64
+}
+
+// DiskForecast is the disk usage of a data dir of an instance and the
+// projected time the disk will be full, based on the growth rate of the
+// previous samples.
+type DiskForecast struct {
+	ID           string     `json:"id"`
+	DataDir      string     `json:"data_dir"`
+	Used         uint64     `json:"used"`
+	Total        uint64     `json:"total"`
+	GrowthPerDay int64      `json:"growth_per_day"`
+	FullAt       *time.Time `json:"full_at,omitempty"`
+	Warning      bool       `json:"warning"`
+}
+
+// getDiskUsages returns the used and total bytes of the filesystems of the dirs
+func getDiskUsages(ctx context.Context, e ctxt.Executor, dirs []string) ([]dataDirUsage, error) {
+	quoted := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		quoted = append(quoted, shellescape.Quote(dir))
+	}
+	cmd := fmt.Sprintf("df -B1 --output=used,size %s | tail -n +2", strings.Join(quoted, " "))
+	stdout, stderr, err := e.Execute(ctx, cmd, false)
+	if err != nil {
+		return nil, perrs.Annotatef(err, "failed to get disk usage of %s: %s", strings.Join(dirs, ","), stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	if len(lines) != len(dirs) {
+		return nil, perrs.Errorf("unknown output of df: %s %s", stdout, stderr)
+	}
+
+	usages := make([]dataDirUsage, 0, len(dirs))
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, perrs.Errorf("unknown output of df: %s", stdout)
+		}
+		u := dataDirUsage{dir: dirs[i]}
+		if u.used, err = strconv.ParseUint(fields[0], 10, 64); err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		if u.total, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		usages = append(usages, u)
+	}
+	return usages, nil
+}
+
+// diskSampleDue checks if the disk usage of the instance should be sampled,
+// it's sampled at most once in diskSampleInterval
+func diskSampleDue(history []spec.DiskUsageHistory, id string, now time.Time) bool {
+	for _, h := range history {
+		if h.Node == id && len(h.Samples) > 0 && now.Sub(h.Samples[len(h.Samples)-1].Time) < diskSampleInterval {
+			return false
+		}
+	}
+	return true
+}
+
+// hasDiskUsages checks if the disk usage of any instance is sampled
+func hasDiskUsages(infos []InstInfo) bool {
+	for _, info := range infos {
+		if len(info.disks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// recordDiskSamples appends the disk usages of the instances to the history
+// as new samples, the data dirs not sampled for maxDiskSamples intervals are
+// removed, e.g. the ones of the instances scaled in
+func recordDiskSamples(history *[]spec.DiskUsageHistory, infos []InstInfo, now time.Time) {
+	index := make(map[string]int)
+	for i, h := range *history {
+		index[h.Node+" "+h.DataDir] = i
+	}
+	for _, info := range infos {
+		for _, d := range info.disks {
+			if d.total == 0 {
+				continue
+			}
+			sample := spec.DiskUsageSample{Time: now, Used: d.used, Total: d.total}
+			i, ok := index[info.ID+" "+d.dir]
+			if !ok {
+				index[info.ID+" "+d.dir] = len(*history)
+				*history = append(*history, spec.DiskUsageHistory{
+					Node:    info.ID,
+					DataDir: d.dir,
+					Samples: []spec.DiskUsageSample{sample},
+				})
+				continue
+			}
+			h := &(*history)[i]
+			if now.Sub(h.Samples[len(h.Samples)-1].Time) < diskSampleInterval {
+				continue
+			}
+			h.Samples = append(h.Samples, sample)
+			if len(h.Samples) > maxDiskSamples {
+				h.Samples = h.Samples[len(h.Samples)-maxDiskSamples:]
+			}
+		}
+	}
+
+	kept := (*history)[:0]
+	for _, h := range *history {
+		if len(h.Samples) > 0 && now.Sub(h.Samples[len(h.Samples)-1].Time) < maxDiskSamples*diskSampleInterval {
+			kept = append(kept, h)
+		}
+	}
+	*history = kept
+}
+
+// forecastDiskFull computes the growth rate per day of the disk usage and
+// the time the disk will be full, the time is zero if the usage is not growing.
+func forecastDiskFull(samples []spec.DiskUsageSample) (growthPerDay int64, fullAt time.Time) {
+	if len(samples) < 2 {
+		return 0, time.Time{}
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	elapsed := last.Time.Sub(first.Time)
+	if elapsed < diskSampleInterval {
+		return 0, time.Time{}
+	}
+
+	growth := float64(int64(last.Used) - int64(first.Used))
+	growthPerDay = int64(growth / elapsed.Hours() * 24)
+	if growth <= 0 || last.Total <= last.Used {
+		return growthPerDay, time.Time{}
+	}
+
+	remaining := float64(last.Total - last.Used)
+	return growthPerDay, last.Time.Add(time.Duration(remaining / growth * float64(elapsed)))
+}
+
+// forecastDiskUsage returns the forecasts of the data dirs of the instances
+// from the samples in the history and their current usages, the data dir is
+// marked as warning if the disk is projected to be full within the horizon.
+func forecastDiskUsage(history []spec.DiskUsageHistory, infos []InstInfo, horizon time.Duration, now time.Time) []DiskForecast {
+	samples := make(map[string][]spec.DiskUsageSample)
+	for _, h := range history {
+		samples[h.Node+" "+h.DataDir] = h.Samples
+	}
+
+	forecasts := make([]DiskForecast, 0)
+	for _, info := range infos {
+		for _, d := range info.disks {
+			if d.total == 0 {
+				continue
+			}
+			// the current usage is always used for the forecast
+			previous := samples[info.ID+" "+d.dir]
+			latest := append(previous[:len(previous):len(previous)], spec.DiskUsageSample{
+				Time: now, Used: d.used, Total: d.total,
+			})
+			growth, fullAt := forecastDiskFull(latest)
+			f := DiskForecast{
+				ID:           info.ID,
+				DataDir:      d.dir,
+				Used:         d.used,
+				Total:        d.total,
+				GrowthPerDay: growth,
+			}
+			if !fullAt.IsZero() {
+				f.FullAt = &fullAt
+				f.Warning = fullAt.Sub(now) <= horizon
+			}
+			forecasts = append(forecasts, f)
+		}
+	}
+
+	sort.Slice(forecasts, func(i, j int) bool {
+		if forecasts[i].ID != forecasts[j].ID {
+			return forecasts[i].ID < forecasts[j].ID
+		}
+		return forecasts[i].DataDir < forecasts[j].DataDir
+	})
+	return forecasts
+}
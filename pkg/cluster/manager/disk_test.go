@@ -0,0 +1,113 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dfExecutor struct {
+	cmd    string
+	stdout string
+}
+
+func (e *dfExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.cmd = cmd
+	return []byte(e.stdout), nil, nil
+}
+
+func (e *dfExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	return nil
+}
+
+func TestGetDiskUsages(t *testing.T) {
+	e := &dfExecutor{stdout: "100 1000\n200 2000\n"}
+	usages, err := getDiskUsages(context.Background(), e, []string{"/data1", "/tidb data"})
+	assert.Nil(t, err)
+	assert.Equal(t, "df -B1 --output=used,size /data1 '/tidb data' | tail -n +2", e.cmd)
+	assert.Equal(t, []dataDirUsage{{"/data1", 100, 1000}, {"/tidb data", 200, 2000}}, usages)
+
+	// one of the dirs fails
+	e.stdout = "100 1000\n"
+	_, err = getDiskUsages(context.Background(), e, []string{"/data1", "/tidb data"})
+	assert.NotNil(t, err)
+}
+
+func TestRecordDiskSamples(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	infos := []InstInfo{
+		{ID: "172.16.5.140:20160", disks: []dataDirUsage{{"/data1", 100, 1000}, {"/data2", 100, 1000}}},
+		{ID: "172.16.5.140:4000"},
+	}
+	var history []spec.DiskUsageHistory
+	assert.True(t, diskSampleDue(history, "172.16.5.140:20160", start))
+	recordDiskSamples(&history, infos, start)
+	require.Len(t, history, 2)
+	assert.Equal(t, "/data2", history[1].DataDir)
+	assert.False(t, diskSampleDue(history, "172.16.5.140:20160", start.Add(time.Minute)))
+
+	// sampled at most once in the interval
+	recordDiskSamples(&history, infos, start.Add(time.Minute))
+	assert.Len(t, history[0].Samples, 1)
+
+	// grows 100 bytes per day in /data1
+	infos[0].disks[0].used = 200
+	now := start.Add(24 * time.Hour)
+	assert.True(t, diskSampleDue(history, "172.16.5.140:20160", now))
+	recordDiskSamples(&history, infos, now)
+	assert.Len(t, history[0].Samples, 2)
+
+	infos[0].disks[0].used = 300
+	forecasts := forecastDiskUsage(history, infos, 10*24*time.Hour, start.Add(48*time.Hour))
+	require.Len(t, forecasts, 2)
+	assert.Equal(t, int64(100), forecasts[0].GrowthPerDay)
+	assert.Equal(t, start.Add(9*24*time.Hour), *forecasts[0].FullAt)
+	assert.True(t, forecasts[0].Warning)
+	assert.Nil(t, forecasts[1].FullAt)
+
+	// the data dirs not sampled for long are removed
+	recordDiskSamples(&history, nil, now.Add(maxDiskSamples*diskSampleInterval))
+	assert.Empty(t, history)
+}
+
+func TestForecastDiskFull(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// not enough samples
+	growth, fullAt := forecastDiskFull([]spec.DiskUsageSample{{Time: start, Used: 100, Total: 1000}})
+	assert.Equal(t, int64(0), growth)
+	assert.True(t, fullAt.IsZero())
+
+	// grows 100 bytes per day, 800 bytes left
+	growth, fullAt = forecastDiskFull([]spec.DiskUsageSample{
+		{Time: start, Used: 100, Total: 1000},
+		{Time: start.Add(24 * time.Hour), Used: 200, Total: 1000},
+	})
+	assert.Equal(t, int64(100), growth)
+	assert.Equal(t, start.Add(9*24*time.Hour), fullAt)
+
+	// not growing
+	growth, fullAt = forecastDiskFull([]spec.DiskUsageSample{
+		{Time: start, Used: 200, Total: 1000},
+		{Time: start.Add(24 * time.Hour), Used: 100, Total: 1000},
+	})
+	assert.Equal(t, int64(-100), growth)
+	assert.True(t, fullAt.IsZero())
+}
@@ -27,6 +27,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/fatih/color"
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/checkpoint"
@@ -45,6 +46,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// statusUnreachable is the status of instances on quarantined hosts
+const statusUnreachable = "Unreachable"
+
 // InstInfo represents an instance info
 type InstInfo struct {
 	ID        string `json:"id"`
@@ -56,9 +60,14 @@ type InstInfo struct {
 	Since     string `json:"since"`
 	DataDir   string `json:"data_dir"`
 	DeployDir string `json:"deploy_dir"`
+	DiskUsed  uint64 `json:"disk_used,omitempty"`
+	DiskTotal uint64 `json:"disk_total,omitempty"`
+	Restarts  *int   `json:"restarts_7d,omitempty"`
 
 	ComponentName string
 	Port          int
+	uptime        time.Duration
+	disks         []dataDirUsage
 }
 
 // LabelInfo represents an instance label info
@@ -90,10 +99,12 @@ type ClusterMetaInfo struct {
 
 // JSONOutput holds the structure for the JSON output of `tiup cluster display --json`
 type JSONOutput struct {
-	ClusterMetaInfo ClusterMetaInfo `json:"cluster_meta"`
-	InstanceInfos   []InstInfo      `json:"instances,omitempty"`
-	LocationLabel   string          `json:"location_label,omitempty"`
-	LabelInfos      []api.LabelInfo `json:"labels,omitempty"`
+	ClusterMetaInfo ClusterMetaInfo  `json:"cluster_meta"`
+	InstanceInfos   []InstInfo       `json:"instances,omitempty"`
+	LocationLabel   string           `json:"location_label,omitempty"`
+	LabelInfos      []api.LabelInfo  `json:"labels,omitempty"`
+	Changefeeds     []ChangefeedInfo `json:"changefeeds,omitempty"`
+	DiskUsages      []DiskForecast   `json:"disk_usages,omitempty"`
 }
 
 // Display cluster meta and topology.
@@ -102,14 +113,35 @@ func (m *Manager) Display(name string, opt operator.Options) error {
 		return err
 	}
 
-	clusterInstInfos, err := m.GetClusterTopology(name, opt)
+	clusterInstInfos, err := m.getClusterTopology(name, opt, !opt.Viewer)
 	if err != nil {
 		return err
 	}
 
+	now := time.Now()
+	if !opt.Viewer && (opt.ShowDetail || hasDiskUsages(clusterInstInfos)) {
+		err := m.updateMetaRecords(name, func(metadata spec.Metadata) {
+			base := metadata.GetBaseMeta()
+			if base.DiskUsages != nil {
+				recordDiskSamples(base.DiskUsages, clusterInstInfos, now)
+			}
+			if opt.ShowDetail && base.Restarts != nil {
+				updateRestarts(base.Restarts, clusterInstInfos, now)
+			}
+		})
+		if err != nil {
+			m.logger.Warnf("Failed to record the disk usage samples and restarts: %s", err)
+		}
+	}
+
 	metadata, _ := m.meta(name)
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	// the restarts are detected again in case they are not saved, e.g. the
+	// cluster is being operated, it changes nothing if they are
+	if opt.ShowDetail && base.Restarts != nil {
+		updateRestarts(base.Restarts, clusterInstInfos, now)
+	}
 	cyan := color.New(color.FgCyan, color.Bold)
 
 	statusTimeout := time.Duration(opt.APITimeout) * time.Second
@@ -138,34 +170,39 @@ func (m *Manager) Display(name string, opt operator.Options) error {
 			j.ClusterMetaInfo.TLSClientCert = m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSClientCert)
 		}
 	} else {
-		fmt.Printf("Cluster type:       %s\n", cyan.Sprint(m.sysName))
-		fmt.Printf("Cluster name:       %s\n", cyan.Sprint(name))
-		fmt.Printf("Cluster version:    %s\n", cyan.Sprint(base.Version))
-		fmt.Printf("Deploy user:        %s\n", cyan.Sprint(topo.BaseTopo().GlobalOptions.User))
-		fmt.Printf("SSH type:           %s\n", cyan.Sprint(topo.BaseTopo().GlobalOptions.SSHType))
+		fmt.Fprintf(m.stdout, "Cluster type:       %s\n", cyan.Sprint(m.sysName))
+		fmt.Fprintf(m.stdout, "Cluster name:       %s\n", cyan.Sprint(name))
+		fmt.Fprintf(m.stdout, "Cluster version:    %s\n", cyan.Sprint(base.Version))
+		fmt.Fprintf(m.stdout, "Deploy user:        %s\n", cyan.Sprint(topo.BaseTopo().GlobalOptions.User))
+		fmt.Fprintf(m.stdout, "SSH type:           %s\n", cyan.Sprint(topo.BaseTopo().GlobalOptions.SSHType))
+		if base.QuarantinedHosts != nil && len(*base.QuarantinedHosts) > 0 {
+			fmt.Fprintf(m.stdout, "Quarantined hosts:  %s\n", color.YellowString(strings.Join(*base.QuarantinedHosts, ",")))
+		}
 
 		// display TLS info
 		if topo.BaseTopo().GlobalOptions.TLSEnabled {
-			fmt.Printf("TLS encryption:     %s\n", cyan.Sprint("enabled"))
-			fmt.Printf("CA certificate:     %s\n", cyan.Sprint(
+			fmt.Fprintf(m.stdout, "TLS encryption:     %s\n", cyan.Sprint("enabled"))
+			fmt.Fprintf(m.stdout, "CA certificate:     %s\n", cyan.Sprint(
 				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCACert),
 			))
-			fmt.Printf("Client private key: %s\n", cyan.Sprint(
+			fmt.Fprintf(m.stdout, "Client private key: %s\n", cyan.Sprint(
 				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSClientKey),
 			))
-			fmt.Printf("Client certificate: %s\n", cyan.Sprint(
+			fmt.Fprintf(m.stdout, "Client certificate: %s\n", cyan.Sprint(
 				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSClientCert),
 			))
 		}
 	}
 
 	// display topology
-	var clusterTable [][]string
+	header := []string{"ID", "Role", "Host", "Ports", "OS/Arch", "Status"}
 	if opt.ShowUptime {
-		clusterTable = append(clusterTable, []string{"ID", "Role", "Host", "Ports", "OS/Arch", "Status", "Since", "Data Dir", "Deploy Dir"})
-	} else {
-		clusterTable = append(clusterTable, []string{"ID", "Role", "Host", "Ports", "OS/Arch", "Status", "Data Dir", "Deploy Dir"})
+		header = append(header, "Since")
 	}
+	if opt.ShowDetail {
+		header = append(header, "Restarts (7d)")
+	}
+	clusterTable := [][]string{append(header, "Data Dir", "Deploy Dir")}
 
 	masterActive := make([]string, 0)
 	for _, v := range clusterInstInfos {
@@ -180,6 +217,9 @@ func (m *Manager) Display(name string, opt operator.Options) error {
 		if opt.ShowUptime {
 			row = append(row, v.Since)
 		}
+		if opt.ShowDetail {
+			row = append(row, formatRestarts(v.Restarts))
+		}
 		row = append(row, v.DataDir, v.DeployDir)
 		clusterTable = append(clusterTable, row)
 
@@ -198,23 +238,21 @@ func (m *Manager) Display(name string, opt operator.Options) error {
 	}
 
 	var dashboardAddr string
-	ctx := ctxt.New(
-		context.Background(),
+	// the status queried for every instance could be slightly stale
+	ctx := api.WithCacheContext(ctxt.New(
+		m.baseContext(name, opt),
 		opt.Concurrency,
 		m.logger,
-	)
+	), api.DefaultCacheTTL)
 	if t, ok := topo.(*spec.Specification); ok {
 		var err error
 		dashboardAddr, err = t.GetDashboardAddress(ctx, tlsCfg, statusTimeout, masterActive...)
 		if err == nil && !set.NewStringSet("", "auto", "none").Exist(dashboardAddr) {
-			scheme := "http"
-			if tlsCfg != nil {
-				scheme = "https"
-			}
+			dashboardURL := api.URL(tlsCfg != nil, dashboardAddr) + "/dashboard"
 			if m.logger.GetDisplayMode() == logprinter.DisplayModeJSON {
-				j.ClusterMetaInfo.DashboardURL = fmt.Sprintf("%s://%s/dashboard", scheme, dashboardAddr)
+				j.ClusterMetaInfo.DashboardURL = dashboardURL
 			} else {
-				fmt.Printf("Dashboard URL:      %s\n", cyan.Sprintf("%s://%s/dashboard", scheme, dashboardAddr))
+				fmt.Fprintf(m.stdout, "Dashboard URL:      %s\n", cyan.Sprint(dashboardURL))
 			}
 		}
 	}
@@ -222,36 +260,100 @@ func (m *Manager) Display(name string, opt operator.Options) error {
 	if m.logger.GetDisplayMode() != logprinter.DisplayModeJSON {
 		urls, exist := getGrafanaURLStr(clusterInstInfos)
 		if exist {
-			fmt.Printf("Grafana URL:        %s\n", cyan.Sprintf("%s", urls))
+			fmt.Fprintf(m.stdout, "Grafana URL:        %s\n", cyan.Sprintf("%s", urls))
+		}
+	}
+
+	var changefeeds []ChangefeedInfo
+	if t, ok := topo.(*spec.Specification); ok && opt.ShowDetail {
+		changefeeds, err = m.getChangefeedInfos(m.apiContext(name, opt), t, tlsCfg, statusTimeout)
+		if err != nil {
+			m.logger.Warnf("Failed to get changefeeds from TiCDC: %s", err)
 		}
 	}
 
+	var diskUsages []DiskForecast
+	if opt.ShowDetail {
+		var history []spec.DiskUsageHistory
+		if base.DiskUsages != nil {
+			history = *base.DiskUsages
+		}
+		horizon := time.Duration(opt.DiskFullHorizon) * 24 * time.Hour
+		diskUsages = forecastDiskUsage(history, clusterInstInfos, horizon, time.Now())
+	}
+
 	if m.logger.GetDisplayMode() == logprinter.DisplayModeJSON {
+		j.Changefeeds = changefeeds
+		j.DiskUsages = diskUsages
 		d, err := json.MarshalIndent(j, "", "  ")
 		if err != nil {
 			return err
 		}
-		fmt.Println(string(d))
+		fmt.Fprintln(m.stdout, string(d))
 		return nil
 	}
 
-	tui.PrintTable(clusterTable, true)
-	fmt.Printf("Total nodes: %d\n", len(clusterTable)-1)
+	tui.FprintTable(m.stdout, clusterTable, true)
+	fmt.Fprintf(m.stdout, "Total nodes: %d\n", len(clusterTable)-1)
+
+	if len(changefeeds) > 0 {
+		changefeedTable := [][]string{{"Changefeed", "State", "Checkpoint", "Lag", "Error"}}
+		for _, cf := range changefeeds {
+			changefeedTable = append(changefeedTable, []string{
+				color.CyanString(cf.ID),
+				formatChangefeedState(cf.State),
+				cf.Checkpoint,
+				cf.Lag,
+				cf.Error,
+			})
+		}
+		fmt.Fprintln(m.stdout)
+		tui.FprintTable(m.stdout, changefeedTable, true)
+	}
+
+	if len(diskUsages) > 0 {
+		diskTable := [][]string{{"ID", "Data Dir", "Used", "Capacity", "Growth/Day", "Projected Full"}}
+		var warnings []string
+		for _, d := range diskUsages {
+			fullAt := "-"
+			if d.FullAt != nil {
+				fullAt = d.FullAt.Format("2006-01-02")
+				if d.Warning {
+					fullAt = color.YellowString(fullAt)
+					warnings = append(warnings, fmt.Sprintf("%s (%s)", d.ID, d.FullAt.Format("2006-01-02")))
+				}
+			}
+			diskTable = append(diskTable, []string{
+				color.CyanString(d.ID),
+				d.DataDir,
+				units.BytesSize(float64(d.Used)),
+				units.BytesSize(float64(d.Total)),
+				formatDiskGrowth(d.GrowthPerDay),
+				fullAt,
+			})
+		}
+		fmt.Fprintln(m.stdout)
+		tui.FprintTable(m.stdout, diskTable, true)
+		if len(warnings) > 0 {
+			fmt.Fprintln(m.stdout, color.YellowString("\nWARN: the disk of the following instances is projected to be full within %d days, please consider scaling out:\n\t%s",
+				opt.DiskFullHorizon, strings.Join(warnings, "\n\t")))
+		}
+	}
 
 	if t, ok := topo.(*spec.Specification); ok {
 		// Check if TiKV's label set correctly
 		pdClient := api.NewPDClient(
 			context.WithValue(ctx, logprinter.ContextKeyLogger, m.logger),
 			masterActive,
-			10*time.Second,
+			api.RequestTimeout(ctx, 10*time.Second),
 			tlsCfg,
 		)
 
-		if lbs, placementRule, err := pdClient.GetLocationLabels(); err != nil {
+		if lbs, placementRule, err := pdClient.GetLocationLabels(ctx); err != nil {
 			m.logger.Debugf("get location labels from pd failed: %v", err)
 		} else if !placementRule {
-			if err := spec.CheckTiKVLabels(lbs, pdClient); err != nil {
-				color.Yellow("\nWARN: there is something wrong with TiKV labels, which may cause data losing:\n%v", err)
+			if err := spec.CheckTiKVLabels(lbs, pdLabelProvider{ctx: ctx, client: pdClient}); err != nil {
+				fmt.Fprintln(m.stdout, color.YellowString("\nWARN: there is something wrong with TiKV labels, which may cause data losing:\n%v", err))
 			}
 		}
 
@@ -265,6 +367,26 @@ func (m *Manager) Display(name string, opt operator.Options) error {
 	return nil
 }
 
+func formatDiskGrowth(growth int64) string {
+	if growth < 0 {
+		return "-" + units.BytesSize(float64(-growth))
+	}
+	return units.BytesSize(float64(growth))
+}
+
+func formatChangefeedState(state string) string {
+	switch strings.ToLower(state) {
+	case "normal", "finished":
+		return color.GreenString(state)
+	case "error", "failed", "warning":
+		return color.RedString(state)
+	case "stopped", "removed":
+		return color.YellowString(state)
+	default:
+		return state
+	}
+}
+
 func getGrafanaURLStr(clusterInstInfos []InstInfo) (result string, exist bool) {
 	var grafanaURLs []string
 	for _, instance := range clusterInstInfos {
@@ -319,22 +441,22 @@ func (m *Manager) DisplayTiKVLabels(name string, opt operator.Options) error {
 			j.ClusterMetaInfo.TLSClientCert = m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSClientCert)
 		}
 	} else {
-		fmt.Printf("Cluster type:       %s\n", cyan.Sprint(m.sysName))
-		fmt.Printf("Cluster name:       %s\n", cyan.Sprint(name))
-		fmt.Printf("Cluster version:    %s\n", cyan.Sprint(base.Version))
-		fmt.Printf("SSH type:           %s\n", cyan.Sprint(topo.BaseTopo().GlobalOptions.SSHType))
-		fmt.Printf("Component name:     %s\n", cyan.Sprint("TiKV"))
+		fmt.Fprintf(m.stdout, "Cluster type:       %s\n", cyan.Sprint(m.sysName))
+		fmt.Fprintf(m.stdout, "Cluster name:       %s\n", cyan.Sprint(name))
+		fmt.Fprintf(m.stdout, "Cluster version:    %s\n", cyan.Sprint(base.Version))
+		fmt.Fprintf(m.stdout, "SSH type:           %s\n", cyan.Sprint(topo.BaseTopo().GlobalOptions.SSHType))
+		fmt.Fprintf(m.stdout, "Component name:     %s\n", cyan.Sprint("TiKV"))
 
 		// display TLS info
 		if topo.BaseTopo().GlobalOptions.TLSEnabled {
-			fmt.Printf("TLS encryption:  	%s\n", cyan.Sprint("enabled"))
-			fmt.Printf("CA certificate:     %s\n", cyan.Sprint(
+			fmt.Fprintf(m.stdout, "TLS encryption:  	%s\n", cyan.Sprint("enabled"))
+			fmt.Fprintf(m.stdout, "CA certificate:     %s\n", cyan.Sprint(
 				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCACert),
 			))
-			fmt.Printf("Client private key: %s\n", cyan.Sprint(
+			fmt.Fprintf(m.stdout, "Client private key: %s\n", cyan.Sprint(
 				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSClientKey),
 			))
-			fmt.Printf("Client certificate: %s\n", cyan.Sprint(
+			fmt.Fprintf(m.stdout, "Client certificate: %s\n", cyan.Sprint(
 				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSClientCert),
 			))
 		}
@@ -352,11 +474,12 @@ func (m *Manager) DisplayTiKVLabels(name string, opt operator.Options) error {
 		}
 	}
 
-	ctx := ctxt.New(
-		context.Background(),
+	// the status queried for every instance could be slightly stale
+	ctx := api.WithCacheContext(ctxt.New(
+		m.baseContext(name, opt),
 		opt.Concurrency,
 		m.logger,
-	)
+	), api.DefaultCacheTTL)
 
 	masterList := topo.BaseTopo().MasterList
 	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
@@ -384,14 +507,14 @@ func (m *Manager) DisplayTiKVLabels(name string, opt operator.Options) error {
 
 	if _, ok := topo.(*spec.Specification); ok {
 		// Check if TiKV's label set correctly
-		pdClient := api.NewPDClient(ctx, masterActive, 10*time.Second, tlsCfg)
+		pdClient := api.NewPDClient(ctx, masterActive, api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
 		// No
-		locationLabel, _, err = pdClient.GetLocationLabels()
+		locationLabel, _, err = pdClient.GetLocationLabels(ctx)
 		if err != nil {
 			m.logger.Debugf("get location labels from pd failed: %v", err)
 		}
 
-		_, storeInfos, err := pdClient.GetTiKVLabels()
+		_, storeInfos, err := pdClient.GetTiKVLabels(ctx)
 		if err != nil {
 			m.logger.Debugf("get tikv state and labels from pd failed: %v", err)
 		}
@@ -438,42 +561,72 @@ func (m *Manager) DisplayTiKVLabels(name string, opt operator.Options) error {
 		if err != nil {
 			return err
 		}
-		fmt.Println(string(d))
+		fmt.Fprintln(m.stdout, string(d))
 		return nil
 	}
-	fmt.Printf("Location labels:    %s\n", cyan.Sprint(strings.Join(locationLabel, ",")))
-	tui.PrintTable(clusterTable, true)
-	fmt.Printf("Total nodes: %d\n", len(clusterTable)-1)
+	fmt.Fprintf(m.stdout, "Location labels:    %s\n", cyan.Sprint(strings.Join(locationLabel, ",")))
+	tui.FprintTable(m.stdout, clusterTable, true)
+	fmt.Fprintf(m.stdout, "Total nodes: %d\n", len(clusterTable)-1)
 
 	return nil
 }
 
 // GetClusterTopology get the topology of the cluster.
 func (m *Manager) GetClusterTopology(name string, opt operator.Options) ([]InstInfo, error) {
-	ctx := ctxt.New(
-		context.Background(),
-		opt.Concurrency,
-		m.logger,
-	)
+	return m.getClusterTopology(name, opt, false)
+}
+
+// getClusterTopology gets the topology of the cluster, the disk usages of the
+// data dirs are also sampled if they are due and sampleDisks is set, or in
+// detail mode
+func (m *Manager) getClusterTopology(name string, opt operator.Options, sampleDisks bool) ([]InstInfo, error) {
 	metadata, err := m.meta(name)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
 		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
 		return nil, err
 	}
+	// the status queried for every instance could be slightly stale
+	ctx := api.WithCacheContext(ctxt.New(
+		m.baseContext(name, opt),
+		opt.Concurrency,
+		m.logger,
+	), api.DefaultCacheTTL)
 
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
 
 	statusTimeout := time.Duration(opt.APITimeout) * time.Second
 
-	err = SetSSHKeySet(ctx, m.specManager.Path(name, "ssh", "id_rsa"), m.specManager.Path(name, "ssh", "id_rsa.pub"))
+	switch {
+	case !opt.Viewer:
+		err = SetSSHKeySet(ctx, m.specManager.Path(name, "ssh", "id_rsa"), m.specManager.Path(name, "ssh", "id_rsa.pub"))
+		if err != nil {
+			return nil, err
+		}
+		err = SetClusterSSH(ctx, topo, base.User, opt.SSHTimeout, opt.SSHType, topo.BaseTopo().GlobalOptions.SSHType)
+	case opt.ViewerIdentityFile != "":
+		// the read-only credentials are used instead of the private key of
+		// the cluster, the commands needing privileges fail and are skipped
+		err = SetSSHKeySet(ctx, opt.ViewerIdentityFile, "")
+		if err != nil {
+			return nil, err
+		}
+		err = SetClusterSSH(ctx, topo, opt.ViewerUser, opt.SSHTimeout, opt.SSHType, topo.BaseTopo().GlobalOptions.SSHType)
+	default:
+		// no executor is set, the status is queried from the APIs only
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	err = SetClusterSSH(ctx, topo, base.User, opt.SSHTimeout, opt.SSHType, topo.BaseTopo().GlobalOptions.SSHType)
-	if err != nil {
-		return nil, err
+	var skipHosts set.StringSet
+	if opt.SkipUnreachable {
+		if opt.Viewer {
+			return nil, perrs.New("the unreachable hosts can't be quarantined in the viewer mode")
+		}
+		if skipHosts, err = m.quarantineUnreachableHosts(name, metadata, opt); err != nil {
+			return nil, err
+		}
 	}
 
 	filterRoles := set.NewStringSet(opt.Roles...)
@@ -492,6 +645,12 @@ func (m *Manager) GetClusterTopology(name string, opt operator.Options) ([]InstI
 		if ins.ComponentName() != spec.ComponentPD && ins.ComponentName() != spec.ComponentDMMaster {
 			return
 		}
+		if skipHosts.Exist(ins.GetHost()) {
+			mu.Lock()
+			masterStatus[ins.ID()] = statusUnreachable
+			mu.Unlock()
+			return
+		}
 
 		status := ins.Status(ctx, statusTimeout, tlsCfg, masterList...)
 		mu.Lock()
@@ -509,6 +668,11 @@ func (m *Manager) GetClusterTopology(name string, opt operator.Options) ([]InstI
 	}
 
 	clusterInstInfos := []InstInfo{}
+	var diskHistory []spec.DiskUsageHistory
+	if base.DiskUsages != nil {
+		diskHistory = *base.DiskUsages
+	}
+	now := time.Now()
 
 	topo.IterInstance(func(ins spec.Instance) {
 		// apply role filter
@@ -528,26 +692,49 @@ func (m *Manager) GetClusterTopology(name string, opt operator.Options) ([]InstI
 		}
 
 		var status string
-		switch ins.ComponentName() {
-		case spec.ComponentPD:
+		switch {
+		case skipHosts.Exist(ins.GetHost()):
+			status = statusUnreachable
+		case ins.ComponentName() == spec.ComponentPD:
 			status = masterStatus[ins.ID()]
 			instAddr := fmt.Sprintf("%s:%d", ins.GetHost(), ins.GetPort())
 			if dashboardAddr == instAddr {
 				status += "|UI"
 			}
-		case spec.ComponentDMMaster:
+		case ins.ComponentName() == spec.ComponentDMMaster:
 			status = masterStatus[ins.ID()]
 		default:
 			status = ins.Status(ctx, statusTimeout, tlsCfg, masterActive...)
 		}
 
-		since := "-"
-		if opt.ShowUptime {
-			since = formatInstanceSince(ins.Uptime(ctx, statusTimeout, tlsCfg))
+		// the disk usages are sampled for the forecast periodically, and
+		// always shown in detail mode
+		var disks []dataDirUsage
+		sampleDisk := opt.ShowDetail || (sampleDisks && diskSampleDue(diskHistory, ins.ID(), now))
+		if sampleDisk && status != statusUnreachable && ins.DataDir() != "" {
+			if e, found := ctxt.GetInner(ctx).GetExecutor(ins.GetHost()); found {
+				var err error
+				disks, err = getDiskUsages(checkpoint.NewContext(ctx), e, spec.MultiDirAbs(base.User, ins.DataDir()))
+				if err != nil {
+					m.logger.Debugf("Failed to get disk usage of %s: %s", ins.ID(), err)
+				}
+			}
+		}
+		// the first data dir is shown for components with multiple data dirs
+		var diskUsed, diskTotal uint64
+		if len(disks) > 0 && opt.ShowDetail {
+			diskUsed, diskTotal = disks[0].used, disks[0].total
+		}
+
+		// the uptime is also used to detect the restarts in detail mode
+		showUptime := opt.ShowUptime || opt.ShowDetail
+		var uptime time.Duration
+		if showUptime && status != statusUnreachable {
+			uptime = ins.Uptime(ctx, statusTimeout, tlsCfg)
 		}
 
 		// Query the service status and uptime
-		if status == "-" || (opt.ShowUptime && since == "-") {
+		if status != statusUnreachable && (status == "-" || (showUptime && uptime == 0)) {
 			e, found := ctxt.GetInner(ctx).GetExecutor(ins.GetHost())
 			if found {
 				nctx := checkpoint.NewContext(ctx)
@@ -561,12 +748,17 @@ func (m *Manager) GetClusterTopology(name string, opt operator.Options) ([]InstI
 						}
 					}
 				}
-				if opt.ShowUptime && since == "-" {
-					since = formatInstanceSince(parseSystemctlSince(active))
+				if showUptime && uptime == 0 {
+					uptime = parseSystemctlSince(active)
 				}
 			}
 		}
 
+		since := "-"
+		if opt.ShowUptime {
+			since = formatInstanceSince(uptime)
+		}
+
 		// check if the role is patched
 		roleName := ins.Role()
 		if ins.IsPatched() {
@@ -585,6 +777,10 @@ func (m *Manager) GetClusterTopology(name string, opt operator.Options) ([]InstI
 			ComponentName: ins.ComponentName(),
 			Port:          ins.GetPort(),
 			Since:         since,
+			uptime:        uptime,
+			DiskUsed:      diskUsed,
+			DiskTotal:     diskTotal,
+			disks:         disks,
 		})
 		mu.Unlock()
 	}, opt.Concurrency)
@@ -621,7 +817,7 @@ func formatInstanceStatus(status string) string {
 		return color.HiGreenString(status)
 	case startsWith("up", "healthy", "free"):
 		return color.GreenString(status)
-	case startsWith("down", "err", "inactive"): // down, down|ui
+	case startsWith("down", "err", "inactive", "unreachable"): // down, down|ui
 		return color.RedString(status)
 	case startsWith("tombstone", "disconnected", "n/a"), strings.Contains(strings.ToLower(status), "offline"):
 		return color.YellowString(status)
@@ -630,6 +826,17 @@ func formatInstanceStatus(status string) string {
 	}
 }
 
+func formatRestarts(restarts *int) string {
+	switch {
+	case restarts == nil:
+		return "-"
+	case *restarts > 0:
+		return color.YellowString("%d", *restarts)
+	default:
+		return "0"
+	}
+}
+
 func formatInstanceSince(uptime time.Duration) string {
 	if uptime == 0 {
 		return "-"
@@ -721,7 +928,7 @@ func SetClusterSSH(ctx context.Context, topo spec.Topology, deployUser string, s
 				Timeout: time.Second * time.Duration(sshTimeout),
 			}
 
-			e, err := executor.New(sshType, false, cf)
+			e, err := executor.NewFromContext(ctx, sshType, false, cf)
 			if err != nil {
 				return err
 			}
@@ -733,7 +940,7 @@ func SetClusterSSH(ctx context.Context, topo spec.Topology, deployUser string, s
 }
 
 // DisplayDashboardInfo prints the dashboard address of cluster
-func (m *Manager) DisplayDashboardInfo(clusterName string, timeout time.Duration, tlsCfg *tls.Config) error {
+func (m *Manager) DisplayDashboardInfo(clusterName string, tlsCfg *tls.Config, gOpt operator.Options) error {
 	metadata, err := spec.ClusterMetadata(clusterName)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
 		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
@@ -745,9 +952,9 @@ func (m *Manager) DisplayDashboardInfo(clusterName string, timeout time.Duration
 		pdEndpoints = append(pdEndpoints, fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort))
 	}
 
-	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, m.logger)
-	pdAPI := api.NewPDClient(ctx, pdEndpoints, timeout, tlsCfg)
-	dashboardAddr, err := pdAPI.GetDashboardAddress()
+	ctx := m.apiContext(clusterName, gOpt)
+	pdAPI := api.NewPDClient(ctx, pdEndpoints, api.RequestTimeout(ctx, time.Second*time.Duration(gOpt.APITimeout)), tlsCfg)
+	dashboardAddr, err := pdAPI.GetDashboardAddress(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve TiDB Dashboard instance from PD: %s", err)
 	}
@@ -765,19 +972,31 @@ func (m *Manager) DisplayDashboardInfo(clusterName string, timeout time.Duration
 	u.Path = "/dashboard/"
 
 	if tlsCfg != nil {
-		fmt.Println(
+		fmt.Fprintln(m.stdout,
 			"Client certificate:",
 			color.CyanString(m.specManager.Path(clusterName, spec.TLSCertKeyDir, spec.PFXClientCert)),
 		)
-		fmt.Println(
+		fmt.Fprintln(m.stdout,
 			"Certificate password:",
 			color.CyanString(crypto.PKCS12Password),
 		)
 	}
-	fmt.Println(
+	fmt.Fprintln(m.stdout,
 		"Dashboard URL:",
 		color.CyanString(u.String()),
 	)
 
 	return nil
 }
+
+// pdLabelProvider provides the store labels from PD with the context of the
+// requests
+type pdLabelProvider struct {
+	ctx    context.Context
+	client *api.PDClient
+}
+
+// GetTiKVLabels implements spec.TiKVLabelProvider
+func (p pdLabelProvider) GetTiKVLabels() (map[string]map[string]string, []map[string]api.LabelInfo, error) {
+	return p.client.GetTiKVLabels(p.ctx)
+}
@@ -41,6 +41,12 @@ func (m *Manager) EditConfig(name string, opt EditConfigOptions, skipConfirm boo
 		return err
 	}
 
+	release, pause, err := m.lockPausableOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return err
@@ -53,7 +59,20 @@ func (m *Manager) EditConfig(name string, opt EditConfigOptions, skipConfirm boo
 		return perrs.AddStack(err)
 	}
 
+	// the cluster is not locked while the topology is being edited, the
+	// changes are discarded if the meta is saved by others meanwhile
+	var resume func() error
+	if pause != nil {
+		if resume, err = pause(); err != nil {
+			return err
+		}
+	}
 	newTopo, err := m.editTopo(topo, data, opt, skipConfirm)
+	if resume != nil {
+		if rerr := resume(); rerr != nil {
+			return rerr
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -63,7 +82,9 @@ func (m *Manager) EditConfig(name string, opt EditConfigOptions, skipConfirm boo
 	}
 
 	m.logger.Infof("Applying changes...")
-	metadata.SetTopology(newTopo)
+	if err := metadata.SetTopology(newTopo); err != nil {
+		return err
+	}
 	err = m.specManager.SaveMeta(name, metadata)
 	if err != nil {
 		return perrs.Annotate(err, "failed to save meta")
@@ -115,10 +136,10 @@ func (m *Manager) editTopo(origTopo spec.Topology, data []byte, opt EditConfigOp
 	newTopo := m.specManager.NewMetadata().GetTopology()
 	err = yaml.UnmarshalStrict(newData, newTopo)
 	if err != nil {
-		fmt.Print(color.RedString("New topology could not be saved: "))
+		fmt.Fprint(m.stdout, color.RedString("New topology could not be saved: "))
 		m.logger.Infof("Failed to parse topology file: %v", err)
 		if opt.NewTopoFile == "" {
-			if pass, _ := tui.PromptForConfirmNo("Do you want to continue editing? [Y/n]: "); !pass {
+			if pass, _ := m.prompter.PromptForConfirmNo("Do you want to continue editing? [Y/n]: "); !pass {
 				return m.editTopo(origTopo, newData, opt, skipConfirm)
 			}
 		}
@@ -128,10 +149,10 @@ func (m *Manager) editTopo(origTopo spec.Topology, data []byte, opt EditConfigOp
 
 	// report error if immutable field has been changed
 	if err := utils.ValidateSpecDiff(origTopo, newTopo); err != nil {
-		fmt.Print(color.RedString("New topology could not be saved: "))
+		fmt.Fprint(m.stdout, color.RedString("New topology could not be saved: "))
 		m.logger.Errorf("%s", err)
 		if opt.NewTopoFile == "" {
-			if pass, _ := tui.PromptForConfirmNo("Do you want to continue editing? [Y/n]: "); !pass {
+			if pass, _ := m.prompter.PromptForConfirmNo("Do you want to continue editing? [Y/n]: "); !pass {
 				return m.editTopo(origTopo, newData, opt, skipConfirm)
 			}
 		}
@@ -149,10 +170,10 @@ func (m *Manager) editTopo(origTopo spec.Topology, data []byte, opt EditConfigOp
 		return nil, nil
 	}
 
-	utils.ShowDiff(string(origData), string(newData), os.Stdout)
+	utils.ShowDiff(string(origData), string(newData), m.stdout)
 
 	if !skipConfirm {
-		if err := tui.PromptForConfirmOrAbortError(
+		if err := m.prompter.PromptForConfirmOrAbortError(
 			color.HiYellowString("Please check change highlight above, do you want to apply the change? [y/N]:"),
 		); err != nil {
 			return nil, err
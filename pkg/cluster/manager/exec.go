@@ -14,8 +14,8 @@
 package manager
 
 import (
-	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -79,8 +79,10 @@ func (m *Manager) Exec(name string, opt ExecOptions, gOpt operator.Options) erro
 		}
 	})
 
+	hosts := set.NewStringSet()
 	for hostKey, i := range uniqueHosts {
 		host := strings.Split(hostKey, ":")[0]
+		hosts.Insert(host)
 		for _, cmd := range i.Slice() {
 			shellTasks = append(shellTasks,
 				task.NewBuilder(m.logger).
@@ -89,6 +91,8 @@ func (m *Manager) Exec(name string, opt ExecOptions, gOpt operator.Options) erro
 		}
 	}
 
+	m.warnForeignInstances(hosts)
+
 	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
 	if err != nil {
 		return err
@@ -99,7 +103,7 @@ func (m *Manager) Exec(name string, opt ExecOptions, gOpt operator.Options) erro
 		Build()
 
 	execCtx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -133,3 +137,45 @@ func (m *Manager) Exec(name string, opt ExecOptions, gOpt operator.Options) erro
 
 	return nil
 }
+
+// foreignInstances returns the instances of the other kinds of clusters in
+// the host registry on the hosts, e.g. the DM workers sharing the hosts with
+// a TiDB cluster, grouped by the host
+func foreignInstances(entries []spec.HostEntry, hosts set.StringSet) map[string][]string {
+	instances := make(map[string][]string)
+	for _, e := range entries {
+		if !hosts.Exist(e.Host) || e.Component == spec.RoleMonitor {
+			continue
+		}
+		id := e.Component
+		if len(e.Ports) > 0 {
+			id = fmt.Sprintf("%s:%d", e.Component, e.Ports[0])
+		}
+		instances[e.Host] = append(instances[e.Host], fmt.Sprintf("%s (%s cluster %s)", id, e.Kind, e.Cluster))
+	}
+	for _, insts := range instances {
+		sort.Strings(insts)
+	}
+	return instances
+}
+
+// warnForeignInstances warns the instances of the other kinds of clusters on
+// the hosts, which are affected by the commands run or the files transferred
+// on the hosts too
+func (m *Manager) warnForeignInstances(hosts set.StringSet) {
+	entries, err := m.specManager.HostRegistry().ForeignEntries()
+	if err != nil {
+		m.logger.Warnf("Failed to read the host registry: %s", err)
+		return
+	}
+	instances := foreignInstances(entries, hosts)
+	shared := make([]string, 0, len(instances))
+	for host := range instances {
+		shared = append(shared, host)
+	}
+	sort.Strings(shared)
+	for _, host := range shared {
+		m.logger.Warnf("Host %s is shared with the instances of other clusters: %s",
+			host, strings.Join(instances[host], ", "))
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForeignInstances(t *testing.T) {
+	entries := []spec.HostEntry{
+		{Kind: "dm", Cluster: "dm-test", Component: spec.ComponentDMWorker, Host: "172.16.5.140", Ports: []int{8262}},
+		{Kind: "dm", Cluster: "dm-test", Component: spec.ComponentDMMaster, Host: "172.16.5.140", Ports: []int{8261, 8291}},
+		{Kind: "dm", Cluster: "dm-test", Component: spec.RoleMonitor, Host: "172.16.5.140", Ports: []int{9100, 9115}},
+		{Kind: "dm", Cluster: "dm-test", Component: spec.ComponentDMWorker, Host: "172.16.5.141", Ports: []int{8262}},
+	}
+
+	instances := foreignInstances(entries, set.NewStringSet("172.16.5.140", "172.16.5.142"))
+	assert.Equal(t, map[string][]string{
+		"172.16.5.140": {
+			"dm-master:8261 (dm cluster dm-test)",
+			"dm-worker:8262 (dm cluster dm-test)",
+		},
+	}, instances)
+	assert.Empty(t, foreignInstances(nil, set.NewStringSet("172.16.5.140")))
+}
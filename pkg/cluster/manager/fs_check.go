@@ -0,0 +1,250 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var errDataFsCheckFailed = errNSDeploy.NewType("data_fs_check_failed", utils.ErrTraitPreCheck)
+
+// dataFsRequirements are the filesystems supported for the data dirs of the
+// components and the mount options required of each filesystem
+var dataFsRequirements = map[string]map[string][]string{
+	spec.ComponentTiKV:    {"ext4": {"nodelalloc"}, "xfs": nil},
+	spec.ComponentTiFlash: {"ext4": {"nodelalloc"}, "xfs": nil},
+	spec.ComponentPD:      {"ext4": nil, "xfs": nil},
+	spec.ComponentCDC:     {"ext4": nil, "xfs": nil},
+}
+
+// dataDir is a data dir of an instance, the existing ones are only checked
+// for sharing the filesystem with the new ones
+type dataDir struct {
+	instance  string
+	component string
+	path      string
+	existing  bool
+}
+
+// dataDirMount is the filesystem mounted of a data dir
+type dataDirMount struct {
+	Target  string
+	FSType  string
+	Options []string
+}
+
+// dataFsScript prints the filesystem of the dirs, the nearest existing parent
+// is looked up for the dirs not created yet
+func dataFsScript(dirs []string) string {
+	quoted := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		quoted = append(quoted, fmt.Sprintf("'%s'", dir))
+	}
+	return fmt.Sprintf(`for d in %s; do p="$d"; while [ ! -e "$p" ]; do p=$(dirname "$p"); done; `+
+		`echo "$d $(findmnt -n -o TARGET,FSTYPE,OPTIONS -T "$p" | head -n 1)"; done`,
+		strings.Join(quoted, " "))
+}
+
+// parseDataFsMounts parses the output of dataFsScript
+func parseDataFsMounts(output string) map[string]dataDirMount {
+	mounts := make(map[string]dataDirMount)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		mounts[fields[0]] = dataDirMount{
+			Target:  fields[1],
+			FSType:  fields[2],
+			Options: strings.Split(fields[3], ","),
+		}
+	}
+	return mounts
+}
+
+// checkDataDirMounts returns the problems of the filesystems of the data dirs
+// on a host
+func checkDataDirMounts(host string, dirs []dataDir, mounts map[string]dataDirMount) []string {
+	var problems []string
+	sharing := make(map[string]set.StringSet) // mount target -> instances
+	for _, dir := range dirs {
+		mount, ok := mounts[dir.path]
+		if !ok {
+			if !dir.existing {
+				problems = append(problems, fmt.Sprintf("%s: failed to detect the filesystem of %s", dir.instance, dir.path))
+			}
+			continue
+		}
+		if sharing[mount.Target] == nil {
+			sharing[mount.Target] = set.NewStringSet()
+		}
+		sharing[mount.Target].Insert(dir.instance)
+		if dir.existing {
+			continue
+		}
+
+		if mount.Target == "/" {
+			problems = append(problems, fmt.Sprintf("%s: data dir %s is on the root filesystem", dir.instance, dir.path))
+		}
+		options, supported := dataFsRequirements[dir.component][mount.FSType]
+		if !supported {
+			problems = append(problems, fmt.Sprintf("%s: data dir %s is on %s mounted at %s, which is not supported",
+				dir.instance, dir.path, mount.FSType, mount.Target))
+			continue
+		}
+		mounted := set.NewStringSet(mount.Options...)
+		for _, opt := range options {
+			if !mounted.Exist(opt) {
+				problems = append(problems, fmt.Sprintf("%s: %s mounted at %s for data dir %s does not have '%s' option set",
+					dir.instance, mount.FSType, mount.Target, dir.path, opt))
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		mount, ok := mounts[dir.path]
+		if !ok || dir.existing {
+			continue
+		}
+		if instances := sharing[mount.Target].Slice(); len(instances) > 1 {
+			sort.Strings(instances)
+			problems = append(problems, fmt.Sprintf("%s: filesystem mounted at %s is shared by %s",
+				dir.instance, mount.Target, strings.Join(instances, ", ")))
+		}
+	}
+	for i := range problems {
+		problems[i] = host + " " + problems[i]
+	}
+	return problems
+}
+
+// checkDataFilesystems validates the filesystems of the data dirs of the I/O
+// sensitive components of topo are supported, mounted with the options
+// required, not the root filesystem and not shared with other instances on
+// the same host, including the ones of existing if it's not nil
+func (m *Manager) checkDataFilesystems(
+	s, p *tui.SSHConnectionProps,
+	topo spec.Topology,
+	existing spec.Topology,
+	gOpt *operator.Options,
+	user string,
+) error {
+	deployUser := topo.BaseTopo().GlobalOptions.User
+	hostDirs := make(map[string][]dataDir)
+	hostSSHPort := make(map[string]int)
+	collect := func(inst spec.Instance, isExisting bool) {
+		if _, ok := dataFsRequirements[inst.ComponentName()]; !ok {
+			return
+		}
+		if _, ok := hostSSHPort[inst.GetHost()]; !ok && isExisting {
+			return
+		}
+		for _, dir := range spec.MultiDirAbs(deployUser, inst.DataDir()) {
+			hostDirs[inst.GetHost()] = append(hostDirs[inst.GetHost()], dataDir{
+				instance:  inst.ID(),
+				component: inst.ComponentName(),
+				path:      dir,
+				existing:  isExisting,
+			})
+		}
+	}
+	topo.IterInstance(func(inst spec.Instance) {
+		if _, ok := dataFsRequirements[inst.ComponentName()]; ok {
+			hostSSHPort[inst.GetHost()] = inst.GetSSHPort()
+		}
+		collect(inst, false)
+	})
+	if existing != nil {
+		existing.IterInstance(func(inst spec.Instance) {
+			collect(inst, true)
+		})
+	}
+	if len(hostDirs) == 0 {
+		return nil
+	}
+
+	globalSSHType := topo.BaseTopo().GlobalOptions.SSHType
+	var detectTasks []*task.StepDisplay
+	for host, dirs := range hostDirs {
+		paths := make([]string, 0, len(dirs))
+		for _, dir := range dirs {
+			paths = append(paths, dir.path)
+		}
+		t := task.NewBuilder(m.logger).
+			RootSSH(
+				host,
+				hostSSHPort[host],
+				user,
+				s.Password,
+				s.IdentityFile,
+				s.IdentityFilePassphrase,
+				gOpt.SSHTimeout,
+				gOpt.OptTimeout,
+				gOpt.SSHProxyHost,
+				gOpt.SSHProxyPort,
+				gOpt.SSHProxyUser,
+				p.Password,
+				p.IdentityFile,
+				p.IdentityFilePassphrase,
+				gOpt.SSHProxyTimeout,
+				gOpt.SSHType,
+				globalSSHType,
+			).
+			Shell(host, dataFsScript(paths), "", false).
+			BuildAsStep(fmt.Sprintf("  - Detecting filesystems of data dirs on %s", host))
+		detectTasks = append(detectTasks, t)
+	}
+
+	ctx := ctxt.New(
+		m.baseContext("", *gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	t := task.NewBuilder(m.logger).
+		ParallelStep("+ Detect filesystems of data dirs", false, detectTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		return perrs.Annotate(err, "failed to detect filesystems of data dirs")
+	}
+
+	var problems []string
+	for host, dirs := range hostDirs {
+		stdout, _, ok := ctxt.GetInner(ctx).GetOutputs(host)
+		if !ok {
+			return fmt.Errorf("no detect results found for %s", host)
+		}
+		problems = append(problems, checkDataDirMounts(host, dirs, parseDataFsMounts(string(stdout)))...)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return errDataFsCheckFailed.
+		New("The filesystems of some data dirs are not suitable:\n  %s", strings.Join(problems, "\n  ")).
+		WithProperty(tui.SuggestionFromString(
+			"Please mount ext4 or xfs filesystems with the options required for the data dirs, " +
+				"or use --ignore-fs-check to skip the check."))
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDataFsMounts(t *testing.T) {
+	mounts := parseDataFsMounts("/data1/tikv /data1 ext4 rw,noatime,nodelalloc\n" +
+		"/data2/pd / xfs rw,relatime\n" +
+		"/data3/cdc \n")
+	assert.Equal(t, map[string]dataDirMount{
+		"/data1/tikv": {Target: "/data1", FSType: "ext4", Options: []string{"rw", "noatime", "nodelalloc"}},
+		"/data2/pd":   {Target: "/", FSType: "xfs", Options: []string{"rw", "relatime"}},
+	}, mounts)
+}
+
+func TestCheckDataDirMounts(t *testing.T) {
+	mounts := map[string]dataDirMount{
+		"/data1/tikv":    {Target: "/data1", FSType: "ext4", Options: []string{"rw", "nodelalloc"}},
+		"/data2/tiflash": {Target: "/data2", FSType: "ext4", Options: []string{"rw"}},
+		"/data3/pd":      {Target: "/", FSType: "xfs", Options: []string{"rw"}},
+		"/data4/cdc":     {Target: "/data4", FSType: "btrfs", Options: []string{"rw"}},
+		"/data1/old":     {Target: "/data1", FSType: "ext4", Options: []string{"rw", "nodelalloc"}},
+	}
+
+	problems := checkDataDirMounts("h1", []dataDir{
+		{instance: "h1:20160", component: "tikv", path: "/data1/tikv"},
+	}, mounts)
+	assert.Empty(t, problems)
+
+	problems = checkDataDirMounts("h1", []dataDir{
+		{instance: "h1:9000", component: "tiflash", path: "/data2/tiflash"},
+		{instance: "h1:2379", component: "pd", path: "/data3/pd"},
+		{instance: "h1:8300", component: "cdc", path: "/data4/cdc"},
+		{instance: "h1:20161", component: "tikv", path: "/data5/tikv"},
+	}, mounts)
+	assert.Equal(t, []string{
+		"h1 h1:9000: ext4 mounted at /data2 for data dir /data2/tiflash does not have 'nodelalloc' option set",
+		"h1 h1:2379: data dir /data3/pd is on the root filesystem",
+		"h1 h1:8300: data dir /data4/cdc is on btrfs mounted at /data4, which is not supported",
+		"h1 h1:20161: failed to detect the filesystem of /data5/tikv",
+	}, problems)
+
+	// the filesystem is shared with an existing instance
+	problems = checkDataDirMounts("h1", []dataDir{
+		{instance: "h1:20160", component: "tikv", path: "/data1/tikv"},
+		{instance: "h1:20170", component: "tikv", path: "/data1/old", existing: true},
+	}, mounts)
+	assert.Equal(t, []string{
+		"h1 h1:20160: filesystem mounted at /data1 is shared by h1:20160, h1:20170",
+	}, problems)
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/repository"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var errHostRequirementsNotMet = errNSDeploy.NewType("host_requirements_not_met", utils.ErrTraitPreCheck)
+
+// hostFactsScript prints the kernel version and the libc info of the host
+const hostFactsScript = "uname -r; ldd --version 2>&1 | head -n 2"
+
+// parseHostFacts parses the output of hostFactsScript
+func parseHostFacts(output string) v1manifest.HostFacts {
+	var facts v1manifest.HostFacts
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > 0 {
+		facts.KernelVersion = strings.TrimSpace(lines[0])
+	}
+	if len(lines) < 2 {
+		return facts
+	}
+
+	ldd := strings.TrimSpace(lines[1])
+	switch {
+	case strings.Contains(ldd, "musl"):
+		// musl libc (x86_64)
+		// Version 1.2.2
+		facts.Libc = "musl"
+		if len(lines) > 2 {
+			facts.LibcVersion = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[2]), "Version"))
+		}
+	case strings.Contains(ldd, "libc") || strings.Contains(ldd, "GLIBC"):
+		// ldd (GNU libc) 2.17
+		facts.Libc = "glibc"
+		if fields := strings.Fields(ldd); len(fields) > 0 {
+			facts.LibcVersion = fields[len(fields)-1]
+		}
+	}
+	return facts
+}
+
+// checkHostRequirements validates the hosts of the instances against the
+// requirements declared in the component manifests, e.g. the minimum glibc
+// version, the hosts are only connected if any requirement is declared
+func (m *Manager) checkHostRequirements(
+	s, p *tui.SSHConnectionProps,
+	topo spec.Topology,
+	clusterVersion string,
+	gOpt *operator.Options,
+	user string,
+) error {
+	type usage struct {
+		component string
+		version   string
+		requires  *v1manifest.HostRequirements
+	}
+	hostUsages := make(map[string][]usage)
+	hostSSHPort := make(map[string]int)
+	items := make(map[string]*v1manifest.VersionItem)
+
+	var iterErr error
+	topo.IterInstance(func(inst spec.Instance) {
+		if iterErr != nil || inst.ComponentName() == spec.ComponentTiSpark {
+			return
+		}
+		version := m.bindVersion(inst.ComponentName(), clusterVersion)
+		key := fmt.Sprintf("%s:%s:%s/%s", inst.ComponentName(), version, inst.OS(), inst.Arch())
+		item, ok := items[key]
+		if !ok {
+			item, iterErr = environment.GlobalEnv().V1Repository().WithOptions(repository.Options{
+				GOOS:   inst.OS(),
+				GOARCH: inst.Arch(),
+			}).ComponentVersion(inst.ComponentName(), version, false)
+			if iterErr != nil {
+				return
+			}
+			items[key] = item
+		}
+		if item.Requires == nil {
+			return
+		}
+		hostUsages[inst.GetHost()] = append(hostUsages[inst.GetHost()], usage{inst.ComponentName(), version, item.Requires})
+		hostSSHPort[inst.GetHost()] = inst.GetSSHPort()
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+	if len(hostUsages) == 0 {
+		return nil
+	}
+
+	globalSSHType := topo.BaseTopo().GlobalOptions.SSHType
+	var detectTasks []*task.StepDisplay
+	for host, port := range hostSSHPort {
+		t := task.NewBuilder(m.logger).
+			RootSSH(
+				host,
+				port,
+				user,
+				s.Password,
+				s.IdentityFile,
+				s.IdentityFilePassphrase,
+				gOpt.SSHTimeout,
+				gOpt.OptTimeout,
+				gOpt.SSHProxyHost,
+				gOpt.SSHProxyPort,
+				gOpt.SSHProxyUser,
+				p.Password,
+				p.IdentityFile,
+				p.IdentityFilePassphrase,
+				gOpt.SSHProxyTimeout,
+				gOpt.SSHType,
+				globalSSHType,
+			).
+			Shell(host, hostFactsScript, "", false).
+			BuildAsStep(fmt.Sprintf("  - Detecting libc and kernel of %s", host))
+		detectTasks = append(detectTasks, t)
+	}
+
+	ctx := ctxt.New(
+		m.baseContext("", *gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	t := task.NewBuilder(m.logger).
+		ParallelStep("+ Detect host requirements of components", false, detectTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		return perrs.Annotate(err, "failed to detect libc and kernel of hosts")
+	}
+
+	var unmet []string
+	for host, usages := range hostUsages {
+		stdout, _, ok := ctxt.GetInner(ctx).GetOutputs(host)
+		if !ok {
+			return fmt.Errorf("no detect results found for %s", host)
+		}
+		facts := parseHostFacts(string(stdout))
+
+		checked := make(map[string]bool)
+		for _, u := range usages {
+			if checked[u.component] {
+				continue
+			}
+			checked[u.component] = true
+			for _, reason := range u.requires.Check(facts) {
+				unmet = append(unmet, fmt.Sprintf("%s %s %s", u.component, u.version, strings.Replace(reason, "host", "host "+host, 1)))
+			}
+		}
+	}
+	if len(unmet) == 0 {
+		return nil
+	}
+
+	sort.Strings(unmet)
+	return errHostRequirementsNotMet.
+		New("Some hosts do not meet the requirements of the components:\n  %s", strings.Join(unmet, "\n  ")).
+		WithProperty(tui.SuggestionFromString("Please upgrade the hosts or deploy the components to other hosts."))
+}
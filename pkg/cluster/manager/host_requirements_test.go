@@ -0,0 +1,43 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHostFacts(t *testing.T) {
+	facts := parseHostFacts("3.10.0-1160.el7.x86_64\nldd (GNU libc) 2.17\nCopyright (C) 2012 Free Software Foundation, Inc.\n")
+	assert.Equal(t, v1manifest.HostFacts{
+		Libc:          "glibc",
+		LibcVersion:   "2.17",
+		KernelVersion: "3.10.0-1160.el7.x86_64",
+	}, facts)
+
+	facts = parseHostFacts("5.15.0-1019-aws\nldd (Ubuntu GLIBC 2.35-0ubuntu3.1) 2.35\n")
+	assert.Equal(t, "glibc", facts.Libc)
+	assert.Equal(t, "2.35", facts.LibcVersion)
+
+	facts = parseHostFacts("5.10.0\nmusl libc (x86_64)\nVersion 1.2.2\n")
+	assert.Equal(t, "musl", facts.Libc)
+	assert.Equal(t, "1.2.2", facts.LibcVersion)
+
+	// ldd is not installed
+	facts = parseHostFacts("5.10.0\nsh: ldd: command not found\n")
+	assert.Equal(t, "", facts.Libc)
+	assert.Equal(t, "5.10.0", facts.KernelVersion)
+}
@@ -0,0 +1,310 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// the formats the inventory can be exported in
+const (
+	InventoryFormatCSV  = "csv"
+	InventoryFormatJSON = "json"
+)
+
+// InventoryRecord is the record of an instance in the inventory of a cluster
+type InventoryRecord struct {
+	Cluster   string `json:"cluster"`
+	ID        string `json:"id"`
+	Host      string `json:"host"`
+	Role      string `json:"role"`
+	Component string `json:"component"`
+	// Version is the version of the component recorded in the meta
+	Version string `json:"version"`
+	// LiveVersion is the version reported by the running instance, it's
+	// empty if it's not detected
+	LiveVersion string            `json:"live_version,omitempty"`
+	Patched     bool              `json:"patched"`
+	Ports       []int             `json:"ports"`
+	DeployDir   string            `json:"deploy_dir"`
+	DataDirs    []string          `json:"data_dirs,omitempty"`
+	LogDir      string            `json:"log_dir,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	OS          string            `json:"os"`
+	Arch        string            `json:"arch"`
+}
+
+// inventoryColumns are the columns of the inventory exported in CSV
+var inventoryColumns = []string{
+	"cluster", "id", "host", "role", "component", "version", "live_version", "patched",
+	"ports", "deploy_dir", "data_dirs", "log_dir", "labels", "tags", "os", "arch",
+}
+
+// formatKV formats the map as sorted k=v pairs separated by ';'
+func formatKV(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+// csvRow returns the fields of the record in the order of inventoryColumns
+func (r *InventoryRecord) csvRow() []string {
+	ports := make([]string, 0, len(r.Ports))
+	for _, p := range r.Ports {
+		ports = append(ports, strconv.Itoa(p))
+	}
+	return []string{
+		r.Cluster, r.ID, r.Host, r.Role, r.Component, r.Version, r.LiveVersion, strconv.FormatBool(r.Patched),
+		strings.Join(ports, ";"), r.DeployDir, strings.Join(r.DataDirs, ";"), r.LogDir,
+		formatKV(r.Labels), formatKV(r.Tags), r.OS, r.Arch,
+	}
+}
+
+// writeInventory writes the records to w in the format
+func writeInventory(w io.Writer, records []*InventoryRecord, format string) error {
+	switch format {
+	case InventoryFormatJSON:
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return perrs.AddStack(err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case InventoryFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(inventoryColumns); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write(r.csvRow()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return perrs.Errorf("unknown inventory format %s, should be one of csv and json", format)
+	}
+}
+
+// tidbLiveVersion extracts the TiDB version from the MySQL compatible version
+// reported by the status API, e.g. 5.7.25-TiDB-v6.1.0
+func tidbLiveVersion(version string) string {
+	if i := strings.Index(version, "-TiDB-"); i >= 0 {
+		return version[i+len("-TiDB-"):]
+	}
+	return version
+}
+
+// detectLiveVersions queries the versions reported by the running instances,
+// the stores and PD are asked through the PD API, TiDB and TiCDC through their
+// status APIs, the instances failed to be detected are omitted
+func (m *Manager) detectLiveVersions(ctx context.Context, topo spec.Topology, tlsCfg *tls.Config, gOpt operator.Options) map[string]string {
+	timeout := api.RequestTimeout(ctx, time.Duration(gOpt.APITimeout)*time.Second)
+	var mu sync.Mutex
+	versions := make(map[string]string)
+	record := func(id, version string) {
+		if version == "" {
+			return
+		}
+		mu.Lock()
+		versions[id] = version
+		mu.Unlock()
+	}
+
+	storeAddrs := make(map[string]string) // store address -> instance ID
+	topo.IterInstance(func(inst spec.Instance) {
+		switch inst.ComponentName() {
+		case spec.ComponentTiKV:
+			storeAddrs[inst.ID()] = inst.ID()
+		case spec.ComponentTiFlash:
+			if tf, ok := inst.(*spec.TiFlashInstance); ok {
+				storeAddrs[fmt.Sprintf("%s:%d", inst.GetHost(), tf.GetServicePort())] = inst.ID()
+			}
+		}
+	})
+	if pdList := topo.BaseTopo().MasterList; len(pdList) > 0 && len(storeAddrs) > 0 {
+		stores, err := api.NewPDClient(ctx, pdList, timeout, tlsCfg).GetStores(ctx)
+		if err != nil {
+			m.logger.Debugf("Failed to get the stores from PD: %s", err)
+		} else {
+			for _, s := range stores.Stores {
+				if s.Store == nil || s.Store.Store == nil {
+					continue
+				}
+				if id, ok := storeAddrs[s.Store.Address]; ok {
+					record(id, s.Store.Version)
+				}
+			}
+		}
+	}
+
+	topo.IterInstance(func(inst spec.Instance) {
+		var version string
+		switch inst.ComponentName() {
+		case spec.ComponentPD:
+			pdClient := api.NewPDClient(ctx, []string{inst.ID()}, timeout, tlsCfg)
+			version = pdClient.Version()
+		case spec.ComponentTiDB:
+			tidb, ok := inst.(*spec.TiDBInstance)
+			if !ok {
+				return
+			}
+			addr := fmt.Sprintf("%s:%d", inst.GetHost(), tidb.InstanceSpec.(*spec.TiDBSpec).StatusPort)
+			client := utils.NewHTTPClient(timeout, tlsCfg)
+			body, err := client.Get(ctx, api.URL(tlsCfg != nil, addr)+"/status")
+			if err != nil {
+				m.logger.Debugf("Failed to get the status of %s: %s", inst.ID(), err)
+				return
+			}
+			status := struct {
+				Version string `json:"version"`
+			}{}
+			if err := json.Unmarshal(body, &status); err == nil {
+				version = tidbLiveVersion(status.Version)
+			}
+		case spec.ComponentCDC:
+			status, err := api.NewCDCOpenAPIClient([]string{inst.ID()}, timeout, tlsCfg).GetStatus(ctx)
+			if err != nil {
+				m.logger.Debugf("Failed to get the status of %s: %s", inst.ID(), err)
+				return
+			}
+			version = status.Version
+		}
+		record(inst.ID(), version)
+	}, gOpt.Concurrency)
+	return versions
+}
+
+// InventoryRecords returns the records of the instances of the cluster, the
+// versions reported by the running instances are detected if detect is set
+func (m *Manager) InventoryRecords(name string, gOpt operator.Options, detect bool) ([]*InventoryRecord, error) {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return nil, err
+	}
+	metadata, err := m.meta(name)
+	if err != nil {
+		return nil, err
+	}
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+	global := topo.BaseTopo().GlobalOptions
+
+	labels := make(map[string]map[string]string)
+	if cluster, ok := topo.(*spec.Specification); ok {
+		for _, kv := range cluster.TiKVServers {
+			lbs, err := kv.Labels()
+			if err != nil {
+				return nil, err
+			}
+			labels[fmt.Sprintf("%s:%d", kv.Host, kv.Port)] = lbs
+		}
+	}
+
+	var liveVersions map[string]string
+	if detect {
+		tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+		if err != nil {
+			return nil, err
+		}
+		ctx := ctxt.New(m.baseContext(name, gOpt), gOpt.Concurrency, m.logger)
+		liveVersions = m.detectLiveVersions(ctx, topo, tlsCfg, gOpt)
+	}
+
+	var records []*InventoryRecord
+	topo.IterInstance(func(inst spec.Instance) {
+		r := &InventoryRecord{
+			Cluster:     name,
+			ID:          inst.ID(),
+			Host:        inst.GetHost(),
+			Role:        inst.Role(),
+			Component:   inst.ComponentName(),
+			Version:     m.bindVersion(inst.ComponentName(), base.Version),
+			LiveVersion: liveVersions[inst.ID()],
+			Patched:     inst.IsPatched(),
+			Ports:       inst.UsedPorts(),
+			DeployDir:   spec.Abs(base.User, inst.DeployDir()),
+			LogDir:      spec.Abs(base.User, inst.LogDir()),
+			Labels:      labels[inst.ID()],
+			Tags:        global.Tags,
+			OS:          inst.OS(),
+			Arch:        inst.Arch(),
+		}
+		if inst.DataDir() != "" {
+			r.DataDirs = spec.MultiDirAbs(base.User, inst.DataDir())
+		}
+		records = append(records, r)
+	})
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Host != records[j].Host {
+			return records[i].Host < records[j].Host
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+// ExportInventory exports the records of the instances of the cluster in the
+// format for the CMDB and asset systems, to the file or stdout if it's empty
+func (m *Manager) ExportInventory(name, format, file string, gOpt operator.Options, detect bool) error {
+	switch format {
+	case InventoryFormatCSV, InventoryFormatJSON:
+	default:
+		return perrs.Errorf("unknown inventory format %s, should be one of csv and json", format)
+	}
+	records, err := m.InventoryRecords(name, gOpt, detect)
+	if err != nil {
+		return err
+	}
+
+	if file == "" {
+		return writeInventory(m.stdout, records, format)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := writeInventory(f, records, format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return perrs.AddStack(err)
+	}
+	m.logger.Infof("Exported the inventory of %d instances of cluster %s to %s", len(records), name, file)
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteInventory(t *testing.T) {
+	records := []*InventoryRecord{
+		{
+			Cluster:     "test",
+			ID:          "172.16.5.140:20160",
+			Host:        "172.16.5.140",
+			Role:        "tikv",
+			Component:   "tikv",
+			Version:     "v6.1.0",
+			LiveVersion: "6.1.0",
+			Ports:       []int{20160, 20180},
+			DeployDir:   "/home/tidb/deploy/tikv-20160",
+			DataDirs:    []string{"/data1/tikv", "/data2/tikv"},
+			LogDir:      "/home/tidb/deploy/tikv-20160/log",
+			Labels:      map[string]string{"zone": "z1", "host": "h1"},
+			Tags:        map[string]string{"env": "prod"},
+			OS:          "linux",
+			Arch:        "amd64",
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeInventory(&buf, records, InventoryFormatCSV))
+	assert.Equal(t, "cluster,id,host,role,component,version,live_version,patched,ports,deploy_dir,data_dirs,log_dir,labels,tags,os,arch\n"+
+		"test,172.16.5.140:20160,172.16.5.140,tikv,tikv,v6.1.0,6.1.0,false,20160;20180,/home/tidb/deploy/tikv-20160,"+
+		"/data1/tikv;/data2/tikv,/home/tidb/deploy/tikv-20160/log,host=h1;zone=z1,env=prod,linux,amd64\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, writeInventory(&buf, records, InventoryFormatJSON))
+	var decoded []*InventoryRecord
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, records, decoded)
+
+	assert.NotNil(t, writeInventory(&buf, records, "yaml"))
+}
+
+func TestTiDBLiveVersion(t *testing.T) {
+	assert.Equal(t, "v6.1.0", tidbLiveVersion("5.7.25-TiDB-v6.1.0"))
+	assert.Equal(t, "v6.1.0", tidbLiveVersion("v6.1.0"))
+}
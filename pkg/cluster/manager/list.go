@@ -52,7 +52,7 @@ func (m *Manager) ListCluster() error {
 		if err != nil {
 			return err
 		}
-		fmt.Println(string(data))
+		fmt.Fprintln(m.stdout, string(data))
 	default:
 		clusterTable := [][]string{
 			// Header
@@ -67,7 +67,7 @@ func (m *Manager) ListCluster() error {
 				v.PrivateKey,
 			})
 		}
-		tui.PrintTable(clusterTable, true)
+		tui.FprintTable(m.stdout, clusterTable, true)
 	}
 	return nil
 }
@@ -0,0 +1,440 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+const (
+	// the level of the components not reporting their current one
+	defaultLogLevel = "info"
+)
+
+var (
+	errNSLogLevel          = errorx.NewNamespace("log_level")
+	errLogLevelUnsupported = errNSLogLevel.NewType("unsupported", utils.ErrTraitPreCheck)
+	errLogLevelInProgress  = errNSLogLevel.NewType("in_progress", utils.ErrTraitPreCheck)
+	errLogLevelNotFound    = errNSLogLevel.NewType("not_found", utils.ErrTraitPreCheck)
+)
+
+// the levels accepted by all the supported components
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// the components supporting changing the log level
+var logLevelComponents = []string{
+	spec.ComponentTiDB, spec.ComponentTiKV, spec.ComponentPD, spec.ComponentCDC,
+	spec.ComponentDMMaster, spec.ComponentDMWorker,
+}
+
+// the components without an API to change the log level at runtime, they
+// are restarted with the level set by a runtime override
+var logLevelRestartComponents = set.NewStringSet(spec.ComponentDMMaster, spec.ComponentDMWorker)
+
+// LogLevelOptions contains the options of changing the log level
+type LogLevelOptions struct {
+	Level    string        // the level to set
+	Duration time.Duration // the levels are reverted after it, 0 means never
+	Revert   bool          // revert the levels recorded by an interrupted run
+}
+
+// logLevelTarget is an instance supporting changing the log level, Addr is
+// the address of its status API, and Configured is the level set in the
+// topology, which is used if the current level is not reported by the API
+type logLevelTarget struct {
+	ID         string
+	Component  string
+	Addr       string
+	Configured string
+}
+
+func logLevelTargets(topo spec.Topology, gOpt operator.Options) ([]logLevelTarget, error) {
+	roles := set.NewStringSet(gOpt.Roles...)
+	nodes := set.NewStringSet(gOpt.Nodes...)
+	for _, role := range gOpt.Roles {
+		if !set.NewStringSet(logLevelComponents...).Exist(role) {
+			return nil, errLogLevelUnsupported.
+				New("Changing the log level of %s at runtime is not supported", role).
+				WithProperty(tui.SuggestionFromFormat("The supported roles are: %s", strings.Join(logLevelComponents, ", ")))
+		}
+	}
+
+	var targets []logLevelTarget
+	add := func(t logLevelTarget) {
+		if len(roles) > 0 && !roles.Exist(t.Component) {
+			return
+		}
+		if len(nodes) > 0 && !nodes.Exist(t.ID) {
+			return
+		}
+		targets = append(targets, t)
+	}
+	addr := func(host string, port int) string {
+		return fmt.Sprintf("%s:%d", host, port)
+	}
+
+	cluster, ok := topo.(*spec.Specification)
+	if !ok {
+		topo.IterInstance(func(inst spec.Instance) {
+			if logLevelRestartComponents.Exist(inst.ComponentName()) {
+				add(logLevelTarget{ID: inst.ID(), Component: inst.ComponentName()})
+			}
+		})
+		return targets, nil
+	}
+	for _, s := range cluster.TiDBServers {
+		add(logLevelTarget{ID: addr(s.Host, s.Port), Component: spec.ComponentTiDB, Addr: addr(s.Host, s.StatusPort)})
+	}
+	for _, s := range cluster.TiKVServers {
+		add(logLevelTarget{ID: addr(s.Host, s.Port), Component: spec.ComponentTiKV, Addr: addr(s.Host, s.StatusPort)})
+	}
+	for _, s := range cluster.PDServers {
+		add(logLevelTarget{ID: addr(s.Host, s.ClientPort), Component: spec.ComponentPD, Addr: addr(s.Host, s.ClientPort)})
+	}
+	for _, s := range cluster.CDCServers {
+		// TiCDC doesn't report its level, it's the one in the config
+		configured, _ := spec.GetValueFromPath(s.Config, "log-level").(string)
+		if configured == "" {
+			configured, _ = spec.GetValueFromPath(cluster.ServerConfigs.CDC, "log-level").(string)
+		}
+		add(logLevelTarget{ID: addr(s.Host, s.Port), Component: spec.ComponentCDC, Addr: addr(s.Host, s.Port), Configured: configured})
+	}
+	return targets, nil
+}
+
+// configLogLevel reads the log level from the config reported by the
+// status API, both `log.level` and the legacy `log-level` are supported
+func configLogLevel(data []byte) string {
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return ""
+	}
+	if l, ok := config["log"].(map[string]interface{}); ok {
+		if level, ok := l["level"].(string); ok && level != "" {
+			return level
+		}
+	}
+	if level, ok := config["log-level"].(string); ok {
+		return level
+	}
+	return ""
+}
+
+// getLogLevel returns the current log level of the instance, the configured
+// one or defaultLogLevel is returned for the components not reporting it
+func getLogLevel(ctx context.Context, t logLevelTarget, endpoints *api.EndpointResolver, timeout time.Duration) (string, error) {
+	var path string
+	switch t.Component {
+	case spec.ComponentTiDB:
+		path = "/settings"
+	case spec.ComponentTiKV:
+		path = "/config"
+	case spec.ComponentPD:
+		path = "/pd/api/v1/config"
+	}
+	if path == "" {
+		if t.Configured != "" {
+			return t.Configured, nil
+		}
+		return defaultLogLevel, nil
+	}
+
+	data, err := utils.NewHTTPClient(timeout, endpoints.TLSConfig(t.Component)).Get(ctx, endpoints.URL(t.Component, t.Addr)+path)
+	if err != nil {
+		return "", err
+	}
+	if level := configLogLevel(data); level != "" {
+		return level, nil
+	}
+	return defaultLogLevel, nil
+}
+
+// setLogLevel changes the log level of the instance with its status API
+func setLogLevel(ctx context.Context, t logLevelTarget, endpoints *api.EndpointResolver, level string, timeout time.Duration) error {
+	client := utils.NewHTTPClient(timeout, endpoints.TLSConfig(t.Component))
+	base := endpoints.URL(t.Component, t.Addr)
+	var err error
+	switch t.Component {
+	case spec.ComponentTiDB:
+		client.SetRequestHeader("Content-Type", "application/x-www-form-urlencoded")
+		form := url.Values{"log_level": {level}}
+		_, err = client.Post(ctx, base+"/settings", strings.NewReader(form.Encode()))
+	case spec.ComponentTiKV:
+		body := fmt.Sprintf(`{"log_level":%q}`, level)
+		_, _, err = client.Put(ctx, base+"/log-level", bytes.NewBufferString(body))
+	case spec.ComponentPD:
+		_, err = client.Post(ctx, base+"/pd/api/v1/admin/log", bytes.NewBufferString(fmt.Sprintf("%q", level)))
+	case spec.ComponentCDC:
+		body := fmt.Sprintf(`{"log_level":%q}`, level)
+		_, err = client.Post(ctx, base+"/api/v1/log", bytes.NewBufferString(body))
+	default:
+		return perrs.Errorf("changing the log level of %s is not supported", t.Component)
+	}
+	return perrs.Annotatef(err, "failed to set the log level of %s to %s", t.ID, level)
+}
+
+// LogLevel changes the log level of the instances temporarily, with the
+// status APIs at runtime, or by restarting the DM instances with a runtime
+// override. The changes are recorded in the meta with the original levels
+// before applied, and reverted after opt.Duration or on interruption, or
+// with opt.Revert from the meta if the process is gone before reverting.
+// The operation lock is not held while waiting for the duration.
+func (m *Manager) LogLevel(name string, opt LogLevelOptions, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	locked := true
+	defer func() {
+		if locked {
+			release()
+		}
+	}()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	base := metadata.GetBaseMeta()
+	if base.LogLevelChanges == nil {
+		return errLogLevelUnsupported.New("Changing the log level is not supported by the cluster")
+	}
+
+	if opt.Revert {
+		if len(*base.LogLevelChanges) == 0 {
+			return errLogLevelNotFound.New("No log levels to revert for cluster %s", name)
+		}
+		return m.revertLogLevels(name, metadata, gOpt)
+	}
+	if len(*base.LogLevelChanges) > 0 {
+		now := time.Now()
+		for _, c := range *base.LogLevelChanges {
+			if !c.Expired(now) {
+				return errLogLevelInProgress.
+					New("The log level of %s in cluster %s is set to %s temporarily", c.Node, name, c.Level).
+					WithProperty(tui.SuggestionFromFormat("Please wait for it to be reverted, or revert it now with '%s log-level %s --revert'", tui.OsArgs0(), name))
+			}
+		}
+		m.logger.Infof("Reverting the log levels expired, which were left by an interrupted run")
+		if err := m.revertLogLevels(name, metadata, gOpt); err != nil {
+			return err
+		}
+	}
+
+	level := strings.ToLower(opt.Level)
+	if !set.NewStringSet(logLevels...).Exist(level) {
+		return perrs.Errorf("unknown log level %s, it should be one of %s", opt.Level, strings.Join(logLevels, ", "))
+	}
+	targets, err := logLevelTargets(metadata.GetTopology(), gOpt)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return perrs.New("no instance supporting changing the log level is matched")
+	}
+	ctx, endpoints, timeout, err := m.logLevelClients(name, metadata, gOpt)
+	if err != nil {
+		return err
+	}
+
+	var expire time.Time
+	if opt.Duration > 0 {
+		expire = time.Now().Add(opt.Duration).Truncate(time.Second)
+	}
+	var changes []spec.LogLevelChange
+	var restarts []string
+	for _, t := range targets {
+		change := spec.LogLevelChange{Node: t.ID, Level: level, Expire: expire}
+		if logLevelRestartComponents.Exist(t.Component) {
+			if len(base.RuntimeFlags(t.ID, time.Time{})) > 0 {
+				return perrs.Errorf("%s has a runtime override, please clear it before changing its log level", t.ID)
+			}
+			restarts = append(restarts, t.ID)
+		} else if change.Origin, err = getLogLevel(ctx, t, endpoints, timeout); err != nil {
+			return perrs.Annotatef(err, "failed to get the log level of %s", t.ID)
+		}
+		changes = append(changes, change)
+	}
+
+	// record the original levels before changing any of them, the changes
+	// are reverted instead of left if the process is terminated from now on
+	if opt.Duration > 0 {
+		*base.LogLevelChanges = changes
+		m.specManager.WatchTermination()
+	}
+	for _, node := range restarts {
+		if err := base.SetRuntimeOverride(spec.RuntimeOverride{Node: node, Flags: []string{"-L=" + level}, Expire: expire}); err != nil {
+			return err
+		}
+	}
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+
+	var applyErr error
+	for i, t := range targets {
+		if logLevelRestartComponents.Exist(t.Component) {
+			continue
+		}
+		if applyErr = setLogLevel(ctx, t, endpoints, level, timeout); applyErr != nil {
+			break
+		}
+		m.logger.Infof("Set the log level of %s %s from %s to %s", t.Component, t.ID, changes[i].Origin, level)
+	}
+	if applyErr == nil && len(restarts) > 0 {
+		m.logger.Infof("Restarting %s to set the log level to %s", strings.Join(restarts, ","), level)
+		applyErr = m.reloadNodes(name, restarts, gOpt)
+	}
+	if applyErr != nil {
+		if opt.Duration > 0 {
+			m.logger.Warnf("Reverting the log levels changed as %s", applyErr)
+			if err := m.withoutTermination().revertLogLevels(name, metadata, gOpt); err != nil {
+				m.logger.Errorf("Failed to revert the log levels: %s", err)
+			}
+		}
+		return applyErr
+	}
+	if opt.Duration <= 0 {
+		return nil
+	}
+
+	// revert the levels after the duration, on interruption or termination,
+	// the lock is released meanwhile so the cluster could be operated
+	locked = false
+	release()
+	m.logger.Infof("The log levels will be reverted at %s, press Ctrl+C to revert now", expire.Format(time.RFC3339))
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, os.Interrupt)
+	defer signal.Stop(sc)
+	select {
+	case <-time.After(time.Until(expire)):
+	case sig := <-sc:
+		m.logger.Infof("Got signal %s, reverting the log levels", sig)
+	case <-m.specManager.Context().Done():
+		m.logger.Infof("Reverting the log levels before exiting")
+	}
+
+	if release, err = m.lockOperation(name); err != nil {
+		return perrs.Annotatef(err, "failed to revert the log levels, please retry with '%s log-level %s --revert'", tui.OsArgs0(), name)
+	}
+	locked = true
+	if metadata, err = m.meta(name); err != nil {
+		return err
+	}
+	return m.withoutTermination().revertLogLevels(name, metadata, gOpt)
+}
+
+// logLevelClients returns the context, endpoints and timeout to request the
+// status APIs of the instances
+func (m *Manager) logLevelClients(name string, metadata spec.Metadata, gOpt operator.Options) (context.Context, *api.EndpointResolver, time.Duration, error) {
+	tlsCfg, err := metadata.GetTopology().TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return m.apiContext(name, gOpt), spec.NewEndpointResolver(tlsCfg), time.Duration(gOpt.APITimeout) * time.Second, nil
+}
+
+// reloadNodes restarts the nodes to apply the runtime overrides
+func (m *Manager) reloadNodes(name string, nodes []string, gOpt operator.Options) error {
+	gOpt.Nodes = nodes
+	gOpt.Roles = nil
+	return m.Reload(name, gOpt, false, true)
+}
+
+// revertLogLevels sets the levels of the instances back to the ones recorded
+// in the meta, the changes by runtime overrides are reverted by removing the
+// overrides and restarting the instances. The changes failed to revert are
+// kept in the meta.
+func (m *Manager) revertLogLevels(name string, metadata spec.Metadata, gOpt operator.Options) error {
+	base := metadata.GetBaseMeta()
+	targets, err := logLevelTargets(metadata.GetTopology(), operator.Options{})
+	if err != nil {
+		return err
+	}
+	ctx, endpoints, timeout, err := m.logLevelClients(name, metadata, gOpt)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]logLevelTarget)
+	for _, t := range targets {
+		byID[t.ID] = t
+	}
+	changes := append([]spec.LogLevelChange(nil), *base.LogLevelChanges...)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Node < changes[j].Node })
+
+	var failed []spec.LogLevelChange
+	restarts := set.NewStringSet()
+	for _, c := range changes {
+		t, ok := byID[c.Node]
+		if !ok {
+			m.logger.Warnf("%s is not in the cluster anymore, skip reverting its log level", c.Node)
+			continue
+		}
+		if c.Origin == "" {
+			restarts.Insert(c.Node)
+			continue
+		}
+		if err := setLogLevel(ctx, t, endpoints, c.Origin, timeout); err != nil {
+			m.logger.Warnf("%s", err)
+			failed = append(failed, c)
+			continue
+		}
+		m.logger.Infof("Reverted the log level of %s %s to %s", t.Component, c.Node, c.Origin)
+	}
+
+	base.RemoveRuntimeOverrides(func(o *spec.RuntimeOverride) bool { return restarts.Exist(o.Node) })
+	*base.LogLevelChanges = failed
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+	if len(restarts) > 0 {
+		nodes := restarts.Slice()
+		sort.Strings(nodes)
+		m.logger.Infof("Restarting %s to revert the log levels", strings.Join(nodes, ","))
+		if err := m.reloadNodes(name, nodes, gOpt); err != nil {
+			return perrs.Annotatef(err, "failed to restart %s to revert the log levels, please reload them", strings.Join(nodes, ","))
+		}
+	}
+	if len(failed) > 0 {
+		ids := make([]string, 0, len(failed))
+		for _, c := range failed {
+			ids = append(ids, c.Node)
+		}
+		return perrs.Errorf("failed to revert the log level of %s, please retry with --revert", strings.Join(ids, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/joomcode/errorx"
+	dmspec "github.com/pingcap/tiup/components/dm/spec"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigLogLevel(t *testing.T) {
+	assert.Equal(t, "warn", configLogLevel([]byte(`{"log":{"level":"warn","file":{}}}`)))
+	assert.Equal(t, "debug", configLogLevel([]byte(`{"log-level":"debug"}`)))
+	assert.Equal(t, "", configLogLevel([]byte(`{"log":{}}`)))
+	assert.Equal(t, "", configLogLevel([]byte(`not json`)))
+}
+
+func TestLogLevelTargets(t *testing.T) {
+	topo := &spec.Specification{
+		TiDBServers: []*spec.TiDBSpec{{Host: "10.0.0.1", Port: 4000, StatusPort: 10080}},
+		TiKVServers: []*spec.TiKVSpec{
+			{Host: "10.0.0.2", Port: 20160, StatusPort: 20180},
+			{Host: "10.0.0.3", Port: 20160, StatusPort: 20180},
+		},
+		PDServers: []*spec.PDSpec{{Host: "10.0.0.4", ClientPort: 2379}},
+		CDCServers: []*spec.CDCSpec{
+			{Host: "10.0.0.5", Port: 8300},
+			{Host: "10.0.0.6", Port: 8300, Config: map[string]interface{}{"log-level": "warn"}},
+		},
+	}
+	topo.ServerConfigs.CDC = map[string]interface{}{"log-level": "error"}
+
+	targets, err := logLevelTargets(topo, operator.Options{})
+	assert.Nil(t, err)
+	assert.Len(t, targets, 6)
+	// TiCDC doesn't report its level, the configured one is the origin
+	assert.Equal(t, "error", targets[4].Configured)
+	assert.Equal(t, "warn", targets[5].Configured)
+
+	targets, err = logLevelTargets(topo, operator.Options{Roles: []string{"tikv"}, Nodes: []string{"10.0.0.3:20160"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []logLevelTarget{{ID: "10.0.0.3:20160", Component: "tikv", Addr: "10.0.0.3:20180"}}, targets)
+
+	_, err = logLevelTargets(topo, operator.Options{Roles: []string{"tiflash"}})
+	assert.NotNil(t, err)
+
+	// the DM instances have no status API to change the level
+	dmTopo := &dmspec.Specification{
+		Masters: []*dmspec.MasterSpec{{Host: "10.0.0.7", Port: 8261}},
+		Workers: []*dmspec.WorkerSpec{{Host: "10.0.0.8", Port: 8262}},
+	}
+	targets, err = logLevelTargets(dmTopo, operator.Options{Roles: []string{"dm-worker"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []logLevelTarget{{ID: "10.0.0.8:8262", Component: "dm-worker"}}, targets)
+}
+
+// logLevelServer serves the status API of a TiKV with the log level warn,
+// the levels set are recorded in levels
+type logLevelServer struct {
+	mu     sync.Mutex
+	levels []string
+	port   int
+}
+
+func newLogLevelServer(t *testing.T) *logLevelServer {
+	s := &logLevelServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/config":
+			_, _ = w.Write([]byte(`{"log":{"level":"warn"}}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/log-level":
+			body, _ := io.ReadAll(r.Body)
+			s.levels = append(s.levels, string(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.Nil(t, err)
+	s.port, err = strconv.Atoi(port)
+	require.Nil(t, err)
+	return s
+}
+
+func TestLogLevelRevert(t *testing.T) {
+	srv := newLogLevelServer(t)
+	statusPort := srv.port
+
+	m := NewManager("tidb", spec.NewSpec(t.TempDir(), func() spec.Metadata { return &spec.ClusterMeta{} }), nil, logprinter.NewLogger(""))
+	meta := &spec.ClusterMeta{User: "tidb", Version: "v6.1.0", Topology: &spec.Specification{
+		TiKVServers: []*spec.TiKVSpec{{Host: "127.0.0.1", Port: 20160, StatusPort: statusPort}},
+	}}
+	require.Nil(t, m.specManager.SaveMeta("test", meta))
+	gOpt := operator.Options{APITimeout: 5}
+
+	// the original level is reverted after the duration
+	opt := LogLevelOptions{Level: "debug", Duration: time.Second}
+	require.Nil(t, m.LogLevel("test", opt, gOpt))
+	assert.Equal(t, []string{`{"log_level":"debug"}`, `{"log_level":"warn"}`}, srv.levels)
+	metadata, err := m.meta("test")
+	require.Nil(t, err)
+	assert.Empty(t, *metadata.GetBaseMeta().LogLevelChanges)
+
+	// the changes left by an interrupted run are in progress until expired
+	meta.LogLevelChanges = []spec.LogLevelChange{{
+		Node: "127.0.0.1:20160", Level: "debug", Origin: "info", Expire: time.Now().Add(time.Hour),
+	}}
+	require.Nil(t, m.specManager.SaveMeta("test", meta))
+	err = m.LogLevel("test", opt, gOpt)
+	assert.True(t, errorx.IsOfType(err, errLogLevelInProgress))
+
+	// and reverted from the meta
+	srv.levels = nil
+	require.Nil(t, m.LogLevel("test", LogLevelOptions{Revert: true}, gOpt))
+	assert.Equal(t, []string{`{"log_level":"info"}`}, srv.levels)
+	metadata, err = m.meta("test")
+	require.Nil(t, err)
+	assert.Empty(t, *metadata.GetBaseMeta().LogLevelChanges)
+	err = m.LogLevel("test", LogLevelOptions{Revert: true}, gOpt)
+	assert.True(t, errorx.IsOfType(err, errLogLevelNotFound))
+}
+
+// waitWriter closes done once the output contains match
+type waitWriter struct {
+	match []byte
+	once  sync.Once
+	done  chan struct{}
+}
+
+func (w *waitWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, w.match) {
+		w.once.Do(func() { close(w.done) })
+	}
+	return len(p), nil
+}
+
+func TestLogLevelRevertOnTermination(t *testing.T) {
+	srv := newLogLevelServer(t)
+	waiting := &waitWriter{match: []byte("will be reverted"), done: make(chan struct{})}
+	m := NewManager("tidb", spec.NewSpec(t.TempDir(), func() spec.Metadata { return &spec.ClusterMeta{} }), nil, logprinter.NewLogger(""),
+		WithIO(strings.NewReader(""), waiting, io.Discard),
+	)
+	meta := &spec.ClusterMeta{User: "tidb", Version: "v6.1.0", Topology: &spec.Specification{
+		TiKVServers: []*spec.TiKVSpec{{Host: "127.0.0.1", Port: 20160, StatusPort: srv.port}},
+	}}
+	require.Nil(t, m.specManager.SaveMeta("test", meta))
+
+	// SIGTERM is handled by the termination watched once the changes are
+	// recorded, so the process is not killed
+	go func() {
+		<-waiting.done
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+	opt := LogLevelOptions{Level: "debug", Duration: time.Hour}
+	require.Nil(t, m.LogLevel("test", opt, operator.Options{APITimeout: 5}))
+	assert.Equal(t, []string{`{"log_level":"debug"}`, `{"log_level":"warn"}`}, srv.levels)
+	assert.NotNil(t, m.specManager.Context().Err())
+	metadata, err := m.meta("test")
+	require.Nil(t, err)
+	assert.Empty(t, *metadata.GetBaseMeta().LogLevelChanges)
+}
@@ -16,11 +16,16 @@ package manager
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -42,11 +47,68 @@ var (
 )
 
 // Manager to deploy a cluster.
+//
+// Besides the CLI of tiup-cluster and tiup-dm, the Manager is the API for the
+// programs embedding the cluster operations as a library, e.g.
+//
+//	m := manager.NewManager("tidb", spec.GetSpecManager(), spec.TiDBComponentVersion, logger,
+//		manager.WithExecutorFactory(factory),
+//		manager.WithIO(stdin, stdout, stderr),
+//	)
+//	err := m.Display("prod", operator.Options{Concurrency: 5, APITimeout: 10})
+//
+// The methods like Deploy, ScaleOut, ScaleIn, Upgrade and Display take the
+// same options as the commands, and the confirmations are skipped if
+// skipConfirm is true. The spec manager must be initialized with
+// spec.Initialize. The operations changing the meta hold the operation lock of
+// the cluster, so the ones of the same cluster run concurrently, even by the
+// other processes, fail instead of overwriting the meta of each other. The
+// state of a cluster, e.g. the extra headers of its component API requests
+// and its modified templates, is kept in the Manager and passed to the
+// operations through their contexts and the instance paths, so the Managers of
+// different clusters can be used at the same time in a process.
 type Manager struct {
 	sysName     string
 	specManager *spec.SpecManager
 	bindVersion spec.BindVersion
 	logger      *logprinter.Logger
+
+	executorFactory executor.Factory
+	stdin           io.Reader
+	stdout          io.Writer
+	stderr          io.Writer
+	prompter        *tui.Prompter
+
+	// apiHeaders is the extra headers of the component API requests of each
+	// cluster, they are loaded with the meta of the cluster
+	apiHeaders *sync.Map
+
+	// detached makes the operations not cancelled on termination
+	detached bool
+}
+
+// Option customizes the Manager created by NewManager
+type Option func(m *Manager)
+
+// WithExecutorFactory makes the executors of the hosts created by f instead of
+// the SSH executors chosen by the SSH type of the options
+func WithExecutorFactory(f executor.Factory) Option {
+	return func(m *Manager) {
+		m.executorFactory = f
+	}
+}
+
+// WithIO redirects the input and output of the Manager and its logger, the
+// confirmations of the operations are answered by stdin
+func WithIO(stdin io.Reader, stdout, stderr io.Writer) Option {
+	return func(m *Manager) {
+		m.stdin = stdin
+		m.stdout = stdout
+		m.stderr = stderr
+		m.prompter = tui.NewPrompter(stdin, stdout)
+		m.logger.SetStdout(stdout)
+		m.logger.SetStderr(stderr)
+	}
 }
 
 // NewManager create a Manager.
@@ -55,13 +117,64 @@ func NewManager(
 	specManager *spec.SpecManager,
 	bindVersion spec.BindVersion,
 	logger *logprinter.Logger,
+	opts ...Option,
 ) *Manager {
-	return &Manager{
+	m := &Manager{
 		sysName:     sysName,
 		specManager: specManager,
 		bindVersion: bindVersion,
 		logger:      logger,
+		stdin:       os.Stdin,
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		prompter:    tui.NewPrompter(os.Stdin, os.Stdout),
+		apiHeaders:  &sync.Map{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if specManager != nil {
+		specManager.SetLogger(logger)
 	}
+	return m
+}
+
+// baseContext returns the context the operations of the cluster name are run
+// in, it carries the executor factory if it's set, the prompter of the
+// Manager, the extra headers of the component API requests of the cluster, and
+// the timeout of the requests if it's specified by gOpt. The read-only
+// requests to the PD, TiCDC and DM-master APIs are hedged. It's cancelled on
+// termination once an encrypted cluster is unlocked.
+func (m *Manager) baseContext(name string, gOpt operator.Options) context.Context {
+	parent := context.Background()
+	if m.specManager != nil && !m.detached {
+		parent = m.specManager.Context()
+	}
+	ctx := tui.WithPrompter(parent, m.prompter)
+	ctx = api.WithRequestTimeout(ctx, time.Second*time.Duration(gOpt.RequestTimeout))
+	ctx = api.WithHedgeContext(ctx, api.DefaultHedgeOption)
+	if headers, ok := m.apiHeaders.Load(name); ok {
+		ctx = api.WithExtraHeaders(ctx, headers.(*api.ExtraHeaders))
+	}
+	if m.executorFactory != nil {
+		ctx = executor.WithFactory(ctx, m.executorFactory)
+	}
+	return ctx
+}
+
+// withoutTermination returns a copy of the Manager whose operations are not
+// cancelled on termination, it reverts the temporary changes after the
+// operation is stopped by a signal
+func (m *Manager) withoutTermination() *Manager {
+	c := *m
+	c.detached = true
+	return &c
+}
+
+// apiContext returns the context to request the component APIs out of the
+// tasks, it carries the logger
+func (m *Manager) apiContext(name string, gOpt operator.Options) context.Context {
+	return context.WithValue(m.baseContext(name, gOpt), logprinter.ContextKeyLogger, m.logger)
 }
 
 func (m *Manager) meta(name string) (metadata spec.Metadata, err error) {
@@ -74,24 +187,58 @@ func (m *Manager) meta(name string) (metadata spec.Metadata, err error) {
 		return nil, perrs.Errorf("%s cluster `%s` not exists", m.sysName, name)
 	}
 
+	if err := m.specManager.Unlock(name); err != nil {
+		return nil, err
+	}
+
 	metadata = m.specManager.NewMetadata()
 	err = m.specManager.Metadata(name, metadata)
 	if err != nil {
 		return metadata, err
 	}
 
+	if err := m.loadAPIHeaders(name, metadata.GetTopology()); err != nil {
+		return metadata, err
+	}
+
 	return metadata, nil
 }
 
+// loadAPIHeaders loads the extra headers of the component API requests of the
+// cluster from the global options and the bearer token in its credential
+// store, they are sent in the contexts of its operations, see baseContext.
+func (m *Manager) loadAPIHeaders(name string, topo spec.Topology) error {
+	if topo == nil || topo.BaseTopo().GlobalOptions == nil {
+		m.apiHeaders.Delete(name)
+		return nil
+	}
+
+	token, err := m.specManager.ReadCredential(name, spec.APITokenFile)
+	if err != nil {
+		return perrs.Annotate(err, "failed to read the API token")
+	}
+	var hosts []string
+	topo.IterInstance(func(inst spec.Instance) {
+		hosts = append(hosts, inst.GetHost())
+	})
+	headers := api.NewExtraHeaders(topo.BaseTopo().GlobalOptions.APIHeaders, strings.TrimSpace(string(token)), hosts)
+	if headers == nil {
+		m.apiHeaders.Delete(name)
+		return nil
+	}
+	m.apiHeaders.Store(name, headers)
+	return nil
+}
+
 func (m *Manager) confirmTopology(name, version string, topo spec.Topology, patchedRoles set.StringSet) error {
 	m.logger.Infof("Please confirm your topology:")
 
 	cyan := color.New(color.FgCyan, color.Bold)
-	fmt.Printf("Cluster type:    %s\n", cyan.Sprint(m.sysName))
-	fmt.Printf("Cluster name:    %s\n", cyan.Sprint(name))
-	fmt.Printf("Cluster version: %s\n", cyan.Sprint(version))
+	fmt.Fprintf(m.stdout, "Cluster type:    %s\n", cyan.Sprint(m.sysName))
+	fmt.Fprintf(m.stdout, "Cluster name:    %s\n", cyan.Sprint(name))
+	fmt.Fprintf(m.stdout, "Cluster version: %s\n", cyan.Sprint(version))
 	if topo.BaseTopo().GlobalOptions.TLSEnabled {
-		fmt.Printf("TLS encryption:  %s\n", cyan.Sprint("enabled"))
+		fmt.Fprintf(m.stdout, "TLS encryption:  %s\n", cyan.Sprint("enabled"))
 	}
 
 	clusterTable := [][]string{
@@ -113,7 +260,7 @@ func (m *Manager) confirmTopology(name, version string, topo spec.Topology, patc
 		})
 	})
 
-	tui.PrintTable(clusterTable, true)
+	tui.FprintTable(m.stdout, clusterTable, true)
 
 	m.logger.Warnf("Attention:")
 	m.logger.Warnf("    1. If the topology is not what you expected, check your yaml file.")
@@ -132,12 +279,12 @@ You may read the OpenJDK doc for a reference: https://openjdk.java.net/install/
 		}
 	}
 
-	return tui.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: ")
+	return m.prompter.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: ")
 }
 
 func (m *Manager) sshTaskBuilder(name string, topo spec.Topology, user string, gOpt operator.Options) (*task.Builder, error) {
 	var p *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
-	if gOpt.SSHType != executor.SSHTypeNone && len(gOpt.SSHProxyHost) != 0 {
+	if gOpt.SSHType.UseSSH() && len(gOpt.SSHProxyHost) != 0 {
 		var err error
 		if p, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
 			return nil, err
@@ -181,24 +328,24 @@ func (m *Manager) fillHostArchOrOS(s, p *tui.SSHConnectionProps, topo spec.Topol
 	hostArchOrOS := map[string]string{}
 	var detectTasks []*task.StepDisplay
 
-	topo.IterInstance(func(inst spec.Instance) {
+	detect := func(host string, sshPort int, hostOS, hostArch string) {
 		if fullType == spec.FullOSType {
-			if inst.OS() != "" {
+			if hostOS != "" {
 				return
 			}
-		} else if inst.Arch() != "" {
+		} else if hostArch != "" {
 			return
 		}
 
-		if _, ok := hostArchOrOS[inst.GetHost()]; ok {
+		if _, ok := hostArchOrOS[host]; ok {
 			return
 		}
-		hostArchOrOS[inst.GetHost()] = ""
+		hostArchOrOS[host] = ""
 
 		tf := task.NewBuilder(m.logger).
 			RootSSH(
-				inst.GetHost(),
-				inst.GetSSHPort(),
+				host,
+				sshPort,
 				user,
 				s.Password,
 				s.IdentityFile,
@@ -218,18 +365,24 @@ func (m *Manager) fillHostArchOrOS(s, p *tui.SSHConnectionProps, topo spec.Topol
 
 		switch fullType {
 		case spec.FullOSType:
-			tf = tf.Shell(inst.GetHost(), "uname -s", "", false)
+			tf = tf.Shell(host, "uname -s", "", false)
 		default:
-			tf = tf.Shell(inst.GetHost(), "uname -m", "", false)
+			tf = tf.Shell(host, "uname -m", "", false)
 		}
-		detectTasks = append(detectTasks, tf.BuildAsStep(fmt.Sprintf("  - Detecting node %s %s info", inst.GetHost(), string(fullType))))
+		detectTasks = append(detectTasks, tf.BuildAsStep(fmt.Sprintf("  - Detecting node %s %s info", host, string(fullType))))
+	}
+	topo.IterInstance(func(inst spec.Instance) {
+		detect(inst.GetHost(), inst.GetSSHPort(), inst.OS(), inst.Arch())
 	})
+	for _, h := range spec.MonitoredHosts(topo) {
+		detect(h.Host, h.SSHPort, h.OS, h.Arch)
+	}
 	if len(detectTasks) == 0 {
 		return nil
 	}
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext("", *gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
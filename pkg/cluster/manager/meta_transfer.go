@@ -0,0 +1,475 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// the manifest of an exported meta, and the dirs of the meta and the
+	// audit logs in the archive
+	metaManifestName = "manifest.json"
+	metaArchiveDir   = "meta"
+	auditArchiveDir  = "audit"
+	// the prefix of the operation lock and its journal in the meta dir
+	operationFilePrefix = ".operation."
+)
+
+var (
+	errNSMetaTransfer = errorx.NewNamespace("meta_transfer")
+	errMetaCorrupted  = errNSMetaTransfer.NewType("corrupted", utils.ErrTraitPreCheck)
+	errMetaConflict   = errNSMetaTransfer.NewType("conflict", utils.ErrTraitPreCheck)
+)
+
+// metaManifest records the hashes of all files in an exported meta
+type metaManifest struct {
+	Cluster  string            `json:"cluster"`
+	SysName  string            `json:"sys_name"`
+	Host     string            `json:"host"`
+	Exported time.Time         `json:"exported"`
+	Files    map[string]string `json:"files"` // path in the archive -> sha256
+}
+
+// ExportClusterMeta exports the meta, keys, certificates and audit logs of a
+// cluster to a tarball, which could be imported on another control machine
+// with ImportClusterMeta
+func (m *Manager) ExportClusterMeta(name, file string) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	if _, err := m.meta(name); err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return err
+	}
+	if err := m.specManager.ScaleOutLockedErr(name); err != nil {
+		return err
+	}
+
+	auditLogs, err := clusterAuditLogs(spec.AuditDir(), name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	hostname, _ := os.Hostname()
+	manifest := metaManifest{
+		Cluster:  name,
+		SysName:  m.sysName,
+		Host:     hostname,
+		Exported: time.Now(),
+		Files:    make(map[string]string),
+	}
+	metaDir := m.specManager.Path(name)
+	err = filepath.Walk(metaDir, func(p string, info fs.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), operationFilePrefix) {
+			return err
+		}
+		rel, err := filepath.Rel(metaDir, p)
+		if err != nil {
+			return err
+		}
+		return addArchiveFile(tw, manifest.Files, path.Join(metaArchiveDir, filepath.ToSlash(rel)), p, info)
+	})
+	if err != nil {
+		return perrs.Annotate(err, "failed to archive the meta")
+	}
+	for _, id := range auditLogs {
+		p := filepath.Join(spec.AuditDir(), id)
+		info, err := os.Stat(p)
+		if err != nil {
+			return perrs.AddStack(err)
+		}
+		if err := addArchiveFile(tw, manifest.Files, path.Join(auditArchiveDir, id), p, info); err != nil {
+			return perrs.Annotate(err, "failed to archive the audit logs")
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    metaManifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: manifest.Exported,
+	}); err != nil {
+		return perrs.AddStack(err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := tw.Close(); err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := gw.Close(); err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return perrs.AddStack(err)
+	}
+
+	m.logger.Infof("Exported meta of cluster %s with %d audit logs to %s", name, len(auditLogs), file)
+	return nil
+}
+
+// addArchiveFile writes the file to the archive and records its hash
+func addArchiveFile(tw *tar.Writer, hashes map[string]string, name, file string, info fs.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	fd, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, h), fd); err != nil {
+		return err
+	}
+	hashes[name] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// clusterAuditLogs returns the IDs of the audit logs of the commands on the
+// cluster, which have the cluster name as an argument
+func clusterAuditLogs(dir, name string) ([]string, error) {
+	if utils.IsNotExist(dir) {
+		return nil, nil
+	}
+	items, err := audit.GetAuditList(dir)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	var ids []string
+	for _, item := range items {
+		args := strings.Fields(item.Command)
+		for i := 1; i < len(args); i++ {
+			if args[i] == name {
+				ids = append(ids, item.ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// metaArchiveEntryName returns the name of the entry in the archive, only
+// the regular files with relative paths inside the archive are accepted
+func metaArchiveEntryName(hdr *tar.Header) (string, error) {
+	name := hdr.Name
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(name) || strings.Contains(name, "\\") ||
+		clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errMetaCorrupted.New("Unsafe path %s in the archive", name)
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		return "", errMetaCorrupted.New("%s in the archive is not a regular file", name)
+	}
+	// the operation lock of the cluster is never exported or overridden
+	if strings.HasPrefix(path.Base(clean), operationFilePrefix) {
+		return "", errMetaCorrupted.New("Unexpected %s in the archive", name)
+	}
+	return clean, nil
+}
+
+// walkMetaArchive calls fn with the regular files in the archive in order,
+// the directories are skipped and the other entries, e.g. links, are rejected
+func walkMetaArchive(file string, fn func(name string, hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return errMetaCorrupted.Wrap(err, "Failed to read %s", file)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errMetaCorrupted.Wrap(err, "Failed to read %s", file)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		name, err := metaArchiveEntryName(hdr)
+		if err != nil {
+			return err
+		}
+		if err := fn(name, hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// verifyMetaArchive checks the files in the archive match its manifest, the
+// archive is read without extracting anything
+func verifyMetaArchive(file string) (*metaManifest, error) {
+	var data []byte
+	hashes := make(map[string]string)
+	err := walkMetaArchive(file, func(name string, hdr *tar.Header, r io.Reader) error {
+		if _, ok := hashes[name]; ok || (name == metaManifestName && data != nil) {
+			return errMetaCorrupted.New("Duplicated %s in the archive", name)
+		}
+		if name == metaManifestName {
+			var err error
+			if data, err = io.ReadAll(r); err != nil {
+				return errMetaCorrupted.Wrap(err, "Failed to read the manifest")
+			}
+			return nil
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return errMetaCorrupted.Wrap(err, "Failed to read %s", name)
+		}
+		hashes[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		return nil, errMetaCorrupted.New("No manifest in the archive")
+	}
+	manifest := &metaManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errMetaCorrupted.Wrap(err, "Invalid manifest in the archive")
+	}
+	if err := clusterutil.ValidateClusterNameOrError(manifest.Cluster); err != nil {
+		return nil, err
+	}
+	for name, hash := range hashes {
+		expected, ok := manifest.Files[name]
+		if !ok {
+			return nil, errMetaCorrupted.New("%s is not in the manifest", name)
+		}
+		if hash != expected {
+			return nil, errMetaCorrupted.New("%s is corrupted, expect sha256 %s but got %s", name, expected, hash)
+		}
+	}
+	for name := range manifest.Files {
+		if _, ok := hashes[name]; !ok {
+			return nil, errMetaCorrupted.New("%s is missing in the archive", name)
+		}
+	}
+	return manifest, nil
+}
+
+// extractMetaArchive extracts the files of the archive verified with the
+// manifest to dir, the files are checked again in case the archive changes
+func extractMetaArchive(file, dir string, manifest *metaManifest) error {
+	return walkMetaArchive(file, func(name string, hdr *tar.Header, r io.Reader) error {
+		if name == metaManifestName {
+			return nil
+		}
+		expected, ok := manifest.Files[name]
+		if !ok {
+			return errMetaCorrupted.New("%s is not in the manifest", name)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := utils.CreateDir(filepath.Dir(dst)); err != nil {
+			return perrs.AddStack(err)
+		}
+		fw, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, hdr.FileInfo().Mode().Perm())
+		if err != nil {
+			return perrs.AddStack(err)
+		}
+		defer fw.Close()
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(fw, h), r); err != nil {
+			return perrs.AddStack(err)
+		}
+		if hash := hex.EncodeToString(h.Sum(nil)); hash != expected {
+			return errMetaCorrupted.New("%s is corrupted, expect sha256 %s but got %s", name, expected, hash)
+		}
+		return nil
+	})
+}
+
+// ImportClusterMeta imports the meta exported by ExportClusterMeta. The
+// archive is verified with its manifest, and it's rejected if a different
+// meta of the cluster exists or the cluster conflicts with the others,
+// unless force is true.
+func (m *Manager) ImportClusterMeta(file string, force, skipConfirm bool) error {
+	manifest, err := verifyMetaArchive(file)
+	if err != nil {
+		return err
+	}
+	if manifest.SysName != m.sysName {
+		return errMetaCorrupted.New("The archive is exported by %s, not %s", manifest.SysName, m.sysName)
+	}
+
+	dir, err := os.MkdirTemp("", "tiup-import-meta")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := extractMetaArchive(file, dir, manifest); err != nil {
+		return err
+	}
+	name := manifest.Cluster
+	m.logger.Infof("Importing meta of cluster %s exported from %s at %s", name, manifest.Host, manifest.Exported.Format(time.RFC3339))
+
+	// the cluster may be new to the control machine
+	release, _, err := m.acquireOperationLock(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// the extracted meta is read as the cluster `meta` in the dir
+	imported := spec.NewSpec(dir, m.specManager.NewMetadata)
+	metadata := imported.NewMetadata()
+	if err := imported.Metadata(metaArchiveDir, metadata); err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return perrs.Annotate(err, "failed to read the imported meta")
+	}
+
+	exist, err := m.specManager.Exist(name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		same, err := m.sameMeta(name, metadata)
+		if err != nil {
+			return err
+		}
+		if !same && !force {
+			return errMetaConflict.
+				New("A different meta of cluster %s exists", name).
+				WithProperty(tui.SuggestionFromFormat("Please check the meta with '%s display %s', and use '--force' to override it", tui.OsArgs0(), name))
+		}
+	}
+	if err := checkConflict(m, name, metadata.GetTopology()); err != nil {
+		if !force {
+			return err
+		}
+		m.logger.Warnf("%s", err)
+	}
+
+	if exist && !skipConfirm {
+		if err := m.prompter.PromptForConfirmOrAbortError(
+			"This operation will override the meta of cluster %s.\nDo you want to continue? [y/N]:",
+			color.HiYellowString(name)); err != nil {
+			return err
+		}
+	}
+
+	if err := m.replaceMetaDir(name, filepath.Join(dir, metaArchiveDir)); err != nil {
+		return perrs.Annotate(err, "failed to install the meta")
+	}
+
+	auditLogs, _ := filepath.Glob(filepath.Join(dir, auditArchiveDir, "*"))
+	sort.Strings(auditLogs)
+	count := 0
+	if err := utils.CreateDir(spec.AuditDir()); err != nil {
+		return perrs.AddStack(err)
+	}
+	for _, p := range auditLogs {
+		dst := filepath.Join(spec.AuditDir(), filepath.Base(p))
+		if utils.IsExist(dst) {
+			continue
+		}
+		if err := utils.Copy(p, dst); err != nil {
+			return perrs.Annotate(err, "failed to import the audit logs")
+		}
+		count++
+	}
+
+	m.logger.Infof("Imported meta of cluster %s with %d audit logs successfully", name, count)
+	return nil
+}
+
+// replaceMetaDir replaces the files in the meta dir of the cluster with the
+// ones in src, the operation lock held and its journal are kept
+func (m *Manager) replaceMetaDir(name, src string) error {
+	entries, err := os.ReadDir(m.specManager.Path(name))
+	if err != nil && !os.IsNotExist(err) {
+		return perrs.AddStack(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), operationFilePrefix) {
+			continue
+		}
+		if err := os.RemoveAll(m.specManager.Path(name, e.Name())); err != nil {
+			return perrs.AddStack(err)
+		}
+	}
+	if utils.IsNotExist(src) {
+		return nil
+	}
+	return utils.Copy(src, m.specManager.Path(name))
+}
+
+// sameMeta checks if the metadata is the same as the one of the cluster
+func (m *Manager) sameMeta(name string, metadata spec.Metadata) (bool, error) {
+	local := m.specManager.NewMetadata()
+	if err := m.specManager.Metadata(name, local); err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return false, err
+	}
+	a, err := yaml.Marshal(local)
+	if err != nil {
+		return false, perrs.AddStack(err)
+	}
+	b, err := yaml.Marshal(metadata)
+	if err != nil {
+		return false, perrs.AddStack(err)
+	}
+	return bytes.Equal(a, b), nil
+}
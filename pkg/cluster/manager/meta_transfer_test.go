@@ -0,0 +1,114 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type metaArchiveEntry struct {
+	name     string
+	content  string
+	typeflag byte
+}
+
+func writeMetaArchive(t *testing.T, file string, manifest metaManifest, entries ...metaArchiveEntry) {
+	f, err := os.Create(file)
+	assert.Nil(t, err)
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	data, err := json.Marshal(manifest)
+	assert.Nil(t, err)
+	entries = append(entries, metaArchiveEntry{name: metaManifestName, content: string(data)})
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: e.typeflag}
+		if e.typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			hdr.Linkname, hdr.Size = "/etc/passwd", 0
+		}
+		assert.Nil(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.content)[:hdr.Size])
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, gw.Close())
+}
+
+func TestVerifyMetaArchive(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "meta.tar.gz")
+	metaYaml := metaArchiveEntry{name: "meta/meta.yaml", content: "user: tidb\n"}
+	manifest := metaManifest{
+		Cluster: "prod",
+		SysName: "cluster",
+		Files: map[string]string{
+			"meta/meta.yaml": "0000",
+		},
+	}
+
+	// the hash mismatches
+	writeMetaArchive(t, file, manifest, metaYaml)
+	_, err := verifyMetaArchive(file)
+	assert.NotNil(t, err)
+
+	manifest.Files["meta/meta.yaml"], err = utils.SHA256(strings.NewReader("user: tidb\n"))
+	assert.Nil(t, err)
+	writeMetaArchive(t, file, manifest, metaYaml)
+	m, err := verifyMetaArchive(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", m.Cluster)
+
+	dir := t.TempDir()
+	assert.Nil(t, extractMetaArchive(file, dir, m))
+	data, err := os.ReadFile(filepath.Join(dir, "meta", "meta.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "user: tidb\n", string(data))
+	assert.False(t, utils.IsExist(filepath.Join(dir, metaManifestName)))
+
+	// a file not in the manifest
+	writeMetaArchive(t, file, manifest, metaYaml, metaArchiveEntry{name: "meta/ssh/id_rsa", content: "key"})
+	_, err = verifyMetaArchive(file)
+	assert.NotNil(t, err)
+
+	// the unsafe entries are rejected
+	for _, e := range []metaArchiveEntry{
+		{name: "../../.ssh/authorized_keys", content: "key"},
+		{name: "meta/../../id_rsa", content: "key"},
+		{name: "/etc/cron.d/tiup", content: "key"},
+		{name: "meta/ssh/id_rsa", typeflag: tar.TypeSymlink},
+		{name: "meta/.operation.lock", content: "{}"},
+	} {
+		writeMetaArchive(t, file, manifest, metaYaml, e)
+		_, err = verifyMetaArchive(file)
+		assert.NotNil(t, err, e.name)
+	}
+
+	// a file in the manifest is missing
+	manifest.Files["audit/fDDkzPEX3KW"] = "00"
+	writeMetaArchive(t, file, manifest, metaYaml)
+	_, err = verifyMetaArchive(file)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,406 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/checkpoint"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// the count of the last completed steps shown for an interrupted operation
+const interruptedStepsShown = 5
+
+// InterruptedOperation is an operation interrupted with the cluster locked
+type InterruptedOperation struct {
+	Lock *spec.OperationLock
+	// Steps is the checkpoints of the steps completed, from the journal
+	Steps []map[string]interface{}
+	// Journal is the audit log of the operation, nil if it's not journaled
+	Journal []byte
+}
+
+// Command returns the command line of the operation
+func (op *InterruptedOperation) Command() string {
+	if len(op.Lock.Args) == 0 {
+		return tui.OsArgs0()
+	}
+	return strings.Join(append([]string{filepath.Base(op.Lock.Args[0])}, op.Lock.Args[1:]...), " ")
+}
+
+// lockOperation acquires the operation lock of the cluster, and journals the
+// audit log of the operation until the returned func releases the lock. The
+// nested operations in the same process share the lock.
+func (m *Manager) lockOperation(name string) (func(), error) {
+	release, _, err := m.lockPausableOperation(name)
+	return release, err
+}
+
+// lockPausableOperation acquires the operation lock of the cluster like
+// lockOperation, the returned pauser releases the lock while the operation
+// waits for a long running change, it's nil for the nested operations. The
+// operation fails on resuming if the meta is changed by the others meanwhile.
+func (m *Manager) lockPausableOperation(name string) (func(), operator.LockPauser, error) {
+	// the lock is in the dir of the cluster, don't create it for the
+	// clusters not deployed, e.g. a mistyped name
+	exist, err := m.specManager.Exist(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exist {
+		return nil, nil, perrs.Errorf("%s cluster `%s` not exists", m.sysName, name)
+	}
+	return m.acquireOperationLock(name)
+}
+
+// acquireOperationLock implements lockPausableOperation without checking the
+// cluster exists, the dir of the cluster is created if absent
+func (m *Manager) acquireOperationLock(name string) (func(), operator.LockPauser, error) {
+	journal := func() {
+		if err := logger.StartAuditJournal(m.specManager.Path(name, spec.OperationJournalName)); err != nil {
+			m.logger.Warnf("Failed to journal the operation on cluster %s, it can't be resumed if interrupted: %s", name, err)
+		}
+	}
+
+	held, err := m.specManager.AcquireOperationLock(name, spec.NewOperationLock())
+	if err != nil {
+		if held == nil {
+			return nil, nil, err
+		}
+		if host, _ := os.Hostname(); held.PID == os.Getpid() && held.Host == host {
+			return func() {}, nil, nil
+		}
+		return nil, nil, m.operationLockedError(name, held, err)
+	}
+	journal()
+
+	locked := true
+	release := func() {
+		if !locked {
+			return
+		}
+		locked = false
+		logger.StopAuditJournal()
+		if err := m.specManager.ReleaseOperationLock(name); err != nil {
+			m.logger.Warnf("Failed to release the operation lock of cluster %s: %s", name, err)
+		}
+	}
+	pause := func() (func() error, error) {
+		snapshot, err := m.specManager.MetaSnapshot(name)
+		if err != nil {
+			return nil, err
+		}
+		release()
+		m.logger.Debugf("The operation lock of cluster %s is released while waiting", name)
+		return func() error {
+			held, err := m.specManager.AcquireOperationLock(name, spec.NewOperationLock())
+			if err != nil {
+				if held == nil {
+					return err
+				}
+				return m.operationLockedError(name, held, err)
+			}
+			locked = true
+			journal()
+			current, err := m.specManager.MetaSnapshot(name)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(snapshot, current) {
+				return perrs.Errorf("the meta of cluster %s was changed by another operation while waiting, please check it and retry", name)
+			}
+			return nil
+		}, nil
+	}
+	return release, pause, nil
+}
+
+// updateMetaRecords applies update to the meta re-read under the operation
+// lock and saves it, it's used to record the samples gathered by the commands
+// not changing the cluster, e.g. display, without overwriting the meta saved
+// by an operation meanwhile. The update is skipped if the cluster is being
+// operated.
+func (m *Manager) updateMetaRecords(name string, update func(metadata spec.Metadata)) error {
+	held, err := m.specManager.AcquireOperationLock(name, spec.NewOperationLock())
+	if err != nil {
+		if held == nil {
+			return err
+		}
+		m.logger.Debugf("Cluster %s is being operated, skip updating the records in the meta: %s", name, err)
+		return nil
+	}
+	defer func() {
+		if err := m.specManager.ReleaseOperationLock(name); err != nil {
+			m.logger.Warnf("Failed to release the operation lock of cluster %s: %s", name, err)
+		}
+	}()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	update(metadata)
+	return m.specManager.SaveMeta(name, metadata)
+}
+
+// operationLockedError describes the operation holding the lock of the
+// cluster, and suggests how to recover if it's interrupted
+func (m *Manager) operationLockedError(name string, held *spec.OperationLock, err error) error {
+	errx := errorx.Cast(err)
+	if errx == nil {
+		return err
+	}
+	op := &InterruptedOperation{Lock: held}
+	if !errx.IsOfType(spec.ErrOperationInterrupted) {
+		if host, _ := os.Hostname(); held.Host == host {
+			return errx.WithProperty(tui.SuggestionFromFormat(
+				"The operation `%s` started at %s is still running, please wait for it to finish.",
+				op.Command(), held.Started.Format(time.RFC3339)))
+		}
+		return errx.WithProperty(tui.SuggestionFromFormat(
+			"The operation `%s` started at %s on another control machine may be still running.\n"+
+				"Please wait for it to finish, or run '%s recover %s' if you are sure it's gone.",
+			op.Command(), held.Started.Format(time.RFC3339), tui.OsArgs0(), name))
+	}
+
+	if loaded, lerr := m.InterruptedOperation(name, false); lerr == nil && loaded != nil {
+		op = loaded
+	}
+	return errx.WithProperty(tui.SuggestionFromFormat(
+		"The operation `%s` started at %s was interrupted after %d steps completed.\n"+
+			"Please run '%s recover %s' to see what was in progress and resume it,\n"+
+			"roll back the meta or force clear the lock.",
+		op.Command(), held.Started.Format(time.RFC3339), len(op.Steps), tui.OsArgs0(), name))
+}
+
+// InterruptedOperation returns the operation holding the lock of the cluster
+// if it's interrupted, nil is returned if the cluster is not locked. The locks
+// held by the processes alive on the control machine can't be recovered
+// unless forced.
+func (m *Manager) InterruptedOperation(name string, force bool) (*InterruptedOperation, error) {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return nil, err
+	}
+	held, err := m.specManager.OperationLock(name)
+	if err != nil || held == nil {
+		return nil, err
+	}
+	running, err := m.specManager.IsOperationRunning(name)
+	if err != nil {
+		return nil, err
+	}
+	if running && !force {
+		return nil, spec.ErrOperationLocked.
+			New("Cluster %s is being operated by pid %d, it can't be recovered", name, held.PID).
+			WithProperty(tui.SuggestionFromFormat(
+				"Please wait for it to finish, or run '%s recover %s --force' if you are sure it hangs.",
+				tui.OsArgs0(), name))
+	}
+
+	op := &InterruptedOperation{Lock: held}
+	journal, err := os.ReadFile(m.specManager.Path(name, spec.OperationJournalName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return op, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	op.Journal = journal
+	cp, err := checkpoint.NewCheckPoint(bytes.NewReader(journal))
+	if err != nil {
+		return nil, err
+	}
+	op.Steps = cp.Points()
+	return op, nil
+}
+
+// the ways to recover an interrupted operation
+const (
+	RecoverResume     = "resume"
+	RecoverRollback   = "rollback"
+	RecoverForceClear = "force-clear"
+)
+
+// RecoverOptions is the options of recovering an interrupted operation
+type RecoverOptions struct {
+	Action string // one of RecoverResume, RecoverRollback and RecoverForceClear, it's asked if empty
+	Force  bool   // recover the lock even if the process holding it is alive
+}
+
+// RecoverOperation shows the operation interrupted with the cluster locked,
+// and recovers it as chosen. If it's resumed, the command args of the
+// operation and the audit log to replay it are returned for the caller to run.
+func (m *Manager) RecoverOperation(name string, opt RecoverOptions, skipConfirm bool) ([]string, string, error) {
+	switch opt.Action {
+	case "", RecoverResume, RecoverRollback, RecoverForceClear:
+	default:
+		return nil, "", perrs.Errorf("unknown action %s", opt.Action)
+	}
+
+	op, err := m.InterruptedOperation(name, opt.Force)
+	if err != nil {
+		return nil, "", err
+	}
+	if op == nil {
+		m.logger.Infof("Cluster %s is not locked by any operation", name)
+		return nil, "", nil
+	}
+	m.ShowInterruptedOperation(name, op)
+
+	action := opt.Action
+	switch {
+	case action != "":
+		if !skipConfirm {
+			if err := m.prompter.PromptForConfirmOrAbortError("\nDo you want to %s the operation? [y/N]: ", action); err != nil {
+				return nil, "", err
+			}
+		}
+	case skipConfirm:
+		return nil, "", perrs.Errorf("please specify how to recover it, %s, %s or %s", RecoverResume, RecoverRollback, RecoverForceClear)
+	default:
+		action = strings.TrimSpace(strings.ToLower(m.prompter.Prompt(
+			fmt.Sprintf("\nHow to recover it? [%s/%s/%s/abort] (default=abort):", RecoverResume, RecoverRollback, RecoverForceClear))))
+	}
+
+	switch action {
+	case RecoverResume:
+		return m.ResumeOperation(name, op)
+	case RecoverRollback:
+		return nil, "", m.RollbackOperation(name, op)
+	case RecoverForceClear:
+		return nil, "", m.ForceClearOperation(name, op)
+	default:
+		return nil, "", perrs.Errorf("operation aborted by user (with answer '%s')", action)
+	}
+}
+
+// describeStep returns the readable description of a checkpoint
+func describeStep(p map[string]interface{}) string {
+	switch {
+	case p["cmd"] != nil:
+		return fmt.Sprintf("run `%v` on %v", p["cmd"], p["host"])
+	case p["src"] != nil:
+		return fmt.Sprintf("copy %v to %v:%v", p["src"], p["host"], p["dst"])
+	case p["instance"] != nil:
+		return fmt.Sprintf("upgrade %v", p["instance"])
+	case p["config-file"] != nil:
+		return fmt.Sprintf("generate %v", p["config-file"])
+	}
+	fn := fmt.Sprint(p["__func__"])
+	return fn[strings.LastIndex(fn, "/")+1:]
+}
+
+// ShowInterruptedOperation prints what was in progress when the operation was
+// interrupted
+func (m *Manager) ShowInterruptedOperation(name string, op *InterruptedOperation) {
+	fmt.Fprintf(m.stdout, "Cluster %s is locked by an interrupted operation:\n", color.HiYellowString(name))
+	fmt.Fprintf(m.stdout, "Command:    %s\n", color.CyanString(op.Command()))
+	fmt.Fprintf(m.stdout, "Started:    %s by pid %d on %s\n", op.Lock.Started.Format(time.RFC3339), op.Lock.PID, op.Lock.Host)
+	if op.Journal == nil {
+		fmt.Fprintln(m.stdout, "Progress:   unknown, the operation was not journaled")
+		return
+	}
+	fmt.Fprintf(m.stdout, "Progress:   %d steps completed\n", len(op.Steps))
+	start := 0
+	if len(op.Steps) > interruptedStepsShown {
+		start = len(op.Steps) - interruptedStepsShown
+		fmt.Fprintln(m.stdout, "  ...")
+	}
+	for _, p := range op.Steps[start:] {
+		fmt.Fprintf(m.stdout, "  - %s\n", describeStep(p))
+	}
+}
+
+// ResumeOperation releases the lock of the interrupted operation and saves
+// its journal as an audit log, the operation can then be replayed from the
+// audit log with the completed steps skipped. The command args and the path
+// of the audit log are returned.
+func (m *Manager) ResumeOperation(name string, op *InterruptedOperation) ([]string, string, error) {
+	if op.Journal == nil {
+		return nil, "", perrs.Errorf("the operation on cluster %s was not journaled, it can't be resumed", name)
+	}
+	if err := utils.CreateDir(spec.AuditDir()); err != nil {
+		return nil, "", err
+	}
+	auditID, err := audit.ImportAuditLog(spec.AuditDir(), op.Journal)
+	if err != nil {
+		return nil, "", err
+	}
+	zap.L().Info("Resume interrupted operation",
+		zap.String("cluster", name), zap.Strings("args", op.Lock.Args), zap.String("audit-id", auditID))
+	if err := m.specManager.ReleaseOperationLock(name); err != nil {
+		return nil, "", err
+	}
+	return op.Lock.Args, filepath.Join(spec.AuditDir(), auditID), nil
+}
+
+// RollbackOperation restores the meta of the cluster to the one before the
+// interrupted operation and releases the lock. The changes already made on
+// the hosts are not reverted.
+func (m *Manager) RollbackOperation(name string, op *InterruptedOperation) error {
+	if err := m.specManager.RestoreOperationMeta(name); err != nil {
+		return err
+	}
+	if locked, _ := m.specManager.IsScaleOutLocked(name); locked && !op.Lock.ScaleOutLocked {
+		if err := m.specManager.ReleaseScaleOutLock(name); err != nil {
+			return perrs.AddStack(err)
+		}
+	}
+	zap.L().Info("Roll back interrupted operation",
+		zap.String("cluster", name), zap.Strings("args", op.Lock.Args), zap.Int("steps", len(op.Steps)))
+	if err := m.specManager.ReleaseOperationLock(name); err != nil {
+		return err
+	}
+
+	m.logger.Infof("The meta of cluster %s is rolled back to the one before `%s`", name, op.Command())
+	if len(op.Steps) > 0 {
+		m.logger.Warnf("The %d steps completed on the hosts are not reverted, please check them with '%s display %s'",
+			len(op.Steps), tui.OsArgs0(), name)
+	}
+	return nil
+}
+
+// ForceClearOperation releases the lock of the interrupted operation as is,
+// the lock cleared is recorded in the audit log
+func (m *Manager) ForceClearOperation(name string, op *InterruptedOperation) error {
+	zap.L().Info("Force clear operation lock",
+		zap.String("cluster", name),
+		zap.Strings("args", op.Lock.Args),
+		zap.Int("pid", op.Lock.PID),
+		zap.String("host", op.Lock.Host),
+		zap.Time("started", op.Lock.Started),
+		zap.Int("steps", len(op.Steps)),
+	)
+	if err := m.specManager.ReleaseOperationLock(name); err != nil {
+		return err
+	}
+	m.logger.Infof("The operation lock of cluster %s is cleared", name)
+	return nil
+}
@@ -49,6 +49,12 @@ func (m *Manager) Patch(name string, packagePath string, opt operator.Options, o
 		}
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil {
 		return err
@@ -62,7 +68,7 @@ func (m *Manager) Patch(name string, packagePath string, opt operator.Options, o
 	}
 
 	if !skipConfirm {
-		if err := tui.PromptForConfirmOrAbortError(
+		if err := m.prompter.PromptForConfirmOrAbortError(
 			fmt.Sprintf("Will patch the cluster %s with package path is %s, nodes: %s, roles: %s.\nDo you want to continue? [y/N]:",
 				color.HiYellowString(name),
 				color.HiYellowString(packagePath),
@@ -109,7 +115,7 @@ func (m *Manager) Patch(name string, packagePath string, opt operator.Options, o
 		Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, opt),
 		opt.Concurrency,
 		m.logger,
 	)
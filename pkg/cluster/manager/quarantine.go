@@ -0,0 +1,307 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// probeUnreachableHosts tries to connect to the SSH port of every host in the
+// topology, and returns the hosts that could not be connected in time.
+func probeUnreachableHosts(topo spec.Topology, gOpt operator.Options) set.StringSet {
+	unreachable := set.NewStringSet()
+
+	sshType := gOpt.SSHType
+	if sshType == "" {
+		sshType = topo.BaseTopo().GlobalOptions.SSHType
+	}
+	// nothing to probe if we are not going to use SSH at all
+	if !sshType.UseSSH() {
+		return unreachable
+	}
+
+	timeout := time.Second * time.Duration(gOpt.SSHTimeout)
+	if timeout == 0 {
+		timeout = time.Second * 5
+	}
+	concurrency := gOpt.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		pool = make(chan struct{}, concurrency)
+	)
+	spec.IterHost(topo, func(inst spec.Instance) {
+		wg.Add(1)
+		pool <- struct{}{}
+		go func(host string, port int) {
+			defer func() {
+				<-pool
+				wg.Done()
+			}()
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+			if err != nil {
+				mu.Lock()
+				unreachable.Insert(host)
+				mu.Unlock()
+				return
+			}
+			conn.Close()
+		}(inst.GetHost(), inst.GetSSHPort())
+	})
+	wg.Wait()
+
+	return unreachable
+}
+
+// quarantineUnreachableHosts probes the hosts of the cluster and records the
+// unreachable ones in meta, so that they could be caught up later by the
+// reconcile command. The returned hosts should be excluded from the operation.
+func (m *Manager) quarantineUnreachableHosts(name string, metadata spec.Metadata, gOpt operator.Options) (set.StringSet, error) {
+	unreachable := probeUnreachableHosts(metadata.GetTopology(), gOpt)
+	if len(unreachable) == 0 {
+		return unreachable, nil
+	}
+
+	hosts := unreachable.Slice()
+	sort.Strings(hosts)
+	m.logger.Warnf("Hosts %s are unreachable, they are quarantined and will be skipped", color.YellowString(strings.Join(hosts, ",")))
+
+	base := metadata.GetBaseMeta()
+	if base.QuarantinedHosts == nil {
+		return unreachable, nil
+	}
+	quarantined := set.NewStringSet(*base.QuarantinedHosts...).Join(unreachable).Slice()
+	sort.Strings(quarantined)
+	*base.QuarantinedHosts = quarantined
+
+	return unreachable, m.specManager.SaveMeta(name, metadata)
+}
+
+// reachableNodes returns the IDs of the instances that are not located on
+// any of the skipped hosts, the result is limited to nodes and roles if they
+// are not empty. It returns false if all the instances matched are skipped,
+// as an empty result means all nodes to the operations, there is nothing to
+// do in that case.
+func reachableNodes(topo spec.Topology, nodes, roles []string, skipHosts set.StringSet) ([]string, bool) {
+	if len(skipHosts) == 0 {
+		return nodes, true
+	}
+
+	filter := set.NewStringSet(nodes...)
+	roleFilter := set.NewStringSet(roles...)
+	result := make([]string, 0)
+	for _, comp := range topo.ComponentsByStartOrder() {
+		for _, inst := range comp.Instances() {
+			if skipHosts.Exist(inst.GetHost()) {
+				continue
+			}
+			if len(filter) > 0 && !filter.Exist(inst.ID()) {
+				continue
+			}
+			if len(roleFilter) > 0 && !roleFilter.Exist(inst.Role()) {
+				continue
+			}
+			result = append(result, inst.ID())
+		}
+	}
+	return result, len(result) > 0
+}
+
+// skippedNodes returns the IDs of the instances located on the skipped hosts.
+func skippedNodes(topo spec.Topology, skipHosts set.StringSet) []string {
+	result := make([]string, 0)
+	topo.IterInstance(func(inst spec.Instance) {
+		if skipHosts.Exist(inst.GetHost()) {
+			result = append(result, inst.ID())
+		}
+	})
+	return result
+}
+
+// Reconcile catches up the hosts quarantined by previous operations, the
+// components of current cluster version are deployed to them, configs are
+// refreshed and the instances are restarted.
+func (m *Manager) Reconcile(name string, gOpt operator.Options, skipConfirm bool) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	// check locked
+	if err := m.specManager.ScaleOutLockedErr(name); err != nil {
+		return err
+	}
+
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	if base.QuarantinedHosts == nil || len(*base.QuarantinedHosts) == 0 {
+		m.logger.Infof("There is no quarantined host in cluster `%s`", name)
+		return nil
+	}
+
+	quarantined := set.NewStringSet(*base.QuarantinedHosts...)
+	unreachable := probeUnreachableHosts(topo, gOpt).Intersection(quarantined)
+	recovered := quarantined.Difference(unreachable)
+	if len(recovered) == 0 {
+		return perrs.Errorf("all quarantined hosts of cluster %s are still unreachable: %s",
+			name, strings.Join(*base.QuarantinedHosts, ","))
+	}
+
+	recoveredHosts := recovered.Slice()
+	sort.Strings(recoveredHosts)
+	if !skipConfirm {
+		if err := m.prompter.PromptForConfirmOrAbortError(
+			fmt.Sprintf("Will reconcile hosts %s of cluster %s to version %s, instances on them will be restarted.\nDo you want to continue? [y/N]:",
+				color.HiYellowString(strings.Join(recoveredHosts, ",")),
+				color.HiYellowString(name),
+				color.HiYellowString(base.Version),
+			),
+		); err != nil {
+			return err
+		}
+	}
+
+	// every host except the recovered ones should be left untouched
+	skipHosts := set.NewStringSet()
+	spec.IterHost(topo, func(inst spec.Instance) {
+		if !recovered.Exist(inst.GetHost()) {
+			skipHosts.Insert(inst.GetHost())
+		}
+	})
+	gOpt.Roles = nil
+	var ok bool
+	// the hosts recovered may have no instance left after scaling in
+	if gOpt.Nodes, ok = reachableNodes(topo, nil, nil, skipHosts); ok {
+		if err := m.reconcileHosts(name, metadata, skipHosts, gOpt); err != nil {
+			return err
+		}
+	}
+
+	remains := unreachable.Slice()
+	sort.Strings(remains)
+	*base.QuarantinedHosts = remains
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+
+	if len(remains) > 0 {
+		m.logger.Warnf("Hosts %s are still unreachable and kept quarantined", color.YellowString(strings.Join(remains, ",")))
+	}
+	m.logger.Infof("Reconciled cluster `%s` successfully", name)
+	return nil
+}
+
+// reconcileHosts deploys the components of current cluster version to the
+// hosts not skipped, refreshes the configs and restarts the instances on them
+func (m *Manager) reconcileHosts(name string, metadata spec.Metadata, skipHosts set.StringSet, gOpt operator.Options) error {
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	var sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
+	if gOpt.SSHType.UseSSH() && len(gOpt.SSHProxyHost) != 0 {
+		var err error
+		if sshProxyProps, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
+			return err
+		}
+	}
+
+	downloadCompTasks, copyCompTasks, _, err := buildUpgradeComponentTasks(m, name, topo, base, base.Version, gOpt, skipHosts)
+	if err != nil {
+		return err
+	}
+
+	// the configs changed while the hosts were quarantined are refreshed
+	refreshConfigTasks, _ := buildInitConfigTasks(m, name, topo, base, gOpt, skippedNodes(topo, skipHosts))
+	uniqueHosts, noAgentHosts := getMonitorHosts(topo)
+	for host := range skipHosts {
+		delete(uniqueHosts, host)
+	}
+	monitorConfigTasks := buildInitMonitoredConfigTasks(
+		m.specManager,
+		name,
+		uniqueHosts,
+		noAgentHosts,
+		*topo.BaseTopo().GlobalOptions,
+		topo.GetMonitoredOptions(),
+		m.logger,
+		gOpt.SSHTimeout,
+		gOpt.OptTimeout,
+		gOpt,
+		sshProxyProps,
+	)
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
+	if err != nil {
+		return err
+	}
+	b.Parallel(false, downloadCompTasks...).
+		Parallel(gOpt.Force, copyCompTasks...).
+		ParallelStep("+ Refresh instance configs", gOpt.Force, refreshConfigTasks...)
+	if len(monitorConfigTasks) > 0 {
+		b.ParallelStep("+ Refresh monitor configs", gOpt.Force, monitorConfigTasks...)
+	}
+	t := b.Func("ReconcileCluster", func(ctx context.Context) error {
+		return operator.Upgrade(ctx, topo, gOpt, tlsCfg)
+	}).Build()
+
+	ctx := ctxt.New(
+		m.baseContext(name, gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+	return nil
+}
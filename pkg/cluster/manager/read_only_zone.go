@@ -0,0 +1,55 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// ensureReadOnlyZoneRules applies the placement rules of the read-only zones,
+// the failure is only warned as the cluster works with the rules outdated
+// and they are applied again on next start or reload.
+func (m *Manager) ensureReadOnlyZoneRules(ctx context.Context, cluster *spec.Specification, tlsCfg *tls.Config, gOpt operator.Options) {
+	ctx = context.WithValue(ctx, logprinter.ContextKeyLogger, m.logger)
+	if err := cluster.EnsureReadOnlyZoneRules(ctx, tlsCfg, time.Second*time.Duration(gOpt.APITimeout), cluster.GetPDList()...); err != nil {
+		m.logger.Warnf("Failed to apply the placement rules of read-only zones: %s", err)
+	}
+}
+
+// readOnlyZonesAfterScaleIn returns the cluster with the TiKV instances in
+// nodes removed, to place the replicas of the read-only zones before the
+// stores are deleted. nil is returned if there is no read-only zone.
+func readOnlyZonesAfterScaleIn(topo spec.Topology, nodes []string) *spec.Specification {
+	cluster, ok := topo.(*spec.Specification)
+	if !ok || len(cluster.GlobalOptions.ReadOnlyZones) == 0 {
+		return nil
+	}
+	deleted := set.NewStringSet(nodes...)
+	remaining := *cluster
+	remaining.TiKVServers = nil
+	for _, kv := range cluster.TiKVServers {
+		if !deleted.Exist(fmt.Sprintf("%s:%d", kv.Host, kv.GetMainPort())) {
+			remaining.TiKVServers = append(remaining.TiKVServers, kv)
+		}
+	}
+	return &remaining
+}
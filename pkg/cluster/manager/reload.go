@@ -23,10 +23,10 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
-
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/tui"
 )
 
@@ -41,6 +41,12 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	sshTimeout := gOpt.SSHTimeout
 	exeTimeout := gOpt.OptTimeout
 
@@ -50,7 +56,7 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 	}
 
 	var sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
-	if gOpt.SSHType != executor.SSHTypeNone && len(gOpt.SSHProxyHost) != 0 {
+	if gOpt.SSHType.UseSSH() && len(gOpt.SSHProxyHost) != 0 {
 		var err error
 		if sshProxyProps, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
 			return err
@@ -58,26 +64,61 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 	}
 
 	if !skipConfirm {
-		if err := tui.PromptForConfirmOrAbortError(
-			fmt.Sprintf("Will reload the cluster %s with restart policy is %s, nodes: %s, roles: %s.\nDo you want to continue? [y/N]:",
+		rollback := ""
+		if gOpt.ConfigGeneration > 0 {
+			rollback = fmt.Sprintf("\nThe configs will be rolled back to generation %s.",
+				color.HiRedString(fmt.Sprintf("%d", gOpt.ConfigGeneration)))
+		}
+		if err := m.prompter.PromptForConfirmOrAbortError(
+			fmt.Sprintf("Will reload the cluster %s with restart policy is %s, nodes: %s, roles: %s.%s\nDo you want to continue? [y/N]:",
 				color.HiYellowString(name),
 				color.HiRedString(fmt.Sprintf("%v", !skipRestart)),
 				color.HiRedString(strings.Join(gOpt.Nodes, ",")),
 				color.HiRedString(strings.Join(gOpt.Roles, ",")),
+				rollback,
 			),
 		); err != nil {
 			return err
 		}
 	}
 
+	if err := m.pruneRuntimeOverrides(name, metadata); err != nil {
+		return err
+	}
+
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
 
+	var skipHosts set.StringSet
+	if gOpt.SkipUnreachable {
+		if skipHosts, err = m.quarantineUnreachableHosts(name, metadata, gOpt); err != nil {
+			return err
+		}
+		nodes, ok := reachableNodes(topo, gOpt.Nodes, gOpt.Roles, skipHosts)
+		if !ok {
+			m.logger.Infof("All the instances to reload are on the unreachable hosts, nothing to do")
+			return nil
+		}
+		gOpt.Nodes = nodes
+	}
+
 	// monitor
 	uniqueHosts, noAgentHosts := getMonitorHosts(topo)
+	for host := range skipHosts {
+		delete(uniqueHosts, host)
+	}
 
-	// init config
-	refreshConfigTasks, hasImported := buildInitConfigTasks(m, name, topo, base, gOpt, nil)
+	// init config, or roll back to the generation kept in the config history
+	var refreshConfigTasks []*task.StepDisplay
+	hasImported := false
+	if gOpt.ConfigGeneration > 0 {
+		refreshConfigTasks, err = buildRollbackConfigTasks(m, name, topo, base, gOpt, gOpt.ConfigGeneration, skippedNodes(topo, skipHosts))
+		if err != nil {
+			return err
+		}
+	} else {
+		refreshConfigTasks, hasImported = buildInitConfigTasks(m, name, topo, base, gOpt, skippedNodes(topo, skipHosts))
+	}
 
 	// handle dir scheme changes
 	if hasImported {
@@ -100,10 +141,23 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		sshProxyProps,
 	)
 
+	// the hostnames may be changed by edit-config
+	etcHosts, err := spec.EtcHostsEntries(topo)
+	if err != nil {
+		return err
+	}
+	etcHostsHosts := make([]string, 0, len(uniqueHosts))
+	for host := range uniqueHosts {
+		etcHostsHosts = append(etcHostsHosts, host)
+	}
+
 	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
 	if err != nil {
 		return err
 	}
+	if etcHostsTasks := buildEtcHostsTasks(m, name, etcHostsHosts, etcHosts); len(etcHostsTasks) > 0 {
+		b.ParallelStep("+ Update /etc/hosts", gOpt.Force, etcHostsTasks...)
+	}
 	if topo.Type() == spec.TopoTypeTiDB && !skipRestart {
 		b.UpdateTopology(
 			name,
@@ -126,12 +180,18 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		b.Func("Upgrade Cluster", func(ctx context.Context) error {
 			return operator.Upgrade(ctx, topo, gOpt, tlsCfg)
 		})
+		if cluster, ok := topo.(*spec.Specification); ok {
+			b.Func("EnsureReadOnlyZoneRules", func(ctx context.Context) error {
+				m.ensureReadOnlyZoneRules(ctx, cluster, tlsCfg, gOpt)
+				return nil
+			})
+		}
 	}
 
 	t := b.Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -143,7 +203,14 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		return perrs.Trace(err)
 	}
 
+	if !skipRestart {
+		m.recordRestarts(name, metadata, "reload", gOpt)
+	}
 	m.logger.Infof("Reloaded cluster `%s` successfully", name)
+	if gOpt.ConfigGeneration > 0 {
+		m.logger.Warnf("The configs are rolled back to generation %d, they are rendered from the topology again by the next reload,\n"+
+			"please edit the topology with '%s edit-config %s' to keep them", gOpt.ConfigGeneration, tui.OsArgs0(), name)
+	}
 
 	return nil
 }
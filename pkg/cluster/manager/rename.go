@@ -46,14 +46,14 @@ func (m *Manager) Rename(name string, opt operator.Options, newName string, skip
 	}
 
 	if !skipConfirm {
-		if err := tui.PromptForConfirmOrAbortError(
+		if err := m.prompter.PromptForConfirmOrAbortError(
 			fmt.Sprintf("Will rename the cluster name from %s to %s.\nDo you confirm this action? [y/N]:", color.HiYellowString(name), color.HiYellowString(newName)),
 		); err != nil {
 			return err
 		}
 	}
 
-	_, err := m.meta(name)
+	metadata, err := m.meta(name)
 	if err != nil { // refuse renaming if current cluster topology is not valid
 		return err
 	}
@@ -61,6 +61,12 @@ func (m *Manager) Rename(name string, opt operator.Options, newName string, skip
 	if err := os.Rename(m.specManager.Path(name), m.specManager.Path(newName)); err != nil {
 		return err
 	}
+	if err := m.specManager.HostRegistry().Unregister(name); err != nil {
+		return err
+	}
+	if err := m.specManager.HostRegistry().Register(newName, metadata.GetTopology()); err != nil {
+		return err
+	}
 
 	m.logger.Infof("Rename cluster `%s` -> `%s` successfully", name, newName)
 
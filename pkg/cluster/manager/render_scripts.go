@@ -0,0 +1,148 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/checkpoint"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// renderExecutor implements ctxt.Executor, it records the files transferred
+// to the host instead of touching it, so the generated files can be previewed.
+type renderExecutor struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newRenderExecutor() *renderExecutor {
+	return &renderExecutor{files: make(map[string][]byte)}
+}
+
+// Execute implements ctxt.Executor interface, only the files moved by mv are
+// tracked, all other commands are ignored.
+func (e *renderExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 3 && fields[0] == "mv" {
+		e.mu.Lock()
+		if data, ok := e.files[fields[1]]; ok {
+			delete(e.files, fields[1])
+			e.files[fields[2]] = data
+		}
+		e.mu.Unlock()
+	}
+	return nil, nil, nil
+}
+
+// Transfer implements ctxt.Executor interface.
+func (e *renderExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	if download {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	e.mu.Lock()
+	e.files[dst] = data
+	e.mu.Unlock()
+	return nil
+}
+
+// isRenderedScript checks if the file is a run script or a systemd unit
+func isRenderedScript(fname string) bool {
+	return strings.Contains(fname, "/scripts/") || strings.HasSuffix(fname, ".service")
+}
+
+// RenderScripts prints the run scripts and systemd units that would be
+// generated for the instances, without touching the hosts.
+func (m *Manager) RenderScripts(name string, nodes []string) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return perrs.New("at least one node must be specified with -N")
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	cacheDir, err := os.MkdirTemp("", "tiup-render-scripts")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	ctx := checkpoint.NewContext(ctxt.New(m.baseContext(name, operator.Options{}), 0, m.logger))
+	filter := set.NewStringSet(nodes...)
+	found := set.NewStringSet()
+	for _, comp := range topo.ComponentsByStartOrder() {
+		for _, inst := range comp.Instances() {
+			if !filter.Exist(inst.ID()) {
+				continue
+			}
+			found.Insert(inst.ID())
+
+			e := newRenderExecutor()
+			paths := meta.DirPaths{
+				Deploy:    spec.Abs(base.User, inst.DeployDir()),
+				Data:      spec.MultiDirAbs(base.User, inst.DataDir()),
+				Log:       spec.Abs(base.User, inst.LogDir()),
+				Cache:     cacheDir,
+				Templates: m.specManager.Path(name, spec.TemplateDirName),
+			}
+			// the config check needs the binaries on the host, ignore it
+			if err := inst.InitConfig(ctx, e, name, base.Version, base.User, paths); err != nil &&
+				perrs.Cause(err) != spec.ErrorCheckConfig {
+				return perrs.Annotatef(err, "render scripts failed: %s", inst.ID())
+			}
+
+			fnames := make([]string, 0, len(e.files))
+			for fname := range e.files {
+				if isRenderedScript(fname) {
+					fnames = append(fnames, fname)
+				}
+			}
+			sort.Strings(fnames)
+			for _, fname := range fnames {
+				fmt.Fprintln(m.stdout, color.CyanString("# %s: %s", inst.ID(), fname))
+				fmt.Fprintln(m.stdout, strings.TrimRight(string(e.files[fname]), "\n"))
+				fmt.Fprintln(m.stdout)
+			}
+		}
+	}
+
+	if missing := filter.Difference(found); len(missing) > 0 {
+		return perrs.Errorf("instances not found in the cluster: %s", strings.Join(missing.Slice(), ","))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderExecutor(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "cdc.service")
+	require.NoError(t, os.WriteFile(src, []byte("[Unit]"), 0644))
+
+	e := newRenderExecutor()
+	ctx := context.Background()
+	require.NoError(t, e.Transfer(ctx, src, "/tmp/cdc_uuid.service", false, 0, false))
+	require.NoError(t, e.Transfer(ctx, src, "/deploy/scripts/run_cdc.sh", false, 0, false))
+	require.NoError(t, e.Transfer(ctx, "/not/exist", "/deploy/log", true, 0, false))
+	_, _, err := e.Execute(ctx, "mv /tmp/cdc_uuid.service /etc/systemd/system/cdc-8300.service", true)
+	require.NoError(t, err)
+	_, _, err = e.Execute(ctx, "chmod +x /deploy/scripts/run_cdc.sh", false)
+	require.NoError(t, err)
+
+	assert.Len(t, e.files, 2)
+	assert.Equal(t, "[Unit]", string(e.files["/etc/systemd/system/cdc-8300.service"]))
+	assert.True(t, isRenderedScript("/etc/systemd/system/cdc-8300.service"))
+	assert.True(t, isRenderedScript("/deploy/scripts/run_cdc.sh"))
+	assert.False(t, isRenderedScript("/deploy/conf/cdc.toml"))
+
+	// the file to upload must exist
+	assert.Error(t, e.Transfer(ctx, "/not/exist", "/deploy/conf/cdc.toml", false, 0, false))
+}
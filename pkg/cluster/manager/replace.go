@@ -0,0 +1,358 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+const (
+	// the record of the replacement in progress, and the scale-out topology
+	// of the new instance
+	replaceRecordName   = "replace.json"
+	replaceTopologyName = "replace.yaml"
+
+	// the stages of a replacement, they are run in order
+	replaceStageScaleOut = "scale-out"
+	replaceStageConverge = "converge"
+	replaceStageScaleIn  = "scale-in"
+
+	// the new TiKV store is considered converged if its region count
+	// reaches the ratio of the average of the other stores
+	replaceRegionRatio = 0.8
+)
+
+var (
+	errNSReplace         = errorx.NewNamespace("replace")
+	errReplaceInProgress = errNSReplace.NewType("in_progress", utils.ErrTraitPreCheck)
+	errReplaceNotFound   = errNSReplace.NewType("not_found", utils.ErrTraitPreCheck)
+)
+
+// ReplaceOptions contains the options of replacing an instance
+type ReplaceOptions struct {
+	Node        string        // the ID of the instance replaced
+	NewHost     string        // the host of the new instance
+	WaitTimeout time.Duration // the timeout waiting for the new instance to converge
+	Resume      bool          // resume the replacement in progress
+	Abort       bool          // discard the record of the replacement in progress
+	Deploy      DeployOptions
+}
+
+// replaceRecord is the record of a replacement in progress, which makes the
+// replacement resumable from the stage it's interrupted
+type replaceRecord struct {
+	Old     string    `json:"old"`
+	New     string    `json:"new"`
+	Stage   string    `json:"stage"`
+	Started time.Time `json:"started"`
+}
+
+func (m *Manager) loadReplaceRecord(name string) (*replaceRecord, error) {
+	data, err := os.ReadFile(m.specManager.Path(name, replaceRecordName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	record := &replaceRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, perrs.Annotate(err, "invalid record of replacement")
+	}
+	return record, nil
+}
+
+func (m *Manager) saveReplaceRecord(name string, record *replaceRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(os.WriteFile(m.specManager.Path(name, replaceRecordName), data, 0644))
+}
+
+func (m *Manager) removeReplaceRecord(name string) error {
+	for _, f := range []string{replaceRecordName, replaceTopologyName} {
+		if err := os.Remove(m.specManager.Path(name, f)); err != nil && !os.IsNotExist(err) {
+			return perrs.AddStack(err)
+		}
+	}
+	return nil
+}
+
+// Replace replaces an instance with a new one on another host, by scaling out
+// the new instance, waiting for it to converge and scaling in the old one.
+// The progress is recorded so an interrupted replacement can be resumed.
+func (m *Manager) Replace(
+	name string,
+	opt ReplaceOptions,
+	afterDeploy func(b *task.Builder, newPart spec.Topology, gOpt operator.Options),
+	final func(b *task.Builder, name string, meta spec.Metadata, gOpt operator.Options),
+	scale func(b *task.Builder, metadata spec.Metadata, gOpt operator.Options, tlsCfg *tls.Config),
+	skipConfirm bool,
+	gOpt operator.Options,
+) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	// the stages share the lock, it's only released while waiting for the
+	// new instance to converge
+	release, pause, err := m.lockPausableOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+
+	record, err := m.loadReplaceRecord(name)
+	if err != nil {
+		return err
+	}
+	if opt.Abort {
+		if record == nil {
+			return errReplaceNotFound.New("No replacement in progress for cluster %s", name)
+		}
+		m.logger.Warnf("The replacement of %s with %s is discarded at stage %s, the instances are kept as is", record.Old, record.New, record.Stage)
+		return m.removeReplaceRecord(name)
+	}
+
+	switch {
+	case record != nil && (opt.Resume || opt.Node == "" || opt.Node == record.Old):
+		m.logger.Infof("Resume the replacement of %s with %s from stage %s", record.Old, record.New, record.Stage)
+	case record != nil:
+		return errReplaceInProgress.
+			New("The replacement of %s with %s is in progress since %s", record.Old, record.New, record.Started.Format(time.RFC3339)).
+			WithProperty(tui.SuggestionFromFormat("Please resume it with '%s replace %s --resume' or discard it with '--abort'", tui.OsArgs0(), name))
+	case opt.Resume:
+		return errReplaceNotFound.New("No replacement in progress for cluster %s", name)
+	default:
+		if opt.Node == "" || opt.NewHost == "" {
+			return perrs.New("both the node replaced and the new host must be specified")
+		}
+		data, newID, err := spec.ReplacementTopology(metadata.GetTopology(), opt.Node, opt.NewHost)
+		if err != nil {
+			return err
+		}
+		if !skipConfirm {
+			fmt.Fprintf(m.stdout, "Spec of the new instance:\n%s\n", data)
+			if err := m.prompter.PromptForConfirmOrAbortError(
+				"This operation will deploy %s, wait for it to converge and delete %s and all its data in `%s`.\nDo you want to continue? [y/N]:",
+				color.HiYellowString(newID),
+				color.HiYellowString(opt.Node),
+				color.HiYellowString(name)); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(m.specManager.Path(name, replaceTopologyName), data, 0644); err != nil {
+			return perrs.AddStack(err)
+		}
+		record = &replaceRecord{
+			Old:     opt.Node,
+			New:     newID,
+			Stage:   replaceStageScaleOut,
+			Started: time.Now(),
+		}
+		if err := m.saveReplaceRecord(name, record); err != nil {
+			return err
+		}
+	}
+
+	if record.Stage == replaceStageScaleOut {
+		// the new instance may be scaled out before interrupted
+		if !hasInstance(metadata.GetTopology(), record.New) {
+			if err := m.ScaleOut(
+				name,
+				m.specManager.Path(name, replaceTopologyName),
+				afterDeploy,
+				final,
+				opt.Deploy,
+				true,
+				gOpt,
+			); err != nil {
+				return perrs.Annotatef(err, "failed to scale out %s, fix it and resume the replacement", record.New)
+			}
+		}
+		record.Stage = replaceStageConverge
+		if err := m.saveReplaceRecord(name, record); err != nil {
+			return err
+		}
+	}
+
+	if record.Stage == replaceStageConverge {
+		var resume func() error
+		if pause != nil {
+			if resume, err = pause(); err != nil {
+				return err
+			}
+		}
+		err := m.waitReplaceConverge(name, record.New, opt.WaitTimeout, gOpt)
+		if resume != nil {
+			if rerr := resume(); rerr != nil {
+				return rerr
+			}
+		}
+		if err != nil {
+			return perrs.Annotatef(err, "%s is not converged, resume the replacement later", record.New)
+		}
+		record.Stage = replaceStageScaleIn
+		if err := m.saveReplaceRecord(name, record); err != nil {
+			return err
+		}
+	}
+
+	if metadata, err = m.meta(name); err != nil {
+		return err
+	}
+	if hasInstance(metadata.GetTopology(), record.Old) {
+		gOpt.Nodes = []string{record.Old}
+		if err := m.ScaleIn(name, true, gOpt, func(b *task.Builder, metadata spec.Metadata, tlsCfg *tls.Config) {
+			scale(b, metadata, gOpt, tlsCfg)
+		}); err != nil {
+			return perrs.Annotatef(err, "failed to scale in %s, fix it and resume the replacement", record.Old)
+		}
+	}
+
+	if err := m.removeReplaceRecord(name); err != nil {
+		return err
+	}
+	m.logger.Infof("Replaced %s with %s in cluster `%s` successfully", record.Old, record.New, name)
+	return nil
+}
+
+func hasInstance(topo spec.Topology, id string) bool {
+	found := false
+	topo.IterInstance(func(inst spec.Instance) {
+		if inst.ID() == id {
+			found = true
+		}
+	})
+	return found
+}
+
+// waitReplaceConverge waits for the new instance to be up, and for TiKV, to
+// take over its share of regions
+func (m *Manager) waitReplaceConverge(name, id string, timeout time.Duration, gOpt operator.Options) error {
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo := metadata.GetTopology()
+	var inst spec.Instance
+	topo.IterInstance(func(i spec.Instance) {
+		if i.ID() == id {
+			inst = i
+		}
+	})
+	if inst == nil {
+		return perrs.Errorf("instance %s not found in cluster %s", id, name)
+	}
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+	ctx := ctxt.New(
+		m.baseContext(name, gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	masterList := topo.BaseTopo().MasterList
+	statusTimeout := api.RequestTimeout(ctx, time.Duration(gOpt.APITimeout)*time.Second)
+
+	m.logger.Infof("Waiting for %s to converge, timeout %s", id, timeout)
+	return utils.Wait(ctx, func() error {
+		status := inst.Status(ctx, statusTimeout, tlsCfg, masterList...)
+		if !strings.HasPrefix(status, "Up") && !strings.HasPrefix(status, "Healthy") {
+			return perrs.Errorf("%s is %s", id, status)
+		}
+		if inst.ComponentName() != spec.ComponentTiKV {
+			return nil
+		}
+
+		stores, err := api.NewPDClient(ctx, masterList, statusTimeout, tlsCfg).GetStores(ctx)
+		if err != nil {
+			return err
+		}
+		converged, progress := storeConverged(stores, id)
+		m.logger.Infof("\t%s", progress)
+		if !converged {
+			return perrs.New(progress)
+		}
+		return nil
+	}, utils.WaitOption{
+		Interval: time.Second * 10,
+		Timeout:  timeout,
+	})
+}
+
+// storeConverged checks if the store of addr has taken over its share of
+// regions, compared with the average of the other up TiKV stores
+func storeConverged(stores *api.StoresInfo, addr string) (bool, string) {
+	var (
+		current *api.StoreInfo
+		total   int
+		count   int
+	)
+	for _, s := range stores.Stores {
+		if s.Store == nil || s.Status == nil || s.Store.State != metapb.StoreState_Up || isTiFlashStore(s) {
+			continue
+		}
+		if s.Store.Address == addr {
+			current = s
+			continue
+		}
+		total += s.Status.RegionCount
+		count++
+	}
+	if current == nil {
+		return false, fmt.Sprintf("store %s is not up", addr)
+	}
+	if count == 0 {
+		return true, fmt.Sprintf("store %s has %d regions", addr, current.Status.RegionCount)
+	}
+
+	target := int(float64(total) / float64(count) * replaceRegionRatio)
+	progress := fmt.Sprintf("store %s has %d regions, target %d", addr, current.Status.RegionCount, target)
+	return current.Status.RegionCount >= target, progress
+}
+
+func isTiFlashStore(s *api.StoreInfo) bool {
+	for _, l := range s.Store.Labels {
+		if l.Key == "engine" && l.Value == "tiflash" {
+			return true
+		}
+	}
+	return false
+}
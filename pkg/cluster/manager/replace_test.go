@@ -0,0 +1,52 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStore(addr string, state metapb.StoreState, regions int, labels ...*metapb.StoreLabel) *api.StoreInfo {
+	return &api.StoreInfo{
+		Store: &api.MetaStore{Store: &metapb.Store{
+			Address: addr,
+			State:   state,
+			Labels:  labels,
+		}},
+		Status: &api.StoreStatus{RegionCount: regions},
+	}
+}
+
+func TestStoreConverged(t *testing.T) {
+	stores := &api.StoresInfo{Stores: []*api.StoreInfo{
+		testStore("172.16.5.1:20160", metapb.StoreState_Up, 1000),
+		testStore("172.16.5.2:20160", metapb.StoreState_Up, 1000),
+		testStore("172.16.5.3:20160", metapb.StoreState_Offline, 10),
+		testStore("172.16.5.4:3930", metapb.StoreState_Up, 10, &metapb.StoreLabel{Key: "engine", Value: "tiflash"}),
+		testStore("172.16.5.9:20160", metapb.StoreState_Up, 500),
+	}}
+
+	converged, _ := storeConverged(stores, "172.16.5.9:20160")
+	assert.False(t, converged)
+	stores.Stores[4].Status.RegionCount = 800
+	converged, _ = storeConverged(stores, "172.16.5.9:20160")
+	assert.True(t, converged)
+
+	converged, _ = storeConverged(stores, "172.16.5.10:20160")
+	assert.False(t, converged)
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// the states of the restart hooks shown
+const (
+	restartHookRun      = "run"
+	restartHookDisabled = "disabled"
+	restartHookForced   = "skipped (--force)"
+)
+
+// RestartHookPlan is a hook run around restarting an instance
+type RestartHookPlan struct {
+	ID      string `json:"id"`
+	Role    string `json:"role"`
+	Host    string `json:"host"`
+	Hook    string `json:"hook"`
+	Action  string `json:"action"`
+	Timeout string `json:"timeout"`
+	State   string `json:"state"`
+}
+
+// restartHookPlans returns the hooks run around restarting the instances,
+// the instances without hooks are omitted
+func restartHookPlans(instances []spec.Instance, topo spec.Topology, opt operator.Options) ([]RestartHookPlan, error) {
+	var plans []RestartHookPlan
+	for _, inst := range instances {
+		hooks, err := spec.DescribeRestartHooks(inst, topo, int(opt.APITimeout), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hooks {
+			p := RestartHookPlan{
+				ID:      inst.ID(),
+				Role:    inst.Role(),
+				Host:    inst.GetHost(),
+				Hook:    h.Name,
+				Action:  h.Action,
+				Timeout: "-",
+				State:   restartHookRun,
+			}
+			if h.Timeout > 0 {
+				p.Timeout = h.Timeout.String()
+			}
+			switch {
+			case opt.Force:
+				p.State = restartHookForced
+			case spec.IsRestartHookDisabled(opt.DisabledRestartHooks, inst.ComponentName(), h.Name):
+				p.State = restartHookDisabled
+			}
+			plans = append(plans, p)
+		}
+	}
+	return plans, nil
+}
+
+// ShowRestartHooks lists the graceful hooks run around restarting the
+// instances, e.g. evicting the leaders, and their timeouts, nothing is
+// changed on the cluster
+func (m *Manager) ShowRestartHooks(name string, opt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo := metadata.GetTopology()
+	if err := spec.ValidateRestartHooks(opt.DisabledRestartHooks, topo); err != nil {
+		return err
+	}
+
+	instances := filterInstances(topo, opt)
+	if len(instances) == 0 {
+		return perrs.New("no instance matches the roles and nodes specified")
+	}
+	plans, err := restartHookPlans(instances, topo, opt)
+	if err != nil {
+		return err
+	}
+
+	if m.logger.GetDisplayMode() == logprinter.DisplayModeJSON {
+		d, err := json.MarshalIndent(plans, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(m.stdout, string(d))
+		return nil
+	}
+
+	if len(plans) == 0 {
+		m.logger.Infof("No graceful hook is run when restarting the instances")
+		return nil
+	}
+	rows := [][]string{{"ID", "Role", "Host", "Hook", "Action", "Timeout", "State"}}
+	for _, p := range plans {
+		state := p.State
+		if state != restartHookRun {
+			state = color.YellowString(state)
+		}
+		rows = append(rows, []string{p.ID, p.Role, p.Host, p.Hook, p.Action, p.Timeout, state})
+	}
+	fmt.Fprintf(m.stdout, "Cluster: %s\n", color.HiYellowString(name))
+	tui.FprintTable(m.stdout, rows, true)
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRestartHookPlans(t *testing.T) {
+	topo := new(spec.Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+tidb_servers:
+  - host: 172.16.5.140
+`), topo))
+
+	opt := operator.Options{APITimeout: 600, DisabledRestartHooks: []string{"tikv:resign-leader", "tikv:ready"}}
+	instances := filterInstances(topo, opt)
+	plans, err := restartHookPlans(instances, topo, opt)
+	assert.Nil(t, err)
+
+	hooks := make(map[string][]RestartHookPlan)
+	for _, p := range plans {
+		hooks[p.ID] = append(hooks[p.ID], p)
+	}
+	// the leader of the only PD is not transferred
+	assert.Len(t, hooks["172.16.5.140:2379"], 1)
+	assert.Equal(t, spec.RestartHookHealth, hooks["172.16.5.140:2379"][0].Hook)
+	assert.Equal(t, "2m0s", hooks["172.16.5.140:2379"][0].Timeout)
+	// TiDB has no hooks
+	assert.Empty(t, hooks["172.16.5.140:4000"])
+
+	kv := hooks["172.16.5.141:20160"]
+	assert.Len(t, kv, 2)
+	assert.Equal(t, spec.RestartHookResignLeader, kv[0].Hook)
+	assert.Equal(t, "10m0s", kv[0].Timeout)
+	assert.Equal(t, restartHookDisabled, kv[0].State)
+	assert.Equal(t, spec.RestartHookReady, kv[1].Hook)
+	assert.Equal(t, "-", kv[1].Timeout)
+	assert.Equal(t, restartHookDisabled, kv[1].State)
+
+	// all hooks are skipped by force
+	opt.Force = true
+	plans, err = restartHookPlans(instances, topo, opt)
+	assert.Nil(t, err)
+	for _, p := range plans {
+		assert.Equal(t, restartHookForced, p.State)
+	}
+
+	assert.Nil(t, spec.ValidateRestartHooks(opt.DisabledRestartHooks, topo))
+	err = spec.ValidateRestartHooks([]string{spec.RestartHookDrain, "evict"}, topo)
+	assert.True(t, errorx.IsOfType(err, spec.ErrUnknownRestartHook))
+	err = spec.ValidateRestartHooks([]string{"tikvv:drain"}, topo)
+	assert.True(t, errorx.IsOfType(err, spec.ErrUnknownRestartHook))
+
+	// the leaders resigned are never reverted without the ready hook
+	err = spec.ValidateRestartHooks([]string{spec.RestartHookReady}, topo)
+	assert.True(t, errorx.IsOfType(err, spec.ErrInvalidRestartHooks))
+	err = spec.ValidateRestartHooks([]string{spec.RestartHookResignLeader, "tikv:ready"}, topo)
+	assert.Nil(t, err)
+	err = spec.ValidateRestartHooks([]string{"pd:resign-leader", "tikv:ready"}, topo)
+	assert.True(t, errorx.IsOfType(err, spec.ErrInvalidRestartHooks))
+}
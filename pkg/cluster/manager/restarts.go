@@ -0,0 +1,172 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sort"
+	"time"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+const (
+	// the restarts in the window are shown in display
+	restartWindow = 7 * 24 * time.Hour
+	// the events older than the retention are pruned
+	restartRetention = 30 * 24 * time.Hour
+	// the start time derived from the uptime is not precise, the instance is
+	// considered restarted only if it moves later than the slack
+	restartStartSlack = time.Minute
+
+	// the reason of starting the instances, it's not counted as a restart
+	restartReasonStart = "start"
+	// the reason of the restarts not made by tiup, detected by the regression
+	// of the uptime
+	restartReasonDetected = "detected"
+)
+
+// recordRestarts records the instances restarted by the operation in the
+// meta and saves it. It never fails the operation, the errors are only logged.
+func (m *Manager) recordRestarts(name string, metadata spec.Metadata, reason string, gOpt operator.Options) {
+	markRestarts(metadata, reason, gOpt, time.Now())
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		m.logger.Warnf("Failed to record the restarts of cluster %s: %s", name, err)
+	}
+}
+
+// markRestarts records the instances restarted by the operation in the meta
+// without saving it, the instances are filtered by the roles and nodes of gOpt
+func markRestarts(metadata spec.Metadata, reason string, gOpt operator.Options, now time.Time) {
+	history := metadata.GetBaseMeta().Restarts
+	if history == nil {
+		return
+	}
+
+	roles := set.NewStringSet(gOpt.Roles...)
+	nodes := set.NewStringSet(gOpt.Nodes...)
+	var ids []string
+	metadata.GetTopology().IterInstance(func(inst spec.Instance) {
+		if len(roles) > 0 && !roles.Exist(inst.Role()) {
+			return
+		}
+		if len(nodes) > 0 && !nodes.Exist(inst.ID()) {
+			return
+		}
+		ids = append(ids, inst.ID())
+	})
+	addRestartEvents(history, ids, reason, now)
+}
+
+// restartHistoryOf returns the history of the instance, it's added if absent
+func restartHistoryOf(history *[]spec.RestartHistory, id string) *spec.RestartHistory {
+	for i := range *history {
+		if (*history)[i].Node == id {
+			return &(*history)[i]
+		}
+	}
+	*history = append(*history, spec.RestartHistory{Node: id})
+	return &(*history)[len(*history)-1]
+}
+
+// addRestartEvents appends an event of the reason to the history of each
+// instance in ids. Starting the instances is not a restart, only their start
+// time is moved so that it's not detected as one.
+func addRestartEvents(history *[]spec.RestartHistory, ids []string, reason string, now time.Time) {
+	for _, id := range ids {
+		h := restartHistoryOf(history, id)
+		if reason == restartReasonStart {
+			h.Started = now
+			continue
+		}
+		h.Events = append(h.Events, spec.RestartEvent{Time: now, Reason: reason})
+	}
+}
+
+// detectRestarts compares the start time of the instances derived from their
+// uptime with the ones seen last time, a restart is recorded if the instance
+// started later and no operation restarted it in between. The events out of
+// the retention are pruned, and the instances not in uptimes are kept as is.
+func detectRestarts(history *[]spec.RestartHistory, uptimes map[string]time.Duration, now time.Time) {
+	for id, uptime := range uptimes {
+		if uptime <= 0 {
+			continue
+		}
+		started := now.Add(-uptime)
+		h := restartHistoryOf(history, id)
+		if !h.Started.IsZero() && started.After(h.Started.Add(restartStartSlack)) {
+			explained := false
+			for _, e := range h.Events {
+				if e.Time.After(h.Started) && !e.Time.After(started.Add(restartStartSlack)) {
+					explained = true
+					break
+				}
+			}
+			if !explained {
+				h.Events = append(h.Events, spec.RestartEvent{Time: started, Reason: restartReasonDetected})
+			}
+		}
+		// only move forward, the uptime reported may jitter a little
+		if started.After(h.Started) {
+			h.Started = started
+		}
+	}
+
+	for i := range *history {
+		h := &(*history)[i]
+		events := h.Events[:0]
+		for _, e := range h.Events {
+			if now.Sub(e.Time) <= restartRetention {
+				events = append(events, e)
+			}
+		}
+		h.Events = events
+	}
+	sort.Slice(*history, func(i, j int) bool {
+		return (*history)[i].Node < (*history)[j].Node
+	})
+}
+
+// countRestarts returns the count of restarts of the instance in the window
+// before now
+func countRestarts(history []spec.RestartHistory, id string, window time.Duration, now time.Time) int {
+	count := 0
+	for _, h := range history {
+		if h.Node != id {
+			continue
+		}
+		for _, e := range h.Events {
+			if now.Sub(e.Time) <= window {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// updateRestarts detects the restarts of the instances from their uptime in
+// history and fills the count of restarts in restartWindow
+func updateRestarts(history *[]spec.RestartHistory, infos []InstInfo, now time.Time) {
+	uptimes := make(map[string]time.Duration)
+	for _, info := range infos {
+		uptimes[info.ID] = info.uptime
+	}
+	detectRestarts(history, uptimes, now)
+
+	for i := range infos {
+		count := countRestarts(*history, infos[i].ID, restartWindow, now)
+		infos[i].Restarts = &count
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRestarts(t *testing.T) {
+	now := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+	var history []spec.RestartHistory
+	historyOf := func(id string) *spec.RestartHistory {
+		for i := range history {
+			if history[i].Node == id {
+				return &history[i]
+			}
+		}
+		return nil
+	}
+
+	// the first seen start time is recorded without events
+	detectRestarts(&history, map[string]time.Duration{"a:1": 48 * time.Hour, "b:1": 0}, now)
+	assert.Equal(t, now.Add(-48*time.Hour), historyOf("a:1").Started)
+	assert.Empty(t, historyOf("a:1").Events)
+	assert.Nil(t, historyOf("b:1"))
+
+	// the same start time with a little jitter
+	now = now.Add(time.Hour)
+	detectRestarts(&history, map[string]time.Duration{"a:1": 49*time.Hour + 10*time.Second}, now)
+	assert.Empty(t, historyOf("a:1").Events)
+
+	// restarted outside of tiup
+	now = now.Add(time.Hour)
+	detectRestarts(&history, map[string]time.Duration{"a:1": 10 * time.Minute}, now)
+	assert.Len(t, historyOf("a:1").Events, 1)
+	assert.Equal(t, restartReasonDetected, historyOf("a:1").Events[0].Reason)
+	assert.Equal(t, now.Add(-10*time.Minute), historyOf("a:1").Started)
+
+	// restarted by an operation
+	now = now.Add(time.Hour)
+	addRestartEvents(&history, []string{"a:1"}, "restart", now)
+	now = now.Add(time.Minute)
+	detectRestarts(&history, map[string]time.Duration{"a:1": 50 * time.Second}, now)
+	assert.Len(t, historyOf("a:1").Events, 2)
+	assert.Equal(t, "restart", historyOf("a:1").Events[1].Reason)
+
+	// started after stopped, it's neither counted nor detected as a restart
+	now = now.Add(time.Hour)
+	addRestartEvents(&history, []string{"a:1", "c:1"}, restartReasonStart, now)
+	assert.Equal(t, now, historyOf("c:1").Started)
+	now = now.Add(time.Minute)
+	detectRestarts(&history, map[string]time.Duration{"a:1": 55 * time.Second, "c:1": 55 * time.Second}, now)
+	assert.Len(t, historyOf("a:1").Events, 2)
+	assert.Empty(t, historyOf("c:1").Events)
+
+	// the events out of the window are not counted, and the ones out of the
+	// retention are pruned
+	assert.Equal(t, 2, countRestarts(history, "a:1", restartWindow, now))
+	assert.Equal(t, 1, countRestarts(history, "a:1", restartWindow, now.Add(restartWindow-90*time.Minute)))
+	assert.Equal(t, 0, countRestarts(history, "d:1", restartWindow, now))
+	detectRestarts(&history, nil, now.Add(restartRetention-90*time.Minute))
+	assert.Len(t, historyOf("a:1").Events, 1)
+}
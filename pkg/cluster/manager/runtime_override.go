@@ -0,0 +1,164 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// SetRuntimeOverride overrides the command line flags of the instance
+// temporarily and reloads it to apply the flags. The override is removed by
+// the first reload after it expires, it never expires if ttl is 0.
+func (m *Manager) SetRuntimeOverride(name, node string, flags []string, ttl time.Duration, gOpt operator.Options, skipConfirm bool) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	if len(flags) == 0 {
+		return perrs.New("at least one flag must be specified")
+	}
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	metadata.GetTopology().IterInstance(func(inst spec.Instance) {
+		if inst.ID() == node {
+			found = true
+		}
+	})
+	if !found {
+		return perrs.Errorf("instance %s not found in the cluster", node)
+	}
+
+	override := spec.RuntimeOverride{Node: node, Flags: flags}
+	if ttl > 0 {
+		override.Expire = time.Now().Add(ttl).Truncate(time.Second)
+	}
+	if err := metadata.GetBaseMeta().SetRuntimeOverride(override); err != nil {
+		return err
+	}
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+
+	gOpt.Nodes = []string{node}
+	gOpt.Roles = nil
+	return m.Reload(name, gOpt, false, skipConfirm)
+}
+
+// ClearRuntimeOverrides removes the runtime overrides of the nodes, or all
+// overrides if no node is specified, and reloads the affected instances.
+func (m *Manager) ClearRuntimeOverrides(name string, nodes []string, gOpt operator.Options, skipConfirm bool) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+
+	filter := set.NewStringSet(nodes...)
+	removed := metadata.GetBaseMeta().RemoveRuntimeOverrides(func(o *spec.RuntimeOverride) bool {
+		return len(filter) == 0 || filter.Exist(o.Node)
+	})
+	if len(removed) == 0 {
+		m.logger.Infof("No runtime override to clear")
+		return nil
+	}
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+
+	affected := set.NewStringSet()
+	for _, o := range removed {
+		affected.Insert(o.Node)
+	}
+	gOpt.Nodes = affected.Slice()
+	sort.Strings(gOpt.Nodes)
+	gOpt.Roles = nil
+	m.logger.Infof("Runtime overrides of %s are cleared", color.YellowString(strings.Join(gOpt.Nodes, ",")))
+	return m.Reload(name, gOpt, false, skipConfirm)
+}
+
+// ListRuntimeOverrides prints the runtime overrides of the cluster
+func (m *Manager) ListRuntimeOverrides(name string) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+
+	base := metadata.GetBaseMeta()
+	if base.RuntimeOverrides == nil || len(*base.RuntimeOverrides) == 0 {
+		m.logger.Infof("No runtime override")
+		return nil
+	}
+
+	now := time.Now()
+	rows := [][]string{{"Node", "Flags", "Expire", "Status"}}
+	for _, o := range *base.RuntimeOverrides {
+		expire, status := "-", "active"
+		if !o.Expire.IsZero() {
+			expire = o.Expire.Format(time.RFC3339)
+		}
+		if o.Expired(now) {
+			status = color.YellowString("expired, removed on next reload")
+		}
+		rows = append(rows, []string{o.Node, strings.Join(o.Flags, " "), expire, status})
+	}
+	tui.FprintTable(m.stdout, rows, true)
+	return nil
+}
+
+// pruneRuntimeOverrides removes the expired runtime overrides before the
+// configs are refreshed, so the flags are dropped from the run scripts.
+func (m *Manager) pruneRuntimeOverrides(name string, metadata spec.Metadata) error {
+	now := time.Now()
+	removed := metadata.GetBaseMeta().RemoveRuntimeOverrides(func(o *spec.RuntimeOverride) bool {
+		return o.Expired(now)
+	})
+	if len(removed) == 0 {
+		return nil
+	}
+	for _, o := range removed {
+		m.logger.Infof("Runtime override of %s expired, removing flags: %s", o.Node, strings.Join(o.Flags, " "))
+	}
+	return m.specManager.SaveMeta(name, metadata)
+}
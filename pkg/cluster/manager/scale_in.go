@@ -18,11 +18,15 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -49,6 +53,12 @@ func (m *Manager) ScaleIn(
 		return err
 	}
 
+	release, pause, err := m.lockPausableOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	var (
 		force bool     = gOpt.Force
 		nodes []string = gOpt.Nodes
@@ -68,36 +78,49 @@ func (m *Manager) ScaleIn(
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
 
-	if !skipConfirm {
-		if force {
-			m.logger.Warnf(color.HiRedString(tui.ASCIIArtWarning))
-			if err := tui.PromptForAnswerOrAbortError(
-				"Yes, I know my data might be lost.",
-				color.HiRedString("Forcing scale in is unsafe and may result in data loss for stateful components.\n"+
-					"DO NOT use `--force` if you have any component in ")+
-					color.YellowString("Pending Offline")+color.HiRedString(" status.\n")+
-					color.HiRedString("The process is irreversible and could NOT be cancelled.\n")+
-					"Only use `--force` when some of the servers are already permanently offline.\n"+
-					"Are you sure to continue?",
-			); err != nil {
-				return err
-			}
+	if force && !skipConfirm {
+		m.logger.Warnf(color.HiRedString(tui.ASCIIArtWarning))
+		if err := m.prompter.PromptForAnswerOrAbortError(
+			"Yes, I know my data might be lost.",
+			color.HiRedString("Forcing scale in is unsafe and may result in data loss for stateful components.\n"+
+				"DO NOT use `--force` if you have any component in ")+
+				color.YellowString("Pending Offline")+color.HiRedString(" status.\n")+
+				color.HiRedString("The process is irreversible and could NOT be cancelled.\n")+
+				"Only use `--force` when some of the servers are already permanently offline.\n"+
+				"Are you sure to continue?",
+		); err != nil {
+			return err
+		}
+	}
+	// the regions lost are always reported, even if not confirmed
+	if force {
+		if err := m.confirmForceScaleIn(name, topo, nodes, gOpt, skipConfirm); err != nil {
+			return err
 		}
+	}
 
-		if err := tui.PromptForConfirmOrAbortError(
+	if !skipConfirm {
+		if err := m.prompter.PromptForConfirmOrAbortError(
 			"This operation will delete the %s nodes in `%s` and all their data.\nDo you want to continue? [y/N]:",
 			strings.Join(nodes, ","),
 			color.HiYellowString(name)); err != nil {
 			return err
 		}
 
-		if err := checkAsyncComps(topo, nodes); err != nil {
+		if err := m.checkAsyncComps(topo, nodes); err != nil {
 			return err
 		}
 
 		m.logger.Infof("Scale-in nodes...")
 	}
 
+	remaining := readOnlyZonesAfterScaleIn(topo, nodes)
+	if remaining != nil && !force {
+		if err := remaining.ValidateReadOnlyZones(); err != nil {
+			return err
+		}
+	}
+
 	// Regenerate configuration
 	gOpt.IgnoreConfigCheck = true
 	regenConfigTasks, hasImported := buildInitConfigTasks(m, name, topo, base, gOpt, nodes)
@@ -114,21 +137,44 @@ func (m *Manager) ScaleIn(
 		return err
 	}
 
+	// the entries of the removed nodes are dropped from the remaining hosts
+	etcHosts, err := spec.EtcHostsEntries(topo, nodes...)
+	if err != nil {
+		return err
+	}
+	remainingHosts := set.NewStringSet()
+	deletedNodes := set.NewStringSet(nodes...)
+	topo.IterInstance(func(inst spec.Instance) {
+		if !deletedNodes.Exist(inst.ID()) {
+			remainingHosts.Insert(inst.GetHost())
+		}
+	})
+	for _, h := range spec.MonitoredHosts(topo) {
+		remainingHosts.Insert(h.Host)
+	}
+
 	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
 	if err != nil {
 		return err
 	}
 
+	if remaining != nil {
+		b.Func("EnsureReadOnlyZoneRules", func(ctx context.Context) error {
+			m.ensureReadOnlyZoneRules(ctx, remaining, tlsCfg, gOpt)
+			return nil
+		})
+	}
 	scale(b, metadata, tlsCfg)
 
 	t := b.
+		ParallelStep("+ Update /etc/hosts", force, buildEtcHostsTasks(m, name, remainingHosts.Slice(), etcHosts)...).
 		ParallelStep("+ Refresh instance configs", force, regenConfigTasks...).
 		ParallelStep("+ Reload prometheus and grafana", gOpt.Force,
 			buildReloadPromAndGrafanaTasks(metadata.GetTopology(), m.logger, gOpt, nodes...)...).
 		Build()
 
 	ctx := ctxt.New(
-		context.Background(),
+		operator.WithLockPauser(m.baseContext(name, gOpt), pause),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -146,7 +192,7 @@ func (m *Manager) ScaleIn(
 }
 
 // checkAsyncComps
-func checkAsyncComps(topo spec.Topology, nodes []string) error {
+func (m *Manager) checkAsyncComps(topo spec.Topology, nodes []string) error {
 	var asyncOfflineComps = set.NewStringSet(spec.ComponentPump, spec.ComponentTiKV, spec.ComponentTiFlash, spec.ComponentDrainer)
 	deletedNodes := set.NewStringSet(nodes...)
 	delAsyncOfflineComps := set.NewStringSet()
@@ -159,10 +205,193 @@ func checkAsyncComps(topo spec.Topology, nodes []string) error {
 	})
 
 	if len(delAsyncOfflineComps.Slice()) > 0 {
-		return tui.PromptForConfirmOrAbortError(fmt.Sprintf(
+		return m.prompter.PromptForConfirmOrAbortError(fmt.Sprintf(
 			"%s\nDo you want to continue? [y/N]:", color.YellowString(
 				"The component `%s` will become tombstone, maybe exists in several minutes or hours, after that you can use the prune command to clean it",
 				delAsyncOfflineComps.Slice())))
 	}
 	return nil
 }
+
+// storeSafetyReport is the number of regions on a store that would be
+// affected if the store is removed forcibly
+type storeSafetyReport struct {
+	ID         string // the id of the instance
+	StoreID    uint64
+	Regions    int // regions having a peer on the store
+	LoseQuorum int // regions whose healthy voters would drop below quorum
+	LoseAll    int // regions whose all replicas would be removed
+}
+
+// regionSafety checks if the region would lose quorum or all its replicas
+// after the removed stores are gone, the peers on unavailable stores and the
+// down peers are not counted as healthy.
+func regionSafety(region *api.RegionInfo, removed, unavailable map[uint64]bool) (loseQuorum, loseAll bool) {
+	down := make(map[uint64]bool)
+	for _, p := range region.DownPeers {
+		if p.Peer != nil {
+			down[p.Peer.Id] = true
+		}
+	}
+
+	voters, healthyVoters, remaining := 0, 0, 0
+	for _, p := range region.Peers {
+		if !removed[p.StoreId] {
+			remaining++
+		}
+		if p.Role == metapb.PeerRole_Learner {
+			continue
+		}
+		voters++
+		if !removed[p.StoreId] && !unavailable[p.StoreId] && !down[p.Id] {
+			healthyVoters++
+		}
+	}
+
+	return voters > 0 && healthyVoters < voters/2+1, remaining == 0
+}
+
+// regionLoss is the count of distinct regions that would lose quorum or all
+// their replicas after the stores are removed
+type regionLoss struct {
+	LoseQuorum int
+	LoseAll    int
+}
+
+// countRegionSafety fills the reports with the safety of the regions on each
+// store, and counts the distinct regions affected, as a region may have
+// peers on more than one of the stores removed.
+func countRegionSafety(reports []storeSafetyReport, regions map[uint64][]*api.RegionInfo, removed, unavailable map[uint64]bool) regionLoss {
+	loseQuorum := make(map[uint64]bool)
+	loseAll := make(map[uint64]bool)
+	for i := range reports {
+		reports[i].Regions = len(regions[reports[i].StoreID])
+		for _, region := range regions[reports[i].StoreID] {
+			quorum, all := regionSafety(region, removed, unavailable)
+			if quorum {
+				reports[i].LoseQuorum++
+				loseQuorum[region.ID] = true
+			}
+			if all {
+				reports[i].LoseAll++
+				loseAll[region.ID] = true
+			}
+		}
+	}
+	return regionLoss{LoseQuorum: len(loseQuorum), LoseAll: len(loseAll)}
+}
+
+// forceScaleInReport reports the regions on the TiKV and TiFlash nodes to be
+// scaled in forcibly, whose replicas would drop below quorum.
+func (m *Manager) forceScaleInReport(name string, topo *spec.Specification, nodes []string, gOpt operator.Options) ([]storeSafetyReport, regionLoss, error) {
+	deleted := set.NewStringSet(nodes...)
+	addrs := make(map[string]string) // store address -> instance id
+	topo.IterInstance(func(inst spec.Instance) {
+		if !deleted.Exist(inst.ID()) {
+			return
+		}
+		switch inst.ComponentName() {
+		case spec.ComponentTiKV:
+			addrs[inst.ID()] = inst.ID()
+		case spec.ComponentTiFlash:
+			addr := inst.GetHost() + ":" + strconv.Itoa(inst.(*spec.TiFlashInstance).GetServicePort())
+			addrs[addr] = inst.ID()
+		}
+	})
+	if len(addrs) == 0 {
+		return nil, regionLoss{}, nil
+	}
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return nil, regionLoss{}, err
+	}
+	// the transfer timeout is too long for querying a PD which may be down
+	ctx := m.apiContext(name, gOpt)
+	pdClient := api.NewPDClient(ctx, topo.GetPDList(), api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
+
+	stores, err := pdClient.GetStores(ctx)
+	if err != nil {
+		return nil, regionLoss{}, err
+	}
+	removed := make(map[uint64]bool)
+	unavailable := make(map[uint64]bool)
+	var reports []storeSafetyReport
+	for _, store := range stores.Stores {
+		if store.Store.State == metapb.StoreState_Tombstone {
+			continue
+		}
+		if id, ok := addrs[store.Store.Address]; ok {
+			removed[store.Store.Id] = true
+			reports = append(reports, storeSafetyReport{ID: id, StoreID: store.Store.Id})
+		}
+		if store.Store.StateName == "Down" || store.Store.StateName == "Disconnected" {
+			unavailable[store.Store.Id] = true
+		}
+	}
+
+	regions := make(map[uint64][]*api.RegionInfo)
+	for _, r := range reports {
+		info, err := pdClient.GetStoreRegions(ctx, r.StoreID)
+		if err != nil {
+			return nil, regionLoss{}, err
+		}
+		regions[r.StoreID] = info.Regions
+	}
+	return reports, countRegionSafety(reports, regions, removed, unavailable), nil
+}
+
+// confirmForceScaleIn shows the regions that would lose quorum or replicas if
+// the nodes are scaled in forcibly, and asks for an extra confirmation unless
+// skipConfirm, in which case the regions lost are only warned.
+func (m *Manager) confirmForceScaleIn(name string, topo spec.Topology, nodes []string, gOpt operator.Options, skipConfirm bool) error {
+	t, ok := topo.(*spec.Specification)
+	if !ok {
+		return nil
+	}
+
+	reports, loss, err := m.forceScaleInReport(name, t, nodes, gOpt)
+	if err != nil {
+		// the PD may be unavailable, which is one of the reasons to use --force
+		m.logger.Warnf("Failed to get the region distribution from PD: %s", err)
+		if skipConfirm {
+			return nil
+		}
+		return m.prompter.PromptForAnswerOrAbortError(
+			"Yes, I know the regions on the nodes are unknown.",
+			color.HiRedString("The data safety of the nodes could not be checked.\n")+"Are you sure to continue?",
+		)
+	}
+	if len(reports) == 0 {
+		return nil
+	}
+
+	table := [][]string{{"ID", "Store", "Regions", "Lose Quorum", "Lose All Replicas"}}
+	for _, r := range reports {
+		table = append(table, []string{
+			r.ID,
+			strconv.FormatUint(r.StoreID, 10),
+			strconv.Itoa(r.Regions),
+			strconv.Itoa(r.LoseQuorum),
+			strconv.Itoa(r.LoseAll),
+		})
+	}
+	fmt.Fprintln(m.stdout, "Regions on the stores to be removed forcibly:")
+	tui.FprintTable(m.stdout, table, true)
+
+	if loss.LoseQuorum == 0 && loss.LoseAll == 0 {
+		m.logger.Infof("No region would drop below quorum after the stores are removed.")
+		return nil
+	}
+	if skipConfirm {
+		m.logger.Warnf("%d regions will drop below quorum and %d regions will lose all replicas, they need to be recovered manually with unsafe recovery after the scale-in", loss.LoseQuorum, loss.LoseAll)
+		return nil
+	}
+
+	return m.prompter.PromptForAnswerOrAbortError(
+		fmt.Sprintf("Yes, %d regions will lose quorum.", loss.LoseQuorum),
+		color.HiRedString("%d regions would drop below quorum and %d regions would lose all replicas.\n", loss.LoseQuorum, loss.LoseAll)+
+			"They need to be recovered manually with unsafe recovery after the scale-in.\n"+
+			"Are you sure to continue?",
+	)
+}
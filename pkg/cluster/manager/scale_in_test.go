@@ -0,0 +1,125 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionSafety(t *testing.T) {
+	region := &api.RegionInfo{
+		ID: 1,
+		Peers: []*metapb.Peer{
+			{Id: 11, StoreId: 1},
+			{Id: 12, StoreId: 2},
+			{Id: 13, StoreId: 3},
+			{Id: 14, StoreId: 4, Role: metapb.PeerRole_Learner},
+		},
+	}
+
+	// one of three voters removed
+	loseQuorum, loseAll := regionSafety(region, map[uint64]bool{1: true}, nil)
+	assert.False(t, loseQuorum)
+	assert.False(t, loseAll)
+
+	// one removed and one on an unavailable store
+	loseQuorum, loseAll = regionSafety(region, map[uint64]bool{1: true}, map[uint64]bool{2: true})
+	assert.True(t, loseQuorum)
+	assert.False(t, loseAll)
+
+	// one removed and one down peer
+	region.DownPeers = []*pdpb.PeerStats{{Peer: &metapb.Peer{Id: 13, StoreId: 3}}}
+	loseQuorum, loseAll = regionSafety(region, map[uint64]bool{1: true}, nil)
+	assert.True(t, loseQuorum)
+	assert.False(t, loseAll)
+
+	// all replicas removed
+	loseQuorum, loseAll = regionSafety(region, map[uint64]bool{1: true, 2: true, 3: true, 4: true}, nil)
+	assert.True(t, loseQuorum)
+	assert.True(t, loseAll)
+}
+
+func TestCountRegionSafety(t *testing.T) {
+	peers := func(stores ...uint64) []*metapb.Peer {
+		var ps []*metapb.Peer
+		for _, s := range stores {
+			ps = append(ps, &metapb.Peer{Id: s*10 + 1, StoreId: s})
+		}
+		return ps
+	}
+	// region 1 has peers on both stores removed, region 2 only on store 1
+	r1 := &api.RegionInfo{ID: 1, Peers: peers(1, 2, 3)}
+	r2 := &api.RegionInfo{ID: 2, Peers: peers(1, 3, 4)}
+	r3 := &api.RegionInfo{ID: 3, Peers: peers(1, 2)}
+	regions := map[uint64][]*api.RegionInfo{
+		1: {r1, r2, r3},
+		2: {r1, r3},
+	}
+	reports := []storeSafetyReport{{ID: "tikv1", StoreID: 1}, {ID: "tikv2", StoreID: 2}}
+
+	loss := countRegionSafety(reports, regions, map[uint64]bool{1: true, 2: true}, nil)
+	assert.Equal(t, []storeSafetyReport{
+		{ID: "tikv1", StoreID: 1, Regions: 3, LoseQuorum: 2, LoseAll: 1},
+		{ID: "tikv2", StoreID: 2, Regions: 2, LoseQuorum: 2, LoseAll: 1},
+	}, reports)
+	// the regions on both stores are counted once
+	assert.Equal(t, regionLoss{LoseQuorum: 2, LoseAll: 1}, loss)
+}
+
+func TestLockPausableOperation(t *testing.T) {
+	m := NewManager("tidb", spec.NewSpec(t.TempDir(), func() spec.Metadata { return &spec.ClusterMeta{} }), nil, logprinter.NewLogger(""))
+	meta := &spec.ClusterMeta{User: "tidb", Version: "v6.1.0", Topology: new(spec.Specification)}
+	require.Nil(t, m.specManager.SaveMeta("test", meta))
+
+	release, pause, err := m.lockPausableOperation("test")
+	require.Nil(t, err)
+	require.NotNil(t, pause)
+
+	// the nested operations share the lock and can't pause it
+	nestedRelease, nestedPause, err := m.lockPausableOperation("test")
+	assert.Nil(t, err)
+	assert.Nil(t, nestedPause)
+	nestedRelease()
+
+	// the others operate on the cluster while it's paused
+	resume, err := pause()
+	require.Nil(t, err)
+	other, err := m.lockOperation("test")
+	require.Nil(t, err)
+	other()
+	assert.Nil(t, resume())
+	running, err := m.specManager.IsOperationRunning("test")
+	assert.Nil(t, err)
+	assert.True(t, running)
+
+	// the meta is changed while it's paused
+	resume, err = pause()
+	require.Nil(t, err)
+	meta.Version = "v6.5.0"
+	require.Nil(t, m.specManager.SaveMeta("test", meta))
+	assert.ErrorContains(t, resume(), "was changed by another operation")
+
+	release()
+	running, err = m.specManager.IsOperationRunning("test")
+	assert.Nil(t, err)
+	assert.False(t, running)
+}
@@ -17,6 +17,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -25,7 +26,6 @@ import (
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
@@ -56,6 +56,12 @@ func (m *Manager) ScaleOut(
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	// allow specific validation errors so that user can recover a broken
 	// cluster if it is somehow in a bad state.
@@ -78,16 +84,15 @@ func (m *Manager) ScaleOut(
 			return err
 		}
 	} else { // if stage2 is true, not need check topology or other
-		// check for the input topology to let user confirm if there're any
-		// global configs set
-		if err := checkForGlobalConfigs(m.logger, topoFile, skipConfirm); err != nil {
+		// reject the input topology if it changes any global configs
+		if err := checkForGlobalConfigs(m.logger, topo, topoFile); err != nil {
 			return err
 		}
 
 		// The no tispark master error is ignored, as if the tispark master is removed from the topology
 		// file for some reason (manual edit, for example), it is still possible to scale-out it to make
 		// the whole topology back to normal state.
-		if err := spec.ParseTopologyYaml(topoFile, newPart, true); err != nil &&
+		if err := spec.ParseScaleOutTopologyYaml(topoFile, topo, newPart, opt.Inherit); err != nil &&
 			!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
 			return err
 		}
@@ -109,7 +114,7 @@ func (m *Manager) ScaleOut(
 		sshConnProps  *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 	)
-	if gOpt.SSHType != executor.SSHTypeNone {
+	if gOpt.SSHType.UseSSH() {
 		var err error
 		if sshConnProps, err = tui.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword); err != nil {
 			return err
@@ -125,31 +130,48 @@ func (m *Manager) ScaleOut(
 		return err
 	}
 
+	if err := m.checkHostRequirements(sshConnProps, sshProxyProps, newPart, base.Version, &gOpt, opt.User); err != nil {
+		return err
+	}
+
+	if !opt.IgnoreFsCheck && !opt.Stage2 {
+		if err := m.checkDataFilesystems(sshConnProps, sshProxyProps, newPart, topo, &gOpt, opt.User); err != nil {
+			return err
+		}
+	}
+
+	// the labels are inferred in stage1 and saved with the new part
+	if newPartTopo, ok := newPart.(*spec.Specification); ok && opt.CloudLabels != "" && !opt.Stage2 {
+		lbs, _, err := m.pdLocationLabels(name, topo, gOpt)
+		if err != nil {
+			return err
+		}
+		if err := m.inferCloudLabels(sshConnProps, sshProxyProps, newPartTopo, lbs, opt.CloudLabels, &gOpt, opt.User); err != nil {
+			return err
+		}
+	}
+
 	var mergedTopo spec.Topology
 	// in satge2, not need mergedTopo
 	if opt.Stage2 {
 		mergedTopo = topo
 	} else {
 		// Abort scale out operation if the merged topology is invalid
-		mergedTopo = topo.MergeTopo(newPart)
+		if mergedTopo, err = spec.MergeTopology(topo, newPart); err != nil {
+			return err
+		}
 		if err := mergedTopo.Validate(); err != nil {
 			return err
 		}
 		spec.ExpandRelativeDir(mergedTopo)
 
 		if topo, ok := mergedTopo.(*spec.Specification); ok {
+			if err := topo.ValidateReadOnlyZones(); err != nil {
+				return err
+			}
 			// Check if TiKV's label set correctly
 			if !opt.NoLabels {
-				pdList := topo.BaseTopo().MasterList
-				tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
-				if err != nil {
-					return err
-				}
-				pdClient := api.NewPDClient(
-					context.WithValue(context.TODO(), logprinter.ContextKeyLogger, m.logger),
-					pdList, 10*time.Second, tlsCfg,
-				)
-				lbs, placementRule, err := pdClient.GetLocationLabels()
+				lbs, placementRule, err := m.pdLocationLabels(name, topo, gOpt)
 				if err != nil {
 					return err
 				}
@@ -175,6 +197,16 @@ func (m *Manager) ScaleOut(
 		}
 	})
 
+	// show the resolved spec of the new instances, as the fields inherited
+	// from the existing instances are not shown in the topology table
+	if opt.Inherit && !opt.Stage2 && !skipConfirm {
+		data, err := spec.MarshalInstances(newPart)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(m.stdout, "Resolved spec of the new instances:\n%s\n", data)
+	}
+
 	if !skipConfirm {
 		// patchedComponents are components that have been patched and overwrited
 		if err := m.confirmTopology(name, base.Version, newPart, patchedComponents); err != nil {
@@ -191,7 +223,7 @@ func (m *Manager) ScaleOut(
 	}
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -204,6 +236,15 @@ func (m *Manager) ScaleOut(
 		return perrs.Trace(err)
 	}
 
+	// the learners of the read-only zones may be counted by the instances
+	if cluster, ok := mergedTopo.(*spec.Specification); ok && !opt.Stage1 && len(cluster.GlobalOptions.ReadOnlyZones) > 0 {
+		tlsCfg, err := cluster.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+		if err != nil {
+			return err
+		}
+		m.ensureReadOnlyZoneRules(ctx, cluster, tlsCfg, gOpt)
+	}
+
 	if opt.Stage1 {
 		m.logger.Infof(`The new instance is not started!
 You need to execute '%s' to start the new instance.`, color.YellowString("tiup cluster scale-out %s --stage2", name))
@@ -229,35 +270,39 @@ func validateNewTopo(topo spec.Topology) (err error) {
 	return err
 }
 
-// checkForGlobalConfigs checks the input scale out topology to make sure users are aware
-// of the global config fields in it will be ignored.
-func checkForGlobalConfigs(logger *logprinter.Logger, topoFile string, skipConfirm bool) error {
+// checkForGlobalConfigs checks the input scale out topology to make sure it
+// doesn't change the global configs of the cluster, the global configs in the
+// input topology are ignored if they are the same as the existing ones
+func checkForGlobalConfigs(logger *logprinter.Logger, topo spec.Topology, topoFile string) error {
 	yamlFile, err := spec.ReadYamlFile(topoFile)
 	if err != nil {
 		return err
 	}
 
+	changes, err := spec.GlobalConfigChanges(topo, yamlFile)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return spec.ErrTopologyGlobalChanged.
+			New("The scale out topology changes the global configs of the cluster:\n  %s", strings.Join(changes, "\n  ")).
+			WithProperty(tui.SuggestionFromFormat(`The %s fields are shared by all instances and can't be changed by scaling out.
+Please remove them from the scale out topology, set the configs in the specification fields
+for each host, or change them with '%s' before scaling out.`,
+				color.YellowString(`["global", "monitored", "server_configs"]`),
+				color.YellowString("%s edit-config", tui.OsArgs0())))
+	}
+
 	var newPart map[string]interface{}
 	if err := yaml.Unmarshal(yamlFile, &newPart); err != nil {
 		return err
 	}
-
-	// user confirmed, skip checks
-
 	for k := range newPart {
 		switch k {
 		case "global",
 			"monitored",
 			"server_configs":
-			logger.Warnf(`You have one or more of %s fields configured in
-	the scale out topology, but they will be ignored during the scaling out process.
-	If you want to use configs different from the existing cluster, cancel now and
-	set them in the specification fileds for each host.`, color.YellowString(`["global", "monitored", "server_configs"]`))
-			if !skipConfirm {
-				if err := tui.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: "); err != nil {
-					return err
-				}
-			}
+			logger.Infof("The global configs in the scale out topology are the same as the existing ones, they are ignored")
 			return nil
 		}
 	}
@@ -282,7 +327,7 @@ func checkScaleOutLock(m *Manager, name string, opt DeployOptions, skipConfirm b
 			color.YellowString("--stage1"),
 			color.YellowString("tiup cluster scale-out %s --stage2", name))
 		if !skipConfirm {
-			if err := tui.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: "); err != nil {
+			if err := m.prompter.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: "); err != nil {
 				return err
 			}
 		}
@@ -295,7 +340,7 @@ func checkScaleOutLock(m *Manager, name string, opt DeployOptions, skipConfirm b
 
 		m.logger.Warnf(`The parameter '%s' is set, only start the new instances and reload configs.`, color.YellowString("--stage2"))
 		if !skipConfirm {
-			if err := tui.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: "); err != nil {
+			if err := m.prompter.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: "); err != nil {
 				return err
 			}
 		}
@@ -303,3 +348,15 @@ func checkScaleOutLock(m *Manager, name string, opt DeployOptions, skipConfirm b
 
 	return nil
 }
+
+// pdLocationLabels returns the location labels of the running cluster from
+// PD, and if the placement rules are enabled
+func (m *Manager) pdLocationLabels(name string, topo spec.Topology, gOpt operator.Options) ([]string, bool, error) {
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return nil, false, err
+	}
+	apiCtx := m.apiContext(name, gOpt)
+	pdClient := api.NewPDClient(apiCtx, topo.BaseTopo().MasterList, api.RequestTimeout(apiCtx, 10*time.Second), tlsCfg)
+	return pdClient.GetLocationLabels(apiCtx)
+}
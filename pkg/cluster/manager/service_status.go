@@ -0,0 +1,311 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// ServiceStatus is the state of the systemd unit of an instance
+type ServiceStatus struct {
+	ID            string `json:"id"`
+	Role          string `json:"role"`
+	Host          string `json:"host"`
+	Unit          string `json:"unit"`
+	LoadState     string `json:"load_state,omitempty"`
+	ActiveState   string `json:"active_state,omitempty"`
+	SubState      string `json:"sub_state,omitempty"`
+	UnitFileState string `json:"unit_file_state,omitempty"`
+	// LastExitCode is the exit code of the last main process
+	LastExitCode string `json:"last_exit_code,omitempty"`
+	// Restarts is the count of automatic restarts, it's empty if it's not
+	// supported by the systemd on the host
+	Restarts string `json:"restarts,omitempty"`
+	// Drifted is whether the unit file differs from the one rendered from
+	// current topology, nil if it's not checked
+	Drifted *bool  `json:"drifted,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	checksum string
+	expected string
+}
+
+// Problems returns the problems of the unit
+func (s *ServiceStatus) Problems() []string {
+	if s.Error != "" {
+		return []string{s.Error}
+	}
+	var problems []string
+	if s.LoadState != "" && s.LoadState != "loaded" {
+		problems = append(problems, "unit "+s.LoadState)
+	}
+	if s.UnitFileState != "" && s.UnitFileState != "enabled" {
+		problems = append(problems, s.UnitFileState)
+	}
+	if s.Drifted != nil && *s.Drifted {
+		problems = append(problems, "drifted")
+	}
+	return problems
+}
+
+// serviceStatusScript prints the properties of the units and the checksums of
+// their unit files, the records of the units are started by the Unit lines
+func serviceStatusScript(units []string) string {
+	return fmt.Sprintf(`for u in %s; do echo "Unit=$u"; `+
+		`systemctl show -p LoadState -p ActiveState -p SubState -p UnitFileState -p ExecMainStatus -p NRestarts "$u"; `+
+		`echo "Checksum=$(sha256sum "/etc/systemd/system/$u" 2>/dev/null | cut -d' ' -f1)"; done`,
+		strings.Join(units, " "))
+}
+
+// parseServiceStatus parses the output of serviceStatusScript into the
+// properties by units
+func parseServiceStatus(output string) map[string]map[string]string {
+	units := make(map[string]map[string]string)
+	var props map[string]string
+	for _, line := range strings.Split(output, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if kv[0] == "Unit" {
+			props = make(map[string]string)
+			units[kv[1]] = props
+			continue
+		}
+		if props != nil {
+			props[kv[0]] = kv[1]
+		}
+	}
+	return units
+}
+
+// fill sets the state of the unit from the properties read from the host
+func (s *ServiceStatus) fill(props map[string]string) {
+	if props == nil {
+		s.Error = "no status returned"
+		return
+	}
+	s.LoadState = props["LoadState"]
+	s.ActiveState = props["ActiveState"]
+	s.SubState = props["SubState"]
+	s.UnitFileState = props["UnitFileState"]
+	s.LastExitCode = props["ExecMainStatus"]
+	s.Restarts = props["NRestarts"]
+	s.checksum = props["Checksum"]
+	if s.expected != "" && s.checksum != "" {
+		drifted := s.checksum != s.expected
+		s.Drifted = &drifted
+	}
+}
+
+// queryServiceStatus reads the states of the units on the hosts in parallel,
+// the hosts not queried yet are skipped once ctx is canceled
+func queryServiceStatus(ctx context.Context, hostStatus map[string][]*ServiceStatus, concurrency int) {
+	hosts := make(chan string, len(hostStatus))
+	for host := range hostStatus {
+		hosts <- host
+	}
+	close(hosts)
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(hostStatus); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range hosts {
+				statuses := hostStatus[host]
+				setError := func(msg string) {
+					for _, s := range statuses {
+						s.Error = msg
+					}
+				}
+				if ctx.Err() != nil {
+					setError("canceled")
+					continue
+				}
+				e, found := ctxt.GetInner(ctx).GetExecutor(host)
+				if !found {
+					setError("no executor")
+					continue
+				}
+				units := make([]string, 0, len(statuses))
+				for _, s := range statuses {
+					units = append(units, s.Unit)
+				}
+				stdout, _, err := e.Execute(ctx, serviceStatusScript(units), false)
+				if err != nil {
+					if ctx.Err() != nil {
+						setError("canceled")
+					} else {
+						setError("unreachable: " + strings.Split(err.Error(), "\n")[0])
+					}
+					continue
+				}
+				props := parseServiceStatus(string(stdout))
+				for _, s := range statuses {
+					s.fill(props[s.Unit])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ServiceStatus audits the systemd units of the instances in parallel, the
+// units disabled or drifted from the ones rendered from current topology are
+// flagged. It complements the status checked through the ports in display,
+// and can be canceled by Ctrl+C with the results collected shown.
+func (m *Manager) ServiceStatus(name string, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	instances := filterInstances(topo, gOpt)
+	if len(instances) == 0 {
+		return perrs.New("no instance matches the roles and nodes specified")
+	}
+	var statuses []*ServiceStatus
+	hostStatus := make(map[string][]*ServiceStatus)
+	for _, inst := range instances {
+		s := &ServiceStatus{
+			ID:   inst.ID(),
+			Role: inst.Role(),
+			Host: inst.GetHost(),
+			Unit: filepath.Base(spec.SystemdUnitFile(inst)),
+		}
+		unit, ok, err := spec.ExpectedSystemdUnit(inst, *topo.BaseTopo().GlobalOptions, base.User, m.specManager.Path(name, spec.TemplateDirName))
+		if err != nil {
+			return perrs.Annotatef(err, "failed to render the systemd unit of %s", inst.ID())
+		}
+		if ok {
+			sum := sha256.Sum256(unit)
+			s.expected = hex.EncodeToString(sum[:])
+		}
+		statuses = append(statuses, s)
+		hostStatus[s.Host] = append(hostStatus[s.Host], s)
+	}
+
+	baseCtx, cancel := context.WithCancel(m.baseContext(name, gOpt))
+	defer cancel()
+	ctx := ctxt.New(
+		baseCtx,
+		gOpt.Concurrency,
+		m.logger,
+	)
+	if err := m.setViewerSSH(ctx, name, topo, base.User, gOpt, "the systemd units"); err != nil {
+		return err
+	}
+
+	// SIGTERM cancels baseCtx by the termination watched
+	m.specManager.WatchTermination()
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, os.Interrupt)
+	defer signal.Stop(sc)
+	go func() {
+		select {
+		case <-sc:
+			cancel()
+		case <-baseCtx.Done():
+		}
+	}()
+	queryServiceStatus(ctx, hostStatus, gOpt.Concurrency)
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Host != statuses[j].Host {
+			return statuses[i].Host < statuses[j].Host
+		}
+		return statuses[i].ID < statuses[j].ID
+	})
+	flagged := 0
+	for _, s := range statuses {
+		if len(s.Problems()) > 0 {
+			flagged++
+		}
+	}
+
+	if m.logger.GetDisplayMode() == logprinter.DisplayModeJSON {
+		d, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(m.stdout, string(d))
+		return nil
+	}
+
+	rows := [][]string{{"ID", "Role", "Host", "Unit", "Active", "Enabled", "Last Exit", "Restarts", "Problems"}}
+	for _, s := range statuses {
+		active := "-"
+		if s.ActiveState != "" {
+			active = fmt.Sprintf("%s (%s)", s.ActiveState, s.SubState)
+		}
+		problems := strings.Join(s.Problems(), ", ")
+		if problems != "" {
+			problems = color.RedString(problems)
+		}
+		rows = append(rows, []string{
+			s.ID,
+			s.Role,
+			s.Host,
+			s.Unit,
+			active,
+			valueOrDash(s.UnitFileState),
+			valueOrDash(s.LastExitCode),
+			valueOrDash(s.Restarts),
+			problems,
+		})
+	}
+	fmt.Fprintf(m.stdout, "Cluster: %s\n", color.HiYellowString(name))
+	tui.FprintTable(m.stdout, rows, true)
+	if flagged > 0 {
+		m.logger.Warnf("%d of %d units are flagged, the disabled units are not started on boot, "+
+			"and the drifted ones could be rendered again with '%s reload %s'", flagged, len(statuses), tui.OsArgs0(), name)
+	}
+	return nil
+}
+
+// valueOrDash returns the value, or "-" if it's empty
+func valueOrDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
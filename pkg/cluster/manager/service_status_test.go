@@ -0,0 +1,62 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseServiceStatus(t *testing.T) {
+	props := parseServiceStatus(`Unit=tikv-20160.service
+LoadState=loaded
+ActiveState=active
+SubState=running
+UnitFileState=enabled
+ExecMainStatus=0
+NRestarts=2
+Checksum=abc
+Unit=pd-2379.service
+LoadState=not-found
+ActiveState=inactive
+SubState=dead
+UnitFileState=
+ExecMainStatus=0
+Checksum=
+`)
+	assert.Len(t, props, 2)
+
+	tikv := &ServiceStatus{Unit: "tikv-20160.service", expected: "abc"}
+	tikv.fill(props[tikv.Unit])
+	assert.Equal(t, "active", tikv.ActiveState)
+	assert.Equal(t, "2", tikv.Restarts)
+	assert.False(t, *tikv.Drifted)
+	assert.Empty(t, tikv.Problems())
+
+	tikv.expected = "def"
+	tikv.fill(map[string]string{"UnitFileState": "disabled", "Checksum": "abc"})
+	assert.Equal(t, []string{"disabled", "drifted"}, tikv.Problems())
+
+	// the unit file is missing, its drift is not checked
+	pd := &ServiceStatus{Unit: "pd-2379.service", expected: "abc"}
+	pd.fill(props[pd.Unit])
+	assert.Nil(t, pd.Drifted)
+	assert.Equal(t, "", pd.Restarts)
+	assert.Equal(t, []string{"unit not-found"}, pd.Problems())
+
+	missing := &ServiceStatus{Unit: "tidb-4000.service"}
+	missing.fill(props[missing.Unit])
+	assert.Equal(t, []string{"no status returned"}, missing.Problems())
+}
@@ -41,6 +41,6 @@ func (m *Manager) ShowConfig(name string) error {
 		return perrs.AddStack(err)
 	}
 
-	fmt.Print(string(data))
+	fmt.Fprint(m.stdout, string(data))
 	return nil
 }
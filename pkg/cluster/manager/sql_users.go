@@ -0,0 +1,262 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/go-sql-driver/mysql"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/crypto/rand"
+	"github.com/pingcap/tiup/pkg/proxy"
+)
+
+const (
+	// the TiDB users managed by tiup
+	sqlUserRoot     = "root"
+	sqlUserOperator = "operator"
+
+	// operatorAccount is the account used by tiup for its own SQL needs
+	operatorAccount = "tiup_operator"
+	// operatorPrivileges are the privileges of the operator account, it
+	// reads the status and configs but never the data
+	operatorPrivileges = "PROCESS, CONFIG, SHOW DATABASES, RELOAD"
+
+	passwordLength = 18
+)
+
+// SetPasswordOptions contains the options of setting the password of a TiDB user
+type SetPasswordOptions struct {
+	User       string // root or operator
+	Password   string // a random password is generated if empty
+	AskCurrent bool   // ask for the current root password instead of reading it from the credential store
+}
+
+// openTiDB runs fn with the connection to the first reachable TiDB of the
+// cluster as user
+func openTiDB(topo *spec.Specification, user, password string, fn func(db *sql.DB) error) error {
+	if len(topo.TiDBServers) == 0 {
+		return perrs.New("no TiDB instance in the cluster")
+	}
+	tcpProxy := proxy.GetTCPProxy()
+
+	var lastErr error
+	for _, inst := range topo.TiDBServers {
+		endpoint := fmt.Sprintf("%s:%d", inst.Host, inst.Port)
+		if tcpProxy != nil {
+			closeC := tcpProxy.Run([]string{endpoint})
+			defer tcpProxy.Close(closeC)
+			endpoint = tcpProxy.GetEndpoints()[0]
+		}
+
+		cfg := mysql.NewConfig()
+		cfg.User = user
+		cfg.Passwd = password
+		cfg.Net = "tcp"
+		cfg.Addr = endpoint
+		cfg.InterpolateParams = true
+		cfg.MultiStatements = true
+		db, err := sql.Open("mysql", cfg.FormatDSN())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		defer db.Close()
+		if err := db.Ping(); err != nil {
+			lastErr = err
+			continue
+		}
+		return fn(db)
+	}
+	return perrs.Annotate(lastErr, "failed to connect to TiDB")
+}
+
+// rootPassword returns the current root password of TiDB, which is read from
+// the credential store, or empty if it's not stored
+func (m *Manager) rootPassword(name string, ask bool) (string, error) {
+	if ask {
+		return m.prompter.PromptForPassword("Current password of root: "), nil
+	}
+	cred, err := m.specManager.SQLCredential(name, spec.RootCredentialFile)
+	if err != nil || cred == nil {
+		return "", err
+	}
+	return cred.Password, nil
+}
+
+// InitSQLUsers sets a random root password for a newly started cluster,
+// creates the operator account for the SQL needs of tiup, and stores the
+// credentials in the credential store. The root password is returned.
+func (m *Manager) InitSQLUsers(name string) (string, error) {
+	metadata, err := m.meta(name)
+	if err != nil {
+		return "", err
+	}
+	topo, err := spec.AsClusterTopology(metadata.GetTopology())
+	if err != nil {
+		return "", err
+	}
+
+	root, err := rand.Password(passwordLength)
+	if err != nil {
+		return "", err
+	}
+	operator, err := rand.Password(passwordLength)
+	if err != nil {
+		return "", err
+	}
+
+	// the credentials are stored before changing the passwords, so that they
+	// are never lost, and restored if the passwords are not changed
+	restoreOperator, err := m.stageSQLCredential(name, spec.OperatorCredentialFile, &spec.SQLCredential{
+		User:     operatorAccount,
+		Password: operator,
+	})
+	if err != nil {
+		return "", err
+	}
+	restoreRoot, err := m.stageSQLCredential(name, spec.RootCredentialFile, &spec.SQLCredential{
+		User:     sqlUserRoot,
+		Password: root,
+	})
+	if err != nil {
+		restoreOperator()
+		return "", err
+	}
+
+	// the root password is empty for a new cluster
+	operatorCreated := false
+	err = openTiDB(topo, sqlUserRoot, "", func(db *sql.DB) error {
+		if err := createOperatorAccount(db, operator); err != nil {
+			return err
+		}
+		operatorCreated = true
+		_, err := db.Exec("ALTER USER 'root'@'%' IDENTIFIED BY ?", root)
+		return err
+	})
+	if err != nil {
+		if !operatorCreated {
+			restoreOperator()
+		}
+		restoreRoot()
+		return "", err
+	}
+	return root, nil
+}
+
+// stageSQLCredential saves the credential to the credential store of the
+// cluster, the returned function restores the previous one
+func (m *Manager) stageSQLCredential(name, file string, cred *spec.SQLCredential) (func(), error) {
+	prev, err := m.specManager.ReadCredential(name, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.specManager.SaveSQLCredential(name, file, cred); err != nil {
+		return nil, err
+	}
+	return func() {
+		var err error
+		if prev == nil {
+			err = m.specManager.RemoveCredential(name, file)
+		} else {
+			err = m.specManager.WriteCredential(name, file, prev)
+		}
+		if err != nil {
+			m.logger.Warnf("Failed to restore the credential %s of cluster %s: %s", file, name, err)
+		}
+	}, nil
+}
+
+func createOperatorAccount(db *sql.DB, password string) error {
+	if _, err := db.Exec(fmt.Sprintf("CREATE USER IF NOT EXISTS '%s'@'%%' IDENTIFIED BY ?", operatorAccount), password); err != nil {
+		return err
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER USER '%s'@'%%' IDENTIFIED BY ?", operatorAccount), password); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("GRANT %s ON *.* TO '%s'@'%%'", operatorPrivileges, operatorAccount))
+	return err
+}
+
+// SetPassword sets or rotates the password of the root or operator account
+// of TiDB, the operator account is created if it doesn't exist. The new
+// password is stored in the credential store of the cluster.
+func (m *Manager) SetPassword(name string, opt SetPasswordOptions, skipConfirm bool) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	if opt.User != sqlUserRoot && opt.User != sqlUserOperator {
+		return perrs.Errorf("unknown user %s, it should be %s or %s", opt.User, sqlUserRoot, sqlUserOperator)
+	}
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo, err := spec.AsClusterTopology(metadata.GetTopology())
+	if err != nil {
+		return err
+	}
+
+	password := opt.Password
+	if password == "" {
+		if password, err = rand.Password(passwordLength); err != nil {
+			return err
+		}
+	}
+	current, err := m.rootPassword(name, opt.AskCurrent)
+	if err != nil {
+		return err
+	}
+	if !skipConfirm {
+		if err := m.prompter.PromptForConfirmOrAbortError(
+			"This operation will change the password of the %s account of cluster %s.\nDo you want to continue? [y/N]:",
+			color.HiYellowString(opt.User),
+			color.HiYellowString(name)); err != nil {
+			return err
+		}
+	}
+
+	cred := &spec.SQLCredential{User: sqlUserRoot, Password: password}
+	credFile := spec.RootCredentialFile
+	if opt.User == sqlUserOperator {
+		cred.User = operatorAccount
+		credFile = spec.OperatorCredentialFile
+	}
+	// store the new password before changing it, so that it's never lost
+	restore, err := m.stageSQLCredential(name, credFile, cred)
+	if err != nil {
+		return perrs.Annotatef(err, "failed to store the password of %s, it's not changed", opt.User)
+	}
+	err = openTiDB(topo, sqlUserRoot, current, func(db *sql.DB) error {
+		if opt.User == sqlUserOperator {
+			return createOperatorAccount(db, password)
+		}
+		_, err := db.Exec("ALTER USER 'root'@'%' IDENTIFIED BY ?", password)
+		return err
+	})
+	if err != nil {
+		restore()
+		return err
+	}
+
+	m.logger.Infof("The password of %s account of cluster %s is changed and stored in the credential store", opt.User, name)
+	if opt.Password == "" && opt.User == sqlUserRoot {
+		fmt.Fprintf(m.stdout, "The new password is: '%s'.\n", color.HiYellowString(password)) // use fmt to avoid printing to audit log
+	}
+	return nil
+}
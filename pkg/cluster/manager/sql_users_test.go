@@ -0,0 +1,49 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageSQLCredential(t *testing.T) {
+	m := NewManager("tidb", spec.NewSpec(t.TempDir(), func() spec.Metadata { return &spec.ClusterMeta{} }), nil, logprinter.NewLogger(""))
+
+	// the credential not stored before is removed on restoring
+	restore, err := m.stageSQLCredential("test", spec.RootCredentialFile, &spec.SQLCredential{User: "root", Password: "p1"})
+	assert.Nil(t, err)
+	cred, err := m.specManager.SQLCredential("test", spec.RootCredentialFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "p1", cred.Password)
+	restore()
+	cred, err = m.specManager.SQLCredential("test", spec.RootCredentialFile)
+	assert.Nil(t, err)
+	assert.Nil(t, cred)
+
+	// the previous credential is restored
+	assert.Nil(t, m.specManager.SaveSQLCredential("test", spec.RootCredentialFile, &spec.SQLCredential{User: "root", Password: "p1"}))
+	restore, err = m.stageSQLCredential("test", spec.RootCredentialFile, &spec.SQLCredential{User: "root", Password: "p2"})
+	assert.Nil(t, err)
+	cred, err = m.specManager.SQLCredential("test", spec.RootCredentialFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "p2", cred.Password)
+	restore()
+	cred, err = m.specManager.SQLCredential("test", spec.RootCredentialFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "p1", cred.Password)
+}
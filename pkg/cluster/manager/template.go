@@ -0,0 +1,82 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/embed"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// ExportTemplates writes the embedded config and script templates to the
+// template directory of the cluster, so they can be modified and take
+// precedence over the embedded ones. All templates are exported if none is
+// specified, a template can be specified by its path relative to the
+// template directory (e.g. scripts/run_cdc.sh.tpl) or its file name. The
+// existing templates are kept unless force is set.
+func (m *Manager) ExportTemplates(name string, templates []string, force bool) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	all, err := embed.ListTemplates()
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+
+	selected := set.NewStringSet(templates...)
+	matched := set.NewStringSet()
+	dir := m.specManager.Path(name, spec.TemplateDirName)
+	for _, tpl := range all {
+		if len(selected) > 0 {
+			switch {
+			case selected.Exist(tpl):
+				matched.Insert(tpl)
+			case selected.Exist(path.Base(tpl)):
+				matched.Insert(path.Base(tpl))
+			default:
+				continue
+			}
+		}
+
+		fname := filepath.Join(dir, filepath.FromSlash(tpl))
+		if _, err := os.Stat(fname); err == nil && !force {
+			m.logger.Warnf("Template %s already exists, skipped", fname)
+			continue
+		}
+
+		data, err := embed.ReadTemplate(path.Join("templates", tpl))
+		if err != nil {
+			return perrs.AddStack(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			return perrs.AddStack(err)
+		}
+		if err := os.WriteFile(fname, data, 0644); err != nil {
+			return perrs.AddStack(err)
+		}
+		m.logger.Infof("Exported template %s", fname)
+	}
+
+	if unknown := selected.Difference(matched); len(unknown) > 0 {
+		return perrs.Errorf("unknown templates: %v", unknown.Slice())
+	}
+	return nil
+}
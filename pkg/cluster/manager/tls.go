@@ -14,7 +14,6 @@
 package manager
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -23,7 +22,6 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 
@@ -42,6 +40,12 @@ func (m *Manager) TLS(name string, gOpt operator.Options, enable, cleanCertifica
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil {
 		return err
@@ -63,14 +67,14 @@ func (m *Manager) TLS(name string, gOpt operator.Options, enable, cleanCertifica
 	}
 	globalOptions.TLSEnabled = enable
 
-	if err := checkTLSEnv(topo, name, base.Version, skipConfirm); err != nil {
+	if err := m.checkTLSEnv(topo, name, base.Version, skipConfirm); err != nil {
 		return err
 	}
 
 	var (
 		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 	)
-	if gOpt.SSHType != executor.SSHTypeNone {
+	if gOpt.SSHType.UseSSH() {
 		var err error
 		if len(gOpt.SSHProxyHost) != 0 {
 			if sshProxyProps, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
@@ -93,7 +97,7 @@ func (m *Manager) TLS(name string, gOpt operator.Options, enable, cleanCertifica
 	}
 
 	ctx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
@@ -126,7 +130,7 @@ func (m *Manager) TLS(name string, gOpt operator.Options, enable, cleanCertifica
 }
 
 // checkTLSEnv check tiflash vserson and show confirm
-func checkTLSEnv(topo spec.Topology, clusterName, version string, skipConfirm bool) error {
+func (m *Manager) checkTLSEnv(topo spec.Topology, clusterName, version string, skipConfirm bool) error {
 	// check tiflash version
 	if err := checkTiFlashWithTLS(topo, version); err != nil {
 		return err
@@ -144,7 +148,7 @@ func checkTLSEnv(topo spec.Topology, clusterName, version string, skipConfirm bo
 	}
 
 	if !skipConfirm {
-		return tui.PromptForConfirmOrAbortError(
+		return m.prompter.PromptForConfirmOrAbortError(
 			fmt.Sprintf("Enable/Disable TLS will %s the cluster `%s`\nDo you want to continue? [y/N]:",
 				color.HiYellowString("stop and restart"),
 				color.HiYellowString(clusterName),
@@ -173,7 +177,7 @@ func getTLSFileMap(m *Manager, clusterName string, topo spec.Topology,
 		m.logger.Warnf("The parameter `%s` will delete the following files: %s", color.YellowString("--clean-certificate"), delFileList)
 
 		if !skipConfirm {
-			if err := tui.PromptForConfirmOrAbortError("Do you want to continue? [y/N]:"); err != nil {
+			if err := m.prompter.PromptForConfirmOrAbortError("Do you want to continue? [y/N]:"); err != nil {
 				return delFileMap, err
 			}
 		}
@@ -0,0 +1,402 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"golang.org/x/term"
+)
+
+// the columns the top view can be sorted by
+const (
+	TopSortCPU  = "cpu"
+	TopSortMem  = "mem"
+	TopSortDisk = "disk"
+	TopSortNet  = "net"
+)
+
+// TopOptions contains the options of the top command
+type TopOptions struct {
+	Interval time.Duration // the interval between two refreshes
+	SortBy   string        // the column to sort by: cpu, mem, disk or net
+	Count    int           // exit after refreshing count times, 0 to refresh until Ctrl+C
+	Limit    int           // only show the top instances, 0 to show all
+}
+
+// procCounters is the counters of a process read from /proc
+type procCounters struct {
+	pid        int
+	cpuTicks   uint64
+	rss        uint64
+	readBytes  uint64
+	writeBytes uint64
+	hasIO      bool
+}
+
+// topSample is the counters of the processes of a host at a time
+type topSample struct {
+	uptime float64 // in seconds, the time of the host
+	clkTck float64
+	procs  map[string]procCounters // service name -> counters
+	netRx  uint64
+	netTx  uint64
+}
+
+// TopRow is the resource usage of an instance in the top view, the disk
+// rates are -1 if they are not readable, and the network rates are of the
+// whole host as they are not accounted per process
+type TopRow struct {
+	ID        string
+	Role      string
+	Host      string
+	PID       int
+	CPU       float64 // percentage of a core
+	Memory    uint64  // resident memory in bytes
+	DiskRead  float64 // bytes per second
+	DiskWrite float64 // bytes per second
+	NetRx     float64 // bytes per second of the host
+	NetTx     float64 // bytes per second of the host
+}
+
+// topScript prints the counters of the processes of the services and the
+// network of the host
+func topScript(services []string) string {
+	return fmt.Sprintf(`echo "uptime $(cut -d' ' -f1 /proc/uptime) $(getconf CLK_TCK)"; `+
+		`for s in %s; do pid=$(systemctl show -p MainPID $s 2>/dev/null | cut -d= -f2); pid=${pid:-0}; `+
+		`echo "proc $s $pid $(cut -d' ' -f14,15 /proc/$pid/stat 2>/dev/null) `+
+		`$(awk '/^VmRSS/{print $2}' /proc/$pid/status 2>/dev/null) `+
+		`$(sed -n '5,6p' /proc/$pid/io 2>/dev/null | awk '{print $2}' | tr '\n' ' ')"; done; `+
+		`awk 'NR>2 {sub(":", " "); if ($1 != "lo") {rx+=$2; tx+=$10}} END {print "net", rx+0, tx+0}' /proc/net/dev`,
+		strings.Join(services, " "))
+}
+
+// parseTopSample parses the output of topScript
+func parseTopSample(output string) (*topSample, error) {
+	sample := &topSample{procs: make(map[string]procCounters)}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uptime":
+			if len(fields) != 3 {
+				return nil, perrs.Errorf("unknown uptime: %s", line)
+			}
+			sample.uptime, _ = strconv.ParseFloat(fields[1], 64)
+			sample.clkTck, _ = strconv.ParseFloat(fields[2], 64)
+		case "proc":
+			// proc <service> <pid> <utime> <stime> <rss in KiB> [<read_bytes> <write_bytes>]
+			if len(fields) < 6 {
+				continue
+			}
+			var nums []uint64
+			for _, f := range fields[2:] {
+				n, err := strconv.ParseUint(f, 10, 64)
+				if err != nil {
+					return nil, perrs.Errorf("unknown counters of process: %s", line)
+				}
+				nums = append(nums, n)
+			}
+			c := procCounters{
+				pid:      int(nums[0]),
+				cpuTicks: nums[1] + nums[2],
+				rss:      nums[3] * 1024,
+			}
+			if len(nums) >= 6 {
+				c.readBytes, c.writeBytes, c.hasIO = nums[4], nums[5], true
+			}
+			sample.procs[fields[1]] = c
+		case "net":
+			if len(fields) != 3 {
+				return nil, perrs.Errorf("unknown network counters: %s", line)
+			}
+			sample.netRx, _ = strconv.ParseUint(fields[1], 10, 64)
+			sample.netTx, _ = strconv.ParseUint(fields[2], 10, 64)
+		}
+	}
+	if sample.uptime == 0 || sample.clkTck == 0 {
+		return nil, perrs.Errorf("no uptime found in the sample")
+	}
+	return sample, nil
+}
+
+// rate returns the increase per second of the counter, a restarted process
+// or a reset counter is regarded as no increase
+func rate(prev, cur uint64, seconds float64) float64 {
+	if cur < prev || seconds <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+// topRows computes the resource usage of the instances between two samples
+// of their hosts, the instances not running are omitted
+func topRows(instances []spec.Instance, prev, cur map[string]*topSample) []TopRow {
+	var rows []TopRow
+	for _, inst := range instances {
+		p, c := prev[inst.GetHost()], cur[inst.GetHost()]
+		if p == nil || c == nil {
+			continue
+		}
+		pc, ok := p.procs[inst.ServiceName()]
+		if !ok {
+			continue
+		}
+		cc, ok := c.procs[inst.ServiceName()]
+		if !ok || cc.pid == 0 {
+			continue
+		}
+		seconds := c.uptime - p.uptime
+		row := TopRow{
+			ID:        inst.ID(),
+			Role:      inst.Role(),
+			Host:      inst.GetHost(),
+			PID:       cc.pid,
+			Memory:    cc.rss,
+			DiskRead:  -1,
+			DiskWrite: -1,
+			NetRx:     rate(p.netRx, c.netRx, seconds),
+			NetTx:     rate(p.netTx, c.netTx, seconds),
+		}
+		if pc.pid == cc.pid {
+			row.CPU = rate(pc.cpuTicks, cc.cpuTicks, seconds) / c.clkTck * 100
+			if pc.hasIO && cc.hasIO {
+				row.DiskRead = rate(pc.readBytes, cc.readBytes, seconds)
+				row.DiskWrite = rate(pc.writeBytes, cc.writeBytes, seconds)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// sortTopRows sorts the rows by the column in descending order
+func sortTopRows(rows []TopRow, sortBy string) {
+	key := func(r TopRow) float64 {
+		switch sortBy {
+		case TopSortMem:
+			return float64(r.Memory)
+		case TopSortDisk:
+			// the rates not readable are -1
+			return math.Max(r.DiskRead, 0) + math.Max(r.DiskWrite, 0)
+		case TopSortNet:
+			return r.NetRx + r.NetTx
+		default:
+			return r.CPU
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if ki, kj := key(rows[i]), key(rows[j]); ki != kj {
+			return ki > kj
+		}
+		return rows[i].ID < rows[j].ID
+	})
+}
+
+func formatRate(r float64) string {
+	if r < 0 {
+		return "-"
+	}
+	return units.BytesSize(r) + "/s"
+}
+
+// sampleTop samples the counters of the processes on the hosts
+func sampleTop(ctx context.Context, hostServices map[string][]string, concurrency int) map[string]*topSample {
+	var mu sync.Mutex
+	samples := make(map[string]*topSample)
+	hosts := make(chan string, len(hostServices))
+	for host := range hostServices {
+		hosts <- host
+	}
+	close(hosts)
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(hostServices); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range hosts {
+				e, found := ctxt.GetInner(ctx).GetExecutor(host)
+				if !found {
+					continue
+				}
+				stdout, _, err := e.Execute(ctx, topScript(hostServices[host]), false)
+				if err != nil {
+					continue
+				}
+				sample, err := parseTopSample(string(stdout))
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				samples[host] = sample
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return samples
+}
+
+// setViewerSSH sets the SSH executors of the hosts of the cluster, the viewer
+// credentials are used in the viewer mode, what is read through SSH is
+// described by what in the error if they are not set
+func (m *Manager) setViewerSSH(ctx context.Context, name string, topo spec.Topology, user string, gOpt operator.Options, what string) error {
+	globalSSHType := topo.BaseTopo().GlobalOptions.SSHType
+	switch {
+	case !gOpt.Viewer:
+		if err := SetSSHKeySet(ctx, m.specManager.Path(name, "ssh", "id_rsa"), m.specManager.Path(name, "ssh", "id_rsa.pub")); err != nil {
+			return err
+		}
+		return SetClusterSSH(ctx, topo, user, gOpt.SSHTimeout, gOpt.SSHType, globalSSHType)
+	case gOpt.ViewerIdentityFile != "":
+		if err := SetSSHKeySet(ctx, gOpt.ViewerIdentityFile, ""); err != nil {
+			return err
+		}
+		return SetClusterSSH(ctx, topo, gOpt.ViewerUser, gOpt.SSHTimeout, gOpt.SSHType, globalSSHType)
+	default:
+		return perrs.Errorf("%s can't be read without SSH, please specify --viewer-identity-file", what)
+	}
+}
+
+// Top shows the resource usage of the processes of the instances across the
+// hosts in a table refreshed periodically, the counters are read from /proc
+// through the executors.
+func (m *Manager) Top(name string, opt TopOptions, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	switch opt.SortBy {
+	case TopSortCPU, TopSortMem, TopSortDisk, TopSortNet:
+	default:
+		return perrs.Errorf("unknown column %s to sort by, should be one of cpu, mem, disk and net", opt.SortBy)
+	}
+	if opt.Interval < time.Second {
+		opt.Interval = time.Second
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	ctx := ctxt.New(
+		m.baseContext(name, gOpt),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	// the disk I/O of the processes is not readable by other users
+	if err := m.setViewerSSH(ctx, name, topo, base.User, gOpt, "the usage of processes"); err != nil {
+		return err
+	}
+
+	filterRoles := set.NewStringSet(gOpt.Roles...)
+	filterNodes := set.NewStringSet(gOpt.Nodes...)
+	var instances []spec.Instance
+	hostServices := make(map[string][]string)
+	topo.IterInstance(func(inst spec.Instance) {
+		if (len(filterRoles) > 0 && !filterRoles.Exist(inst.Role())) ||
+			(len(filterNodes) > 0 && !filterNodes.Exist(inst.ID())) {
+			return
+		}
+		instances = append(instances, inst)
+		hostServices[inst.GetHost()] = append(hostServices[inst.GetHost()], inst.ServiceName())
+	})
+	if len(instances) == 0 {
+		return perrs.New("no instance matches the roles and nodes specified")
+	}
+
+	// the screen is only cleared on terminals, so the output can be logged
+	redraw := false
+	if f, ok := m.stdout.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		redraw = true
+	}
+	m.specManager.WatchTermination()
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, os.Interrupt)
+	defer signal.Stop(sc)
+
+	prev := sampleTop(ctx, hostServices, gOpt.Concurrency)
+	for i := 0; opt.Count <= 0 || i < opt.Count; i++ {
+		select {
+		case <-time.After(opt.Interval):
+		case <-sc:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+		cur := sampleTop(ctx, hostServices, gOpt.Concurrency)
+		rows := topRows(instances, prev, cur)
+		sortTopRows(rows, opt.SortBy)
+		prev = cur
+
+		if redraw {
+			fmt.Fprint(m.stdout, "\033[H\033[2J")
+		}
+		fmt.Fprintf(m.stdout, "Cluster %s at %s, refreshed every %s, sorted by %s\n",
+			color.CyanString(name), time.Now().Format("15:04:05"), opt.Interval, opt.SortBy)
+		if unreachable := len(hostServices) - len(cur); unreachable > 0 {
+			fmt.Fprintf(m.stdout, "%s\n", color.YellowString("%d hosts are not sampled", unreachable))
+		}
+		m.printTopRows(rows, opt.Limit)
+	}
+	return nil
+}
+
+func (m *Manager) printTopRows(rows []TopRow, limit int) {
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	table := [][]string{{"ID", "Role", "Host", "PID", "CPU%", "Memory", "Disk Read", "Disk Write", "Host Net RX", "Host Net TX"}}
+	for _, r := range rows {
+		table = append(table, []string{
+			r.ID,
+			r.Role,
+			r.Host,
+			strconv.Itoa(r.PID),
+			fmt.Sprintf("%.1f", r.CPU),
+			units.BytesSize(float64(r.Memory)),
+			formatRate(r.DiskRead),
+			formatRate(r.DiskWrite),
+			formatRate(r.NetRx),
+			formatRate(r.NetTx),
+		})
+	}
+	tui.FprintTable(m.stdout, table, true)
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTopSample(t *testing.T) {
+	sample, err := parseTopSample(`uptime 100.50 100
+proc tikv-20160.service 1234 1000 500 2048 4096 8192
+proc pd-2379.service 2345 200 100 1024
+proc tidb-4000.service 0
+net 1000 2000
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, &topSample{
+		uptime: 100.5,
+		clkTck: 100,
+		procs: map[string]procCounters{
+			"tikv-20160.service": {pid: 1234, cpuTicks: 1500, rss: 2048 * 1024, readBytes: 4096, writeBytes: 8192, hasIO: true},
+			"pd-2379.service":    {pid: 2345, cpuTicks: 300, rss: 1024 * 1024},
+		},
+		netRx: 1000,
+		netTx: 2000,
+	}, sample)
+
+	_, err = parseTopSample("net 1 2\n")
+	assert.NotNil(t, err)
+}
+
+func TestTopRows(t *testing.T) {
+	topo := &spec.Specification{
+		TiKVServers: []*spec.TiKVSpec{
+			{Host: "10.0.0.1", Port: 20160, StatusPort: 20180},
+			{Host: "10.0.0.2", Port: 20160, StatusPort: 20180},
+		},
+		PDServers: []*spec.PDSpec{{Host: "10.0.0.1", ClientPort: 2379, PeerPort: 2380}},
+	}
+	var instances []spec.Instance
+	topo.IterInstance(func(inst spec.Instance) {
+		instances = append(instances, inst)
+	})
+
+	prev := map[string]*topSample{
+		"10.0.0.1": {uptime: 100, clkTck: 100, netRx: 1000, netTx: 1000, procs: map[string]procCounters{
+			"tikv-20160.service": {pid: 1, cpuTicks: 1000, readBytes: 0, writeBytes: 0, hasIO: true},
+			"pd-2379.service":    {pid: 2, cpuTicks: 100},
+		}},
+		"10.0.0.2": {uptime: 50, clkTck: 100, procs: map[string]procCounters{
+			"tikv-20160.service": {pid: 3, cpuTicks: 100},
+		}},
+	}
+	cur := map[string]*topSample{
+		"10.0.0.1": {uptime: 102, clkTck: 100, netRx: 3000, netTx: 1400, procs: map[string]procCounters{
+			"tikv-20160.service": {pid: 1, cpuTicks: 1300, rss: 1 << 30, readBytes: 2048, writeBytes: 4096, hasIO: true},
+			"pd-2379.service":    {pid: 2, cpuTicks: 110, rss: 1 << 20},
+		}},
+		// the TiKV is restarted
+		"10.0.0.2": {uptime: 52, clkTck: 100, procs: map[string]procCounters{
+			"tikv-20160.service": {pid: 4, cpuTicks: 10},
+		}},
+	}
+
+	rows := topRows(instances, prev, cur)
+	sortTopRows(rows, TopSortCPU)
+	assert.Equal(t, []TopRow{
+		{ID: "10.0.0.1:20160", Role: "tikv", Host: "10.0.0.1", PID: 1, CPU: 150, Memory: 1 << 30,
+			DiskRead: 1024, DiskWrite: 2048, NetRx: 1000, NetTx: 200},
+		{ID: "10.0.0.1:2379", Role: "pd", Host: "10.0.0.1", PID: 2, CPU: 5, Memory: 1 << 20,
+			DiskRead: -1, DiskWrite: -1, NetRx: 1000, NetTx: 200},
+		{ID: "10.0.0.2:20160", Role: "tikv", Host: "10.0.0.2", PID: 4,
+			DiskRead: -1, DiskWrite: -1},
+	}, rows)
+
+	sortTopRows(rows, TopSortMem)
+	assert.Equal(t, "10.0.0.1:20160", rows[0].ID)
+	assert.Equal(t, "10.0.0.2:20160", rows[2].ID)
+}
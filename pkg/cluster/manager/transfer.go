@@ -15,7 +15,6 @@ package manager
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 	"html/template"
 	"reflect"
@@ -90,8 +89,10 @@ func (m *Manager) Transfer(name string, opt TransferOptions, gOpt operator.Optio
 	})
 
 	srcPath := opt.Local
+	hosts := set.NewStringSet()
 	for hostKey, i := range uniqueHosts {
 		host := strings.Split(hostKey, "-")[0]
+		hosts.Insert(host)
 		for _, p := range i.Slice() {
 			t := task.NewBuilder(m.logger)
 			if opt.Pull {
@@ -103,6 +104,8 @@ func (m *Manager) Transfer(name string, opt TransferOptions, gOpt operator.Optio
 		}
 	}
 
+	m.warnForeignInstances(hosts)
+
 	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
 	if err != nil {
 		return err
@@ -113,7 +116,7 @@ func (m *Manager) Transfer(name string, opt TransferOptions, gOpt operator.Optio
 		Build()
 
 	execCtx := ctxt.New(
-		context.Background(),
+		m.baseContext(name, gOpt),
 		gOpt.Concurrency,
 		m.logger,
 	)
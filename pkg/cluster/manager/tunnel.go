@@ -0,0 +1,372 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// TunnelTargetDashboard is the tunnel target of TiDB Dashboard, it's resolved
+// to the PD running it.
+const TunnelTargetDashboard = "dashboard"
+
+// the components serving HTTP APIs on their main ports, which are proxied
+// with the client certificate of the cluster if TLS is enabled
+var tlsAPIComponents = set.NewStringSet(
+	spec.ComponentPD,
+	spec.ComponentCDC,
+	spec.ComponentPump,
+	spec.ComponentDrainer,
+	TunnelTargetDashboard,
+)
+
+// portForward is a local port forwarded to a port of the host through SSH
+type portForward struct {
+	Name       string
+	Host       string
+	SSHPort    int
+	RemotePort int
+	// LocalPort is the local port listened by ssh
+	LocalPort int
+	Scheme    string
+	Path      string
+
+	// proxy is the listener of the local proxy wiring the client certificate
+	// of the cluster to the forwarded TLS API, it's nil if not proxied
+	proxy net.Listener
+}
+
+// URL returns the local URL of the forwarded port
+func (f portForward) URL() string {
+	if f.proxy != nil {
+		return fmt.Sprintf("http://%s%s", f.proxy.Addr(), f.Path)
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%d%s", f.Scheme, f.LocalPort, f.Path)
+}
+
+// serveProxy proxies the plain HTTP requests to the TLS API forwarded, with
+// the client certificate of the cluster, the server certificate is verified
+// against the host of the component as it's issued for the host
+func (f portForward) serveProxy(tlsCfg *tls.Config) error {
+	cfg := tlsCfg.Clone()
+	cfg.ServerName = f.Host
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("127.0.0.1:%d", f.LocalPort),
+	})
+	proxy.Transport = &http.Transport{TLSClientConfig: cfg}
+	err := http.Serve(f.proxy, proxy)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return perrs.AddStack(err)
+}
+
+// portAllocator allocates the local ports of a tunnel, GetFreePort doesn't
+// reserve the ports, so the ones allocated are tracked to avoid duplicates
+type portAllocator struct {
+	used map[int]struct{}
+}
+
+func newPortAllocator() *portAllocator {
+	return &portAllocator{used: make(map[int]struct{})}
+}
+
+// alloc returns a free local port not allocated yet, the preferred one if
+// it's available
+func (a *portAllocator) alloc(prefer int) (int, error) {
+	for i := 0; i < 100; i++ {
+		port, err := utils.GetFreePort("127.0.0.1", prefer)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := a.used[port]; !ok {
+			a.used[port] = struct{}{}
+			return port, nil
+		}
+		prefer = port + 1
+	}
+	return 0, perrs.New("no free local port")
+}
+
+// listen listens on a free local port not allocated yet, the listener keeps
+// the port reserved until it's closed
+func (a *portAllocator) listen(prefer int) (net.Listener, error) {
+	for i := 0; i < 100; i++ {
+		port, err := a.alloc(prefer)
+		if err != nil {
+			return nil, err
+		}
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err == nil {
+			return l, nil
+		}
+		prefer = port + 1
+	}
+	return nil, perrs.New("no free local port")
+}
+
+// sshForwardCmd builds the ssh command forwarding the local ports to the
+// host, through the SSH proxy if it's specified.
+func (m *Manager) sshForwardCmd(name, user, host string, sshPort int, forwards []portForward, gOpt operator.Options) *exec.Cmd {
+	args := []string{
+		"-N",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "ExitOnForwardFailure=yes",
+		"-i", m.specManager.Path(name, "ssh", "id_rsa"),
+		"-p", fmt.Sprint(sshPort),
+	}
+	for _, f := range forwards {
+		args = append(args, "-L", fmt.Sprintf("127.0.0.1:%d:%s:%d", f.LocalPort, f.Host, f.RemotePort))
+	}
+	if gOpt.SSHProxyHost != "" {
+		args = append(args, "-J", fmt.Sprintf("%s@%s:%d", gOpt.SSHProxyUser, gOpt.SSHProxyHost, gOpt.SSHProxyPort))
+	}
+	args = append(args, fmt.Sprintf("%s@%s", user, host))
+
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = m.stdin
+	cmd.Stdout = m.stdout
+	cmd.Stderr = m.stderr
+	m.logger.Debugf("ssh %s", strings.Join(args, " "))
+	return cmd
+}
+
+// Tunnel forwards local ports to the web UIs and APIs of the components
+// through SSH, so they can be accessed from where the cluster network is
+// unreachable. The targets are component names, node IDs or "dashboard".
+func (m *Manager) Tunnel(name string, targets []string, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return perrs.New("at least one target must be specified with -N")
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo := metadata.GetTopology()
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	forwards, err := m.tunnelForwards(name, topo, targets, gOpt)
+	defer func() {
+		for _, f := range forwards {
+			if f.proxy != nil {
+				f.proxy.Close()
+			}
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{{"Target", "Remote", "Local URL"}}
+	rawTLS := false
+	for _, f := range forwards {
+		rows = append(rows, []string{f.Name, fmt.Sprintf("%s:%d", f.Host, f.RemotePort), color.CyanString(f.URL())})
+		if f.Scheme == "https" && f.proxy == nil {
+			rawTLS = true
+		}
+	}
+	tui.FprintTable(m.stdout, rows, true)
+
+	if rawTLS {
+		certDir := m.specManager.Path(name, spec.TLSCertKeyDir)
+		fmt.Fprintln(m.stdout)
+		fmt.Fprintln(m.stdout, "The ports forwarded as is require the client certificate of the cluster, e.g.:")
+		fmt.Fprintf(m.stdout, "  --ssl-ca %s/%s --ssl-cert %s/%s --ssl-key %s/%s\n",
+			certDir, spec.TLSCACert, certDir, spec.TLSClientCert, certDir, spec.TLSClientKey)
+	}
+	fmt.Fprintln(m.stdout)
+
+	// one ssh process per host
+	byHost := make(map[string][]portForward)
+	for _, f := range forwards {
+		byHost[f.Host] = append(byHost[f.Host], f)
+	}
+	cmds := make([]*exec.Cmd, 0, len(byHost))
+	for host, fs := range byHost {
+		cmds = append(cmds, m.sshForwardCmd(name, metadata.GetBaseMeta().User, host, fs[0].SSHPort, fs, gOpt))
+	}
+
+	for _, f := range forwards {
+		if f.proxy == nil {
+			continue
+		}
+		go func(f portForward) {
+			if err := f.serveProxy(tlsCfg); err != nil {
+				m.logger.Warnf("The proxy of %s stopped: %s", f.Name, err)
+			}
+		}(f)
+	}
+
+	m.logger.Infof("Forwarding %d ports through SSH, press Ctrl+C to stop", len(forwards))
+	return runTunnels(cmds)
+}
+
+// runTunnels starts the ssh processes and stops all of them once any exits
+func runTunnels(cmds []*exec.Cmd) error {
+	done := make(chan error, len(cmds))
+	for _, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			for _, c := range cmds {
+				if c.Process != nil {
+					_ = c.Process.Kill()
+				}
+			}
+			return perrs.AddStack(err)
+		}
+		go func(cmd *exec.Cmd) {
+			done <- cmd.Wait()
+		}(cmd)
+	}
+
+	err := <-done
+	for _, cmd := range cmds {
+		if cmd.ProcessState == nil {
+			_ = cmd.Process.Kill()
+		}
+	}
+	return perrs.AddStack(err)
+}
+
+// tunnelForwards resolves the targets to the ports to forward, the TLS APIs
+// are proxied by the listeners returned, which must be closed by the caller
+// even if an error is returned
+func (m *Manager) tunnelForwards(name string, topo spec.Topology, targets []string, gOpt operator.Options) ([]portForward, error) {
+	tlsEnabled := topo.BaseTopo().GlobalOptions.TLSEnabled
+	var forwards []portForward
+	seen := set.NewStringSet()
+	ports := newPortAllocator()
+	add := func(f portForward) error {
+		key := fmt.Sprintf("%s/%s:%d", f.Name, f.Host, f.RemotePort)
+		if seen.Exist(key) {
+			return nil
+		}
+		seen.Insert(key)
+		f.Scheme = "http"
+		if tlsEnabled {
+			f.Scheme = "https"
+		}
+		var err error
+		if tlsEnabled && tlsAPIComponents.Exist(f.Name) {
+			// the proxy takes the preferred port as it's the one visited
+			if f.proxy, err = ports.listen(f.RemotePort); err == nil {
+				f.LocalPort, err = ports.alloc(0)
+			}
+		} else {
+			f.LocalPort, err = ports.alloc(f.RemotePort)
+		}
+		if err != nil {
+			if f.proxy != nil {
+				f.proxy.Close()
+			}
+			return perrs.Annotatef(err, "failed to forward %s:%d", f.Host, f.RemotePort)
+		}
+		forwards = append(forwards, f)
+		return nil
+	}
+
+	for _, target := range targets {
+		if target == TunnelTargetDashboard {
+			f, err := m.dashboardForward(name, topo, gOpt)
+			if err != nil {
+				return forwards, err
+			}
+			if err := add(f); err != nil {
+				return forwards, err
+			}
+			continue
+		}
+
+		found := false
+		for _, comp := range topo.ComponentsByStartOrder() {
+			for _, inst := range comp.Instances() {
+				if comp.Name() != target && inst.ID() != target {
+					continue
+				}
+				found = true
+				if err := add(portForward{
+					Name:       inst.ComponentName(),
+					Host:       inst.GetHost(),
+					SSHPort:    inst.GetSSHPort(),
+					RemotePort: inst.GetPort(),
+					Path:       "/",
+				}); err != nil {
+					return forwards, err
+				}
+			}
+		}
+		if !found {
+			return forwards, perrs.Errorf("no component or instance matches the target %s", target)
+		}
+	}
+
+	sort.SliceStable(forwards, func(i, j int) bool { return forwards[i].Name < forwards[j].Name })
+	return forwards, nil
+}
+
+// dashboardForward returns the port forwarded to TiDB Dashboard
+func (m *Manager) dashboardForward(name string, topo spec.Topology, gOpt operator.Options) (portForward, error) {
+	cluster, err := spec.AsClusterTopology(topo)
+	if err != nil {
+		return portForward{}, err
+	}
+	tlsCfg, err := cluster.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return portForward{}, err
+	}
+
+	ctx := m.apiContext(name, gOpt)
+	addr, err := cluster.GetDashboardAddress(ctx, tlsCfg, time.Second*time.Duration(gOpt.APITimeout), cluster.GetPDList()...)
+	if err != nil {
+		return portForward{}, perrs.Annotate(err, "failed to retrieve TiDB Dashboard instance from PD")
+	}
+	for _, pd := range cluster.PDServers {
+		if addr == fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort) {
+			return portForward{
+				Name:       TunnelTargetDashboard,
+				Host:       pd.Host,
+				SSHPort:    pd.SSHPort,
+				RemotePort: pd.ClientPort,
+				Path:       "/dashboard/",
+			}, nil
+		}
+	}
+	return portForward{}, perrs.Errorf("TiDB Dashboard %s is not running on any PD of the cluster", addr)
+}
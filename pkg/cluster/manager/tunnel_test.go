@@ -0,0 +1,119 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHForwardCmd(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager("tidb", spec.NewSpec(dir, func() spec.Metadata { return &spec.ClusterMeta{} }), nil, logprinter.NewLogger(""))
+
+	forwards := []portForward{
+		{Name: "grafana", Host: "172.16.5.140", RemotePort: 3000, LocalPort: 3000, Scheme: "http", Path: "/"},
+		{Name: "dashboard", Host: "172.16.5.140", RemotePort: 2379, LocalPort: 12379, Scheme: "https", Path: "/dashboard/"},
+	}
+	assert.Equal(t, "https://127.0.0.1:12379/dashboard/", forwards[1].URL())
+
+	cmd := m.sshForwardCmd("test", "tidb", "172.16.5.140", 22, forwards, operator.Options{})
+	assert.Equal(t, []string{
+		"ssh", "-N",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "ExitOnForwardFailure=yes",
+		"-i", filepath.Join(dir, "test", "ssh", "id_rsa"),
+		"-p", "22",
+		"-L", "127.0.0.1:3000:172.16.5.140:3000",
+		"-L", "127.0.0.1:12379:172.16.5.140:2379",
+		"tidb@172.16.5.140",
+	}, cmd.Args)
+
+	// through the bastion
+	cmd = m.sshForwardCmd("test", "tidb", "172.16.5.140", 22, forwards[:1], operator.Options{
+		SSHProxyHost: "bastion",
+		SSHProxyPort: 2222,
+		SSHProxyUser: "ops",
+	})
+	assert.Equal(t, []string{"-J", "ops@bastion:2222", "tidb@172.16.5.140"}, cmd.Args[len(cmd.Args)-3:])
+}
+
+func TestPortAllocator(t *testing.T) {
+	ports := newPortAllocator()
+	l, err := ports.listen(0)
+	assert.Nil(t, err)
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	// the ports allocated are never returned again
+	seen := map[int]bool{port: true}
+	for i := 0; i < 5; i++ {
+		p, err := ports.alloc(port)
+		assert.Nil(t, err)
+		assert.False(t, seen[p])
+		seen[p] = true
+	}
+}
+
+func TestTunnelProxy(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+	remotePort, err := strconv.Atoi(u.Port())
+	assert.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	tlsCfg := &tls.Config{RootCAs: pool}
+
+	proxy := func(host string) *http.Response {
+		l, err := newPortAllocator().listen(0)
+		assert.Nil(t, err)
+		defer l.Close()
+		f := portForward{Name: "pd", Host: host, LocalPort: remotePort, Path: "/pd/api/v1/stores", proxy: l}
+		go func() {
+			assert.Nil(t, f.serveProxy(tlsCfg))
+		}()
+		resp, err := http.Get(f.URL())
+		assert.Nil(t, err)
+		return resp
+	}
+
+	// the server certificate is issued for the host
+	resp := proxy("127.0.0.1")
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "GET /pd/api/v1/stores", string(body))
+
+	resp = proxy("172.16.5.140")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
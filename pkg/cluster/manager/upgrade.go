@@ -17,10 +17,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -29,7 +32,7 @@ import (
 	"github.com/pingcap/tiup/pkg/environment"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/repository"
-	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/utils"
 	"golang.org/x/mod/semver"
 )
@@ -45,6 +48,12 @@ func (m *Manager) Upgrade(name string, clusterVersion string, opt operator.Optio
 		return err
 	}
 
+	release, err := m.lockOperation(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	metadata, err := m.meta(name)
 	if err != nil {
 		return err
@@ -53,24 +62,52 @@ func (m *Manager) Upgrade(name string, clusterVersion string, opt operator.Optio
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
 
+	var skipHosts set.StringSet
+	if opt.SkipUnreachable {
+		if skipHosts, err = m.quarantineUnreachableHosts(name, metadata, opt); err != nil {
+			return err
+		}
+		nodes, ok := reachableNodes(topo, opt.Nodes, opt.Roles, skipHosts)
+		if !ok {
+			m.logger.Infof("All the instances to upgrade are on the unreachable hosts, nothing to do")
+			return nil
+		}
+		opt.Nodes = nodes
+	}
+
+	// the hooks left by the previous upgrade to the same version are resumed
+	history, err := m.loadUpgradeHookHistory(name)
+	if err != nil {
+		return err
+	}
+	hasPendingHooks := len(pendingUpgradeHooks(history)) > 0
+	if hasPendingHooks && base.Version == clusterVersion {
+		m.logger.Infof("Resuming the upgrade hooks of cluster %s...", name)
+		if err := m.runUpgradeHooks(name, topo, opt, offline); err != nil {
+			return err
+		}
+		m.logger.Infof("Upgraded cluster `%s` successfully", name)
+		return nil
+	}
+
 	// Adjust topo by new version
 	if clusterTopo, ok := topo.(*spec.Specification); ok {
 		clusterTopo.AdjustByVersion(clusterVersion)
 	}
 
-	var (
-		downloadCompTasks []task.Task // tasks which are used to download components
-		copyCompTasks     []task.Task // tasks which are used to copy components to remote host
-
-		uniqueComps = map[string]struct{}{}
-	)
-
 	if err := versionCompare(base.Version, clusterVersion); err != nil {
 		return err
 	}
+	if err := spec.ValidateRestartHooks(opt.DisabledRestartHooks, topo); err != nil {
+		return err
+	}
+	if len(opt.DisabledRestartHooks) > 0 {
+		m.logger.Warnf("The %s hooks are disabled, the instances are restarted without them",
+			strings.Join(opt.DisabledRestartHooks, ", "))
+	}
 
 	if !skipConfirm {
-		if err := tui.PromptForConfirmOrAbortError(
+		if err := m.prompter.PromptForConfirmOrAbortError(
 			"This operation will upgrade %s %s cluster %s to %s.\nDo you want to continue? [y/N]:",
 			m.sysName,
 			color.HiYellowString(base.Version),
@@ -81,9 +118,112 @@ func (m *Manager) Upgrade(name string, clusterVersion string, opt operator.Optio
 		m.logger.Infof("Upgrading cluster...")
 	}
 
-	hasImported := false
+	// the hooks of the version running must be applied before upgrading
+	// across the next ones
+	if hasPendingHooks {
+		m.logger.Infof("Resuming the upgrade hooks of the previous upgrade...")
+		if err := m.runUpgradeHooks(name, topo, opt, offline); err != nil {
+			return err
+		}
+	}
+	fromVersion := base.Version
+
+	downloadCompTasks, copyCompTasks, hasImported, err := buildUpgradeComponentTasks(m, name, topo, base, clusterVersion, opt, skipHosts)
+	if err != nil {
+		return err
+	}
+
+	// handle dir scheme changes
+	if hasImported {
+		if err := spec.HandleImportPathMigration(name); err != nil {
+			return err
+		}
+	}
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+	b, err := m.sshTaskBuilder(name, topo, base.User, opt)
+	if err != nil {
+		return err
+	}
+	t := b.
+		Parallel(false, downloadCompTasks...).
+		Parallel(opt.Force, copyCompTasks...).
+		Func("UpgradeCluster", func(ctx context.Context) error {
+			if offline {
+				return nil
+			}
+			return operator.Upgrade(ctx, topo, opt, tlsCfg)
+		}).
+		Build()
+
+	// the status queried by the restart hooks of each instance is cached,
+	// and dropped once an instance is restarted
+	ctx := api.WithCacheContext(spec.WithDisabledRestartHooks(ctxt.New(
+		m.baseContext(name, opt),
+		opt.Concurrency,
+		m.logger,
+	), opt.DisabledRestartHooks), api.DefaultCacheTTL)
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	// the hooks are planned before saving the new version, and run after it,
+	// so they are resumed by upgrading to the version again if interrupted
+	if err := m.planUpgradeHooks(name, topo, fromVersion, clusterVersion); err != nil {
+		return err
+	}
+
+	// clear patched packages and tags
+	if err := os.RemoveAll(m.specManager.Path(name, "patch")); err != nil {
+		return perrs.Trace(err)
+	}
+	topo.IterInstance(func(ins spec.Instance) {
+		if ins.IsPatched() {
+			ins.SetPatched(false)
+		}
+	})
+
+	markRestarts(metadata, "upgrade", opt, time.Now())
+	metadata.SetVersion(clusterVersion)
+
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+
+	if err := m.runUpgradeHooks(name, topo, opt, offline); err != nil {
+		return err
+	}
+
+	m.logger.Infof("Upgraded cluster `%s` successfully", name)
+
+	return nil
+}
+
+// buildUpgradeComponentTasks builds the tasks to download the components of the
+// target version and deploy them to every instance not located on skipHosts.
+func buildUpgradeComponentTasks(
+	m *Manager,
+	name string,
+	topo spec.Topology,
+	base *spec.BaseMeta,
+	clusterVersion string,
+	opt operator.Options,
+	skipHosts set.StringSet,
+) (downloadCompTasks, copyCompTasks []task.Task, hasImported bool, err error) {
+	uniqueComps := map[string]struct{}{}
+
 	for _, comp := range topo.ComponentsByUpdateOrder() {
 		for _, inst := range comp.Instances() {
+			if skipHosts.Exist(inst.GetHost()) {
+				continue
+			}
 			compName := inst.ComponentName()
 
 			// ignore monitor agents for instances marked as ignore_exporter
@@ -151,7 +291,7 @@ func (m *Manager) Upgrade(name string, clusterVersion string, opt operator.Optio
 						GOARCH: inst.Arch(),
 					}).LatestStableVersion(spec.ComponentSpark, false)
 					if err != nil {
-						return err
+						return nil, nil, false, err
 					}
 					tb = tb.DeploySpark(inst, sparkVer.String(), "" /* default srcPath */, deployDir)
 				default:
@@ -175,74 +315,18 @@ func (m *Manager) Upgrade(name string, clusterVersion string, opt operator.Optio
 				base.User,
 				opt.IgnoreConfigCheck,
 				meta.DirPaths{
-					Deploy: deployDir,
-					Data:   dataDirs,
-					Log:    logDir,
-					Cache:  m.specManager.Path(name, spec.TempConfigPath),
+					Deploy:    deployDir,
+					Data:      dataDirs,
+					Log:       logDir,
+					Cache:     m.specManager.Path(name, spec.TempConfigPath),
+					Templates: m.specManager.Path(name, spec.TemplateDirName),
 				},
 			)
 			copyCompTasks = append(copyCompTasks, tb.Build())
 		}
 	}
 
-	// handle dir scheme changes
-	if hasImported {
-		if err := spec.HandleImportPathMigration(name); err != nil {
-			return err
-		}
-	}
-
-	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
-	if err != nil {
-		return err
-	}
-	b, err := m.sshTaskBuilder(name, topo, base.User, opt)
-	if err != nil {
-		return err
-	}
-	t := b.
-		Parallel(false, downloadCompTasks...).
-		Parallel(opt.Force, copyCompTasks...).
-		Func("UpgradeCluster", func(ctx context.Context) error {
-			if offline {
-				return nil
-			}
-			return operator.Upgrade(ctx, topo, opt, tlsCfg)
-		}).
-		Build()
-
-	ctx := ctxt.New(
-		context.Background(),
-		opt.Concurrency,
-		m.logger,
-	)
-	if err := t.Execute(ctx); err != nil {
-		if errorx.Cast(err) != nil {
-			// FIXME: Map possible task errors and give suggestions.
-			return err
-		}
-		return perrs.Trace(err)
-	}
-
-	// clear patched packages and tags
-	if err := os.RemoveAll(m.specManager.Path(name, "patch")); err != nil {
-		return perrs.Trace(err)
-	}
-	topo.IterInstance(func(ins spec.Instance) {
-		if ins.IsPatched() {
-			ins.SetPatched(false)
-		}
-	})
-
-	metadata.SetVersion(clusterVersion)
-
-	if err := m.specManager.SaveMeta(name, metadata); err != nil {
-		return err
-	}
-
-	m.logger.Infof("Upgraded cluster `%s` successfully", name)
-
-	return nil
+	return downloadCompTasks, copyCompTasks, hasImported, nil
 }
 
 func versionCompare(curVersion, newVersion string) error {
@@ -0,0 +1,290 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/embed"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// the history of the upgrade hooks run on the cluster
+	upgradeHookHistoryName = "upgrade_hooks.json"
+
+	// the status of the upgrade hooks in the history
+	upgradeHookPending = "pending"
+	upgradeHookApplied = "applied"
+	upgradeHookSkipped = "skipped"
+	upgradeHookFailed  = "failed"
+)
+
+var (
+	errNSUpgradeHook     = errorx.NewNamespace("upgrade_hook")
+	errUpgradeHookFailed = errNSUpgradeHook.NewType("failed")
+)
+
+// UpgradeHook is the SQL bundled for a version, it's run after the cluster is
+// upgraded from a version before it to it or later
+type UpgradeHook struct {
+	Version string
+	Name    string
+	SQL     string
+}
+
+// ID returns the unique ID of the hook
+func (h UpgradeHook) ID() string {
+	return h.Version + "/" + h.Name
+}
+
+// upgradeHookRecord is a run of an upgrade hook in the history
+type upgradeHookRecord struct {
+	Hook   string    `json:"hook"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// bundledUpgradeHooks returns all the upgrade hooks embedded
+func bundledUpgradeHooks() ([]UpgradeHook, error) {
+	paths, err := embed.ListUpgradeHooks()
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	var hooks []UpgradeHook
+	for _, p := range paths {
+		parts := strings.SplitN(p, "/", 2)
+		if len(parts) != 2 || !semver.IsValid(parts[0]) {
+			continue
+		}
+		data, err := embed.ReadUpgradeHook(p)
+		if err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		hooks = append(hooks, UpgradeHook{
+			Version: parts[0],
+			Name:    strings.TrimSuffix(parts[1], ".sql"),
+			SQL:     string(data),
+		})
+	}
+	return hooks, nil
+}
+
+// selectUpgradeHooks returns the hooks to run when upgrading from `from` to
+// `to`, sorted by version and name. A nightly build is ahead of all the
+// versions released, so nothing is run when upgrading from it.
+func selectUpgradeHooks(hooks []UpgradeHook, from, to string) []UpgradeHook {
+	if from == utils.NightlyVersionAlias {
+		return nil
+	}
+	var selected []UpgradeHook
+	for _, h := range hooks {
+		if semver.Compare(from, h.Version) >= 0 {
+			continue
+		}
+		if to != utils.NightlyVersionAlias && semver.Compare(to, h.Version) < 0 {
+			continue
+		}
+		selected = append(selected, h)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if c := semver.Compare(selected[i].Version, selected[j].Version); c != 0 {
+			return c < 0
+		}
+		return selected[i].Name < selected[j].Name
+	})
+	return selected
+}
+
+func (m *Manager) loadUpgradeHookHistory(name string) ([]upgradeHookRecord, error) {
+	data, err := os.ReadFile(m.specManager.Path(name, upgradeHookHistoryName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	var history []upgradeHookRecord
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, perrs.Annotate(err, "invalid history of upgrade hooks")
+	}
+	return history, nil
+}
+
+func (m *Manager) saveUpgradeHookHistory(name string, history []upgradeHookRecord) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(os.WriteFile(m.specManager.Path(name, upgradeHookHistoryName), data, 0644))
+}
+
+// pendingUpgradeHooks returns the indexes of the hooks in the history not
+// applied yet, including the failed ones
+func pendingUpgradeHooks(history []upgradeHookRecord) []int {
+	var pending []int
+	for i, r := range history {
+		if r.Status == upgradeHookPending || r.Status == upgradeHookFailed {
+			pending = append(pending, i)
+		}
+	}
+	return pending
+}
+
+// planUpgradeHooks records the hooks to run when upgrading the cluster from
+// `from` to `to` as pending, it's called before the new version is saved to
+// the meta, so the hooks are never lost if they are interrupted
+func (m *Manager) planUpgradeHooks(name string, topo spec.Topology, from, to string) error {
+	cluster, ok := topo.(*spec.Specification)
+	if !ok || len(cluster.TiDBServers) == 0 {
+		return nil
+	}
+	hooks, err := bundledUpgradeHooks()
+	if err != nil {
+		return err
+	}
+	hooks = selectUpgradeHooks(hooks, from, to)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	history, err := m.loadUpgradeHookHistory(name)
+	if err != nil {
+		return err
+	}
+	planned := set.NewStringSet()
+	for _, r := range history {
+		if r.Status != upgradeHookSkipped {
+			planned.Insert(r.Hook)
+		}
+	}
+	for _, hook := range hooks {
+		if planned.Exist(hook.ID()) {
+			m.logger.Debugf("Upgrade hook %s is already applied or pending", hook.ID())
+			continue
+		}
+		history = append(history, upgradeHookRecord{
+			Hook:   hook.ID(),
+			From:   from,
+			To:     to,
+			Time:   time.Now(),
+			Status: upgradeHookPending,
+		})
+	}
+	return m.saveUpgradeHookHistory(name, history)
+}
+
+// runUpgradeHooks runs the pending upgrade hooks of the cluster with the
+// operator account, the result of every hook is recorded in the history and
+// the ones failed are kept pending, so they are resumed by upgrading to the
+// same version again
+func (m *Manager) runUpgradeHooks(name string, topo spec.Topology, opt operator.Options, offline bool) error {
+	history, err := m.loadUpgradeHookHistory(name)
+	if err != nil {
+		return err
+	}
+	pending := pendingUpgradeHooks(history)
+	if len(pending) == 0 {
+		return nil
+	}
+	cluster, ok := topo.(*spec.Specification)
+	if !ok {
+		return nil
+	}
+	hooks, err := bundledUpgradeHooks()
+	if err != nil {
+		return err
+	}
+	bundled := make(map[string]UpgradeHook)
+	for _, h := range hooks {
+		bundled[h.ID()] = h
+	}
+
+	var cred *spec.SQLCredential
+	if !opt.SkipUpgradeHooks && !offline {
+		if cred, err = m.specManager.SQLCredential(name, spec.OperatorCredentialFile); err != nil {
+			return err
+		}
+		if cred == nil {
+			return errUpgradeHookFailed.
+				New("The operator account of cluster %s is not found, it's required to run the upgrade hooks", name).
+				WithProperty(tui.SuggestionFromFormat("Please create it with '%s admin set-password %s --user operator' and upgrade to %s again, or skip the hooks with '--skip-hooks'",
+					tui.OsArgs0(), name, history[pending[0]].To))
+		}
+	}
+
+	for _, i := range pending {
+		record := &history[i]
+		hook, ok := bundled[record.Hook]
+		if !ok {
+			// the hook is removed from the bundle of this version of tiup
+			m.logger.Warnf("Upgrade hook %s is not bundled anymore, skipped", record.Hook)
+			record.Status = upgradeHookSkipped
+			record.Error = ""
+			record.Time = time.Now()
+			if err := m.saveUpgradeHookHistory(name, history); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var hookErr error
+		switch {
+		case opt.SkipUpgradeHooks:
+			record.Status = upgradeHookSkipped
+			m.logger.Warnf("Skipped upgrade hook %s", hook.ID())
+		case offline:
+			m.logger.Warnf("Upgrade hook %s is not run as the cluster is offline, please upgrade to %s again after the cluster is started", hook.ID(), record.To)
+			continue
+		default:
+			m.logger.Infof("Running upgrade hook %s", hook.ID())
+			hookErr = openTiDB(cluster, cred.User, cred.Password, func(db *sql.DB) error {
+				_, err := db.Exec(hook.SQL)
+				return err
+			})
+			record.Status = upgradeHookApplied
+			record.Error = ""
+			if hookErr != nil {
+				record.Status = upgradeHookFailed
+				record.Error = hookErr.Error()
+			}
+		}
+		record.Time = time.Now()
+
+		if err := m.saveUpgradeHookHistory(name, history); err != nil {
+			return err
+		}
+		if hookErr != nil {
+			return errUpgradeHookFailed.
+				Wrap(hookErr, "Upgrade hook %s failed", hook.ID()).
+				WithProperty(tui.SuggestionFromFormat("The cluster is upgraded to %s, please fix it and upgrade to %s again to resume the hooks, or skip them with '--skip-hooks'", record.To, record.To))
+		}
+	}
+	return nil
+}
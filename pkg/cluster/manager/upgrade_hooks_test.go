@@ -0,0 +1,106 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"os"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSelectUpgradeHooks(t *testing.T) {
+	hooks := []UpgradeHook{
+		{Version: "v6.1.0", Name: "002-stats"},
+		{Version: "v5.4.0", Name: "001-variables"},
+		{Version: "v6.1.0", Name: "001-variables"},
+		{Version: "v6.5.0", Name: "001-variables"},
+	}
+	ids := func(hooks []UpgradeHook) []string {
+		var ids []string
+		for _, h := range hooks {
+			ids = append(ids, h.ID())
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{
+		"v5.4.0/001-variables",
+		"v6.1.0/001-variables",
+		"v6.1.0/002-stats",
+	}, ids(selectUpgradeHooks(hooks, "v5.3.0", "v6.1.2")))
+	assert.Equal(t, []string{"v6.5.0/001-variables"}, ids(selectUpgradeHooks(hooks, "v6.1.0", "nightly")))
+	assert.Empty(t, selectUpgradeHooks(hooks, "v6.5.0", "v6.5.1"))
+	// a nightly build is ahead of all the versions
+	assert.Empty(t, selectUpgradeHooks(hooks, "nightly", "nightly"))
+
+	bundled, err := bundledUpgradeHooks()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, bundled)
+	for _, h := range bundled {
+		assert.NotEmpty(t, h.SQL, h.ID())
+	}
+}
+
+func TestResumeUpgradeHooks(t *testing.T) {
+	m := NewManager("tidb", spec.NewSpec(t.TempDir(), func() spec.Metadata { return &spec.ClusterMeta{} }), nil, logprinter.NewLogger(""))
+	assert.Nil(t, os.MkdirAll(m.specManager.Path("test"), 0755))
+	topo := new(spec.Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.140
+`), topo))
+	status := func() map[string]string {
+		history, err := m.loadUpgradeHookHistory("test")
+		assert.Nil(t, err)
+		status := make(map[string]string)
+		for _, r := range history {
+			status[r.Hook] = r.Status
+		}
+		return status
+	}
+
+	// nothing is planned when upgrading from a nightly build
+	assert.Nil(t, m.planUpgradeHooks("test", topo, "nightly", "nightly"))
+	assert.Empty(t, status())
+
+	// the hooks are recorded as pending once
+	assert.Nil(t, m.planUpgradeHooks("test", topo, "v5.0.0", "v5.1.0"))
+	assert.Nil(t, m.planUpgradeHooks("test", topo, "v5.0.0", "v5.1.0"))
+	history, err := m.loadUpgradeHookHistory("test")
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, "v5.1.0/001-flush-privileges", history[0].Hook)
+	assert.Equal(t, []int{0}, pendingUpgradeHooks(history))
+
+	// the hooks are kept pending if the cluster is offline
+	assert.Nil(t, m.runUpgradeHooks("test", topo, operator.Options{}, true))
+	assert.Equal(t, upgradeHookPending, status()["v5.1.0/001-flush-privileges"])
+
+	// the operator account is required to run the hooks
+	err = m.runUpgradeHooks("test", topo, operator.Options{}, false)
+	assert.True(t, errorx.IsOfType(err, errUpgradeHookFailed))
+	assert.Equal(t, upgradeHookPending, status()["v5.1.0/001-flush-privileges"])
+
+	assert.Nil(t, m.runUpgradeHooks("test", topo, operator.Options{SkipUpgradeHooks: true}, false))
+	assert.Equal(t, upgradeHookSkipped, status()["v5.1.0/001-flush-privileges"])
+	history, err = m.loadUpgradeHookHistory("test")
+	assert.Nil(t, err)
+	assert.Empty(t, pendingUpgradeHooks(history))
+}
@@ -16,6 +16,7 @@ package module
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 )
@@ -48,12 +49,15 @@ var (
 
 // UserModuleConfig is the configurations used to initialize a UserModule
 type UserModuleConfig struct {
-	Action string // add, del or modify user
-	Name   string // username
-	Group  string // group name
-	Home   string // home directory of user
-	Shell  string // login shell of the user
-	Sudoer bool   // when true, the user will be added to sudoers list
+	Action string   // add, del or modify user
+	Name   string   // username
+	Group  string   // group name
+	Home   string   // home directory of user
+	Shell  string   // login shell of the user
+	Sudoer bool     // when true, the user will be added to sudoers list
+	UID    int      // UID of the user, assigned by the system if 0
+	GID    int      // GID of the group, assigned by the system if 0
+	Groups []string // supplementary groups of the user
 }
 
 // UserModule is the module used to control systemd units
@@ -90,6 +94,22 @@ func NewUserModule(config UserModuleConfig) *UserModule {
 
 		// groupadd -f <group-name>
 		groupAdd := fmt.Sprintf("%s -f %s", groupaddCmd, config.Group)
+		if config.GID != 0 {
+			// -f picks another GID silently if the GID is in use, so the
+			// group is only created if it doesn't exist
+			groupAdd = fmt.Sprintf("(getent group %[2]s > /dev/null || %[1]s -g %[3]d %[2]s)",
+				groupaddCmd, config.Group, config.GID)
+		}
+		for _, group := range config.Groups {
+			groupAdd = fmt.Sprintf("%s && %s -f %s", groupAdd, groupaddCmd, group)
+		}
+
+		if config.UID != 0 {
+			cmd = fmt.Sprintf("%s -u %d", cmd, config.UID)
+		}
+		if len(config.Groups) > 0 {
+			cmd = fmt.Sprintf("%s -G %s", cmd, strings.Join(config.Groups, ","))
+		}
 
 		// useradd -g <group-name> <user-name>
 		cmd = fmt.Sprintf("%s -g %s %s", cmd, config.Group, config.Name)
@@ -65,11 +65,11 @@ func NewWaitFor(c WaitForConfig) *WaitFor {
 func (w *WaitFor) Execute(ctx context.Context, e ctxt.Executor) (err error) {
 	pattern := []byte(fmt.Sprintf(":%d ", w.c.Port))
 
-	retryOpt := utils.RetryOption{
-		Delay:   w.c.Sleep,
-		Timeout: w.c.Timeout,
+	waitOpt := utils.WaitOption{
+		Interval: w.c.Sleep,
+		Timeout:  w.c.Timeout,
 	}
-	if err := utils.Retry(func() error {
+	if err := utils.Wait(ctx, func() error {
 		// only listing TCP ports
 		stdout, _, err := e.Execute(ctx, "ss -ltn", false)
 		if err == nil {
@@ -86,7 +86,7 @@ func (w *WaitFor) Execute(ctx context.Context, e ctxt.Executor) (err error) {
 			return errors.New("still waiting for port state to be satisfied")
 		}
 		return err
-	}, retryOpt); err != nil {
+	}, waitOpt); err != nil {
 		zap.L().Debug("retry error", zap.Error(err))
 		return errors.Errorf("timed out waiting for port %d to be %s after %s", w.c.Port, w.c.State, w.c.Timeout)
 	}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/checkpoint"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	"github.com/pingcap/tiup/pkg/cluster/module"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
@@ -97,10 +98,25 @@ func Enable(
 		}
 		hosts = append(hosts, host)
 	}
+	hosts = append(hosts, monitoredHosts(cluster, options)...)
 
 	return EnableMonitored(ctx, hosts, noAgentHosts, monitoredOptions, options.OptTimeout, isEnable)
 }
 
+// monitoredHosts returns the extra hosts only running the monitoring agents,
+// they are not touched if the operation is limited to some roles or nodes.
+func monitoredHosts(cluster spec.Topology, options Options) []string {
+	if len(options.Roles) > 0 || len(options.Nodes) > 0 {
+		return nil
+	}
+
+	hosts := make([]string, 0)
+	for _, h := range spec.MonitoredHosts(cluster) {
+		hosts = append(hosts, h.Host)
+	}
+	return hosts
+}
+
 // Start the cluster.
 func Start(
 	ctx context.Context,
@@ -126,6 +142,7 @@ func Start(
 	for _, comp := range components {
 		insts := FilterInstance(comp.Instances(), nodeFilter)
 		err := StartComponent(ctx, insts, noAgentHosts, options, tlsCfg)
+		api.InvalidateCache(ctx)
 		if err != nil {
 			return errors.Annotatef(err, "failed to start %s", comp.Name())
 		}
@@ -137,12 +154,12 @@ func Start(
 			}
 
 			if restoreLeader {
-				rIns, ok := inst.(spec.RollingUpdateInstance)
-				if ok {
+				if spec.IsRollingUpdateInstance(inst) {
+					inst := inst
 					// checkpoint must be in a new context
 					nctx := checkpoint.NewContext(ctx)
 					errg.Go(func() error {
-						err := rIns.PostRestart(nctx, cluster, tlsCfg)
+						err := spec.PostRestart(nctx, inst, cluster, int(options.APITimeout), tlsCfg)
 						if err != nil && !options.Force {
 							return err
 						}
@@ -164,6 +181,7 @@ func Start(
 	for host := range uniqueHosts {
 		hosts = append(hosts, host)
 	}
+	hosts = append(hosts, monitoredHosts(cluster, options)...)
 	return StartMonitored(ctx, hosts, noAgentHosts, monitoredOptions, options.OptTimeout)
 }
 
@@ -224,6 +242,7 @@ func Stop(
 		}
 		hosts = append(hosts, host)
 	}
+	hosts = append(hosts, monitoredHosts(cluster, options)...)
 
 	if err := StopMonitored(ctx, hosts, noAgentHosts, monitoredOptions, options.OptTimeout); err != nil && !options.Force {
 		return err
@@ -353,18 +372,18 @@ func systemctlMonitor(ctx context.Context, hosts []string, noAgentHosts set.Stri
 	return nil
 }
 
-func restartInstance(ctx context.Context, ins spec.Instance, timeout uint64, tlsCfg *tls.Config) error {
+func restartInstance(ctx context.Context, ins spec.Instance, timeout uint64, diagLines int, tlsCfg *tls.Config) error {
 	e := ctxt.GetInner(ctx).Get(ins.GetHost())
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 	logger.Infof("\tRestarting instance %s", ins.ID())
 
 	if err := systemctl(ctx, e, ins.ServiceName(), "restart", timeout); err != nil {
-		return toFailedActionError(err, "restart", ins.GetHost(), ins.ServiceName(), ins.LogDir())
+		return withDiagnostics(ctx, e, ins, diagLines, toFailedActionError(err, "restart", ins.GetHost(), ins.ServiceName(), ins.LogDir()))
 	}
 
 	// Check ready.
 	if err := ins.Ready(ctx, e, timeout, tlsCfg); err != nil {
-		return toFailedActionError(err, "restart", ins.GetHost(), ins.ServiceName(), ins.LogDir())
+		return withDiagnostics(ctx, e, ins, diagLines, toFailedActionError(err, "restart", ins.GetHost(), ins.ServiceName(), ins.LogDir()))
 	}
 
 	logger.Infof("\tRestart instance %s success", ins.ID())
@@ -392,18 +411,18 @@ func enableInstance(ctx context.Context, ins spec.Instance, timeout uint64, isEn
 	return nil
 }
 
-func startInstance(ctx context.Context, ins spec.Instance, timeout uint64, tlsCfg *tls.Config) error {
+func startInstance(ctx context.Context, ins spec.Instance, timeout uint64, diagLines int, tlsCfg *tls.Config) error {
 	e := ctxt.GetInner(ctx).Get(ins.GetHost())
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 	logger.Infof("\tStarting instance %s", ins.ID())
 
 	if err := systemctl(ctx, e, ins.ServiceName(), "start", timeout); err != nil {
-		return toFailedActionError(err, "start", ins.GetHost(), ins.ServiceName(), ins.LogDir())
+		return withDiagnostics(ctx, e, ins, diagLines, toFailedActionError(err, "start", ins.GetHost(), ins.ServiceName(), ins.LogDir()))
 	}
 
 	// Check ready.
 	if err := ins.Ready(ctx, e, timeout, tlsCfg); err != nil {
-		return toFailedActionError(err, "start", ins.GetHost(), ins.ServiceName(), ins.LogDir())
+		return withDiagnostics(ctx, e, ins, diagLines, toFailedActionError(err, "start", ins.GetHost(), ins.ServiceName(), ins.LogDir()))
 	}
 
 	logger.Infof("\tStart instance %s success", ins.ID())
@@ -531,7 +550,7 @@ func StartComponent(ctx context.Context, instances []spec.Instance, noAgentHosts
 			if err := ins.PrepareStart(nctx, tlsCfg); err != nil {
 				return err
 			}
-			return startInstance(nctx, ins, options.OptTimeout, tlsCfg)
+			return startInstance(nctx, ins, options.OptTimeout, options.DiagnoseLines, tlsCfg)
 		})
 	}
 
@@ -543,7 +562,7 @@ func serialStartInstances(ctx context.Context, instances []spec.Instance, option
 		if err := ins.PrepareStart(ctx, tlsCfg); err != nil {
 			return err
 		}
-		if err := startInstance(ctx, ins, options.OptTimeout, tlsCfg); err != nil {
+		if err := startInstance(ctx, ins, options.OptTimeout, options.DiagnoseLines, tlsCfg); err != nil {
 			return err
 		}
 	}
@@ -597,17 +616,18 @@ func StopComponent(ctx context.Context,
 			nctx := checkpoint.NewContext(ctx)
 			if !forceStop {
 				// when scale-in cdc node, each node should be stopped one by one.
-				cdc, ok := ins.(spec.RollingUpdateInstance)
-				if !ok {
-					panic("cdc should support rolling upgrade, but not")
+				if !spec.IsRollingUpdateInstance(ins) {
+					return errors.Errorf("cdc instance %s should support rolling upgrade, but not", ins.ID())
 				}
-				err := cdc.PreRestart(nctx, topo, int(options.APITimeout), tlsCfg)
+				err := spec.PreRestart(nctx, ins, topo, int(options.APITimeout), tlsCfg)
 				if err != nil {
 					// this should never hit, since all errors swallowed to trigger hard stop.
 					return err
 				}
 			}
-			if err := stopInstance(nctx, ins, options.OptTimeout); err != nil {
+			err := stopInstance(nctx, ins, options.OptTimeout)
+			api.InvalidateCache(nctx)
+			if err != nil {
 				return err
 			}
 			// continue here, to skip the logic below.
@@ -620,15 +640,12 @@ func StopComponent(ctx context.Context,
 		nctx := checkpoint.NewContext(ctx)
 		errg.Go(func() error {
 			if evictLeader {
-				rIns, ok := ins.(spec.RollingUpdateInstance)
-				if ok {
-					err := rIns.PreRestart(nctx, topo, int(options.APITimeout), tlsCfg)
-					if err != nil {
-						return err
-					}
+				if err := spec.PreRestart(nctx, ins, topo, int(options.APITimeout), tlsCfg); err != nil {
+					return err
 				}
 			}
 			err := stopInstance(nctx, ins, options.OptTimeout)
+			api.InvalidateCache(nctx)
 			if err != nil {
 				return err
 			}
@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AstroProfundis/sysinfo"
 	"github.com/pingcap/tidb-insight/collector/insight"
@@ -38,6 +39,13 @@ type CheckOptions struct {
 	EnableMem  bool
 	EnableDisk bool
 
+	// the network matrix between TiKV and PD hosts
+	EnableNetwork   bool
+	EnableBandwidth bool
+	NetworkMaxPairs int           // max pairs of hosts measured, 0 for all
+	NetworkMaxRTT   time.Duration // the max RTT allowed
+	NetworkMinMbps  int           // the min bandwidth allowed, in Mbits/s
+
 	// pre-defined goups of checks
 	// GroupMinimal bool // a minimal set of checks
 }
@@ -66,6 +74,10 @@ var (
 	CheckNameDirPermission = "permission"
 	CheckNameDirExist      = "exist"
 	CheckNameTimeZone      = "timezone"
+	CheckNameNUMA          = "numa"
+	CheckNameHostState     = "host-state"
+	CheckNameNetMatrix     = "network-matrix"
+	CheckNameCloudLabels   = "cloud-labels"
 )
 
 // CheckResult is the result of a check
@@ -74,6 +86,14 @@ type CheckResult struct {
 	Err  error  // An embedded error
 	Warn bool   // The check didn't pass, but not a big problem
 	Msg  string // A message or description
+
+	NUMABinding *NUMABinding // The numa_node suggested by the NUMA check
+}
+
+// NUMABinding is the numa_node suggested for an instance
+type NUMABinding struct {
+	Instance string // ID of the instance
+	Node     string // The value of numa_node
 }
 
 // Error implements the error interface
@@ -847,6 +867,104 @@ func CheckTHP(ctx context.Context, e ctxt.Executor) *CheckResult {
 	return result
 }
 
+// THPServiceName is the systemd unit disabling THP on boot
+const THPServiceName = "disable-transparent-hugepages.service"
+
+// CheckHostState checks if the managed system settings are still applied on
+// the host persistently. The item to repair is stored in the Msg of the failed
+// results as "disable_swap", "swappiness <value>" or "disable_thp".
+func CheckHostState(ctx context.Context, e ctxt.Executor, state spec.HostState) []*CheckResult {
+	var results []*CheckResult
+	if state.IsEmpty() {
+		return results
+	}
+
+	execute := func(cmd string) (string, error) {
+		stdout, stderr, err := e.Execute(ctx, cmd, true)
+		if err != nil {
+			return "", fmt.Errorf("%w %s", err, stderr)
+		}
+		return strings.TrimSpace(string(stdout)), nil
+	}
+
+	if state.DisableSwap {
+		active, err := execute("tail -n +2 /proc/swaps")
+		persisted, err2 := execute("grep -E '^[^#[:space:]]+[[:space:]]+[^[:space:]]+[[:space:]]+swap[[:space:]]' /etc/fstab || true")
+		switch {
+		case err != nil || err2 != nil:
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Err:  fmt.Errorf("failed to check swap, %v %v", err, err2),
+			})
+		case active != "":
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Err:  fmt.Errorf("swap is enabled, but disable_swap is set"),
+				Msg:  "disable_swap",
+			})
+		case persisted != "":
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Err:  fmt.Errorf("swap is configured in /etc/fstab, but disable_swap is set"),
+				Msg:  "disable_swap",
+			})
+		default:
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Msg:  "swap is disabled",
+			})
+		}
+	}
+
+	if state.Swappiness != nil {
+		out, err := execute("sysctl -n vm.swappiness")
+		val, _ := strconv.Atoi(out)
+		switch {
+		case err != nil:
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Err:  fmt.Errorf("failed to check vm.swappiness, %s", err),
+			})
+		case val != *state.Swappiness:
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Err:  fmt.Errorf("vm.swappiness = %d, should be %d", val, *state.Swappiness),
+				Msg:  fmt.Sprintf("swappiness %d", *state.Swappiness),
+			})
+		default:
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Msg:  fmt.Sprintf("vm.swappiness = %d", val),
+			})
+		}
+	}
+
+	if state.DisableTHP {
+		// is-enabled exits with non-zero if the unit is not enabled
+		out, _ := execute(fmt.Sprintf("systemctl is-enabled %s 2>/dev/null || true", THPServiceName))
+		if out != "enabled" {
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Err:  fmt.Errorf("%s is not enabled, but disable_thp is set", THPServiceName),
+				Msg:  "disable_thp",
+			})
+		} else if thp := CheckTHP(ctx, e); thp.Err != nil {
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Err:  thp.Err,
+				Msg:  "disable_thp",
+			})
+		} else {
+			results = append(results, &CheckResult{
+				Name: CheckNameHostState,
+				Msg:  "THP is disabled persistently",
+			})
+		}
+	}
+
+	return results
+}
+
 // CheckJRE checks if java command is available for TiSpark nodes
 func CheckJRE(ctx context.Context, e ctxt.Executor, host string, topo *spec.Specification) []*CheckResult {
 	var results []*CheckResult
@@ -952,9 +1070,24 @@ func CheckDirIsExist(ctx context.Context, e ctxt.Executor, path string) []*Check
 	return results
 }
 
-// CheckTimeZone performs checks if time zone is the same
+// CheckTimeZone performs checks if time zone is the same, the time zones
+// specified in the topology are checked to exist in the tzdata of the host
+// instead if none of the instances on the host uses the system one
 func CheckTimeZone(ctx context.Context, topo *spec.Specification, host string, rawData []byte) []*CheckResult {
-	var results []*CheckResult
+	results := checkTimeZoneData(ctx, topo, host)
+	if r := checkMixedTimeZones(topo, host); r != nil {
+		results = append(results, r)
+	}
+	systemTZ := false
+	topo.IterInstance(func(inst spec.Instance) {
+		if inst.GetHost() == host && spec.InstanceTimeZone(inst, topo.GlobalOptions) == "" {
+			systemTZ = true
+		}
+	})
+	if !systemTZ {
+		return results
+	}
+
 	var insightInfo, pd0insightInfo insight.InsightInfo
 	if err := json.Unmarshal(rawData, &insightInfo); err != nil {
 		return append(results, &CheckResult{
@@ -971,7 +1104,7 @@ func CheckTimeZone(ctx context.Context, topo *spec.Specification, host string, r
 	}
 	// skip compare with itself
 	if topo.PDServers[0].Host == host {
-		return nil
+		return results
 	}
 	pd0stdout, _, _ := ctxt.GetInner(ctx).GetOutputs(topo.PDServers[0].Host)
 	if err := json.Unmarshal(pd0stdout, &pd0insightInfo); err != nil {
@@ -997,3 +1130,244 @@ func CheckTimeZone(ctx context.Context, topo *spec.Specification, host string, r
 	}
 	return results
 }
+
+// checkTimeZoneData checks the time zones specified of the instances on
+// the host exist in its tzdata
+func checkTimeZoneData(ctx context.Context, topo *spec.Specification, host string) []*CheckResult {
+	zones := topo.TimeZones(host)
+	if len(zones) == 0 {
+		return nil
+	}
+	e, ok := ctxt.GetInner(ctx).GetExecutor(host)
+	if !ok {
+		return []*CheckResult{{Name: CheckNameTimeZone, Err: fmt.Errorf("no executor found for %s", host)}}
+	}
+	cmd := fmt.Sprintf(`for tz in %s; do [ -f "%s/$tz" ] || echo "$tz"; done`,
+		strings.Join(zones, " "), spec.TimeZoneDataDir)
+	stdout, stderr, err := e.Execute(ctx, cmd, false)
+	if err != nil {
+		return []*CheckResult{{Name: CheckNameTimeZone, Err: fmt.Errorf("%w %s", err, stderr)}}
+	}
+	missing := strings.Fields(string(stdout))
+	if len(missing) > 0 {
+		return []*CheckResult{{
+			Name: CheckNameTimeZone,
+			Err: fmt.Errorf("time zone %s is not found in %s, please install tzdata",
+				strings.Join(missing, ", "), spec.TimeZoneDataDir),
+		}}
+	}
+	return []*CheckResult{{
+		Name: CheckNameTimeZone,
+		Msg:  "time zone " + strings.Join(zones, ", ") + " is available",
+	}}
+}
+
+// checkMixedTimeZones warns if the instances are in different time zones,
+// which makes the timestamps in the logs confusing and may cause bugs of TTL.
+// It's of the whole topology, so it's only reported with the first host.
+func checkMixedTimeZones(topo *spec.Specification, host string) *CheckResult {
+	first := ""
+	spec.IterHost(topo, func(inst spec.Instance) {
+		if first == "" {
+			first = inst.GetHost()
+		}
+	})
+	if host != first {
+		return nil
+	}
+	mixed := topo.MixedTimeZones()
+	if mixed == nil {
+		return nil
+	}
+	zones := make([]string, 0, len(mixed))
+	for tz, instances := range mixed {
+		if tz == "" {
+			tz = "system"
+		}
+		zones = append(zones, fmt.Sprintf("%s (%d instances)", tz, len(instances)))
+	}
+	sort.Strings(zones)
+	return &CheckResult{
+		Name: CheckNameTimeZone,
+		Warn: true,
+		Err:  fmt.Errorf("instances are in mixed time zones: %s", strings.Join(zones, ", ")),
+	}
+}
+
+// NUMANode is a NUMA node of the host
+type NUMANode struct {
+	ID     int
+	CPUs   []int
+	SizeMB int
+}
+
+// ParseNUMAHardware parses the output of `numactl --hardware`, e.g.:
+//
+//	available: 2 nodes (0-1)
+//	node 0 cpus: 0 1 2 3
+//	node 0 size: 32147 MB
+//	node 0 free: 30512 MB
+//	node 1 cpus: 4 5 6 7
+//	node 1 size: 32254 MB
+//	node 1 free: 31025 MB
+func ParseNUMAHardware(rawData []byte) map[int]*NUMANode {
+	nodes := make(map[int]*NUMANode)
+	for _, line := range strings.Split(string(rawData), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "node" {
+			continue
+		}
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue // the "node distances:" line
+		}
+		node, ok := nodes[id]
+		if !ok {
+			node = &NUMANode{ID: id}
+			nodes[id] = node
+		}
+		switch fields[2] {
+		case "cpus:":
+			for _, f := range fields[3:] {
+				if cpu, err := strconv.Atoi(f); err == nil {
+					node.CPUs = append(node.CPUs, cpu)
+				}
+			}
+		case "size:":
+			if len(fields) > 3 {
+				node.SizeMB, _ = strconv.Atoi(fields[3])
+			}
+		}
+	}
+	return nodes
+}
+
+// parseNUMANodes parses the numa_node value of an instance, which is passed
+// to numactl as is, e.g. "0", "0,1" or "0-1"
+func parseNUMANodes(numa string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(numa, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid NUMA node '%s'", part)
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil || end < start {
+				return nil, fmt.Errorf("invalid NUMA node range '%s'", part)
+			}
+		}
+		for id := start; id <= end; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// CheckNUMA validates the numa_node of the instances on the host against its
+// NUMA topology, and suggests bindings for the TiKV instances without one if
+// there are multiple TiKV instances on the host. The suggestion is stored in
+// the NUMABinding of the result.
+func CheckNUMA(host string, topo *spec.Specification, rawData []byte) []*CheckResult {
+	var results []*CheckResult
+
+	nodes := ParseNUMAHardware(rawData)
+	if len(nodes) == 0 {
+		// numactl is checked with the packages
+		return results
+	}
+
+	usage := make(map[int]int) // node -> number of instances bound to it
+	tikvCount := 0
+	var unbound []spec.Instance
+	topo.IterInstance(func(inst spec.Instance) {
+		if inst.GetHost() != host {
+			return
+		}
+		if inst.ComponentName() == spec.ComponentTiKV {
+			tikvCount++
+		}
+
+		numa := inst.NumaNode()
+		if numa == "" {
+			if inst.ComponentName() == spec.ComponentTiKV {
+				unbound = append(unbound, inst)
+			}
+			return
+		}
+
+		ids, err := parseNUMANodes(numa)
+		if err != nil {
+			results = append(results, &CheckResult{
+				Name: CheckNameNUMA,
+				Err:  fmt.Errorf("numa_node of %s is invalid, %s", inst.ID(), err),
+			})
+			return
+		}
+		valid := true
+		for _, id := range ids {
+			node, ok := nodes[id]
+			switch {
+			case !ok:
+				err = fmt.Errorf("numa_node %d of %s does not exist on the host", id, inst.ID())
+			case len(node.CPUs) == 0:
+				err = fmt.Errorf("numa_node %d of %s has no CPU cores", id, inst.ID())
+			case node.SizeMB == 0:
+				err = fmt.Errorf("numa_node %d of %s has no local memory", id, inst.ID())
+			default:
+				usage[id]++
+				continue
+			}
+			valid = false
+			results = append(results, &CheckResult{
+				Name: CheckNameNUMA,
+				Err:  err,
+			})
+		}
+		if valid {
+			results = append(results, &CheckResult{
+				Name: CheckNameNUMA,
+				Msg:  fmt.Sprintf("numa_node %s of %s is valid", numa, inst.ID()),
+			})
+		}
+	})
+
+	// only suggest bindings for hosts running multiple TiKV instances
+	available := make([]int, 0, len(nodes))
+	for id, node := range nodes {
+		if len(node.CPUs) > 0 && node.SizeMB > 0 {
+			available = append(available, id)
+		}
+	}
+	if tikvCount < 2 || len(available) < 2 {
+		return results
+	}
+	sort.Ints(available)
+
+	for _, inst := range unbound {
+		// bind to the least used node
+		best := available[0]
+		for _, id := range available[1:] {
+			if usage[id] < usage[best] {
+				best = id
+			}
+		}
+		usage[best]++
+		results = append(results, &CheckResult{
+			Name: CheckNameNUMA,
+			Err: fmt.Errorf(
+				"%d TiKV instances on the host without NUMA binding, numa_node %d is suggested for %s",
+				tikvCount, best, inst.ID(),
+			),
+			Warn: true,
+			Msg:  fmt.Sprintf("%s numa_node %d", inst.ID(), best),
+			NUMABinding: &NUMABinding{
+				Instance: inst.ID(),
+				Node:     strconv.Itoa(best),
+			},
+		})
+	}
+
+	return results
+}
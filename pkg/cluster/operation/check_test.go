@@ -0,0 +1,49 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCheckMixedTimeZones(t *testing.T) {
+	topo := new(spec.Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+global:
+  time_zone:
+    default: UTC
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.2
+cdc_servers:
+  - host: 172.16.5.3
+    tz: Asia/Shanghai
+`), topo))
+
+	// the warning of the topology is only reported with the first host
+	r := checkMixedTimeZones(topo, "172.16.5.1")
+	assert.NotNil(t, r)
+	assert.True(t, r.Warn)
+	assert.EqualError(t, r.Err, "instances are in mixed time zones: Asia/Shanghai (1 instances), UTC (2 instances)")
+	assert.Nil(t, checkMixedTimeZones(topo, "172.16.5.2"))
+	assert.Nil(t, checkMixedTimeZones(topo, "172.16.5.3"))
+
+	topo.CDCServers[0].TZ = ""
+	assert.Nil(t, checkMixedTimeZones(topo, "172.16.5.1"))
+}
@@ -67,6 +67,16 @@ func Destroy(
 		}
 	}
 
+	// the extra hosts only running the monitoring agents
+	for _, h := range spec.MonitoredHosts(cluster) {
+		if cluster.GetMonitoredOptions() != nil {
+			if err := DestroyMonitoredHost(ctx, h.Host, cluster.GetMonitoredOptions(), options.OptTimeout); err != nil && !options.Force {
+				return err
+			}
+		}
+		instCount[h.Host] = 0
+	}
+
 	gOpts := cluster.BaseTopo().GlobalOptions
 
 	// Delete all global deploy directory
@@ -242,11 +252,21 @@ func DeletePublicKey(ctx context.Context, host string) error {
 
 // DestroyMonitored destroy the monitored service.
 func DestroyMonitored(ctx context.Context, inst spec.Instance, options *spec.MonitoredOptions, timeout uint64) error {
-	e := ctxt.GetInner(ctx).Get(inst.GetHost())
+	return destroyMonitoredHost(ctx, inst.GetHost(), inst.IsImported(), inst.InstanceName(), options, timeout)
+}
+
+// DestroyMonitoredHost destroy the monitored service on a host not running
+// any instance, i.e. one of the monitored hosts.
+func DestroyMonitoredHost(ctx context.Context, host string, options *spec.MonitoredOptions, timeout uint64) error {
+	return destroyMonitoredHost(ctx, host, false, host, options, timeout)
+}
+
+func destroyMonitoredHost(ctx context.Context, host string, imported bool, name string, options *spec.MonitoredOptions, timeout uint64) error {
+	e := ctxt.GetInner(ctx).Get(host)
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 
-	logger.Infof("Destroying monitored %s", inst.GetHost())
-	logger.Infof("\tDestroying instance %s", inst.GetHost())
+	logger.Infof("Destroying monitored %s", host)
+	logger.Infof("\tDestroying instance %s", host)
 
 	// Stop by systemd.
 	delPaths := make([]string, 0)
@@ -256,11 +276,11 @@ func DestroyMonitored(ctx context.Context, inst spec.Instance, options *spec.Mon
 
 	// In TiDB-Ansible, deploy dir are shared by all components on the same
 	// host, so not deleting it.
-	if !inst.IsImported() {
+	if !imported {
 		delPaths = append(delPaths, options.DeployDir)
 	} else {
 		logger.Warnf("Monitored deploy dir %s not deleted for TiDB-Ansible imported instance %s.",
-			options.DeployDir, inst.InstanceName())
+			options.DeployDir, name)
 	}
 
 	delPaths = append(delPaths, fmt.Sprintf("/etc/systemd/system/%s-%d.service", spec.ComponentNodeExporter, options.NodeExporterPort))
@@ -283,21 +303,21 @@ func DestroyMonitored(ctx context.Context, inst spec.Instance, options *spec.Mon
 	}
 
 	if err != nil {
-		return errors.Annotatef(err, "failed to destroy monitored: %s", inst.GetHost())
+		return errors.Annotatef(err, "failed to destroy monitored: %s", host)
 	}
 
 	if err := spec.PortStopped(ctx, e, options.NodeExporterPort, timeout); err != nil {
-		str := fmt.Sprintf("%s failed to destroy node exportoer: %s", inst.GetHost(), err)
+		str := fmt.Sprintf("%s failed to destroy node exportoer: %s", host, err)
 		logger.Errorf(str)
 		return errors.Annotatef(err, str)
 	}
 	if err := spec.PortStopped(ctx, e, options.BlackboxExporterPort, timeout); err != nil {
-		str := fmt.Sprintf("%s failed to destroy blackbox exportoer: %s", inst.GetHost(), err)
+		str := fmt.Sprintf("%s failed to destroy blackbox exportoer: %s", host, err)
 		logger.Errorf(str)
 		return errors.Annotatef(err, str)
 	}
 
-	logger.Infof("Destroy monitored on %s success", inst.GetHost())
+	logger.Infof("Destroy monitored on %s success", host)
 
 	return nil
 }
@@ -492,7 +512,7 @@ func DestroyClusterTombstone(
 		pdEndpoints = cluster.GetPDList()
 	}
 
-	var pdClient = api.NewPDClient(ctx, pdEndpoints, 10*time.Second, tlsCfg)
+	var pdClient = api.NewPDClient(ctx, pdEndpoints, api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
 
 	tcpProxy := proxy.GetTCPProxy()
 	if tcpProxy != nil {
@@ -500,7 +520,7 @@ func DestroyClusterTombstone(
 		defer tcpProxy.Close(closeC)
 		pdEndpoints = tcpProxy.GetEndpoints()
 	}
-	binlogClient, err := api.NewBinlogClient(pdEndpoints, 5*time.Second, tlsCfg)
+	binlogClient, err := api.NewBinlogClient(pdEndpoints, api.RequestTimeout(ctx, 5*time.Second), tlsCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -541,7 +561,7 @@ func DestroyClusterTombstone(
 
 		id := s.Host + ":" + strconv.Itoa(s.Port)
 
-		tombstone, err := pdClient.IsTombStone(id)
+		tombstone, err := pdClient.IsTombStone(ctx, id)
 		if err != nil {
 			return nil, err
 		}
@@ -571,7 +591,7 @@ func DestroyClusterTombstone(
 
 		id := s.Host + ":" + strconv.Itoa(s.FlashServicePort)
 
-		tombstone, err := pdClient.IsTombStone(id)
+		tombstone, err := pdClient.IsTombStone(ctx, id)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,112 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alessio/shellescape"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/localdata"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"go.uber.org/zap"
+)
+
+const (
+	// the count of the latest log files of the instance harvested
+	diagnoseLogFiles = 3
+	// the core dumps created within the window are considered fresh
+	diagnoseCoreWindow = time.Hour
+)
+
+// diagnoseScript prints the last lines of the latest logs, the journal of the
+// service and the metadata of the fresh core dumps of the instance, every
+// part is optional as the tools may be missing on the host
+func diagnoseScript(ins spec.Instance, lines int) string {
+	minutes := int(diagnoseCoreWindow.Minutes())
+	logDir := shellescape.Quote(ins.LogDir())
+	service := shellescape.Quote(ins.ServiceName())
+	parts := []string{
+		fmt.Sprintf(`echo "### logs in "%s`, logDir),
+		fmt.Sprintf(`ls -t %s/*.log 2>/dev/null | head -n %d | while IFS= read -r f; do echo "--- $f"; tail -n %d "$f"; done`,
+			logDir, diagnoseLogFiles, lines),
+		fmt.Sprintf(`echo "### journal of "%s`, service),
+		fmt.Sprintf(`journalctl -u %s -n %d --no-pager 2>&1`, service, lines),
+		`echo "### core dumps"`,
+		fmt.Sprintf(`coredumpctl list --no-pager --since=-%dmin 2>/dev/null`, minutes),
+		fmt.Sprintf(`find %s -maxdepth 2 -type f -name 'core*' -mmin -%d -exec ls -l {} \; 2>/dev/null`,
+			shellescape.Quote(ins.DeployDir()), minutes),
+		"true",
+	}
+	return strings.Join(parts, "; ")
+}
+
+// diagnosticsDir returns the dir on the control machine the diagnostics are
+// saved to, it's next to the debug logs
+func diagnosticsDir() string {
+	logDir := os.Getenv(localdata.EnvNameLogPath)
+	if logDir == "" {
+		logDir = localdata.InitProfile().Path("logs")
+	}
+	return filepath.Join(logDir, "diagnostics")
+}
+
+// harvestDiagnostics fetches the diagnostics of the instance failed to come
+// up to the control machine, and returns the path of the file saved
+func harvestDiagnostics(ctx context.Context, e ctxt.Executor, ins spec.Instance, lines int) (string, error) {
+	stdout, stderr, err := e.Execute(ctx, diagnoseScript(ins, lines), true)
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to collect diagnostics: %s", stderr)
+	}
+	zap.L().Debug("Diagnostics of instance", zap.String("instance", ins.ID()), zap.ByteString("output", stdout))
+
+	dir := diagnosticsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.AddStack(err)
+	}
+	name := fmt.Sprintf("%s-%s.log",
+		strings.NewReplacer(":", "-", "/", "-").Replace(ins.ID()),
+		time.Now().Format("2006-01-02-15-04-05"),
+	)
+	path := filepath.Join(dir, name)
+	header := fmt.Sprintf("# %s %s on %s, collected at %s\n", ins.Role(), ins.ID(), ins.GetHost(), time.Now().Format(time.RFC3339))
+	if err := os.WriteFile(path, append([]byte(header), stdout...), 0644); err != nil {
+		return "", errors.AddStack(err)
+	}
+	return path, nil
+}
+
+// withDiagnostics attaches the diagnostics of the instance to the error of
+// the failed action, the error is returned as is if the harvesting is
+// disabled or fails
+func withDiagnostics(ctx context.Context, e ctxt.Executor, ins spec.Instance, lines int, err error) error {
+	if lines <= 0 {
+		return err
+	}
+	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+	path, herr := harvestDiagnostics(ctx, e, ins, lines)
+	if herr != nil {
+		logger.Warnf("\tFailed to harvest diagnostics of %s: %s", ins.ID(), herr)
+		return err
+	}
+	logger.Warnf("\tDiagnostics of %s are saved to %s", ins.ID(), path)
+	return errors.Annotatef(err, "diagnostics of %s (logs, journal and core dumps) are saved to %s", ins.ID(), path)
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/localdata"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+type diagnoseExecutor struct {
+	cmds   []string
+	stdout string
+	err    error
+}
+
+func (e *diagnoseExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.cmds = append(e.cmds, cmd)
+	return []byte(e.stdout), nil, e.err
+}
+
+func (e *diagnoseExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	return nil
+}
+
+func TestWithDiagnostics(t *testing.T) {
+	t.Setenv(localdata.EnvNameLogPath, t.TempDir())
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, logprinter.NewLogger(""))
+
+	topo := new(spec.Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+global:
+  user: tidb
+  deploy_dir: "/tidb deploy"
+tidb_servers:
+  - host: 172.16.5.140
+`), topo))
+	var ins spec.Instance
+	topo.IterInstance(func(inst spec.Instance) {
+		ins = inst
+	})
+	actionErr := errors.New("failed to start")
+
+	// the harvesting is disabled
+	e := &diagnoseExecutor{}
+	assert.Equal(t, actionErr, withDiagnostics(ctx, e, ins, 0, actionErr))
+	assert.Empty(t, e.cmds)
+
+	// the paths are quoted in the script
+	e = &diagnoseExecutor{stdout: "### logs in /tidb deploy/tidb-4000/log"}
+	err := withDiagnostics(ctx, e, ins, 10, actionErr)
+	assert.Equal(t, actionErr, errors.Cause(err))
+	assert.Len(t, e.cmds, 1)
+	assert.Contains(t, e.cmds[0], `ls -t '/tidb deploy/tidb-4000/log'/*.log`)
+	assert.Contains(t, e.cmds[0], `find '/tidb deploy/tidb-4000' -maxdepth 2`)
+	assert.Contains(t, e.cmds[0], "journalctl -u tidb-4000.service -n 10")
+
+	idx := strings.LastIndex(err.Error(), " are saved to ")
+	assert.NotEqual(t, -1, idx)
+	path := strings.TrimSuffix(err.Error()[idx+len(" are saved to "):], ": failed to start")
+	data, rerr := os.ReadFile(path)
+	assert.Nil(t, rerr)
+	assert.Contains(t, string(data), "### logs in /tidb deploy/tidb-4000/log")
+
+	// the error is returned as is if the harvesting fails
+	e = &diagnoseExecutor{err: errors.New("connection refused")}
+	assert.Equal(t, actionErr, withDiagnostics(ctx, e, ins, 10, actionErr))
+}
@@ -35,6 +35,7 @@ type Options struct {
 	SSHTimeout          uint64           // timeout in seconds when connecting an SSH server
 	OptTimeout          uint64           // timeout in seconds for operations that support it, not to confuse with SSH timeout
 	APITimeout          uint64           // timeout in seconds for API operations that support it, like transferring store leader
+	RequestTimeout      uint64           // timeout in seconds of each request to the component APIs, 0 to use the built-in ones
 	IgnoreConfigCheck   bool             // should we ignore the config check result after init config
 	NativeSSH           bool             // should use native ssh client or builtin easy ssh (deprecated, shoule use SSHType)
 	SSHType             executor.SSHType // the ssh type: 'builtin', 'system', 'none'
@@ -58,6 +59,48 @@ type Options struct {
 	// Show uptime or not
 	ShowUptime bool
 
+	// Show details of components, such as changefeeds of TiCDC
+	ShowDetail bool
+
+	// Warn if the disk of an instance is projected to be full within the days
+	DiskFullHorizon uint64
+
+	// Remove multiple TiKV stores at the same time instead of in batches
+	ParallelStoreRemoval bool
+	// timeout in seconds waiting for a batch of TiKV stores to become tombstone
+	StoreRemovalTimeout uint64
+
+	// Skip hosts that can not be reached, they are quarantined in meta and
+	// could be caught up later with the reconcile command
+	SkipUnreachable bool
+
+	// Upgrade the instances of quorum-bearing components zone by zone, the
+	// zone of an instance is read from its ZoneLabel or that of its host
+	ZoneAware bool
+	ZoneLabel string
+
+	// Skip the SQL hooks of the versions upgraded across
+	SkipUpgradeHooks bool
+
+	// The graceful hooks not to run around restarting the instances, e.g.
+	// drain, see spec.RestartHookNames
+	DisabledRestartHooks []string
+
+	// Roll the configs of the instances back to the generation kept in the
+	// config history instead of rendering them from the topology, 0 disables it
+	ConfigGeneration int
+
+	// The count of log and journal lines harvested from the instances failed
+	// to come up, 0 disables the harvesting
+	DiagnoseLines int
+
+	// Run in the read-only viewer mode, the private key of the cluster is
+	// never used, the hosts are accessed with the viewer credentials if the
+	// identity file is set, or only the status APIs are queried
+	Viewer             bool
+	ViewerUser         string
+	ViewerIdentityFile string
+
 	DisplayMode string // the output format
 	Operation   Operation
 }
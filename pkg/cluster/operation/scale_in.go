@@ -19,12 +19,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
@@ -121,7 +123,7 @@ func ScaleInCluster(
 
 	if skipTopoCheck {
 		logger.Warnf("%s is set, topology checks ignored, the cluster might be broken after the operations!", EnvNameSkipScaleInTopoCheck)
-		if ok, input := tui.PromptForConfirmYes("Are you sure to continue? [y/N]"); !ok {
+		if ok, input := tui.ContextPrompter(ctx).PromptForConfirmYes("Are you sure to continue? [y/N]"); !ok {
 			return errors.Errorf("user aborted with '%s'", input)
 		}
 	} else {
@@ -173,7 +175,7 @@ func ScaleInCluster(
 		return errors.New("cannot find available PD instance")
 	}
 
-	pdClient := api.NewPDClient(ctx, pdEndpoints, 10*time.Second, tlsCfg)
+	pdClient := api.NewPDClient(ctx, pdEndpoints, api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
 
 	tcpProxy := proxy.GetTCPProxy()
 	if tcpProxy != nil {
@@ -181,7 +183,7 @@ func ScaleInCluster(
 		defer tcpProxy.Close(closeC)
 		pdEndpoints = tcpProxy.GetEndpoints()
 	}
-	binlogClient, err := api.NewBinlogClient(pdEndpoints, 5*time.Second, tlsCfg)
+	binlogClient, err := api.NewBinlogClient(pdEndpoints, api.RequestTimeout(ctx, 5*time.Second), tlsCfg)
 	if err != nil {
 		return err
 	}
@@ -245,7 +247,7 @@ func ScaleInCluster(
 		}
 
 		var config replicateConfig
-		bytes, err := pdClient.GetReplicateConfig()
+		bytes, err := pdClient.GetReplicateConfig(ctx)
 		if err != nil {
 			return err
 		}
@@ -260,6 +262,15 @@ func ScaleInCluster(
 		}
 	}
 
+	// remove multiple TiKV stores in batches to reduce the rebalancing load
+	var tikvBatches [][]spec.Instance
+	if len(deletedDiff[spec.ComponentTiKV]) > 1 && !options.ParallelStoreRemoval {
+		tikvBatches, err = planStoreRemoval(ctx, pdClient, deletedDiff[spec.ComponentTiKV])
+		if err != nil {
+			return err
+		}
+	}
+
 	cdcInstances := make([]spec.Instance, 0)
 	// Delete member from cluster
 	for _, component := range cluster.ComponentsByStartOrder() {
@@ -274,6 +285,11 @@ func ScaleInCluster(
 				continue
 			}
 
+			// the batches of TiKV are handled separately
+			if component.Name() == spec.ComponentTiKV && len(tikvBatches) > 1 {
+				continue
+			}
+
 			err := deleteMember(ctx, component, instance, pdClient, binlogClient, options.APITimeout)
 			if err != nil {
 				return errors.Trace(err)
@@ -291,6 +307,12 @@ func ScaleInCluster(
 		}
 	}
 
+	if len(tikvBatches) > 1 {
+		if err := removeStoresInBatches(ctx, pdClient, tikvBatches, options); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	if len(cdcInstances) != 0 {
 		err := scaleInCDC(ctx, cluster, cdcInstances, tlsCfg, options, instCount)
 		if err != nil {
@@ -341,6 +363,201 @@ func ScaleInCluster(
 	return nil
 }
 
+// planStoreRemoval splits the TiKV instances to be removed into batches by
+// the isolation domains of the replication config of PD, see
+// batchStoresByLabel.
+func planStoreRemoval(ctx context.Context, pdClient *api.PDClient, instances []spec.Instance) ([][]spec.Instance, error) {
+	rc, err := pdClient.GetReplicationConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := pdClient.GetStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	storeLabels := make(map[string]map[string]string) // address -> labels
+	for _, store := range stores.Stores {
+		if store.Store.State == metapb.StoreState_Tombstone {
+			continue
+		}
+		labels := make(map[string]string)
+		for _, l := range store.Store.GetLabels() {
+			labels[l.GetKey()] = l.GetValue()
+		}
+		storeLabels[store.Store.Address] = labels
+	}
+
+	return batchStoresByLabel(instances, storeLabels, rc), nil
+}
+
+// batchStoresByLabel groups the instances by the isolation domain, i.e. the
+// values of the location labels down to the isolation level. PD never places
+// two replicas of a region in one domain, so a batch of at most
+// (max-replicas-1)/2 domains never takes a quorum of a region away. Without
+// the isolation level the placement is best-effort, so the stores, as well as
+// the ones not labeled down to the level, are batched by max-replicas-1 at
+// most, which keeps a replica of each region on the stores not removed. The
+// batches are sorted by the domains for a stable order, and the stores fit in
+// one batch are removed at once without waiting as before.
+func batchStoresByLabel(instances []spec.Instance, storeLabels map[string]map[string]string, rc *api.PDReplicationConfig) [][]spec.Instance {
+	var levelLabels []string
+	for i, l := range rc.LocationLabels {
+		if l == rc.IsolationLevel {
+			levelLabels = rc.LocationLabels[:i+1]
+			break
+		}
+	}
+
+	domains := make(map[string][]spec.Instance)
+	var unlabeled []spec.Instance
+	for _, inst := range instances {
+		domain := isolationDomain(storeLabels[inst.ID()], levelLabels)
+		if domain == "" {
+			unlabeled = append(unlabeled, inst)
+			continue
+		}
+		domains[domain] = append(domains[domain], inst)
+	}
+
+	values := make([]string, 0, len(domains))
+	for v := range domains {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	domainsPerBatch := 1
+	if rc.MaxReplicas > 2 {
+		domainsPerBatch = int(rc.MaxReplicas-1) / 2
+	}
+	var batches [][]spec.Instance
+	for i, v := range values {
+		if i%domainsPerBatch == 0 {
+			batches = append(batches, nil)
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], domains[v]...)
+	}
+
+	storesPerBatch := 1
+	if rc.MaxReplicas > 1 {
+		storesPerBatch = int(rc.MaxReplicas - 1)
+	}
+	for i, inst := range unlabeled {
+		if i%storesPerBatch == 0 {
+			batches = append(batches, nil)
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], inst)
+	}
+	return batches
+}
+
+// isolationDomain returns the values of labels of a store joined, it's empty
+// if no label is given or the store misses any of them
+func isolationDomain(storeLabels map[string]string, labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	values := make([]string, 0, len(labels))
+	for _, l := range labels {
+		v := storeLabels[l]
+		if v == "" {
+			return ""
+		}
+		values = append(values, v)
+	}
+	return strings.Join(values, "/")
+}
+
+// LockPauser releases the operation lock of the cluster while waiting for a
+// long running change, so that the other operations are not blocked for
+// hours, the lock is acquired again by the returned resume func
+type LockPauser func() (resume func() error, err error)
+
+type lockPauserKey struct{}
+
+// WithLockPauser returns a context with which the operation lock is released
+// by pause while waiting for the TiKV stores removed to become tombstone
+func WithLockPauser(ctx context.Context, pause LockPauser) context.Context {
+	if pause == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, lockPauserKey{}, pause)
+}
+
+// pauseOperationLock releases the operation lock by the LockPauser in ctx if
+// any, the returned func acquires it again
+func pauseOperationLock(ctx context.Context) (func() error, error) {
+	pause, ok := ctx.Value(lockPauserKey{}).(LockPauser)
+	if !ok {
+		return func() error { return nil }, nil
+	}
+	return pause()
+}
+
+// removeStoresInBatches deletes the stores batch by batch, the next batch is
+// not deleted until all stores of the previous one become tombstone, the
+// operation lock is paused meanwhile if ctx carries a LockPauser.
+func removeStoresInBatches(ctx context.Context, pdClient *api.PDClient, batches [][]spec.Instance, options Options) error {
+	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+	component := &spec.TiKVComponent{}
+	timeout := time.Second * time.Duration(options.StoreRemovalTimeout)
+	if timeout == 0 {
+		timeout = time.Hour * 24
+	}
+
+	for i, batch := range batches {
+		ids := make([]string, 0, len(batch))
+		for _, inst := range batch {
+			ids = append(ids, inst.ID())
+		}
+		logger.Infof("Removing TiKV stores in batch %d/%d: %s", i+1, len(batches), strings.Join(ids, ","))
+
+		for _, inst := range batch {
+			if err := deleteMember(ctx, component, inst, pdClient, nil, options.APITimeout); err != nil {
+				return err
+			}
+		}
+
+		// the last batch becomes tombstone asynchronously like other stores
+		if i == len(batches)-1 {
+			break
+		}
+
+		logger.Infof("Waiting for the stores to become tombstone, it may take a long time depending on the data size")
+		resume, err := pauseOperationLock(ctx)
+		if err != nil {
+			return err
+		}
+		err = utils.Wait(ctx, func() error {
+			for _, id := range ids {
+				tombstone, err := pdClient.IsTombStone(ctx, id)
+				if err != nil {
+					return err
+				}
+				if !tombstone {
+					return errors.Errorf("store %s is still offline", id)
+				}
+			}
+			return nil
+		}, utils.WaitOption{
+			Timeout:     timeout,
+			Interval:    time.Second * 10,
+			MaxInterval: time.Minute,
+			Progress: func(elapsed time.Duration, err error) {
+				logger.Debugf("\t Still waiting after %s: %s", elapsed.Round(time.Second), err)
+			},
+		})
+		if rerr := resume(); rerr != nil {
+			return rerr
+		}
+		if err != nil {
+			return errors.Annotatef(err, "the stores of batch %d are not removed in time, the remaining stores are not deleted", i+1)
+		}
+	}
+
+	return nil
+}
+
 func deleteMember(
 	ctx context.Context,
 	component spec.Component,
@@ -356,16 +573,16 @@ func deleteMember(
 
 	switch component.Name() {
 	case spec.ComponentTiKV:
-		if err := pdClient.DelStore(instance.ID(), timeoutOpt); err != nil {
+		if err := pdClient.DelStore(ctx, instance.ID(), timeoutOpt); err != nil {
 			return err
 		}
 	case spec.ComponentTiFlash:
 		addr := instance.GetHost() + ":" + strconv.Itoa(instance.(*spec.TiFlashInstance).GetServicePort())
-		if err := pdClient.DelStore(addr, timeoutOpt); err != nil {
+		if err := pdClient.DelStore(ctx, addr, timeoutOpt); err != nil {
 			return err
 		}
 	case spec.ComponentPD:
-		if err := pdClient.DelPD(instance.(*spec.PDInstance).Name, timeoutOpt); err != nil {
+		if err := pdClient.DelPD(ctx, instance.(*spec.PDInstance).Name, timeoutOpt); err != nil {
 			return err
 		}
 	case spec.ComponentDrainer:
@@ -420,8 +637,8 @@ func scaleInCDC(
 		}
 
 		address := instance.(*spec.CDCInstance).GetAddr()
-		client := api.NewCDCOpenAPIClient(ctx, []string{address}, 5*time.Second, tlsCfg)
-		capture, err := client.GetCaptureByAddr(address)
+		client := api.NewCDCOpenAPIClient([]string{address}, api.RequestTimeout(ctx, 5*time.Second), tlsCfg)
+		capture, err := client.GetCaptureByAddr(ctx, address)
 		if err != nil {
 			// After the previous status check, we know that the cdc instance should be `Up`, but know it cannot be found by address
 			// perhaps since the specified version of cdc does not support open api, or the instance just crashed right away
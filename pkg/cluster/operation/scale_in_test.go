@@ -0,0 +1,131 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBatchStoresByLabel(t *testing.T) {
+	topo := new(spec.Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+  - host: 172.16.5.143
+  - host: 172.16.5.144
+  - host: 172.16.5.145
+`), topo))
+	var instances []spec.Instance
+	topo.IterInstance(func(inst spec.Instance) {
+		instances = append(instances, inst)
+	})
+
+	storeLabels := map[string]map[string]string{
+		"172.16.5.141:20160": {"zone": "z1", "host": "h1"},
+		"172.16.5.142:20160": {"zone": "z1", "host": "h2"},
+		"172.16.5.143:20160": {"zone": "z2", "host": "h3"},
+		"172.16.5.144:20160": {"zone": "z3", "host": "h4"},
+		"172.16.5.145:20160": {"host": "h5"},
+	}
+
+	cases := []struct {
+		name    string
+		config  api.PDReplicationConfig
+		batches [][]string
+	}{
+		{
+			// two of three replicas are removed at most
+			name:   "no location labels",
+			config: api.PDReplicationConfig{MaxReplicas: 3},
+			batches: [][]string{
+				{"172.16.5.141:20160", "172.16.5.142:20160"},
+				{"172.16.5.143:20160", "172.16.5.144:20160"},
+				{"172.16.5.145:20160"},
+			},
+		},
+		{
+			// the replicas are only spread across the zones in best-effort
+			name:   "no isolation level",
+			config: api.PDReplicationConfig{MaxReplicas: 3, LocationLabels: []string{"zone", "host"}},
+			batches: [][]string{
+				{"172.16.5.141:20160", "172.16.5.142:20160"},
+				{"172.16.5.143:20160", "172.16.5.144:20160"},
+				{"172.16.5.145:20160"},
+			},
+		},
+		{
+			name:   "isolated by zone",
+			config: api.PDReplicationConfig{MaxReplicas: 3, LocationLabels: []string{"zone", "host"}, IsolationLevel: "zone"},
+			batches: [][]string{
+				{"172.16.5.141:20160", "172.16.5.142:20160"}, {"172.16.5.143:20160"},
+				{"172.16.5.144:20160"}, {"172.16.5.145:20160"},
+			},
+		},
+		{
+			name:   "isolated by host",
+			config: api.PDReplicationConfig{MaxReplicas: 3, LocationLabels: []string{"zone", "host"}, IsolationLevel: "host"},
+			batches: [][]string{
+				{"172.16.5.141:20160"}, {"172.16.5.142:20160"}, {"172.16.5.143:20160"},
+				{"172.16.5.144:20160"}, {"172.16.5.145:20160"},
+			},
+		},
+		{
+			// two of five replicas are removed at most
+			name:   "five replicas",
+			config: api.PDReplicationConfig{MaxReplicas: 5, LocationLabels: []string{"zone", "host"}, IsolationLevel: "zone"},
+			batches: [][]string{
+				{"172.16.5.141:20160", "172.16.5.142:20160", "172.16.5.143:20160"},
+				{"172.16.5.144:20160"}, {"172.16.5.145:20160"},
+			},
+		},
+		{
+			name:   "single replica",
+			config: api.PDReplicationConfig{MaxReplicas: 1, LocationLabels: []string{"zone"}, IsolationLevel: "zone"},
+			batches: [][]string{
+				{"172.16.5.141:20160", "172.16.5.142:20160"}, {"172.16.5.143:20160"},
+				{"172.16.5.144:20160"}, {"172.16.5.145:20160"},
+			},
+		},
+		{
+			name:   "unknown isolation level",
+			config: api.PDReplicationConfig{MaxReplicas: 3, LocationLabels: []string{"zone"}, IsolationLevel: "rack"},
+			batches: [][]string{
+				{"172.16.5.141:20160", "172.16.5.142:20160"},
+				{"172.16.5.143:20160", "172.16.5.144:20160"},
+				{"172.16.5.145:20160"},
+			},
+		},
+	}
+
+	// the stores fit in one batch are removed at once
+	assert.Len(t, batchStoresByLabel(instances[:2], storeLabels, &api.PDReplicationConfig{MaxReplicas: 3}), 1)
+
+	for _, c := range cases {
+		var batches [][]string
+		for _, batch := range batchStoresByLabel(instances, storeLabels, &c.config) {
+			var ids []string
+			for _, inst := range batch {
+				ids = append(ids, inst.ID())
+			}
+			batches = append(batches, ids)
+		}
+		assert.Equal(t, c.batches, batches, c.name)
+	}
+}
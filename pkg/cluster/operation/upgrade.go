@@ -56,6 +56,17 @@ func Upgrade(
 
 	var cdcOpenAPIClient *api.CDCOpenAPIClient // client for cdc openapi, only used when upgrade cdc
 
+	var zones map[string]string
+	if options.ZoneAware {
+		var err error
+		if zones, err = instanceZones(topo, options.ZoneLabel); err != nil {
+			return err
+		}
+		if len(zones) == 0 {
+			logger.Warnf("No PD, TiKV or TiFlash instance is labeled with %s, the instances are not upgraded zone by zone", options.ZoneLabel)
+		}
+	}
+
 	for _, component := range components {
 		instances := FilterInstance(component.Instances(), nodeFilter)
 		if len(instances) < 1 {
@@ -79,7 +90,7 @@ func Upgrade(
 			} else {
 				pdEndpoints = topo.(*spec.Specification).GetPDList()
 			}
-			pdClient := api.NewPDClient(ctx, pdEndpoints, 10*time.Second, tlsCfg)
+			pdClient := api.NewPDClient(ctx, pdEndpoints, api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
 			origLeaderScheduleLimit, origRegionScheduleLimit, err = increaseScheduleLimit(ctx, pdClient)
 			if err != nil {
 				// the config modifying error should be able to be safely ignored, as it will
@@ -87,7 +98,7 @@ func Upgrade(
 				logger.Warnf("failed increasing schedule limit: %s, ignore", err)
 			} else {
 				defer func() {
-					upgErr := decreaseScheduleLimit(pdClient, origLeaderScheduleLimit, origRegionScheduleLimit)
+					upgErr := decreaseScheduleLimit(ctx, pdClient, origLeaderScheduleLimit, origRegionScheduleLimit)
 					if upgErr != nil {
 						logger.Warnf(
 							"failed decreasing schedule limit (original values should be: %s, %s), please check if their current values are reasonable: %s",
@@ -102,6 +113,14 @@ func Upgrade(
 			// do nothing, kept for future usage with other components
 		}
 
+		if len(zones) > 0 && quorumComponents.Exist(component.Name()) {
+			instances = zoneOrderedInstances(ctx, topo, component.Name(), instances, zones, options, tlsCfg)
+		}
+		switch component.Name() {
+		case spec.ComponentTiKV, spec.ComponentTiFlash:
+			instances = readOnlyZonesLast(ctx, topo, component.Name(), instances)
+		}
+
 		// some instances are upgraded after others
 		deferInstances := make([]spec.Instance, 0)
 
@@ -129,11 +148,11 @@ func Upgrade(
 				if ins.Status(ctx, 5*time.Second, tlsCfg) == "Up" {
 					// during the upgrade process, endpoint addresses should not change, so only new the client once.
 					if cdcOpenAPIClient == nil {
-						cdcOpenAPIClient = api.NewCDCOpenAPIClient(ctx, topo.(*spec.Specification).GetCDCList(), 5*time.Second, tlsCfg)
+						cdcOpenAPIClient = api.NewCDCOpenAPIClient(topo.(*spec.Specification).GetCDCList(), api.RequestTimeout(ctx, 5*time.Second), tlsCfg)
 					}
 
 					address := ins.GetAddr()
-					capture, err := cdcOpenAPIClient.GetCaptureByAddr(address)
+					capture, err := cdcOpenAPIClient.GetCaptureByAddr(ctx, address)
 					if err != nil {
 						// After the previous status check, we know that the cdc instance should be `Up`, but know it cannot be found by address
 						// perhaps since the specified version of cdc does not support open api, or the instance just crashed right away
@@ -186,27 +205,20 @@ func upgradeInstance(ctx context.Context, topo spec.Topology, instance spec.Inst
 		return nil
 	}
 
-	var rollingInstance spec.RollingUpdateInstance
-	var isRollingInstance bool
-
 	if !options.Force {
-		rollingInstance, isRollingInstance = instance.(spec.RollingUpdateInstance)
-	}
-
-	if isRollingInstance {
-		err := rollingInstance.PreRestart(ctx, topo, int(options.APITimeout), tlsCfg)
-		if err != nil && !options.Force {
+		if err := spec.PreRestart(ctx, instance, topo, int(options.APITimeout), tlsCfg); err != nil {
 			return err
 		}
 	}
 
-	if err := restartInstance(ctx, instance, options.OptTimeout, tlsCfg); err != nil && !options.Force {
+	err = restartInstance(ctx, instance, options.OptTimeout, options.DiagnoseLines, tlsCfg)
+	api.InvalidateCache(ctx)
+	if err != nil && !options.Force {
 		return err
 	}
 
-	if isRollingInstance {
-		err := rollingInstance.PostRestart(ctx, topo, tlsCfg)
-		if err != nil && !options.Force {
+	if !options.Force {
+		if err := spec.PostRestart(ctx, instance, topo, int(options.APITimeout), tlsCfg); err != nil {
 			return err
 		}
 	}
@@ -253,7 +265,7 @@ func increaseScheduleLimit(ctx context.Context, pc *api.PDClient) (
 	}
 
 	// query current values
-	cfg, err := pc.GetConfig()
+	cfg, err := pc.GetConfig(ctx)
 	if err != nil {
 		return
 	}
@@ -274,7 +286,7 @@ func increaseScheduleLimit(ctx context.Context, pc *api.PDClient) (
 		if newLimit > leaderScheduleLimitThreshold {
 			newLimit = leaderScheduleLimitThreshold
 		}
-		if err := pc.SetReplicationConfig("leader-schedule-limit", newLimit); err != nil {
+		if err := pc.SetReplicationConfig(ctx, "leader-schedule-limit", newLimit); err != nil {
 			return currLeaderScheduleLimit, currRegionScheduleLimit, err
 		}
 	}
@@ -283,10 +295,10 @@ func increaseScheduleLimit(ctx context.Context, pc *api.PDClient) (
 		if newLimit > regionScheduleLimitThreshold {
 			newLimit = regionScheduleLimitThreshold
 		}
-		if err := pc.SetReplicationConfig("region-schedule-limit", newLimit); err != nil {
+		if err := pc.SetReplicationConfig(ctx, "region-schedule-limit", newLimit); err != nil {
 			// try to revert leader scheduler limit by our best effort, does not make sense
 			// to handle this error again
-			_ = pc.SetReplicationConfig("leader-schedule-limit", currLeaderScheduleLimit)
+			_ = pc.SetReplicationConfig(ctx, "leader-schedule-limit", currLeaderScheduleLimit)
 			return currLeaderScheduleLimit, currRegionScheduleLimit, err
 		}
 	}
@@ -296,9 +308,9 @@ func increaseScheduleLimit(ctx context.Context, pc *api.PDClient) (
 
 // decreaseScheduleLimit tries to set the schedule limit back to it's original with
 // the same offset value as increaseScheduleLimit added, with some sanity checks
-func decreaseScheduleLimit(pc *api.PDClient, origLeaderScheduleLimit, origRegionScheduleLimit int) error {
-	if err := pc.SetReplicationConfig("leader-schedule-limit", origLeaderScheduleLimit); err != nil {
+func decreaseScheduleLimit(ctx context.Context, pc *api.PDClient, origLeaderScheduleLimit, origRegionScheduleLimit int) error {
+	if err := pc.SetReplicationConfig(ctx, "leader-schedule-limit", origLeaderScheduleLimit); err != nil {
 		return err
 	}
-	return pc.SetReplicationConfig("region-schedule-limit", origRegionScheduleLimit)
+	return pc.SetReplicationConfig(ctx, "region-schedule-limit", origRegionScheduleLimit)
 }
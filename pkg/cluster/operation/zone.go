@@ -0,0 +1,190 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"crypto/tls"
+	"sort"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// the components whose instances form quorums, they are upgraded zone by
+// zone in zone-aware mode
+var quorumComponents = set.NewStringSet(spec.ComponentPD, spec.ComponentTiKV, spec.ComponentTiFlash)
+
+// instanceZones returns the zones of the PD, TiKV and TiFlash instances by
+// ID. The zone of an instance is read from its own label, i.e. labels of PD
+// and server.labels of TiKV and TiFlash, or it's the zone of the host if the
+// instance is not labeled, which is the label of the other instances on the
+// host or the name of the read-only zone the host is in.
+func instanceZones(topo spec.Topology, label string) (map[string]string, error) {
+	zones := make(map[string]string)
+	cluster, ok := topo.(*spec.Specification)
+	if !ok {
+		return zones, nil
+	}
+
+	hostZones := make(map[string]string)
+	var unlabeled []spec.Instance
+	var err error
+	cluster.IterInstance(func(inst spec.Instance) {
+		if err != nil || !quorumComponents.Exist(inst.ComponentName()) {
+			return
+		}
+		var zone string
+		if zone, err = instanceLabel(inst, label); err != nil {
+			return
+		}
+		if zone == "" {
+			unlabeled = append(unlabeled, inst)
+			return
+		}
+		zones[inst.ID()] = zone
+		if _, ok := hostZones[inst.GetHost()]; !ok {
+			hostZones[inst.GetHost()] = zone
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, zone := range cluster.GlobalOptions.ReadOnlyZones {
+		if zone.LabelName() != label {
+			continue
+		}
+		for _, host := range zone.Hosts {
+			if _, ok := hostZones[host]; !ok {
+				hostZones[host] = zone.Name
+			}
+		}
+	}
+	for _, inst := range unlabeled {
+		if zone, ok := hostZones[inst.GetHost()]; ok {
+			zones[inst.ID()] = zone
+		}
+	}
+	return zones, nil
+}
+
+// instanceLabel returns the value of the label set in the config of a PD,
+// TiKV or TiFlash instance
+func instanceLabel(inst spec.Instance, label string) (string, error) {
+	var value interface{}
+	switch s := inst.(type) {
+	case *spec.TiKVInstance:
+		labels, err := s.InstanceSpec.(*spec.TiKVSpec).Labels()
+		if err != nil {
+			return "", err
+		}
+		return labels[label], nil
+	case *spec.TiFlashInstance:
+		value = spec.GetValueFromPath(s.InstanceSpec.(*spec.TiFlashSpec).LearnerConfig, "server.labels."+label)
+	case *spec.PDInstance:
+		value = spec.GetValueFromPath(s.InstanceSpec.(*spec.PDSpec).Config, "labels."+label)
+	}
+	if value == nil {
+		return "", nil
+	}
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+	return "", perrs.Errorf("the value %v of label %s is not a string, check the instance: %s", value, label, inst.ID())
+}
+
+// orderByZone sorts the instances by zone, the zone of the instance lastID
+// is moved to the end, and the instances in the same zone keep their order
+func orderByZone(instances []spec.Instance, zones map[string]string, lastID string) []spec.Instance {
+	lastZone, hasLast := zones[lastID], lastID != ""
+
+	sorted := append([]spec.Instance(nil), instances...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		zi, zj := zones[sorted[i].ID()], zones[sorted[j].ID()]
+		if hasLast && (zi == lastZone) != (zj == lastZone) {
+			return zj == lastZone
+		}
+		return zi < zj
+	})
+	return sorted
+}
+
+// zoneOrderedInstances sorts the instances of a quorum-bearing component by
+// zone, so the instances in a zone are all upgraded before the next zone.
+// The zone of PD leader is upgraded last as the leader is deferred.
+func zoneOrderedInstances(
+	ctx context.Context,
+	topo spec.Topology,
+	component string,
+	instances []spec.Instance,
+	zones map[string]string,
+	options Options,
+	tlsCfg *tls.Config,
+) []spec.Instance {
+	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+
+	leaderID := ""
+	if component == spec.ComponentPD {
+		for _, inst := range instances {
+			isLeader, err := inst.(*spec.PDInstance).IsLeader(ctx, topo, int(options.APITimeout), tlsCfg)
+			if err == nil && isLeader {
+				leaderID = inst.ID()
+				break
+			}
+		}
+	}
+
+	sorted := orderByZone(instances, zones, leaderID)
+	var order []string
+	for _, inst := range sorted {
+		zone, ok := zones[inst.ID()]
+		if !ok {
+			zone = "(unknown)"
+		}
+		if len(order) == 0 || order[len(order)-1] != zone {
+			order = append(order, zone)
+		}
+	}
+	logger.Infof("Upgrading %s zone by zone: %s", component, strings.Join(order, " -> "))
+	return sorted
+}
+
+// readOnlyZonesLast moves the instances in the read-only zones after the
+// others, so the zones of voters are upgraded together and the learners of
+// the read-only zones are upgraded independently after them
+func readOnlyZonesLast(ctx context.Context, topo spec.Topology, component string, instances []spec.Instance) []spec.Instance {
+	cluster, ok := topo.(*spec.Specification)
+	if !ok || len(cluster.GlobalOptions.ReadOnlyZones) == 0 {
+		return instances
+	}
+	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+
+	sorted := make([]spec.Instance, 0, len(instances))
+	var readOnly []spec.Instance
+	for _, inst := range instances {
+		if cluster.ReadOnlyZoneOf(inst.GetHost()) != nil {
+			readOnly = append(readOnly, inst)
+		} else {
+			sorted = append(sorted, inst)
+		}
+	}
+	if len(readOnly) > 0 {
+		logger.Infof("Upgrading %d %s instances in read-only zones after the others", len(readOnly), component)
+	}
+	return append(sorted, readOnly...)
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestZoneOrder(t *testing.T) {
+	topo := new(spec.Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+global:
+  read_only_zones:
+    - name: z4
+      hosts: [172.16.5.144]
+pd_servers:
+  - host: 172.16.5.141
+  - host: 172.16.5.150
+    config:
+      labels:
+        zone: z2
+  - host: 172.16.5.151
+  - host: 172.16.5.144
+tikv_servers:
+  - host: 172.16.5.141
+    config:
+      server.labels: { zone: z1 }
+  - host: 172.16.5.142
+    config:
+      server.labels: { zone: z2 }
+tiflash_servers:
+  - host: 172.16.5.152
+    learner_config:
+      server.labels:
+        zone: z3
+  - host: 172.16.5.142
+`), topo))
+
+	zones, err := instanceZones(topo, "zone")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		// from the TiKV on the host
+		"172.16.5.141:2379": "z1",
+		// the own labels of the dedicated hosts
+		"172.16.5.150:2379": "z2",
+		"172.16.5.152:9000": "z3",
+		// from the read-only zone of the host
+		"172.16.5.144:2379":  "z4",
+		"172.16.5.141:20160": "z1",
+		"172.16.5.142:20160": "z2",
+		"172.16.5.142:9000":  "z2",
+	}, zones)
+
+	// no instance is labeled with rack
+	zones, err = instanceZones(topo, "rack")
+	assert.Nil(t, err)
+	assert.Empty(t, zones)
+
+	ids := func(instances []spec.Instance) []string {
+		var ids []string
+		for _, inst := range instances {
+			ids = append(ids, inst.ID())
+		}
+		return ids
+	}
+	zones, _ = instanceZones(topo, "zone")
+	pds := topo.ComponentsByStartOrder()[0].Instances()
+	assert.Equal(t, []string{"172.16.5.151:2379", "172.16.5.141:2379", "172.16.5.150:2379", "172.16.5.144:2379"},
+		ids(orderByZone(pds, zones, "")))
+	// the zone of the leader is the last, the unknown zones are the first
+	assert.Equal(t, []string{"172.16.5.151:2379", "172.16.5.150:2379", "172.16.5.144:2379", "172.16.5.141:2379"},
+		ids(orderByZone(pds, zones, "172.16.5.141:2379")))
+
+	// the labels must be strings
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.141
+    config:
+      labels:
+        zone: 1
+`), topo))
+	_, err = instanceZones(topo, "zone")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulate runs the operations of clusters against a recorded
+// snapshot of the SSH and API responses instead of the real hosts, so the
+// task graph of an operation can be validated before it's applied.
+package simulate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// SSHResponse is the recorded response of the commands matching Command
+type SSHResponse struct {
+	Host    string `yaml:"host,omitempty"` // matches all hosts if empty
+	Command string `yaml:"command"`        // regular expression of the command
+	Stdout  string `yaml:"stdout,omitempty"`
+	Stderr  string `yaml:"stderr,omitempty"`
+	Error   string `yaml:"error,omitempty"` // the command fails if not empty
+
+	re *regexp.Regexp
+}
+
+// HTTPResponse is the recorded response of the requests matching URL
+type HTTPResponse struct {
+	Method string `yaml:"method,omitempty"` // matches all methods if empty
+	URL    string `yaml:"url"`              // regular expression of the URL
+	Status int    `yaml:"status,omitempty"` // 200 if not set
+	Body   string `yaml:"body,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Call is a command, transfer or request made in the simulation
+type Call struct {
+	Kind    string // ssh, transfer or http
+	Target  string // the host or the URL
+	Detail  string
+	Matched bool
+}
+
+// Snapshot is the recorded responses of a cluster, the first response
+// matching a call is used. If Strict is true, the calls matching no response
+// fail, otherwise they succeed with empty output.
+type Snapshot struct {
+	Strict bool           `yaml:"strict,omitempty"`
+	SSH    []SSHResponse  `yaml:"ssh,omitempty"`
+	HTTP   []HTTPResponse `yaml:"http,omitempty"`
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var (
+	_ executor.Simulator = &Snapshot{}
+	_ http.RoundTripper  = &Snapshot{}
+)
+
+// Load reads the snapshot from file
+func Load(file string) (*Snapshot, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, perrs.Annotatef(err, "failed to read snapshot %s", file)
+	}
+	return Parse(data)
+}
+
+// Parse parses the snapshot and compiles the patterns in it
+func Parse(data []byte) (*Snapshot, error) {
+	s := &Snapshot{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, perrs.Annotate(err, "invalid snapshot")
+	}
+	for i := range s.SSH {
+		re, err := regexp.Compile(s.SSH[i].Command)
+		if err != nil {
+			return nil, perrs.Annotatef(err, "invalid command pattern %s", s.SSH[i].Command)
+		}
+		s.SSH[i].re = re
+	}
+	for i := range s.HTTP {
+		re, err := regexp.Compile(s.HTTP[i].URL)
+		if err != nil {
+			return nil, perrs.Annotatef(err, "invalid URL pattern %s", s.HTTP[i].URL)
+		}
+		s.HTTP[i].re = re
+	}
+	return s, nil
+}
+
+// Enable makes the simulate executors and all HTTP clients created after use
+// the snapshot
+func Enable(s *Snapshot) {
+	executor.SetSimulator(s)
+	utils.SetHTTPTransport(s)
+}
+
+func (s *Snapshot) record(call Call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+// Calls returns all calls made in the simulation in order
+func (s *Snapshot) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Execute implements executor.Simulator interface.
+func (s *Snapshot) Execute(host, cmd string, sudo bool) ([]byte, []byte, error) {
+	for _, r := range s.SSH {
+		if (r.Host != "" && r.Host != host) || !r.re.MatchString(cmd) {
+			continue
+		}
+		s.record(Call{Kind: "ssh", Target: host, Detail: cmd, Matched: true})
+		if r.Error != "" {
+			return []byte(r.Stdout), []byte(r.Stderr), perrs.Errorf("simulated failure of `%s` on %s: %s", cmd, host, r.Error)
+		}
+		return []byte(r.Stdout), []byte(r.Stderr), nil
+	}
+
+	s.record(Call{Kind: "ssh", Target: host, Detail: cmd})
+	if s.Strict {
+		return nil, nil, perrs.Errorf("no recorded response of `%s` on %s", cmd, host)
+	}
+	return nil, nil, nil
+}
+
+// Transfer implements executor.Simulator interface, the files downloaded are
+// created empty
+func (s *Snapshot) Transfer(host, src, dst string, download bool) error {
+	detail := fmt.Sprintf("%s -> %s:%s", src, host, dst)
+	if download {
+		detail = fmt.Sprintf("%s:%s -> %s", host, src, dst)
+	}
+	s.record(Call{Kind: "transfer", Target: host, Detail: detail, Matched: true})
+
+	if !download {
+		return nil
+	}
+	if err := utils.CreateDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	return perrs.AddStack(os.WriteFile(dst, nil, 0644))
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (s *Snapshot) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	for _, r := range s.HTTP {
+		if (r.Method != "" && r.Method != req.Method) || !r.re.MatchString(url) {
+			continue
+		}
+		s.record(Call{Kind: "http", Target: url, Detail: req.Method, Matched: true})
+		status := r.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		return newResponse(req, status, r.Body), nil
+	}
+
+	s.record(Call{Kind: "http", Target: url, Detail: req.Method})
+	if s.Strict {
+		return nil, perrs.Errorf("no recorded response of %s %s", req.Method, url)
+	}
+	return newResponse(req, http.StatusOK, "{}"), nil
+}
+
+func newResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// Summary returns the count of calls by kind, and the calls matching no
+// recorded response
+func (s *Snapshot) Summary() (counts map[string]int, unmatched []Call) {
+	counts = make(map[string]int)
+	for _, c := range s.Calls() {
+		counts[c.Kind]++
+		if !c.Matched {
+			unmatched = append(unmatched, c)
+		}
+	}
+	return counts, unmatched
+}
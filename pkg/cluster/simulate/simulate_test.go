@@ -0,0 +1,83 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSnapshot = `
+ssh:
+  - host: 172.16.5.1
+    command: ^uname -m$
+    stdout: aarch64
+  - command: ^uname -m$
+    stdout: x86_64
+  - command: systemctl start tikv-20160
+    error: exit status 1
+http:
+  - method: GET
+    url: /pd/api/v1/stores
+    body: '{"count": 0}'
+  - url: /pd/api/v1/config
+    status: 500
+`
+
+func TestSnapshot(t *testing.T) {
+	s, err := Parse([]byte(testSnapshot))
+	assert.Nil(t, err)
+
+	stdout, _, err := s.Execute("172.16.5.1", "uname -m", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "aarch64", string(stdout))
+	stdout, _, err = s.Execute("172.16.5.2", "uname -m", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "x86_64", string(stdout))
+	_, _, err = s.Execute("172.16.5.2", "sudo systemctl start tikv-20160.service", true)
+	assert.NotNil(t, err)
+	_, _, err = s.Execute("172.16.5.2", "ls /", false)
+	assert.Nil(t, err)
+
+	dst := filepath.Join(t.TempDir(), "conf", "tikv.toml")
+	assert.Nil(t, s.Transfer("172.16.5.2", "/tidb-deploy/conf/tikv.toml", dst, true))
+	_, err = os.Stat(dst)
+	assert.Nil(t, err)
+
+	client := &http.Client{Transport: s}
+	resp, err := client.Get("http://172.16.5.1:2379/pd/api/v1/stores")
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"count": 0}`, string(body))
+	resp, err = client.Get("http://172.16.5.1:2379/pd/api/v1/config")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	counts, unmatched := s.Summary()
+	assert.Equal(t, map[string]int{"ssh": 4, "transfer": 1, "http": 2}, counts)
+	assert.Len(t, unmatched, 1)
+	assert.Equal(t, "ls /", unmatched[0].Detail)
+
+	s.Strict = true
+	_, _, err = s.Execute("172.16.5.2", "ls /", false)
+	assert.NotNil(t, err)
+	_, err = client.Get("http://172.16.5.1:2379/pd/api/v1/members")
+	assert.NotNil(t, err)
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/config"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
@@ -108,11 +109,11 @@ func (c *AlertManagerComponent) Instances() []Instance {
 					s.DeployDir,
 					s.DataDir,
 				},
-				StatusFn: func(_ context.Context, timeout time.Duration, _ *tls.Config, _ ...string) string {
-					return statusByHost(s.Host, s.WebPort, "/-/ready", timeout, nil)
+				StatusFn: func(_ context.Context, timeout time.Duration, tlsCfg *tls.Config, _ ...string) string {
+					return statusByHost(s.Host, s.WebPort, "/-/ready", timeout, NewEndpointResolver(tlsCfg).TLSConfig(ComponentAlertmanager))
 				},
 				UptimeFn: func(_ context.Context, timeout time.Duration, tlsCfg *tls.Config) time.Duration {
-					return UptimeByHost(s.Host, s.WebPort, timeout, tlsCfg)
+					return UptimeByHost(s.Host, s.WebPort, timeout, NewEndpointResolver(tlsCfg).TLSConfig(ComponentAlertmanager))
 				},
 			},
 			topo: c.Topology,
@@ -156,7 +157,7 @@ func (i *AlertManagerInstance) InitConfig(
 	}
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_alertmanager_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 
@@ -174,7 +175,7 @@ func (i *AlertManagerInstance) InitConfig(
 		return i.TransferLocalConfigFile(ctx, e, spec.ConfigFilePath, dst)
 	}
 	configPath := filepath.Join(paths.Cache, fmt.Sprintf("alertmanager_%s.yml", i.GetHost()))
-	if err := config.NewAlertManagerConfig().ConfigToFile(configPath); err != nil {
+	if err := template.ConfigToFile(config.NewAlertManagerConfig(), paths.Templates, configPath); err != nil {
 		return err
 	}
 	if err := i.TransferLocalConfigFile(ctx, e, configPath, dst); err != nil {
@@ -0,0 +1,323 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// the fields of an instance filled by tiup, they are kept if they are not
+// set in the desired state
+var runtimeFields = set.NewStringSet("arch", "os", "imported", "patched")
+
+// DesiredState is the declarative state of a cluster
+type DesiredState struct {
+	Version  string        `yaml:"version,omitempty"` // the version is not managed if empty
+	Topology yaml.MapSlice `yaml:"topology"`
+}
+
+// ParseDesiredState reads the desired state from `file` and unmarshal the
+// topology in it to `out`, the version in it is returned
+func ParseDesiredState(file string, out Topology) (string, error) {
+	zap.L().Debug("Parse desired state file", zap.String("file", file))
+
+	data, err := ReadYamlFile(file)
+	if err != nil {
+		return "", err
+	}
+	state := DesiredState{}
+	if err := yaml.UnmarshalStrict(data, &state); err != nil {
+		return "", ErrTopologyParseFailed.Wrap(err, "Failed to parse desired state file %s", file)
+	}
+	if len(state.Topology) == 0 {
+		return "", ErrTopologyParseFailed.New("No topology in desired state file %s", file)
+	}
+
+	topoData, err := yaml.Marshal(state.Topology)
+	if err != nil {
+		return "", errors.AddStack(err)
+	}
+	if err := parseTopologyData(file, topoData, out); err != nil {
+		return "", err
+	}
+	ExpandRelativeDir(out)
+	return state.Version, nil
+}
+
+// ApplyPlan is the operations converging a cluster to the desired state,
+// the new instances are scaled out, the instances absent in the desired
+// state are scaled in and the changed specs are reloaded
+type ApplyPlan struct {
+	ScaleOut  []byte   // the scale-out topology of the new instances
+	NewNodes  []string // the IDs of the new instances
+	ScaleIn   []string // the IDs of the instances absent in the desired state
+	Changes   []string // the changes of the global sections and existing instances
+	Reload    []string // the IDs of the existing instances whose spec is changed
+	ReloadAll bool     // the global sections are changed
+	Current   Topology // the current topology without the instances scaled in
+	Target    Topology // the desired topology without the new instances
+}
+
+// Converged returns true if there is nothing to do
+func (p *ApplyPlan) Converged() bool {
+	return len(p.NewNodes) == 0 && len(p.ScaleIn) == 0 && len(p.Changes) == 0
+}
+
+// instanceEntry is an instance of a topology marshaled as yaml
+type instanceEntry struct {
+	role string // the yaml key of the role
+	id   string
+	spec yaml.MapSlice
+}
+
+// topologyInstances returns the instances of the topology in order
+func topologyInstances(topo Topology) ([]instanceEntry, error) {
+	var insts []instanceEntry
+
+	v := reflect.Indirect(reflect.ValueOf(topo))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		field := v.Field(i)
+		if key == "" || field.Kind() != reflect.Slice {
+			continue
+		}
+		for j := 0; j < field.Len(); j++ {
+			inst, ok := field.Index(j).Interface().(InstanceSpec)
+			if !ok {
+				continue
+			}
+			data, err := yaml.Marshal(inst)
+			if err != nil {
+				return nil, errors.AddStack(err)
+			}
+			var spec yaml.MapSlice
+			if err := yaml.Unmarshal(data, &spec); err != nil {
+				return nil, errors.AddStack(err)
+			}
+			host := reflect.Indirect(field.Index(j)).FieldByName("Host").String()
+			insts = append(insts, instanceEntry{
+				role: key,
+				id:   fmt.Sprintf("%s:%d", host, inst.GetMainPort()),
+				spec: spec,
+			})
+		}
+	}
+	return insts, nil
+}
+
+// PlanApply compares the desired topology with the actual one and returns
+// the plan converging the cluster, newTopo creates empty topologies of the
+// same type
+func PlanApply(actual, desired Topology, newTopo func() Topology) (*ApplyPlan, error) {
+	actualInsts, err := topologyInstances(actual)
+	if err != nil {
+		return nil, err
+	}
+	desiredInsts, err := topologyInstances(desired)
+	if err != nil {
+		return nil, err
+	}
+	desiredByID := make(map[string]instanceEntry)
+	for _, inst := range desiredInsts {
+		desiredByID[inst.id] = inst
+	}
+
+	plan := &ApplyPlan{}
+	kept := set.NewStringSet()
+	current := make(map[string][]interface{})
+	target := make(map[string][]interface{})
+	for _, inst := range actualInsts {
+		want, ok := desiredByID[inst.id]
+		if !ok {
+			plan.ScaleIn = append(plan.ScaleIn, inst.id)
+			continue
+		}
+		if want.role != inst.role {
+			return nil, errors.Errorf("%s is in %s, it can't be moved to %s", inst.id, inst.role, want.role)
+		}
+		kept.Insert(inst.id)
+
+		spec := want.spec
+		specified := make(map[interface{}]bool)
+		for _, item := range spec {
+			specified[item.Key] = true
+		}
+		for _, item := range inst.spec {
+			name, _ := item.Key.(string)
+			if runtimeFields.Exist(name) && !specified[item.Key] {
+				spec = append(spec, item)
+			}
+		}
+		changes, err := valueChanges(inst.spec, spec)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			plan.Reload = append(plan.Reload, inst.id)
+			for _, c := range changes {
+				plan.Changes = append(plan.Changes, fmt.Sprintf("%s: %s", inst.id, c))
+			}
+		}
+		current[inst.role] = append(current[inst.role], inst.spec)
+		target[inst.role] = append(target[inst.role], spec)
+	}
+
+	var (
+		newRoles []string
+		newInsts = make(map[string][]interface{})
+	)
+	for _, inst := range desiredInsts {
+		if kept.Exist(inst.id) {
+			continue
+		}
+		if _, ok := newInsts[inst.role]; !ok {
+			newRoles = append(newRoles, inst.role)
+		}
+		newInsts[inst.role] = append(newInsts[inst.role], inst.spec)
+		plan.NewNodes = append(plan.NewNodes, inst.id)
+	}
+	if len(newRoles) > 0 {
+		var scaleOut yaml.MapSlice
+		for _, role := range newRoles {
+			scaleOut = append(scaleOut, yaml.MapItem{Key: role, Value: newInsts[role]})
+		}
+		if plan.ScaleOut, err = yaml.Marshal(scaleOut); err != nil {
+			return nil, errors.AddStack(err)
+		}
+	}
+
+	actualItems, err := marshalItems(actual)
+	if err != nil {
+		return nil, err
+	}
+	desiredItems, err := marshalItems(desired)
+	if err != nil {
+		return nil, err
+	}
+	desiredSections := make(map[interface{}]interface{})
+	for _, item := range desiredItems {
+		desiredSections[item.Key] = item.Value
+	}
+
+	var currentItems, targetItems yaml.MapSlice
+	for _, item := range actualItems {
+		key, _ := item.Key.(string)
+		switch {
+		case globalSections.Exist(key):
+			currentItems = append(currentItems, item)
+			changes, err := valueChanges(item.Value, desiredSections[key])
+			if err != nil {
+				return nil, err
+			}
+			if len(changes) == 0 {
+				targetItems = append(targetItems, item)
+				continue
+			}
+			plan.ReloadAll = true
+			for _, c := range changes {
+				plan.Changes = append(plan.Changes, fmt.Sprintf("%s.%s", key, c))
+			}
+			targetItems = append(targetItems, yaml.MapItem{Key: key, Value: desiredSections[key]})
+		case len(current[key]) > 0:
+			currentItems = append(currentItems, yaml.MapItem{Key: key, Value: current[key]})
+			targetItems = append(targetItems, yaml.MapItem{Key: key, Value: target[key]})
+		}
+	}
+
+	if plan.Current, err = unmarshalItems(currentItems, newTopo()); err != nil {
+		return nil, err
+	}
+	if plan.Target, err = unmarshalItems(targetItems, newTopo()); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func marshalItems(topo Topology) (yaml.MapSlice, error) {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var items yaml.MapSlice
+	if err := yaml.Unmarshal(data, &items); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return items, nil
+}
+
+func unmarshalItems(items yaml.MapSlice, out Topology) (Topology, error) {
+	data, err := yaml.Marshal(items)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return out, nil
+}
+
+// valueChanges returns the changes between two yaml mappings, formatted as
+// `<path>: <old> -> <new>`
+func valueChanges(from, to interface{}) ([]string, error) {
+	oldValues, err := flattenValue(from)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := flattenValue(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for path, v := range newValues {
+		o, ok := oldValues[path]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s: (unset) -> %v", path, v))
+		case fmt.Sprint(o) != fmt.Sprint(v):
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", path, o, v))
+		}
+	}
+	for path, o := range oldValues {
+		if _, ok := newValues[path]; !ok {
+			changes = append(changes, fmt.Sprintf("%s: %v -> (unset)", path, o))
+		}
+	}
+	sort.Strings(changes)
+	return changes, nil
+}
+
+func flattenValue(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return FlattenMap(m), nil
+}
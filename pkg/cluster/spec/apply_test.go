@@ -0,0 +1,106 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPlanApply(t *testing.T) {
+	actual := &Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  user: tidb
+  deploy_dir: /tidb-deploy
+  data_dir: /tidb-data
+server_configs:
+  tikv:
+    storage.reserve-space: 1GB
+tikv_servers:
+  - host: 172.16.5.1
+    arch: arm64
+    os: linux
+  - host: 172.16.5.2
+    arch: arm64
+    os: linux
+    config:
+      log.level: info
+  - host: 172.16.5.3
+    arch: arm64
+    os: linux
+`), actual)
+	assert.Nil(t, err)
+	ExpandRelativeDir(actual)
+
+	file := filepath.Join(t.TempDir(), "desired.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(`
+version: v6.1.0
+topology:
+  global:
+    user: tidb
+    deploy_dir: /tidb-deploy
+    data_dir: /tidb-data
+  server_configs:
+    tikv:
+      storage.reserve-space: 1GB
+  tikv_servers:
+    - host: 172.16.5.1
+    - host: 172.16.5.2
+      config:
+        log.level: warn
+    - host: 172.16.5.4
+`), 0644))
+	desired := &Specification{}
+	version, err := ParseDesiredState(file, desired)
+	assert.Nil(t, err)
+	assert.Equal(t, "v6.1.0", version)
+
+	newTopo := func() Topology { return &Specification{} }
+	plan, err := PlanApply(actual, desired, newTopo)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"172.16.5.4:20160"}, plan.NewNodes)
+	assert.Equal(t, []string{"172.16.5.3:20160"}, plan.ScaleIn)
+	assert.Equal(t, []string{"172.16.5.2:20160"}, plan.Reload)
+	assert.Equal(t, []string{"172.16.5.2:20160: config.log.level: info -> warn"}, plan.Changes)
+	assert.False(t, plan.ReloadAll)
+
+	scaleOut := &Specification{}
+	assert.Nil(t, yaml.Unmarshal(plan.ScaleOut, scaleOut))
+	assert.Len(t, scaleOut.TiKVServers, 1)
+	assert.Equal(t, "172.16.5.4", scaleOut.TiKVServers[0].Host)
+	assert.Equal(t, "/tidb-deploy/tikv-20160", scaleOut.TiKVServers[0].DeployDir)
+
+	target := plan.Target.(*Specification)
+	assert.Len(t, target.TiKVServers, 2)
+	assert.Equal(t, "arm64", target.TiKVServers[0].Arch)
+	assert.Equal(t, "warn", target.TiKVServers[1].Config["log.level"])
+	assert.Len(t, plan.Current.(*Specification).TiKVServers, 2)
+
+	// the global sections are changed
+	desired.ServerConfigs.TiKV = map[string]interface{}{"storage.reserve-space": "2GB"}
+	plan, err = PlanApply(actual, desired, newTopo)
+	assert.Nil(t, err)
+	assert.True(t, plan.ReloadAll)
+	assert.Contains(t, plan.Changes, "server_configs.tikv.storage.reserve-space: 1GB -> 2GB")
+
+	// nothing to do if the cluster is converged
+	plan, err = PlanApply(actual, actual, newTopo)
+	assert.Nil(t, err)
+	assert.True(t, plan.Converged())
+}
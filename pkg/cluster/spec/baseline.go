@@ -0,0 +1,156 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alessio/shellescape"
+)
+
+// BaselineRecord is the result of the micro benchmarks on the hosts of a
+// cluster, it's compared with the current result to find the degradation
+// of the hardware
+type BaselineRecord struct {
+	Time  time.Time                `yaml:"time"`
+	Hosts map[string]*HostBaseline `yaml:"hosts"`
+}
+
+// HostBaseline is the result of the micro benchmarks on a host
+type HostBaseline struct {
+	// FsyncLatency is the average latency of 4KiB synchronized writes in
+	// each data directory on the host, in microseconds
+	FsyncLatency map[string]int64 `yaml:"fsync_latency_us"`
+	// CPUHashSpeed is the speed of sha256 on a single core, in MiB/s
+	CPUHashSpeed int64 `yaml:"cpu_sha256_mibps"`
+	// RTT is the average round trip time to the other hosts, in milliseconds
+	RTT map[string]float64 `yaml:"rtt_ms,omitempty"`
+}
+
+const (
+	baselineFsyncCount = 200
+	baselineHashSize   = 256 // MiB
+)
+
+// BaselineScript returns the shell script running the micro benchmarks, the
+// fsync latency is measured in each of dirs, the result is printed as
+// key=value lines and parsed by ParseHostBaseline
+func BaselineScript(dirs []string, peers []string) string {
+	var b strings.Builder
+	for _, dir := range dirs {
+		quoted := shellescape.Quote(dir)
+		fmt.Fprintf(&b, "f=%s/.tiup_baseline; ", quoted)
+		fmt.Fprintf(&b, "s=$(date +%%s%%N); if dd if=/dev/zero of=\"$f\" bs=4k count=%d oflag=dsync >/dev/null 2>&1; then e=$(date +%%s%%N); ", baselineFsyncCount)
+		fmt.Fprintf(&b, "echo fsync_latency_us=$(( (e-s)/%d/1000 )) %s; fi; rm -f \"$f\"; ", baselineFsyncCount, quoted)
+	}
+	fmt.Fprintf(&b, "s=$(date +%%s%%N); dd if=/dev/zero bs=1M count=%d 2>/dev/null | sha256sum >/dev/null; e=$(date +%%s%%N); ", baselineHashSize)
+	fmt.Fprintf(&b, "echo cpu_sha256_mibps=$(( %d*1000000000/(e-s+1) )); ", baselineHashSize)
+	for _, peer := range peers {
+		fmt.Fprintf(&b, "echo rtt_ms_%s=$(ping -c 3 -q -W 1 %s 2>/dev/null | awk -F/ '/^rtt|^round-trip/{print $5}'); ", peer, shellescape.Quote(peer))
+	}
+	return b.String()
+}
+
+// ParseHostBaseline parses the output of BaselineScript
+func ParseHostBaseline(output string) (*HostBaseline, error) {
+	hb := &HostBaseline{
+		FsyncLatency: make(map[string]int64),
+		RTT:          make(map[string]float64),
+	}
+	for _, line := range strings.Split(output, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			continue
+		}
+		var err error
+		switch {
+		case kv[0] == "fsync_latency_us":
+			// the latency is followed by the dir
+			fields := strings.SplitN(kv[1], " ", 2)
+			if len(fields) != 2 {
+				err = fmt.Errorf("no directory")
+				break
+			}
+			var latency int64
+			if latency, err = strconv.ParseInt(fields[0], 10, 64); err == nil {
+				hb.FsyncLatency[fields[1]] = latency
+			}
+		case kv[0] == "cpu_sha256_mibps":
+			hb.CPUHashSpeed, err = strconv.ParseInt(kv[1], 10, 64)
+		case strings.HasPrefix(kv[0], "rtt_ms_"):
+			var rtt float64
+			if rtt, err = strconv.ParseFloat(kv[1], 64); err == nil {
+				hb.RTT[strings.TrimPrefix(kv[0], "rtt_ms_")] = rtt
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid benchmark result '%s': %s", line, err)
+		}
+	}
+	if len(hb.FsyncLatency) == 0 && hb.CPUHashSpeed == 0 {
+		return nil, fmt.Errorf("no benchmark result found")
+	}
+	return hb, nil
+}
+
+// BaselineDiff is the comparison of a metric with the baseline
+type BaselineDiff struct {
+	Host     string
+	Metric   string
+	Baseline float64
+	Current  float64
+	// Change is the ratio of degradation, positive if worse than baseline
+	Change float64
+}
+
+// CompareBaseline compares the current result with the baseline, the hosts
+// or peers not in both records are skipped
+func CompareBaseline(baseline, current *BaselineRecord) []BaselineDiff {
+	var diffs []BaselineDiff
+	add := func(host, metric string, base, cur float64, higherIsBetter bool) {
+		if base <= 0 || cur <= 0 {
+			return
+		}
+		change := (cur - base) / base
+		if higherIsBetter {
+			change = (base - cur) / base
+		}
+		diffs = append(diffs, BaselineDiff{host, metric, base, cur, change})
+	}
+
+	for host, base := range baseline.Hosts {
+		cur, ok := current.Hosts[host]
+		if !ok {
+			continue
+		}
+		for dir, latency := range base.FsyncLatency {
+			add(host, "fsync latency of "+dir+" (us)", float64(latency), float64(cur.FsyncLatency[dir]), false)
+		}
+		add(host, "cpu sha256 (MiB/s)", float64(base.CPUHashSpeed), float64(cur.CPUHashSpeed), true)
+		for peer, rtt := range base.RTT {
+			add(host, "rtt to "+peer+" (ms)", rtt, cur.RTT[peer], false)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Host != diffs[j].Host {
+			return diffs[i].Host < diffs[j].Host
+		}
+		return diffs[i].Metric < diffs[j].Metric
+	})
+	return diffs
+}
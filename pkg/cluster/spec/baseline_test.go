@@ -0,0 +1,58 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseline(t *testing.T) {
+	hb, err := ParseHostBaseline("fsync_latency_us=850 /data1\nfsync_latency_us=900 /tidb data\ncpu_sha256_mibps=400\nrtt_ms_10.0.1.2=0.250\nrtt_ms_10.0.1.3=\n")
+	assert.Nil(t, err)
+	assert.Equal(t, &HostBaseline{
+		FsyncLatency: map[string]int64{"/data1": 850, "/tidb data": 900},
+		CPUHashSpeed: 400,
+		RTT:          map[string]float64{"10.0.1.2": 0.25},
+	}, hb)
+
+	_, err = ParseHostBaseline("dd: failed to open\n")
+	assert.NotNil(t, err)
+	_, err = ParseHostBaseline("fsync_latency_us=850\n")
+	assert.NotNil(t, err)
+
+	baseline := &BaselineRecord{Hosts: map[string]*HostBaseline{"10.0.1.1": hb}}
+	current := &BaselineRecord{Hosts: map[string]*HostBaseline{
+		"10.0.1.1": {
+			FsyncLatency: map[string]int64{"/data1": 1700},
+			CPUHashSpeed: 400,
+			RTT:          map[string]float64{"10.0.1.2": 0.25},
+		},
+	}}
+	diffs := CompareBaseline(baseline, current)
+	assert.Equal(t, 3, len(diffs))
+	assert.Equal(t, "cpu sha256 (MiB/s)", diffs[0].Metric)
+	assert.Equal(t, 0.0, diffs[0].Change)
+	assert.Equal(t, "fsync latency of /data1 (us)", diffs[1].Metric)
+	assert.Equal(t, 1.0, diffs[1].Change)
+}
+
+func TestBaselineScript(t *testing.T) {
+	script := BaselineScript([]string{"/data1", "/tidb data"}, []string{"10.0.1.2"})
+	assert.Contains(t, script, "f=/data1/.tiup_baseline;")
+	assert.Contains(t, script, "f='/tidb data'/.tiup_baseline;")
+	assert.Contains(t, script, "echo fsync_latency_us=$(( (e-s)/200/1000 )) '/tidb data'; fi;")
+	assert.Contains(t, script, "ping -c 3 -q -W 1 10.0.1.2 ")
+}
@@ -23,8 +23,8 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
-	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/tidbver"
 )
@@ -43,6 +43,7 @@ type CDCSpec struct {
 	Offline         bool                   `yaml:"offline,omitempty"`
 	GCTTL           int64                  `yaml:"gc-ttl,omitempty" validate:"gc-ttl:editable"`
 	TZ              string                 `yaml:"tz,omitempty" validate:"tz:editable"`
+	TiCDCClusterID  string                 `yaml:"ticdc_cluster_id,omitempty" validate:"ticdc_cluster_id:editable"`
 	NumaNode        string                 `yaml:"numa_node,omitempty" validate:"numa_node:editable"`
 	Config          map[string]interface{} `yaml:"config,omitempty" validate:"config:ignore"`
 	ResourceControl meta.ResourceControl   `yaml:"resource_control,omitempty" validate:"resource_control:editable"`
@@ -142,7 +143,11 @@ func (i *CDCInstance) ScaleConfig(
 	defer func() {
 		i.topo = s
 	}()
-	i.topo = mustBeClusterTopo(topo)
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
+	}
+	i.topo = cluster
 
 	return i.InitConfig(ctx, e, clusterName, clusterVersion, user, paths)
 }
@@ -170,6 +175,12 @@ func (i *CDCInstance) InitConfig(
 			return errors.New("server_config is only supported with TiCDC version v4.0.13 or later")
 		}
 	}
+	if enableTLS && !tidbver.TiCDCSupportTLS(clusterVersion) {
+		return errors.New("TLS is only supported with TiCDC version v4.0.3 or later")
+	}
+	if spec.TiCDCClusterID != "" && !tidbver.TiCDCSupportClusterID(clusterVersion) {
+		return errors.New("ticdc_cluster_id is only supported with TiCDC version v6.2.0 or later")
+	}
 
 	cfg := scripts.NewCDCScript(
 		i.GetHost(),
@@ -177,20 +188,23 @@ func (i *CDCInstance) InitConfig(
 		paths.Log,
 		enableTLS,
 		spec.GCTTL,
-		spec.TZ,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(topo.Endpoints(deployUser)...)
+		i.TimeZone(topo.GlobalOptions),
+	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).WithClusterID(spec.TiCDCClusterID).
+		AppendEndpoints(topo.Endpoints(deployUser)...)
 
 	// doesn't work
 	if _, err := i.setTLSConfig(ctx, false, nil, paths); err != nil {
 		return err
 	}
+	dataDir := ""
 	if len(paths.Data) != 0 {
-		cfg = cfg.PatchByVersion(clusterVersion, paths.Data[0])
+		dataDir = paths.Data[0]
 	}
+	cfg = cfg.PatchByVersion(clusterVersion, dataDir)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_cdc_%s_%d.sh", i.GetHost(), i.GetPort()))
 
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_cdc.sh")
@@ -217,38 +231,86 @@ func (i *CDCInstance) GetAddr() string {
 	return fmt.Sprintf("%s:%d", i.GetHost(), i.GetPort())
 }
 
-// PreRestart implements RollingUpdateInstance interface.
-// All errors are ignored, to trigger hard restart.
-func (i *CDCInstance) PreRestart(ctx context.Context, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) error {
-	tidbTopo, ok := topo.(*Specification)
-	if !ok {
-		panic("should be type of tidb topology")
+// API implements RollingUpdateInstance interface.
+func (i *CDCInstance) API(topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) (ComponentAPI, error) {
+	tidbTopo, err := AsClusterTopology(topo)
+	if err != nil {
+		return nil, err
+	}
+	return &cdcAPI{
+		ins:               i,
+		topo:              tidbTopo,
+		apiTimeoutSeconds: apiTimeoutSeconds,
+		tlsCfg:            tlsCfg,
+	}, nil
+}
+
+// cdcAPI resigns the owner and drains the capture before restarting a TiCDC
+// instance. All errors are ignored, to trigger hard restart.
+type cdcAPI struct {
+	NopComponentAPI
+	ins               *CDCInstance
+	topo              *Specification
+	apiTimeoutSeconds int
+	tlsCfg            *tls.Config
+
+	// the capture to drain, empty if it should not be drained
+	captureID string
+	start     time.Time
+}
+
+// RestartHooks implements RestartHookDescriber interface.
+func (a *cdcAPI) RestartHooks() []RestartHook {
+	var hooks []RestartHook
+	if len(a.topo.CDCServers) > 1 {
+		hooks = append(hooks,
+			RestartHook{Name: RestartHookResignLeader, Action: "resign the owner if it's the owner"},
+			RestartHook{Name: RestartHookDrain, Action: "drain the capture", Timeout: time.Second * time.Duration(a.apiTimeoutSeconds)},
+		)
 	}
+	return append(hooks, RestartHook{Name: RestartHookHealth, Action: "check the capture is alive", Timeout: a.client(context.Background()).RetryTimeout()})
+}
 
-	logger, ok := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
-	if !ok {
-		panic("logger not found")
+// client returns the client of the captures, the instance is requested first
+// and the others are the fallbacks, so the captures queried for each instance
+// are cached as one if the responses are cached in ctx
+func (a *cdcAPI) client(ctx context.Context) *api.CDCOpenAPIClient {
+	addrs := []string{a.ins.GetAddr()}
+	for _, s := range a.topo.CDCServers {
+		if addr := fmt.Sprintf("%s:%d", s.Host, s.Port); addr != addrs[0] {
+			addrs = append(addrs, addr)
+		}
 	}
+	return api.NewCDCOpenAPIClient(addrs, api.RequestTimeout(ctx, 5*time.Second), a.tlsCfg)
+}
 
-	address := i.GetAddr()
+// ResignLeader implements ComponentAPI interface.
+func (a *cdcAPI) ResignLeader(ctx context.Context) error {
+	logger, err := ContextLogger(ctx)
+	if err != nil {
+		return err
+	}
+
+	address := a.ins.GetAddr()
+	a.captureID = ""
 	// cdc rolling upgrade strategy only works if there are more than 2 captures
-	if len(tidbTopo.CDCServers) <= 1 {
+	if len(a.topo.CDCServers) <= 1 {
 		logger.Debugf("cdc pre-restart skipped, only one capture in the topology, addr: %s", address)
 		return nil
 	}
 
-	start := time.Now()
-	client := api.NewCDCOpenAPIClient(ctx, []string{address}, 5*time.Second, tlsCfg)
-	captures, err := client.GetAllCaptures()
+	a.start = time.Now()
+	client := a.client(ctx)
+	captures, err := client.GetAllCaptures(ctx)
 	if err != nil {
-		logger.Warnf("cdc pre-restart skipped, cannot get all captures, trigger hard restart, addr: %s, elapsed: %+v", address, time.Since(start))
+		logger.Warnf("cdc pre-restart skipped, cannot get all captures, trigger hard restart, addr: %s, elapsed: %+v", address, time.Since(a.start))
 		return nil
 	}
 
 	// this may happen all other captures crashed, only this one alive,
 	// no need to drain the capture, just return it to trigger hard restart.
 	if len(captures) <= 1 {
-		logger.Debugf("cdc pre-restart finished, only one alive capture found, trigger hard restart, addr: %s, elapsed: %+v", address, time.Since(start))
+		logger.Debugf("cdc pre-restart finished, only one alive capture found, trigger hard restart, addr: %s, elapsed: %+v", address, time.Since(a.start))
 		return nil
 	}
 
@@ -269,41 +331,60 @@ func (i *CDCInstance) PreRestart(ctx context.Context, topo Topology, apiTimeoutS
 
 	// this may happen if the capture crashed right away.
 	if !found {
-		logger.Debugf("cdc pre-restart finished, cannot found the capture, trigger hard restart, captureID: %s, addr: %s, elapsed: %+v", captureID, address, time.Since(start))
+		logger.Debugf("cdc pre-restart finished, cannot found the capture, trigger hard restart, captureID: %s, addr: %s, elapsed: %+v", captureID, address, time.Since(a.start))
 		return nil
 	}
 
 	if isOwner {
-		if err := client.ResignOwner(); err != nil {
+		if err := client.ResignOwner(ctx); err != nil {
 			// if resign the owner failed, no more need to drain the current capture,
 			// since it's not allowed by the cdc.
 			// return nil to trigger hard restart.
-			logger.Debugf("cdc pre-restart finished, resign owner failed, trigger hard restart, captureID: %s, addr: %s, elapsed: %+v", captureID, address, time.Since(start))
+			logger.Debugf("cdc pre-restart finished, resign owner failed, trigger hard restart, captureID: %s, addr: %s, elapsed: %+v", captureID, address, time.Since(a.start))
 			return nil
 		}
 	}
 
-	if err := client.DrainCapture(captureID, apiTimeoutSeconds); err != nil {
-		logger.Debugf("cdc pre-restart finished, drain the capture failed, captureID: %s, addr: %s, err: %+v, elapsed: %+v", captureID, address, err, time.Since(start))
+	a.captureID = captureID
+	return nil
+}
+
+// Drain implements ComponentAPI interface.
+func (a *cdcAPI) Drain(ctx context.Context) error {
+	if a.captureID == "" {
 		return nil
 	}
 
-	logger.Debugf("cdc pre-restart success, captureID: %s, addr: %s, elapsed: %+v", captureID, address, time.Since(start))
+	logger, err := ContextLogger(ctx)
+	if err != nil {
+		return err
+	}
+
+	address := a.ins.GetAddr()
+	client := a.client(ctx).WithDrainObserver(func(p *api.DrainProgress) {
+		ctxt.GetInner(ctx).Ev.PublishDrainProgress(a.ins.ID(), p)
+	})
+	if err := client.DrainCapture(ctx, a.captureID, a.apiTimeoutSeconds); err != nil {
+		logger.Debugf("cdc pre-restart finished, drain the capture failed, captureID: %s, addr: %s, err: %+v, elapsed: %+v", a.captureID, address, err, time.Since(a.start))
+		return nil
+	}
+
+	logger.Debugf("cdc pre-restart success, captureID: %s, addr: %s, elapsed: %+v", a.captureID, address, time.Since(a.start))
 	return nil
 }
 
-// PostRestart implements RollingUpdateInstance interface.
-func (i *CDCInstance) PostRestart(ctx context.Context, topo Topology, tlsCfg *tls.Config) error {
-	logger, ok := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
-	if !ok {
-		panic("logger not found")
+// Health implements ComponentAPI interface.
+func (a *cdcAPI) Health(ctx context.Context) error {
+	logger, err := ContextLogger(ctx)
+	if err != nil {
+		return err
 	}
 
 	start := time.Now()
-	address := i.GetAddr()
+	address := a.ins.GetAddr()
 
-	client := api.NewCDCOpenAPIClient(ctx, []string{address}, 5*time.Second, tlsCfg)
-	err := client.IsCaptureAlive()
+	client := api.NewCDCOpenAPIClient([]string{address}, api.RequestTimeout(ctx, 5*time.Second), a.tlsCfg)
+	err = client.IsCaptureAlive(ctx)
 	if err != nil {
 		logger.Debugf("cdc post-restart finished, get capture status failed, addr: %s, err: %+v, elapsed: %+v", address, err, time.Since(start))
 		return nil
@@ -0,0 +1,237 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// CloudMetadataScript queries the instance metadata service of AWS, GCP and
+// Azure in order, and prints the result as key=value lines, nothing is
+// printed if the host is not a cloud instance
+const CloudMetadataScript = `md() { curl -sf -m 2 "$@"; }
+if token=$(md -X PUT -H "X-aws-ec2-metadata-token-ttl-seconds: 60" http://169.254.169.254/latest/api/token); then
+  h="X-aws-ec2-metadata-token: $token"
+  echo provider=aws
+  echo region=$(md -H "$h" http://169.254.169.254/latest/meta-data/placement/region)
+  echo zone=$(md -H "$h" http://169.254.169.254/latest/meta-data/placement/availability-zone)
+  echo instance=$(md -H "$h" http://169.254.169.254/latest/meta-data/instance-id)
+elif zone=$(md -H "Metadata-Flavor: Google" http://metadata.google.internal/computeMetadata/v1/instance/zone); then
+  echo provider=gcp
+  echo zone=${zone##*/}
+  echo instance=$(md -H "Metadata-Flavor: Google" http://metadata.google.internal/computeMetadata/v1/instance/id)
+elif compute=$(md -H "Metadata: true" "http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01&format=json"); then
+  echo provider=azure
+  echo azure=$compute
+fi`
+
+// the label names inferred from the cloud metadata
+const (
+	CloudLabelRegion = "region"
+	CloudLabelZone   = "zone"
+	CloudLabelHost   = "host"
+)
+
+// CloudLabelNames is the location labels set in PD if it's not configured
+var CloudLabelNames = []string{CloudLabelRegion, CloudLabelZone, CloudLabelHost}
+
+// CloudLocation is the location of a host reported by the cloud metadata
+type CloudLocation struct {
+	Provider string
+	Region   string
+	Zone     string
+	Instance string
+}
+
+// ParseCloudLocation parses the output of CloudMetadataScript, nil is
+// returned if the host is not a cloud instance
+func ParseCloudLocation(output string) (*CloudLocation, error) {
+	kv := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) == 2 {
+			kv[parts[0]] = parts[1]
+		}
+	}
+	if kv["provider"] == "" {
+		return nil, nil
+	}
+
+	loc := &CloudLocation{
+		Provider: kv["provider"],
+		Region:   kv["region"],
+		Zone:     kv["zone"],
+		Instance: kv["instance"],
+	}
+	switch loc.Provider {
+	case "gcp":
+		// the zone is like us-central1-a, and the region is us-central1
+		if i := strings.LastIndex(loc.Zone, "-"); i > 0 {
+			loc.Region = loc.Zone[:i]
+		}
+	case "azure":
+		var compute struct {
+			Location string `json:"location"`
+			Zone     string `json:"zone"`
+			VMID     string `json:"vmId"`
+		}
+		if err := json.Unmarshal([]byte(kv["azure"]), &compute); err != nil {
+			return nil, errors.Annotate(err, "failed to parse the metadata of azure")
+		}
+		loc.Region = compute.Location
+		loc.Instance = compute.VMID
+		// the zone of azure is a number in the region, and it's empty if
+		// the vm is not deployed in availability zones
+		if compute.Zone != "" {
+			loc.Zone = fmt.Sprintf("%s-%s", compute.Location, compute.Zone)
+		}
+	}
+	return loc, nil
+}
+
+// Label returns the value of the location label
+func (l *CloudLocation) Label(name string) string {
+	switch name {
+	case CloudLabelRegion:
+		return l.Region
+	case CloudLabelZone:
+		return l.Zone
+	case CloudLabelHost:
+		return l.Instance
+	}
+	return ""
+}
+
+// CloudLabelIssue is a label of an instance that doesn't match the cloud
+// metadata
+type CloudLabelIssue struct {
+	Host       string
+	Instance   string
+	Label      string
+	Configured string
+	Detected   string
+}
+
+func (i CloudLabelIssue) String() string {
+	if i.Configured == "" {
+		return fmt.Sprintf("%s: label %s is missing, it's %s in the cloud metadata", i.Instance, i.Label, i.Detected)
+	}
+	return fmt.Sprintf("%s: label %s is %s, but it's %s in the cloud metadata", i.Instance, i.Label, i.Configured, i.Detected)
+}
+
+// cloudLabeledInstance is an instance whose location labels are inferred
+// from the cloud metadata, the labels are set in config at path
+type cloudLabeledInstance struct {
+	host     string
+	instance string
+	config   *map[string]interface{}
+	path     string
+}
+
+// cloudLabeledInstances returns the PD, TiKV and TiFlash instances of topo,
+// the labels of PD are in the labels of its config, and the ones of TiFlash
+// are in the server.labels of its learner config
+func cloudLabeledInstances(topo *Specification) []cloudLabeledInstance {
+	var insts []cloudLabeledInstance
+	for _, pd := range topo.PDServers {
+		insts = append(insts, cloudLabeledInstance{pd.Host, fmt.Sprintf("%s:%d", pd.Host, pd.GetMainPort()), &pd.Config, "labels"})
+	}
+	for _, kv := range topo.TiKVServers {
+		insts = append(insts, cloudLabeledInstance{kv.Host, fmt.Sprintf("%s:%d", kv.Host, kv.GetMainPort()), &kv.Config, "server.labels"})
+	}
+	for _, flash := range topo.TiFlashServers {
+		insts = append(insts, cloudLabeledInstance{flash.Host, fmt.Sprintf("%s:%d", flash.Host, flash.GetMainPort()), &flash.LearnerConfig, "server.labels"})
+	}
+	return insts
+}
+
+// CloudLabeledHosts returns the hosts of the instances whose labels are
+// inferred from the cloud metadata, and their SSH ports
+func CloudLabeledHosts(topo *Specification) map[string]int {
+	hosts := make(map[string]int)
+	topo.IterInstance(func(inst Instance) {
+		switch inst.ComponentName() {
+		case ComponentPD, ComponentTiKV, ComponentTiFlash:
+			hosts[inst.GetHost()] = inst.GetSSHPort()
+		}
+	})
+	return hosts
+}
+
+// FillCloudLocationLabels sets replication.location-labels of PD to
+// CloudLabelNames if it's not configured, and returns the location labels
+func FillCloudLocationLabels(topo *Specification) ([]string, error) {
+	locLabels, err := topo.LocationLabels()
+	if err != nil || len(locLabels) > 0 {
+		return locLabels, err
+	}
+	value := make([]interface{}, 0, len(CloudLabelNames))
+	for _, name := range CloudLabelNames {
+		value = append(value, name)
+	}
+	topo.ServerConfigs.PD = MergeConfig(topo.ServerConfigs.PD, map[string]interface{}{
+		"replication.location-labels": value,
+	})
+	return CloudLabelNames, nil
+}
+
+// InferCloudLabels verifies the labels of the PD, TiKV and TiFlash instances
+// in locLabels with the locations of the hosts reported by the cloud
+// metadata. If fill is true, the missing labels are set to the detected
+// values, otherwise they are reported as issues. The host label is only
+// filled and never compared as it's usually named by the users.
+func InferCloudLabels(topo *Specification, locLabels []string, locations map[string]*CloudLocation, fill bool) []CloudLabelIssue {
+	enabled := set.NewStringSet(locLabels...)
+
+	var issues []CloudLabelIssue
+	for _, inst := range cloudLabeledInstances(topo) {
+		loc := locations[inst.host]
+		if loc == nil {
+			continue
+		}
+		labels := labelsAt(*inst.config, inst.path)
+
+		missing := make(map[string]interface{})
+		for _, name := range CloudLabelNames {
+			detected := loc.Label(name)
+			if !enabled.Exist(name) || detected == "" {
+				continue
+			}
+			configured, ok := labels[name]
+			switch {
+			case !ok && fill:
+				missing[name] = detected
+			case !ok || (name != CloudLabelHost && configured != detected):
+				issues = append(issues, CloudLabelIssue{
+					Host:       inst.host,
+					Instance:   inst.instance,
+					Label:      name,
+					Configured: configured,
+					Detected:   detected,
+				})
+			}
+		}
+		if len(missing) > 0 {
+			*inst.config = MergeConfig(*inst.config, map[string]interface{}{
+				inst.path: missing,
+			})
+		}
+	}
+	return issues
+}
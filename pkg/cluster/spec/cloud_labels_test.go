@@ -0,0 +1,105 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseCloudLocation(t *testing.T) {
+	loc, err := ParseCloudLocation("")
+	assert.Nil(t, err)
+	assert.Nil(t, loc)
+
+	loc, err = ParseCloudLocation("provider=aws\nregion=us-west-2\nzone=us-west-2a\ninstance=i-0123\n")
+	assert.Nil(t, err)
+	assert.Equal(t, &CloudLocation{Provider: "aws", Region: "us-west-2", Zone: "us-west-2a", Instance: "i-0123"}, loc)
+
+	loc, err = ParseCloudLocation("provider=gcp\nzone=us-central1-a\ninstance=42\n")
+	assert.Nil(t, err)
+	assert.Equal(t, &CloudLocation{Provider: "gcp", Region: "us-central1", Zone: "us-central1-a", Instance: "42"}, loc)
+
+	loc, err = ParseCloudLocation(`provider=azure
+azure={"location":"eastus","zone":"2","vmId":"abc"}
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, &CloudLocation{Provider: "azure", Region: "eastus", Zone: "eastus-2", Instance: "abc"}, loc)
+}
+
+func TestInferCloudLabels(t *testing.T) {
+	topo := &Specification{}
+	err := yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.3
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+    config:
+      server.labels: { zone: us-west-2b, host: kv2 }
+tiflash_servers:
+  - host: 172.16.5.3
+`), topo)
+	assert.Nil(t, err)
+	locations := map[string]*CloudLocation{
+		"172.16.5.1": {Provider: "aws", Region: "us-west-2", Zone: "us-west-2a", Instance: "i-1"},
+		"172.16.5.2": {Provider: "aws", Region: "us-west-2", Zone: "us-west-2a", Instance: "i-2"},
+		"172.16.5.3": {Provider: "aws", Region: "us-west-2", Zone: "us-west-2c", Instance: "i-3"},
+	}
+	assert.Equal(t, map[string]int{"172.16.5.1": 22, "172.16.5.2": 22, "172.16.5.3": 22}, CloudLabeledHosts(topo))
+
+	// the location labels of PD are not set, no label is checked
+	lbs, err := topo.LocationLabels()
+	assert.Nil(t, err)
+	assert.Empty(t, InferCloudLabels(topo, lbs, locations, false))
+
+	lbs, err = FillCloudLocationLabels(topo)
+	assert.Nil(t, err)
+	assert.Equal(t, CloudLabelNames, lbs)
+	lbs, err = topo.LocationLabels()
+	assert.Nil(t, err)
+	assert.Equal(t, CloudLabelNames, lbs)
+
+	issues := InferCloudLabels(topo, lbs, locations, true)
+	labels, err := topo.TiKVServers[0].Labels()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"region": "us-west-2", "zone": "us-west-2a", "host": "i-1"}, labels)
+	labels, err = topo.TiKVServers[1].Labels()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"region": "us-west-2", "zone": "us-west-2b", "host": "kv2"}, labels)
+	assert.Equal(t, []CloudLabelIssue{{
+		Host:       "172.16.5.2",
+		Instance:   "172.16.5.2:20160",
+		Label:      "zone",
+		Configured: "us-west-2b",
+		Detected:   "us-west-2a",
+	}}, issues)
+
+	// the labels of PD and TiFlash are in their own configs
+	expected := map[string]string{"region": "us-west-2", "zone": "us-west-2c", "host": "i-3"}
+	assert.Equal(t, expected, labelsAt(topo.PDServers[0].Config, "labels"))
+	assert.Equal(t, expected, configLabels(topo.TiFlashServers[0].LearnerConfig))
+	assert.Empty(t, configLabels(topo.TiFlashServers[0].Config))
+
+	// only the label mismatched is reported in verifying
+	topo.TiFlashServers[0].LearnerConfig = MergeConfig(topo.TiFlashServers[0].LearnerConfig, map[string]interface{}{
+		"server.labels": map[string]interface{}{"zone": "us-west-2a"},
+	})
+	issues = InferCloudLabels(topo, lbs, locations, false)
+	assert.Len(t, issues, 2)
+	assert.Equal(t, "172.16.5.3:9000", issues[1].Instance)
+	assert.Equal(t, "us-west-2a", issues[1].Configured)
+}
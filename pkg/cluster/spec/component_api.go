@@ -0,0 +1,250 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"time"
+
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// ErrNoLoggerInContext is returned if the context passed to the component
+// API has no logger inside.
+var ErrNoLoggerInContext = errNS.NewType("no_logger_in_context")
+
+// ComponentAPI is the API of a component used to restart an instance of it
+// gracefully, the methods not supported by the component should be no-op.
+type ComponentAPI interface {
+	// ResignLeader transfers the leaderships on the instance to others
+	// before it is restarted.
+	ResignLeader(ctx context.Context) error
+	// Drain moves the workload of the instance to others before it is restarted.
+	Drain(ctx context.Context) error
+	// Health waits until the instance is healthy after it is restarted.
+	Health(ctx context.Context) error
+	// Ready makes the instance accept workload again after it is healthy.
+	Ready(ctx context.Context) error
+}
+
+// the names of the hooks of ComponentAPI run around restarting an instance
+const (
+	RestartHookResignLeader = "resign-leader"
+	RestartHookDrain        = "drain"
+	RestartHookHealth       = "health"
+	RestartHookReady        = "ready"
+)
+
+// RestartHookNames are the names of the hooks in the order they run
+var RestartHookNames = []string{RestartHookResignLeader, RestartHookDrain, RestartHookHealth, RestartHookReady}
+
+// ErrUnknownRestartHook is returned if the name of a hook is not supported
+var ErrUnknownRestartHook = errNS.NewType("unknown_restart_hook")
+
+// ErrInvalidRestartHooks is returned if the hooks can't be disabled together
+var ErrInvalidRestartHooks = errNS.NewType("invalid_restart_hooks")
+
+// RestartHook describes a hook run around restarting an instance
+type RestartHook struct {
+	Name string
+	// Action is what the hook does, e.g. evict the store leaders
+	Action string
+	// Timeout is the time the hook waits at most, 0 if it's only bounded by
+	// the timeouts of the requests
+	Timeout time.Duration
+}
+
+// RestartHookDescriber is implemented by the ComponentAPI to describe the
+// hooks it runs, the ones not described are no-op
+type RestartHookDescriber interface {
+	RestartHooks() []RestartHook
+}
+
+// RollingUpdateInstance represent a instance need to transfer state when restart.
+// e.g transfer leader.
+type RollingUpdateInstance interface {
+	// API returns the API of the component to restart the instance, an error
+	// is returned if the topology is not of the type the component belongs to.
+	API(topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) (ComponentAPI, error)
+}
+
+// NopComponentAPI implements ComponentAPI with no-op methods, it's supposed
+// to be embedded to implement the methods supported by the component only.
+type NopComponentAPI struct{}
+
+// ResignLeader implements ComponentAPI interface.
+func (NopComponentAPI) ResignLeader(ctx context.Context) error { return nil }
+
+// Drain implements ComponentAPI interface.
+func (NopComponentAPI) Drain(ctx context.Context) error { return nil }
+
+// Health implements ComponentAPI interface.
+func (NopComponentAPI) Health(ctx context.Context) error { return nil }
+
+// Ready implements ComponentAPI interface.
+func (NopComponentAPI) Ready(ctx context.Context) error { return nil }
+
+// IsRollingUpdateInstance checks if the instance needs to transfer state when restart
+func IsRollingUpdateInstance(ins Instance) bool {
+	_, ok := ins.(RollingUpdateInstance)
+	return ok
+}
+
+// DescribeRestartHooks returns the hooks run around restarting the instance,
+// it's empty if the instance is not a RollingUpdateInstance.
+func DescribeRestartHooks(ins Instance, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) ([]RestartHook, error) {
+	rIns, ok := ins.(RollingUpdateInstance)
+	if !ok {
+		return nil, nil
+	}
+
+	c, err := rIns.API(topo, apiTimeoutSeconds, tlsCfg)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := c.(RestartHookDescriber); ok {
+		return d.RestartHooks(), nil
+	}
+	return nil, nil
+}
+
+// parseRestartHook splits the hook disabled into the component and the name
+// of the hook, e.g. tikv:resign-leader, the component is empty if the hook is
+// disabled for all components
+func parseRestartHook(hook string) (component, name string) {
+	if idx := strings.Index(hook, ":"); idx >= 0 {
+		return hook[:idx], hook[idx+1:]
+	}
+	return "", hook
+}
+
+// IsRestartHookDisabled checks if the hook of the component is disabled by
+// the hooks, which are the names of the hooks disabled for all components or
+// prefixed with the component, e.g. tikv:resign-leader
+func IsRestartHookDisabled(hooks []string, component, name string) bool {
+	for _, h := range hooks {
+		if h == name || h == component+":"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRestartHooks checks the names of the hooks and the components of
+// the topology are supported. The ready hook can't be disabled if the leaders
+// are resigned, as it's what reverts the resigning, e.g. the eviction of the
+// leaders of TiKV.
+func ValidateRestartHooks(hooks []string, topo Topology) error {
+	names := set.NewStringSet(RestartHookNames...)
+	components := set.NewStringSet()
+	for _, comp := range topo.ComponentsByStartOrder() {
+		components.Insert(comp.Name())
+	}
+
+	scopes := set.NewStringSet("")
+	for _, h := range hooks {
+		comp, name := parseRestartHook(h)
+		if !names.Exist(name) {
+			return ErrUnknownRestartHook.New("Unknown hook %s, should be one of %s", h, strings.Join(RestartHookNames, ", "))
+		}
+		if strings.Contains(h, ":") && !components.Exist(comp) {
+			return ErrUnknownRestartHook.New("Unknown component %s of hook %s, should be one of %s",
+				comp, h, strings.Join(components.Slice(), ", "))
+		}
+		scopes.Insert(comp)
+	}
+	for scope := range scopes {
+		if IsRestartHookDisabled(hooks, scope, RestartHookReady) &&
+			!IsRestartHookDisabled(hooks, scope, RestartHookResignLeader) {
+			prefix := ""
+			if scope != "" {
+				prefix = scope + ":"
+			}
+			return ErrInvalidRestartHooks.New("Hook %s%s reverts the resigning of the leaders, it can't be disabled unless %s%s is disabled too",
+				prefix, RestartHookReady, prefix, RestartHookResignLeader)
+		}
+	}
+	return nil
+}
+
+type disabledRestartHooksKey struct{}
+
+// WithDisabledRestartHooks returns a context in which the hooks are not run
+// by PreRestart and PostRestart, see IsRestartHookDisabled for the format.
+func WithDisabledRestartHooks(ctx context.Context, hooks []string) context.Context {
+	if len(hooks) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, disabledRestartHooksKey{}, hooks)
+}
+
+// runRestartHook runs the hook unless it's disabled in the context
+func runRestartHook(ctx context.Context, ins Instance, name string, hook func(context.Context) error) error {
+	if disabled, ok := ctx.Value(disabledRestartHooksKey{}).([]string); ok &&
+		IsRestartHookDisabled(disabled, ins.ComponentName(), name) {
+		if logger, err := ContextLogger(ctx); err == nil {
+			logger.Debugf("Skipped the disabled %s hook of %s", name, ins.ID())
+		}
+		return nil
+	}
+	return hook(ctx)
+}
+
+// PreRestart resigns the leaderships and drains the workload of the instance
+// before it is restarted, it's no-op if the instance is not a RollingUpdateInstance.
+func PreRestart(ctx context.Context, ins Instance, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) error {
+	rIns, ok := ins.(RollingUpdateInstance)
+	if !ok {
+		return nil
+	}
+
+	c, err := rIns.API(topo, apiTimeoutSeconds, tlsCfg)
+	if err != nil {
+		return err
+	}
+	if err := runRestartHook(ctx, ins, RestartHookResignLeader, c.ResignLeader); err != nil {
+		return err
+	}
+	return runRestartHook(ctx, ins, RestartHookDrain, c.Drain)
+}
+
+// PostRestart waits the instance to be healthy and makes it accept workload
+// after it is restarted, it's no-op if the instance is not a RollingUpdateInstance.
+func PostRestart(ctx context.Context, ins Instance, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) error {
+	rIns, ok := ins.(RollingUpdateInstance)
+	if !ok {
+		return nil
+	}
+
+	c, err := rIns.API(topo, apiTimeoutSeconds, tlsCfg)
+	if err != nil {
+		return err
+	}
+	if err := runRestartHook(ctx, ins, RestartHookHealth, c.Health); err != nil {
+		return err
+	}
+	return runRestartHook(ctx, ins, RestartHookReady, c.Ready)
+}
+
+// ContextLogger returns the logger in the context passed to the component API
+func ContextLogger(ctx context.Context) (*logprinter.Logger, error) {
+	logger, ok := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+	if !ok {
+		return nil, ErrNoLoggerInContext.New("The context has no logger inside")
+	}
+	return logger, nil
+}
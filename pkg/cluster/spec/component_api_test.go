@@ -0,0 +1,134 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/api/vcr"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCDCRestartReplay(t *testing.T) {
+	r := vcr.Use(t, "testdata/cdc_restart.yaml")
+	ctx := ctxt.New(context.Background(), 0, logprinter.NewLogger(""))
+
+	topo := new(Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+cdc_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+`), topo))
+	var cdc Instance
+	topo.IterInstance(func(inst Instance) {
+		if cdc == nil {
+			cdc = inst
+		}
+	})
+
+	var progress []int
+	ctxt.GetInner(ctx).Ev.Subscribe(ctxt.EventDrainProgress, func(instance string, p interface{}) {
+		progress = append(progress, p.(*api.DrainProgress).CurrentTableCount)
+	})
+
+	// the owner is resigned, and the capture is drained until no table is
+	// left on it before restarting
+	assert.Nil(t, PreRestart(ctx, cdc, topo, 10, nil))
+	// the new capture is alive after restarting
+	assert.Nil(t, PostRestart(ctx, cdc, topo, 10, nil))
+	if r.Mode() == vcr.ModeRecord {
+		return
+	}
+	assert.Equal(t, []int{3, 0}, progress)
+	assert.Empty(t, r.Unused())
+}
+
+func TestCDCCapturesCached(t *testing.T) {
+	var queried int32
+	var addrs []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/captures" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&queried, 1)
+		fmt.Fprintf(w, `[{"id":"a","is_owner":false,"address":%q},{"id":"b","is_owner":false,"address":%q}]`, addrs[0], addrs[1])
+	})
+	topo := new(Specification)
+	for i := 0; i < 2; i++ {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+		host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+		require.Nil(t, err)
+		addrs = append(addrs, srv.Listener.Addr().String())
+		p, err := strconv.Atoi(port)
+		require.Nil(t, err)
+		topo.CDCServers = append(topo.CDCServers, &CDCSpec{Host: host, Port: p})
+	}
+
+	resign := func(ctx context.Context) {
+		topo.IterInstance(func(inst Instance) {
+			a, err := inst.(*CDCInstance).API(topo, 10, nil)
+			require.Nil(t, err)
+			assert.Nil(t, a.ResignLeader(ctx))
+		})
+	}
+
+	// the captures are queried for each instance
+	resign(ctxt.New(context.Background(), 0, logprinter.NewLogger("")))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&queried))
+
+	// and only once if the responses are cached
+	atomic.StoreInt32(&queried, 0)
+	resign(api.WithCacheContext(ctxt.New(context.Background(), 0, logprinter.NewLogger("")), time.Minute))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&queried))
+}
+
+func TestTiDBStatusReplay(t *testing.T) {
+	r := vcr.Use(t, "testdata/tidb_status.yaml")
+	ctx := context.Background()
+
+	topo := new(Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.1
+`), topo))
+	var tidb Instance
+	topo.IterInstance(func(inst Instance) {
+		tidb = inst
+	})
+
+	assert.Equal(t, "Up", tidb.Status(ctx, 5*time.Second, nil))
+	assert.Greater(t, tidb.Uptime(ctx, 5*time.Second, nil), time.Duration(0))
+	if r.Mode() == vcr.ModeRecord {
+		return
+	}
+	assert.Empty(t, r.Unused())
+
+	// the instance is down if the status can't be queried
+	assert.Equal(t, "Down", tidb.Status(ctx, 5*time.Second, nil))
+}
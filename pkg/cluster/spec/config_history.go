@@ -0,0 +1,221 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+const (
+	// ConfigHistoryDir is the directory to keep the generations of the config
+	// files rendered for the instances, eg. {ConfigHistoryDir}/tikv-10.0.1.1-20160/3/tikv.toml
+	ConfigHistoryDir = "config-history"
+	// ConfigHistoryLimit is the count of the last generations kept for an instance
+	ConfigHistoryLimit = 10
+)
+
+// ErrConfigGenerationNotFound is the generation not kept in the config history
+var ErrConfigGenerationNotFound = errNS.NewType("config_generation_not_found", utils.ErrTraitPreCheck)
+
+// ConfigGeneration is a generation of the config files rendered for an instance
+type ConfigGeneration struct {
+	Generation int
+	Time       time.Time
+	// Files are the contents of the config files by their names in the conf
+	// dir of the instance
+	Files map[string][]byte
+}
+
+// FileNames returns the sorted names of the config files of the generation
+func (g *ConfigGeneration) FileNames() []string {
+	names := make([]string, 0, len(g.Files))
+	for name := range g.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Equal returns if the config files of the generations are the same
+func (g *ConfigGeneration) Equal(other *ConfigGeneration) bool {
+	if other == nil || len(g.Files) != len(other.Files) {
+		return false
+	}
+	for name, data := range g.Files {
+		if !bytes.Equal(data, other.Files[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RenderedConfigFiles returns the config files rendered in the cache dir for
+// the instance, by their names in the conf dir of the instance
+func RenderedConfigFiles(inst Instance) map[string]string {
+	comp := inst.ComponentName()
+	files := map[string]string{
+		comp + ".toml": fmt.Sprintf("%s-%s-%d.toml", comp, inst.GetHost(), inst.GetPort()),
+	}
+	if comp == ComponentTiFlash {
+		files[comp+"-learner.toml"] = fmt.Sprintf("%s-learner-%s-%d.toml", comp, inst.GetHost(), inst.GetPort())
+	}
+	return files
+}
+
+// configHistoryPath returns the path of the config history of the instance
+func (s *SpecManager) configHistoryPath(clusterName string, inst Instance, subpath ...string) string {
+	dir := fmt.Sprintf("%s-%s-%d", inst.ComponentName(), inst.GetHost(), inst.GetPort())
+	return s.Path(clusterName, append([]string{ConfigHistoryDir, dir}, subpath...)...)
+}
+
+// ConfigGenerationFile returns the path of a config file of a generation of
+// the config history of the instance
+func (s *SpecManager) ConfigGenerationFile(clusterName string, inst Instance, gen int, name string) string {
+	return s.configHistoryPath(clusterName, inst, strconv.Itoa(gen), name)
+}
+
+// configGenerationNumbers returns the sorted generations kept for the instance
+func (s *SpecManager) configGenerationNumbers(clusterName string, inst Instance) ([]int, error) {
+	entries, err := os.ReadDir(s.configHistoryPath(clusterName, inst))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	var gens []int
+	for _, entry := range entries {
+		gen, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() || gen <= 0 {
+			continue
+		}
+		gens = append(gens, gen)
+	}
+	sort.Ints(gens)
+	return gens, nil
+}
+
+// ConfigGeneration reads a generation of the config history of the instance
+func (s *SpecManager) ConfigGeneration(clusterName string, inst Instance, gen int) (*ConfigGeneration, error) {
+	dir := s.configHistoryPath(clusterName, inst, strconv.Itoa(gen))
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrConfigGenerationNotFound.
+				New("Generation %d of the configs of %s is not found in the config history", gen, inst.ID())
+		}
+		return nil, perrs.AddStack(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	g := &ConfigGeneration{Generation: gen, Time: info.ModTime(), Files: make(map[string][]byte)}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		g.Files[entry.Name()] = data
+	}
+	return g, nil
+}
+
+// ConfigGenerations reads the generations kept in the config history of the
+// instance, from the oldest to the latest
+func (s *SpecManager) ConfigGenerations(clusterName string, inst Instance) ([]*ConfigGeneration, error) {
+	gens, err := s.configGenerationNumbers(clusterName, inst)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]*ConfigGeneration, 0, len(gens))
+	for _, gen := range gens {
+		g, err := s.ConfigGeneration(clusterName, inst, gen)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, g)
+	}
+	return history, nil
+}
+
+// SaveConfigGeneration saves the config files of the instance as a new
+// generation of its config history if they are changed since the latest one,
+// only the last ConfigHistoryLimit generations are kept. The generation of the
+// config files is returned, 0 if there are no config files.
+func (s *SpecManager) SaveConfigGeneration(clusterName string, inst Instance, files map[string][]byte) (int, error) {
+	if len(files) == 0 {
+		return 0, nil
+	}
+	gens, err := s.configGenerationNumbers(clusterName, inst)
+	if err != nil {
+		return 0, err
+	}
+	gen := 1
+	if len(gens) > 0 {
+		latest, err := s.ConfigGeneration(clusterName, inst, gens[len(gens)-1])
+		if err != nil {
+			return 0, err
+		}
+		if latest.Equal(&ConfigGeneration{Files: files}) {
+			return latest.Generation, nil
+		}
+		gen = latest.Generation + 1
+	}
+
+	dir := s.configHistoryPath(clusterName, inst, strconv.Itoa(gen))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, perrs.AddStack(err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return 0, perrs.AddStack(err)
+		}
+	}
+
+	gens = append(gens, gen)
+	for len(gens) > ConfigHistoryLimit {
+		if err := os.RemoveAll(s.configHistoryPath(clusterName, inst, strconv.Itoa(gens[0]))); err != nil {
+			return 0, perrs.AddStack(err)
+		}
+		gens = gens[1:]
+	}
+	return gen, nil
+}
+
+// SaveRenderedConfigGeneration saves the config files rendered in the cache
+// dir for the instance as a new generation of its config history
+func (s *SpecManager) SaveRenderedConfigGeneration(clusterName string, inst Instance, cacheDir string) (int, error) {
+	files := make(map[string][]byte)
+	for name, cached := range RenderedConfigFiles(inst) {
+		data, err := os.ReadFile(filepath.Join(cacheDir, cached))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, perrs.AddStack(err)
+		}
+		files[name] = data
+	}
+	return s.SaveConfigGeneration(clusterName, inst, files)
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigHistory(t *testing.T) {
+	spec := NewSpec(t.TempDir(), nil)
+	inst := &TiFlashInstance{BaseInstance: BaseInstance{Name: ComponentTiFlash, Host: "172.16.5.140", Port: 9000}}
+
+	gen, err := spec.SaveConfigGeneration("name1", inst, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, gen)
+
+	// the rendered configs in the cache dir are saved by their names in the conf dir
+	cacheDir := t.TempDir()
+	assert.Equal(t, map[string]string{
+		"tiflash.toml":         "tiflash-172.16.5.140-9000.toml",
+		"tiflash-learner.toml": "tiflash-learner-172.16.5.140-9000.toml",
+	}, RenderedConfigFiles(inst))
+	assert.Nil(t, os.WriteFile(filepath.Join(cacheDir, "tiflash-172.16.5.140-9000.toml"), []byte("a"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(cacheDir, "tiflash-learner-172.16.5.140-9000.toml"), []byte("b"), 0644))
+	gen, err = spec.SaveRenderedConfigGeneration("name1", inst, cacheDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, gen)
+
+	// unchanged configs are not saved as a new generation
+	gen, err = spec.SaveRenderedConfigGeneration("name1", inst, cacheDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, gen)
+	g, err := spec.ConfigGeneration("name1", inst, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tiflash-learner.toml", "tiflash.toml"}, g.FileNames())
+	assert.Equal(t, "b", string(g.Files["tiflash-learner.toml"]))
+
+	// only the last generations are kept
+	for i := 0; i < ConfigHistoryLimit+2; i++ {
+		gen, err = spec.SaveConfigGeneration("name1", inst, map[string][]byte{"tiflash.toml": []byte(fmt.Sprint(i))})
+		assert.Nil(t, err)
+		assert.Equal(t, i+2, gen)
+	}
+	history, err := spec.ConfigGenerations("name1", inst)
+	assert.Nil(t, err)
+	assert.Len(t, history, ConfigHistoryLimit)
+	assert.Equal(t, 4, history[0].Generation)
+	assert.Equal(t, ConfigHistoryLimit+3, history[len(history)-1].Generation)
+	_, err = spec.ConfigGeneration("name1", inst, 1)
+	assert.True(t, errorx.IsOfType(err, ErrConfigGenerationNotFound))
+}
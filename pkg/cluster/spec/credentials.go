@@ -0,0 +1,90 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"os"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// the credentials of the TiDB users in the credential store of a cluster
+const (
+	RootCredentialFile     = "tidb_root.json"
+	OperatorCredentialFile = "tidb_operator.json"
+)
+
+// SQLCredential is a TiDB user and its password
+type SQLCredential struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// ReadCredential reads the credential file from the credential store of the
+// cluster, nil is returned if it doesn't exist
+func (s *SpecManager) ReadCredential(clusterName, name string) ([]byte, error) {
+	fname := s.Path(clusterName, CredentialsDir, name)
+	if utils.IsNotExist(fname) {
+		return nil, nil
+	}
+	return s.readFile(fname)
+}
+
+// WriteCredential writes the credential file to the credential store of the
+// cluster, it's encrypted if the storage of the cluster is encrypted
+func (s *SpecManager) WriteCredential(clusterName, name string, data []byte) error {
+	if err := os.MkdirAll(s.Path(clusterName, CredentialsDir), 0700); err != nil {
+		return perrs.AddStack(err)
+	}
+	data, err := s.sealData(clusterName, data)
+	if err != nil {
+		return err
+	}
+	return perrs.AddStack(os.WriteFile(s.Path(clusterName, CredentialsDir, name), data, 0600))
+}
+
+// RemoveCredential removes the credential file from the credential store of
+// the cluster
+func (s *SpecManager) RemoveCredential(clusterName, name string) error {
+	if err := os.Remove(s.Path(clusterName, CredentialsDir, name)); err != nil && !os.IsNotExist(err) {
+		return perrs.AddStack(err)
+	}
+	return nil
+}
+
+// SQLCredential reads the credential of a TiDB user from the credential store
+// of the cluster, nil is returned if it's not stored
+func (s *SpecManager) SQLCredential(clusterName, name string) (*SQLCredential, error) {
+	data, err := s.ReadCredential(clusterName, name)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	cred := &SQLCredential{}
+	if err := json.Unmarshal(data, cred); err != nil {
+		return nil, perrs.Annotatef(err, "invalid credential %s", name)
+	}
+	return cred, nil
+}
+
+// SaveSQLCredential saves the credential of a TiDB user to the credential
+// store of the cluster
+func (s *SpecManager) SaveSQLCredential(clusterName, name string, cred *SQLCredential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	return s.WriteCredential(clusterName, name, data)
+}
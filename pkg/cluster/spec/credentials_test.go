@@ -0,0 +1,48 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLCredential(t *testing.T) {
+	s := NewSpec(t.TempDir(), func() Metadata { return &ClusterMeta{} })
+
+	cred, err := s.SQLCredential("prod", RootCredentialFile)
+	assert.Nil(t, err)
+	assert.Nil(t, cred)
+
+	assert.Nil(t, s.SaveSQLCredential("prod", RootCredentialFile, &SQLCredential{User: "root", Password: "p@ss"}))
+	cred, err = s.SQLCredential("prod", RootCredentialFile)
+	assert.Nil(t, err)
+	assert.Equal(t, &SQLCredential{User: "root", Password: "p@ss"}, cred)
+	info, err := os.Stat(s.Path("prod", CredentialsDir, RootCredentialFile))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the credentials are encrypted with the storage
+	assert.Nil(t, os.Setenv(EnvStoragePassphrase, "secret"))
+	defer os.Unsetenv(EnvStoragePassphrase)
+	assert.Nil(t, s.EncryptStorage("prod"))
+	data, err := os.ReadFile(s.Path("prod", CredentialsDir, RootCredentialFile))
+	assert.Nil(t, err)
+	assert.True(t, IsEncryptedData(data))
+	cred, err = s.SQLCredential("prod", RootCredentialFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", cred.Password)
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"path/filepath"
+
+	"github.com/pingcap/errors"
+)
+
+// ForHost returns the deploy user settings of the host, the settings of the
+// host override the global ones field by field
+func (o *DeployUserOptions) ForHost(host string) DeployUserSettings {
+	settings := o.DeployUserSettings
+	override, ok := o.Hosts[host]
+	if !ok {
+		return settings
+	}
+	if override.UID != 0 {
+		settings.UID = override.UID
+	}
+	if override.GID != 0 {
+		settings.GID = override.GID
+	}
+	if len(override.Groups) > 0 {
+		settings.Groups = override.Groups
+	}
+	if override.Shell != "" {
+		settings.Shell = override.Shell
+	}
+	if override.Create != nil {
+		settings.Create = override.Create
+	}
+	return settings
+}
+
+// CreateUser returns whether the user is created if it doesn't exist
+func (s *DeployUserSettings) CreateUser() bool {
+	return s.Create == nil || *s.Create
+}
+
+func (s *DeployUserSettings) validate(field string) error {
+	if s.UID < 0 {
+		return errors.Errorf("`%s` of uid=%d is invalid", field, s.UID)
+	}
+	if s.GID < 0 {
+		return errors.Errorf("`%s` of gid=%d is invalid", field, s.GID)
+	}
+	for _, group := range s.Groups {
+		if !reGroup.MatchString(group) {
+			return errors.Annotatef(ErrUserOrGroupInvalid, "`%s` of group='%s' is invalid", field, group)
+		}
+	}
+	if s.Shell != "" && !filepath.IsAbs(s.Shell) {
+		return errors.Errorf("`%s` of shell='%s' is not an absolute path", field, s.Shell)
+	}
+	return nil
+}
+
+// validateDeployUser checks the deploy user settings, the settings of the
+// hosts not in the topology are ignored as they may be scaled out later
+func (s *Specification) validateDeployUser() error {
+	opt := s.GlobalOptions.DeployUser
+	if err := opt.validate("global.deploy_user"); err != nil {
+		return err
+	}
+	for host, settings := range opt.Hosts {
+		settings := settings
+		if err := settings.validate("global.deploy_user.hosts." + host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
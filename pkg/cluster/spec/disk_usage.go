@@ -0,0 +1,33 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"time"
+)
+
+// DiskUsageSample is the disk usage of the filesystem of a data dir at a time
+type DiskUsageSample struct {
+	Time  time.Time `yaml:"time"`
+	Used  uint64    `yaml:"used"`
+	Total uint64    `yaml:"total"`
+}
+
+// DiskUsageHistory is the samples of the disk usage of a data dir of an
+// instance, they are taken by display to forecast when the disk is full.
+type DiskUsageHistory struct {
+	Node    string            `yaml:"node"`
+	DataDir string            `yaml:"data_dir"`
+	Samples []DiskUsageSample `yaml:"samples,flow"`
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
 )
@@ -57,7 +58,7 @@ func (s *DrainerSpec) Status(ctx context.Context, timeout time.Duration, tlsCfg
 	state := statusByHost(s.Host, s.Port, "/status", timeout, tlsCfg)
 
 	if s.Offline {
-		binlogClient, err := api.NewBinlogClient(pdList, timeout, tlsCfg)
+		binlogClient, err := api.NewBinlogClient(pdList, api.RequestTimeout(ctx, timeout), tlsCfg)
 		if err != nil {
 			return state
 		}
@@ -159,7 +160,11 @@ func (i *DrainerInstance) ScaleConfig(
 	defer func() {
 		i.topo = s
 	}()
-	i.topo = mustBeClusterTopo(topo)
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
+	}
+	i.topo = cluster
 
 	return i.InitConfig(ctx, e, clusterName, clusterVersion, user, paths)
 }
@@ -194,7 +199,7 @@ func (i *DrainerInstance) InitConfig(
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_drainer_%s_%d.sh", i.GetHost(), i.GetPort()))
 
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_drainer.sh")
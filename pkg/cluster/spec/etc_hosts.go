@@ -0,0 +1,115 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+var errEtcHostsUnresolved = errNSTopolohy.NewType("etc_hosts_unresolved")
+
+// lookupHost resolves the hostname on the control machine, it's a variable
+// to be replaced in tests
+var lookupHost = net.LookupHost
+
+// EtcHostsEntries returns the hostname -> address entries of the cluster to be
+// managed in /etc/hosts of all hosts, the instances in excludedNodes are not
+// counted. It returns nil if the entries are not managed by tiup.
+//
+// The hosts specified by IP addresses need no entry, the address of the
+// others are taken from global.etc_hosts.addresses, or resolved on the
+// control machine as it must reach them by the hostnames anyway.
+func EtcHostsEntries(topo Topology, excludedNodes ...string) (map[string]string, error) {
+	opt := topo.BaseTopo().GlobalOptions.EtcHosts
+	if !opt.Manage {
+		return nil, nil
+	}
+
+	excluded := set.NewStringSet(excludedNodes...)
+	hostnames := set.NewStringSet()
+	topo.IterInstance(func(inst Instance) {
+		if excluded.Exist(inst.ID()) {
+			return
+		}
+		hostnames.Insert(inst.GetHost())
+	})
+	for _, h := range MonitoredHosts(topo) {
+		hostnames.Insert(h.Host)
+	}
+
+	entries := make(map[string]string)
+	var unresolved []string
+	for host := range hostnames {
+		if net.ParseIP(host) != nil || host == "localhost" {
+			continue
+		}
+		if addr, ok := opt.Addresses[host]; ok {
+			entries[host] = addr
+			continue
+		}
+		addrs, err := lookupHost(host)
+		if err != nil || len(addrs) == 0 {
+			unresolved = append(unresolved, host)
+			continue
+		}
+		sort.Strings(addrs)
+		entries[host] = addrs[0]
+	}
+
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return nil, errEtcHostsUnresolved.
+			New("Failed to resolve the address of %s", strings.Join(unresolved, ", ")).
+			WithProperty(tui.SuggestionFromString(
+				"Please specify the address of the hosts in `global.etc_hosts.addresses` of the topology",
+			))
+	}
+	return entries, nil
+}
+
+// EtcHostsBlock returns the block of /etc/hosts managed by tiup for the cluster,
+// the lines between the begin and end markers are replaced on every update.
+func EtcHostsBlock(clusterName string, entries map[string]string) (begin, end, content string) {
+	begin = fmt.Sprintf("# BEGIN tiup cluster %s", clusterName)
+	end = fmt.Sprintf("# END tiup cluster %s", clusterName)
+
+	hosts := make([]string, 0, len(entries))
+	for host := range entries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	lines := []string{begin}
+	for _, host := range hosts {
+		lines = append(lines, fmt.Sprintf("%s %s", entries[host], host))
+	}
+	lines = append(lines, end)
+	return begin, end, strings.Join(lines, "\n") + "\n"
+}
+
+// validateEtcHosts checks the addresses specified for the hosts are valid
+func (s *Specification) validateEtcHosts() error {
+	for host, addr := range s.GlobalOptions.EtcHosts.Addresses {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("invalid address %s of host %s in `global.etc_hosts.addresses`", addr, host)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/config"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
@@ -114,11 +115,11 @@ func (c *GrafanaComponent) Instances() []Instance {
 				Dirs: []string{
 					s.DeployDir,
 				},
-				StatusFn: func(_ context.Context, timeout time.Duration, _ *tls.Config, _ ...string) string {
-					return statusByHost(s.Host, s.Port, "", timeout, nil)
+				StatusFn: func(_ context.Context, timeout time.Duration, tlsCfg *tls.Config, _ ...string) string {
+					return statusByHost(s.Host, s.Port, "", timeout, NewEndpointResolver(tlsCfg).TLSConfig(ComponentGrafana))
 				},
 				UptimeFn: func(_ context.Context, timeout time.Duration, tlsCfg *tls.Config) time.Duration {
-					return UptimeByHost(s.Host, s.Port, timeout, tlsCfg)
+					return UptimeByHost(s.Host, s.Port, timeout, NewEndpointResolver(tlsCfg).TLSConfig(ComponentGrafana))
 				},
 			},
 			topo: c.Topology,
@@ -150,7 +151,7 @@ func (i *GrafanaInstance) InitConfig(
 	// transfer run script
 	cfg := scripts.NewGrafanaScript(clusterName, paths.Deploy)
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_grafana_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 
@@ -166,7 +167,7 @@ func (i *GrafanaInstance) InitConfig(
 	// transfer config
 	spec := i.InstanceSpec.(*GrafanaSpec)
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("grafana_%s.ini", i.GetHost()))
-	if err := config.NewGrafanaConfig(i.GetHost(), paths.Deploy).
+	grafanaCfg := config.NewGrafanaConfig(i.GetHost(), paths.Deploy).
 		WithPort(uint64(i.GetPort())).
 		WithUsername(spec.Username).
 		WithPassword(spec.Password).
@@ -175,8 +176,8 @@ func (i *GrafanaInstance) InitConfig(
 		WithDomain(spec.Domain).
 		WithDefaultTheme(spec.DefaultTheme).
 		WithOrgName(spec.OrgName).
-		WithOrgRole(spec.OrgRole).
-		ConfigToFile(fp); err != nil {
+		WithOrgRole(spec.OrgRole)
+	if err := template.ConfigToFile(grafanaCfg, paths.Templates, fp); err != nil {
 		return err
 	}
 
@@ -212,7 +213,7 @@ func (i *GrafanaInstance) InitConfig(
 
 	// transfer dashboard.yml
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("dashboard_%s.yml", i.GetHost()))
-	if err := config.NewDashboardConfig(clusterName, paths.Deploy).ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(config.NewDashboardConfig(clusterName, paths.Deploy), paths.Templates, fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "provisioning", "dashboards", "dashboard.yml")
@@ -234,9 +235,9 @@ func (i *GrafanaInstance) InitConfig(
 		return errors.New("no prometheus found in topology")
 	}
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("datasource_%s.yml", i.GetHost()))
-	if err := config.NewDatasourceConfig(clusterName, monitors[0].Host).
-		WithPort(uint64(monitors[0].Port)).
-		ConfigToFile(fp); err != nil {
+	datasource := config.NewDatasourceConfig(clusterName, monitors[0].Host).
+		WithPort(uint64(monitors[0].Port))
+	if err := template.ConfigToFile(datasource, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "provisioning", "datasources", "datasource.yml")
@@ -0,0 +1,266 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// GrafanaDashboardBackupDir is the directory in the deploy dir of Grafana
+// where the dashboards created by users are backed up during restart, as
+// the dashboards provisioned by tiup are overwritten by every reload. Each
+// restart backs up to a subdirectory named by the time, e.g.
+// {GrafanaDashboardBackupDir}/20220102150405, which is removed once imported.
+const GrafanaDashboardBackupDir = "dashboards-backup"
+
+// grafanaBackupTimeFormat is the name format of the backups, which sort by time
+const grafanaBackupTimeFormat = "20060102150405"
+
+var _ RollingUpdateInstance = &GrafanaInstance{}
+
+// API implements RollingUpdateInstance interface.
+func (i *GrafanaInstance) API(topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) (ComponentAPI, error) {
+	timeout := time.Duration(apiTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	return &grafanaAPI{
+		ins:       i,
+		backupDir: filepath.Join(Abs(topo.BaseTopo().GlobalOptions.User, i.DeployDir()), GrafanaDashboardBackupDir),
+		timeout:   timeout,
+	}, nil
+}
+
+// grafanaAPI backs up the dashboards created by users on the Grafana
+// instance before restarting it, and imports them again after it's ready.
+type grafanaAPI struct {
+	NopComponentAPI
+	ins       *GrafanaInstance
+	backupDir string
+	timeout   time.Duration
+}
+
+// RestartHooks implements RestartHookDescriber interface.
+func (a *grafanaAPI) RestartHooks() []RestartHook {
+	return []RestartHook{
+		{Name: RestartHookDrain, Action: "back up the dashboards created by users"},
+		{Name: RestartHookHealth, Action: "wait for the Grafana to be healthy", Timeout: a.timeout},
+		{Name: RestartHookReady, Action: "import the dashboards backed up"},
+	}
+}
+
+// grafanaDashboard is the dashboard returned by the Grafana API
+type grafanaDashboard struct {
+	Dashboard map[string]interface{} `json:"dashboard"`
+	Meta      struct {
+		Provisioned bool   `json:"provisioned"`
+		FolderID    int    `json:"folderId"`
+		FolderUID   string `json:"folderUid"`
+		Slug        string `json:"slug"`
+	} `json:"meta"`
+}
+
+func (a *grafanaAPI) url(path string) string {
+	return fmt.Sprintf("http://%s:%d%s", a.ins.GetHost(), a.ins.GetPort(), path)
+}
+
+func (a *grafanaAPI) client() *utils.HTTPClient {
+	spec := a.ins.InstanceSpec.(*GrafanaSpec)
+	client := utils.NewHTTPClient(5*time.Second, nil)
+	auth := base64.StdEncoding.EncodeToString([]byte(spec.Username + ":" + spec.Password))
+	client.SetRequestHeader("Authorization", "Basic "+auth)
+	return client
+}
+
+// userDashboards returns the dashboards not provisioned by tiup
+func (a *grafanaAPI) userDashboards(ctx context.Context) ([]*grafanaDashboard, error) {
+	client := a.client()
+	data, err := client.Get(ctx, a.url("/api/search?type=dash-db&limit=5000"))
+	if err != nil {
+		return nil, err
+	}
+	var items []struct {
+		UID string `json:"uid"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	var dashboards []*grafanaDashboard
+	for _, item := range items {
+		data, err := client.Get(ctx, a.url("/api/dashboards/uid/"+url.PathEscape(item.UID)))
+		if err != nil {
+			return nil, err
+		}
+		d := &grafanaDashboard{}
+		if err := json.Unmarshal(data, d); err != nil {
+			return nil, errors.AddStack(err)
+		}
+		if d.Meta.Provisioned {
+			continue
+		}
+		dashboards = append(dashboards, d)
+	}
+	return dashboards, nil
+}
+
+// Drain implements ComponentAPI interface, it backs up the dashboards created
+// by users to the deploy dir. It's skipped if Grafana is not reachable, as
+// there is nothing to back up then.
+func (a *grafanaAPI) Drain(ctx context.Context) error {
+	logger, err := ContextLogger(ctx)
+	if err != nil {
+		return err
+	}
+
+	dashboards, err := a.userDashboards(ctx)
+	if err != nil {
+		logger.Warnf("Skip backing up the dashboards of %s, failed to list them: %s", a.ins.ID(), err)
+		return nil
+	}
+
+	if len(dashboards) == 0 {
+		return nil
+	}
+
+	// the backups not imported by the previous restarts are kept
+	e := ctxt.GetInner(ctx).Get(a.ins.GetHost())
+	backupDir := filepath.Join(a.backupDir, time.Now().Format(grafanaBackupTimeFormat))
+	if _, stderr, err := e.Execute(ctx, "mkdir -p "+backupDir, false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+
+	tmp, err := os.MkdirTemp("", "tiup-grafana-dashboards")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, d := range dashboards {
+		uid, _ := d.Dashboard["uid"].(string)
+		data, err := json.Marshal(d)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		fp := filepath.Join(tmp, uid+".json")
+		if err := os.WriteFile(fp, data, 0644); err != nil {
+			return errors.AddStack(err)
+		}
+		if err := e.Transfer(ctx, fp, filepath.Join(backupDir, uid+".json"), false, 0, false); err != nil {
+			return err
+		}
+	}
+	logger.Infof("\tBacked up %d dashboards created by users on %s to %s", len(dashboards), a.ins.ID(), backupDir)
+	return nil
+}
+
+// Health implements ComponentAPI interface.
+func (a *grafanaAPI) Health(ctx context.Context) error {
+	client := a.client()
+	return utils.Wait(ctx, func() error {
+		_, err := client.Get(ctx, a.url("/api/health"))
+		return err
+	}, utils.WaitOption{
+		Timeout:     a.timeout,
+		Interval:    time.Second,
+		MaxInterval: 5 * time.Second,
+	})
+}
+
+// Ready implements ComponentAPI interface, it imports the dashboards backed up
+// before restarting, from the oldest backup to the latest one, so the ones
+// left by the restarts failed before are imported too. Each backup is removed
+// once all dashboards in it are imported.
+func (a *grafanaAPI) Ready(ctx context.Context) error {
+	logger, err := ContextLogger(ctx)
+	if err != nil {
+		return err
+	}
+
+	e := ctxt.GetInner(ctx).Get(a.ins.GetHost())
+	cmd := fmt.Sprintf("find %s -mindepth 2 -maxdepth 2 -type f -name '*.json' 2>/dev/null | sort || true", a.backupDir)
+	stdout, stderr, err := e.Execute(ctx, cmd, false)
+	if err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	var backups []string
+	files := make(map[string][]string)
+	for _, fp := range strings.Fields(string(stdout)) {
+		dir := filepath.Dir(fp)
+		if len(files[dir]) == 0 {
+			backups = append(backups, dir)
+		}
+		files[dir] = append(files[dir], fp)
+	}
+
+	client := a.client()
+	for _, dir := range backups {
+		for _, fp := range files[dir] {
+			if err := a.importDashboard(ctx, client, fp); err != nil {
+				return errors.Annotatef(err, "failed to restore dashboard %s, the backup is kept in %s:%s", fp, a.ins.GetHost(), dir)
+			}
+		}
+		if _, stderr, err := e.Execute(ctx, "rm -rf "+dir, false); err != nil {
+			return errors.Annotatef(err, "stderr: %s", string(stderr))
+		}
+		logger.Infof("\tRestored %d dashboards created by users on %s from %s", len(files[dir]), a.ins.ID(), dir)
+	}
+	return nil
+}
+
+// importDashboard imports the dashboard backed up in fp
+func (a *grafanaAPI) importDashboard(ctx context.Context, client *utils.HTTPClient, fp string) error {
+	e := ctxt.GetInner(ctx).Get(a.ins.GetHost())
+	data, stderr, err := e.Execute(ctx, "cat "+fp, false)
+	if err != nil {
+		return errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	d := &grafanaDashboard{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return errors.Annotate(err, "invalid dashboard backup")
+	}
+
+	// the id is assigned by Grafana, the dashboard is matched by uid
+	delete(d.Dashboard, "id")
+	req := map[string]interface{}{
+		"dashboard": d.Dashboard,
+		"overwrite": true,
+		"message":   "Restored by tiup",
+	}
+	if d.Meta.FolderUID != "" {
+		req["folderUid"] = d.Meta.FolderUID
+	} else {
+		req["folderId"] = d.Meta.FolderID
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	_, err = client.Post(ctx, a.url("/api/dashboards/db"), bytes.NewReader(body))
+	return err
+}
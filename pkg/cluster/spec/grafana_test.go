@@ -15,11 +15,16 @@ package spec
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/user"
 	"path"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/google/uuid"
@@ -69,6 +74,89 @@ func TestLocalDashboards(t *testing.T) {
 	}
 }
 
+func TestGrafanaDashboardBackup(t *testing.T) {
+	var restored []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/health":
+			fmt.Fprint(w, `{"database":"ok"}`)
+		case "/api/search":
+			fmt.Fprint(w, `[{"uid":"custom"},{"uid":"tidb"}]`)
+		case "/api/dashboards/uid/custom":
+			fmt.Fprint(w, `{"dashboard":{"id":12,"uid":"custom","title":"Custom"},"meta":{"folderUid":"ops"}}`)
+		case "/api/dashboards/uid/tidb":
+			fmt.Fprint(w, `{"dashboard":{"id":1,"uid":"tidb","title":"TiDB"},"meta":{"provisioned":true}}`)
+		case "/api/dashboards/db":
+			req := make(map[string]interface{})
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+			restored = append(restored, req)
+			fmt.Fprint(w, `{"status":"success"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	assert.Nil(t, err)
+	portNum, err := strconv.Atoi(port)
+	assert.Nil(t, err)
+
+	deployDir, err := os.MkdirTemp("", "tiup-*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(deployDir)
+
+	user, err := user.Current()
+	assert.Nil(t, err)
+	topo := new(Specification)
+	topo.GlobalOptions.User = user.Username
+	topo.Grafanas = append(topo.Grafanas, &GrafanaSpec{
+		Host:      host,
+		Port:      portNum,
+		DeployDir: deployDir,
+		Username:  "admin",
+		Password:  "secret",
+	})
+	comp := GrafanaComponent{topo}
+	inst := comp.Instances()[0]
+
+	e, err := executor.New(executor.SSHTypeNone, false, executor.SSHConfig{Host: host, User: user.Username})
+	assert.Nil(t, err)
+	ctx := ctxt.New(context.Background(), 0, logprinter.NewLogger(""))
+	ctxt.GetInner(ctx).SetExecutor(host, e)
+
+	// the backup left by a restart failed before is kept
+	backupDir := filepath.Join(deployDir, GrafanaDashboardBackupDir)
+	failed := filepath.Join(backupDir, "20220101000000")
+	assert.Nil(t, os.MkdirAll(failed, 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(failed, "lost.json"),
+		[]byte(`{"dashboard":{"id":13,"uid":"lost","title":"Lost"},"meta":{"folderId":3}}`), 0644))
+
+	assert.Nil(t, PreRestart(ctx, inst, topo, 5, nil))
+	backups, err := filepath.Glob(filepath.Join(backupDir, "*", "*.json"))
+	assert.Nil(t, err)
+	assert.Len(t, backups, 2)
+	assert.Equal(t, filepath.Join(failed, "lost.json"), backups[0])
+	assert.Equal(t, "custom.json", filepath.Base(backups[1]))
+
+	// the older backups are imported first
+	assert.Nil(t, PostRestart(ctx, inst, topo, 5, nil))
+	assert.Equal(t, 2, len(restored))
+	dashboard := restored[0]["dashboard"].(map[string]interface{})
+	assert.Equal(t, "lost", dashboard["uid"])
+	assert.Equal(t, float64(3), restored[0]["folderId"])
+	dashboard = restored[1]["dashboard"].(map[string]interface{})
+	assert.Equal(t, "custom", dashboard["uid"])
+	assert.NotContains(t, dashboard, "id")
+	assert.Equal(t, "ops", restored[1]["folderUid"])
+	backups, err = filepath.Glob(filepath.Join(backupDir, "*"))
+	assert.Nil(t, err)
+	assert.Empty(t, backups)
+}
+
 func TestMergeAdditionalGrafanaConf(t *testing.T) {
 	file, err := os.CreateTemp("", "tiup-cluster-spec-test")
 	if err != nil {
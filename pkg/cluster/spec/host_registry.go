@@ -0,0 +1,219 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// HostRegistryDir is the directory shared by the profiles of all kinds of
+// clusters managed on the control machine, e.g. tiup-cluster and tiup-dm,
+// to record the ports and directories used on the hosts, eg.
+// {HostRegistryDir}/dm/dm-test.json
+const HostRegistryDir = "host-registry"
+
+// HostDir is a directory used by an instance on a host
+type HostDir struct {
+	Kind string `json:"kind"`
+	Dir  string `json:"dir"`
+}
+
+// HostEntry is the record of the ports and directories used by an instance
+// on a host, the monitor agents of a host are recorded as the component
+// RoleMonitor
+type HostEntry struct {
+	Kind               string    `json:"kind"`
+	Cluster            string    `json:"cluster"`
+	Component          string    `json:"component"`
+	Host               string    `json:"host"`
+	Ports              []int     `json:"ports,omitempty"`
+	Dirs               []HostDir `json:"dirs,omitempty"`
+	IgnoreMonitorAgent bool      `json:"ignore_monitor_agent,omitempty"`
+}
+
+// HostEntries returns the records of the ports and directories used by the
+// instances and monitor agents of topo
+func HostEntries(kind, clusterName string, topo Topology) []HostEntry {
+	instanceDirAccessor, hostDirAccessor := dirAccessors()
+	dirs := func(inst Instance, accessors []DirAccessor) []HostDir {
+		var entries []DirEntry
+		for _, dirAccessor := range accessors {
+			entries = appendEntries(clusterName, topo, inst, dirAccessor, entries)
+		}
+		var dirs []HostDir
+		for _, d := range entries {
+			if d.dir != "" {
+				dirs = append(dirs, HostDir{Kind: d.dirKind, Dir: d.dir})
+			}
+		}
+		return dirs
+	}
+
+	var entries []HostEntry
+	topo.IterInstance(func(inst Instance) {
+		entries = append(entries, HostEntry{
+			Kind:               kind,
+			Cluster:            clusterName,
+			Component:          inst.ComponentName(),
+			Host:               inst.GetHost(),
+			Ports:              inst.UsedPorts(),
+			Dirs:               dirs(inst, instanceDirAccessor),
+			IgnoreMonitorAgent: inst.IgnoreMonitorAgent(),
+		})
+	})
+
+	mOpt := topo.GetMonitoredOptions()
+	if mOpt == nil {
+		return entries
+	}
+	IterHost(topo, func(inst Instance) {
+		entries = append(entries, HostEntry{
+			Kind:               kind,
+			Cluster:            clusterName,
+			Component:          RoleMonitor,
+			Host:               inst.GetHost(),
+			Ports:              []int{mOpt.NodeExporterPort, mOpt.BlackboxExporterPort},
+			Dirs:               dirs(inst, hostDirAccessor),
+			IgnoreMonitorAgent: inst.IgnoreMonitorAgent(),
+		})
+	})
+	return entries
+}
+
+// HostRegistry records the ports and directories used on the hosts by the
+// clusters of a kind, so that the clusters of the other kinds managed on the
+// same control machine could avoid conflicting with them
+type HostRegistry struct {
+	dir  string
+	kind string
+}
+
+// NewHostRegistry returns the registry of the clusters of kind in dir
+func NewHostRegistry(dir, kind string) *HostRegistry {
+	return &HostRegistry{dir: dir, kind: kind}
+}
+
+// Kind returns the kind of the clusters recorded by the registry
+func (r *HostRegistry) Kind() string {
+	if r == nil {
+		return ""
+	}
+	return r.kind
+}
+
+func (r *HostRegistry) path(kind, clusterName string) string {
+	return filepath.Join(r.dir, kind, clusterName+".json")
+}
+
+// Register records the ports and directories used by the cluster, the
+// previous records of the cluster are replaced
+func (r *HostRegistry) Register(clusterName string, topo Topology) error {
+	if r == nil || topo == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(HostEntries(r.kind, clusterName, topo), "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	fname := r.path(r.kind, clusterName)
+	if err := utils.CreateDir(filepath.Dir(fname)); err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(os.WriteFile(fname, data, 0644))
+}
+
+// Unregister removes the records of the cluster
+func (r *HostRegistry) Unregister(clusterName string) error {
+	if r == nil {
+		return nil
+	}
+	if err := os.Remove(r.path(r.kind, clusterName)); err != nil && !os.IsNotExist(err) {
+		return perrs.AddStack(err)
+	}
+	return nil
+}
+
+// Sync records the clusters and removes the records of the clusters not
+// existing anymore, it's used to catch up with the clusters deployed before
+// the registry is introduced or changed by the legacy versions
+func (r *HostRegistry) Sync(clusters map[string]Metadata) error {
+	if r == nil {
+		return nil
+	}
+	files, err := os.ReadDir(filepath.Join(r.dir, r.kind))
+	if err != nil && !os.IsNotExist(err) {
+		return perrs.AddStack(err)
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), ".json")
+		if _, ok := clusters[name]; ok || f.IsDir() || name == f.Name() {
+			continue
+		}
+		if err := r.Unregister(name); err != nil {
+			return err
+		}
+	}
+	for name, metadata := range clusters {
+		if err := r.Register(name, metadata.GetTopology()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForeignEntries returns the records of the clusters of the other kinds
+func (r *HostRegistry) ForeignEntries() ([]HostEntry, error) {
+	if r == nil {
+		return nil, nil
+	}
+	kinds, err := os.ReadDir(r.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+
+	var entries []HostEntry
+	for _, kind := range kinds {
+		if !kind.IsDir() || kind.Name() == r.kind {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(r.dir, kind.Name()))
+		if err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		for _, f := range files {
+			if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(r.dir, kind.Name(), f.Name()))
+			if err != nil {
+				return nil, perrs.AddStack(err)
+			}
+			var cluster []HostEntry
+			if err := json.Unmarshal(data, &cluster); err != nil {
+				return nil, perrs.Annotatef(err, "failed to parse the host registry %s", f.Name())
+			}
+			entries = append(entries, cluster...)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestHostRegistry(t *testing.T) {
+	dir := t.TempDir()
+	cluster := NewHostRegistry(dir, "cluster")
+	dm := NewHostRegistry(dir, "dm")
+
+	topo := new(Specification)
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+global:
+  user: tidb
+  deploy_dir: /tidb-deploy
+  data_dir: /tidb-data
+tikv_servers:
+  - host: 172.16.5.140
+    port: 20160
+    status_port: 20180
+`), topo))
+
+	// nothing is recorded by the other kinds yet
+	entries, err := dm.ForeignEntries()
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	assert.Nil(t, cluster.Register("test1", topo))
+	entries, err = dm.ForeignEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "cluster", entries[0].Kind)
+	assert.Equal(t, "test1", entries[0].Cluster)
+	assert.Equal(t, ComponentTiKV, entries[0].Component)
+	assert.Equal(t, []int{20160, 20180}, entries[0].Ports)
+	assert.Contains(t, entries[0].Dirs, HostDir{Kind: "deploy directory", Dir: "/tidb-deploy/tikv-20160"})
+	assert.Equal(t, RoleMonitor, entries[1].Component)
+
+	// the records of the own kind are not foreign
+	entries, err = cluster.ForeignEntries()
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	// the clusters not existing anymore are removed on sync
+	assert.Nil(t, cluster.Register("test2", topo))
+	assert.Nil(t, cluster.Sync(map[string]Metadata{"test2": &ClusterMeta{Topology: topo}}))
+	entries, err = dm.ForeignEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "test2", entries[0].Cluster)
+
+	assert.Nil(t, cluster.Unregister("test2"))
+	assert.Nil(t, cluster.Unregister("test2"))
+	entries, err = dm.ForeignEntries()
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	// a nil registry is a no-op
+	var registry *HostRegistry
+	assert.Nil(t, registry.Register("test1", topo))
+	entries, err = registry.ForeignEntries()
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCheckHostRegistryConflict(t *testing.T) {
+	existing := []HostEntry{
+		{
+			Kind:      "dm",
+			Cluster:   "dm-test",
+			Component: ComponentDMWorker,
+			Host:      "172.16.5.140",
+			Ports:     []int{8262},
+			Dirs:      []HostDir{{Kind: "deploy directory", Dir: "/tidb-deploy/tikv-20160"}},
+		},
+	}
+
+	parse := func(content string) Topology {
+		topo := new(Specification)
+		assert.Nil(t, yaml.Unmarshal([]byte(content), topo))
+		return topo
+	}
+
+	// the deploy dir of the TiKV is used by the DM worker
+	err := CheckHostRegistryConflict(existing, "test1", parse(`
+global:
+  user: tidb
+  deploy_dir: /tidb-deploy
+tikv_servers:
+  - host: 172.16.5.140
+    port: 20160
+    status_port: 20180
+`))
+	assert.True(t, errorx.IsOfType(err, errDeployDirConflict))
+
+	// the port of the TiKV is used by the DM worker
+	err = CheckHostRegistryConflict(existing, "test1", parse(`
+global:
+  user: tidb
+  deploy_dir: /tidb-deploy
+tikv_servers:
+  - host: 172.16.5.140
+    port: 8262
+    status_port: 20180
+    deploy_dir: /tikv-deploy
+`))
+	assert.True(t, errorx.IsOfType(err, errDeployPortConflict))
+
+	// no conflict on the other hosts
+	err = CheckHostRegistryConflict(existing, "test1", parse(`
+global:
+  user: tidb
+  deploy_dir: /tidb-deploy
+tikv_servers:
+  - host: 172.16.5.141
+    port: 8262
+    status_port: 20180
+`))
+	assert.Nil(t, err)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/tiup/pkg/checkpoint"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	"github.com/pingcap/tiup/pkg/cluster/module"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	system "github.com/pingcap/tiup/pkg/cluster/template/systemd"
 	"github.com/pingcap/tiup/pkg/meta"
 	"go.uber.org/zap"
@@ -70,13 +71,6 @@ type Component interface {
 	Instances() []Instance
 }
 
-// RollingUpdateInstance represent a instance need to transfer state when restart.
-// e.g transfer leader.
-type RollingUpdateInstance interface {
-	PreRestart(ctx context.Context, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) error
-	PostRestart(ctx context.Context, topo Topology, tlsCfg *tls.Config) error
-}
-
 // Instance represents the instance.
 type Instance interface {
 	InstanceSpec
@@ -102,6 +96,8 @@ type Instance interface {
 	Arch() string
 	IsPatched() bool
 	SetPatched(bool)
+	NumaNode() string
+	SetNumaNode(string)
 	setTLSConfig(ctx context.Context, enableTLS bool, configs map[string]interface{}, paths meta.DirPaths) (map[string]interface{}, error)
 }
 
@@ -165,21 +161,8 @@ func (i *BaseInstance) InitConfig(ctx context.Context, e ctxt.Executor, opt Glob
 		return nil
 	}
 
-	resource := MergeResourceControl(opt.ResourceControl, i.resourceControl())
-	systemCfg := system.NewConfig(comp, user, paths.Deploy).
-		WithMemoryLimit(resource.MemoryLimit).
-		WithCPUQuota(resource.CPUQuota).
-		WithLimitCORE(resource.LimitCORE).
-		WithIOReadBandwidthMax(resource.IOReadBandwidthMax).
-		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax)
-
-	// For not auto start if using binlogctl to offline.
-	// bad design
-	if comp == ComponentPump || comp == ComponentDrainer {
-		systemCfg.Restart = "on-failure"
-	}
-
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	systemCfg := i.SystemdConfig(opt, user, paths.Deploy)
+	if err := template.ConfigToFile(systemCfg, paths.Templates, sysCfg); err != nil {
 		return errors.Trace(err)
 	}
 	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
@@ -199,6 +182,53 @@ func (i *BaseInstance) InitConfig(ctx context.Context, e ctxt.Executor, opt Glob
 	return nil
 }
 
+// SystemdConfig returns the config of the systemd unit of the instance
+func (i *BaseInstance) SystemdConfig(opt GlobalOptions, user, deployDir string) *system.Config {
+	comp := i.ComponentName()
+	resource := MergeResourceControl(opt.ResourceControl, i.resourceControl())
+	systemCfg := system.NewConfig(comp, user, deployDir).
+		WithMemoryLimit(resource.MemoryLimit).
+		WithCPUQuota(resource.CPUQuota).
+		WithLimitCORE(resource.LimitCORE).
+		WithIOReadBandwidthMax(resource.IOReadBandwidthMax).
+		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax).
+		WithTimeZone(normalizeTimeZone(i.TimeZone(opt)))
+
+	// For not auto start if using binlogctl to offline.
+	// bad design
+	if comp == ComponentPump || comp == ComponentDrainer {
+		systemCfg.Restart = "on-failure"
+	}
+	return systemCfg
+}
+
+// SystemdUnitFile returns the path of the systemd unit file of the instance
+// on its host
+func SystemdUnitFile(inst Instance) string {
+	comp := inst.ComponentName()
+	if comp == ComponentTiSpark {
+		comp = inst.Role()
+	}
+	return fmt.Sprintf("/etc/systemd/system/%s-%d.service", comp, inst.GetPort())
+}
+
+// ExpectedSystemdUnit renders the systemd unit file expected on the host of
+// the instance with the templates in templateDir, false is returned if it's
+// not rendered by the BaseInstance
+func ExpectedSystemdUnit(inst Instance, opt GlobalOptions, user, templateDir string) ([]byte, bool, error) {
+	sc, ok := inst.(interface {
+		SystemdConfig(opt GlobalOptions, user, deployDir string) *system.Config
+	})
+	if !ok || inst.ComponentName() == ComponentTiSpark {
+		return nil, false, nil
+	}
+	data, err := template.Config(sc.SystemdConfig(opt, user, Abs(user, inst.DeployDir())), templateDir)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
 // setTLSConfig set TLS Config to support enable/disable TLS
 // baseInstance no need to configure TLS
 func (i *BaseInstance) setTLSConfig(ctx context.Context, enableTLS bool, configs map[string]interface{}, paths meta.DirPaths) (map[string]interface{}, error) {
@@ -416,6 +446,24 @@ func (i *BaseInstance) SetPatched(p bool) {
 	v.SetBool(p)
 }
 
+// NumaNode implements Instance interface
+func (i *BaseInstance) NumaNode() string {
+	v := reflect.Indirect(reflect.ValueOf(i.InstanceSpec)).FieldByName("NumaNode")
+	if !v.IsValid() {
+		return ""
+	}
+	return v.String()
+}
+
+// SetNumaNode implements the Instance interface
+func (i *BaseInstance) SetNumaNode(node string) {
+	v := reflect.Indirect(reflect.ValueOf(i.InstanceSpec)).FieldByName("NumaNode")
+	if !v.CanSet() {
+		return
+	}
+	v.SetString(node)
+}
+
 // PrepareStart checks instance requirements before starting
 func (i *BaseInstance) PrepareStart(ctx context.Context, tlsCfg *tls.Config) error {
 	return nil
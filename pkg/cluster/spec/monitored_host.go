@@ -0,0 +1,97 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// MonitoredHostSpec represents a host not running any component of the
+// cluster, but monitored by it, e.g. the HAProxy or backup servers. Only
+// the monitoring agents are deployed to it and scraped by Prometheus.
+type MonitoredHostSpec struct {
+	Host    string `yaml:"host"`
+	SSHPort int    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
+	OS      string `yaml:"os,omitempty"`
+	Arch    string `yaml:"arch,omitempty"`
+}
+
+var monitoredHostsType = reflect.TypeOf([]*MonitoredHostSpec{})
+
+// MonitoredHosts returns the extra monitored hosts of the topology, it's
+// always empty for topologies other than the TiDB cluster one.
+func MonitoredHosts(topo Topology) []*MonitoredHostSpec {
+	s, ok := topo.(*Specification)
+	if !ok || s == nil {
+		return nil
+	}
+	return s.MonitoredHosts
+}
+
+// fillMonitoredHostDefaults sets the default values of the monitored hosts
+func (s *Specification) fillMonitoredHostDefaults() {
+	for _, h := range s.MonitoredHosts {
+		if h.SSHPort == 0 {
+			h.SSHPort = s.GlobalOptions.SSHPort
+		}
+		switch strings.ToLower(h.Arch) {
+		case "x86_64":
+			h.Arch = "amd64"
+		case "aarch64":
+			h.Arch = "arm64"
+		default:
+			h.Arch = strings.ToLower(h.Arch)
+		}
+		h.OS = strings.ToLower(h.OS)
+	}
+}
+
+// validateMonitoredHosts checks the monitored hosts are neither duplicated
+// nor hosts of the cluster, the agents are deployed to the latter anyway.
+func (s *Specification) validateMonitoredHosts() error {
+	if len(s.MonitoredHosts) == 0 {
+		return nil
+	}
+
+	clusterHosts := set.NewStringSet()
+	s.IterInstance(func(inst Instance) {
+		clusterHosts.Insert(inst.GetHost())
+	})
+
+	monitoredHosts := set.NewStringSet()
+	for _, h := range s.MonitoredHosts {
+		if h.Host == "" {
+			return errors.Errorf("`monitored_hosts` contains empty host field")
+		}
+		if monitoredHosts.Exist(h.Host) {
+			return &meta.ValidateErr{
+				Type:   meta.TypeConflict,
+				Target: "host",
+				LHS:    "monitored_hosts",
+				RHS:    "monitored_hosts",
+				Value:  h.Host,
+			}
+		}
+		if clusterHosts.Exist(h.Host) {
+			return errors.Errorf("host %s in `monitored_hosts` is already a host of the cluster, the monitoring agents are deployed to it anyway", h.Host)
+		}
+		monitoredHosts.Insert(h.Host)
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/config"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
@@ -127,11 +128,11 @@ func (c *MonitorComponent) Instances() []Instance {
 				s.DeployDir,
 				s.DataDir,
 			},
-			StatusFn: func(_ context.Context, timeout time.Duration, _ *tls.Config, _ ...string) string {
-				return statusByHost(s.Host, s.Port, "/-/ready", timeout, nil)
+			StatusFn: func(_ context.Context, timeout time.Duration, tlsCfg *tls.Config, _ ...string) string {
+				return statusByHost(s.Host, s.Port, "/-/ready", timeout, NewEndpointResolver(tlsCfg).TLSConfig(ComponentPrometheus))
 			},
 			UptimeFn: func(_ context.Context, timeout time.Duration, tlsCfg *tls.Config) time.Duration {
-				return UptimeByHost(s.Host, s.Port, timeout, tlsCfg)
+				return UptimeByHost(s.Host, s.Port, timeout, NewEndpointResolver(tlsCfg).TLSConfig(ComponentPrometheus))
 			},
 		}, c.Topology}
 		if s.NgPort > 0 {
@@ -176,7 +177,7 @@ func (i *MonitorInstance) InitConfig(
 		WithNG(spec.NgPort)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_prometheus_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 
@@ -289,6 +290,10 @@ func (i *MonitorInstance) InitConfig(
 		}
 	}
 
+	for _, h := range MonitoredHosts(i.topo) {
+		uniqueHosts.Insert(h.Host)
+	}
+
 	if monitoredOptions != nil {
 		for host := range uniqueHosts {
 			cfig.AddNodeExpoertor(host, uint64(monitoredOptions.NodeExporterPort))
@@ -352,7 +357,7 @@ func (i *MonitorInstance) InitConfig(
 			}
 		}
 		fp = filepath.Join(paths.Cache, fmt.Sprintf("ngmonitoring_%s_%d.toml", i.GetHost(), i.GetPort()))
-		if err := ngcfg.ConfigToFile(fp); err != nil {
+		if err := template.ConfigToFile(ngcfg, paths.Templates, fp); err != nil {
 			return err
 		}
 		dst = filepath.Join(paths.Deploy, "conf", "ngmonitoring.toml")
@@ -362,7 +367,7 @@ func (i *MonitorInstance) InitConfig(
 	}
 
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("prometheus_%s_%d.yml", i.GetHost(), i.GetPort()))
-	if err := cfig.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfig, paths.Templates, fp); err != nil {
 		return err
 	}
 	if spec.AdditionalScrapeConf != nil {
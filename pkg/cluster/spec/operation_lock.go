@@ -0,0 +1,231 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/gofrs/flock"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+const (
+	// OperationLockName is the file held by the operation in progress on
+	// the cluster
+	OperationLockName = ".operation.lock"
+	// OperationJournalName is the audit log of the operation in progress,
+	// it's left for recovery if the operation is interrupted
+	OperationJournalName = ".operation.journal"
+	// operationMetaBackupName is the meta of the cluster before the
+	// operation in progress
+	operationMetaBackupName = ".operation.meta.yaml"
+	// operationFlockName is the file flocked by the process holding the
+	// operation lock, the lock left is stale if no process flocks it
+	operationFlockName = ".operation.flock"
+)
+
+var (
+	// ErrOperationLocked is the cluster being operated by another process
+	ErrOperationLocked = errNS.NewType("operation_locked", utils.ErrTraitPreCheck)
+	// ErrOperationInterrupted is the cluster left locked by an operation
+	// interrupted
+	ErrOperationInterrupted = errNS.NewType("operation_interrupted", utils.ErrTraitPreCheck)
+)
+
+// OperationLock is the lock of an operation in progress on the cluster
+type OperationLock struct {
+	Args    []string  `json:"args"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+	// ScaleOutLocked is whether the scale-out file lock existed before the
+	// operation, it's released on rollback if not
+	ScaleOutLocked bool `json:"scale_out_locked,omitempty"`
+}
+
+// NewOperationLock returns the lock of the operation run by current process
+func NewOperationLock() *OperationLock {
+	host, _ := os.Hostname()
+	return &OperationLock{
+		Args:    os.Args,
+		PID:     os.Getpid(),
+		Host:    host,
+		Started: time.Now(),
+	}
+}
+
+// AcquireOperationLock creates the operation lock of the cluster and backs up
+// the meta, an ErrOperationLocked or ErrOperationInterrupted is returned with
+// the lock held if the cluster is already locked. The process holding the
+// lock flocks a file until the lock is released, so the lock left by a
+// process exited is told by the flock, even if its pid is reused.
+func (s *SpecManager) AcquireOperationLock(clusterName string, lock *OperationLock) (*OperationLock, error) {
+	if err := s.ensureDir(clusterName); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	_, nested := s.operations[clusterName]
+	s.mu.Unlock()
+	fl := flock.New(s.Path(clusterName, operationFlockName))
+	locked := false
+	if !nested {
+		var err error
+		if locked, err = fl.TryLock(); err != nil {
+			return nil, perrs.AddStack(err)
+		}
+	}
+	if !locked {
+		held, err := s.OperationLock(clusterName)
+		if err != nil {
+			return nil, err
+		}
+		if held == nil {
+			// the lock is being created by the other process
+			held = &OperationLock{}
+		}
+		return held, ErrOperationLocked.New("Cluster %s is being operated by pid %d on %s", clusterName, held.PID, held.Host)
+	}
+
+	// no process is holding the lock, it's left by an interrupted operation
+	// if exists, the ones held on other control machines sharing the profile
+	// are not told by the flock
+	held, err := s.OperationLock(clusterName)
+	if err != nil || held != nil {
+		_ = fl.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if host, _ := os.Hostname(); held.Host != "" && held.Host != host {
+			return held, ErrOperationLocked.New("Cluster %s is being operated by pid %d on %s", clusterName, held.PID, held.Host)
+		}
+		return held, ErrOperationInterrupted.New("Cluster %s is locked by an interrupted operation", clusterName)
+	}
+
+	lock.ScaleOutLocked, _ = s.IsScaleOutLocked(clusterName)
+	data, err := json.Marshal(lock)
+	if err == nil {
+		err = os.WriteFile(s.Path(clusterName, OperationLockName), data, 0644)
+	}
+	if err != nil {
+		_ = fl.Unlock()
+		return nil, perrs.AddStack(err)
+	}
+	s.mu.Lock()
+	if s.operations == nil {
+		s.operations = make(map[string]*flock.Flock)
+	}
+	s.operations[clusterName] = fl
+	s.mu.Unlock()
+
+	// back up the meta to roll back to if the operation is interrupted
+	meta, err := s.readFile(s.Path(clusterName, metaFileName))
+	if err == nil {
+		if meta, err = s.sealData(clusterName, meta); err == nil {
+			err = os.WriteFile(s.Path(clusterName, operationMetaBackupName), meta, 0644)
+		}
+	}
+	if err != nil && !os.IsNotExist(perrs.Cause(err)) {
+		_ = s.ReleaseOperationLock(clusterName)
+		return nil, perrs.Annotate(err, "failed to back up the meta")
+	}
+	return nil, nil
+}
+
+// IsOperationRunning checks if the operation lock of the cluster is held by a
+// process alive on the control machine, including the current one
+func (s *SpecManager) IsOperationRunning(clusterName string) (bool, error) {
+	s.mu.Lock()
+	_, ok := s.operations[clusterName]
+	s.mu.Unlock()
+	if ok {
+		return true, nil
+	}
+	if utils.IsNotExist(s.Path(clusterName, operationFlockName)) {
+		return false, nil
+	}
+	fl := flock.New(s.Path(clusterName, operationFlockName))
+	locked, err := fl.TryLock()
+	if err != nil {
+		return false, perrs.AddStack(err)
+	}
+	if locked {
+		_ = fl.Unlock()
+	}
+	return !locked, nil
+}
+
+// OperationLock reads the operation lock of the cluster, nil is returned if
+// the cluster is not locked. An empty lock is returned if the file is empty or
+// corrupt, e.g. the process was killed while writing it.
+func (s *SpecManager) OperationLock(clusterName string) (*OperationLock, error) {
+	data, err := os.ReadFile(s.Path(clusterName, OperationLockName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	lock := &OperationLock{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return &OperationLock{}, nil
+	}
+	return lock, nil
+}
+
+// MetaSnapshot returns the content of the meta of the cluster, it tells if
+// the meta is changed by the others while the operation lock is paused
+func (s *SpecManager) MetaSnapshot(clusterName string) ([]byte, error) {
+	return s.readFile(s.Path(clusterName, metaFileName))
+}
+
+// ReleaseOperationLock removes the operation lock of the cluster, and the
+// journal and meta backup of the operation
+func (s *SpecManager) ReleaseOperationLock(clusterName string) error {
+	for _, name := range []string{OperationJournalName, operationMetaBackupName, OperationLockName} {
+		if err := os.Remove(s.Path(clusterName, name)); err != nil && !os.IsNotExist(err) {
+			return perrs.AddStack(err)
+		}
+	}
+	// the flocked file is kept, so that the processes waiting for it always
+	// flock the same one
+	s.mu.Lock()
+	fl, ok := s.operations[clusterName]
+	delete(s.operations, clusterName)
+	s.mu.Unlock()
+	if ok {
+		return perrs.AddStack(fl.Unlock())
+	}
+	return nil
+}
+
+// RestoreOperationMeta restores the meta of the cluster backed up when the
+// operation lock was acquired
+func (s *SpecManager) RestoreOperationMeta(clusterName string) error {
+	backup := s.Path(clusterName, operationMetaBackupName)
+	if utils.IsNotExist(backup) {
+		return perrs.Errorf("no meta backed up for cluster %s", clusterName)
+	}
+	data, err := s.readFile(backup)
+	if err != nil {
+		return err
+	}
+	if data, err = s.sealData(clusterName, data); err != nil {
+		return err
+	}
+	return utils.SaveFileWithBackup(s.Path(clusterName, metaFileName), data, s.Path(clusterName, BackupDirName))
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationLock(t *testing.T) {
+	spec := NewSpec(t.TempDir(), func() Metadata {
+		return new(TestMetadata)
+	})
+	meta := &TestMetadata{BaseMeta: BaseMeta{Version: "1.1.1"}, Topo: &TestTopology{}}
+	assert.Nil(t, spec.SaveMeta("name1", meta))
+
+	held, err := spec.AcquireOperationLock("name1", NewOperationLock())
+	assert.Nil(t, err)
+	assert.Nil(t, held)
+
+	// held by the process alive
+	held, err = spec.AcquireOperationLock("name1", NewOperationLock())
+	assert.True(t, errorx.IsOfType(err, ErrOperationLocked))
+	assert.Equal(t, os.Getpid(), held.PID)
+
+	// the meta is restored to the one before the operation
+	meta.Version = "2.2.2"
+	assert.Nil(t, spec.SaveMeta("name1", meta))
+	assert.Nil(t, spec.RestoreOperationMeta("name1"))
+	restored := new(TestMetadata)
+	assert.Nil(t, spec.Metadata("name1", restored))
+	assert.Equal(t, "1.1.1", restored.Version)
+
+	assert.Nil(t, spec.ReleaseOperationLock("name1"))
+	held, err = spec.OperationLock("name1")
+	assert.Nil(t, err)
+	assert.Nil(t, held)
+	assert.NotNil(t, spec.RestoreOperationMeta("name1"))
+
+	// left by a process exited, the lock file is not flocked by any process
+	running, err := spec.IsOperationRunning("name1")
+	assert.Nil(t, err)
+	assert.False(t, running)
+	lock := NewOperationLock()
+	lock.PID = 1
+	data, _ := json.Marshal(lock)
+	assert.Nil(t, os.WriteFile(spec.Path("name1", OperationLockName), data, 0644))
+	held, err = spec.AcquireOperationLock("name1", NewOperationLock())
+	assert.True(t, errorx.IsOfType(err, ErrOperationInterrupted))
+	assert.Equal(t, lock.PID, held.PID)
+
+	// the empty or corrupt locks are left by the processes killed too
+	assert.Nil(t, os.WriteFile(spec.Path("name1", OperationLockName), nil, 0644))
+	held, err = spec.AcquireOperationLock("name1", NewOperationLock())
+	assert.True(t, errorx.IsOfType(err, ErrOperationInterrupted))
+	assert.Equal(t, 0, held.PID)
+
+	// the locks held on other hosts are never stale
+	lock.Host = "other-" + lock.Host
+	data, _ = json.Marshal(lock)
+	assert.Nil(t, os.WriteFile(spec.Path("name1", OperationLockName), data, 0644))
+	_, err = spec.AcquireOperationLock("name1", NewOperationLock())
+	assert.True(t, errorx.IsOfType(err, ErrOperationLocked))
+
+	// held by the current process
+	assert.Nil(t, spec.ReleaseOperationLock("name1"))
+	held, err = spec.AcquireOperationLock("name1", NewOperationLock())
+	assert.Nil(t, err)
+	assert.Nil(t, held)
+	running, err = spec.IsOperationRunning("name1")
+	assert.Nil(t, err)
+	assert.True(t, running)
+	assert.Nil(t, spec.ReleaseOperationLock("name1"))
+	running, err = spec.IsOperationRunning("name1")
+	assert.Nil(t, err)
+	assert.False(t, running)
+}
@@ -58,16 +58,20 @@ To generate a sample topology file:
 // ParseTopologyYaml read yaml content from `file` and unmarshal it to `out`
 // ignoreGlobal ignore global variables in file, only ignoreGlobal with a index of 0 is effective
 func ParseTopologyYaml(file string, out Topology, ignoreGlobal ...bool) error {
-	suggestionProps := map[string]string{
-		"File": file,
-	}
-
 	zap.L().Debug("Parse topology file", zap.String("file", file))
 
 	yamlFile, err := ReadYamlFile(file)
 	if err != nil {
 		return err
 	}
+	return parseTopologyData(file, yamlFile, out, ignoreGlobal...)
+}
+
+// parseTopologyData unmarshal the content of topology `file` to `out`
+func parseTopologyData(file string, yamlFile []byte, out Topology, ignoreGlobal ...bool) error {
+	suggestionProps := map[string]string{
+		"File": file,
+	}
 
 	// keep the global config in out
 	if len(ignoreGlobal) > 0 && ignoreGlobal[0] {
@@ -86,7 +90,7 @@ func ParseTopologyYaml(file string, out Topology, ignoreGlobal ...bool) error {
 		yamlFile, _ = yaml.Marshal(newTopo)
 	}
 
-	if err = yaml.UnmarshalStrict(yamlFile, out); err != nil {
+	if err := yaml.UnmarshalStrict(yamlFile, out); err != nil {
 		return ErrTopologyParseFailed.
 			Wrap(err, "Failed to parse topology file %s", file).
 			WithProperty(tui.SuggestionFromTemplate(`
@@ -14,11 +14,13 @@
 package spec
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/joomcode/errorx"
 	"github.com/pingcap/check"
 )
 
@@ -450,7 +452,10 @@ func merge4test(base, scale string) (*Specification, error) {
 		return nil, err
 	}
 
-	mergedTopo := baseTopo.MergeTopo(scaleTopo)
+	mergedTopo, err := baseTopo.MergeTopo(scaleTopo)
+	if err != nil {
+		return nil, err
+	}
 	if err := mergedTopo.Validate(); err != nil {
 		return nil, err
 	}
@@ -565,3 +570,35 @@ func (s *topoSuite) TestFixRelativePath(c *check.C) {
 	c.Assert(topo.TiKVServers[0].DataDir, check.Equals, "")
 	c.Assert(topo.TiKVServers[0].LogDir, check.Equals, "")
 }
+
+func (s *topoSuite) TestTopologyTypeMismatch(c *check.C) {
+	topo := &Specification{}
+	cluster, err := AsClusterTopology(topo)
+	c.Assert(err, check.IsNil)
+	c.Assert(cluster, check.Equals, topo)
+
+	_, err = AsClusterTopology(&TestTopology{})
+	c.Assert(errorx.IsOfType(err, ErrTopologyTypeMismatch), check.IsTrue)
+	_, err = AsClusterTopology(nil)
+	c.Assert(errorx.IsOfType(err, ErrTopologyTypeMismatch), check.IsTrue)
+
+	_, err = MergeTopology(topo, &TestTopology{})
+	c.Assert(errorx.IsOfType(err, ErrTopologyTypeMismatch), check.IsTrue)
+	merged, err := MergeTopology(topo, topo.NewPart())
+	c.Assert(err, check.IsNil)
+	c.Assert(merged, check.FitsTypeOf, topo)
+	_, err = topo.MergeTopo(&TestTopology{})
+	c.Assert(errorx.IsOfType(err, ErrTopologyTypeMismatch), check.IsTrue)
+
+	meta := &ClusterMeta{}
+	err = meta.SetTopology(&TestTopology{})
+	c.Assert(errorx.IsOfType(err, ErrTopologyTypeMismatch), check.IsTrue)
+	c.Assert(meta.SetTopology(topo), check.IsNil)
+	c.Assert(meta.Topology, check.Equals, topo)
+
+	// the hooks return an error instead of panic on the wrong topology
+	_, err = (&TiKVInstance{}).API(&TestTopology{}, 10, nil)
+	c.Assert(errorx.IsOfType(err, ErrTopologyTypeMismatch), check.IsTrue)
+	err = PreRestart(context.Background(), &CDCInstance{}, &TestTopology{}, 10, nil)
+	c.Assert(errorx.IsOfType(err, ErrTopologyTypeMismatch), check.IsTrue)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/utils"
@@ -60,16 +61,16 @@ func (s *PDSpec) Status(ctx context.Context, timeout time.Duration, tlsCfg *tls.
 	}
 
 	addr := fmt.Sprintf("%s:%d", s.Host, s.ClientPort)
-	pc := api.NewPDClient(ctx, []string{addr}, timeout, tlsCfg)
+	pc := api.NewPDClient(ctx, []string{addr}, api.RequestTimeout(ctx, timeout), tlsCfg)
 
 	// check health
-	err := pc.CheckHealth()
+	err := pc.CheckHealth(ctx)
 	if err != nil {
 		return "Down"
 	}
 
 	// find leader node
-	leader, err := pc.GetLeader()
+	leader, err := pc.GetLeader(ctx)
 	if err != nil {
 		return "ERR"
 	}
@@ -190,7 +191,7 @@ func (i *PDInstance) InitConfig(
 		WithAdvertisePeerAddr(spec.AdvertisePeerAddr)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_pd.sh")
@@ -292,7 +293,10 @@ func (i *PDInstance) ScaleConfig(
 		return err
 	}
 
-	cluster := mustBeClusterTopo(topo)
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
+	}
 	spec := i.InstanceSpec.(*PDSpec)
 	cfg0 := scripts.NewPDScript(
 		i.Name,
@@ -313,7 +317,7 @@ func (i *PDInstance) ScaleConfig(
 	cfg := scripts.NewPDScaleScript(cfg0)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 
@@ -331,17 +335,17 @@ var _ RollingUpdateInstance = &PDInstance{}
 
 // IsLeader checks if the instance is PD leader
 func (i *PDInstance) IsLeader(ctx context.Context, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) (bool, error) {
-	tidbTopo, ok := topo.(*Specification)
-	if !ok {
-		panic("topo should be type of tidb topology")
+	tidbTopo, err := AsClusterTopology(topo)
+	if err != nil {
+		return false, err
 	}
-	pdClient := api.NewPDClient(ctx, tidbTopo.GetPDList(), time.Second*5, tlsCfg)
+	pdClient := api.NewPDClient(ctx, tidbTopo.GetPDList(), api.RequestTimeout(ctx, time.Second*5), tlsCfg)
 
-	return i.isLeader(pdClient)
+	return i.isLeader(ctx, pdClient)
 }
 
-func (i *PDInstance) isLeader(pdClient *api.PDClient) (bool, error) {
-	leader, err := pdClient.GetLeader()
+func (i *PDInstance) isLeader(ctx context.Context, pdClient *api.PDClient) (bool, error) {
+	leader, err := pdClient.GetLeader(ctx)
 	if err != nil {
 		return false, errors.Annotatef(err, "failed to get PD leader %s", i.GetHost())
 	}
@@ -349,47 +353,78 @@ func (i *PDInstance) isLeader(pdClient *api.PDClient) (bool, error) {
 	return leader.Name == i.Name, nil
 }
 
-// PreRestart implements RollingUpdateInstance interface.
-func (i *PDInstance) PreRestart(ctx context.Context, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) error {
+// API implements RollingUpdateInstance interface.
+func (i *PDInstance) API(topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) (ComponentAPI, error) {
+	tidbTopo, err := AsClusterTopology(topo)
+	if err != nil {
+		return nil, err
+	}
+	return &pdAPI{
+		ins:               i,
+		topo:              tidbTopo,
+		apiTimeoutSeconds: apiTimeoutSeconds,
+		tlsCfg:            tlsCfg,
+	}, nil
+}
+
+// pdHealthTimeout is the time to wait for a PD to be healthy after restarted
+const pdHealthTimeout = 120 * time.Second
+
+// pdAPI transfers the PD leader before restarting a PD instance
+type pdAPI struct {
+	NopComponentAPI
+	ins               *PDInstance
+	topo              *Specification
+	apiTimeoutSeconds int
+	tlsCfg            *tls.Config
+}
+
+// RestartHooks implements RestartHookDescriber interface.
+func (a *pdAPI) RestartHooks() []RestartHook {
+	var hooks []RestartHook
+	if len(a.topo.PDServers) > 1 {
+		hooks = append(hooks, RestartHook{Name: RestartHookResignLeader, Action: "transfer the PD leader if it's the leader", Timeout: time.Second * time.Duration(a.apiTimeoutSeconds)})
+	}
+	return append(hooks, RestartHook{Name: RestartHookHealth, Action: "wait for the PD to be healthy", Timeout: pdHealthTimeout})
+}
+
+// ResignLeader implements ComponentAPI interface.
+func (a *pdAPI) ResignLeader(ctx context.Context) error {
 	timeoutOpt := &utils.RetryOption{
-		Timeout: time.Second * time.Duration(apiTimeoutSeconds),
+		Timeout: time.Second * time.Duration(a.apiTimeoutSeconds),
 		Delay:   time.Second * 2,
 	}
 
-	tidbTopo, ok := topo.(*Specification)
-	if !ok {
-		panic("topo should be type of tidb topology")
-	}
-	pdClient := api.NewPDClient(ctx, tidbTopo.GetPDList(), time.Second*5, tlsCfg)
+	pdClient := api.NewPDClient(ctx, a.topo.GetPDList(), api.RequestTimeout(ctx, time.Second*5), a.tlsCfg)
 
-	isLeader, err := i.isLeader(pdClient)
+	isLeader, err := a.ins.isLeader(ctx, pdClient)
 	if err != nil {
 		return err
 	}
-	if len(tidbTopo.PDServers) > 1 && isLeader {
-		if err := pdClient.EvictPDLeader(timeoutOpt); err != nil {
-			return errors.Annotatef(err, "failed to evict PD leader %s", i.GetHost())
+	if len(a.topo.PDServers) > 1 && isLeader {
+		if err := pdClient.EvictPDLeader(ctx, timeoutOpt); err != nil {
+			return errors.Annotatef(err, "failed to evict PD leader %s", a.ins.GetHost())
 		}
 	}
 
 	return nil
 }
 
-// PostRestart implements RollingUpdateInstance interface.
-func (i *PDInstance) PostRestart(ctx context.Context, topo Topology, tlsCfg *tls.Config) error {
+// Health implements ComponentAPI interface.
+func (a *pdAPI) Health(ctx context.Context) error {
 	// When restarting the next PD, if the PD has not been fully started and has become the target of
 	// the transfer leader, this may cause the PD service to be unavailable for about 10 seconds.
 
-	timeoutOpt := utils.RetryOption{
+	waitOpt := utils.WaitOption{
+		Timeout:  pdHealthTimeout,
+		Interval: time.Second,
 		Attempts: 100,
-		Delay:    time.Second,
-		Timeout:  120 * time.Second,
 	}
-	currentPDAddrs := []string{fmt.Sprintf("%s:%d", i.Host, i.Port)}
-	pdClient := api.NewPDClient(ctx, currentPDAddrs, 5*time.Second, tlsCfg)
+	currentPDAddrs := []string{fmt.Sprintf("%s:%d", a.ins.Host, a.ins.Port)}
+	pdClient := api.NewPDClient(ctx, currentPDAddrs, api.RequestTimeout(ctx, 5*time.Second), a.tlsCfg)
 
-	if err := utils.Retry(pdClient.CheckHealth, timeoutOpt); err != nil {
-		return errors.Annotatef(err, "failed to start PD peer %s", i.GetHost())
+	if err := utils.Wait(ctx, func() error { return pdClient.CheckHealth(ctx) }, waitOpt); err != nil {
+		return errors.Annotatef(err, "failed to start PD peer %s", a.ins.GetHost())
 	}
 
 	return nil
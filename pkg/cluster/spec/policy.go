@@ -0,0 +1,232 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// PolicyFile is the file in the profile dir defining the policies of the site
+const PolicyFile = "policy.yaml"
+
+var errDeployPolicyViolation = errNSDeploy.NewType("policy_violation", utils.ErrTraitPreCheck)
+
+// Policy is an organizational rule the topology must comply with
+type Policy interface {
+	Name() string
+	// Check returns the violations of the topology, empty if it complies
+	Check(topo Topology) []string
+}
+
+var (
+	policyMu           sync.Mutex
+	registeredPolicies []Policy
+)
+
+// RegisterPolicy registers a policy evaluated for every deploy and scale-out
+func RegisterPolicy(p Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	registeredPolicies = append(registeredPolicies, p)
+}
+
+// PolicyRule is a declarative policy defined in the policy file, e.g.:
+//
+//	rules:
+//	  - name: tikv-data-disk
+//	    components: [tikv]
+//	    data_dir_pattern: "^/data[0-9]*/"
+//	  - name: no-pd-with-tikv
+//	    not_colocated: [pd, tikv]
+//	  - name: rack-label
+//	    required_labels: [rack]
+type PolicyRule struct {
+	RuleName string `yaml:"name"`
+	// the components the rule applies to, all components if empty
+	Components []string `yaml:"components,omitempty"`
+	// the regexp all data directories must match
+	DataDirPattern string `yaml:"data_dir_pattern,omitempty"`
+	// the labels every TiKV instance must have
+	RequiredLabels []string `yaml:"required_labels,omitempty"`
+	// the components that must not be deployed on the same host
+	NotColocated []string `yaml:"not_colocated,omitempty"`
+	// the message shown when the rule is violated
+	Message string `yaml:"message,omitempty"`
+
+	dirPattern *regexp.Regexp
+}
+
+// PolicyConfig is the content of the policy file
+type PolicyConfig struct {
+	Rules []*PolicyRule `yaml:"rules"`
+}
+
+var _ Policy = &PolicyRule{}
+
+// Name implements Policy interface
+func (r *PolicyRule) Name() string {
+	return r.RuleName
+}
+
+func (r *PolicyRule) appliesTo(component string) bool {
+	if len(r.Components) == 0 {
+		return true
+	}
+	for _, c := range r.Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PolicyRule) violation(format string, args ...interface{}) string {
+	msg := fmt.Sprintf(format, args...)
+	if r.Message != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, r.Message)
+	}
+	return msg
+}
+
+// Check implements Policy interface
+func (r *PolicyRule) Check(topo Topology) []string {
+	var violations []string
+	user := topo.BaseTopo().GlobalOptions.User
+
+	hostComponents := make(map[string]set.StringSet)
+	notColocated := set.NewStringSet(r.NotColocated...)
+
+	topo.IterInstance(func(inst Instance) {
+		component := inst.ComponentName()
+		if notColocated.Exist(component) {
+			if hostComponents[inst.GetHost()] == nil {
+				hostComponents[inst.GetHost()] = set.NewStringSet()
+			}
+			hostComponents[inst.GetHost()].Insert(component)
+		}
+
+		if !r.appliesTo(component) {
+			return
+		}
+
+		if r.dirPattern != nil && inst.DataDir() != "" {
+			for _, dir := range MultiDirAbs(user, inst.DataDir()) {
+				if !r.dirPattern.MatchString(dir) {
+					violations = append(violations, r.violation("data directory %s of %s is not allowed", dir, inst.ID()))
+				}
+			}
+		}
+
+		if len(r.RequiredLabels) > 0 {
+			kv, ok := inst.(*TiKVInstance)
+			if !ok {
+				return
+			}
+			labels, err := kv.InstanceSpec.(*TiKVSpec).Labels()
+			if err != nil {
+				violations = append(violations, r.violation("invalid labels of %s: %s", inst.ID(), err))
+				return
+			}
+			for _, l := range r.RequiredLabels {
+				if _, ok := labels[l]; !ok {
+					violations = append(violations, r.violation("label %s is required for %s", l, inst.ID()))
+				}
+			}
+		}
+	})
+
+	hosts := make([]string, 0, len(hostComponents))
+	for host, components := range hostComponents {
+		if len(components) > 1 {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		components := hostComponents[host].Slice()
+		sort.Strings(components)
+		violations = append(violations, r.violation("%s are not allowed on the same host %s", strings.Join(components, ", "), host))
+	}
+
+	return violations
+}
+
+// LoadPolicies loads the declarative policies from the file, no policy is
+// returned if the file does not exist.
+func LoadPolicies(fname string) ([]Policy, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.AddStack(err)
+	}
+
+	cfg := PolicyConfig{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse policy file %s", fname)
+	}
+
+	policies := make([]Policy, 0, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		if r.RuleName == "" {
+			r.RuleName = fmt.Sprintf("rule-%d", i)
+		}
+		if r.DataDirPattern != "" {
+			if r.dirPattern, err = regexp.Compile(r.DataDirPattern); err != nil {
+				return nil, errors.Annotatef(err, "invalid data_dir_pattern of policy %s", r.RuleName)
+			}
+		}
+		policies = append(policies, r)
+	}
+	return policies, nil
+}
+
+// CheckPolicies evaluates the registered policies and the ones defined in the
+// policy file of the profile dir against the topology.
+func CheckPolicies(topo Topology) error {
+	policies, err := LoadPolicies(ProfilePath(PolicyFile))
+	if err != nil {
+		return err
+	}
+	policyMu.Lock()
+	policies = append(policies, registeredPolicies...)
+	policyMu.Unlock()
+
+	var violations []string
+	for _, p := range policies {
+		for _, v := range p.Check(topo) {
+			violations = append(violations, fmt.Sprintf("[%s] %s", p.Name(), v))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return errDeployPolicyViolation.New("Topology violates the policies of the site").
+		WithProperty(tui.SuggestionFromString(
+			"Please fix the following violations in the topology file:\n  " + strings.Join(violations, "\n  "),
+		))
+}
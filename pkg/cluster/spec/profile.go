@@ -37,6 +37,8 @@ const (
 	TLSClientCert            = "client.crt"
 	TLSClientKey             = "client.pem"
 	PFXClientCert            = "client.pfx"
+	CredentialsDir           = "credentials"
+	APITokenFile             = "api_token"
 )
 
 var profileDir string
@@ -58,20 +60,9 @@ var initialized = false
 // the profile directory, otherwise the `$HOME/.tiup` of current user is used.
 // The directory will be created before return if it does not already exist.
 func Initialize(base string) error {
-	tiupData := os.Getenv(tiuplocaldata.EnvNameComponentDataDir)
-	tiupHome := os.Getenv(tiuplocaldata.EnvNameHome)
-
-	switch {
-	case tiupData != "":
-		profileDir = tiupData
-	case tiupHome != "":
-		profileDir = path.Join(tiupHome, tiuplocaldata.StorageParentDir, base)
-	default:
-		homeDir, err := getHomeDir()
-		if err != nil {
-			return errors.Trace(err)
-		}
-		profileDir = path.Join(homeDir, ".tiup", tiuplocaldata.StorageParentDir, base)
+	var err error
+	if profileDir, err = ResolveProfileDir(base); err != nil {
+		return err
 	}
 
 	clusterBaseDir := filepath.Join(profileDir, TiUPClusterDir)
@@ -79,12 +70,28 @@ func Initialize(base string) error {
 		return &ClusterMeta{
 			Topology: new(Specification),
 		}
-	})
+	}).SetHostRegistry(NewHostRegistry(HostRegistryPath(), base))
 	initialized = true
 	// make sure the dir exist
 	return utils2.CreateDir(profileDir)
 }
 
+// ResolveProfileDir returns the profile directory used by Initialize without
+// initializing it.
+func ResolveProfileDir(base string) (string, error) {
+	if tiupData := os.Getenv(tiuplocaldata.EnvNameComponentDataDir); tiupData != "" {
+		return tiupData, nil
+	}
+	if tiupHome := os.Getenv(tiuplocaldata.EnvNameHome); tiupHome != "" {
+		return path.Join(tiupHome, tiuplocaldata.StorageParentDir, base), nil
+	}
+	homeDir, err := getHomeDir()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return path.Join(homeDir, ".tiup", tiuplocaldata.StorageParentDir, base), nil
+}
+
 // ProfileDir returns the full profile directory path of TiUP.
 func ProfileDir() string {
 	return profileDir
@@ -95,6 +102,12 @@ func ProfilePath(subpath ...string) string {
 	return path.Join(append([]string{profileDir}, subpath...)...)
 }
 
+// HostRegistryPath returns the path of the host registry, it's a sibling of
+// the profile dirs so that it's shared by tiup-cluster and tiup-dm.
+func HostRegistryPath() string {
+	return filepath.Join(filepath.Dir(profileDir), HostRegistryDir)
+}
+
 // ClusterPath returns the full path to a subpath (file or directory) of a
 // cluster, it is a subdir in the profile dir of the user, with the cluster name
 // as its name.
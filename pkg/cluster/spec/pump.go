@@ -24,6 +24,7 @@ import (
 
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
 )
@@ -56,7 +57,7 @@ func (s *PumpSpec) Status(ctx context.Context, timeout time.Duration, tlsCfg *tl
 	state := statusByHost(s.Host, s.Port, "/status", timeout, tlsCfg)
 
 	if s.Offline {
-		binlogClient, err := api.NewBinlogClient(pdList, timeout, tlsCfg)
+		binlogClient, err := api.NewBinlogClient(pdList, api.RequestTimeout(ctx, timeout), tlsCfg)
 		if err != nil {
 			return state
 		}
@@ -158,7 +159,11 @@ func (i *PumpInstance) ScaleConfig(
 	defer func() {
 		i.topo = s
 	}()
-	i.topo = mustBeClusterTopo(topo)
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
+	}
+	i.topo = cluster
 
 	return i.InitConfig(ctx, e, clusterName, clusterVersion, deployUser, paths)
 }
@@ -193,7 +198,7 @@ func (i *PumpInstance) InitConfig(
 	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(topo.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pump_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_pump.sh")
@@ -0,0 +1,394 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+const (
+	// the label of the read-only zones if not specified
+	defaultReadOnlyZoneLabel = "zone"
+	// the placement rules generated for the read-only zones are prefixed with
+	readOnlyZoneRulePrefix = "read-only-"
+	// the group of the default placement rule
+	placementRuleGroup = "pd"
+	// the replicas of the default placement rule if max-replicas is not set
+	defaultMaxReplicas = 3
+)
+
+var errReadOnlyZone = errNSTopolohy.NewType("read_only_zone")
+
+// ReadOnlyZone is a zone, usually a separate DC, serving only reads and
+// analytics. Its TiKV instances hold learner replicas and never vote, so the
+// zone can be lost without affecting the quorum of the other zones.
+type ReadOnlyZone struct {
+	// Name is the value of the label of the instances in the zone
+	Name string `yaml:"name" validate:"name:editable"`
+	// Label is the name of the label, "zone" if not specified
+	Label string `yaml:"label,omitempty" validate:"label:editable"`
+	// Hosts are the hosts in the zone
+	Hosts []string `yaml:"hosts" validate:"hosts:editable"`
+	// Learners is the count of TiKV learner replicas in the zone, all the
+	// TiKV instances in the zone hold a replica if it's 0
+	Learners int `yaml:"learners,omitempty" validate:"learners:editable"`
+}
+
+// LabelName returns the name of the label of the zone
+func (z *ReadOnlyZone) LabelName() string {
+	if z.Label == "" {
+		return defaultReadOnlyZoneLabel
+	}
+	return z.Label
+}
+
+// ReadOnlyZoneOf returns the read-only zone the host is in, nil is returned
+// if it's not in any read-only zone
+func (s *Specification) ReadOnlyZoneOf(host string) *ReadOnlyZone {
+	for i := range s.GlobalOptions.ReadOnlyZones {
+		zone := &s.GlobalOptions.ReadOnlyZones[i]
+		for _, h := range zone.Hosts {
+			if h == host {
+				return zone
+			}
+		}
+	}
+	return nil
+}
+
+// readOnlyZoneTiKVCount returns the count of TiKV instances in the zone
+func (s *Specification) readOnlyZoneTiKVCount(zone *ReadOnlyZone) int {
+	count := 0
+	for _, kv := range s.TiKVServers {
+		if s.ReadOnlyZoneOf(kv.Host) == zone {
+			count++
+		}
+	}
+	return count
+}
+
+// readOnlyZoneLearners returns the count of learner replicas in the zone
+func (s *Specification) readOnlyZoneLearners(zone *ReadOnlyZone) int {
+	if zone.Learners > 0 {
+		return zone.Learners
+	}
+	return s.readOnlyZoneTiKVCount(zone)
+}
+
+// maxReplicas returns replication.max-replicas of PD
+func (s *Specification) maxReplicas() int {
+	switch v := GetValueFromPath(s.ServerConfigs.PD, "replication.max-replicas").(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return defaultMaxReplicas
+}
+
+// configLabels returns the server.labels in the config
+func configLabels(config map[string]interface{}) map[string]string {
+	return labelsAt(config, "server.labels")
+}
+
+// labelsAt returns the labels at path in the config
+func labelsAt(config map[string]interface{}, path string) map[string]string {
+	lbs := make(map[string]string)
+	switch m := GetValueFromPath(config, path).(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			lbs[k] = fmt.Sprint(v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			lbs[fmt.Sprint(k)] = fmt.Sprint(v)
+		}
+	}
+	return lbs
+}
+
+// fillReadOnlyZoneLabels labels the TiKV and TiFlash instances in the
+// read-only zones with the zone if they are not labeled, the conflicting
+// labels are left to validation
+func (s *Specification) fillReadOnlyZoneLabels() {
+	for _, kv := range s.TiKVServers {
+		zone := s.ReadOnlyZoneOf(kv.Host)
+		if zone == nil {
+			continue
+		}
+		if _, ok := configLabels(kv.Config)[zone.LabelName()]; !ok {
+			kv.Config = MergeConfig(kv.Config, map[string]interface{}{
+				"server.labels": map[string]interface{}{zone.LabelName(): zone.Name},
+			})
+		}
+	}
+	for _, flash := range s.TiFlashServers {
+		zone := s.ReadOnlyZoneOf(flash.Host)
+		if zone == nil {
+			continue
+		}
+		if _, ok := configLabels(flash.LearnerConfig)[zone.LabelName()]; !ok {
+			flash.LearnerConfig = MergeConfig(flash.LearnerConfig, map[string]interface{}{
+				"server.labels": map[string]interface{}{zone.LabelName(): zone.Name},
+			})
+		}
+	}
+}
+
+// validateReadOnlyZones checks the read-only zones are well defined and the
+// instances are labeled consistently with them
+func (s *Specification) validateReadOnlyZones() error {
+	zones := s.GlobalOptions.ReadOnlyZones
+	if len(zones) == 0 {
+		return nil
+	}
+
+	if enabled, ok := GetValueFromPath(s.ServerConfigs.PD, "replication.enable-placement-rules").(bool); ok && !enabled {
+		return errReadOnlyZone.New("`global.read_only_zones` requires replication.enable-placement-rules of PD")
+	}
+	locLabels, err := s.LocationLabels()
+	if err != nil {
+		return err
+	}
+
+	label := zones[0].LabelName()
+	names := set.NewStringSet()
+	hosts := set.NewStringSet()
+	for _, zone := range zones {
+		if zone.Name == "" {
+			return errReadOnlyZone.New("the name of read-only zone is empty")
+		}
+		if names.Exist(zone.Name) {
+			return errReadOnlyZone.New("read-only zone %s is defined more than once", zone.Name)
+		}
+		names.Insert(zone.Name)
+		if zone.LabelName() != label {
+			return errReadOnlyZone.New("read-only zones %s and %s are labeled by different labels %s and %s",
+				zones[0].Name, zone.Name, label, zone.LabelName())
+		}
+		if len(zone.Hosts) == 0 {
+			return errReadOnlyZone.New("no host is in read-only zone %s", zone.Name)
+		}
+		for _, host := range zone.Hosts {
+			if hosts.Exist(host) {
+				return errReadOnlyZone.New("host %s is in more than one read-only zone", host)
+			}
+			hosts.Insert(host)
+		}
+		if zone.Learners < 0 {
+			return errReadOnlyZone.New("the learners of read-only zone %s is negative", zone.Name)
+		}
+	}
+	if len(locLabels) > 0 && !set.NewStringSet(locLabels...).Exist(label) {
+		return errReadOnlyZone.New("label %s of read-only zones is not in replication.location-labels %v", label, locLabels)
+	}
+
+	for _, kv := range s.TiKVServers {
+		labels, err := kv.Labels()
+		if err != nil {
+			return err
+		}
+		value, labeled := labels[label]
+		zone := s.ReadOnlyZoneOf(kv.Host)
+		switch {
+		case zone != nil && value != zone.Name:
+			return errReadOnlyZone.New("TiKV %s:%d in read-only zone %s is labeled with %s=%s",
+				kv.Host, kv.GetMainPort(), zone.Name, label, value)
+		case zone == nil && labeled && names.Exist(value):
+			return errReadOnlyZone.New("TiKV %s:%d is labeled with read-only zone %s=%s but its host is not in the zone",
+				kv.Host, kv.GetMainPort(), label, value)
+		}
+	}
+	for _, flash := range s.TiFlashServers {
+		zone := s.ReadOnlyZoneOf(flash.Host)
+		if value := configLabels(flash.LearnerConfig)[label]; zone != nil && value != zone.Name {
+			return errReadOnlyZone.New("TiFlash %s:%d in read-only zone %s is labeled with %s=%s",
+				flash.Host, flash.GetMainPort(), zone.Name, label, value)
+		}
+	}
+	return nil
+}
+
+// ValidateReadOnlyZones checks the replicas of the read-only zones can be
+// placed, it's validated on the whole topology as a scaling part may not
+// contain all the instances
+func (s *Specification) ValidateReadOnlyZones() error {
+	if len(s.GlobalOptions.ReadOnlyZones) == 0 {
+		return nil
+	}
+
+	for i := range s.GlobalOptions.ReadOnlyZones {
+		zone := &s.GlobalOptions.ReadOnlyZones[i]
+		if count := s.readOnlyZoneTiKVCount(zone); zone.Learners > count {
+			return errReadOnlyZone.New("read-only zone %s requires %d TiKV learners but only %d TiKV instances are in it",
+				zone.Name, zone.Learners, count)
+		}
+	}
+
+	voters := 0
+	for _, kv := range s.TiKVServers {
+		if s.ReadOnlyZoneOf(kv.Host) == nil {
+			voters++
+		}
+	}
+	if replicas := s.maxReplicas(); voters < replicas {
+		return errReadOnlyZone.New("%d voter replicas are required by replication.max-replicas but only %d TiKV instances are out of the read-only zones",
+			replicas, voters)
+	}
+	return nil
+}
+
+// ReadOnlyZoneRules returns the placement rules of the read-only zones, the
+// default rule places the voters out of the read-only zones and a learner
+// rule is generated for each zone with TiKV learners
+func (s *Specification) ReadOnlyZoneRules() ([]*api.PlacementRule, error) {
+	locLabels, err := s.LocationLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	def := &api.PlacementRule{
+		GroupID:        placementRuleGroup,
+		ID:             "default",
+		Role:           "voter",
+		Count:          s.maxReplicas(),
+		LocationLabels: locLabels,
+	}
+	rules := []*api.PlacementRule{def}
+
+	zones := s.GlobalOptions.ReadOnlyZones
+	if len(zones) == 0 {
+		return rules, nil
+	}
+	names := make([]string, 0, len(zones))
+	for i := range zones {
+		zone := &zones[i]
+		names = append(names, zone.Name)
+		learners := s.readOnlyZoneLearners(zone)
+		if learners == 0 {
+			continue
+		}
+		rules = append(rules, &api.PlacementRule{
+			GroupID: placementRuleGroup,
+			ID:      readOnlyZoneRulePrefix + zone.Name,
+			Role:    "learner",
+			Count:   learners,
+			LabelConstraints: []api.LabelConstraint{
+				{Key: zone.LabelName(), Op: "in", Values: []string{zone.Name}},
+			},
+			LocationLabels: locLabels,
+		})
+	}
+	def.LabelConstraints = []api.LabelConstraint{
+		{Key: zones[0].LabelName(), Op: "notIn", Values: names},
+	}
+	return rules, nil
+}
+
+// liveReadOnlyZoneRules returns the rules to set to PD, the live rules and
+// replication config are kept and only the label constraints are changed,
+// e.g. the count of the default rule may be tuned out of tiup
+func liveReadOnlyZoneRules(desired, existing []*api.PlacementRule, rc *api.PDReplicationConfig) []*api.PlacementRule {
+	live := make(map[string]*api.PlacementRule)
+	for _, rule := range existing {
+		live[rule.ID] = rule
+	}
+
+	rules := make([]*api.PlacementRule, 0, len(desired))
+	for _, rule := range desired {
+		r := *rule
+		if l, ok := live[rule.ID]; ok {
+			r = *l
+			r.LabelConstraints = rule.LabelConstraints
+			if strings.HasPrefix(rule.ID, readOnlyZoneRulePrefix) {
+				// the learners of the zones are managed by tiup
+				r.Count = rule.Count
+			}
+		} else {
+			r.LocationLabels = rc.LocationLabels
+			if rule.ID == "default" && rc.MaxReplicas > 0 {
+				r.Count = int(rc.MaxReplicas)
+			}
+		}
+		rules = append(rules, &r)
+	}
+	return rules
+}
+
+// EnsureReadOnlyZoneRules applies the placement rules of the read-only zones
+// to PD, the rules of the zones removed from the topology are deleted. The
+// rules are left as is if no read-only zone is ever defined.
+func (s *Specification) EnsureReadOnlyZoneRules(ctx context.Context, tlsCfg *tls.Config, timeout time.Duration, pdList ...string) error {
+	if timeout < time.Second {
+		timeout = statusQueryTimeout
+	}
+	pc := api.NewPDClient(ctx, pdList, api.RequestTimeout(ctx, timeout), tlsCfg)
+	existing, err := pc.GetPlacementRules(ctx, placementRuleGroup)
+	if err != nil {
+		return errors.Annotate(err, "failed to get placement rules")
+	}
+	desiredRules, err := s.ReadOnlyZoneRules()
+	if err != nil {
+		return err
+	}
+
+	desired := set.NewStringSet()
+	for _, rule := range desiredRules {
+		desired.Insert(rule.ID)
+	}
+	var stale []string
+	for _, rule := range existing {
+		if strings.HasPrefix(rule.ID, readOnlyZoneRulePrefix) && !desired.Exist(rule.ID) {
+			stale = append(stale, rule.ID)
+		}
+	}
+	if len(s.GlobalOptions.ReadOnlyZones) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	rc, err := pc.GetReplicationConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !rc.EnablePlacementRules {
+		return errors.New("placement rules are not enabled in PD")
+	}
+	rules := liveReadOnlyZoneRules(desiredRules, existing, rc)
+
+	// add the learner rules before restricting the voters, so the replicas
+	// in the read-only zones are turned into learners instead of removed
+	for i := len(rules) - 1; i >= 0; i-- {
+		if err := pc.SetPlacementRule(ctx, rules[i]); err != nil {
+			return errors.Annotatef(err, "failed to set placement rule %s", rules[i].ID)
+		}
+	}
+	for _, id := range stale {
+		if err := pc.DeletePlacementRule(ctx, placementRuleGroup, id); err != nil {
+			return errors.Annotatef(err, "failed to delete placement rule %s", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestReadOnlyZones(t *testing.T) {
+	topo := &Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  read_only_zones:
+    - name: ap
+      hosts: [172.16.5.4, 172.16.5.5]
+      learners: 1
+server_configs:
+  pd:
+    replication.location-labels: [zone, host]
+tikv_servers:
+  - host: 172.16.5.1
+    config:
+      server.labels: { zone: tp1 }
+  - host: 172.16.5.2
+    config:
+      server.labels: { zone: tp2 }
+  - host: 172.16.5.3
+    config:
+      server.labels: { zone: tp3 }
+  - host: 172.16.5.4
+    config:
+      server.labels: { host: kv4 }
+tiflash_servers:
+  - host: 172.16.5.5
+`), topo)
+	assert.Nil(t, err)
+	assert.Nil(t, topo.ValidateReadOnlyZones())
+
+	// the instances in the zone are labeled
+	labels, err := topo.TiKVServers[3].Labels()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"zone": "ap", "host": "kv4"}, labels)
+	assert.Equal(t, map[string]string{"zone": "ap"}, configLabels(topo.TiFlashServers[0].LearnerConfig))
+	assert.Nil(t, topo.ReadOnlyZoneOf("172.16.5.1"))
+	assert.Equal(t, "ap", topo.ReadOnlyZoneOf("172.16.5.4").Name)
+
+	rules, err := topo.ReadOnlyZoneRules()
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PlacementRule{
+		{
+			GroupID:          "pd",
+			ID:               "default",
+			Role:             "voter",
+			Count:            3,
+			LabelConstraints: []api.LabelConstraint{{Key: "zone", Op: "notIn", Values: []string{"ap"}}},
+			LocationLabels:   []string{"zone", "host"},
+		},
+		{
+			GroupID:          "pd",
+			ID:               "read-only-ap",
+			Role:             "learner",
+			Count:            1,
+			LabelConstraints: []api.LabelConstraint{{Key: "zone", Op: "in", Values: []string{"ap"}}},
+			LocationLabels:   []string{"zone", "host"},
+		},
+	}, rules)
+
+	// the live default rule and replication config of PD are kept
+	live := liveReadOnlyZoneRules(rules, []*api.PlacementRule{
+		{
+			GroupID:        "pd",
+			ID:             "default",
+			Role:           "voter",
+			Count:          5,
+			LocationLabels: []string{"dc", "zone", "host"},
+		},
+	}, &api.PDReplicationConfig{MaxReplicas: 5, LocationLabels: []string{"dc", "zone", "host"}})
+	assert.Equal(t, []*api.PlacementRule{
+		{
+			GroupID:          "pd",
+			ID:               "default",
+			Role:             "voter",
+			Count:            5,
+			LabelConstraints: []api.LabelConstraint{{Key: "zone", Op: "notIn", Values: []string{"ap"}}},
+			LocationLabels:   []string{"dc", "zone", "host"},
+		},
+		{
+			GroupID:          "pd",
+			ID:               "read-only-ap",
+			Role:             "learner",
+			Count:            1,
+			LabelConstraints: []api.LabelConstraint{{Key: "zone", Op: "in", Values: []string{"ap"}}},
+			LocationLabels:   []string{"dc", "zone", "host"},
+		},
+	}, live)
+
+	// not enough voters out of the zone
+	topo.ServerConfigs.PD["replication.max-replicas"] = 5
+	assert.NotNil(t, topo.ValidateReadOnlyZones())
+	delete(topo.ServerConfigs.PD, "replication.max-replicas")
+
+	// not enough TiKV instances for the learners
+	topo.GlobalOptions.ReadOnlyZones[0].Learners = 2
+	assert.NotNil(t, topo.ValidateReadOnlyZones())
+}
+
+func TestReadOnlyZonesInvalid(t *testing.T) {
+	for _, topoYaml := range []string{
+		// conflicting label of the TiKV in the zone
+		`
+global:
+  read_only_zones:
+    - name: ap
+      hosts: [172.16.5.4]
+tikv_servers:
+  - host: 172.16.5.4
+    config:
+      server.labels: { zone: tp1 }
+`,
+		// the TiKV out of the zone is labeled with it
+		`
+global:
+  read_only_zones:
+    - name: ap
+      hosts: [172.16.5.4]
+tikv_servers:
+  - host: 172.16.5.1
+    config:
+      server.labels: { zone: ap }
+`,
+		// the label is not a location label
+		`
+global:
+  read_only_zones:
+    - name: ap
+      label: dc
+      hosts: [172.16.5.4]
+server_configs:
+  pd:
+    replication.location-labels: [zone, host]
+`,
+		// the host is in two zones
+		`
+global:
+  read_only_zones:
+    - name: ap1
+      hosts: [172.16.5.4]
+    - name: ap2
+      hosts: [172.16.5.4]
+`,
+		// placement rules are disabled
+		`
+global:
+  read_only_zones:
+    - name: ap
+      hosts: [172.16.5.4]
+server_configs:
+  pd:
+    replication.enable-placement-rules: false
+`,
+	} {
+		topo := &Specification{}
+		assert.NotNil(t, yaml.Unmarshal([]byte(topoYaml), topo), topoYaml)
+	}
+}
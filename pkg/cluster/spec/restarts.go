@@ -0,0 +1,33 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"time"
+)
+
+// RestartEvent is a restart of an instance, the reason is the operation
+// restarting it, or "detected" if it's not restarted by tiup
+type RestartEvent struct {
+	Time   time.Time `yaml:"time"`
+	Reason string    `yaml:"reason"`
+}
+
+// RestartHistory is the restarts of an instance, Started is the start time
+// of the instance seen last time
+type RestartHistory struct {
+	Node    string         `yaml:"node"`
+	Started time.Time      `yaml:"started,omitempty"`
+	Events  []RestartEvent `yaml:"events,omitempty,flow"`
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// RuntimeOverride is a temporary override of the command line flags of an
+// instance, e.g. enabling a debug flag on one TiKV. It's tracked in the meta
+// instead of editing the run script manually, so it's not lost silently and
+// is removed by the next reload once expired.
+type RuntimeOverride struct {
+	Node   string    `yaml:"node"`
+	Flags  []string  `yaml:"flags"`
+	Expire time.Time `yaml:"expire,omitempty"`
+}
+
+// Expired checks if the override is expired, an override without expiry
+// never expires.
+func (o *RuntimeOverride) Expired(now time.Time) bool {
+	return !o.Expire.IsZero() && now.After(o.Expire)
+}
+
+// LogLevelChange is a log level of an instance changed temporarily, it's
+// tracked in the meta with the original level, so it could be reverted even
+// if the command changing it is gone.
+type LogLevelChange struct {
+	Node  string `yaml:"node"`
+	Level string `yaml:"level"`
+	// Origin is the level before the change, it's empty if the level is
+	// changed by a runtime override, which is reverted by removing it
+	Origin string    `yaml:"origin,omitempty"`
+	Expire time.Time `yaml:"expire,omitempty"`
+}
+
+// Expired checks if the change is expired, a change without expiry never
+// expires.
+func (c *LogLevelChange) Expired(now time.Time) bool {
+	return !c.Expire.IsZero() && now.After(c.Expire)
+}
+
+// RuntimeFlags returns the flags of the overrides of the node that are not
+// expired.
+func (m *BaseMeta) RuntimeFlags(node string, now time.Time) []string {
+	if m.RuntimeOverrides == nil {
+		return nil
+	}
+
+	var flags []string
+	for _, o := range *m.RuntimeOverrides {
+		if o.Node == node && !o.Expired(now) {
+			flags = append(flags, o.Flags...)
+		}
+	}
+	return flags
+}
+
+// SetRuntimeOverride adds the override, replacing the existing one of the
+// node, it's not supported if the meta does not track the overrides.
+func (m *BaseMeta) SetRuntimeOverride(override RuntimeOverride) error {
+	if m.RuntimeOverrides == nil {
+		return errors.New("runtime overrides are not supported by the cluster")
+	}
+	m.RemoveRuntimeOverrides(func(o *RuntimeOverride) bool { return o.Node == override.Node })
+	*m.RuntimeOverrides = append(*m.RuntimeOverrides, override)
+	return nil
+}
+
+// RemoveRuntimeOverrides removes the overrides matching the filter and
+// returns the removed ones.
+func (m *BaseMeta) RemoveRuntimeOverrides(filter func(o *RuntimeOverride) bool) []RuntimeOverride {
+	if m.RuntimeOverrides == nil {
+		return nil
+	}
+
+	var kept, removed []RuntimeOverride
+	for _, o := range *m.RuntimeOverrides {
+		o := o
+		if filter(&o) {
+			removed = append(removed, o)
+		} else {
+			kept = append(kept, o)
+		}
+	}
+	*m.RuntimeOverrides = kept
+	return removed
+}
+
+// isExecRedirection checks if the exec line only redirects the output of the
+// script, e.g. `exec > >(tee -i -a log)` or `exec 2>&1`
+func isExecRedirection(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return true
+	}
+	arg := strings.TrimLeft(fields[1], "0123456789&")
+	return strings.HasPrefix(arg, ">") || strings.HasPrefix(arg, "<")
+}
+
+// ApplyRuntimeFlags appends the flags to the exec command of the binary in
+// the run script, the exec lines redirecting the output are skipped
+func ApplyRuntimeFlags(script []byte, flags []string) []byte {
+	if len(flags) == 0 {
+		return script
+	}
+
+	lines := strings.Split(string(script), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "exec ") || isExecRedirection(trimmed) {
+			continue
+		}
+		// the flags are passed in the continuation lines if the command is
+		// split into multiple lines, otherwise appended to the line
+		if strings.HasSuffix(trimmed, "\\") {
+			extra := make([]string, 0, len(flags))
+			for _, flag := range flags {
+				extra = append(extra, "    "+flag+" \\")
+			}
+			lines = append(lines[:i+1], append(extra, lines[i+1:]...)...)
+		} else {
+			lines[i] = line + " " + strings.Join(flags, " ")
+		}
+		break
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
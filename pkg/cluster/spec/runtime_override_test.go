@@ -0,0 +1,96 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
+)
+
+func (s *utilSuite) TestRuntimeOverrides(c *check.C) {
+	now := time.Now()
+	meta := &ClusterMeta{}
+	base := meta.GetBaseMeta()
+
+	c.Assert(base.SetRuntimeOverride(RuntimeOverride{Node: "h1:20160", Flags: []string{"--a"}}), check.IsNil)
+	c.Assert(base.SetRuntimeOverride(RuntimeOverride{Node: "h2:20160", Flags: []string{"--b"}, Expire: now.Add(-time.Minute)}), check.IsNil)
+	// replaces the existing override of the node
+	c.Assert(base.SetRuntimeOverride(RuntimeOverride{Node: "h1:20160", Flags: []string{"--c", "--d=1"}, Expire: now.Add(time.Hour)}), check.IsNil)
+	c.Assert(meta.RuntimeOverrides, check.HasLen, 2)
+
+	c.Assert(base.RuntimeFlags("h1:20160", now), check.DeepEquals, []string{"--c", "--d=1"})
+	c.Assert(base.RuntimeFlags("h2:20160", now), check.IsNil)
+
+	removed := base.RemoveRuntimeOverrides(func(o *RuntimeOverride) bool { return o.Expired(now) })
+	c.Assert(removed, check.HasLen, 1)
+	c.Assert(removed[0].Node, check.Equals, "h2:20160")
+	c.Assert(meta.RuntimeOverrides, check.HasLen, 1)
+
+	// the meta not tracking the overrides
+	c.Assert((&BaseMeta{}).SetRuntimeOverride(RuntimeOverride{Node: "h1:20160"}), check.NotNil)
+	c.Assert((&BaseMeta{}).RuntimeFlags("h1:20160", now), check.IsNil)
+}
+
+func (s *utilSuite) TestApplyRuntimeFlags(c *check.C) {
+	script := `#!/bin/bash
+exec bin/tikv-server \
+    --addr "0.0.0.0:20160" \
+    --log-file "/log/tikv.log" 2>> "/log/tikv_stderr.log"`
+	c.Assert(string(ApplyRuntimeFlags([]byte(script), nil)), check.Equals, script)
+	c.Assert(string(ApplyRuntimeFlags([]byte(script), []string{"--a", "--b=1"})), check.Equals, `#!/bin/bash
+exec bin/tikv-server \
+    --a \
+    --b=1 \
+    --addr "0.0.0.0:20160" \
+    --log-file "/log/tikv.log" 2>> "/log/tikv_stderr.log"`)
+
+	script = "exec bin/prometheus --config.file=conf/prometheus.yml"
+	c.Assert(string(ApplyRuntimeFlags([]byte(script), []string{"--a"})), check.Equals,
+		"exec bin/prometheus --config.file=conf/prometheus.yml --a")
+}
+
+func (s *utilSuite) TestApplyRuntimeFlagsOnTemplates(c *check.C) {
+	tikv, err := scripts.NewTiKVScript("v6.1.0", "172.16.5.140", 20160, 20180, "/deploy", "/data", "/log").Config()
+	c.Assert(err, check.IsNil)
+	exporter, err := scripts.NewNodeExporterScript("/deploy", "/log").WithPort(9100).Config()
+	c.Assert(err, check.IsNil)
+	prometheus, err := scripts.NewPrometheusScript("172.16.5.140", "/deploy", "/data", "/log").WithPort(9090).Config()
+	c.Assert(err, check.IsNil)
+
+	for _, tc := range []struct {
+		script []byte
+		exec   string
+	}{
+		{tikv, "exec bin/tikv-server \\"},
+		{exporter, "exec $EXPORTER_BIN \\"},
+		{prometheus, "exec bin/prometheus/prometheus \\"},
+	} {
+		lines := strings.Split(string(ApplyRuntimeFlags(tc.script, []string{"--a=1"})), "\n")
+		applied := -1
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "--a=1 \\" {
+				applied = i
+			}
+			// the redirections of the output are kept as is
+			if strings.HasPrefix(line, "exec >") || strings.HasPrefix(line, "exec 2>") {
+				c.Assert(line, check.Not(check.Matches), ".*--a=1.*")
+			}
+		}
+		c.Assert(applied > 0, check.IsTrue, check.Commentf("%s", tc.script))
+		c.Assert(lines[applied-1], check.Equals, tc.exec)
+	}
+}
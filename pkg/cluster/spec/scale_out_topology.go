@@ -0,0 +1,291 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/utils"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrTopologyGlobalChanged means the scale-out topology changes the global options
+	ErrTopologyGlobalChanged = errNSTopolohy.NewType("global_changed", utils.ErrTraitPreCheck)
+)
+
+// the sections of a topology shared by all instances, they can't be changed
+// by scaling out
+var globalSections = set.NewStringSet("global", "monitored", "server_configs")
+
+// the fields identifying an instance, they are never inherited
+var identityFields = map[string]bool{
+	"host":                  true,
+	"name":                  true,
+	"advertise_addr":        true,
+	"advertise_status_addr": true,
+	"arch":                  true,
+	"os":                    true,
+	"imported":              true,
+	"patched":               true,
+}
+
+// the dir fields, they are not inherited if they are the default dirs
+// derived from the port of the existing instance
+var dirFields = set.NewStringSet("deploy_dir", "data_dir", "log_dir")
+
+// ParseScaleOutTopologyYaml reads the scale-out topology from `file` and
+// unmarshal it to `out`, the global sections in the file are ignored as they
+// are inherited from `topo`. If inherit is true, the fields not set for the
+// new instances are inherited from the first existing instance of the same
+// role, see InheritInstanceDefaults.
+func ParseScaleOutTopologyYaml(file string, topo, out Topology, inherit bool) error {
+	zap.L().Debug("Parse scale-out topology file", zap.String("file", file))
+
+	yamlFile, err := ReadYamlFile(file)
+	if err != nil {
+		return err
+	}
+	if inherit {
+		if yamlFile, err = InheritInstanceDefaults(topo, yamlFile); err != nil {
+			return err
+		}
+	}
+	return parseTopologyData(file, yamlFile, out, true)
+}
+
+// InheritInstanceDefaults fills the fields not set for the instances in the
+// scale-out topology `data` with the values of the first existing instance
+// of the same role in `topo`. The fields identifying an instance, e.g. host,
+// are never inherited, and the dirs are not inherited if they are derived
+// from the port of the existing instance.
+func InheritInstanceDefaults(topo Topology, data []byte) ([]byte, error) {
+	var delta yaml.MapSlice
+	if err := yaml.Unmarshal(data, &delta); err != nil {
+		return nil, err
+	}
+
+	templates, err := roleTemplates(topo)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range delta {
+		key, _ := item.Key.(string)
+		tmpl, ok := templates[key]
+		if !ok {
+			continue
+		}
+		insts, ok := item.Value.([]interface{})
+		if !ok {
+			continue
+		}
+		for j, inst := range insts {
+			fields, ok := inst.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+			insts[j] = inheritFields(fields, tmpl)
+		}
+		delta[i].Value = insts
+	}
+	return yaml.Marshal(delta)
+}
+
+// roleTemplates returns the first instance of each role in the topology,
+// which is marshaled as yaml and keyed by the yaml name of the role
+func roleTemplates(topo Topology) (map[string]yaml.MapSlice, error) {
+	templates := make(map[string]yaml.MapSlice)
+
+	v := reflect.Indirect(reflect.ValueOf(topo))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		field := v.Field(i)
+		if key == "" || field.Kind() != reflect.Slice || field.Len() == 0 {
+			continue
+		}
+		first := field.Index(0).Interface()
+		data, err := yaml.Marshal(first)
+		if err != nil {
+			return nil, errors.AddStack(err)
+		}
+		var tmpl yaml.MapSlice
+		if err := yaml.Unmarshal(data, &tmpl); err != nil {
+			return nil, errors.AddStack(err)
+		}
+
+		// skip the dirs derived from the port of the instance, the new
+		// instances derive them from their own ports
+		if inst, ok := first.(InstanceSpec); ok {
+			derived := fmt.Sprintf("/%s-%d/", inst.Role(), inst.GetMainPort())
+			filtered := tmpl[:0]
+			for _, item := range tmpl {
+				name, _ := item.Key.(string)
+				value, _ := item.Value.(string)
+				if dirFields.Exist(name) && strings.Contains(value+"/", derived) {
+					continue
+				}
+				filtered = append(filtered, item)
+			}
+			tmpl = filtered
+		}
+		templates[key] = tmpl
+	}
+	return templates, nil
+}
+
+// ReplacementTopology returns the scale-out topology of the instance which
+// replaces the instance `id` of `topo`, the spec of the instance is copied
+// with the host changed to newHost, the ID of the new instance is returned
+func ReplacementTopology(topo Topology, id, newHost string) ([]byte, string, error) {
+	v := reflect.Indirect(reflect.ValueOf(topo))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		field := v.Field(i)
+		if key == "" || field.Kind() != reflect.Slice {
+			continue
+		}
+		for j := 0; j < field.Len(); j++ {
+			inst, ok := field.Index(j).Interface().(InstanceSpec)
+			if !ok {
+				continue
+			}
+			host := reflect.Indirect(field.Index(j)).FieldByName("Host").String()
+			if fmt.Sprintf("%s:%d", host, inst.GetMainPort()) != id {
+				continue
+			}
+
+			data, err := yaml.Marshal(inst)
+			if err != nil {
+				return nil, "", errors.AddStack(err)
+			}
+			var fields yaml.MapSlice
+			if err := yaml.Unmarshal(data, &fields); err != nil {
+				return nil, "", errors.AddStack(err)
+			}
+			replaced := yaml.MapSlice{{Key: "host", Value: newHost}}
+			replaced = inheritFields(replaced, fields)
+			data, err = yaml.Marshal(yaml.MapSlice{{Key: key, Value: []interface{}{replaced}}})
+			if err != nil {
+				return nil, "", errors.AddStack(err)
+			}
+			return data, fmt.Sprintf("%s:%d", newHost, inst.GetMainPort()), nil
+		}
+	}
+	return nil, "", errors.Errorf("instance %s not found", id)
+}
+
+// inheritFields appends the fields of tmpl not set in fields
+func inheritFields(fields, tmpl yaml.MapSlice) yaml.MapSlice {
+	specified := make(map[interface{}]bool)
+	for _, item := range fields {
+		specified[item.Key] = true
+	}
+	for _, item := range tmpl {
+		name, _ := item.Key.(string)
+		if specified[item.Key] || identityFields[name] {
+			continue
+		}
+		fields = append(fields, item)
+	}
+	return fields
+}
+
+// GlobalConfigChanges returns the global options set in the scale-out
+// topology `data` which are different from the ones of `topo`, formatted as
+// `<path>: <current> -> <new>`
+func GlobalConfigChanges(topo Topology, data []byte) ([]string, error) {
+	var delta map[string]interface{}
+	if err := yaml.Unmarshal(data, &delta); err != nil {
+		return nil, err
+	}
+
+	current, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var origin map[string]interface{}
+	if err := yaml.Unmarshal(current, &origin); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	var changes []string
+	for _, section := range globalSections.Slice() {
+		if _, ok := delta[section]; !ok {
+			continue
+		}
+		newValues := FlattenMap(map[string]interface{}{section: delta[section]})
+		oldValues := FlattenMap(map[string]interface{}{section: origin[section]})
+		for path, v := range newValues {
+			old, ok := oldValues[path]
+			if !ok {
+				if v == nil || reflect.ValueOf(v).IsZero() {
+					continue
+				}
+				changes = append(changes, fmt.Sprintf("%s: (unset) -> %v", path, v))
+				continue
+			}
+			if !sameConfigValue(path, old, v) {
+				changes = append(changes, fmt.Sprintf("%s: %v -> %v", path, old, v))
+			}
+		}
+	}
+	sort.Strings(changes)
+	return changes, nil
+}
+
+// sameConfigValue compares the values of a global config, the relative dirs
+// are expanded to absolute paths in the meta, so they are compared by suffix
+func sameConfigValue(path string, old, v interface{}) bool {
+	oldStr, newStr := fmt.Sprint(old), fmt.Sprint(v)
+	if oldStr == newStr {
+		return true
+	}
+	return strings.HasSuffix(path, "_dir") &&
+		!strings.HasPrefix(newStr, "/") &&
+		strings.HasSuffix(oldStr, "/"+newStr)
+}
+
+// MarshalInstances marshals the instances of the topology as yaml, the
+// global sections are omitted
+func MarshalInstances(topo Topology) ([]byte, error) {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var items yaml.MapSlice
+	if err := yaml.Unmarshal(data, &items); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	instances := items[:0]
+	for _, item := range items {
+		key, _ := item.Key.(string)
+		if globalSections.Exist(key) {
+			continue
+		}
+		if insts, ok := item.Value.([]interface{}); !ok || len(insts) == 0 {
+			continue
+		}
+		instances = append(instances, item)
+	}
+	return yaml.Marshal(instances)
+}
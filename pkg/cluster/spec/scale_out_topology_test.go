@@ -0,0 +1,141 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func scaleOutTestTopo(t *testing.T) *Specification {
+	topo := &Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  user: tidb
+  deploy_dir: /tidb-deploy
+  data_dir: /tidb-data
+monitored:
+  deploy_dir: monitored-9100
+server_configs:
+  tikv:
+    storage.reserve-space: 1GB
+tikv_servers:
+  - host: 172.16.5.1
+    port: 20161
+    data_dir: /nvme/tikv
+    numa_node: "0"
+    config:
+      server.grpc-concurrency: 8
+`), topo)
+	assert.Nil(t, err)
+	ExpandRelativeDir(topo)
+	return topo
+}
+
+func TestInheritInstanceDefaults(t *testing.T) {
+	topo := scaleOutTestTopo(t)
+
+	data, err := InheritInstanceDefaults(topo, []byte(`
+tikv_servers:
+  - host: 172.16.5.2
+  - host: 172.16.5.3
+    port: 20162
+    numa_node: "1"
+`))
+	assert.Nil(t, err)
+	newPart := topo.NewPart().(*Specification)
+	assert.Nil(t, yaml.UnmarshalStrict(data, newPart))
+	assert.Len(t, newPart.TiKVServers, 2)
+
+	kv := newPart.TiKVServers[0]
+	assert.Equal(t, "172.16.5.2", kv.Host)
+	assert.Equal(t, 20161, kv.Port)
+	assert.Equal(t, "/nvme/tikv", kv.DataDir)
+	assert.Equal(t, "0", kv.NumaNode)
+	assert.Equal(t, 8, GetValueFromPath(kv.Config, "server.grpc-concurrency"))
+
+	kv = newPart.TiKVServers[1]
+	assert.Equal(t, "172.16.5.3", kv.Host)
+	assert.Equal(t, 20162, kv.Port)
+	assert.Equal(t, "1", kv.NumaNode)
+	// the deploy dir derived from the port is not inherited
+	assert.Equal(t, "/tidb-deploy/tikv-20162", kv.DeployDir)
+
+	data, err = MarshalInstances(newPart)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "server_configs")
+	assert.Contains(t, string(data), "172.16.5.3")
+}
+
+func TestGlobalConfigChanges(t *testing.T) {
+	topo := scaleOutTestTopo(t)
+
+	changes, err := GlobalConfigChanges(topo, []byte(`
+tikv_servers:
+  - host: 172.16.5.2
+`))
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	// the same as the existing ones
+	changes, err = GlobalConfigChanges(topo, []byte(`
+global:
+  user: tidb
+  deploy_dir: /tidb-deploy
+monitored:
+  deploy_dir: monitored-9100
+server_configs:
+  tikv:
+    storage.reserve-space: 1GB
+`))
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = GlobalConfigChanges(topo, []byte(`
+global:
+  user: admin
+server_configs:
+  tikv:
+    storage.reserve-space: 2GB
+  pd:
+    replication.max-replicas: 5
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"global.user: tidb -> admin",
+		"server_configs.pd.replication.max-replicas: (unset) -> 5",
+		"server_configs.tikv.storage.reserve-space: 1GB -> 2GB",
+	}, changes)
+}
+
+func TestReplacementTopology(t *testing.T) {
+	topo := scaleOutTestTopo(t)
+
+	data, newID, err := ReplacementTopology(topo, "172.16.5.1:20161", "172.16.5.9")
+	assert.Nil(t, err)
+	assert.Equal(t, "172.16.5.9:20161", newID)
+	newPart := topo.NewPart().(*Specification)
+	assert.Nil(t, yaml.UnmarshalStrict(data, newPart))
+	assert.Len(t, newPart.TiKVServers, 1)
+	kv := newPart.TiKVServers[0]
+	assert.Equal(t, "172.16.5.9", kv.Host)
+	assert.Equal(t, 20161, kv.Port)
+	assert.Equal(t, "/nvme/tikv", kv.DataDir)
+	assert.Equal(t, "/tidb-deploy/tikv-20161", kv.DeployDir)
+
+	_, _, err = ReplacementTopology(topo, "172.16.5.1:20160", "172.16.5.9")
+	assert.NotNil(t, err)
+}
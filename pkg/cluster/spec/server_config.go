@@ -22,6 +22,7 @@ import (
 	"path"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	perrs "github.com/pingcap/errors"
@@ -323,13 +324,13 @@ func HandleImportPathMigration(clsName string) error {
 	if os.IsNotExist(err) {
 		zap.L().Warn("renaming config dir", zap.String("orig", dirPath), zap.String("new", targetPath))
 
-		if lckErr := utils.Retry(func() error {
+		if lckErr := utils.Wait(context.Background(), func() error {
 			_, lckErr := os.Stat(path.Join(dirPath, migrateLockName))
 			if os.IsNotExist(lckErr) {
 				return nil
 			}
 			return perrs.Errorf("config dir already lock by another task, %s", lckErr)
-		}); lckErr != nil {
+		}, utils.WaitOption{Timeout: time.Second * 10, Attempts: 20}); lckErr != nil {
 			return lckErr
 		}
 		if lckErr := os.Mkdir(path.Join(dirPath, migrateLockName), 0755); lckErr != nil {
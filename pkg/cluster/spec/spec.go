@@ -31,6 +31,7 @@ import (
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/proxy"
+	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/tidbver"
 	"github.com/pingcap/tiup/pkg/tui"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -89,6 +90,82 @@ type (
 		OS              string               `yaml:"os,omitempty" default:"linux"`
 		Arch            string               `yaml:"arch,omitempty"`
 		Custom          interface{}          `yaml:"custom,omitempty" validate:"custom:ignore"`
+		// APIHeaders are attached to the HTTPS requests to the component APIs
+		// on the hosts of the cluster, the bearer token is read from the
+		// credential store of the cluster
+		APIHeaders map[string]string `yaml:"api_headers,omitempty" validate:"api_headers:ignore"`
+		// DeployUser controls how the deploy user is created and checked on
+		// the hosts on deploy and scale-out
+		DeployUser DeployUserOptions `yaml:"deploy_user,omitempty" validate:"deploy_user:editable"`
+		// HostState is applied to the hosts on deploy and scale-out
+		HostState HostState `yaml:"host_state,omitempty" validate:"host_state:editable"`
+		// Dashboard controls which PD runs TiDB Dashboard
+		Dashboard DashboardOptions `yaml:"dashboard,omitempty" validate:"dashboard:editable"`
+		// EtcHosts controls the entries of the cluster in /etc/hosts of the hosts
+		EtcHosts EtcHostsOptions `yaml:"etc_hosts,omitempty" validate:"etc_hosts:editable"`
+		// ReadOnlyZones are the zones of TiKV learners and TiFlash serving
+		// only reads, the placement rules of them are generated
+		ReadOnlyZones []ReadOnlyZone `yaml:"read_only_zones,omitempty" validate:"read_only_zones:editable"`
+		// TimeZone is rendered into the services of the components, the
+		// system time zone of the hosts is used if it's not specified
+		TimeZone TimeZoneOptions `yaml:"time_zone,omitempty" validate:"time_zone:editable"`
+		// Tags are attached to the records of the instances exported by
+		// export-inventory, e.g. the owner and environment of the cluster
+		Tags map[string]string `yaml:"tags,omitempty" validate:"tags:ignore"`
+	}
+
+	// TimeZoneOptions represents the time zones of the components, e.g. Asia/Shanghai
+	TimeZoneOptions struct {
+		// Default is the time zone of all the components
+		Default string `yaml:"default,omitempty" validate:"default:editable"`
+		// Components overrides the time zones of some components
+		Components map[string]string `yaml:"components,omitempty" validate:"components:editable"`
+	}
+
+	// HostState represents the system settings managed on the hosts, they are
+	// persisted on the hosts and re-verified by check
+	HostState struct {
+		DisableSwap bool `yaml:"disable_swap,omitempty"`
+		Swappiness  *int `yaml:"swappiness,omitempty"`
+		DisableTHP  bool `yaml:"disable_thp,omitempty"`
+	}
+
+	// DeployUserSettings represents the attributes of the deploy user, the
+	// ones not specified are not enforced
+	DeployUserSettings struct {
+		UID int `yaml:"uid,omitempty" validate:"uid:editable"`
+		// GID is the GID of the primary group, i.e. global.group or the
+		// group named after the user
+		GID int `yaml:"gid,omitempty" validate:"gid:editable"`
+		// Groups are the supplementary groups, they are created if missing
+		Groups []string `yaml:"groups,omitempty" validate:"groups:editable"`
+		Shell  string   `yaml:"shell,omitempty" validate:"shell:editable"`
+		// Create is whether the user is created if it doesn't exist, the
+		// user is required to exist if it's false
+		Create *bool `yaml:"create,omitempty" validate:"create:editable"`
+	}
+
+	// DeployUserOptions represents the deploy user on all the hosts, and the
+	// settings overridden on some of them
+	DeployUserOptions struct {
+		DeployUserSettings `yaml:",inline" validate:"settings:editable"`
+		Hosts              map[string]DeployUserSettings `yaml:"hosts,omitempty" validate:"hosts:editable"`
+	}
+
+	// DashboardOptions represents the placement of TiDB Dashboard
+	DashboardOptions struct {
+		// the PD hosts allowed to run TiDB Dashboard, any PD if empty
+		PDHosts []string `yaml:"pd_hosts,omitempty"`
+	}
+
+	// EtcHostsOptions represents the entries of the cluster managed in /etc/hosts,
+	// for the clusters deployed by hostnames without reliable DNS
+	EtcHostsOptions struct {
+		// manage the hostname -> address entries of the cluster on all hosts
+		Manage bool `yaml:"manage,omitempty"`
+		// the addresses of the hostnames, they are resolved on the control
+		// machine if not specified
+		Addresses map[string]string `yaml:"addresses,omitempty"`
 	}
 
 	// MonitoredOptions represents the monitored node configuration
@@ -132,6 +209,7 @@ type (
 		Monitors         []*PrometheusSpec    `yaml:"monitoring_servers"`
 		Grafanas         []*GrafanaSpec       `yaml:"grafana_servers,omitempty"`
 		Alertmanagers    []*AlertmanagerSpec  `yaml:"alertmanager_servers,omitempty"`
+		MonitoredHosts   []*MonitoredHostSpec `yaml:"monitored_hosts,omitempty"`
 	}
 )
 
@@ -176,12 +254,27 @@ type BaseMeta struct {
 	Group   string
 	Version string
 	OpsVer  *string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+
+	// hosts that were unreachable during a previous operation and are waiting to be reconciled
+	QuarantinedHosts *[]string `yaml:"quarantined_hosts,omitempty"`
+
+	// temporary flag overrides of the instances, see RuntimeOverride
+	RuntimeOverrides *[]RuntimeOverride `yaml:"runtime_overrides,omitempty"`
+
+	// the log levels changed temporarily at runtime, see LogLevelChange
+	LogLevelChanges *[]LogLevelChange `yaml:"log_level_changes,omitempty"`
+
+	// the samples of the disk usage of the data dirs, see DiskUsageHistory
+	DiskUsages *[]DiskUsageHistory `yaml:"disk_usages,omitempty"`
+
+	// the restarts of the instances, see RestartHistory
+	Restarts *[]RestartHistory `yaml:"restarts,omitempty"`
 }
 
 // Metadata of a cluster.
 type Metadata interface {
 	GetTopology() Topology
-	SetTopology(topo Topology)
+	SetTopology(topo Topology) error
 	GetBaseMeta() *BaseMeta
 
 	UpgradableMetadata
@@ -193,7 +286,7 @@ type ScaleOutTopology interface {
 	// because some default value rely on the global options and monitored options.
 	// TODO: we should separate the  unmarshal and setting default value.
 	NewPart() Topology
-	MergeTopo(topo Topology) Topology
+	MergeTopo(topo Topology) (Topology, error)
 }
 
 // UpgradableMetadata represents a upgradable Metadata.
@@ -212,13 +305,13 @@ func (s *Specification) NewPart() Topology {
 }
 
 // MergeTopo implements ScaleOutTopology interface.
-func (s *Specification) MergeTopo(topo Topology) Topology {
-	other, ok := topo.(*Specification)
-	if !ok {
-		panic("topo should be Specification")
+func (s *Specification) MergeTopo(topo Topology) (Topology, error) {
+	other, err := AsClusterTopology(topo)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.Merge(other)
+	return s.Merge(other), nil
 }
 
 // GetMonitoredOptions implements Topology interface.
@@ -349,6 +442,7 @@ func (s *Specification) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := fillCustomDefaults(&s.GlobalOptions, s); err != nil {
 		return err
 	}
+	s.fillMonitoredHostDefaults()
 
 	// Rewrite TiFlashSpec.DataDir since we may override it with configurations.
 	// Should do it before validatation because we need to detect dir conflicts.
@@ -370,6 +464,8 @@ func (s *Specification) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	// --initial-commit-ts should not be recorded at run_drainer.sh #1682
 	s.removeCommitTS()
 
+	s.fillReadOnlyZoneLabels()
+
 	return s.Validate()
 }
 
@@ -441,8 +537,8 @@ func (s *Specification) GetDashboardAddress(ctx context.Context, tlsCfg *tls.Con
 		timeout = statusQueryTimeout
 	}
 
-	pc := api.NewPDClient(ctx, pdList, timeout, tlsCfg)
-	dashboardAddr, err := pc.GetDashboardAddress()
+	pc := api.NewPDClient(ctx, pdList, api.RequestTimeout(ctx, timeout), tlsCfg)
+	dashboardAddr, err := pc.GetDashboardAddress(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -453,6 +549,47 @@ func (s *Specification) GetDashboardAddress(ctx context.Context, tlsCfg *tls.Con
 	return dashboardAddr, nil
 }
 
+// DashboardCandidates returns the PD servers allowed to run TiDB Dashboard
+func (s *Specification) DashboardCandidates() []*PDSpec {
+	allowed := set.NewStringSet(s.GlobalOptions.Dashboard.PDHosts...)
+	candidates := make([]*PDSpec, 0, len(s.PDServers))
+	for _, pd := range s.PDServers {
+		if len(allowed) == 0 || allowed.Exist(pd.Host) {
+			candidates = append(candidates, pd)
+		}
+	}
+	return candidates
+}
+
+// EnsureDashboardPlacement moves TiDB Dashboard to an allowed PD if it's
+// running on another one, the address of the PD running it is returned.
+func (s *Specification) EnsureDashboardPlacement(ctx context.Context, tlsCfg *tls.Config, timeout time.Duration, pdList ...string) (string, error) {
+	addr, err := s.GetDashboardAddress(ctx, tlsCfg, timeout, pdList...)
+	if err != nil || len(s.GlobalOptions.Dashboard.PDHosts) == 0 || addr == "none" {
+		return addr, err
+	}
+
+	candidates := s.DashboardCandidates()
+	for _, pd := range candidates {
+		if addr == fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort) {
+			return addr, nil
+		}
+	}
+	if len(candidates) == 0 {
+		return addr, errors.Errorf("no PD is allowed to run TiDB Dashboard")
+	}
+
+	target := fmt.Sprintf("%s:%d", candidates[0].Host, candidates[0].ClientPort)
+	if timeout < time.Second {
+		timeout = statusQueryTimeout
+	}
+	pc := api.NewPDClient(ctx, pdList, api.RequestTimeout(ctx, timeout), tlsCfg)
+	if err := pc.SetDashboardAddress(ctx, api.URL(s.GlobalOptions.TLSEnabled, target)); err != nil {
+		return addr, err
+	}
+	return target, nil
+}
+
 // GetEtcdClient loads EtcdClient of current cluster
 func (s *Specification) GetEtcdClient(tlsCfg *tls.Config) (*clientv3.Client, error) {
 	return clientv3.New(clientv3.Config{
@@ -490,6 +627,7 @@ func (s *Specification) Merge(that Topology) Topology {
 		Monitors:         append(s.Monitors, spec.Monitors...),
 		Grafanas:         append(s.Grafanas, spec.Grafanas...),
 		Alertmanagers:    append(s.Alertmanagers, spec.Alertmanagers...),
+		MonitoredHosts:   append(s.MonitoredHosts, spec.MonitoredHosts...),
 	}
 }
 
@@ -514,10 +652,11 @@ var (
 	serverConfigsTypeName = reflect.TypeOf(ServerConfigs{}).Name()
 )
 
-// Skip global/monitored options
+// Skip global/monitored options and the monitored hosts which are not instances
 func isSkipField(field reflect.Value) bool {
 	tp := field.Type().Name()
-	return tp == globalOptionTypeName || tp == monitorOptionTypeName || tp == serverConfigsTypeName
+	return tp == globalOptionTypeName || tp == monitorOptionTypeName || tp == serverConfigsTypeName ||
+		field.Type() == monitoredHostsType
 }
 
 func setDefaultDir(parent, role, port string, field reflect.Value) {
@@ -931,3 +1070,8 @@ func (s *Specification) removeCommitTS() {
 func (s *Specification) GetGrafanaConfig() map[string]string {
 	return s.ServerConfigs.Grafana
 }
+
+// IsEmpty returns true if no system setting is managed
+func (s HostState) IsEmpty() bool {
+	return !s.DisableSwap && s.Swappiness == nil && !s.DisableTHP
+}
@@ -14,12 +14,16 @@
 package spec
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
+	"sync"
 
+	"github.com/gofrs/flock"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/tui"
 	"github.com/pingcap/tiup/pkg/utils"
@@ -44,6 +48,9 @@ const (
 	PatchDirName = "patch"
 	// BackupDirName is the directory to save backup files.
 	BackupDirName = "backup"
+	// TemplateDirName is the directory of the modified templates taking
+	// precedence over the embedded ones, eg. {TemplateDirName}/scripts/run_cdc.sh.tpl
+	TemplateDirName = "templates"
 	// ScaleOutLockName scale_out snapshot file, like file lock
 	ScaleOutLockName = ".scale-out.yaml"
 )
@@ -54,14 +61,52 @@ const (
 type SpecManager struct {
 	base    string
 	newMeta func() Metadata
+
+	mu sync.Mutex
+	// unlocked is the runtime directories of the encrypted clusters unlocked
+	unlocked map[string]*runtimeDir
+	// operations is the files flocked for the operation locks held
+	operations map[string]*flock.Flock
+
+	// registry records the ports and directories used by the clusters for
+	// the other kinds of clusters, it's nil if not shared
+	registry *HostRegistry
+	// logger warns the failures not failing the operations
+	logger *logprinter.Logger
+
+	// terminated is cancelled on termination once it's watched
+	terminated context.Context
+	terminate  context.CancelFunc
+	watchOnce  sync.Once
 }
 
 // NewSpec create a spec instance.
 func NewSpec(base string, newMeta func() Metadata) *SpecManager {
-	return &SpecManager{
+	s := &SpecManager{
 		base:    base,
 		newMeta: newMeta,
 	}
+	s.terminated, s.terminate = context.WithCancel(context.Background())
+	return s
+}
+
+// SetHostRegistry sets the host registry shared with the other kinds of
+// clusters managed on the control machine.
+func (s *SpecManager) SetHostRegistry(registry *HostRegistry) *SpecManager {
+	s.registry = registry
+	return s
+}
+
+// SetLogger sets the logger to warn the failures not failing the operations,
+// e.g. updating the host registry.
+func (s *SpecManager) SetLogger(logger *logprinter.Logger) *SpecManager {
+	s.logger = logger
+	return s
+}
+
+// HostRegistry returns the shared host registry, nil if not set.
+func (s *SpecManager) HostRegistry() *HostRegistry {
+	return s.registry
 }
 
 // NewMetadata alloc a Metadata according the type.
@@ -71,8 +116,17 @@ func (s *SpecManager) NewMetadata() Metadata {
 
 // Path returns the full path to a subpath (file or directory) of a
 // cluster, it is a subdir in the profile dir of the user, with the cluster name
-// as its name.
+// as its name. The private keys and certificates of an unlocked encrypted
+// cluster are in its runtime directory instead.
 func (s *SpecManager) Path(cluster string, subpath ...string) string {
+	if path, ok := s.unlockedPath(cluster, subpath...); ok {
+		return path
+	}
+	return s.rawPath(cluster, subpath...)
+}
+
+// rawPath returns the path in the storage of the cluster
+func (s *SpecManager) rawPath(cluster string, subpath ...string) string {
 	if cluster == "" {
 		// keep the same behavior with legacy version of TiUP, we could change
 		// it in the future if needed.
@@ -85,6 +139,11 @@ func (s *SpecManager) Path(cluster string, subpath ...string) string {
 	}, subpath...)...)
 }
 
+// MetaPath returns the path of the meta file of the cluster.
+func (s *SpecManager) MetaPath(clusterName string) string {
+	return s.Path(clusterName, metaFileName)
+}
+
 // SaveMeta save the meta with specified cluster name.
 func (s *SpecManager) SaveMeta(clusterName string, meta Metadata) error {
 	wrapError := func(err error) *errorx.Error {
@@ -112,21 +171,40 @@ func (s *SpecManager) SaveMeta(clusterName string, meta Metadata) error {
 		*opsVer = version.NewTiUPVersion().String()
 	}
 
+	data, err = s.sealData(clusterName, data)
+	if err != nil {
+		return wrapError(err)
+	}
+
 	err = utils.SaveFileWithBackup(metaFile, data, backupDir)
 	if err != nil {
 		return wrapError(err)
 	}
 
+	// the registry is synced again on the next conflict check, so the meta
+	// saved is not rolled back for it
+	if err := s.registry.Register(clusterName, meta.GetTopology()); err != nil {
+		s.warnf("Failed to update the host registry of cluster %s, the conflicts with it may not be detected by the other kinds of clusters: %s", clusterName, err)
+	}
+
 	return nil
 }
 
+func (s *SpecManager) warnf(format string, args ...interface{}) {
+	if s.logger == nil {
+		logprinter.Warnf(format, args...)
+		return
+	}
+	s.logger.Warnf(format, args...)
+}
+
 // Metadata tries to read the metadata of a cluster from file
 func (s *SpecManager) Metadata(clusterName string, meta interface{}) error {
 	fname := s.Path(clusterName, metaFileName)
 
-	yamlFile, err := os.ReadFile(fname)
+	yamlFile, err := s.readFile(fname)
 	if err != nil {
-		return perrs.AddStack(err)
+		return err
 	}
 
 	err = yaml.Unmarshal(yamlFile, meta)
@@ -154,6 +232,9 @@ func (s *SpecManager) Exist(clusterName string) (exist bool, err error) {
 
 // Remove remove the data with specified cluster name.
 func (s *SpecManager) Remove(clusterName string) error {
+	if err := s.registry.Unregister(clusterName); err != nil {
+		return err
+	}
 	return os.RemoveAll(s.Path(clusterName))
 }
 
@@ -218,6 +299,16 @@ func (s *SpecManager) ScaleOutLock(clusterName string) (Topology, error) {
 
 	// UnMarshal file lock
 	topo := &Specification{}
+	if s.IsEncrypted(clusterName) {
+		data, err := s.readFile(fname)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, topo); err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		return topo, nil
+	}
 	err := ParseTopologyYaml(fname, topo)
 	if err != nil {
 		return nil, err
@@ -271,6 +362,11 @@ func (s *SpecManager) NewScaleOutLock(clusterName string, topo Topology) error {
 		return wrapError(err)
 	}
 
+	data, err = s.sealData(clusterName, data)
+	if err != nil {
+		return wrapError(err)
+	}
+
 	err = os.WriteFile(lockFile, data, 0644)
 	if err != nil {
 		return wrapError(err)
@@ -18,8 +18,10 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,13 +54,14 @@ func (t *TestTopology) FillHostArchOrOS(hostArchOrOS map[string]string, fullType
 	panic("not support")
 }
 
-func (m *TestMetadata) SetTopology(topo Topology) {
+func (m *TestMetadata) SetTopology(topo Topology) error {
 	testTopo, ok := topo.(*TestTopology)
 	if !ok {
-		panic("wrong toplogy type")
+		return NewTopologyTypeMismatchError("test", topo)
 	}
 
 	m.Topo = testTopo
+	return nil
 }
 
 type TestTopology struct {
@@ -77,7 +80,7 @@ func (t *TestTopology) NewPart() Topology {
 	panic("not support")
 }
 
-func (t *TestTopology) MergeTopo(topo Topology) Topology {
+func (t *TestTopology) MergeTopo(topo Topology) (Topology, error) {
 	panic("not support")
 }
 
@@ -200,3 +203,84 @@ func TestSpec(t *testing.T) {
 	err = spec.Remove("name1")
 	assert.Nil(t, err)
 }
+
+func TestEncryptedSpec(t *testing.T) {
+	dir, err := os.MkdirTemp("", "test-*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	spec := NewSpec(dir, func() Metadata {
+		return new(TestMetadata)
+	})
+	meta1 := &TestMetadata{
+		BaseMeta: BaseMeta{
+			Version: "1.1.1",
+		},
+		Topo: &TestTopology{},
+	}
+	assert.Nil(t, spec.SaveMeta("name1", meta1))
+	assert.Nil(t, os.MkdirAll(spec.Path("name1", "ssh"), 0700))
+	assert.Nil(t, os.WriteFile(spec.Path("name1", "ssh", "id_rsa"), []byte("private key"), 0600))
+
+	// no key specified
+	os.Unsetenv(EnvStorageKeyFile)
+	os.Unsetenv(EnvStoragePassphrase)
+	assert.NotNil(t, spec.EncryptStorage("name1"))
+
+	os.Setenv(EnvStoragePassphrase, "secret")
+	defer os.Unsetenv(EnvStoragePassphrase)
+	assert.Nil(t, spec.EncryptStorage("name1"))
+	assert.True(t, spec.IsEncrypted("name1"))
+
+	data, err := os.ReadFile(spec.Path("name1", metaFileName))
+	assert.Nil(t, err)
+	assert.True(t, IsEncryptedData(data))
+	data, err = os.ReadFile(spec.Path("name1", "ssh", "id_rsa"))
+	assert.Nil(t, err)
+	assert.True(t, IsEncryptedData(data))
+
+	// the meta is decrypted transparently
+	getMeta := new(TestMetadata)
+	assert.Nil(t, spec.Metadata("name1", getMeta))
+	assert.Equal(t, meta1, getMeta)
+
+	// the runtime dirs left by the processes killed are removed
+	stale := filepath.Join(dir, runtimeDirName, "name1-stale")
+	assert.Nil(t, os.MkdirAll(filepath.Join(stale, "ssh"), 0700))
+
+	// the private key is decrypted to the runtime dir when unlocked
+	assert.Nil(t, spec.Unlock("name1"))
+	keyPath := spec.Path("name1", "ssh", "id_rsa")
+	assert.NotEqual(t, filepath.Join(dir, "name1", "ssh", "id_rsa"), keyPath)
+	assert.True(t, strings.HasPrefix(keyPath, filepath.Join(dir, runtimeDirName)))
+	info, err := os.Stat(filepath.Dir(filepath.Dir(keyPath)))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	assert.True(t, utils.IsNotExist(stale))
+	data, err = os.ReadFile(keyPath)
+	assert.Nil(t, err)
+	assert.Equal(t, "private key", string(data))
+
+	// new files are written back encrypted when locked
+	assert.Nil(t, os.WriteFile(spec.Path("name1", "ssh", "id_rsa.pub"), []byte("public key"), 0644))
+	assert.Nil(t, spec.LockAll())
+	assert.Equal(t, filepath.Join(dir, "name1", "ssh", "id_rsa.pub"), spec.Path("name1", "ssh", "id_rsa.pub"))
+	assert.True(t, utils.IsNotExist(keyPath))
+	data, err = os.ReadFile(spec.Path("name1", "ssh", "id_rsa.pub"))
+	assert.Nil(t, err)
+	assert.True(t, IsEncryptedData(data))
+
+	// wrong key
+	os.Setenv(EnvStoragePassphrase, "wrong")
+	assert.NotNil(t, spec.Metadata("name1", getMeta))
+
+	os.Setenv(EnvStoragePassphrase, "secret")
+	assert.Nil(t, spec.DecryptStorage("name1"))
+	assert.False(t, spec.IsEncrypted("name1"))
+	data, err = os.ReadFile(spec.Path("name1", "ssh", "id_rsa.pub"))
+	assert.Nil(t, err)
+	assert.Equal(t, "public key", string(data))
+	getMeta = new(TestMetadata)
+	assert.Nil(t, spec.Metadata("name1", getMeta))
+	assert.Equal(t, meta1, getMeta)
+}
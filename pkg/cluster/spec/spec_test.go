@@ -16,6 +16,7 @@ package spec
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -304,6 +305,63 @@ monitored:
 	c.Assert(topo.MonitoredOptions.LogDir, Equals, "test-deploy/log")
 }
 
+func (s *metaSuiteTopo) TestMonitoredHosts(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  ssh_port: 220
+tidb_servers:
+  - host: 172.16.5.138
+monitored_hosts:
+  - host: 172.16.5.10
+  - host: 172.16.5.11
+    ssh_port: 22
+    arch: x86_64
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(len(topo.MonitoredHosts), Equals, 2)
+	c.Assert(topo.MonitoredHosts[0].SSHPort, Equals, 220)
+	c.Assert(topo.MonitoredHosts[1].SSHPort, Equals, 22)
+	c.Assert(topo.MonitoredHosts[1].Arch, Equals, "amd64")
+	c.Assert(MonitoredHosts(&topo), DeepEquals, topo.MonitoredHosts)
+
+	// the monitored hosts are not instances of the cluster
+	hosts := []string{}
+	topo.IterInstance(func(inst Instance) {
+		hosts = append(hosts, inst.GetHost())
+	})
+	c.Assert(hosts, DeepEquals, []string{"172.16.5.138"})
+
+	// the monitored hosts are merged in scale-out
+	scale := Specification{}
+	err = yaml.Unmarshal([]byte(`
+monitored_hosts:
+  - host: 172.16.5.12
+`), &scale)
+	c.Assert(err, IsNil)
+	merged := topo.Merge(&scale).(*Specification)
+	c.Assert(len(merged.MonitoredHosts), Equals, 3)
+	c.Assert(merged.MonitoredHosts[2].Host, Equals, "172.16.5.12")
+
+	// duplicated monitored hosts
+	err = yaml.Unmarshal([]byte(`
+monitored_hosts:
+  - host: 172.16.5.10
+  - host: 172.16.5.10
+`), &Specification{})
+	c.Assert(err, NotNil)
+
+	// the hosts of the cluster are monitored anyway
+	err = yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.138
+monitored_hosts:
+  - host: 172.16.5.138
+`), &Specification{})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*already a host of the cluster.*")
+}
+
 func (s *metaSuiteTopo) TestMerge2Toml(c *C) {
 	topo := Specification{}
 	err := yaml.Unmarshal([]byte(`
@@ -854,3 +912,210 @@ tidb_servers:
 	c.Assert(err, NotNil)
 	c.Assert(strings.Contains(err.Error(), "not found"), IsTrue)
 }
+
+func (s *metaSuiteTopo) TestHostState(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  user: "test1"
+  host_state:
+    disable_swap: true
+    swappiness: 0
+tikv_servers:
+  - host: 172.16.5.138
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.GlobalOptions.HostState.IsEmpty(), IsFalse)
+	c.Assert(topo.GlobalOptions.HostState.DisableSwap, IsTrue)
+	c.Assert(topo.GlobalOptions.HostState.DisableTHP, IsFalse)
+	c.Assert(*topo.GlobalOptions.HostState.Swappiness, Equals, 0)
+
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.138
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.GlobalOptions.HostState.IsEmpty(), IsTrue)
+}
+
+func (s *metaSuiteTopo) TestEtcHostsEntries(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  etc_hosts:
+    manage: true
+    addresses:
+      tidb-1: 10.0.1.1
+tidb_servers:
+  - host: tidb-1
+  - host: 10.0.1.2
+pd_servers:
+  - host: pd-1
+monitored_hosts:
+  - host: haproxy-1
+`), &topo)
+	c.Assert(err, IsNil)
+
+	defer func(fn func(string) ([]string, error)) { lookupHost = fn }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		switch host {
+		case "pd-1":
+			return []string{"10.0.1.4", "10.0.1.3"}, nil
+		case "haproxy-1":
+			return []string{"10.0.1.5"}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+
+	entries, err := EtcHostsEntries(&topo)
+	c.Assert(err, IsNil)
+	c.Assert(entries, DeepEquals, map[string]string{
+		"tidb-1":    "10.0.1.1",
+		"pd-1":      "10.0.1.3",
+		"haproxy-1": "10.0.1.5",
+	})
+
+	// the removed nodes are excluded
+	entries, err = EtcHostsEntries(&topo, "pd-1:2379")
+	c.Assert(err, IsNil)
+	c.Assert(entries, DeepEquals, map[string]string{
+		"tidb-1":    "10.0.1.1",
+		"haproxy-1": "10.0.1.5",
+	})
+
+	begin, end, block := EtcHostsBlock("test", entries)
+	c.Assert(begin, Equals, "# BEGIN tiup cluster test")
+	c.Assert(end, Equals, "# END tiup cluster test")
+	c.Assert(block, Equals, "# BEGIN tiup cluster test\n10.0.1.5 haproxy-1\n10.0.1.1 tidb-1\n# END tiup cluster test\n")
+
+	// the hostnames must be resolvable
+	topo.PDServers[0].Host = "pd-2"
+	_, err = EtcHostsEntries(&topo)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*pd-2.*")
+
+	// not managed
+	topo.GlobalOptions.EtcHosts.Manage = false
+	entries, err = EtcHostsEntries(&topo)
+	c.Assert(err, IsNil)
+	c.Assert(entries, IsNil)
+
+	err = yaml.Unmarshal([]byte(`
+global:
+  etc_hosts:
+    addresses:
+      tidb-1: not-an-ip
+tidb_servers:
+  - host: tidb-1
+`), &Specification{})
+	c.Assert(err, NotNil)
+}
+
+func (s *metaSuiteTopo) TestDeployUserSettings(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  user: tidb
+  deploy_user:
+    uid: 1500
+    gid: 1500
+    groups: [wheel]
+    hosts:
+      172.16.5.2:
+        uid: 1600
+        shell: /bin/sh
+        create: false
+tidb_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+`), &topo)
+	c.Assert(err, IsNil)
+
+	settings := topo.GlobalOptions.DeployUser.ForHost("172.16.5.1")
+	c.Assert(settings.UID, Equals, 1500)
+	c.Assert(settings.GID, Equals, 1500)
+	c.Assert(settings.Groups, DeepEquals, []string{"wheel"})
+	c.Assert(settings.CreateUser(), IsTrue)
+
+	settings = topo.GlobalOptions.DeployUser.ForHost("172.16.5.2")
+	c.Assert(settings.UID, Equals, 1600)
+	c.Assert(settings.GID, Equals, 1500)
+	c.Assert(settings.Shell, Equals, "/bin/sh")
+	c.Assert(settings.CreateUser(), IsFalse)
+
+	for _, invalid := range []string{
+		"uid: -1",
+		"groups: [Wheel]",
+		"shell: bash",
+		"hosts: { 172.16.5.2: { gid: -1 } }",
+	} {
+		err = yaml.Unmarshal([]byte(fmt.Sprintf(`
+global:
+  deploy_user: { %s }
+tidb_servers:
+  - host: 172.16.5.1
+`, invalid)), &Specification{})
+		c.Assert(err, NotNil, Commentf("%s", invalid))
+	}
+}
+
+func (s *metaSuiteTopo) TestTimeZones(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  time_zone:
+    default: Asia/Shanghai
+    components:
+      tikv: UTC
+tidb_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+cdc_servers:
+  - host: 172.16.5.2
+    tz: America/Argentina/Buenos_Aires
+  - host: 172.16.5.3
+    tz: System
+`), &topo)
+	c.Assert(err, IsNil)
+
+	c.Assert(topo.TimeZones("172.16.5.1"), DeepEquals, []string{"Asia/Shanghai", "UTC"})
+	c.Assert(topo.TimeZones("172.16.5.2"), DeepEquals, []string{"America/Argentina/Buenos_Aires", "UTC"})
+	c.Assert(topo.TimeZones("172.16.5.3"), HasLen, 0)
+	c.Assert(topo.MixedTimeZones(), DeepEquals, map[string][]string{
+		"Asia/Shanghai":                  {"172.16.5.1:4000"},
+		"UTC":                            {"172.16.5.1:20160", "172.16.5.2:20160"},
+		"America/Argentina/Buenos_Aires": {"172.16.5.2:8300"},
+		"":                               {"172.16.5.3:8300"},
+	})
+
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+global:
+  time_zone:
+    default: Etc/GMT+8
+tidb_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.MixedTimeZones(), IsNil)
+
+	for _, invalid := range []string{
+		"default: Asia/Shang hai",
+		"default: /etc/localtime",
+		"components: { tikv: ../UTC }",
+		"components: { unknown: UTC }",
+	} {
+		err = yaml.Unmarshal([]byte(fmt.Sprintf(`
+global:
+  time_zone: { %s }
+tidb_servers:
+  - host: 172.16.5.1
+`, invalid)), &Specification{})
+		c.Assert(err, NotNil, Commentf("%s", invalid))
+	}
+}
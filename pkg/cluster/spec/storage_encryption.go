@@ -0,0 +1,522 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofrs/flock"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// EnvStorageKeyFile is the environment variable of the key file used to
+	// encrypt the storage directory of clusters
+	EnvStorageKeyFile = "TIUP_CLUSTER_STORAGE_KEY_FILE"
+	// EnvStoragePassphrase is the environment variable of the passphrase used
+	// to encrypt the storage directory of clusters, the key file takes
+	// precedence over it if both are set
+	EnvStoragePassphrase = "TIUP_CLUSTER_STORAGE_PASSPHRASE"
+	// encryptedMarkName is the file marking the storage of a cluster encrypted
+	encryptedMarkName = ".encrypted"
+	// runtimeDirName is the directory in the profile holding the decrypted
+	// files of the unlocked clusters, e.g. {runtimeDirName}/{cluster}-{random}
+	runtimeDirName = ".runtime"
+
+	storageSaltSize = 16
+	storageKeySize  = 32
+)
+
+// storageMagic is the header of the encrypted files
+var storageMagic = []byte("TIUP-ENCRYPTED-V1\n")
+
+var (
+	// ErrStorageKeyMissing means neither the key file nor the passphrase is set
+	ErrStorageKeyMissing = errNS.NewType("storage_key_missing")
+	// ErrStorageDecryptFailed means the encrypted file can not be decrypted
+	ErrStorageDecryptFailed = errNS.NewType("storage_decrypt_failed")
+)
+
+// encryptedEntries are the files and directories in the storage of a cluster
+// encrypted at rest, the other ones (e.g. patches and templates) are kept as is.
+var encryptedEntries = []string{metaFileName, ScaleOutLockName, BackupDirName, "ssh", TLSCertKeyDir, CredentialsDir}
+
+// unlockedEntries are the directories decrypted to a runtime directory while
+// the cluster is being operated, as they are read by path (e.g. by the ssh
+// client) instead of through the SpecManager.
+var unlockedEntries = []string{"ssh", TLSCertKeyDir}
+
+// storageSecret reads the secret to encrypt the storage from the environment
+func storageSecret() ([]byte, error) {
+	if fname := os.Getenv(EnvStorageKeyFile); fname != "" {
+		data, err := os.ReadFile(fname)
+		if err != nil {
+			return nil, perrs.Annotatef(err, "failed to read storage key file %s", fname)
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			return nil, perrs.Errorf("storage key file %s is empty", fname)
+		}
+		return data, nil
+	}
+	if passphrase := os.Getenv(EnvStoragePassphrase); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	return nil, ErrStorageKeyMissing.New("The storage of the cluster is encrypted, but no key is specified").
+		WithProperty(tui.SuggestionFromFormat(
+			"Please set the key file with %s or the passphrase with %s and try again.",
+			EnvStorageKeyFile, EnvStoragePassphrase,
+		))
+}
+
+func storageCipher(secret, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(secret, salt, 1<<15, 8, 1, storageKeySize)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncryptedData checks if the data is encrypted by EncryptData
+func IsEncryptedData(data []byte) bool {
+	return bytes.HasPrefix(data, storageMagic)
+}
+
+// EncryptData encrypts the data with AES-256-GCM, the key is derived from the
+// secret with scrypt and a random salt, which is stored with the nonce in the
+// header of the output.
+func EncryptData(secret, data []byte) ([]byte, error) {
+	salt := make([]byte, storageSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	aead, err := storageCipher(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+
+	out := make([]byte, 0, len(storageMagic)+len(salt)+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, storageMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, storageMagic), nil
+}
+
+// DecryptData decrypts the data encrypted by EncryptData, the data not
+// encrypted is returned as is.
+func DecryptData(secret, data []byte) ([]byte, error) {
+	if !IsEncryptedData(data) {
+		return data, nil
+	}
+	data = data[len(storageMagic):]
+	if len(data) < storageSaltSize {
+		return nil, ErrStorageDecryptFailed.New("The encrypted data is truncated")
+	}
+	aead, err := storageCipher(secret, data[:storageSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[storageSaltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrStorageDecryptFailed.New("The encrypted data is truncated")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], storageMagic)
+	if err != nil {
+		return nil, ErrStorageDecryptFailed.Wrap(err, "Failed to decrypt the data").
+			WithProperty(tui.SuggestionFromFormat(
+				"Please check the key file in %s or the passphrase in %s is the one used to encrypt the cluster.",
+				EnvStorageKeyFile, EnvStoragePassphrase,
+			))
+	}
+	return plain, nil
+}
+
+// IsEncrypted checks if the storage of the cluster is encrypted
+func (s *SpecManager) IsEncrypted(clusterName string) bool {
+	return utils.IsExist(filepath.Join(s.Path(clusterName), encryptedMarkName))
+}
+
+// readFile reads a file in the storage of the cluster, the file is decrypted
+// transparently if it's encrypted
+func (s *SpecManager) readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	if !IsEncryptedData(data) {
+		return data, nil
+	}
+	secret, err := storageSecret()
+	if err != nil {
+		return nil, err
+	}
+	return DecryptData(secret, data)
+}
+
+// sealData encrypts the data to be written to the storage of the cluster if
+// the storage is encrypted
+func (s *SpecManager) sealData(clusterName string, data []byte) ([]byte, error) {
+	if !s.IsEncrypted(clusterName) {
+		return data, nil
+	}
+	secret, err := storageSecret()
+	if err != nil {
+		return nil, err
+	}
+	return EncryptData(secret, data)
+}
+
+// walkEncryptedEntries calls fn on every regular file in the encrypted entries
+// of the storage of the cluster
+func (s *SpecManager) walkEncryptedEntries(clusterName string, fn func(path string, mode fs.FileMode) error) error {
+	for _, entry := range encryptedEntries {
+		root := filepath.Join(s.rawPath(clusterName), entry)
+		if utils.IsNotExist(root) {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return fn(path, info.Mode().Perm())
+		})
+		if err != nil {
+			return perrs.AddStack(err)
+		}
+	}
+	return nil
+}
+
+// EncryptStorage encrypts the topology, private keys and certificates in the
+// storage of the cluster with the key from the environment
+func (s *SpecManager) EncryptStorage(clusterName string) error {
+	secret, err := storageSecret()
+	if err != nil {
+		return err
+	}
+
+	// mark it first, so that the files already encrypted are decrypted
+	// transparently even if it's interrupted
+	if err := os.WriteFile(filepath.Join(s.Path(clusterName), encryptedMarkName), nil, 0600); err != nil {
+		return perrs.AddStack(err)
+	}
+	return s.walkEncryptedEntries(clusterName, func(path string, mode fs.FileMode) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if IsEncryptedData(data) {
+			return nil
+		}
+		if data, err = EncryptData(secret, data); err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, mode)
+	})
+}
+
+// DecryptStorage decrypts the storage of the cluster encrypted by EncryptStorage
+func (s *SpecManager) DecryptStorage(clusterName string) error {
+	secret, err := storageSecret()
+	if err != nil {
+		return err
+	}
+
+	err = s.walkEncryptedEntries(clusterName, func(path string, mode fs.FileMode) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !IsEncryptedData(data) {
+			return nil
+		}
+		if data, err = DecryptData(secret, data); err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, mode)
+	})
+	if err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(s.Path(clusterName), encryptedMarkName))
+}
+
+// runtimeDir is the directory holding the decrypted files of an unlocked
+// cluster, it's flocked by the process using it, so that the ones left by the
+// processes killed could be told and removed
+type runtimeDir struct {
+	path string
+	lock *flock.Flock
+}
+
+func newRuntimeDir(path string) *runtimeDir {
+	return &runtimeDir{path: path, lock: flock.New(path + ".lock")}
+}
+
+// remove removes the directory and releases the lock
+func (d *runtimeDir) remove() {
+	_ = os.RemoveAll(d.path)
+	_ = os.Remove(d.lock.Path())
+	_ = d.lock.Unlock()
+}
+
+// removeStaleRuntimeDirs removes the runtime directories not used by any
+// process, which are left by the processes killed before locking the clusters
+func removeStaleRuntimeDirs(parent string) {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		d := newRuntimeDir(filepath.Join(parent, e.Name()))
+		if locked, err := d.lock.TryLock(); err == nil && locked {
+			d.remove()
+		}
+	}
+}
+
+// Unlock decrypts the private keys and certificates of an encrypted cluster
+// to a runtime directory in the profile only accessible by the current user,
+// Path returns the files in it until the cluster is locked again. It's a no-op
+// if the storage of the cluster is not encrypted.
+func (s *SpecManager) Unlock(clusterName string) (err error) {
+	if !s.IsEncrypted(clusterName) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.unlocked[clusterName]; ok {
+		return nil
+	}
+
+	secret, err := storageSecret()
+	if err != nil {
+		return err
+	}
+	parent := filepath.Join(s.base, runtimeDirName)
+	if err := os.MkdirAll(parent, 0700); err != nil {
+		return perrs.AddStack(err)
+	}
+	removeStaleRuntimeDirs(parent)
+	path, err := os.MkdirTemp(parent, clusterName+"-")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	runtime := newRuntimeDir(path)
+	defer func() {
+		if err != nil {
+			runtime.remove()
+		}
+	}()
+	if err := runtime.lock.Lock(); err != nil {
+		return perrs.AddStack(err)
+	}
+
+	base := s.rawPath(clusterName)
+	for _, entry := range unlockedEntries {
+		root := filepath.Join(base, entry)
+		if utils.IsNotExist(root) {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(runtime.path, rel)
+			if d.IsDir() {
+				return os.MkdirAll(target, 0700)
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if data, err = DecryptData(secret, data); err != nil {
+				return err
+			}
+			return os.WriteFile(target, data, info.Mode().Perm())
+		})
+		if err != nil {
+			return perrs.AddStack(err)
+		}
+	}
+
+	if s.unlocked == nil {
+		s.unlocked = make(map[string]*runtimeDir)
+	}
+	s.unlocked[clusterName] = runtime
+	// stop the command instead of exiting on termination, so the decrypted
+	// files are removed by LockAll after it returns
+	s.WatchTermination()
+	return nil
+}
+
+// Lock writes the changes in the runtime directory of the cluster (e.g. the
+// certificates generated for new instances) back to the storage encrypted and
+// removes the runtime directory.
+func (s *SpecManager) Lock(clusterName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runtime, ok := s.unlocked[clusterName]
+	if !ok {
+		return nil
+	}
+	delete(s.unlocked, clusterName)
+	defer runtime.remove()
+	runtimeDir := runtime.path
+
+	base := s.rawPath(clusterName)
+	// the cluster is destroyed, or its storage decrypted, while operating it
+	if utils.IsNotExist(filepath.Join(base, encryptedMarkName)) {
+		return nil
+	}
+
+	secret, err := storageSecret()
+	if err != nil {
+		return err
+	}
+
+	// remove the files removed in the runtime directory
+	err = s.walkEncryptedEntries(clusterName, func(path string, mode fs.FileMode) error {
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		if !isUnlockedEntry(rel) || utils.IsExist(filepath.Join(runtimeDir, rel)) {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(runtimeDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(runtimeDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(base, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// skip the files not changed, to avoid rewriting all of them each time
+		if old, err := os.ReadFile(target); err == nil {
+			if plain, err := DecryptData(secret, old); err == nil && bytes.Equal(plain, data) {
+				return nil
+			}
+		}
+		if data, err = EncryptData(secret, data); err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+	return perrs.AddStack(err)
+}
+
+// LockAll locks all the clusters unlocked, it should be called before exiting
+func (s *SpecManager) LockAll() error {
+	s.mu.Lock()
+	clusters := make([]string, 0, len(s.unlocked))
+	for name := range s.unlocked {
+		clusters = append(clusters, name)
+	}
+	s.mu.Unlock()
+
+	for _, name := range clusters {
+		if err := s.Lock(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlockedPath returns the path in the runtime directory if the cluster is
+// unlocked and the subpath is one of the unlocked entries
+func (s *SpecManager) unlockedPath(cluster string, subpath ...string) (string, bool) {
+	if len(subpath) == 0 || !isUnlockedEntry(subpath[0]) {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runtime, ok := s.unlocked[cluster]
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(append([]string{runtime.path}, subpath...)...), true
+}
+
+func isUnlockedEntry(path string) bool {
+	entry := strings.SplitN(filepath.ToSlash(filepath.Clean(path)), "/", 2)[0]
+	for _, e := range unlockedEntries {
+		if entry == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchTermination handles SIGHUP and SIGTERM by cancelling the context
+// returned by Context instead of killing the process, so the command could
+// stop and clean up as if it failed, e.g. lock the encrypted clusters again,
+// revert the temporary changes and release the operation lock. It's only
+// watched once, and the process is killed by the second signal.
+func (s *SpecManager) WatchTermination() {
+	s.watchOnce.Do(func() {
+		sc := make(chan os.Signal, 1)
+		signal.Notify(sc, syscall.SIGHUP, syscall.SIGTERM)
+		go func() {
+			sig := <-sc
+			signal.Stop(sc)
+			s.warnf("Got signal %s, stopping the operation", sig)
+			s.terminate()
+		}()
+	})
+}
+
+// Context returns the context cancelled when the process receives SIGHUP or
+// SIGTERM if the termination is watched, see WatchTermination
+func (s *SpecManager) Context() context.Context {
+	return s.terminated
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/tidbver"
@@ -148,7 +149,7 @@ func (i *TiDBInstance) InitConfig(
 		WithAdvertiseAddr(spec.Host).
 		SupportSecureBootstrap(tidbver.TiDBSupportSecureBoot(clusterVersion))
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tidb_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 
@@ -247,14 +248,10 @@ func (i *TiDBInstance) ScaleConfig(
 ) error {
 	s := i.topo
 	defer func() { i.topo = s }()
-	i.topo = mustBeClusterTopo(topo)
-	return i.InitConfig(ctx, e, clusterName, clusterVersion, deployUser, paths)
-}
-
-func mustBeClusterTopo(topo Topology) *Specification {
-	spec, ok := topo.(*Specification)
-	if !ok {
-		panic("must be cluster spec")
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
 	}
-	return spec
+	i.topo = cluster
+	return i.InitConfig(ctx, e, clusterName, clusterVersion, deployUser, paths)
 }
@@ -31,6 +31,7 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/set"
@@ -655,7 +656,7 @@ func (i *TiFlashInstance) InitConfig(
 		AppendEndpoints(topo.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tiflash_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_tiflash.sh")
@@ -753,7 +754,11 @@ func (i *TiFlashInstance) ScaleConfig(
 	defer func() {
 		i.topo = s
 	}()
-	i.topo = mustBeClusterTopo(topo)
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
+	}
+	i.topo = cluster
 	return i.InitConfig(ctx, e, clusterName, clusterVersion, deployUser, paths)
 }
 
@@ -792,8 +797,8 @@ func (i *TiFlashInstance) PrepareStart(ctx context.Context, tlsCfg *tls.Config)
 	}
 
 	endpoints := i.getEndpoints(topo)
-	pdClient := api.NewPDClient(ctx, endpoints, 10*time.Second, tlsCfg)
-	return pdClient.UpdateReplicateConfig(bytes.NewBuffer(enablePlacementRules))
+	pdClient := api.NewPDClient(ctx, endpoints, api.RequestTimeout(ctx, 10*time.Second), tlsCfg)
+	return pdClient.UpdateReplicateConfig(ctx, bytes.NewBuffer(enablePlacementRules))
 }
 
 // Ready implements Instance interface
@@ -804,23 +809,20 @@ func (i *TiFlashInstance) Ready(ctx context.Context, e ctxt.Executor, timeout ui
 		return err
 	}
 
-	scheme := "http"
-	if i.topo.BaseTopo().GlobalOptions.TLSEnabled {
-		scheme = "https"
-	}
-	addr := fmt.Sprintf("%s://%s:%d/tiflash/store-status", scheme, i.Host, i.GetStatusPort())
+	addr := fmt.Sprintf("%s://%s:%d/tiflash/store-status", api.Scheme(i.topo.BaseTopo().GlobalOptions.TLSEnabled), i.Host, i.GetStatusPort())
 	req, err := http.NewRequest("GET", addr, nil)
 	if err != nil {
 		return err
 	}
 	req = req.WithContext(ctx)
 
-	retryOpt := utils.RetryOption{
-		Delay:   time.Second,
-		Timeout: time.Second * time.Duration(timeout),
+	waitOpt := utils.WaitOption{
+		Timeout:     time.Second * time.Duration(timeout),
+		Interval:    time.Second,
+		MaxInterval: time.Second * 5,
 	}
 	var queryErr error
-	if err := utils.Retry(func() error {
+	if err := utils.Wait(ctx, func() error {
 		client := utils.NewHTTPClient(statusQueryTimeout, tlsCfg)
 		res, err := client.Client().Do(req)
 		if err != nil {
@@ -840,7 +842,7 @@ func (i *TiFlashInstance) Ready(ctx context.Context, e ctxt.Executor, timeout ui
 		err = fmt.Errorf("tiflash store status is '%s', not fully running yet", string(body))
 		queryErr = err
 		return err
-	}, retryOpt); err != nil {
+	}, waitOpt); err != nil {
 		return errors.Annotatef(queryErr, "timed out waiting for tiflash %s:%d to be ready after %ds",
 			i.Host, i.Port, timeout)
 	}
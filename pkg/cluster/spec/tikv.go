@@ -29,6 +29,7 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/meta"
@@ -71,8 +72,8 @@ func checkStoreStatus(ctx context.Context, storeAddr string, tlsCfg *tls.Config,
 	if len(pdList) < 1 {
 		return "N/A"
 	}
-	pdapi := api.NewPDClient(ctx, pdList, statusQueryTimeout, tlsCfg)
-	store, err := pdapi.GetCurrentStore(storeAddr)
+	pdapi := api.NewPDClient(ctx, pdList, api.RequestTimeout(ctx, statusQueryTimeout), tlsCfg)
+	store, err := pdapi.GetCurrentStore(ctx, storeAddr)
 	if err != nil {
 		if errors.Is(err, api.ErrNoStore) {
 			return "N/A"
@@ -222,7 +223,7 @@ func (i *TiKVInstance) InitConfig(
 		WithAdvertiseStatusAddr(spec.AdvertiseStatusAddr)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tikv_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_tikv.sh")
@@ -323,65 +324,95 @@ func (i *TiKVInstance) ScaleConfig(
 	defer func() {
 		i.topo = s
 	}()
-	i.topo = mustBeClusterTopo(topo)
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
+	}
+	i.topo = cluster
 	return i.InitConfig(ctx, e, clusterName, clusterVersion, deployUser, paths)
 }
 
 var _ RollingUpdateInstance = &TiKVInstance{}
 
-// PreRestart implements RollingUpdateInstance interface.
-func (i *TiKVInstance) PreRestart(ctx context.Context, topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) error {
-	timeoutOpt := &utils.RetryOption{
-		Timeout: time.Second * time.Duration(apiTimeoutSeconds),
-		Delay:   time.Second * 2,
+// API implements RollingUpdateInstance interface.
+func (i *TiKVInstance) API(topo Topology, apiTimeoutSeconds int, tlsCfg *tls.Config) (ComponentAPI, error) {
+	tidbTopo, err := AsClusterTopology(topo)
+	if err != nil {
+		return nil, err
 	}
+	return &tikvAPI{
+		ins:               i,
+		topo:              tidbTopo,
+		apiTimeoutSeconds: apiTimeoutSeconds,
+		tlsCfg:            tlsCfg,
+	}, nil
+}
+
+// tikvAPI evicts the store leaders before restarting a TiKV instance
+type tikvAPI struct {
+	NopComponentAPI
+	ins               *TiKVInstance
+	topo              *Specification
+	apiTimeoutSeconds int
+	tlsCfg            *tls.Config
+}
 
-	tidbTopo, ok := topo.(*Specification)
-	if !ok {
-		panic("should be type of tidb topology")
+// RestartHooks implements RestartHookDescriber interface.
+func (a *tikvAPI) RestartHooks() []RestartHook {
+	if len(a.topo.TiKVServers) <= 1 {
+		return nil
+	}
+	return []RestartHook{
+		{Name: RestartHookResignLeader, Action: "evict the store leaders", Timeout: time.Second * time.Duration(a.apiTimeoutSeconds)},
+		{Name: RestartHookReady, Action: "remove the evict leader scheduler"},
+	}
+}
+
+func (a *tikvAPI) pdClient(ctx context.Context) *api.PDClient {
+	return api.NewPDClient(ctx, a.topo.GetPDList(), api.RequestTimeout(ctx, 5*time.Second), a.tlsCfg)
+}
+
+// ResignLeader implements ComponentAPI interface.
+func (a *tikvAPI) ResignLeader(ctx context.Context) error {
+	timeoutOpt := &utils.RetryOption{
+		Timeout: time.Second * time.Duration(a.apiTimeoutSeconds),
+		Delay:   time.Second * 2,
 	}
 
-	if len(tidbTopo.TiKVServers) <= 1 {
+	if len(a.topo.TiKVServers) <= 1 {
 		return nil
 	}
 
-	pdClient := api.NewPDClient(ctx, tidbTopo.GetPDList(), 5*time.Second, tlsCfg)
+	pdClient := a.pdClient(ctx)
 
 	// Make sure there's leader of PD.
 	// Although we evict pd leader when restart pd,
 	// But when there's only one PD instance the pd might not serve request right away after restart.
-	err := pdClient.WaitLeader(timeoutOpt)
+	err := pdClient.WaitLeader(ctx, timeoutOpt)
 	if err != nil {
 		return err
 	}
 
-	if err := pdClient.EvictStoreLeader(addr(i.InstanceSpec.(*TiKVSpec)), timeoutOpt, genLeaderCounter(tidbTopo, tlsCfg)); err != nil {
+	if err := pdClient.EvictStoreLeader(ctx, addr(a.ins.InstanceSpec.(*TiKVSpec)), timeoutOpt, genLeaderCounter(a.topo, a.tlsCfg)); err != nil {
 		if utils.IsTimeoutOrMaxRetry(err) {
 			ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger).
-				Warnf("Ignore evicting store leader from %s, %v", i.ID(), err)
+				Warnf("Ignore evicting store leader from %s, %v", a.ins.ID(), err)
 		} else {
-			return perrs.Annotatef(err, "failed to evict store leader %s", i.GetHost())
+			return perrs.Annotatef(err, "failed to evict store leader %s", a.ins.GetHost())
 		}
 	}
 	return nil
 }
 
-// PostRestart implements RollingUpdateInstance interface.
-func (i *TiKVInstance) PostRestart(ctx context.Context, topo Topology, tlsCfg *tls.Config) error {
-	tidbTopo, ok := topo.(*Specification)
-	if !ok {
-		panic("should be type of tidb topology")
-	}
-
-	if len(tidbTopo.TiKVServers) <= 1 {
+// Ready implements ComponentAPI interface.
+func (a *tikvAPI) Ready(ctx context.Context) error {
+	if len(a.topo.TiKVServers) <= 1 {
 		return nil
 	}
 
-	pdClient := api.NewPDClient(ctx, tidbTopo.GetPDList(), 5*time.Second, tlsCfg)
-
 	// remove store leader evict scheduler after restart
-	if err := pdClient.RemoveStoreEvict(addr(i.InstanceSpec.(*TiKVSpec))); err != nil {
-		return perrs.Annotatef(err, "failed to remove evict store scheduler for %s", i.GetHost())
+	if err := a.pdClient(ctx).RemoveStoreEvict(ctx, addr(a.ins.InstanceSpec.(*TiKVSpec))); err != nil {
+		return perrs.Annotatef(err, "failed to remove evict store scheduler for %s", a.ins.GetHost())
 	}
 
 	return nil
@@ -399,6 +430,7 @@ func addr(spec *TiKVSpec) string {
 }
 
 func genLeaderCounter(topo *Specification, tlsCfg *tls.Config) func(string) (int, error) {
+	tlsCfg = NewEndpointResolver(tlsCfg).TLSConfig(ComponentTiKV)
 	return func(id string) (int, error) {
 		statusAddress := ""
 		foundIds := []string{}
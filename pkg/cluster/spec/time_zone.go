@@ -0,0 +1,128 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// TimeZoneDataDir is where the time zones are looked up on the hosts
+const TimeZoneDataDir = "/usr/share/zoneinfo"
+
+// the names in the tz database, e.g. UTC, Asia/Shanghai, America/Argentina/Buenos_Aires, Etc/GMT+8
+var reTimeZone = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+\-]*(/[A-Za-z0-9_+\-]+)*$`)
+
+// ForComponent returns the time zone of the component, empty if it's not
+// specified
+func (o *TimeZoneOptions) ForComponent(comp string) string {
+	if tz, ok := o.Components[comp]; ok && tz != "" {
+		return tz
+	}
+	return o.Default
+}
+
+// TimeZone returns the time zone of TiCDC specified by tz
+func (s *CDCSpec) TimeZone() string {
+	return s.TZ
+}
+
+// TimeZone returns the time zone of the instance, the one of the instance
+// spec (the tz of TiCDC) overrides the global one of the component
+func (i *BaseInstance) TimeZone(opt GlobalOptions) string {
+	if s, ok := i.InstanceSpec.(interface{ TimeZone() string }); ok && s.TimeZone() != "" {
+		return s.TimeZone()
+	}
+	return opt.TimeZone.ForComponent(i.ComponentName())
+}
+
+// normalizeTimeZone returns empty for the system time zone, which could be
+// specified as "System" for TiCDC
+func normalizeTimeZone(tz string) string {
+	if strings.EqualFold(tz, "system") {
+		return ""
+	}
+	return tz
+}
+
+// InstanceTimeZone returns the time zone of the instance, empty if it's the
+// system time zone of the host
+func InstanceTimeZone(inst Instance, opt GlobalOptions) string {
+	if i, ok := inst.(interface{ TimeZone(GlobalOptions) string }); ok {
+		return normalizeTimeZone(i.TimeZone(opt))
+	}
+	return normalizeTimeZone(opt.TimeZone.ForComponent(inst.ComponentName()))
+}
+
+// TimeZones returns the time zones specified of the instances on the host,
+// all the instances of the topology if host is empty
+func (s *Specification) TimeZones(host string) []string {
+	zones := set.NewStringSet()
+	s.IterInstance(func(inst Instance) {
+		if host != "" && inst.GetHost() != host {
+			return
+		}
+		if tz := InstanceTimeZone(inst, s.GlobalOptions); tz != "" {
+			zones.Insert(tz)
+		}
+	})
+	tzs := zones.Slice()
+	sort.Strings(tzs)
+	return tzs
+}
+
+// MixedTimeZones returns the instances of each time zone if the instances
+// are not in the same time zone, the ones of the system time zone of the
+// hosts are listed under the empty name
+func (s *Specification) MixedTimeZones() map[string][]string {
+	instances := make(map[string][]string)
+	s.IterInstance(func(inst Instance) {
+		tz := InstanceTimeZone(inst, s.GlobalOptions)
+		instances[tz] = append(instances[tz], inst.ID())
+	})
+	if len(instances) <= 1 {
+		return nil
+	}
+	return instances
+}
+
+// validateTimeZones checks the names of the time zones and the components
+// they are specified for, the time zones are checked against the tzdata of
+// the hosts by check
+func (s *Specification) validateTimeZones() error {
+	opt := s.GlobalOptions.TimeZone
+	if opt.Default != "" && !reTimeZone.MatchString(opt.Default) {
+		return errors.Errorf("`global.time_zone.default` of '%s' is not a valid time zone", opt.Default)
+	}
+	components := set.NewStringSet(AllComponentNames()...)
+	for comp, tz := range opt.Components {
+		if !components.Exist(comp) {
+			return errors.Errorf("`global.time_zone.components` of unknown component '%s', should be one of %s",
+				comp, strings.Join(AllComponentNames(), ", "))
+		}
+		if !reTimeZone.MatchString(tz) {
+			return errors.Errorf("`global.time_zone.components.%s` of '%s' is not a valid time zone", comp, tz)
+		}
+	}
+	for _, spec := range s.CDCServers {
+		if spec.TZ != "" && !reTimeZone.MatchString(spec.TZ) {
+			return errors.Errorf("tz of '%s' of cdc %s:%d is not a valid time zone", spec.TZ, spec.Host, spec.Port)
+		}
+	}
+	return nil
+}
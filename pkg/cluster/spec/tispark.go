@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/checkpoint"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/template"
 	"github.com/pingcap/tiup/pkg/cluster/template/config"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
 	system "github.com/pingcap/tiup/pkg/cluster/template/systemd"
@@ -220,7 +221,7 @@ func (i *TiSparkMasterInstance) InitConfig(
 
 	systemCfg := system.NewTiSparkConfig(comp, deployUser, paths.Deploy, i.GetJavaHome())
 
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	if err := template.ConfigToFile(systemCfg, paths.Templates, sysCfg); err != nil {
 		return errors.Trace(err)
 	}
 	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
@@ -246,7 +247,7 @@ func (i *TiSparkMasterInstance) InitConfig(
 		WithCustomFields(i.GetCustomFields())
 	// transfer spark-defaults.conf
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("spark-defaults-%s-%d.conf", host, port))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "conf", "spark-defaults.conf")
@@ -255,6 +256,7 @@ func (i *TiSparkMasterInstance) InitConfig(
 	}
 
 	env := scripts.NewTiSparkEnv(host).
+		WithTemplateDir(paths.Templates).
 		WithLocalIP(i.GetListenHost()).
 		WithMaster(host).
 		WithMasterPorts(i.Ports[0], i.Ports[1]).
@@ -272,7 +274,7 @@ func (i *TiSparkMasterInstance) InitConfig(
 
 	// transfer log4j config (it's not a template but a static file)
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("spark-log4j-%s-%d.properties", host, port))
-	log4jFile, err := config.GetConfig("spark-log4j.properties.tpl")
+	log4jFile, err := config.GetConfig(paths.Templates, "spark-log4j.properties.tpl")
 	if err != nil {
 		return err
 	}
@@ -295,7 +297,10 @@ func (i *TiSparkMasterInstance) ScaleConfig(
 ) error {
 	s := i.topo
 	defer func() { i.topo = s }()
-	cluster := mustBeClusterTopo(topo)
+	cluster, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
+	}
 	i.topo = cluster.Merge(i.topo)
 	return i.InitConfig(ctx, e, clusterName, clusterVersion, deployUser, paths)
 }
@@ -383,7 +388,7 @@ func (i *TiSparkWorkerInstance) InitConfig(
 
 	systemCfg := system.NewTiSparkConfig(comp, deployUser, paths.Deploy, i.GetJavaHome())
 
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	if err := template.ConfigToFile(systemCfg, paths.Templates, sysCfg); err != nil {
 		return errors.Trace(err)
 	}
 	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
@@ -415,7 +420,7 @@ func (i *TiSparkWorkerInstance) InitConfig(
 
 	// transfer spark-defaults.conf
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("spark-defaults-%s-%d.conf", host, port))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := template.ConfigToFile(cfg, paths.Templates, fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "conf", "spark-defaults.conf")
@@ -424,6 +429,7 @@ func (i *TiSparkWorkerInstance) InitConfig(
 	}
 
 	env := scripts.NewTiSparkEnv(host).
+		WithTemplateDir(paths.Templates).
 		WithLocalIP(i.GetListenHost()).
 		WithMaster(topo.TiSparkMasters[0].Host).
 		WithMasterPorts(topo.TiSparkMasters[0].Port, topo.TiSparkMasters[0].WebPort).
@@ -456,7 +462,7 @@ func (i *TiSparkWorkerInstance) InitConfig(
 
 	// transfer log4j config (it's not a template but a static file)
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("spark-log4j-%s-%d.properties", host, port))
-	log4jFile, err := config.GetConfig("spark-log4j.properties.tpl")
+	log4jFile, err := config.GetConfig(paths.Templates, "spark-log4j.properties.tpl")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,59 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// ErrTopologyTypeMismatch is returned if a topology of another type is passed
+// to the code handling a specific type of topology, e.g. a DM topology is
+// passed to the hooks of TiDB cluster components.
+var ErrTopologyTypeMismatch = errNSTopolohy.NewType("type_mismatch")
+
+// NewTopologyTypeMismatchError creates the error returned if the topology is
+// not of the expected type.
+func NewTopologyTypeMismatchError(expected string, topo Topology) error {
+	actual := fmt.Sprint(reflect.TypeOf(topo))
+	if v := reflect.ValueOf(topo); v.IsValid() && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		actual = topo.Type()
+	}
+	return ErrTopologyTypeMismatch.
+		New("Topology should be of type %s, but got %s", expected, actual).
+		WithProperty(tui.SuggestionFromString(
+			"This is a bug of TiUP, please report it at https://github.com/pingcap/tiup/issues/new",
+		))
+}
+
+// AsClusterTopology converts the topology to the TiDB cluster topology, an
+// error is returned instead of panic if it's not a TiDB cluster topology.
+func AsClusterTopology(topo Topology) (*Specification, error) {
+	spec, ok := topo.(*Specification)
+	if !ok || spec == nil {
+		return nil, NewTopologyTypeMismatchError(TopoTypeTiDB, topo)
+	}
+	return spec, nil
+}
+
+// MergeTopology merges the scale-out topology into the current one, an error
+// is returned instead of panic if they are not of the same type.
+func MergeTopology(topo, scale Topology) (Topology, error) {
+	if scale == nil || reflect.TypeOf(topo) != reflect.TypeOf(scale) {
+		return nil, NewTopologyTypeMismatchError(topo.Type(), scale)
+	}
+	return topo.MergeTopo(scale)
+}
@@ -19,10 +19,10 @@ import (
 	"crypto/tls"
 	"fmt"
 	"path/filepath"
-	"reflect"
 	"strings"
 	"time"
 
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/pingcap/tiup/pkg/version"
 	"github.com/prometheus/common/expfmt"
@@ -45,6 +45,18 @@ type ClusterMeta struct {
 	Version string `yaml:"tidb_version"` // the version of TiDB cluster
 	// EnableFirewall bool   `yaml:"firewall"`
 	OpsVer string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	// hosts skipped by previous operations because they were unreachable
+	QuarantinedHosts []string `yaml:"quarantined_hosts,omitempty"`
+	// temporary flag overrides of the instances
+	RuntimeOverrides []RuntimeOverride `yaml:"runtime_overrides,omitempty"`
+	// the log levels changed temporarily at runtime
+	LogLevelChanges []LogLevelChange `yaml:"log_level_changes,omitempty"`
+	// the samples of the disk usage of the data dirs
+	DiskUsages []DiskUsageHistory `yaml:"disk_usages,omitempty"`
+	// the restarts of the instances
+	Restarts []RestartHistory `yaml:"restarts,omitempty"`
+	// the hardware baseline recorded after deploying
+	Baseline *BaselineRecord `yaml:"baseline,omitempty"`
 
 	Topology *Specification `yaml:"topology"`
 }
@@ -67,13 +79,14 @@ func (m *ClusterMeta) GetTopology() Topology {
 }
 
 // SetTopology implement Metadata interface.
-func (m *ClusterMeta) SetTopology(topo Topology) {
-	tidbTopo, ok := topo.(*Specification)
-	if !ok {
-		panic(fmt.Sprintln("wrong type: ", reflect.TypeOf(topo)))
+func (m *ClusterMeta) SetTopology(topo Topology) error {
+	tidbTopo, err := AsClusterTopology(topo)
+	if err != nil {
+		return err
 	}
 
 	m.Topology = tidbTopo
+	return nil
 }
 
 // GetBaseMeta implements Metadata interface.
@@ -82,6 +95,12 @@ func (m *ClusterMeta) GetBaseMeta() *BaseMeta {
 		Version: m.Version,
 		User:    m.User,
 		OpsVer:  &m.OpsVer,
+
+		QuarantinedHosts: &m.QuarantinedHosts,
+		RuntimeOverrides: &m.RuntimeOverrides,
+		LogLevelChanges:  &m.LogLevelChanges,
+		DiskUsages:       &m.DiskUsages,
+		Restarts:         &m.Restarts,
 	}
 }
 
@@ -123,6 +142,16 @@ func LoadClientCert(dir string) (*tls.Config, error) {
 	}.ClientConfig()
 }
 
+// NewEndpointResolver returns the resolver of the schemes to access the HTTP
+// APIs of the components, the monitoring components are always served over
+// http as they are deployed without certs.
+func NewEndpointResolver(tlsCfg *tls.Config) *api.EndpointResolver {
+	return api.NewEndpointResolver(tlsCfg).
+		WithScheme(ComponentPrometheus, api.SchemeHTTP).
+		WithScheme(ComponentGrafana, api.SchemeHTTP).
+		WithScheme(ComponentAlertmanager, api.SchemeHTTP)
+}
+
 // statusByHost queries current status of the instance by http status api.
 func statusByHost(host string, port int, path string, timeout time.Duration, tlsCfg *tls.Config) string {
 	if timeout < time.Second {
@@ -131,14 +160,10 @@ func statusByHost(host string, port int, path string, timeout time.Duration, tls
 
 	client := utils.NewHTTPClient(timeout, tlsCfg)
 
-	scheme := "http"
-	if tlsCfg != nil {
-		scheme = "https"
-	}
 	if path == "" {
 		path = "/"
 	}
-	url := fmt.Sprintf("%s://%s:%d%s", scheme, host, port, path)
+	url := fmt.Sprintf("%s://%s:%d%s", api.Scheme(tlsCfg != nil), host, port, path)
 
 	// body doesn't have any status section needed
 	body, err := client.Get(context.TODO(), url)
@@ -154,11 +179,7 @@ func UptimeByHost(host string, port int, timeout time.Duration, tlsCfg *tls.Conf
 		timeout = statusQueryTimeout
 	}
 
-	scheme := "http"
-	if tlsCfg != nil {
-		scheme = "https"
-	}
-	url := fmt.Sprintf("%s://%s:%d/metrics", scheme, host, port)
+	url := fmt.Sprintf("%s://%s:%d/metrics", api.Scheme(tlsCfg != nil), host, port)
 
 	client := utils.NewHTTPClient(timeout, tlsCfg)
 
@@ -125,6 +125,38 @@ func appendEntries(name string, topo Topology, inst Instance, dirAccessor DirAcc
 	return targets
 }
 
+// dirConflictError returns the error of a directory conflicting to an existing cluster
+func dirConflictError(properties map[string]string) error {
+	return errDeployDirConflict.New("Deploy directory conflicts to an existing cluster").WithProperty(tui.SuggestionFromTemplate(`
+The directory you specified in the topology file is:
+  Directory: {{ColorKeyword}}{{.ThisDirKind}} {{.ThisDir}}{{ColorReset}}
+  Component: {{ColorKeyword}}{{.ThisComponent}} {{.ThisHost}}{{ColorReset}}
+
+It conflicts to a directory in the existing cluster:
+  Existing Cluster Name: {{ColorKeyword}}{{.ExistCluster}}{{ColorReset}}
+  Existing Directory:    {{ColorKeyword}}{{.ExistDirKind}} {{.ExistDir}}{{ColorReset}}
+  Existing Component:    {{ColorKeyword}}{{.ExistComponent}} {{.ExistHost}}{{ColorReset}}
+
+Please change to use another directory or another host.
+`, properties))
+}
+
+// portConflictError returns the error of a port conflicting to an existing cluster
+func portConflictError(properties map[string]string) error {
+	return errDeployPortConflict.New("Deploy port conflicts to an existing cluster").WithProperty(tui.SuggestionFromTemplate(`
+The port you specified in the topology file is:
+  Port:      {{ColorKeyword}}{{.ThisPort}}{{ColorReset}}
+  Component: {{ColorKeyword}}{{.ThisComponent}} {{.ThisHost}}{{ColorReset}}
+
+It conflicts to a port in the existing cluster:
+  Existing Cluster Name: {{ColorKeyword}}{{.ExistCluster}}{{ColorReset}}
+  Existing Port:         {{ColorKeyword}}{{.ExistPort}}{{ColorReset}}
+  Existing Component:    {{ColorKeyword}}{{.ExistComponent}} {{.ExistHost}}{{ColorReset}}
+
+Please change to use another port or another host.
+`, properties))
+}
+
 // CheckClusterDirConflict checks cluster dir conflict or overlap
 func CheckClusterDirConflict(clusterList map[string]Metadata, clusterName string, topo Topology) error {
 	instanceDirAccessor, hostDirAccessor := dirAccessors()
@@ -194,18 +226,7 @@ func CheckClusterDirConflict(clusterList map[string]Metadata, clusterName string
 					"ExistHost":      d2.instance.GetHost(),
 				}
 				zap.L().Info("Meet deploy directory conflict", zap.Any("info", properties))
-				return errDeployDirConflict.New("Deploy directory conflicts to an existing cluster").WithProperty(tui.SuggestionFromTemplate(`
-The directory you specified in the topology file is:
-  Directory: {{ColorKeyword}}{{.ThisDirKind}} {{.ThisDir}}{{ColorReset}}
-  Component: {{ColorKeyword}}{{.ThisComponent}} {{.ThisHost}}{{ColorReset}}
-
-It conflicts to a directory in the existing cluster:
-  Existing Cluster Name: {{ColorKeyword}}{{.ExistCluster}}{{ColorReset}}
-  Existing Directory:    {{ColorKeyword}}{{.ExistDirKind}} {{.ExistDir}}{{ColorReset}}
-  Existing Component:    {{ColorKeyword}}{{.ExistComponent}} {{.ExistHost}}{{ColorReset}}
-
-Please change to use another directory or another host.
-`, properties))
+				return dirConflictError(properties)
 			}
 		}
 	}
@@ -392,18 +413,71 @@ func CheckClusterPortConflict(clusterList map[string]Metadata, clusterName strin
 
 				// build error message
 				zap.L().Info("Meet deploy port conflict", zap.Any("info", properties))
-				return errDeployPortConflict.New("Deploy port conflicts to an existing cluster").WithProperty(tui.SuggestionFromTemplate(`
-The port you specified in the topology file is:
-  Port:      {{ColorKeyword}}{{.ThisPort}}{{ColorReset}}
-  Component: {{ColorKeyword}}{{.ThisComponent}} {{.ThisHost}}{{ColorReset}}
+				return portConflictError(properties)
+			}
+		}
+	}
 
-It conflicts to a port in the existing cluster:
-  Existing Cluster Name: {{ColorKeyword}}{{.ExistCluster}}{{ColorReset}}
-  Existing Port:         {{ColorKeyword}}{{.ExistPort}}{{ColorReset}}
-  Existing Component:    {{ColorKeyword}}{{.ExistComponent}} {{.ExistHost}}{{ColorReset}}
+	return nil
+}
 
-Please change to use another port or another host.
-`, properties))
+// CheckHostRegistryConflict checks the ports and directories of topo conflict
+// with the ones recorded in the host registry by the clusters of other kinds
+// managed on the same control machine, e.g. the DM clusters for tiup-cluster
+func CheckHostRegistryConflict(existing []HostEntry, clusterName string, topo Topology) error {
+	current := HostEntries("", clusterName, topo)
+	for _, e1 := range current {
+		for _, e2 := range existing {
+			if e1.Host != e2.Host {
+				continue
+			}
+			// if one of the sides marks itself as ignore_exporter, do not report
+			// the conflicts of the monitoring agents
+			ignored := (e1.Component == RoleMonitor || e2.Component == RoleMonitor) &&
+				(e1.IgnoreMonitorAgent || e2.IgnoreMonitorAgent)
+			existCluster := fmt.Sprintf("%s (%s)", e2.Cluster, e2.Kind)
+
+			for _, p1 := range e1.Ports {
+				for _, p2 := range e2.Ports {
+					if p1 != p2 || ignored {
+						continue
+					}
+					properties := map[string]string{
+						"ThisPort":       strconv.Itoa(p1),
+						"ThisComponent":  e1.Component,
+						"ThisHost":       e1.Host,
+						"ExistCluster":   existCluster,
+						"ExistPort":      strconv.Itoa(p2),
+						"ExistComponent": e2.Component,
+						"ExistHost":      e2.Host,
+					}
+					zap.L().Info("Meet deploy port conflict", zap.Any("info", properties))
+					return portConflictError(properties)
+				}
+			}
+
+			if e1.Component == RoleMonitor && e2.Component == RoleMonitor && ignored {
+				continue
+			}
+			for _, d1 := range e1.Dirs {
+				for _, d2 := range e2.Dirs {
+					if d1.Dir != d2.Dir {
+						continue
+					}
+					properties := map[string]string{
+						"ThisDirKind":    d1.Kind,
+						"ThisDir":        d1.Dir,
+						"ThisComponent":  e1.Component,
+						"ThisHost":       e1.Host,
+						"ExistCluster":   existCluster,
+						"ExistDirKind":   d2.Kind,
+						"ExistDir":       d2.Dir,
+						"ExistComponent": e2.Component,
+						"ExistHost":      e2.Host,
+					}
+					zap.L().Info("Meet deploy directory conflict", zap.Any("info", properties))
+					return dirConflictError(properties)
+				}
 			}
 		}
 	}
@@ -975,6 +1049,19 @@ func (s *Specification) validatePDNames() error {
 	return nil
 }
 
+// validateDashboardHosts checks at least one of the PD hosts allowed to run
+// TiDB Dashboard is in the topology, the others are ignored so that a PD host
+// could be scaled in without editing the option.
+func (s *Specification) validateDashboardHosts() error {
+	if len(s.GlobalOptions.Dashboard.PDHosts) == 0 || len(s.PDServers) == 0 {
+		return nil
+	}
+	if len(s.DashboardCandidates()) == 0 {
+		return errors.Errorf("none of global.dashboard.pd_hosts %v is a host of pd_servers", s.GlobalOptions.Dashboard.PDHosts)
+	}
+	return nil
+}
+
 func (s *Specification) validateTiFlashConfigs() error {
 	c := FindComponent(s, ComponentTiFlash)
 	for _, ins := range c.Instances() {
@@ -1053,10 +1140,16 @@ func (s *Specification) Validate() error {
 		s.portConflictsDetect,
 		s.dirConflictsDetect,
 		s.validateUserGroup,
+		s.validateDeployUser,
 		s.validatePDNames,
+		s.validateDashboardHosts,
 		s.validateTiSparkSpec,
 		s.validateTiFlashConfigs,
 		s.validateMonitorAgent,
+		s.validateMonitoredHosts,
+		s.validateEtcHosts,
+		s.validateReadOnlyZones,
+		s.validateTimeZones,
 	}
 
 	for _, v := range validators {
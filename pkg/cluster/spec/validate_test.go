@@ -1281,3 +1281,90 @@ tikv_servers:
 		c.Assert(err.Error(), Equals, "spec.deploy.dir_overlap: Deploy directory overlaps to another instance")
 	}
 }
+
+func (s *metaSuiteTopo) TestPolicyRules(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  user: "test1"
+  deploy_dir: "/home/test1/deploy"
+pd_servers:
+  - host: 172.16.5.138
+    data_dir: "/data1/pd"
+tikv_servers:
+  - host: 172.16.5.138
+    data_dir: "/var/lib/tikv"
+    config:
+      server.labels: { zone: "z1" }
+  - host: 172.16.5.139
+    data_dir: "/data2/tikv"
+    config:
+      server.labels: { zone: "z1", rack: "r1" }
+`), &topo)
+	c.Assert(err, IsNil)
+
+	dir := c.MkDir()
+	fname := filepath.Join(dir, PolicyFile)
+	err = os.WriteFile(fname, []byte(`
+rules:
+  - name: tikv-data-disk
+    components: [tikv]
+    data_dir_pattern: "^/data[0-9]*/"
+  - name: no-pd-with-tikv
+    not_colocated: [pd, tikv]
+  - name: rack-label
+    required_labels: [rack]
+`), 0644)
+	c.Assert(err, IsNil)
+
+	policies, err := LoadPolicies(fname)
+	c.Assert(err, IsNil)
+	c.Assert(policies, HasLen, 3)
+
+	c.Assert(policies[0].Check(&topo), DeepEquals, []string{"data directory /var/lib/tikv of 172.16.5.138:20160 is not allowed"})
+	c.Assert(policies[1].Check(&topo), DeepEquals, []string{"pd, tikv are not allowed on the same host 172.16.5.138"})
+	c.Assert(policies[2].Check(&topo), DeepEquals, []string{"label rack is required for 172.16.5.138:20160"})
+
+	// no policy file
+	policies, err = LoadPolicies(filepath.Join(dir, "not-exist.yaml"))
+	c.Assert(err, IsNil)
+	c.Assert(policies, HasLen, 0)
+}
+
+func (s *metaSuiteTopo) TestDashboardHosts(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  dashboard:
+    pd_hosts: [172.16.5.139, 172.16.5.200]
+pd_servers:
+  - host: 172.16.5.138
+  - host: 172.16.5.139
+`), &topo)
+	c.Assert(err, IsNil)
+
+	candidates := topo.DashboardCandidates()
+	c.Assert(candidates, HasLen, 1)
+	c.Assert(candidates[0].Host, Equals, "172.16.5.139")
+
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+global:
+  dashboard:
+    pd_hosts: [172.16.5.200]
+pd_servers:
+  - host: 172.16.5.138
+`), &topo)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, "none of global.dashboard.pd_hosts .* is a host of pd_servers")
+
+	// all PD servers are allowed if not specified
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.138
+  - host: 172.16.5.139
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.DashboardCandidates(), HasLen, 2)
+}
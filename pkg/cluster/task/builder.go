@@ -18,6 +18,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
@@ -140,10 +141,10 @@ func (b *Builder) ClusterSSH(
 		sshType = defaultSSHType
 	}
 	var tasks []Task
-	topo.IterInstance(func(inst spec.Instance) {
+	userSSH := func(host string, port int) {
 		tasks = append(tasks, &UserSSH{
-			host:            inst.GetHost(),
-			port:            inst.GetSSHPort(),
+			host:            host,
+			port:            port,
 			deployUser:      deployUser,
 			timeout:         sshTimeout,
 			exeTimeout:      exeTimeout,
@@ -156,7 +157,13 @@ func (b *Builder) ClusterSSH(
 			proxyTimeout:    proxySSHTimeout,
 			sshType:         sshType,
 		})
+	}
+	topo.IterInstance(func(inst spec.Instance) {
+		userSSH(inst.GetHost(), inst.GetSSHPort())
 	})
+	for _, h := range spec.MonitoredHosts(topo) {
+		userSSH(h.Host, h.SSHPort)
+	}
 
 	b.tasks = append(b.tasks, &Parallel{inner: tasks})
 
@@ -250,7 +257,10 @@ func (b *Builder) InitConfig(clusterName, clusterVersion string, specManager *sp
 
 	//  full version
 	componentVersion = utils.Version(clusterVersion)
+	var runtimeFlags []string
 	if err := specManager.Metadata(clusterName, meta); err == nil {
+		runtimeFlags = meta.GetBaseMeta().RuntimeFlags(inst.ID(), time.Now())
+
 		// get nightly version
 		if clusterVersion == utils.NightlyVersionAlias {
 			componentVersion, _, err = environment.GlobalEnv().V1Repository().LatestNightlyVersion(inst.ComponentName())
@@ -273,6 +283,7 @@ func (b *Builder) InitConfig(clusterName, clusterVersion string, specManager *sp
 		deployUser:     deployUser,
 		ignoreCheck:    ignoreCheck,
 		paths:          paths,
+		runtimeFlags:   runtimeFlags,
 	})
 	return b
 }
@@ -324,12 +335,13 @@ func (b *Builder) SSHKeySet(privKeyPath, pubKeyPath string) *Builder {
 }
 
 // EnvInit appends a EnvInit task to the current task collection
-func (b *Builder) EnvInit(host, deployUser string, userGroup string, skipCreateUser bool) *Builder {
+func (b *Builder) EnvInit(host, deployUser string, userGroup string, skipCreateUser bool, settings spec.DeployUserSettings) *Builder {
 	b.tasks = append(b.tasks, &EnvInit{
 		host:           host,
 		deployUser:     deployUser,
 		userGroup:      userGroup,
 		skipCreateUser: skipCreateUser,
+		settings:       settings,
 	})
 	return b
 }
@@ -404,6 +416,32 @@ func (b *Builder) Sysctl(host, key, val string) *Builder {
 	return b
 }
 
+// HostState applies the managed system settings on host
+func (b *Builder) HostState(host string, state spec.HostState) *Builder {
+	if state.IsEmpty() {
+		return b
+	}
+	b.tasks = append(b.tasks, &HostState{
+		host:  host,
+		state: state,
+	})
+	return b
+}
+
+// EtcHosts updates the entries of the cluster in /etc/hosts on host, it's
+// no-op if the entries are not managed, i.e. nil
+func (b *Builder) EtcHosts(host, cluster string, entries map[string]string) *Builder {
+	if entries == nil {
+		return b
+	}
+	b.tasks = append(b.tasks, &EtcHosts{
+		host:    host,
+		cluster: cluster,
+		entries: entries,
+	})
+	return b
+}
+
 // Limit set a system limit
 func (b *Builder) Limit(host, domain, limit, item, value string) *Builder {
 	b.tasks = append(b.tasks, &Limit{
@@ -38,6 +38,7 @@ var (
 	CheckTypePermission   = "permission"
 	ChecktypeIsExist      = "exist"
 	CheckTypeTimeZone     = "timezone"
+	CheckTypeNUMA         = "numa"
 )
 
 // place the check utilities are stored
@@ -85,6 +86,13 @@ func (c *CheckSys) Execute(ctx context.Context) error {
 			operator.CheckSELinux(ctx, e),
 			operator.CheckTHP(ctx, e),
 		)
+		if state := c.topo.GlobalOptions.HostState; !state.IsEmpty() {
+			if state.Swappiness != nil {
+				// the managed value is checked instead of the recommended one
+				results = filterSysctlResults(results, "vm.swappiness")
+			}
+			results = append(results, operator.CheckHostState(ctx, e, state)...)
+		}
 		storeResults(ctx, c.host, results)
 	case CheckTypePort:
 		storeResults(ctx, c.host, operator.CheckListeningPort(c.opt, c.host, c.topo, stdout))
@@ -158,11 +166,25 @@ func (c *CheckSys) Execute(ctx context.Context) error {
 		storeResults(ctx, c.host, operator.CheckDirIsExist(ctx, e, c.checkDir))
 	case CheckTypeTimeZone:
 		storeResults(ctx, c.host, operator.CheckTimeZone(ctx, c.topo, c.host, stdout))
+	case CheckTypeNUMA:
+		storeResults(ctx, c.host, operator.CheckNUMA(c.host, c.topo, stdout))
 	}
 
 	return nil
 }
 
+// filterSysctlResults removes the results of the kernel parameter
+func filterSysctlResults(results []*operator.CheckResult, key string) []*operator.CheckResult {
+	filtered := results[:0]
+	for _, r := range results {
+		if r.Name == operator.CheckNameSysctl && strings.HasPrefix(r.Msg, key+" ") {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
 // Rollback implements the Task interface
 func (c *CheckSys) Rollback(ctx context.Context) error {
 	return ErrUnsupportedRollback
@@ -17,12 +17,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/joomcode/errorx"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/module"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/set"
 )
 
 var (
@@ -30,6 +34,9 @@ var (
 	errEnvInitSubCommandFailed = errNSEnvInit.NewType("sub_command_failed")
 	// ErrEnvInitFailed is ErrEnvInitFailed
 	ErrEnvInitFailed = errNSEnvInit.NewType("failed")
+	// ErrDeployUserMismatch is the deploy user on the host not matching
+	// the topology, or missing while it's required to exist
+	ErrDeployUserMismatch = errNSEnvInit.NewType("deploy_user_mismatch")
 )
 
 // EnvInit is used to initialize the remote environment, e.g:
@@ -40,6 +47,7 @@ type EnvInit struct {
 	deployUser     string
 	userGroup      string
 	skipCreateUser bool
+	settings       spec.DeployUserSettings
 }
 
 // Execute implements the Task interface
@@ -57,12 +65,16 @@ func (e *EnvInit) execute(ctx context.Context) error {
 		panic(ErrNoExecutor)
 	}
 
-	if !e.skipCreateUser {
+	if !e.skipCreateUser && e.settings.CreateUser() {
 		um := module.NewUserModule(module.UserModuleConfig{
 			Action: module.UserActionAdd,
 			Name:   e.deployUser,
 			Group:  e.userGroup,
+			Shell:  e.settings.Shell,
 			Sudoer: true,
+			UID:    e.settings.UID,
+			GID:    e.settings.GID,
+			Groups: e.settings.Groups,
 		})
 
 		_, _, errx := um.Execute(ctx, exec)
@@ -70,6 +82,9 @@ func (e *EnvInit) execute(ctx context.Context) error {
 			return wrapError(errx)
 		}
 	}
+	if err := e.checkDeployUser(ctx, exec); err != nil {
+		return err
+	}
 
 	pubKey, err := os.ReadFile(ctxt.GetInner(ctx).PublicKeyPath)
 	if err != nil {
@@ -97,6 +112,54 @@ func (e *EnvInit) execute(ctx context.Context) error {
 	return nil
 }
 
+// checkDeployUser checks the deploy user exists and matches the settings
+// specified, the mismatches are reported instead of changing the user, as
+// the files on the host may be owned by the existing UID and GID
+func (e *EnvInit) checkDeployUser(ctx context.Context, exec ctxt.Executor) error {
+	cmd := fmt.Sprintf("getent passwd %[1]s && id -gn %[1]s && id -Gn %[1]s", e.deployUser)
+	stdout, _, err := exec.Execute(ctx, cmd, false)
+	if err != nil {
+		return ErrDeployUserMismatch.Wrap(err,
+			"Deploy user '%s' does not exist on host '%s', please create it or set `global.deploy_user.create` to true",
+			e.deployUser, e.host)
+	}
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	// name:password:uid:gid:gecos:home:shell
+	passwd := strings.Split(strings.TrimSpace(lines[0]), ":")
+	if len(lines) < 3 || len(passwd) < 7 {
+		return ErrDeployUserMismatch.New("Unexpected output of `%s` on host '%s': %s", cmd, e.host, stdout)
+	}
+	primary := strings.TrimSpace(lines[1])
+	groups := set.NewStringSet(strings.Fields(lines[2])...)
+
+	var mismatches []string
+	if e.settings.UID != 0 && passwd[2] != strconv.Itoa(e.settings.UID) {
+		mismatches = append(mismatches, fmt.Sprintf("uid is %s instead of %d", passwd[2], e.settings.UID))
+	}
+	if e.settings.GID != 0 && passwd[3] != strconv.Itoa(e.settings.GID) {
+		mismatches = append(mismatches, fmt.Sprintf("gid is %s instead of %d", passwd[3], e.settings.GID))
+	}
+	// the group is used to create the user by the legacy versions, the
+	// existing users of the clusters deployed by them may be in other groups
+	if e.userGroup != "" && primary != e.userGroup {
+		ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger).
+			Warnf("The primary group of deploy user '%s' on host '%s' is %s instead of %s", e.deployUser, e.host, primary, e.userGroup)
+	}
+	for _, group := range e.settings.Groups {
+		if !groups.Exist(group) {
+			mismatches = append(mismatches, fmt.Sprintf("not in group %s", group))
+		}
+	}
+	if e.settings.Shell != "" && passwd[6] != e.settings.Shell {
+		mismatches = append(mismatches, fmt.Sprintf("shell is %s instead of %s", passwd[6], e.settings.Shell))
+	}
+	if len(mismatches) > 0 {
+		return ErrDeployUserMismatch.New("Deploy user '%s' on host '%s' does not match the topology: %s",
+			e.deployUser, e.host, strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
 // Rollback implements the Task interface
 func (e *EnvInit) Rollback(ctx context.Context) error {
 	return ErrUnsupportedRollback
@@ -0,0 +1,54 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+)
+
+type userExecutor struct {
+	stdout string
+}
+
+func (e *userExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return []byte(e.stdout), nil, nil
+}
+
+func (e *userExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	return nil
+}
+
+func TestCheckDeployUser(t *testing.T) {
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, logprinter.NewLogger(""))
+	exec := &userExecutor{stdout: "tidb:x:1000:1000::/home/tidb:/bin/bash\nstaff\nstaff wheel\n"}
+
+	// the primary group of an existing user is only warned
+	e := &EnvInit{host: "172.16.5.140", deployUser: "tidb", userGroup: "tidb"}
+	assert.Nil(t, e.checkDeployUser(ctx, exec))
+
+	e.settings = spec.DeployUserSettings{UID: 1000, Groups: []string{"wheel"}, Shell: "/bin/bash"}
+	assert.Nil(t, e.checkDeployUser(ctx, exec))
+
+	e.settings = spec.DeployUserSettings{UID: 1001, Groups: []string{"docker"}}
+	err := e.checkDeployUser(ctx, exec)
+	assert.True(t, errorx.IsOfType(err, ErrDeployUserMismatch))
+	assert.Contains(t, err.Error(), "uid is 1000 instead of 1001, not in group docker")
+}
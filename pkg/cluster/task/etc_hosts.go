@@ -0,0 +1,78 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+var etcHostsFilePath = "/etc/hosts"
+
+// EtcHosts replaces the entries of the cluster in /etc/hosts on host, the
+// other lines of the file are kept untouched
+type EtcHosts struct {
+	host    string
+	cluster string
+	entries map[string]string
+}
+
+// Execute implements the Task interface
+func (h *EtcHosts) Execute(ctx context.Context) error {
+	e, ok := ctxt.GetInner(ctx).GetExecutor(h.host)
+	if !ok {
+		return ErrNoExecutor
+	}
+
+	begin, end, block := spec.EtcHostsBlock(h.cluster, h.entries)
+	cmds := []string{
+		fmt.Sprintf("cp %s{,.bak}", etcHostsFilePath),
+		fmt.Sprintf("sed -i '/^%s$/,/^%s$/d' %s", sedEscape(begin), sedEscape(end), etcHostsFilePath),
+	}
+	if len(h.entries) > 0 {
+		cmds = append(cmds, fmt.Sprintf("echo %s | base64 -d >> %s",
+			base64.StdEncoding.EncodeToString([]byte(block)), etcHostsFilePath))
+	}
+
+	stdout, stderr, err := e.Execute(ctx, strings.Join(cmds, " && "), true)
+	ctxt.GetInner(ctx).SetOutputs(h.host, stdout, stderr)
+	if err != nil {
+		return errors.Annotatef(err, "failed to update %s on %s", etcHostsFilePath, h.host)
+	}
+	return nil
+}
+
+var sedSpecialChars = regexp.MustCompile(`[][\\/.*^$]`)
+
+// sedEscape escapes the string to be matched literally in a sed address
+func sedEscape(s string) string {
+	return sedSpecialChars.ReplaceAllString(s, `\$0`)
+}
+
+// Rollback implements the Task interface
+func (h *EtcHosts) Rollback(ctx context.Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (h *EtcHosts) String() string {
+	return fmt.Sprintf("EtcHosts: host=%s cluster=%s entries=%d", h.host, h.cluster, len(h.entries))
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+var (
+	fstabFilePath = "/etc/fstab"
+	thpUnitPath   = "/etc/systemd/system/" + operator.THPServiceName
+)
+
+// the unit disables THP on every boot, as the setting in sysfs is not persisted
+var thpUnit = `[Unit]
+Description=Disable Transparent Huge Pages (THP)
+DefaultDependencies=no
+After=sysinit.target local-fs.target
+Before=basic.target
+
+[Service]
+Type=oneshot
+ExecStart=/bin/sh -c 'if [ -d /sys/kernel/mm/transparent_hugepage ]; then echo never > /sys/kernel/mm/transparent_hugepage/enabled && echo never > /sys/kernel/mm/transparent_hugepage/defrag; fi'
+
+[Install]
+WantedBy=basic.target
+`
+
+// HostState applies the managed system settings on host persistently
+type HostState struct {
+	host  string
+	state spec.HostState
+}
+
+// Execute implements the Task interface
+func (h *HostState) Execute(ctx context.Context) error {
+	e, ok := ctxt.GetInner(ctx).GetExecutor(h.host)
+	if !ok {
+		return ErrNoExecutor
+	}
+
+	var cmds []string
+	if h.state.DisableSwap {
+		cmds = append(cmds,
+			"swapoff -a",
+			// comment out the swap entries so that swap is not enabled on boot
+			fmt.Sprintf("cp %s{,.bak}", fstabFilePath),
+			fmt.Sprintf("sed -i -E 's/^([^#[:space:]]+[[:space:]]+[^[:space:]]+[[:space:]]+swap[[:space:]].*)$/# \\1/' %s", fstabFilePath),
+		)
+	}
+	if h.state.Swappiness != nil {
+		cmds = append(cmds,
+			fmt.Sprintf("touch %s", sysctlFilePath),
+			fmt.Sprintf("sed -i '/vm.swappiness/d' %s", sysctlFilePath),
+			fmt.Sprintf("echo 'vm.swappiness=%d' >> %s", *h.state.Swappiness, sysctlFilePath),
+			fmt.Sprintf("sysctl -p %s", sysctlFilePath),
+		)
+	}
+	if h.state.DisableTHP {
+		cmds = append(cmds,
+			fmt.Sprintf("echo %s | base64 -d > %s", base64.StdEncoding.EncodeToString([]byte(thpUnit)), thpUnitPath),
+			"systemctl daemon-reload",
+			fmt.Sprintf("systemctl enable --now %s", operator.THPServiceName),
+		)
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	stdout, stderr, err := e.Execute(ctx, strings.Join(cmds, " && "), true)
+	ctxt.GetInner(ctx).SetOutputs(h.host, stdout, stderr)
+	if err != nil {
+		return errors.Annotatef(err, "failed to apply host state on %s", h.host)
+	}
+
+	return nil
+}
+
+// Rollback implements the Task interface
+func (h *HostState) Rollback(ctx context.Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (h *HostState) String() string {
+	var items []string
+	if h.state.DisableSwap {
+		items = append(items, "disable_swap")
+	}
+	if h.state.Swappiness != nil {
+		items = append(items, fmt.Sprintf("swappiness=%d", *h.state.Swappiness))
+	}
+	if h.state.DisableTHP {
+		items = append(items, "disable_thp")
+	}
+	return fmt.Sprintf("HostState: host=%s %s", h.host, strings.Join(items, " "))
+}
@@ -17,6 +17,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
@@ -33,6 +35,32 @@ type InitConfig struct {
 	deployUser     string
 	ignoreCheck    bool
 	paths          meta.DirPaths
+	runtimeFlags   []string
+}
+
+// runtimeFlagsExecutor appends the runtime flags to the run scripts
+// transferred to the host
+type runtimeFlagsExecutor struct {
+	ctxt.Executor
+	scriptDir string
+	flags     []string
+}
+
+// Transfer implements ctxt.Executor interface.
+func (e *runtimeFlagsExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	if download || filepath.Dir(dst) != e.scriptDir || !strings.HasPrefix(filepath.Base(dst), "run_") {
+		return e.Executor.Transfer(ctx, src, dst, download, limit, compress)
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	overridden := src + ".override"
+	if err := os.WriteFile(overridden, spec.ApplyRuntimeFlags(data, e.flags), 0755); err != nil {
+		return errors.AddStack(err)
+	}
+	return e.Executor.Transfer(ctx, overridden, dst, download, limit, compress)
 }
 
 // Execute implements the Task interface
@@ -47,13 +75,23 @@ func (c *InitConfig) Execute(ctx context.Context) error {
 		return errors.Annotatef(err, "create cache directory failed: %s", c.paths.Cache)
 	}
 
-	err := c.instance.InitConfig(ctx, exec, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
-	if err != nil {
-		if c.ignoreCheck && errors.Cause(err) == spec.ErrorCheckConfig {
-			return nil
+	if len(c.runtimeFlags) > 0 {
+		exec = &runtimeFlagsExecutor{
+			Executor:  exec,
+			scriptDir: filepath.Join(c.paths.Deploy, "scripts"),
+			flags:     c.runtimeFlags,
 		}
+	}
+
+	err := c.instance.InitConfig(ctx, exec, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
+	if err != nil && !(c.ignoreCheck && errors.Cause(err) == spec.ErrorCheckConfig) {
 		return errors.Annotatef(err, "init config failed: %s:%d", c.instance.GetHost(), c.instance.GetPort())
 	}
+
+	// the configs are transferred to the host, keep them in the config history
+	if _, err := c.specManager.SaveRenderedConfigGeneration(c.clusterName, c.instance, c.paths.Cache); err != nil {
+		return errors.Annotatef(err, "save config history failed: %s:%d", c.instance.GetHost(), c.instance.GetPort())
+	}
 	return nil
 }
 
@@ -15,6 +15,8 @@ package task
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -69,6 +71,10 @@ func (i *fakeInstance) GetPort() int {
 	return 4000
 }
 
+func (i *fakeInstance) ComponentName() string {
+	return spec.ComponentTiDB
+}
+
 func Test(t *testing.T) { check.TestingT(t) }
 
 var _ = check.Suite(&initConfigSuite{})
@@ -77,11 +83,14 @@ func (s *initConfigSuite) TestCheckConfig(c *check.C) {
 	ctx := ctxt.New(context.Background(), 0, logprinter.NewLogger(""))
 	defer mock.With("FakeExecutor", &fakeExecutor{})()
 
+	cacheDir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(cacheDir, "tidb-1.1.1.1-4000.toml"), []byte("[log]\n"), 0644), check.IsNil)
 	t := &InitConfig{
+		specManager:    spec.NewSpec(c.MkDir(), nil),
 		clusterName:    "test-cluster-name",
 		clusterVersion: "v4.0.0",
 		paths: meta.DirPaths{
-			Cache: "/tmp",
+			Cache: cacheDir,
 		},
 	}
 
@@ -102,3 +111,36 @@ func (s *initConfigSuite) TestCheckConfig(c *check.C) {
 		}
 	}
 }
+
+func (s *initConfigSuite) TestConfigHistory(c *check.C) {
+	ctx := ctxt.New(context.Background(), 0, logprinter.NewLogger(""))
+	ctxt.GetInner(ctx).SetExecutor("1.1.1.1", &fakeExecutor{})
+
+	cacheDir := c.MkDir()
+	cached := filepath.Join(cacheDir, "tidb-1.1.1.1-4000.toml")
+	c.Assert(os.WriteFile(cached, []byte("[log]\n"), 0644), check.IsNil)
+	t := &InitConfig{
+		specManager:    spec.NewSpec(c.MkDir(), nil),
+		clusterName:    "test-cluster-name",
+		clusterVersion: "v4.0.0",
+		instance:       &fakeInstance{},
+		paths: meta.DirPaths{
+			Cache: cacheDir,
+		},
+	}
+
+	// the configs unchanged are kept as one generation
+	c.Assert(t.Execute(ctx), check.IsNil)
+	c.Assert(t.Execute(ctx), check.IsNil)
+	history, err := t.specManager.ConfigGenerations(t.clusterName, t.instance)
+	c.Assert(err, check.IsNil)
+	c.Assert(history, check.HasLen, 1)
+	c.Assert(string(history[0].Files["tidb.toml"]), check.Equals, "[log]\n")
+
+	c.Assert(os.WriteFile(cached, []byte("[log]\nlevel = \"warn\"\n"), 0644), check.IsNil)
+	c.Assert(t.Execute(ctx), check.IsNil)
+	history, err = t.specManager.ConfigGenerations(t.clusterName, t.instance)
+	c.Assert(err, check.IsNil)
+	c.Assert(history, check.HasLen, 2)
+	c.Assert(string(history[1].Files["tidb.toml"]), check.Equals, "[log]\nlevel = \"warn\"\n")
+}
@@ -29,10 +29,14 @@ func NewAlertManagerConfig() *AlertManagerConfig {
 	return &AlertManagerConfig{}
 }
 
+// Template returns the path of the embedded template
+func (c *AlertManagerConfig) Template() string {
+	return path.Join("templates", "config", "alertmanager.yml")
+}
+
 // Config generate the config file data.
 func (c *AlertManagerConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "config", "alertmanager.yml")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
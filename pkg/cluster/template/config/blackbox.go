@@ -36,10 +36,14 @@ func NewBlackboxConfig(deployDir string, tlsEnabled bool) *BlackboxConfig {
 	}
 }
 
+// Template returns the path of the embedded template
+func (c *BlackboxConfig) Template() string {
+	return path.Join("templates", "config", "blackbox.yml.tpl")
+}
+
 // Config generate the config file data.
 func (c *BlackboxConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "config", "blackbox.yml.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -19,8 +19,9 @@ import (
 	"github.com/pingcap/tiup/embed"
 )
 
-// GetConfig returns a raw config file from embed templates
-func GetConfig(filename string) ([]byte, error) {
+// GetConfig returns a raw config file from embed templates, the modified one
+// in dir takes precedence
+func GetConfig(dir, filename string) ([]byte, error) {
 	fp := filepath.Join("templates", "config", filename)
-	return embed.ReadTemplate(fp)
+	return embed.ReadTemplateFrom(dir, fp)
 }
@@ -36,10 +36,14 @@ func NewDashboardConfig(cluster, deployDir string) *DashboardConfig {
 	}
 }
 
+// Template returns the path of the embedded template
+func (c *DashboardConfig) Template() string {
+	return path.Join("templates", "config", "dashboard.yml.tpl")
+}
+
 // Config generate the config file data.
 func (c *DashboardConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "config", "dashboard.yml.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
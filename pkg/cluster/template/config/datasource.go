@@ -44,10 +44,14 @@ func (c *DatasourceConfig) WithPort(port uint64) *DatasourceConfig {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *DatasourceConfig) Template() string {
+	return path.Join("templates", "config", "datasource.yml.tpl")
+}
+
 // Config generate the config file data.
 func (c *DatasourceConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "config", "datasource.yml.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
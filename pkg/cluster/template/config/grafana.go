@@ -100,10 +100,14 @@ func (c *GrafanaConfig) WithOrgRole(orgRole string) *GrafanaConfig {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *GrafanaConfig) Template() string {
+	return path.Join("templates", "config", "grafana.ini.tpl")
+}
+
 // Config generate the config file data.
 func (c *GrafanaConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "config", "grafana.ini.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -99,10 +99,14 @@ func (c *NgMonitoringConfig) ConfigWithTemplate(tpl string) ([]byte, error) {
 	return content.Bytes(), nil
 }
 
+// Template returns the path of the embedded template
+func (c *NgMonitoringConfig) Template() string {
+	return path.Join("templates", "config", "ngmonitoring.toml.tpl")
+}
+
 // Config generate the config file data.
 func (c *NgMonitoringConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "config", "ngmonitoring.toml.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
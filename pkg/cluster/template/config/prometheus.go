@@ -191,10 +191,14 @@ func (c *PrometheusConfig) SetRemoteConfig(cfg string) *PrometheusConfig {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *PrometheusConfig) Template() string {
+	return path.Join("templates", "config", "prometheus.yml.tpl")
+}
+
 // Config generate the config file data.
 func (c *PrometheusConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "config", "prometheus.yml.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -46,10 +46,14 @@ func (c *TiSparkConfig) WithCustomFields(m map[string]interface{}) *TiSparkConfi
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *TiSparkConfig) Template() string {
+	return filepath.Join("templates", "config", "spark-defaults.conf.tpl")
+}
+
 // Config generate the config file data.
 func (c *TiSparkConfig) Config() ([]byte, error) {
-	fp := filepath.Join("templates", "config", "spark-defaults.conf.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -86,10 +86,14 @@ func (c *AlertManagerScript) ConfigToFile(file string) error {
 	return os.WriteFile(file, config, 0755)
 }
 
+// Template returns the path of the embedded template
+func (c *AlertManagerScript) Template() string {
+	return path.Join("templates", "scripts", "run_alertmanager.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *AlertManagerScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_alertmanager.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
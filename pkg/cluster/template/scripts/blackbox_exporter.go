@@ -51,10 +51,14 @@ func (c *BlackboxExporterScript) WithNumaNode(numa string) *BlackboxExporterScri
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *BlackboxExporterScript) Template() string {
+	return path.Join("templates", "scripts", "run_blackbox_exporter.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *BlackboxExporterScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_blackbox_exporter.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +34,7 @@ type CDCScript struct {
 	GCTTL             int64
 	TZ                string
 	TLSEnabled        bool
+	ClusterID         string
 	Endpoints         []*PDScript
 	ConfigFileEnabled bool
 	DataDirEnabled    bool
@@ -64,6 +65,12 @@ func (c *CDCScript) WithNumaNode(numa string) *CDCScript {
 	return c
 }
 
+// WithClusterID set ClusterID field of TiCDCScript
+func (c *CDCScript) WithClusterID(id string) *CDCScript {
+	c.ClusterID = id
+	return c
+}
+
 // WithConfigFileEnabled enables config file
 func (c *CDCScript) WithConfigFileEnabled() *CDCScript {
 	c.ConfigFileEnabled = true
@@ -82,10 +89,14 @@ func (c *CDCScript) WithDataDirEnabled() *CDCScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *CDCScript) Template() string {
+	return path.Join("templates", "scripts", "run_cdc.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *CDCScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_cdc.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -122,17 +133,27 @@ func (c *CDCScript) AppendEndpoints(ends ...*PDScript) *CDCScript {
 	return c
 }
 
-// PatchByVersion update fields by cluster version
+// PatchByVersion update fields by cluster version, the flags not supported
+// by the version are not rendered
 func (c *CDCScript) PatchByVersion(clusterVersion, dataDir string) *CDCScript {
-	// config support since v4.0.13, ignore v5.0.0-rc
-	// the same to data-dir, but we treat it as --sort-dir
-	if tidbver.TiCDCSupportConfigFile(clusterVersion) {
-		c = c.WithConfigFileEnabled().WithDataDir(dataDir)
+	if dataDir != "" {
+		// config support since v4.0.13, ignore v5.0.0-rc
+		// the same to data-dir, but we treat it as --sort-dir
+		if tidbver.TiCDCSupportConfigFile(clusterVersion) {
+			c = c.WithConfigFileEnabled().WithDataDir(dataDir)
+		}
+
+		// cdc support --data-dir since v4.0.14 and v5.0.3
+		if tidbver.TiCDCSupportDataDir(clusterVersion) {
+			c = c.WithDataDirEnabled()
+		}
 	}
 
-	// cdc support --data-dir since v4.0.14 and v5.0.3
-	if tidbver.TiCDCSupportDataDir(clusterVersion) {
-		c = c.WithDataDirEnabled()
+	if !tidbver.TiCDCSupportTLS(clusterVersion) {
+		c.TLSEnabled = false
+	}
+	if !tidbver.TiCDCSupportClusterID(clusterVersion) {
+		c.ClusterID = ""
 	}
 
 	return c
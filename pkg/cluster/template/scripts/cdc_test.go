@@ -0,0 +1,69 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scripts
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+type cdcSuite struct{}
+
+var _ = Suite(&cdcSuite{})
+
+func (s *cdcSuite) TestFlagsByVersion(c *C) {
+	expected := map[string]struct {
+		flags   []string
+		noFlags []string
+	}{
+		"v4.0.2": {
+			noFlags: []string{"--ca", "--cluster-id", "--config", "--sort-dir", "--data-dir"},
+		},
+		"v4.0.13": {
+			flags:   []string{"--ca", "--config", "--sort-dir"},
+			noFlags: []string{"--cluster-id", "--data-dir"},
+		},
+		"v5.0.2": {
+			flags:   []string{"--ca", "--config", "--sort-dir"},
+			noFlags: []string{"--cluster-id", "--data-dir"},
+		},
+		"v6.1.0": {
+			flags:   []string{"--ca", "--config", "--data-dir"},
+			noFlags: []string{"--cluster-id", "--sort-dir"},
+		},
+		"v6.2.0": {
+			flags:   []string{"--ca", "--cluster-id", "--config", "--data-dir"},
+			noFlags: []string{"--sort-dir"},
+		},
+		"nightly": {
+			flags:   []string{"--ca", "--cluster-id", "--config", "--data-dir"},
+			noFlags: []string{"--sort-dir"},
+		},
+	}
+
+	for version, wanted := range expected {
+		script, err := NewCDCScript("1.1.1.1", "/home/deploy/cdc-8300", "/home/deploy/cdc-8300/log", true, 0, "").
+			WithClusterID("cluster-1").
+			PatchByVersion(version, "/home/deploy/cdc-8300/data").
+			Config()
+		c.Assert(err, IsNil)
+		for _, flag := range wanted.flags {
+			c.Assert(strings.Contains(string(script), flag), IsTrue, Commentf("%s %s", version, flag))
+		}
+		for _, flag := range wanted.noFlags {
+			c.Assert(strings.Contains(string(script), flag), IsFalse, Commentf("%s %s", version, flag))
+		}
+	}
+}
@@ -89,10 +89,14 @@ func (c *DMMasterScript) AppendEndpoints(ends ...*DMMasterScript) *DMMasterScrip
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *DMMasterScript) Template() string {
+	return path.Join("templates", "scripts", "run_dm-master.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *DMMasterScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_dm-master.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -172,10 +176,14 @@ func (c *DMMasterScaleScript) AppendEndpoints(ends ...*DMMasterScript) *DMMaster
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *DMMasterScaleScript) Template() string {
+	return path.Join("templates", "scripts", "run_dm-master_scale.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *DMMasterScaleScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_dm-master_scale.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -62,10 +62,14 @@ func (c *DMWorkerScript) AppendEndpoints(ends ...*DMMasterScript) *DMWorkerScrip
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *DMWorkerScript) Template() string {
+	return path.Join("templates", "scripts", "run_dm-worker.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *DMWorkerScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_dm-worker.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
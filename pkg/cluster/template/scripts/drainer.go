@@ -64,10 +64,14 @@ func (c *DrainerScript) AppendEndpoints(ends ...*PDScript) *DrainerScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *DrainerScript) Template() string {
+	return path.Join("templates", "scripts", "run_drainer.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *DrainerScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_drainer.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -50,14 +50,17 @@ func (c *GrafanaScript) WithTPLFile(file string) *GrafanaScript {
 	return c
 }
 
-// Config generate the config file data.
-func (c *GrafanaScript) Config() ([]byte, error) {
-	fp := c.tplName
-	if fp == "" {
-		fp = path.Join("templates", "scripts", "run_grafana.sh.tpl")
+// Template returns the path of the template
+func (c *GrafanaScript) Template() string {
+	if c.tplName != "" {
+		return c.tplName
 	}
+	return path.Join("templates", "scripts", "run_grafana.sh.tpl")
+}
 
-	tpl, err := embed.ReadTemplate(fp)
+// Config generate the config file data.
+func (c *GrafanaScript) Config() ([]byte, error) {
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
@@ -86,14 +86,17 @@ func (c *PrometheusScript) WithNG(ngPort int) *PrometheusScript {
 	return c
 }
 
-// Config generate the config file data.
-func (c *PrometheusScript) Config() ([]byte, error) {
-	fp := c.tplFile
-	if fp == "" {
-		fp = path.Join("templates", "scripts", "run_prometheus.sh.tpl")
+// Template returns the path of the template
+func (c *PrometheusScript) Template() string {
+	if c.tplFile != "" {
+		return c.tplFile
 	}
+	return path.Join("templates", "scripts", "run_prometheus.sh.tpl")
+}
 
-	tpl, err := embed.ReadTemplate(fp)
+// Config generate the config file data.
+func (c *PrometheusScript) Config() ([]byte, error) {
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
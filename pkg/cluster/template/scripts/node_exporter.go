@@ -51,10 +51,14 @@ func (c *NodeExporterScript) WithNumaNode(numa string) *NodeExporterScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *NodeExporterScript) Template() string {
+	return path.Join("templates", "scripts", "run_node_exporter.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *NodeExporterScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_node_exporter.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
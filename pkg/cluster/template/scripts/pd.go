@@ -117,13 +117,17 @@ func (c *PDScript) AppendEndpoints(ends ...*PDScript) *PDScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *PDScript) Template() string {
+	return path.Join("templates", "scripts", "run_pd.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *PDScript) Config() ([]byte, error) {
-	return c.configWithScript("run_pd.sh.tpl")
+	return c.configWithScript(c.Template())
 }
 
-func (c *PDScript) configWithScript(script string) ([]byte, error) {
-	fp := path.Join("templates", "scripts", script)
+func (c *PDScript) configWithScript(fp string) ([]byte, error) {
 	tpl, err := embed.ReadTemplate(fp)
 	if err != nil {
 		return nil, err
@@ -174,9 +178,14 @@ func NewPDScaleScript(pdScript *PDScript) *PDScaleScript {
 	return &PDScaleScript{*pdScript}
 }
 
+// Template returns the path of the embedded template
+func (c *PDScaleScript) Template() string {
+	return path.Join("templates", "scripts", "run_pd_scale.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *PDScaleScript) Config() ([]byte, error) {
-	return c.configWithScript("run_pd_scale.sh.tpl")
+	return c.configWithScript(c.Template())
 }
 
 // ConfigToFile write config content to specific path
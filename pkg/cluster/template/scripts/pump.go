@@ -65,10 +65,14 @@ func (c *PumpScript) AppendEndpoints(ends ...*PDScript) *PumpScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *PumpScript) Template() string {
+	return path.Join("templates", "scripts", "run_pump.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *PumpScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_pump.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
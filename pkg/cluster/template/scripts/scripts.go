@@ -19,8 +19,9 @@ import (
 	"github.com/pingcap/tiup/embed"
 )
 
-// GetScript returns a raw config file from embed templates
-func GetScript(filename string) ([]byte, error) {
+// GetScript returns a raw config file from embed templates, the modified one
+// in dir takes precedence
+func GetScript(dir, filename string) ([]byte, error) {
 	fp := filepath.Join("templates", "scripts", filename)
-	return embed.ReadTemplate(fp)
+	return embed.ReadTemplateFrom(dir, fp)
 }
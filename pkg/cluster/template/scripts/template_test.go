@@ -0,0 +1,54 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/template"
+)
+
+type templateSuite struct{}
+
+var _ = Suite(&templateSuite{})
+
+func (s *templateSuite) TestConfigWithTemplateDir(c *C) {
+	dir := c.MkDir()
+	pd := NewPDScript("pd-1", "1.1.1.1", "/home/deploy/pd-2379", "/home/deploy/pd-2379/data", "/home/deploy/pd-2379/log")
+	scale := NewPDScaleScript(pd)
+
+	embedded, err := template.Config(scale, "")
+	c.Assert(err, IsNil)
+	data, err := scale.Config()
+	c.Assert(err, IsNil)
+	c.Assert(data, BytesEquals, embedded)
+
+	// only the modified template of the scale script is used for it
+	c.Assert(os.MkdirAll(filepath.Join(dir, "scripts"), 0755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "scripts", "run_pd_scale.sh.tpl"), []byte("scale {{.Name}}"), 0644), IsNil)
+	data, err = template.Config(scale, dir)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "scale pd-1")
+	data, err = template.Config(pd, dir)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Not(Equals), "scale pd-1")
+
+	fp := filepath.Join(dir, "run_pd.sh")
+	c.Assert(template.ConfigToFile(scale, dir, fp), IsNil)
+	data, err = os.ReadFile(fp)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "scale pd-1")
+}
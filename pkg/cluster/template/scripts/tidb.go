@@ -92,10 +92,14 @@ func (c *TiDBScript) AppendEndpoints(ends ...*PDScript) *TiDBScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *TiDBScript) Template() string {
+	return path.Join("templates", "scripts", "run_tidb.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *TiDBScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_tidb.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
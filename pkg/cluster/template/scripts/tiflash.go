@@ -118,10 +118,14 @@ func (c *TiFlashScript) AppendEndpoints(ends ...*PDScript) *TiFlashScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *TiFlashScript) Template() string {
+	return path.Join("templates", "scripts", "run_tiflash.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *TiFlashScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_tiflash.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
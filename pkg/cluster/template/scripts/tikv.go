@@ -89,10 +89,14 @@ func (c *TiKVScript) AppendEndpoints(ends ...*PDScript) *TiKVScript {
 	return c
 }
 
+// Template returns the path of the embedded template
+func (c *TiKVScript) Template() string {
+	return path.Join("templates", "scripts", "run_tikv.sh.tpl")
+}
+
 // Config generate the config file data.
 func (c *TiKVScript) Config() ([]byte, error) {
-	fp := path.Join("templates", "scripts", "run_tikv.sh.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
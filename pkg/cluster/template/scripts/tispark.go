@@ -29,6 +29,8 @@ type TiSparkEnv struct {
 	MasterUIPort   int
 	WorkerUIPort   int
 	CustomEnvs     map[string]string
+
+	templateDir string
 }
 
 // NewTiSparkEnv returns a TiSparkConfig
@@ -42,6 +44,12 @@ func (c *TiSparkEnv) WithMaster(master string) *TiSparkEnv {
 	return c
 }
 
+// WithTemplateDir sets the directory of the modified templates
+func (c *TiSparkEnv) WithTemplateDir(dir string) *TiSparkEnv {
+	c.templateDir = dir
+	return c
+}
+
 // WithCustomEnv sets custom setting fields
 func (c *TiSparkEnv) WithCustomEnv(m map[string]string) *TiSparkEnv {
 	c.CustomEnvs = m
@@ -73,7 +81,7 @@ func (c *TiSparkEnv) WithWorkerPorts(port, ui int) *TiSparkEnv {
 
 // Script generate the script file data.
 func (c *TiSparkEnv) Script() ([]byte, error) {
-	tpl, err := GetScript("spark-env.sh.tpl")
+	tpl, err := GetScript(c.templateDir, "spark-env.sh.tpl")
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +114,7 @@ func (c *TiSparkEnv) ScriptWithTemplate(tpl string) ([]byte, error) {
 
 // SlaveScriptWithTemplate parses the template file
 func (c *TiSparkEnv) SlaveScriptWithTemplate() ([]byte, error) {
-	tpl, err := GetScript("start_tispark_slave.sh.tpl")
+	tpl, err := GetScript(c.templateDir, "start_tispark_slave.sh.tpl")
 	if err != nil {
 		return nil, err
 	}
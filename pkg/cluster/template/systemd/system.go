@@ -34,6 +34,7 @@ type Config struct {
 	DeployDir           string
 	DisableSendSigkill  bool
 	GrantCapNetRaw      bool
+	TimeZone            string
 	// Takes one of no, on-success, on-failure, on-abnormal, on-watchdog, on-abort, or always.
 	// The Template set as always if this is not setted.
 	Restart string
@@ -72,6 +73,12 @@ func (c *Config) WithIOWriteBandwidthMax(io string) *Config {
 	return c
 }
 
+// WithTimeZone set the TimeZone field of Config
+func (c *Config) WithTimeZone(tz string) *Config {
+	c.TimeZone = tz
+	return c
+}
+
 // WithLimitCORE set the LimitCORE field of Config
 func (c *Config) WithLimitCORE(core string) *Config {
 	c.LimitCORE = core
@@ -87,10 +94,14 @@ func (c *Config) ConfigToFile(file string) error {
 	return os.WriteFile(file, config, 0755)
 }
 
+// Template returns the path of the embedded template
+func (c *Config) Template() string {
+	return path.Join("templates", "systemd", "system.service.tpl")
+}
+
 // Config generate the config file data.
 func (c *Config) Config() ([]byte, error) {
-	fp := path.Join("templates", "systemd", "system.service.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
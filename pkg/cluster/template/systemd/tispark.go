@@ -58,10 +58,14 @@ func (c *TiSparkConfig) ConfigToFile(file string) error {
 	return os.WriteFile(file, config, 0755)
 }
 
+// Template returns the path of the embedded template
+func (c *TiSparkConfig) Template() string {
+	return path.Join("templates", "systemd", "tispark.service.tpl")
+}
+
 // Config generate the config file data.
 func (c *TiSparkConfig) Config() ([]byte, error) {
-	fp := path.Join("templates", "systemd", "tispark.service.tpl")
-	tpl, err := embed.ReadTemplate(fp)
+	tpl, err := embed.ReadTemplate(c.Template())
 	if err != nil {
 		return nil, err
 	}
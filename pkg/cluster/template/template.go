@@ -13,9 +13,35 @@
 
 package template
 
+import (
+	"os"
+
+	"github.com/pingcap/tiup/embed"
+)
+
 // ConfigGenerator is used to generate configuration for component
 type ConfigGenerator interface {
+	Template() string
 	Config() ([]byte, error)
 	ConfigWithTemplate(tpl string) ([]byte, error)
 	ConfigToFile(file string) error
 }
+
+// Config generates the config with the template of gen, the modified one in
+// dir takes precedence over the embedded one, see embed.ReadTemplateFrom
+func Config(gen ConfigGenerator, dir string) ([]byte, error) {
+	tpl, err := embed.ReadTemplateFrom(dir, gen.Template())
+	if err != nil {
+		return nil, err
+	}
+	return gen.ConfigWithTemplate(string(tpl))
+}
+
+// ConfigToFile write the config generated with the template in dir to file
+func ConfigToFile(gen ConfigGenerator, dir, file string) error {
+	config, err := Config(gen, dir)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, config, 0755)
+}
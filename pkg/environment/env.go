@@ -33,6 +33,8 @@ import (
 // Name of components
 const (
 	tiupName = "tiup"
+	// the binary replaced by the last self update
+	tiupBackupName = "tiup.old"
 )
 
 var (
@@ -92,6 +94,13 @@ func InitEnv(options repository.Options, mOpt repository.MirrorOptions) (*Enviro
 	// Initialize the repository
 	// Replace the mirror if some sub-commands use different mirror address
 	mirrorAddr := Mirror()
+	if mOpt.TLSConfig == nil {
+		tlsCfg, err := profile.Config.MirrorTLSConfig(mirrorAddr)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid TLS settings of mirror(%s)", mirrorAddr)
+		}
+		mOpt.TLSConfig = tlsCfg
+	}
 	mirror := repository.NewMirror(mirrorAddr, mOpt)
 	if err := mirror.Open(); err != nil {
 		return nil, err
@@ -159,16 +168,93 @@ func (env *Environment) PlatformString() string {
 	return env.v1Repo.PlatformString()
 }
 
-// SelfUpdate updates TiUP.
+// SelfUpdate updates TiUP to the version pinned in the profile, or the latest
+// version if not pinned. The tarball is verified with the hashes in the signed
+// manifest before replacing the current binary, which is kept for rollback.
 func (env *Environment) SelfUpdate() error {
-	if err := env.v1Repo.DownloadTiUP(env.LocalPath("bin")); err != nil {
+	version := env.profile.Config.TiUPVersion
+	if version != "" {
+		fmt.Printf("The version of tiup is pinned to %s\n", version)
+	}
+
+	stageDir := env.LocalPath("bin", ".tiup-update")
+	if err := os.RemoveAll(stageDir); err != nil {
+		return errors.Trace(err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	if err := env.v1Repo.DownloadTiUP(stageDir, version); err != nil {
 		return err
 	}
+	fi, err := os.Stat(filepath.Join(stageDir, tiupName))
+	if err != nil {
+		return errors.Annotate(err, "tiup binary not found in the downloaded package")
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm()&0100 == 0 {
+		return errors.Errorf("the downloaded tiup binary is not executable")
+	}
+
+	binary := env.LocalPath("bin", tiupName)
+	backup := env.LocalPath("bin", tiupBackupName)
+	if utils.IsExist(binary) {
+		if err := os.Rename(binary, backup); err != nil {
+			return errors.Annotatef(err, "backup %s", binary)
+		}
+	}
+
+	entries, err := os.ReadDir(stageDir)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(stageDir, entry.Name()), env.LocalPath("bin", entry.Name())); err != nil {
+			// restore the current binary if it's not replaced yet
+			if utils.IsNotExist(binary) && utils.IsExist(backup) {
+				_ = os.Rename(backup, binary)
+			}
+			return errors.Trace(err)
+		}
+	}
 
 	// Cover the root.json from tiup.bar.gz
 	return localdata.InitProfile().ResetMirror(Mirror(), "")
 }
 
+// SelfRollback restores the tiup binary replaced by the last SelfUpdate, the
+// current binary is kept as the backup so the rollback could be reverted
+func (env *Environment) SelfRollback() error {
+	binary := env.LocalPath("bin", tiupName)
+	backup := env.LocalPath("bin", tiupBackupName)
+	if utils.IsNotExist(backup) {
+		return errors.Errorf("no previous version of tiup found in %s", env.LocalPath("bin"))
+	}
+
+	tmp := binary + ".tmp"
+	if err := os.Rename(binary, tmp); err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.Rename(backup, binary); err != nil {
+		_ = os.Rename(tmp, binary)
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp, backup))
+}
+
+// PinTiUPVersion pins the version of tiup installed by SelfUpdate, the pinning
+// is removed if the version is empty
+func (env *Environment) PinTiUPVersion(version string) error {
+	if version != "" {
+		if !utils.Version(version).IsValid() {
+			return errors.Errorf("invalid version %s", version)
+		}
+		if _, err := env.v1Repo.ComponentVersion(repository.TiUPBinaryName, version, false); err != nil {
+			return err
+		}
+	}
+	env.profile.Config.TiUPVersion = version
+	return env.profile.Config.Flush()
+}
+
 func (env *Environment) downloadComponentv1(component string, version utils.Version, overwrite bool) error {
 	spec := repository.ComponentSpec{
 		ID:      component,
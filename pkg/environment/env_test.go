@@ -0,0 +1,172 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package environment
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestEnv creates an Environment with an empty profile and a local mirror
+// where the versions of tiup are published, the content of the tiup binary in
+// each version is the version itself
+func newTestEnv(t *testing.T, files map[string]string, versions ...string) *Environment {
+	mirrorDir := t.TempDir()
+	require.NoError(t, v1manifest.Init(mirrorDir, filepath.Join(mirrorDir, "keys"), time.Now().UTC()))
+	mirror := repository.NewMirror(mirrorDir, repository.MirrorOptions{})
+	require.NoError(t, mirror.Open())
+	t.Cleanup(func() { mirror.Close() })
+
+	ownerKey, err := v1manifest.GenKeyInfo()
+	require.NoError(t, err)
+	pubKey, err := ownerKey.Public()
+	require.NoError(t, err)
+	require.NoError(t, mirror.Grant("pingcap", "PingCAP", pubKey))
+
+	publisher := repository.NewPublisher(mirror)
+	for _, ver := range versions {
+		tarball := filepath.Join(t.TempDir(), "tiup.tar.gz")
+		content := map[string]string{tiupName: ver}
+		for name, data := range files {
+			content[name] = data
+		}
+		writeTarball(t, tarball, content)
+		_, err := publisher.Publish(tarball, repository.PublishOptions{
+			Component:  repository.TiUPBinaryName,
+			Version:    ver,
+			Entry:      tiupName,
+			OS:         runtime.GOOS,
+			Arch:       runtime.GOARCH,
+			Standalone: true,
+			Key:        ownerKey,
+		})
+		require.NoError(t, err)
+	}
+
+	home := t.TempDir()
+	t.Setenv(localdata.EnvNameHome, home)
+	t.Setenv(repository.EnvMirrors, mirrorDir)
+	profile := localdata.InitProfile()
+	require.NoError(t, os.MkdirAll(profile.Path("bin"), 0755))
+	require.NoError(t, profile.ResetMirror(mirrorDir, ""))
+
+	local, err := v1manifest.NewManifests(profile)
+	require.NoError(t, err)
+	return &Environment{profile, repository.NewV1Repo(mirror, repository.Options{}, local)}
+}
+
+func writeTarball(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(data))}))
+		_, err := tw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSelfUpdate(t *testing.T) {
+	env := newTestEnv(t, nil, "v1.0.0", "v1.1.0")
+	binary := env.LocalPath("bin", tiupName)
+	backup := env.LocalPath("bin", tiupBackupName)
+	require.NoError(t, os.WriteFile(binary, []byte("current"), 0755))
+
+	// the latest version is staged and swapped in, the current one is kept
+	require.NoError(t, env.SelfUpdate())
+	assert.Equal(t, "v1.1.0", readFile(t, binary))
+	assert.Equal(t, "current", readFile(t, backup))
+	assert.NoDirExists(t, env.LocalPath("bin", ".tiup-update"))
+
+	// the pinned version is installed instead of the latest one
+	env.profile.Config.TiUPVersion = "v1.0.0"
+	require.NoError(t, env.SelfUpdate())
+	assert.Equal(t, "v1.0.0", readFile(t, binary))
+	assert.Equal(t, "v1.1.0", readFile(t, backup))
+}
+
+func TestSelfUpdateRestoreOnFailure(t *testing.T) {
+	// the file is moved before the binary, a non-empty dir in its place fails
+	// the rename after the current binary is backed up
+	env := newTestEnv(t, map[string]string{"ctl": "ctl"}, "v1.1.0")
+	binary := env.LocalPath("bin", tiupName)
+	backup := env.LocalPath("bin", tiupBackupName)
+	require.NoError(t, os.WriteFile(binary, []byte("current"), 0755))
+	require.NoError(t, os.MkdirAll(env.LocalPath("bin", "ctl", "busy"), 0755))
+
+	assert.Error(t, env.SelfUpdate())
+	assert.Equal(t, "current", readFile(t, binary))
+	assert.NoFileExists(t, backup)
+	assert.NoDirExists(t, env.LocalPath("bin", ".tiup-update"))
+}
+
+func TestSelfRollback(t *testing.T) {
+	env := newTestEnv(t, nil)
+	binary := env.LocalPath("bin", tiupName)
+	backup := env.LocalPath("bin", tiupBackupName)
+	require.NoError(t, os.WriteFile(binary, []byte("current"), 0755))
+
+	// nothing to roll back to before any update
+	err := env.SelfRollback()
+	assert.ErrorContains(t, err, "no previous version of tiup found")
+	assert.Equal(t, "current", readFile(t, binary))
+
+	// the binaries are swapped, so the rollback could be reverted
+	require.NoError(t, os.WriteFile(backup, []byte("previous"), 0755))
+	require.NoError(t, env.SelfRollback())
+	assert.Equal(t, "previous", readFile(t, binary))
+	assert.Equal(t, "current", readFile(t, backup))
+	require.NoError(t, env.SelfRollback())
+	assert.Equal(t, "current", readFile(t, binary))
+	assert.Equal(t, "previous", readFile(t, backup))
+}
+
+func TestPinTiUPVersion(t *testing.T) {
+	env := newTestEnv(t, nil, "v1.0.0")
+
+	assert.ErrorContains(t, env.PinTiUPVersion("latest"), "invalid version latest")
+	assert.Error(t, env.PinTiUPVersion("v9.9.9"))
+	assert.Empty(t, env.profile.Config.TiUPVersion)
+
+	require.NoError(t, env.PinTiUPVersion("v1.0.0"))
+	cfg, err := localdata.InitConfig(env.profile.Root())
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", cfg.TiUPVersion)
+
+	require.NoError(t, env.PinTiUPVersion(""))
+	cfg, err = localdata.InitConfig(env.profile.Root())
+	require.NoError(t, err)
+	assert.Empty(t, cfg.TiUPVersion)
+}
@@ -28,11 +28,20 @@ type configBase struct {
 type TiUPConfig struct {
 	configBase
 	Mirror string `toml:"mirror"`
+	// TiUPVersion pins the version of tiup on this machine, `tiup update --self`
+	// installs this version instead of the latest one if it's set
+	TiUPVersion string `toml:"tiup_version,omitempty"`
+	// Workspace is the workspace switched to, it's only read from the
+	// config of the default workspace
+	Workspace string `toml:"workspace,omitempty"`
+	// MirrorTLS is the TLS settings of the HTTPS mirrors keyed by the address
+	// prefix of the mirrors, see MirrorTLS
+	MirrorTLS map[string]*MirrorTLS `toml:"mirror_tls,omitempty"`
 }
 
 // InitConfig returns a TiUPConfig struct which can flush config back to disk
 func InitConfig(root string) (*TiUPConfig, error) {
-	config := TiUPConfig{configBase{path.Join(root, "tiup.toml")}, ""}
+	config := TiUPConfig{configBase: configBase{path.Join(root, "tiup.toml")}}
 	if utils.IsNotExist(config.file) {
 		return &config, nil
 	}
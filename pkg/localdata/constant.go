@@ -66,6 +66,17 @@ const (
 	// EnvNameHome represents the environment name of tiup home directory
 	EnvNameHome = "TIUP_HOME"
 
+	// EnvNameWorkspace represents the environment name of the tiup workspace, it's
+	// ignored if TIUP_HOME is set
+	EnvNameWorkspace = "TIUP_WORKSPACE"
+
+	// WorkspaceParentDir represent the parent directory of all workspaces
+	WorkspaceParentDir = "workspaces"
+
+	// WorkspaceFilename is the file in a project directory which specifies the
+	// workspace used in the directory and its sub directories
+	WorkspaceFilename = ".tiup-workspace"
+
 	// EnvNameTelemetryStatus represents the environment name of tiup telemetry status
 	EnvNameTelemetryStatus = "TIUP_TELEMETRY_STATUS"
 
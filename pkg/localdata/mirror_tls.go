@@ -0,0 +1,171 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdata
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// MirrorTLS is the TLS settings of an HTTPS mirror, it's set in tiup.toml as
+//
+//	[mirror_tls."https://mirror.example.com"]
+//	ca_cert = "/path/to/ca-bundle.pem"
+//	fingerprint = "sha256 of the server certificate in hex"
+//	client_cert = "/path/to/client.pem"
+//	client_key = "/path/to/client-key.pem"
+//
+// If the fingerprint is pinned without a CA bundle, the certificate chain is
+// not verified and the pinned certificate is trusted, which is the usual case
+// of self-signed certificates.
+type MirrorTLS struct {
+	CACert      string `toml:"ca_cert,omitempty"`
+	Fingerprint string `toml:"fingerprint,omitempty"`
+	ClientCert  string `toml:"client_cert,omitempty"`
+	ClientKey   string `toml:"client_key,omitempty"`
+}
+
+// MirrorTLSSettings returns the TLS settings of the mirror, the settings of
+// the same scheme and host with the longest path prefix of the mirror address
+// are used, nil is returned if there is no one
+func (c *TiUPConfig) MirrorTLSSettings(addr string) *MirrorTLS {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+
+	var (
+		matched string
+		result  *MirrorTLS
+	)
+	for prefix, settings := range c.MirrorTLS {
+		p, err := url.Parse(prefix)
+		if err != nil || !strings.EqualFold(p.Scheme, u.Scheme) || !strings.EqualFold(p.Host, u.Host) {
+			continue
+		}
+		// the path must match on the boundaries of segments
+		ppath := strings.TrimSuffix(p.Path, "/")
+		if path != ppath && !strings.HasPrefix(path, ppath+"/") {
+			continue
+		}
+		if result == nil || len(ppath) > len(matched) {
+			matched, result = ppath, settings
+		}
+	}
+	return result
+}
+
+// MirrorTLSConfig returns the TLS config of the mirror, nil is returned if
+// there are no TLS settings of it
+func (c *TiUPConfig) MirrorTLSConfig(addr string) (*tls.Config, error) {
+	settings := c.MirrorTLSSettings(addr)
+	if settings == nil {
+		return nil, nil
+	}
+	return settings.TLSConfig()
+}
+
+// MirrorHTTPClient returns the HTTP client to access the mirror with its TLS
+// settings, the default client is returned if there is no one
+func (c *TiUPConfig) MirrorHTTPClient(addr string) (*http.Client, error) {
+	tlsCfg, err := c.MirrorTLSConfig(addr)
+	if err != nil || tlsCfg == nil {
+		return http.DefaultClient, err
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsCfg
+	return &http.Client{Transport: tr}, nil
+}
+
+// Abs expands the relative paths of the certificates and keys, which are
+// resolved from the current directory, as the settings are saved in the
+// profile and used everywhere
+func (s *MirrorTLS) Abs() error {
+	for _, p := range []*string{&s.CACert, &s.ClientCert, &s.ClientKey} {
+		if *p == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*p)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		*p = abs
+	}
+	return nil
+}
+
+// normalizeFingerprint strips the separators of a hex fingerprint
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "", "-", "").Replace(fp))
+}
+
+// TLSConfig builds the TLS config from the settings, the CA bundle is added
+// to the system CAs
+func (s *MirrorTLS) TLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if s.CACert != "" {
+		pem, err := os.ReadFile(s.CACert)
+		if err != nil {
+			return nil, errors.Annotate(err, "failed to read the CA bundle of mirror")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no valid certificate found in the CA bundle %s", s.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if s.ClientCert != "" || s.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(s.ClientCert, s.ClientKey)
+		if err != nil {
+			return nil, errors.Annotate(err, "failed to load the client certificate of mirror")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if s.Fingerprint != "" {
+		pinned := normalizeFingerprint(s.Fingerprint)
+		if _, err := hex.DecodeString(pinned); err != nil || len(pinned) != sha256.Size*2 {
+			return nil, errors.Errorf("invalid fingerprint %s, it should be the SHA256 of the certificate in hex", s.Fingerprint)
+		}
+		// the pinned certificate replaces the verification of the chain if
+		// no CA bundle is specified
+		cfg.InsecureSkipVerify = s.CACert == ""
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("no certificate presented by the mirror")
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if got := hex.EncodeToString(sum[:]); got != pinned {
+				return errors.Errorf("the certificate of mirror doesn't match the pinned fingerprint, got %s", got)
+			}
+			return nil
+		}
+	}
+	return cfg, nil
+}
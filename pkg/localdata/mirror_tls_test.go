@@ -0,0 +1,86 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap/check"
+)
+
+var _ = check.Suite(&mirrorTLSTestSuite{})
+
+type mirrorTLSTestSuite struct{}
+
+func (s *mirrorTLSTestSuite) TestMirrorTLSSettings(c *check.C) {
+	cfg := &TiUPConfig{MirrorTLS: map[string]*MirrorTLS{
+		"https://mirror.example.com/":        {CACert: "a.pem"},
+		"https://mirror.example.com/private": {CACert: "b.pem"},
+	}}
+	c.Assert(cfg.MirrorTLSSettings("https://mirror.example.com").CACert, check.Equals, "a.pem")
+	c.Assert(cfg.MirrorTLSSettings("https://mirror.example.com/private/v2").CACert, check.Equals, "b.pem")
+	c.Assert(cfg.MirrorTLSSettings("https://tiup-mirrors.pingcap.com"), check.IsNil)
+	c.Assert(cfg.MirrorTLSSettings("https://mirror.example.com/privateer").CACert, check.Equals, "a.pem")
+	// the scheme and host must be the same
+	c.Assert(cfg.MirrorTLSSettings("https://mirror.example.com.attacker.io/private"), check.IsNil)
+	c.Assert(cfg.MirrorTLSSettings("https://mirror.example.com:8443"), check.IsNil)
+	c.Assert(cfg.MirrorTLSSettings("http://mirror.example.com"), check.IsNil)
+	c.Assert(cfg.MirrorTLSSettings("/path/to/mirror.example.com"), check.IsNil)
+
+	settings := &MirrorTLS{CACert: "ca.pem", ClientCert: "/etc/client.pem"}
+	c.Assert(settings.Abs(), check.IsNil)
+	wd, err := os.Getwd()
+	c.Assert(err, check.IsNil)
+	c.Assert(settings.CACert, check.Equals, filepath.Join(wd, "ca.pem"))
+	c.Assert(settings.ClientCert, check.Equals, "/etc/client.pem")
+	c.Assert(settings.ClientKey, check.Equals, "")
+
+	_, err = (&MirrorTLS{Fingerprint: "not-hex"}).TLSConfig()
+	c.Assert(err, check.NotNil)
+}
+
+func (s *mirrorTLSTestSuite) TestMirrorTLSConfig(c *check.C) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	sum := sha256.Sum256(srv.Certificate().Raw)
+
+	get := func(settings *MirrorTLS) error {
+		cfg := &TiUPConfig{MirrorTLS: map[string]*MirrorTLS{srv.URL: settings}}
+		client, err := cfg.MirrorHTTPClient(srv.URL)
+		c.Assert(err, check.IsNil)
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// the certificate of the test server is self-signed
+	c.Assert(get(&MirrorTLS{}), check.NotNil)
+	c.Assert(get(&MirrorTLS{Fingerprint: hex.EncodeToString(sum[:])}), check.IsNil)
+	sum[0]++
+	c.Assert(get(&MirrorTLS{Fingerprint: hex.EncodeToString(sum[:])}), check.NotNil)
+
+	// trust the certificate with the CA bundle
+	bundle := filepath.Join(c.MkDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	c.Assert(os.WriteFile(bundle, data, 0644), check.IsNil)
+	c.Assert(get(&MirrorTLS{CACert: bundle}), check.IsNil)
+}
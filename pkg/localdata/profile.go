@@ -44,19 +44,19 @@ func NewProfile(root string, config *TiUPConfig) *Profile {
 }
 
 // InitProfile creates a new profile using environment variables and defaults.
+// The profile of the current workspace is used if TIUP_HOME is not set, the
+// default profile is used with a warning if the workspace can't be resolved,
+// e.g. the name in the .tiup-workspace file is invalid.
 func InitProfile() *Profile {
-	var profileDir string
-	switch {
-	case os.Getenv(EnvNameHome) != "":
-		profileDir = os.Getenv(EnvNameHome)
-	case DefaultTiUPHome != "":
-		profileDir = DefaultTiUPHome
-	default:
-		u, err := user.Current()
+	profileDir := os.Getenv(EnvNameHome)
+	if profileDir == "" {
+		ws, err := CurrentWorkspace()
 		if err != nil {
-			panic("cannot get current user information: " + err.Error())
+			profileDir = baseProfileDir()
+			fmt.Fprintf(os.Stderr, "WARN: cannot resolve the workspace, using the default profile %s: %s\n", profileDir, err)
+		} else {
+			profileDir = ws.Root
 		}
-		profileDir = filepath.Join(u.HomeDir, ProfileDirName)
 	}
 
 	cfg, err := InitConfig(profileDir)
@@ -66,6 +66,19 @@ func InitProfile() *Profile {
 	return NewProfile(profileDir, cfg)
 }
 
+// baseProfileDir returns the default profile directory, which is the profile
+// of the default workspace and holds the other workspaces
+func baseProfileDir() string {
+	if DefaultTiUPHome != "" {
+		return DefaultTiUPHome
+	}
+	u, err := user.Current()
+	if err != nil {
+		panic("cannot get current user information: " + err.Error())
+	}
+	return filepath.Join(u.HomeDir, ProfileDirName)
+}
+
 // Path returns a full path which is related to profile root directory
 func (p *Profile) Path(relpath ...string) string {
 	return filepath.Join(append([]string{p.root}, relpath...)...)
@@ -245,7 +258,11 @@ func (p *Profile) ResetMirror(addr, root string) error {
 	// Fetch root.json
 	var wc io.ReadCloser
 	if strings.HasPrefix(root, "http") {
-		resp, err := http.Get(root)
+		client, err := p.Config.MirrorHTTPClient(root)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Get(root)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,250 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdata
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// DefaultWorkspace is the name of the workspace using the default profile
+const DefaultWorkspace = "default"
+
+// The sources where the current workspace is specified
+const (
+	WorkspaceSourceDefault = "default"
+	WorkspaceSourceHome    = "env " + EnvNameHome
+	WorkspaceSourceEnv     = "env " + EnvNameWorkspace
+	WorkspaceSourceConfig  = "tiup workspace switch"
+)
+
+var workspaceNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Workspace is a named profile isolated from others, the mirror, installed
+// components and the data of components (e.g. clusters) are not shared
+// between the workspaces
+type Workspace struct {
+	Name string
+	Root string
+	// Source is where the workspace is specified, e.g. the path of the
+	// .tiup-workspace file
+	Source string
+}
+
+// ValidateWorkspaceName checks if the name can be used as a workspace
+func ValidateWorkspaceName(name string) error {
+	if !workspaceNameRegexp.MatchString(name) {
+		return errors.Errorf("invalid workspace name '%s', only letters, digits, '_', '.' and '-' are allowed", name)
+	}
+	return nil
+}
+
+// WorkspaceRoot returns the profile directory of the workspace
+func WorkspaceRoot(name string) string {
+	base := baseProfileDir()
+	if name == DefaultWorkspace {
+		return base
+	}
+	return filepath.Join(base, WorkspaceParentDir, name)
+}
+
+// CurrentWorkspace returns the workspace in use, it's specified by (in order):
+//  1. the TIUP_HOME environment variable, which is not a named workspace
+//  2. the TIUP_WORKSPACE environment variable
+//  3. the .tiup-workspace file in the working directory or its parents
+//  4. the workspace switched to by `tiup workspace switch`
+func CurrentWorkspace() (*Workspace, error) {
+	if home := os.Getenv(EnvNameHome); home != "" {
+		return &Workspace{Root: home, Source: WorkspaceSourceHome}, nil
+	}
+	if name := os.Getenv(EnvNameWorkspace); name != "" {
+		return newWorkspace(name, WorkspaceSourceEnv)
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		file, name, err := findWorkspaceFile(wd)
+		if err != nil {
+			return nil, err
+		}
+		if file != "" {
+			return newWorkspace(name, file)
+		}
+	}
+
+	cfg, err := InitConfig(baseProfileDir())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Workspace != "" {
+		return newWorkspace(cfg.Workspace, WorkspaceSourceConfig)
+	}
+	return newWorkspace(DefaultWorkspace, WorkspaceSourceDefault)
+}
+
+func newWorkspace(name, source string) (*Workspace, error) {
+	if err := ValidateWorkspaceName(name); err != nil {
+		return nil, errors.Annotatef(err, "specified by %s", source)
+	}
+	return &Workspace{Name: name, Root: WorkspaceRoot(name), Source: source}, nil
+}
+
+// findWorkspaceFile looks up the .tiup-workspace file from dir to the root
+// directory, the name of the workspace is the first line not commented
+func findWorkspaceFile(dir string) (string, string, error) {
+	for {
+		file := filepath.Join(dir, WorkspaceFilename)
+		if utils.IsExist(file) {
+			name, err := readWorkspaceFile(file)
+			return file, name, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+func readWorkspaceFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return line, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Trace(err)
+	}
+	return "", errors.Errorf("no workspace specified in %s", file)
+}
+
+// WriteWorkspaceFile writes the .tiup-workspace file in dir to use the
+// workspace in it
+func WriteWorkspaceFile(dir, name string) error {
+	if err := ValidateWorkspaceName(name); err != nil {
+		return err
+	}
+	content := "# The tiup workspace used in this directory, see `tiup workspace --help`\n" + name + "\n"
+	return os.WriteFile(filepath.Join(dir, WorkspaceFilename), []byte(content), 0644)
+}
+
+// ListWorkspaces returns the names of all workspaces, the default one included
+func ListWorkspaces() ([]string, error) {
+	names := []string{DefaultWorkspace}
+	entries, err := os.ReadDir(filepath.Join(baseProfileDir(), WorkspaceParentDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Trace(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateWorkspaceName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// CreateWorkspace creates the profile of a workspace, the mirror config of
+// the profile from is inherited.
+func CreateWorkspace(name string, from *Profile) (*Profile, error) {
+	if err := ValidateWorkspaceName(name); err != nil {
+		return nil, err
+	}
+	root := WorkspaceRoot(name)
+	if utils.IsExist(root) {
+		return nil, errors.Errorf("workspace %s already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	cfg, err := InitConfig(root)
+	if err != nil {
+		return nil, err
+	}
+	if from != nil {
+		cfg.Mirror = from.Config.Mirror
+		if err := cfg.Flush(); err != nil {
+			return nil, err
+		}
+		if rootJSON := from.Path("bin", "root.json"); utils.IsExist(rootJSON) {
+			if err := utils.Copy(rootJSON, filepath.Join(root, "bin", "root.json")); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewProfile(root, cfg), nil
+}
+
+// RemoveWorkspace removes the workspace and all data in it
+func RemoveWorkspace(name string) error {
+	if name == DefaultWorkspace {
+		return errors.New("the default workspace can not be removed")
+	}
+	if err := ValidateWorkspaceName(name); err != nil {
+		return err
+	}
+	root := WorkspaceRoot(name)
+	if utils.IsNotExist(root) {
+		return errors.Errorf("workspace %s not found", name)
+	}
+
+	cfg, err := InitConfig(baseProfileDir())
+	if err != nil {
+		return err
+	}
+	if cfg.Workspace == name {
+		cfg.Workspace = ""
+		if err := cfg.Flush(); err != nil {
+			return err
+		}
+	}
+	return errors.Trace(os.RemoveAll(root))
+}
+
+// SwitchWorkspace sets the workspace used when not specified by environment
+// variables or the .tiup-workspace file
+func SwitchWorkspace(name string) error {
+	if err := ValidateWorkspaceName(name); err != nil {
+		return err
+	}
+	if utils.IsNotExist(WorkspaceRoot(name)) {
+		return errors.Errorf("workspace %s not found", name)
+	}
+
+	cfg, err := InitConfig(baseProfileDir())
+	if err != nil {
+		return err
+	}
+	cfg.Workspace = name
+	if name == DefaultWorkspace {
+		cfg.Workspace = ""
+	}
+	return cfg.Flush()
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdata
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap/check"
+)
+
+var _ = check.Suite(&workspaceTestSuite{})
+
+type workspaceTestSuite struct{}
+
+func (s *workspaceTestSuite) TestCurrentWorkspace(c *check.C) {
+	base := c.MkDir()
+	project := filepath.Join(c.MkDir(), "project")
+	c.Assert(os.MkdirAll(filepath.Join(project, "sub"), 0755), check.IsNil)
+
+	defaultHome := DefaultTiUPHome
+	DefaultTiUPHome = base
+	wd, err := os.Getwd()
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Chdir(filepath.Join(project, "sub")), check.IsNil)
+	home, ws := os.Getenv(EnvNameHome), os.Getenv(EnvNameWorkspace)
+	os.Unsetenv(EnvNameHome)
+	os.Unsetenv(EnvNameWorkspace)
+	defer func() {
+		DefaultTiUPHome = defaultHome
+		_ = os.Chdir(wd)
+		os.Setenv(EnvNameHome, home)
+		os.Setenv(EnvNameWorkspace, ws)
+	}()
+
+	current, err := CurrentWorkspace()
+	c.Assert(err, check.IsNil)
+	c.Assert(current.Name, check.Equals, DefaultWorkspace)
+	c.Assert(current.Root, check.Equals, base)
+
+	_, err = CreateWorkspace("ci", nil)
+	c.Assert(err, check.IsNil)
+	_, err = CreateWorkspace("ci", nil)
+	c.Assert(err, check.NotNil)
+	_, err = CreateWorkspace("../ci", nil)
+	c.Assert(err, check.NotNil)
+	_, err = CreateWorkspace("dev", nil)
+	c.Assert(err, check.IsNil)
+
+	names, err := ListWorkspaces()
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{DefaultWorkspace, "ci", "dev"})
+
+	c.Assert(SwitchWorkspace("ci"), check.IsNil)
+	current, err = CurrentWorkspace()
+	c.Assert(err, check.IsNil)
+	c.Assert(current.Name, check.Equals, "ci")
+	c.Assert(current.Root, check.Equals, filepath.Join(base, WorkspaceParentDir, "ci"))
+
+	// the .tiup-workspace file in parent directories takes precedence
+	c.Assert(WriteWorkspaceFile(project, "dev"), check.IsNil)
+	current, err = CurrentWorkspace()
+	c.Assert(err, check.IsNil)
+	c.Assert(current.Name, check.Equals, "dev")
+	c.Assert(current.Source, check.Equals, filepath.Join(project, WorkspaceFilename))
+
+	// the default profile is used if the workspace can't be resolved
+	c.Assert(os.WriteFile(filepath.Join(project, WorkspaceFilename), []byte("../dev\n"), 0644), check.IsNil)
+	_, err = CurrentWorkspace()
+	c.Assert(err, check.NotNil)
+	c.Assert(InitProfile().Root(), check.Equals, base)
+	c.Assert(WriteWorkspaceFile(project, "dev"), check.IsNil)
+
+	os.Setenv(EnvNameWorkspace, "ci")
+	current, err = CurrentWorkspace()
+	c.Assert(err, check.IsNil)
+	c.Assert(current.Name, check.Equals, "ci")
+
+	os.Setenv(EnvNameHome, project)
+	current, err = CurrentWorkspace()
+	c.Assert(err, check.IsNil)
+	c.Assert(current.Root, check.Equals, project)
+	os.Unsetenv(EnvNameHome)
+	os.Unsetenv(EnvNameWorkspace)
+
+	c.Assert(RemoveWorkspace(DefaultWorkspace), check.NotNil)
+	c.Assert(RemoveWorkspace("ci"), check.IsNil)
+	cfg, err := InitConfig(base)
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Workspace, check.Equals, "")
+}
@@ -15,6 +15,8 @@ package logger
 
 import (
 	"bytes"
+	"os"
+	"sync"
 
 	"github.com/pingcap/tiup/pkg/cluster/audit"
 	"github.com/pingcap/tiup/pkg/utils"
@@ -27,6 +29,26 @@ var auditEnabled atomic.Bool
 var auditBuffer *bytes.Buffer
 var auditDir string
 
+// auditJournal is the file the audit log is also written to as it goes, so
+// it survives if the process is interrupted
+var auditJournal struct {
+	sync.Mutex
+	file *os.File
+}
+
+// auditWriter writes the audit log to the buffer and the journal if any
+type auditWriter struct{}
+
+func (auditWriter) Write(p []byte) (int, error) {
+	auditJournal.Lock()
+	defer auditJournal.Unlock()
+	if auditJournal.file != nil {
+		// the journal is best effort, it never fails the audit log
+		_, _ = auditJournal.file.Write(p)
+	}
+	return auditBuffer.Write(p)
+}
+
 // EnableAuditLog enables audit log.
 func EnableAuditLog(dir string) {
 	auditDir = dir
@@ -41,7 +63,45 @@ func DisableAuditLog() {
 func newAuditLogCore() zapcore.Core {
 	auditBuffer = bytes.NewBuffer([]byte{})
 	encoder := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
-	return zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(auditBuffer)), zapcore.DebugLevel)
+	return zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(auditWriter{})), zapcore.DebugLevel)
+}
+
+// StartAuditJournal writes the audit log to the journal file as it goes, the
+// journal is in the format of audit logs and can be replayed as one
+func StartAuditJournal(file string) error {
+	if !auditEnabled.Load() {
+		return nil
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	auditJournal.Lock()
+	defer auditJournal.Unlock()
+	if _, err := f.WriteString(audit.CommandLine(os.Args)); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(auditBuffer.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if auditJournal.file != nil {
+		auditJournal.file.Close()
+	}
+	auditJournal.file = f
+	return nil
+}
+
+// StopAuditJournal stops writing the audit log to the journal file
+func StopAuditJournal() {
+	auditJournal.Lock()
+	defer auditJournal.Unlock()
+	if auditJournal.file != nil {
+		auditJournal.file.Close()
+		auditJournal.file = nil
+	}
 }
 
 // OutputAuditLogToFileIfEnabled outputs audit log to specified fileSuffix if enabled.
@@ -14,6 +14,7 @@
 package logprinter
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -94,3 +95,23 @@ func (l *Logger) Errorf(format string, args ...interface{}) {
 	zap.L().Error(fmt.Sprintf(format, args...))
 	printLog(l.stderr, l.outputFmt, "error", format, args...)
 }
+
+// Eventf outputs an event with its payload to console, it's printed as a JSON
+// object of the event in JSON display mode, or as the message otherwise
+func (l *Logger) Eventf(event string, payload interface{}, format string, args ...interface{}) {
+	zap.L().Info(fmt.Sprintf(format, args...), zap.String("event", event), zap.Any("payload", payload))
+	if l.outputFmt == DisplayModeJSON {
+		data, err := json.Marshal(struct {
+			Event   string      `json:"event"`
+			Payload interface{} `json:"payload"`
+		}{
+			Event:   event,
+			Payload: payload,
+		})
+		if err == nil {
+			_, _ = fmt.Fprint(l.stdout, string(data)+"\n")
+			return
+		}
+	}
+	printLog(l.stdout, l.outputFmt, "info", format, args...)
+}
@@ -23,6 +23,9 @@ type DirPaths struct {
 	Data   []string
 	Log    string
 	Cache  string
+	// Templates is the directory of the modified templates of the cluster,
+	// they take precedence over the embedded ones
+	Templates string
 }
 
 // String implements the fmt.Stringer interface
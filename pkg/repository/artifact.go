@@ -0,0 +1,79 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/localdata"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+)
+
+// isAbsoluteURL checks if the location is out of the mirror
+func isAbsoluteURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// downloadAbsoluteURL downloads the file from a location out of the mirror,
+// e.g. object storage or CDN, with the TLS settings of the location
+func downloadAbsoluteURL(location, target string) error {
+	tlsCfg, err := localdata.InitProfile().Config.MirrorTLSConfig(location)
+	if err != nil {
+		return errors.Annotatef(err, "invalid TLS settings of %s", location)
+	}
+	m := &httpMirror{options: MirrorOptions{Progress: &ProgressBar{}, TLSConfig: tlsCfg}}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	r, err := m.download(location, target, 0)
+	if err != nil {
+		return err
+	}
+	return r.Close()
+}
+
+// fetchArtifact downloads the file of item to targetDir/item.URL, the
+// locations of the item are tried in order until one of them returns the file
+// passing the validation.
+func fetchArtifact(mirror Mirror, item *v1manifest.VersionItem, targetDir string, validate func(path string) error) error {
+	target := filepath.Join(targetDir, item.URL)
+	urls := item.DownloadURLs()
+
+	var lastErr error
+	for i, location := range urls {
+		var err error
+		if isAbsoluteURL(location) {
+			err = downloadAbsoluteURL(location, target)
+		} else if err = mirror.Download(location, targetDir); err == nil && location != item.URL {
+			err = os.Rename(filepath.Join(targetDir, location), target)
+		}
+		if err == nil {
+			if err = validate(target); err == nil {
+				return nil
+			}
+			// remove the file to avoid attacking
+			_ = os.Remove(target)
+		}
+
+		lastErr = err
+		if i < len(urls)-1 {
+			logprinter.Warnf("Failed to download %s from %s: %s, trying the next location...", item.URL, location, err)
+		}
+	}
+	return lastErr
+}
@@ -391,15 +391,13 @@ func download(targetDir, tmpDir string, repo *V1Repository, item *v1manifest.Ver
 		}
 	}
 
-	err := repo.Mirror().Download(item.URL, tmpDir)
+	err := fetchArtifact(repo.Mirror(), item, tmpDir, func(string) error {
+		return validate(tmpDir)
+	})
 	if err != nil {
 		return err
 	}
 
-	if err := validate(tmpDir); err != nil {
-		return err
-	}
-
 	// Move file to target directory if hashes pass verify.
 	return os.Rename(tmpFile, dstFile)
 }
@@ -15,6 +15,8 @@ package repository
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	stderrors "errors"
 	"fmt"
@@ -69,6 +71,9 @@ type (
 		Progress DownloadProgress
 		Upstream string
 		KeyDir   string
+		// TLSConfig is used to access the HTTPS mirror, e.g. with a private
+		// CA or a pinned certificate, see localdata.MirrorTLS
+		TLSConfig *tls.Config
 	}
 
 	// Mirror represents a repository mirror, which can be remote HTTP
@@ -277,6 +282,17 @@ func (l *httpMirror) Source() string {
 	return l.server
 }
 
+// client returns the HTTP client with the TLS config of the mirror
+func (l *httpMirror) client(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if l.options.TLSConfig != nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = l.options.TLSConfig
+		client.Transport = tr
+	}
+	return client
+}
+
 // Open implements the Mirror interface
 func (l *httpMirror) Open() error {
 	tmpDir := filepath.Join(os.TempDir(), strconv.Itoa(rand.Int()))
@@ -293,6 +309,9 @@ func (l *httpMirror) download(url string, to string, maxSize int64) (io.ReadClos
 	}(time.Now())
 
 	client := grab.NewClient()
+	if l.options.TLSConfig != nil {
+		client.HTTPClient = l.client(0)
+	}
 	client.UserAgent = fmt.Sprintf("tiup/%s", version.NewTiUPVersion().SemVer())
 	req, err := grab.NewRequest(to, url)
 	if err != nil {
@@ -372,7 +391,7 @@ func (l *httpMirror) Rotate(m *v1manifest.Manifest) error {
 		return errors.Annotate(err, "marshal root manifest")
 	}
 
-	client := http.Client{Timeout: time.Minute}
+	client := l.client(time.Minute)
 	resp, err := client.Post(rotateAddr, "text/json", bytes.NewBuffer(data))
 	if err != nil {
 		return err
@@ -403,7 +422,7 @@ func (l *httpMirror) Publish(manifest *v1manifest.Manifest, info model.Component
 
 	if info.Filename() != "" {
 		tarAddr := fmt.Sprintf("%s/api/v1/tarball/%s", l.Source(), sid)
-		resp, err := utils.PostFile(info, tarAddr, "file", info.Filename())
+		resp, err := utils.PostFile(l.client(0), info, tarAddr, "file", info.Filename())
 		if err != nil {
 			return err
 		}
@@ -435,7 +454,7 @@ func (l *httpMirror) Publish(manifest *v1manifest.Manifest, info model.Component
 	}
 	manifestAddr := fmt.Sprintf("%s/api/v1/component/%s/%s%s", l.Source(), sid, manifest.Signed.(*v1manifest.Component).ID, qstr)
 
-	client := http.Client{Timeout: time.Minute}
+	client := l.client(time.Minute)
 	resp, err := client.Post(manifestAddr, "text/json", bodyBuf)
 	if err != nil {
 		return err
@@ -483,7 +502,7 @@ func (l *httpMirror) Download(resource, targetDir string) error {
 	// deleted at Close(), in this way an interrupted download won't remain
 	// any partial file on the disk
 	var err error
-	_ = utils.Retry(func() error {
+	_ = utils.Wait(context.Background(), func() error {
 		var r io.ReadCloser
 		if err != nil && l.isRetryable(err) {
 			logprinter.Warnf("failed to download %s(%s), retrying...", resource, err.Error())
@@ -496,7 +515,7 @@ func (l *httpMirror) Download(resource, targetDir string) error {
 			return nil
 		}
 		return r.Close()
-	}, utils.RetryOption{
+	}, utils.WaitOption{
 		Timeout:  time.Hour,
 		Attempts: 3,
 	})
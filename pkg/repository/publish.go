@@ -0,0 +1,238 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/repository/model"
+	ru "github.com/pingcap/tiup/pkg/repository/utils"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// PublishOptions is the options to publish a version of a component
+type PublishOptions struct {
+	Component   string
+	Version     string
+	Entry       string
+	Description string
+	OS          string
+	Arch        string
+	// Standalone and Hidden are only applied when creating the component
+	Standalone bool
+	Hidden     bool
+	// Key is the private key of the owner of the component
+	Key *v1manifest.KeyInfo
+	// URLs are the additional locations of the tarball, e.g. on object
+	// storage or CDN, the clients try them before the mirror
+	URLs []string
+	// Requires is the requirements on the hosts running the version
+	Requires *v1manifest.HostRequirements
+	// Attempts is the max attempts on the conflicts with other publishing,
+	// 10 by default
+	Attempts int
+}
+
+// PublishResult is the result of a successful publishing
+type PublishResult struct {
+	// Created is true if the component is created by the publishing
+	Created  bool
+	Manifest *v1manifest.Component
+}
+
+// Publisher publishes components to a mirror, it does what the
+// `tiup mirror publish` command does: add the version to the component
+// manifest, sign it with the owner key, upload the tarball, and let the
+// mirror bump the index, snapshot and timestamp manifests.
+type Publisher struct {
+	mirror Mirror
+	// fetchManifest returns the current manifest of the component, nil if
+	// the component does not exist
+	fetchManifest func(component string) (*v1manifest.Component, error)
+}
+
+// NewPublisher creates a Publisher to the mirror, the current manifests of the
+// components are read from the mirror as is.
+func NewPublisher(mirror Mirror) *Publisher {
+	return &Publisher{
+		mirror: mirror,
+		fetchManifest: func(component string) (*v1manifest.Component, error) {
+			return fetchComponentManifestFromMirror(mirror, component)
+		},
+	}
+}
+
+// Publisher creates a Publisher to the mirror of the repository, the current
+// manifests of the components are verified with the local trusted manifests.
+func (r *V1Repository) Publisher() *Publisher {
+	return &Publisher{
+		mirror: r.Mirror(),
+		fetchManifest: func(component string) (*v1manifest.Component, error) {
+			r.PurgeTimestamp()
+			m, err := r.FetchComponentManifest(component, true)
+			if errors.Cause(err) == ErrUnknownComponent {
+				return nil, nil
+			}
+			return m, err
+		},
+	}
+}
+
+// ValidatePlatform checks if the platform is supported by the mirror
+func ValidatePlatform(goos, goarch string) error {
+	// Only support any/any, don't support linux/any, any/amd64 .etc.
+	if goos == "any" && goarch == "any" {
+		return nil
+	}
+
+	switch goos + "/" + goarch {
+	case "linux/amd64",
+		"linux/arm64",
+		"darwin/amd64",
+		"darwin/arm64":
+		return nil
+	default:
+		return errors.Errorf("platform %s/%s not supported", goos, goarch)
+	}
+}
+
+// LoadPrivateKey reads the private key saved by `tiup mirror genkey`
+func LoadPrivateKey(path string) (*v1manifest.KeyInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ki := v1manifest.KeyInfo{}
+	if err := json.NewDecoder(f).Decode(&ki); err != nil {
+		return nil, errors.Annotate(err, "decode key")
+	}
+	return &ki, nil
+}
+
+// Publish publishes the tarball as the version of the component, it's retried
+// if the manifests are changed by others during the publishing.
+func (p *Publisher) Publish(tarpath string, opt PublishOptions) (*PublishResult, error) {
+	if opt.Component == "" || opt.Version == "" || opt.Entry == "" {
+		return nil, errors.New("component, version and entry must be specified")
+	}
+	if opt.Key == nil {
+		return nil, errors.New("the private key must be specified")
+	}
+	if err := ValidatePlatform(opt.OS, opt.Arch); err != nil {
+		return nil, err
+	}
+
+	hashes, length, err := ru.HashFile(tarpath)
+	if err != nil {
+		return nil, err
+	}
+	logprinter.Infof("uploading %s with %d bytes, sha256: %v ...", tarpath, length, hashes[v1manifest.SHA256])
+
+	tarfile, err := os.Open(tarpath)
+	if err != nil {
+		return nil, errors.Annotatef(err, "open tarball: %s", tarpath)
+	}
+	defer tarfile.Close()
+
+	publishInfo := &model.PublishInfo{
+		ComponentData: &model.TarInfo{
+			Reader: tarfile,
+			Name:   fmt.Sprintf("%s-%s-%s-%s.tar.gz", opt.Component, opt.Version, opt.OS, opt.Arch),
+		},
+	}
+	fileHash := v1manifest.FileHash{
+		Hashes: hashes,
+		Length: uint(length),
+	}
+
+	attempts := opt.Attempts
+	if attempts <= 0 {
+		attempts = 10
+	}
+
+	var result *PublishResult
+	var reqErr error
+	pubErr := utils.Wait(context.Background(), func() error {
+		res, err := p.publish(opt, publishInfo, fileHash)
+		if err != nil {
+			// retry if the error is manifest too old or validation failed
+			if err == ErrManifestTooOld ||
+				stderrors.Is(errors.Cause(err), utils.ErrValidateChecksum) ||
+				strings.Contains(err.Error(), "INVALID TARBALL") {
+				logprinter.Infof("server returned an error: %s, retry...", err)
+				if _, ferr := tarfile.Seek(0, 0); ferr != nil { // reset the reader
+					return ferr
+				}
+				return err // return err to trigger next retry
+			}
+			reqErr = err // keep the error info
+		}
+		result = res
+		return nil // return nil to end the retry loop
+	}, utils.WaitOption{
+		Attempts: int64(attempts),
+		Interval: time.Second * 2,
+		Timeout:  time.Minute * 10,
+	})
+	if reqErr != nil {
+		return nil, reqErr
+	}
+	if pubErr != nil {
+		return nil, pubErr
+	}
+	return result, nil
+}
+
+func (p *Publisher) publish(opt PublishOptions, publishInfo *model.PublishInfo, fileHash v1manifest.FileHash) (*PublishResult, error) {
+	m, err := p.fetchManifest(opt.Component)
+	if err != nil {
+		return nil, err
+	}
+
+	created := m == nil
+	if created {
+		logprinter.Infof("Creating component %s", opt.Component)
+		publishInfo.Stand = &opt.Standalone
+		publishInfo.Hide = &opt.Hidden
+	}
+
+	m = UpdateManifestForPublish(m, opt.Component, opt.Version, opt.Entry, opt.OS, opt.Arch, opt.Description, fileHash)
+	if len(opt.URLs) > 0 || opt.Requires != nil {
+		platform := fmt.Sprintf("%s/%s", opt.OS, opt.Arch)
+		item := m.Platforms[platform][opt.Version]
+		item.URLs = opt.URLs
+		item.Requires = opt.Requires
+		m.Platforms[platform][opt.Version] = item
+	}
+	manifest, err := v1manifest.SignManifest(m, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.mirror.Publish(manifest, publishInfo); err != nil {
+		return nil, err
+	}
+	return &PublishResult{Created: created, Manifest: m}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublisher(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tiup-mirror-*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, v1manifest.Init(dir, filepath.Join(dir, "keys"), time.Now().UTC()))
+	mirror := NewMirror(dir, MirrorOptions{})
+	assert.Nil(t, mirror.Open())
+	defer mirror.Close()
+
+	ownerKey, err := v1manifest.GenKeyInfo()
+	assert.Nil(t, err)
+	pubKey, err := ownerKey.Public()
+	assert.Nil(t, err)
+	assert.Nil(t, mirror.Grant("test", "Test", pubKey))
+
+	tarball := filepath.Join(t.TempDir(), "foo.tar.gz")
+	assert.Nil(t, os.WriteFile(tarball, []byte("foo"), 0644))
+
+	opt := PublishOptions{
+		Component: "foo",
+		Version:   "v1.0.0",
+		Entry:     "foo",
+		OS:        "linux",
+		Arch:      "amd64",
+		Key:       ownerKey,
+	}
+	publisher := NewPublisher(mirror)
+
+	// invalid platform
+	opt.Arch = "mips"
+	_, err = publisher.Publish(tarball, opt)
+	assert.NotNil(t, err)
+
+	opt.Arch = "amd64"
+	result, err := publisher.Publish(tarball, opt)
+	assert.Nil(t, err)
+	assert.True(t, result.Created)
+
+	opt.Version = "v1.0.1"
+	result, err = publisher.Publish(tarball, opt)
+	assert.Nil(t, err)
+	assert.False(t, result.Created)
+	assert.Len(t, result.Manifest.Platforms["linux/amd64"], 2)
+
+	report, err := VerifyMirror(dir, VerifyOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.OK(), "%+v", report.Errors)
+	assert.Equal(t, 1, report.Components)
+	assert.Equal(t, 2, report.Tarballs)
+	assert.Empty(t, report.Orphans)
+}
@@ -484,7 +484,18 @@ func (r *V1Repository) updateComponentManifest(id string, withYanked bool) (*v1m
 // the component will be removed if hash is not correct
 func (r *V1Repository) DownloadComponent(item *v1manifest.VersionItem, target string) error {
 	targetDir := filepath.Dir(target)
-	err := r.mirror.Download(item.URL, targetDir)
+	err := fetchArtifact(r.mirror, item, targetDir, func(downloaded string) error {
+		reader, err := os.Open(downloaded)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		if _, err := checkHash(reader, item.Hashes[v1manifest.SHA256]); err != nil {
+			return errors.Errorf("validation failed for %s: %s", target, err)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -496,19 +507,6 @@ func (r *V1Repository) DownloadComponent(item *v1manifest.VersionItem, target st
 			return err
 		}
 	}
-
-	reader, err := os.Open(target)
-	if err != nil {
-		return err
-	}
-
-	_, err = checkHash(reader, item.Hashes[v1manifest.SHA256])
-	reader.Close()
-	if err != nil {
-		// remove the target compoonent to avoid attacking
-		_ = os.Remove(target)
-		return errors.Errorf("validation failed for %s: %s", target, err)
-	}
 	return nil
 }
 
@@ -685,13 +683,14 @@ func (r *V1Repository) FetchIndexManifest() (index *v1manifest.Index, err error)
 	return index, nil
 }
 
-// DownloadTiUP downloads the tiup tarball and expands it into targetDir
-func (r *V1Repository) DownloadTiUP(targetDir string) error {
+// DownloadTiUP downloads the tiup tarball and expands it into targetDir, the
+// latest version is downloaded if version is empty
+func (r *V1Repository) DownloadTiUP(targetDir, version string) error {
 	var spec = ComponentSpec{
 		TargetDir: targetDir,
 		ID:        TiUPBinaryName,
-		Version:   "",
-		Force:     false,
+		Version:   version,
+		Force:     true,
 	}
 	return r.UpdateComponents([]ComponentSpec{spec})
 }
@@ -17,6 +17,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/tiup/pkg/utils"
@@ -110,10 +112,117 @@ type VersionItem struct {
 	Entry        string            `json:"entry"`
 	Released     string            `json:"released"`
 	Dependencies map[string]string `json:"dependencies"`
+	// URLs are the locations to download the file, which are tried in order
+	// before URL. They could be relative to the mirror or absolute (e.g. on
+	// object storage or CDN), the file is verified by the hashes anyway.
+	URLs []string `json:"urls,omitempty"`
+	// Requires is the requirements on the hosts running the version
+	Requires *HostRequirements `json:"requires,omitempty"`
 
 	FileHash
 }
 
+// HostRequirements is the requirements of a version of a component on the
+// hosts, the OS and architecture are declared by the platform of the version
+type HostRequirements struct {
+	// Libc is the C library the binaries are linked with, glibc or musl
+	Libc string `json:"libc,omitempty"`
+	// MinLibcVersion is the minimum version of the C library, e.g. 2.28
+	MinLibcVersion string `json:"min_libc_version,omitempty"`
+	// MinKernelVersion is the minimum version of the kernel, e.g. 3.10
+	MinKernelVersion string `json:"min_kernel_version,omitempty"`
+}
+
+// HostFacts is the information of a host to check the requirements with
+type HostFacts struct {
+	Libc          string
+	LibcVersion   string
+	KernelVersion string
+}
+
+// Check returns the unmet requirements of the host, empty if the host meets
+// all of them. The facts unknown are not checked.
+func (r *HostRequirements) Check(facts HostFacts) []string {
+	if r == nil {
+		return nil
+	}
+
+	var unmet []string
+	libc := r.Libc
+	if libc == "" {
+		libc = "glibc"
+	}
+	if r.Libc != "" && facts.Libc != "" && r.Libc != facts.Libc {
+		unmet = append(unmet, fmt.Sprintf("requires %s but host has %s", r.Libc, facts.Libc))
+	} else if r.MinLibcVersion != "" && facts.LibcVersion != "" &&
+		compareDottedVersion(facts.LibcVersion, r.MinLibcVersion) < 0 {
+		unmet = append(unmet, fmt.Sprintf("requires %s >= %s but host has %s", libc, r.MinLibcVersion, facts.LibcVersion))
+	}
+	if r.MinKernelVersion != "" && facts.KernelVersion != "" &&
+		compareDottedVersion(facts.KernelVersion, r.MinKernelVersion) < 0 {
+		unmet = append(unmet, fmt.Sprintf("requires kernel >= %s but host has %s", r.MinKernelVersion, facts.KernelVersion))
+	}
+	return unmet
+}
+
+// compareDottedVersion compares the leading numeric parts of versions like
+// 2.17 and 3.10.0-1160.el7.x86_64
+func compareDottedVersion(a, b string) int {
+	pa, pb := dottedVersionParts(a), dottedVersionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func dottedVersionParts(v string) []int {
+	var parts []int
+	for _, s := range strings.Split(strings.TrimPrefix(v, "v"), ".") {
+		end := 0
+		for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, _ := strconv.Atoi(s[:end])
+		parts = append(parts, n)
+		if end < len(s) {
+			break
+		}
+	}
+	return parts
+}
+
+// DownloadURLs returns the locations to download the file in order
+func (v *VersionItem) DownloadURLs() []string {
+	urls := make([]string, 0, len(v.URLs)+1)
+	hasURL := false
+	for _, url := range v.URLs {
+		if url == v.URL {
+			hasURL = true
+		}
+		urls = append(urls, url)
+	}
+	// the file on the mirror is always the last resort
+	if !hasURL {
+		urls = append(urls, v.URL)
+	}
+	return urls
+}
+
 // Component manifest.
 type Component struct {
 	SignedBase
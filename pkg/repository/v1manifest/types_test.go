@@ -119,3 +119,35 @@ func TestLoadManifestError(t *testing.T) {
 	assert.True(t, errors.Is(err1, err2))
 	assert.False(t, errors.Is(err2, err1))
 }
+
+func TestVersionItemDownloadURLs(t *testing.T) {
+	item := VersionItem{URL: "/tidb-v6.1.0-linux-amd64.tar.gz"}
+	assert.Equal(t, []string{"/tidb-v6.1.0-linux-amd64.tar.gz"}, item.DownloadURLs())
+
+	item.URLs = []string{"https://cdn.example.com/tidb-v6.1.0-linux-amd64.tar.gz"}
+	assert.Equal(t, []string{
+		"https://cdn.example.com/tidb-v6.1.0-linux-amd64.tar.gz",
+		"/tidb-v6.1.0-linux-amd64.tar.gz",
+	}, item.DownloadURLs())
+
+	// the mirror is tried in the specified order
+	item.URLs = []string{"/tidb-v6.1.0-linux-amd64.tar.gz", "https://cdn.example.com/tidb-v6.1.0-linux-amd64.tar.gz"}
+	assert.Equal(t, item.URLs, item.DownloadURLs())
+}
+
+func TestHostRequirementsCheck(t *testing.T) {
+	var none *HostRequirements
+	assert.Equal(t, 0, len(none.Check(HostFacts{Libc: "glibc", LibcVersion: "2.17"})))
+
+	req := &HostRequirements{MinLibcVersion: "2.28", MinKernelVersion: "3.10"}
+	assert.Equal(t, 0, len(req.Check(HostFacts{Libc: "glibc", LibcVersion: "2.28", KernelVersion: "4.18.0-348.el8.x86_64"})))
+	assert.Equal(t, 0, len(req.Check(HostFacts{})))
+	assert.Equal(t, []string{
+		"requires glibc >= 2.28 but host has 2.17",
+		"requires kernel >= 3.10 but host has 3.2.0-4-amd64",
+	}, req.Check(HostFacts{Libc: "glibc", LibcVersion: "2.17", KernelVersion: "3.2.0-4-amd64"}))
+
+	req = &HostRequirements{Libc: "musl", MinLibcVersion: "1.2"}
+	assert.Equal(t, []string{"requires musl but host has glibc"}, req.Check(HostFacts{Libc: "glibc", LibcVersion: "2.31"}))
+	assert.Equal(t, 0, len(req.Check(HostFacts{Libc: "musl", LibcVersion: "1.2.2"})))
+}
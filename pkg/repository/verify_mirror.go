@@ -0,0 +1,483 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	ru "github.com/pingcap/tiup/pkg/repository/utils"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// VerifyOptions is the options of verifying a local mirror
+type VerifyOptions struct {
+	// ExpiryWarning reports the manifests expiring in the duration as warnings
+	ExpiryWarning time.Duration
+}
+
+// VerifyIssue is a problem found in the mirror
+type VerifyIssue struct {
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// VerifyReport is the result of verifying a local mirror
+type VerifyReport struct {
+	Mirror      string        `json:"mirror"`
+	RootVersion uint          `json:"root_version"`
+	Components  int           `json:"components"`
+	Tarballs    int           `json:"tarballs"`
+	Errors      []VerifyIssue `json:"errors"`
+	Warnings    []VerifyIssue `json:"warnings"`
+	Orphans     []string      `json:"orphans"`
+}
+
+// OK returns true if no error is found in the mirror, the warnings and
+// orphan files do not break the clients
+func (r *VerifyReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *VerifyReport) addError(file, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, VerifyIssue{File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *VerifyReport) addWarning(file, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, VerifyIssue{File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+// mirrorVerifier walks through a local mirror as a client does, the files
+// read are recorded to find the orphans
+type mirrorVerifier struct {
+	dir        string
+	opt        VerifyOptions
+	report     *VerifyReport
+	keys       *v1manifest.KeyStore
+	referenced set.StringSet
+}
+
+// VerifyMirror validates the local mirror in dir: the signature chain from the
+// first root manifest to every component manifest, the hash and length of
+// every tarball, the expiry of manifests and the files not referenced. The
+// problems found are collected in the report instead of returned as errors.
+func VerifyMirror(dir string, opt VerifyOptions) (*VerifyReport, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if !fi.IsDir() {
+		return nil, errors.Errorf("local mirror `%s` should be a directory", dir)
+	}
+
+	v := &mirrorVerifier{
+		dir:        dir,
+		opt:        opt,
+		report:     &VerifyReport{Mirror: dir},
+		keys:       v1manifest.NewKeyStore(),
+		referenced: set.NewStringSet(),
+	}
+
+	// the later steps depend on the keys loaded by the previous ones
+	if !v.verifyRoot() {
+		return v.report, nil
+	}
+	snapshot := v.verifyTimestampAndSnapshot()
+	if snapshot == nil {
+		return v.report, nil
+	}
+	index := v.verifyIndex(snapshot)
+	if index == nil {
+		return v.report, nil
+	}
+	v.verifyComponents(snapshot, index)
+	if err := v.findOrphans(); err != nil {
+		return nil, err
+	}
+	return v.report, nil
+}
+
+func (v *mirrorVerifier) read(fname string) ([]byte, bool) {
+	v.referenced.Insert(fname)
+	data, err := os.ReadFile(filepath.Join(v.dir, fname))
+	if err != nil {
+		if os.IsNotExist(err) {
+			v.report.addError(fname, "file not found")
+		} else {
+			v.report.addError(fname, "failed to read: %s", err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// checkManifestError records the error of reading a manifest, the expired
+// manifests are still usable to verify the following ones
+func (v *mirrorVerifier) checkManifestError(fname string, err error) bool {
+	if err == nil {
+		return true
+	}
+	if v1manifest.IsExpirationError(errors.Cause(err)) {
+		v.report.addError(fname, "%s", errors.Cause(err))
+		return true
+	}
+	v.report.addError(fname, "invalid manifest: %s", err)
+	return false
+}
+
+func (v *mirrorVerifier) checkExpiry(fname string, base *v1manifest.SignedBase) {
+	expires, err := time.Parse(time.RFC3339, base.Expires)
+	if err != nil {
+		v.report.addError(fname, "invalid expiry time %s", base.Expires)
+		return
+	}
+	now := time.Now()
+	if expires.Before(now) {
+		// expired manifests except root are reported when reading them
+		if base.Ty == v1manifest.ManifestTypeRoot {
+			v.report.addError(fname, "manifest has expired at %s", base.Expires)
+		}
+		return
+	}
+	if v.opt.ExpiryWarning > 0 && expires.Before(now.Add(v.opt.ExpiryWarning)) {
+		v.report.addWarning(fname, "manifest expires at %s", base.Expires)
+	}
+}
+
+// verifyRoot verifies the chain of the root manifests from 1.root.json, every
+// version must be signed by the keys of the previous one
+func (v *mirrorVerifier) verifyRoot() bool {
+	first := v1manifest.RootManifestFilename(1)
+	if utils.IsNotExist(filepath.Join(v.dir, first)) {
+		v.report.addWarning(first, "file not found, the root manifest is trusted without verifying the chain")
+		first = v1manifest.ManifestFilenameRoot
+	}
+	data, ok := v.read(first)
+	if !ok {
+		return false
+	}
+
+	// bootstrap the key store with the keys in the first root manifest, which
+	// must be signed by itself
+	var root v1manifest.Root
+	if _, err := v1manifest.ReadNoVerify(bytes.NewReader(data), &root); err != nil {
+		v.report.addError(first, "invalid manifest: %s", err)
+		return false
+	}
+	if !v.loadRootKeys(first, &root) {
+		return false
+	}
+	if _, err := v1manifest.ReadManifest(bytes.NewReader(data), &root, v.keys); err != nil {
+		v.report.addError(first, "invalid manifest: %s", err)
+		return false
+	}
+
+	latest := root
+	for ver := root.Version + 1; ; ver++ {
+		fname := v1manifest.RootManifestFilename(ver)
+		if utils.IsNotExist(filepath.Join(v.dir, fname)) {
+			break
+		}
+		data, ok := v.read(fname)
+		if !ok {
+			return false
+		}
+		var next v1manifest.Root
+		if _, err := v1manifest.ReadManifest(bytes.NewReader(data), &next, v.keys); err != nil {
+			v.report.addError(fname, "invalid manifest: %s", err)
+			return false
+		}
+		if next.Version != ver {
+			v.report.addError(fname, "root version is %d, but should be %d", next.Version, ver)
+			return false
+		}
+		if err := v1manifest.ExpiresAfter(&next, &latest); err != nil {
+			v.report.addError(fname, "%s", err)
+		}
+		// the next version must be signed by the keys of this one
+		if !v.loadRootKeys(fname, &next) {
+			return false
+		}
+		latest = next
+	}
+
+	// the unversioned root.json must be the latest one of the chain
+	if first != v1manifest.ManifestFilenameRoot {
+		data, ok := v.read(v1manifest.ManifestFilenameRoot)
+		if !ok {
+			return false
+		}
+		var current v1manifest.Root
+		if _, err := v1manifest.ReadManifest(bytes.NewReader(data), &current, v.keys); err != nil {
+			v.report.addError(v1manifest.ManifestFilenameRoot, "invalid manifest: %s", err)
+			return false
+		}
+		if current.Version != latest.Version {
+			v.report.addError(v1manifest.ManifestFilenameRoot, "root version is %d, but the latest one is %d",
+				current.Version, latest.Version)
+		}
+	}
+
+	v.checkExpiry(v1manifest.RootManifestFilename(latest.Version), &latest.SignedBase)
+	v.report.RootVersion = latest.Version
+	return true
+}
+
+// loadRootKeys replaces the keys of all roles in the key store with the ones
+// declared in root
+func (v *mirrorVerifier) loadRootKeys(fname string, root *v1manifest.Root) bool {
+	for name, role := range root.Roles {
+		if err := v.keys.AddKeys(name, role.Threshold, root.Expires, role.Keys); err != nil {
+			v.report.addError(fname, "invalid keys of role %s: %s", name, err)
+			return false
+		}
+	}
+	return true
+}
+
+// checkFileHash checks the hashes and length of fname
+func (v *mirrorVerifier) checkFileHash(fname string, expected v1manifest.FileHash) bool {
+	v.referenced.Insert(fname)
+	hashes, length, err := ru.HashFile(filepath.Join(v.dir, fname))
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			v.report.addError(fname, "file not found")
+		} else {
+			v.report.addError(fname, "failed to read: %s", err)
+		}
+		return false
+	}
+	if expected.Length > 0 && uint(length) != expected.Length {
+		v.report.addError(fname, "length mismatch, expected %d, got %d", expected.Length, length)
+		return false
+	}
+	if len(expected.Hashes) == 0 {
+		v.report.addError(fname, "no hash specified")
+		return false
+	}
+	for algo, hash := range expected.Hashes {
+		actual, ok := hashes[algo]
+		if !ok {
+			v.report.addWarning(fname, "unsupported hash algorithm %s", algo)
+			continue
+		}
+		if actual != hash {
+			v.report.addError(fname, "%s mismatch, expected %s, got %s", algo, hash, actual)
+			return false
+		}
+	}
+	return true
+}
+
+func (v *mirrorVerifier) verifyTimestampAndSnapshot() *v1manifest.Snapshot {
+	data, ok := v.read(v1manifest.ManifestFilenameTimestamp)
+	if !ok {
+		return nil
+	}
+	var timestamp v1manifest.Timestamp
+	_, err := v1manifest.ReadManifest(bytes.NewReader(data), &timestamp, v.keys)
+	if !v.checkManifestError(v1manifest.ManifestFilenameTimestamp, err) {
+		return nil
+	}
+	v.checkExpiry(v1manifest.ManifestFilenameTimestamp, &timestamp.SignedBase)
+
+	if !v.checkFileHash(v1manifest.ManifestFilenameSnapshot, timestamp.SnapshotHash()) {
+		return nil
+	}
+	data, ok = v.read(v1manifest.ManifestFilenameSnapshot)
+	if !ok {
+		return nil
+	}
+	var snapshot v1manifest.Snapshot
+	_, err = v1manifest.ReadManifest(bytes.NewReader(data), &snapshot, v.keys)
+	if !v.checkManifestError(v1manifest.ManifestFilenameSnapshot, err) {
+		return nil
+	}
+	v.checkExpiry(v1manifest.ManifestFilenameSnapshot, &snapshot.SignedBase)
+
+	if entry, ok := snapshot.Meta[v1manifest.ManifestURLRoot]; !ok {
+		v.report.addError(v1manifest.ManifestFilenameSnapshot, "no entry for %s", v1manifest.ManifestURLRoot)
+	} else if entry.Version != v.report.RootVersion {
+		v.report.addError(v1manifest.ManifestFilenameSnapshot, "version of %s is %d, but the latest one is %d",
+			v1manifest.ManifestURLRoot, entry.Version, v.report.RootVersion)
+	}
+	return &snapshot
+}
+
+// versionedFile returns the file of the url with the version in snapshot
+func (v *mirrorVerifier) versionedFile(snapshot *v1manifest.Snapshot, url string) (string, uint, bool) {
+	versioned, entry, err := snapshot.VersionedURL(url)
+	if err != nil {
+		v.report.addError(v1manifest.ManifestFilenameSnapshot, "%s", err)
+		return "", 0, false
+	}
+	fname := strings.TrimPrefix(versioned, "/")
+	if entry.Length > 0 {
+		if fi, err := os.Stat(filepath.Join(v.dir, fname)); err == nil && uint(fi.Size()) > entry.Length {
+			v.report.addError(fname, "length %d exceeds %d specified in snapshot", fi.Size(), entry.Length)
+			return "", 0, false
+		}
+	}
+	return fname, entry.Version, true
+}
+
+func (v *mirrorVerifier) verifyIndex(snapshot *v1manifest.Snapshot) *v1manifest.Index {
+	fname, ver, ok := v.versionedFile(snapshot, v1manifest.ManifestURLIndex)
+	if !ok {
+		return nil
+	}
+	data, ok := v.read(fname)
+	if !ok {
+		return nil
+	}
+	var index v1manifest.Index
+	_, err := v1manifest.ReadManifest(bytes.NewReader(data), &index, v.keys)
+	if !v.checkManifestError(fname, err) {
+		return nil
+	}
+	if index.Version != ver {
+		v.report.addError(fname, "index version is %d, but should be %d", index.Version, ver)
+	}
+	v.checkExpiry(fname, &index.SignedBase)
+
+	for name, owner := range index.Owners {
+		if err := v.keys.AddKeys(name, uint(owner.Threshold), index.Expires, owner.Keys); err != nil {
+			v.report.addError(fname, "invalid keys of owner %s: %s", name, err)
+		}
+	}
+	return &index
+}
+
+func (v *mirrorVerifier) verifyComponents(snapshot *v1manifest.Snapshot, index *v1manifest.Index) {
+	names := make([]string, 0, len(index.Components))
+	for name := range index.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		item := index.Components[name]
+		fname, ver, ok := v.versionedFile(snapshot, item.URL)
+		if !ok {
+			continue
+		}
+		data, ok := v.read(fname)
+		if !ok {
+			continue
+		}
+		var comp v1manifest.Component
+		_, err := v1manifest.ReadComponentManifest(bytes.NewReader(data), &comp, &item, v.keys)
+		if !v.checkManifestError(fname, err) {
+			continue
+		}
+		if comp.Version != ver {
+			v.report.addError(fname, "component version is %d, but should be %d", comp.Version, ver)
+		}
+		v.checkExpiry(fname, &comp.SignedBase)
+		v.report.Components++
+
+		for plat, versions := range comp.Platforms {
+			for version, vi := range versions {
+				if vi.URL == "" {
+					v.report.addError(fname, "no url of version %s on %s", version, plat)
+					continue
+				}
+				tarball := path.Base(vi.URL)
+				if v.referenced.Exist(tarball) {
+					continue
+				}
+				v.report.Tarballs++
+				if external := externalURLs(vi); len(external) > 0 && !utils.IsExist(filepath.Join(v.dir, tarball)) {
+					// the tarball is served out of the mirror, e.g. object storage
+					v.referenced.Insert(tarball)
+					v.report.addWarning(tarball, "not in the mirror, served by %s", strings.Join(external, ", "))
+					continue
+				}
+				v.checkFileHash(tarball, vi.FileHash)
+			}
+		}
+	}
+}
+
+// findOrphans reports the files not referenced by the manifests, the history
+// versions of the manifests are not counted as they're kept by design
+func (v *mirrorVerifier) findOrphans() error {
+	entries, err := os.ReadDir(v.dir)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || v.referenced.Exist(name) {
+			continue
+		}
+		if isHistoryManifest(name, v.referenced) || isMirrorAuxFile(name) {
+			continue
+		}
+		v.report.Orphans = append(v.report.Orphans, name)
+	}
+	sort.Strings(v.report.Orphans)
+	return nil
+}
+
+// externalURLs returns the locations of the item out of the mirror
+func externalURLs(vi v1manifest.VersionItem) []string {
+	var urls []string
+	for _, u := range vi.URLs {
+		if isAbsoluteURL(u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// isHistoryManifest checks if the file is a history version of the manifests
+// referenced, e.g. 1.tidb.json for 3.tidb.json
+func isHistoryManifest(name string, referenced set.StringSet) bool {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 || !strings.HasSuffix(name, ".json") {
+		return false
+	}
+	if _, err := strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return false
+	}
+	for ref := range referenced {
+		refParts := strings.SplitN(ref, ".", 2)
+		if len(refParts) == 2 && refParts[1] == parts[1] {
+			return true
+		}
+	}
+	return parts[1] == v1manifest.ManifestFilenameRoot
+}
+
+// isMirrorAuxFile checks if the file is maintained by the mirror besides the
+// manifests: the lock of the local store, and the copies of the latest tiup
+// tarballs (e.g. tiup-linux-amd64.tar.gz) for the install script
+func isMirrorAuxFile(name string) bool {
+	if name == "lock" {
+		return true
+	}
+	return strings.HasPrefix(name, "tiup-") && strings.HasSuffix(name, ".tar.gz") &&
+		strings.Count(strings.TrimSuffix(name, ".tar.gz"), "-") == 2
+}
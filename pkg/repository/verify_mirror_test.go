@@ -0,0 +1,150 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyMirror(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tiup-mirror-*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	mirrorDir := filepath.Join(dir, "mirror")
+	assert.Nil(t, os.Mkdir(mirrorDir, 0755))
+	assert.Nil(t, v1manifest.Init(mirrorDir, filepath.Join(dir, "keys"), time.Now().UTC()))
+
+	report, err := VerifyMirror(mirrorDir, VerifyOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.OK(), "%+v", report.Errors)
+	assert.Equal(t, uint(1), report.RootVersion)
+	assert.Empty(t, report.Orphans)
+
+	// the snapshot and timestamp expire in a month
+	report, err = VerifyMirror(mirrorDir, VerifyOptions{ExpiryWarning: 60 * 24 * time.Hour})
+	assert.Nil(t, err)
+	assert.True(t, report.OK())
+	assert.NotEmpty(t, report.Warnings)
+
+	assert.Nil(t, os.WriteFile(filepath.Join(mirrorDir, "foo-v1.0.0-linux-amd64.tar.gz"), []byte("foo"), 0644))
+	report, err = VerifyMirror(mirrorDir, VerifyOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, []string{"foo-v1.0.0-linux-amd64.tar.gz"}, report.Orphans)
+
+	// tamper the snapshot
+	f, err := os.OpenFile(filepath.Join(mirrorDir, v1manifest.ManifestFilenameSnapshot), os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = f.WriteString("\n")
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+	report, err = VerifyMirror(mirrorDir, VerifyOptions{})
+	assert.Nil(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, v1manifest.ManifestFilenameSnapshot, report.Errors[0].File)
+}
+
+func readTestKeys(t *testing.T, dir, ty string) []*v1manifest.KeyInfo {
+	files, err := filepath.Glob(filepath.Join(dir, "*-"+ty+".json"))
+	assert.Nil(t, err)
+	var keys []*v1manifest.KeyInfo
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		assert.Nil(t, err)
+		var ki v1manifest.KeyInfo
+		assert.Nil(t, json.Unmarshal(data, &ki))
+		keys = append(keys, &ki)
+	}
+	return keys
+}
+
+func TestVerifyMirrorRootRotation(t *testing.T) {
+	dir := t.TempDir()
+	mirrorDir := filepath.Join(dir, "mirror")
+	keysDir := filepath.Join(dir, "keys")
+	assert.Nil(t, os.Mkdir(mirrorDir, 0755))
+	assert.Nil(t, v1manifest.Init(mirrorDir, keysDir, time.Now().UTC()))
+
+	readManifest := func(fname string, role v1manifest.ValidManifest) []byte {
+		data, err := os.ReadFile(filepath.Join(mirrorDir, fname))
+		assert.Nil(t, err)
+		_, err = v1manifest.ReadNoVerify(bytes.NewReader(data), role)
+		assert.Nil(t, err)
+		return data
+	}
+	var root v1manifest.Root
+	data := readManifest(v1manifest.ManifestFilenameRoot, &root)
+	assert.Nil(t, os.WriteFile(filepath.Join(mirrorDir, v1manifest.RootManifestFilename(1)), data, 0644))
+
+	// every version rotates the root keys and is signed by the keys of both
+	// the previous version and itself
+	rootKeys := readTestKeys(t, keysDir, v1manifest.ManifestTypeRoot)
+	var m *v1manifest.Manifest
+	var err error
+	for ver := uint(2); ver <= 3; ver++ {
+		var newKeys []*v1manifest.KeyInfo
+		for range rootKeys {
+			k, err := v1manifest.GenKeyInfo()
+			assert.Nil(t, err)
+			newKeys = append(newKeys, k)
+		}
+		root.Version = ver
+		assert.Nil(t, root.SetRole(&root, newKeys...))
+		m, err = v1manifest.SignManifest(&root, append(rootKeys, newKeys...)...)
+		assert.Nil(t, err)
+		assert.Nil(t, v1manifest.WriteManifestFile(filepath.Join(mirrorDir, v1manifest.RootManifestFilename(ver)), m))
+		rootKeys = newKeys
+	}
+	assert.Nil(t, v1manifest.WriteManifestFile(filepath.Join(mirrorDir, v1manifest.ManifestFilenameRoot), m))
+
+	// the snapshot records the latest root version
+	var snapshot v1manifest.Snapshot
+	readManifest(v1manifest.ManifestFilenameSnapshot, &snapshot)
+	_, err = snapshot.SetVersions(map[string]*v1manifest.Manifest{v1manifest.ManifestTypeRoot: m})
+	assert.Nil(t, err)
+	ms, err := v1manifest.SignManifest(&snapshot, readTestKeys(t, keysDir, v1manifest.ManifestTypeSnapshot)...)
+	assert.Nil(t, err)
+	assert.Nil(t, v1manifest.WriteManifestFile(filepath.Join(mirrorDir, v1manifest.ManifestFilenameSnapshot), ms))
+	var timestamp v1manifest.Timestamp
+	readManifest(v1manifest.ManifestFilenameTimestamp, &timestamp)
+	_, err = timestamp.SetSnapshot(ms)
+	assert.Nil(t, err)
+	mt, err := v1manifest.SignManifest(&timestamp, readTestKeys(t, keysDir, v1manifest.ManifestTypeTimestamp)...)
+	assert.Nil(t, err)
+	assert.Nil(t, v1manifest.WriteManifestFile(filepath.Join(mirrorDir, v1manifest.ManifestFilenameTimestamp), mt))
+
+	report, err := VerifyMirror(mirrorDir, VerifyOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.OK(), "%+v", report.Errors)
+	assert.Equal(t, uint(3), report.RootVersion)
+
+	// a version signed by the keys of itself only breaks the chain
+	root.Version = 2
+	m, err = v1manifest.SignManifest(&root, rootKeys...)
+	assert.Nil(t, err)
+	assert.Nil(t, v1manifest.WriteManifestFile(filepath.Join(mirrorDir, v1manifest.RootManifestFilename(2)), m))
+	report, err = VerifyMirror(mirrorDir, VerifyOptions{})
+	assert.Nil(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, v1manifest.RootManifestFilename(2), report.Errors[0].File)
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidbver
+
+import (
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Feature is a feature of a component only supported by some versions
+type Feature string
+
+// features of TiCDC
+const (
+	TiCDCConfigFile Feature = "cdc.config-file"
+	TiCDCDataDir    Feature = "cdc.data-dir"
+	TiCDCTLS        Feature = "cdc.tls"
+	TiCDCClusterID  Feature = "cdc.cluster-id"
+)
+
+// featureVersions is the versions supporting a feature
+type featureVersions struct {
+	// since is the first versions supporting the feature in ascending order,
+	// a version supports the feature if it's no less than the last one listed
+	// of the same or an older major version.
+	since []string
+	// excludes is the versions not supporting the feature in the range
+	excludes []string
+}
+
+// features is the registry of the features and the versions supporting them
+var features = map[Feature]featureVersions{
+	// config support since v4.0.13, ignore v5.0.0-rc
+	// the same to data-dir, but we treat it as --sort-dir
+	TiCDCConfigFile: {since: []string{"v4.0.13"}, excludes: []string{"v5.0.0-rc"}},
+	// TiCDC support --data-dir since v4.0.14 and v5.0.3
+	TiCDCDataDir: {since: []string{"v4.0.14", "v5.0.3"}},
+	TiCDCTLS:     {since: []string{"v4.0.3"}},
+	// multiple TiCDC clusters on the same PD cluster since v6.2.0
+	TiCDCClusterID: {since: []string{"v6.2.0"}},
+}
+
+// Supports return if given version supports the feature, the nightly
+// versions support all the features registered
+func Supports(feature Feature, version string) bool {
+	f, ok := features[feature]
+	if !ok {
+		return false
+	}
+	if strings.Contains(version, "nightly") {
+		return true
+	}
+	for _, v := range f.excludes {
+		if version == v {
+			return false
+		}
+	}
+
+	since := f.since[0]
+	for _, v := range f.since {
+		if semver.Compare(semver.Major(v), semver.Major(version)) <= 0 {
+			since = v
+		}
+	}
+	return semver.Compare(version, since) >= 0
+}
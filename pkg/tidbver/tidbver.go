@@ -63,17 +63,22 @@ func TiFlashNotNeedSomeConfig(version string) bool {
 
 // TiCDCSupportConfigFile return if given version of TiCDC support config file
 func TiCDCSupportConfigFile(version string) bool {
-	// config support since v4.0.13, ignore v5.0.0-rc
-	return semver.Compare(version, "v4.0.13") >= 0 && version != "v5.0.0-rc" || strings.Contains(version, "nightly")
+	return Supports(TiCDCConfigFile, version)
 }
 
 // TiCDCSupportDataDir return if given version of TiCDC support --data-dir
 func TiCDCSupportDataDir(version string) bool {
-	// TiCDC support --data-dir since v4.0.14 and v5.0.3
-	if semver.Compare(version, "v5.0.3") >= 0 || strings.Contains(version, "nightly") {
-		return true
-	}
-	return semver.Major(version) == "v4" && semver.Compare(version, "v4.0.14") >= 0
+	return Supports(TiCDCDataDir, version)
+}
+
+// TiCDCSupportTLS return if given version of TiCDC support TLS
+func TiCDCSupportTLS(version string) bool {
+	return Supports(TiCDCTLS, version)
+}
+
+// TiCDCSupportClusterID return if given version of TiCDC support --cluster-id
+func TiCDCSupportClusterID(version string) bool {
+	return Supports(TiCDCClusterID, version)
 }
 
 // NgMonitorDeployByDefault return if given version of TiDB cluster should contain ng-monitoring
@@ -15,10 +15,13 @@ package tui
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/AstroProfundis/tabby"
 	"github.com/fatih/color"
@@ -26,6 +29,65 @@ import (
 	"golang.org/x/term"
 )
 
+// Prompter asks the user for input, the prompts are written to out and the
+// answers are read from in, e.g. to answer them programmatically when the
+// operations are embedded as a library
+type Prompter struct {
+	in  io.Reader
+	out io.Writer
+}
+
+// stdPrompter is the prompter of the standard input and output
+var stdPrompter = NewPrompter(os.Stdin, os.Stdout)
+
+// NewPrompter creates a Prompter reading the answers from in
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	// the reader is buffered once, so the input of the next prompts is not
+	// consumed by the buffer of the previous one
+	if _, ok := in.(*bufio.Reader); !ok && in != os.Stdin {
+		in = bufio.NewReader(in)
+	}
+	return &Prompter{in: in, out: out}
+}
+
+type prompterKey struct{}
+
+// WithPrompter returns a copy of ctx carrying the prompter
+func WithPrompter(ctx context.Context, p *Prompter) context.Context {
+	return context.WithValue(ctx, prompterKey{}, p)
+}
+
+// ContextPrompter returns the prompter carried by ctx, or the prompter of the
+// standard input and output if there is none
+func ContextPrompter(ctx context.Context) *Prompter {
+	if p, ok := ctx.Value(prompterKey{}).(*Prompter); ok && p != nil {
+		return p
+	}
+	return stdPrompter
+}
+
+// FprintTable prints the table to w, it's the same as PrintTable if w is
+// os.Stdout
+func FprintTable(w io.Writer, rows [][]string, header bool) {
+	if w == os.Stdout {
+		PrintTable(rows, header)
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+		if i == 0 && header {
+			seps := make([]string, len(row))
+			for j, col := range row {
+				seps[j] = strings.Repeat("-", len(col))
+			}
+			fmt.Fprintln(tw, strings.Join(seps, "\t"))
+		}
+	}
+	_ = tw.Flush()
+}
+
 // PrintTable accepts a matrix of strings and print them as ASCII table to terminal
 func PrintTable(rows [][]string, header bool) {
 	if f := mock.On("PrintTable"); f != nil {
@@ -73,12 +135,20 @@ const (
 
 // Prompt accepts input from console by user
 func Prompt(prompt string) string {
+	return stdPrompter.Prompt(prompt)
+}
+
+// Prompt accepts input from the user
+func (p *Prompter) Prompt(prompt string) string {
 	if prompt != "" {
 		prompt += " " // append a whitespace
 	}
-	fmt.Print(prompt)
+	fmt.Fprint(p.out, prompt)
 
-	reader := bufio.NewReader(os.Stdin)
+	reader, ok := p.in.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(p.in)
+	}
 	input, err := reader.ReadString('\n')
 	if err != nil {
 		return ""
@@ -89,7 +159,13 @@ func Prompt(prompt string) string {
 // PromptForConfirmYes accepts yes / no from console by user, default to No and only return true
 // if the user input is Yes
 func PromptForConfirmYes(format string, a ...interface{}) (bool, string) {
-	ans := Prompt(fmt.Sprintf(format, a...) + "(default=N)")
+	return stdPrompter.PromptForConfirmYes(format, a...)
+}
+
+// PromptForConfirmYes accepts yes / no from the user, default to No and only
+// return true if the user input is Yes
+func (p *Prompter) PromptForConfirmYes(format string, a ...interface{}) (bool, string) {
+	ans := p.Prompt(fmt.Sprintf(format, a...) + "(default=N)")
 	switch strings.TrimSpace(strings.ToLower(ans)) {
 	case "y", "yes":
 		return true, ans
@@ -101,7 +177,13 @@ func PromptForConfirmYes(format string, a ...interface{}) (bool, string) {
 // PromptForConfirmNo accepts yes / no from console by user, default to Yes and only return true
 // if the user input is No
 func PromptForConfirmNo(format string, a ...interface{}) (bool, string) {
-	ans := Prompt(fmt.Sprintf(format, a...) + "(default=Y)")
+	return stdPrompter.PromptForConfirmNo(format, a...)
+}
+
+// PromptForConfirmNo accepts yes / no from the user, default to Yes and only
+// return true if the user input is No
+func (p *Prompter) PromptForConfirmNo(format string, a ...interface{}) (bool, string) {
+	ans := p.Prompt(fmt.Sprintf(format, a...) + "(default=Y)")
 	switch strings.TrimSpace(strings.ToLower(ans)) {
 	case "n", "no":
 		return true, ans
@@ -112,7 +194,13 @@ func PromptForConfirmNo(format string, a ...interface{}) (bool, string) {
 
 // PromptForConfirmOrAbortError accepts yes / no from console by user, generates AbortError if user does not input yes.
 func PromptForConfirmOrAbortError(format string, a ...interface{}) error {
-	if pass, ans := PromptForConfirmYes(format, a...); !pass {
+	return stdPrompter.PromptForConfirmOrAbortError(format, a...)
+}
+
+// PromptForConfirmOrAbortError accepts yes / no from the user, generates
+// AbortError if the user does not input yes.
+func (p *Prompter) PromptForConfirmOrAbortError(format string, a ...interface{}) error {
+	if pass, ans := p.PromptForConfirmYes(format, a...); !pass {
 		return errOperationAbort.New("Operation aborted by user (with answer '%s')", ans)
 	}
 	return nil
@@ -121,7 +209,13 @@ func PromptForConfirmOrAbortError(format string, a ...interface{}) error {
 // PromptForConfirmAnswer accepts string from console by user, default to empty and only return
 // true if the user input is exactly the same as pre-defined answer.
 func PromptForConfirmAnswer(answer string, format string, a ...interface{}) (bool, string) {
-	ans := Prompt(fmt.Sprintf(format, a...) + fmt.Sprintf("\n(Type \"%s\" to continue)\n:", color.CyanString(answer)))
+	return stdPrompter.PromptForConfirmAnswer(answer, format, a...)
+}
+
+// PromptForConfirmAnswer accepts string from the user, default to empty and
+// only return true if the user input is exactly the same as pre-defined answer.
+func (p *Prompter) PromptForConfirmAnswer(answer string, format string, a ...interface{}) (bool, string) {
+	ans := p.Prompt(fmt.Sprintf(format, a...) + fmt.Sprintf("\n(Type \"%s\" to continue)\n:", color.CyanString(answer)))
 	if ans == answer {
 		return true, ans
 	}
@@ -131,7 +225,13 @@ func PromptForConfirmAnswer(answer string, format string, a ...interface{}) (boo
 // PromptForAnswerOrAbortError accepts string from console by user, generates AbortError if user does
 // not input the pre-defined answer.
 func PromptForAnswerOrAbortError(answer string, format string, a ...interface{}) error {
-	if pass, ans := PromptForConfirmAnswer(answer, format, a...); !pass {
+	return stdPrompter.PromptForAnswerOrAbortError(answer, format, a...)
+}
+
+// PromptForAnswerOrAbortError accepts string from the user, generates
+// AbortError if the user does not input the pre-defined answer.
+func (p *Prompter) PromptForAnswerOrAbortError(answer string, format string, a ...interface{}) error {
+	if pass, ans := p.PromptForConfirmAnswer(answer, format, a...); !pass {
 		return errOperationAbort.New("Operation aborted by user (with incorrect answer '%s')", ans)
 	}
 	return nil
@@ -139,10 +239,19 @@ func PromptForAnswerOrAbortError(answer string, format string, a ...interface{})
 
 // PromptForPassword reads a password input from console
 func PromptForPassword(format string, a ...interface{}) string {
-	defer fmt.Println("")
+	return stdPrompter.PromptForPassword(format, a...)
+}
 
-	fmt.Printf(format, a...)
+// PromptForPassword reads a password input from the user, it's not echoed if
+// it's read from the terminal
+func (p *Prompter) PromptForPassword(format string, a ...interface{}) string {
+	defer fmt.Fprintln(p.out, "")
 
+	fmt.Fprintf(p.out, format, a...)
+
+	if p.in != os.Stdin {
+		return strings.TrimSpace(p.Prompt(""))
+	}
 	input, err := term.ReadPassword(syscall.Stdin)
 
 	if err != nil {
@@ -21,7 +21,7 @@ import (
 )
 
 // PostFile upload file
-func PostFile(reader io.Reader, url, fieldname, filename string) (*http.Response, error) {
+func PostFile(client *http.Client, reader io.Reader, url, fieldname, filename string) (*http.Response, error) {
 	bodyBuf := &bytes.Buffer{}
 	bodyWriter := multipart.NewWriter(bodyBuf)
 
@@ -39,7 +39,7 @@ func PostFile(reader io.Reader, url, fieldname, filename string) (*http.Response
 	contentType := bodyWriter.FormDataContentType()
 	bodyWriter.Close()
 
-	resp, err := http.Post(url, contentType, bodyBuf)
+	resp, err := client.Post(url, contentType, bodyBuf)
 	if err != nil {
 		return nil, err
 	}
@@ -30,6 +30,18 @@ import (
 type HTTPClient struct {
 	client *http.Client
 	header http.Header
+	// contextHeader returns the headers of a request by its context and URL,
+	// they are sent in addition to the custom headers
+	contextHeader func(ctx context.Context, u *url.URL) http.Header
+}
+
+// httpTransport replaces the transport of the HTTP clients if it's set
+var httpTransport http.RoundTripper
+
+// SetHTTPTransport sets the transport used by all HTTP clients created after,
+// e.g. to serve the requests with recorded responses in simulations
+func SetHTTPTransport(rt http.RoundTripper) {
+	httpTransport = rt
 }
 
 // NewHTTPClient returns a new HTTP client with timeout and HTTPS support
@@ -51,10 +63,14 @@ func NewHTTPClient(timeout time.Duration, tlsConfig *tls.Config) *HTTPClient {
 			tr.Proxy = http.ProxyURL(proxyURL)
 		}
 	}
+	var rt http.RoundTripper = tr
+	if httpTransport != nil {
+		rt = httpTransport
+	}
 	return &HTTPClient{
 		client: &http.Client{
 			Timeout:   timeout,
-			Transport: tr,
+			Transport: rt,
 		},
 	}
 }
@@ -67,6 +83,30 @@ func (c *HTTPClient) SetRequestHeader(key, value string) {
 	c.header.Add(key, value)
 }
 
+// SetContextHeader sets the func returning the headers of a request by its
+// context and URL, e.g. the credentials of the cluster the request is sent to
+func (c *HTTPClient) SetContextHeader(f func(ctx context.Context, u *url.URL) http.Header) {
+	c.contextHeader = f
+}
+
+// setHeader sets the custom headers to the request, the headers are cloned
+// as the request may be modified by the transport.
+func (c *HTTPClient) setHeader(ctx context.Context, req *http.Request, contentType string) {
+	if c.header != nil {
+		req.Header = c.header.Clone()
+	}
+	if c.contextHeader != nil && ctx != nil {
+		for k, vs := range c.contextHeader(ctx, req.URL) {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+}
+
 // Get fetch an URL with GET method and returns the response
 func (c *HTTPClient) Get(ctx context.Context, url string) ([]byte, error) {
 	data, _, err := c.GetWithStatusCode(ctx, url)
@@ -81,7 +121,7 @@ func (c *HTTPClient) GetWithStatusCode(ctx context.Context, url string) ([]byte,
 		return nil, statusCode, err
 	}
 
-	req.Header = c.header
+	c.setHeader(ctx, req, "")
 
 	if ctx != nil {
 		req = req.WithContext(ctx)
@@ -119,7 +159,7 @@ func (c *HTTPClient) Download(ctx context.Context, url, filePath string) error {
 		return err
 	}
 
-	req.Header = c.header
+	c.setHeader(ctx, req, "")
 
 	if ctx != nil {
 		req = req.WithContext(ctx)
@@ -152,11 +192,7 @@ func (c *HTTPClient) PostWithStatusCode(ctx context.Context, url string, body io
 		return nil, statusCode, err
 	}
 
-	if c.header == nil {
-		req.Header.Set("Content-Type", "application/json")
-	} else {
-		req.Header = c.header
-	}
+	c.setHeader(ctx, req, "application/json")
 
 	if ctx != nil {
 		req = req.WithContext(ctx)
@@ -178,11 +214,7 @@ func (c *HTTPClient) Put(ctx context.Context, url string, body io.Reader) ([]byt
 	if err != nil {
 		return nil, statusCode, err
 	}
-	if c.header == nil {
-		req.Header.Set("Content-Type", "application/json")
-	} else {
-		req.Header = c.header
-	}
+	c.setHeader(ctx, req, "application/json")
 	if ctx != nil {
 		req = req.WithContext(ctx)
 	}
@@ -204,6 +236,7 @@ func (c *HTTPClient) Delete(ctx context.Context, url string, body io.Reader) ([]
 	if err != nil {
 		return nil, statusCode, err
 	}
+	c.setHeader(ctx, req, "")
 
 	if ctx != nil {
 		req = req.WithContext(ctx)
@@ -14,7 +14,7 @@
 package utils
 
 import (
-	"fmt"
+	"context"
 	"strings"
 	"time"
 )
@@ -48,7 +48,7 @@ var (
 )
 
 // Retry retries the func until it returns no error or reaches attempts limit or
-// timed out, either one is earlier
+// timed out, either one is earlier, it's Wait without a context
 func Retry(doFunc func() error, opts ...RetryOption) error {
 	var cfg RetryOption
 	if len(opts) > 0 {
@@ -61,37 +61,7 @@ func Retry(doFunc func() error, opts ...RetryOption) error {
 		}
 	}
 
-	// timeout must be greater than 0
-	if cfg.Timeout <= 0 {
-		return fmt.Errorf("timeout (%s) must be greater than 0", cfg.Timeout)
-	}
-	// set options automatically for invalid value
-	if cfg.Delay <= 0 {
-		cfg.Delay = defaultDelay
-	}
-	if cfg.Attempts <= 0 {
-		cfg.Attempts = cfg.Timeout.Milliseconds()/cfg.Delay.Milliseconds() + 1
-	}
-
-	timeoutChan := time.After(cfg.Timeout)
-
-	// call the function
-	var attemptCount int64
-	for attemptCount = 0; attemptCount < cfg.Attempts; attemptCount++ {
-		if err := doFunc(); err == nil {
-			return nil
-		}
-
-		// check for timeout
-		select {
-		case <-timeoutChan:
-			return fmt.Errorf("operation timed out after %s", cfg.Timeout)
-		default:
-			time.Sleep(cfg.Delay)
-		}
-	}
-
-	return fmt.Errorf("operation exceeds the max retry attempts of %d", cfg.Attempts)
+	return Wait(context.Background(), doFunc, WaitOptionFromRetry(cfg))
 }
 
 // IsTimeoutOrMaxRetry return true if it's timeout or reach max retry.
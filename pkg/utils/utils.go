@@ -14,6 +14,7 @@
 package utils
 
 import (
+	"fmt"
 	"math"
 	"strconv"
 	"strings"
@@ -63,6 +64,19 @@ func Base62Tag() string {
 	return string(b)
 }
 
+// ParseDuration parses a duration like time.ParseDuration, and the unit of
+// day is supported, e.g. "90d"
+func ParseDuration(s string) (time.Duration, error) {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
 // Ternary operator
 func Ternary(condition bool, a, b interface{}) interface{} {
 	if condition {
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 )
@@ -9,3 +10,18 @@ import (
 func TestUtils(t *testing.T) {
 	TestingT(t)
 }
+
+type utilsSuite struct{}
+
+var _ = Suite(&utilsSuite{})
+
+func (s *utilsSuite) TestParseDuration(c *C) {
+	d, err := ParseDuration("90d")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 90*24*time.Hour)
+	d, err = ParseDuration("1h30m")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 90*time.Minute)
+	_, err = ParseDuration("1.5d")
+	c.Assert(err, NotNil)
+}
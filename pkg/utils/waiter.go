@@ -0,0 +1,97 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WaitOption controls how Wait polls a condition until the state converges
+type WaitOption struct {
+	// Timeout is the max time to wait, it must be greater than 0
+	Timeout time.Duration
+	// Interval is the time between the first two polls, it's doubled after
+	// each poll up to MaxInterval, the polls are not backed off if MaxInterval
+	// is not greater than Interval.
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// Attempts is the max count of polls, it's not limited if not positive
+	Attempts int64
+	// Progress is called with the time elapsed and the error returned by the
+	// condition after each poll that has not converged, to report the progress.
+	Progress func(elapsed time.Duration, err error)
+}
+
+// WaitOptionFromRetry returns a WaitOption polling like Retry with the
+// RetryOption, i.e. with the fixed delay and limited by the attempts.
+func WaitOptionFromRetry(opt RetryOption) WaitOption {
+	return WaitOption{
+		Timeout:  opt.Timeout,
+		Interval: opt.Delay,
+		Attempts: opt.Attempts,
+	}
+}
+
+// Wait polls the condition until it returns nil, which means the state has
+// converged. It returns an error if it's timed out or the ctx is canceled,
+// the last error returned by the condition is included.
+func Wait(ctx context.Context, cond func() error, opt WaitOption) error {
+	if opt.Timeout <= 0 {
+		return fmt.Errorf("timeout (%s) must be greater than 0", opt.Timeout)
+	}
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = defaultDelay
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	deadline := time.NewTimer(opt.Timeout)
+	defer deadline.Stop()
+
+	for polls := int64(1); ; polls++ {
+		err := cond()
+		if err == nil {
+			return nil
+		}
+		if opt.Progress != nil {
+			opt.Progress(time.Since(start), err)
+		}
+		if opt.Attempts > 0 && polls >= opt.Attempts {
+			return fmt.Errorf("operation exceeds the max retry attempts of %d, %s", opt.Attempts, err)
+		}
+
+		poll := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			poll.Stop()
+			return fmt.Errorf("operation canceled after %s, %w: %s", time.Since(start).Round(time.Second), ctx.Err(), err)
+		case <-deadline.C:
+			poll.Stop()
+			return fmt.Errorf("operation timed out after %s, %s", opt.Timeout, err)
+		case <-poll.C:
+		}
+
+		if interval < opt.MaxInterval {
+			interval *= 2
+			if interval > opt.MaxInterval {
+				interval = opt.MaxInterval
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&TestWaiterSuite{})
+
+type TestWaiterSuite struct{}
+
+func (s *TestWaiterSuite) TestWait(c *C) {
+	// converged after some polls with backoff
+	polls := 0
+	var progress []time.Duration
+	err := Wait(context.Background(), func() error {
+		polls++
+		if polls < 4 {
+			return errors.New("not yet")
+		}
+		return nil
+	}, WaitOption{
+		Timeout:     time.Second,
+		Interval:    time.Millisecond,
+		MaxInterval: 4 * time.Millisecond,
+		Progress: func(elapsed time.Duration, err error) {
+			progress = append(progress, elapsed)
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(polls, Equals, 4)
+	c.Assert(progress, HasLen, 3)
+
+	// timed out
+	err = Wait(context.Background(), func() error {
+		return errors.New("not yet")
+	}, WaitOption{Timeout: 10 * time.Millisecond, Interval: time.Millisecond})
+	c.Assert(err, NotNil)
+	c.Assert(IsTimeoutOrMaxRetry(err), IsTrue)
+	c.Assert(err, ErrorMatches, ".*not yet.*")
+
+	// canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Wait(ctx, func() error {
+		return errors.New("not yet")
+	}, WaitOption{Timeout: time.Second, Interval: time.Millisecond})
+	c.Assert(errors.Is(err, context.Canceled), IsTrue)
+
+	// invalid timeout
+	err = Wait(context.Background(), func() error { return nil }, WaitOption{})
+	c.Assert(err, NotNil)
+
+	// the attempts are limited like Retry, and the polls are not backed off
+	polls = 0
+	var last time.Time
+	var intervals []time.Duration
+	opt := WaitOptionFromRetry(RetryOption{Attempts: 3, Delay: 20 * time.Millisecond, Timeout: time.Second})
+	c.Assert(opt.MaxInterval, Equals, time.Duration(0))
+	err = Wait(context.Background(), func() error {
+		polls++
+		if !last.IsZero() {
+			intervals = append(intervals, time.Since(last))
+		}
+		last = time.Now()
+		return errors.New("not yet")
+	}, opt)
+	c.Assert(IsTimeoutOrMaxRetry(err), IsTrue)
+	c.Assert(err, ErrorMatches, "operation exceeds the max retry attempts of 3.*")
+	c.Assert(polls, Equals, 3)
+	c.Assert(intervals, HasLen, 2)
+	for _, interval := range intervals {
+		c.Assert(interval < 40*time.Millisecond, IsTrue)
+	}
+
+	// Retry is Wait without a context
+	polls = 0
+	err = Retry(func() error {
+		polls++
+		return errors.New("not yet")
+	}, RetryOption{Attempts: 2, Delay: time.Millisecond, Timeout: time.Second})
+	c.Assert(err, ErrorMatches, "operation exceeds the max retry attempts of 2.*")
+	c.Assert(polls, Equals, 2)
+}
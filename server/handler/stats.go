@@ -0,0 +1,42 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/server/stats"
+)
+
+// DownloadStats handles requests to get the download statistics of tarballs
+func DownloadStats(recorder *stats.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(recorder.Entries()); err != nil {
+			logprinter.Errorf("Encode download statistics: %s", err.Error())
+		}
+	})
+}
+
+// DownloadMetrics handles requests to get the download statistics as Prometheus metrics
+func DownloadMetrics(recorder *stats.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := recorder.WriteMetrics(w); err != nil {
+			logprinter.Errorf("Write download metrics: %s", err.Error())
+		}
+	})
+}
@@ -26,6 +26,7 @@ func main() {
 	addr := "0.0.0.0:8989"
 	keyDir := ""
 	upstream := "https://tiup-mirrors.pingcap.com"
+	statsFile := ""
 
 	cmd := &cobra.Command{
 		Use:     fmt.Sprintf("%s <root-dir>", os.Args[0]),
@@ -36,7 +37,10 @@ func main() {
 				return cmd.Help()
 			}
 
-			s, err := newServer(args[0], keyDir, upstream)
+			if statsFile == "" {
+				statsFile = defaultStatsFile(args[0])
+			}
+			s, err := newServer(args[0], keyDir, upstream, statsFile)
 			if err != nil {
 				return err
 			}
@@ -47,6 +51,8 @@ func main() {
 	cmd.Flags().StringVarP(&addr, "addr", "", addr, "addr to listen")
 	cmd.Flags().StringVarP(&keyDir, "key-dir", "", keyDir, "specify the directory where stores the private keys")
 	cmd.Flags().StringVarP(&upstream, "upstream", "", upstream, "specify the upstream mirror")
+	cmd.Flags().StringVarP(&statsFile, "stats-file", "", statsFile, "specify the file to save the download statistics, <root-dir>.stats.json by default")
+	cmd.AddCommand(newStatsCmd())
 
 	if err := cmd.Execute(); err != nil {
 		logprinter.Errorf("Execute command: %s", err.Error())
@@ -43,13 +43,26 @@ func httpRequestMiddleware(h http.Handler) http.Handler {
 	})
 }
 
+// countDownloads records the tarballs downloaded successfully
+func (s *server) countDownloads(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &traceResponseWriter{w, http.StatusOK}
+		h.ServeHTTP(tw, r)
+		if r.Method == http.MethodGet && tw.statusCode == http.StatusOK {
+			s.stats.Record(r.URL.Path, time.Now())
+		}
+	})
+}
+
 func (s *server) router() http.Handler {
 	r := mux.NewRouter()
 
 	r.Handle("/api/v1/tarball/{sid}", handler.UploadTarbal(s.sm))
 	r.Handle("/api/v1/component/{sid}/{name}", handler.SignComponent(s.sm, s.mirror))
 	r.Handle("/api/v1/rotate", handler.RotateRoot(s.mirror))
-	r.PathPrefix("/").Handler(s.static("/", s.mirror.Source(), s.upstream))
+	r.Handle("/api/v1/stats", handler.DownloadStats(s.stats))
+	r.Handle("/metrics", handler.DownloadMetrics(s.stats))
+	r.PathPrefix("/").Handler(s.countDownloads(s.static("/", s.mirror.Source(), s.upstream)))
 
 	return httpRequestMiddleware(r)
 }
@@ -14,36 +14,99 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/repository"
 	"github.com/pingcap/tiup/server/session"
+	"github.com/pingcap/tiup/server/stats"
 )
 
+// statsSaveInterval is the interval to save the download statistics
+const statsSaveInterval = time.Minute
+
 type server struct {
 	mirror   repository.Mirror
 	sm       session.Manager
 	upstream string
+	stats    *stats.Recorder
 }
 
 // NewServer returns a pointer to server
-func newServer(rootDir, keyDir, upstream string) (*server, error) {
+func newServer(rootDir, keyDir, upstream, statsFile string) (*server, error) {
 	mirror := repository.NewMirror(rootDir, repository.MirrorOptions{Upstream: upstream, KeyDir: keyDir})
 	if err := mirror.Open(); err != nil {
 		return nil, err
 	}
 
+	recorder, err := stats.Load(statsFile)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &server{
 		mirror:   mirror,
 		sm:       session.New(),
 		upstream: upstream,
+		stats:    recorder,
 	}
 
 	return s, nil
 }
 
+// defaultStatsFile returns the default file of the download statistics, it's
+// beside the mirror directory so that it's not served
+func defaultStatsFile(rootDir string) string {
+	return filepath.Clean(rootDir) + ".stats.json"
+}
+
 func (s *server) run(addr string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(statsSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.stats.Save(); err != nil {
+					logprinter.Errorf("Save download statistics: %s", err.Error())
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	srv := &http.Server{Addr: addr, Handler: s.router()}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
 	fmt.Println(addr)
-	return http.ListenAndServe(addr, s.router())
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		// save the downloads since the last tick before exiting
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), statsSaveInterval)
+		defer cancelShutdown()
+		err = srv.Shutdown(shutdownCtx)
+	}
+	if serr := s.stats.Save(); serr != nil {
+		logprinter.Errorf("Save download statistics: %s", serr.Error())
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
 }
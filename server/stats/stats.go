@@ -0,0 +1,286 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// Key identifies the tarball of a component version on a platform
+type Key struct {
+	Component string `json:"component"`
+	Version   string `json:"version"`
+	Platform  string `json:"platform"`
+}
+
+// Entry is the download statistics of a tarball
+type Entry struct {
+	Key
+	Downloads  uint64    `json:"downloads"`
+	LastAccess time.Time `json:"last_access"`
+}
+
+// Recorder aggregates the downloads of the tarballs in a mirror, the result
+// is persisted to a file to survive restarts of the server
+type Recorder struct {
+	mu      sync.Mutex
+	path    string
+	entries map[Key]*Entry
+	// changes is increased on every record, and saved is the value of it
+	// when the statistics are saved successfully last time
+	changes uint64
+	saved   uint64
+}
+
+// Load loads the statistics saved in path, it's empty if the file not exists
+func Load(path string) (*Recorder, error) {
+	r := &Recorder{
+		path:    path,
+		entries: make(map[Key]*Entry),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, errors.AddStack(err)
+	}
+
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.Annotatef(err, "invalid statistics file %s", path)
+	}
+	for _, e := range entries {
+		r.entries[e.Key] = e
+	}
+	return r, nil
+}
+
+// ParseTarball parses the filename of a tarball in the mirror, which is
+// named as <component>-<version>-<os>-<arch>.tar.gz. The component name and
+// the version may contain '-', the version starts from the first part like
+// `v1` or `nightly`.
+func ParseTarball(filename string) (Key, bool) {
+	name := path.Base(filename)
+	if !strings.HasSuffix(name, ".tar.gz") {
+		return Key{}, false
+	}
+	parts := strings.Split(strings.TrimSuffix(name, ".tar.gz"), "-")
+	if len(parts) < 4 {
+		return Key{}, false
+	}
+	platform := parts[len(parts)-2] + "/" + parts[len(parts)-1]
+	parts = parts[:len(parts)-2]
+	for i := 1; i < len(parts); i++ {
+		if isVersionStart(parts[i]) {
+			return Key{
+				Component: strings.Join(parts[:i], "-"),
+				Version:   strings.Join(parts[i:], "-"),
+				Platform:  platform,
+			}, true
+		}
+	}
+	return Key{}, false
+}
+
+func isVersionStart(s string) bool {
+	if s == utils.NightlyVersionAlias {
+		return true
+	}
+	return len(s) > 1 && s[0] == 'v' && s[1] >= '0' && s[1] <= '9'
+}
+
+// Record records a download of the file at t, the files other than tarballs
+// are ignored
+func (r *Recorder) Record(filename string, t time.Time) bool {
+	key, ok := ParseTarball(filename)
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[key]
+	if !ok {
+		e = &Entry{Key: key}
+		r.entries[key] = e
+	}
+	e.Downloads++
+	if t.After(e.LastAccess) {
+		e.LastAccess = t
+	}
+	r.changes++
+	return true
+}
+
+var (
+	// combinedLogRegexp matches the common/combined log format of web servers, e.g.
+	// 127.0.0.1 - - [10/Oct/2022:13:55:36 +0800] "GET /tidb-v6.1.0-linux-amd64.tar.gz HTTP/1.1" 200 2326
+	combinedLogRegexp = regexp.MustCompile(`\[([^\]]+)\] "GET ([^ "]+)[^"]*" 200 `)
+	// serverLogRegexp matches the response log of tiup-server, e.g.
+	// Response [200] : 127.0.0.1:53142 - GET - /tidb-v6.1.0-linux-amd64.tar.gz (0.003 sec)
+	serverLogRegexp = regexp.MustCompile(`Response \[200\] : \S+ - GET - (\S+)`)
+)
+
+const combinedLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// ImportLog records the downloads in an access log, both the log of
+// tiup-server and the common log format of web servers are supported. The
+// access time of the former is unknown, so only the counts are recorded.
+func (r *Recorder) ImportLog(reader io.Reader) (int, error) {
+	count := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := combinedLogRegexp.FindStringSubmatch(line); m != nil {
+			t, err := time.Parse(combinedLogTimeLayout, m[1])
+			if err != nil {
+				continue
+			}
+			if r.Record(stripQuery(m[2]), t) {
+				count++
+			}
+			continue
+		}
+		if m := serverLogRegexp.FindStringSubmatch(line); m != nil {
+			if r.Record(stripQuery(m[1]), time.Time{}) {
+				count++
+			}
+		}
+	}
+	return count, errors.AddStack(scanner.Err())
+}
+
+func stripQuery(url string) string {
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
+
+// Entries returns the statistics sorted by component, version and platform
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entriesLocked()
+}
+
+func (r *Recorder) entriesLocked() []Entry {
+	entries := make([]Entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, *e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Component != b.Component {
+			return a.Component < b.Component
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Platform < b.Platform
+	})
+	return entries
+}
+
+// Save writes the statistics to the file if anything changed, the changes
+// are kept to be saved next time if the write fails
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	if r.changes == r.saved {
+		r.mu.Unlock()
+		return nil
+	}
+	changes := r.changes
+	entries := r.entriesLocked()
+	r.mu.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return errors.AddStack(err)
+	}
+
+	r.mu.Lock()
+	if changes > r.saved {
+		r.saved = changes
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// WriteMetrics writes the statistics in the Prometheus text format
+func (r *Recorder) WriteMetrics(w io.Writer) error {
+	entries := r.Entries()
+
+	metrics := []struct {
+		name  string
+		help  string
+		typ   string
+		value func(e Entry) string
+	}{
+		{
+			name:  "tiup_mirror_downloads_total",
+			help:  "The number of downloads of the component tarballs.",
+			typ:   "counter",
+			value: func(e Entry) string { return fmt.Sprintf("%d", e.Downloads) },
+		},
+		{
+			name: "tiup_mirror_last_download_timestamp_seconds",
+			help: "The unix time of the last download of the component tarballs.",
+			typ:  "gauge",
+			value: func(e Entry) string {
+				if e.LastAccess.IsZero() {
+					return "0"
+				}
+				return fmt.Sprintf("%d", e.LastAccess.Unix())
+			},
+		},
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			_, err := fmt.Fprintf(w, "%s{component=%q,version=%q,platform=%q} %s\n",
+				m.name, e.Component, e.Version, e.Platform, m.value(e))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
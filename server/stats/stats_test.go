@@ -0,0 +1,126 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTarball(t *testing.T) {
+	cases := []struct {
+		filename string
+		key      Key
+		ok       bool
+	}{
+		{"tidb-v6.1.0-linux-amd64.tar.gz", Key{"tidb", "v6.1.0", "linux/amd64"}, true},
+		{"/tidb-v6.1.0-linux-amd64.tar.gz", Key{"tidb", "v6.1.0", "linux/amd64"}, true},
+		{"dm-master-v5.4.0-darwin-arm64.tar.gz", Key{"dm-master", "v5.4.0", "darwin/arm64"}, true},
+		{"tidb-v6.2.0-alpha-linux-amd64.tar.gz", Key{"tidb", "v6.2.0-alpha", "linux/amd64"}, true},
+		{"tikv-nightly-linux-amd64.tar.gz", Key{"tikv", "nightly", "linux/amd64"}, true},
+		{"tidb-v6.1.0-linux-amd64.sha256", Key{}, false},
+		{"1.tidb.json", Key{}, false},
+		{"tidb-linux-amd64.tar.gz", Key{}, false},
+		{"tidb-latest-linux-amd64.tar.gz", Key{}, false},
+	}
+	for _, c := range cases {
+		key, ok := ParseTarball(c.filename)
+		assert.Equal(t, c.ok, ok, c.filename)
+		assert.Equal(t, c.key, key, c.filename)
+	}
+}
+
+func TestImportLog(t *testing.T) {
+	r, err := Load(filepath.Join(t.TempDir(), "stats.json"))
+	require.Nil(t, err)
+
+	count, err := r.ImportLog(strings.NewReader(`127.0.0.1 - - [10/Oct/2022:13:55:36 +0800] "GET /tidb-v6.1.0-linux-amd64.tar.gz HTTP/1.1" 200 2326
+127.0.0.1 - - [11/Oct/2022:13:55:36 +0800] "GET /tidb-v6.1.0-linux-amd64.tar.gz?v=1 HTTP/1.1" 200 2326
+127.0.0.1 - - [12/Oct/2022:13:55:36 +0800] "GET /tidb-v6.1.0-linux-amd64.tar.gz HTTP/1.1" 404 0
+127.0.0.1 - - [12/Oct/2022:13:55:36 +0800] "GET /root.json HTTP/1.1" 200 2326
+Response [200] : 127.0.0.1:53142 - GET - /tikv-v6.1.0-linux-amd64.tar.gz (0.003 sec)
+Response [404] : 127.0.0.1:53142 - GET - /tikv-v6.1.0-linux-amd64.tar.gz (0.003 sec)
+not a log line
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	entries := r.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, Key{"tidb", "v6.1.0", "linux/amd64"}, entries[0].Key)
+	assert.Equal(t, uint64(2), entries[0].Downloads)
+	last, _ := time.Parse(combinedLogTimeLayout, "11/Oct/2022:13:55:36 +0800")
+	assert.True(t, last.Equal(entries[0].LastAccess))
+	// the access time is unknown in the log of tiup-server
+	assert.Equal(t, Key{"tikv", "v6.1.0", "linux/amd64"}, entries[1].Key)
+	assert.Equal(t, uint64(1), entries[1].Downloads)
+	assert.True(t, entries[1].LastAccess.IsZero())
+}
+
+func TestWriteMetrics(t *testing.T) {
+	r, err := Load(filepath.Join(t.TempDir(), "stats.json"))
+	require.Nil(t, err)
+	at := time.Unix(1665381336, 0)
+	r.Record("tidb-v6.1.0-linux-amd64.tar.gz", at)
+	r.Record("tidb-v6.1.0-linux-amd64.tar.gz", time.Time{})
+	r.Record("tikv-v6.1.0-linux-amd64.tar.gz", time.Time{})
+
+	var buf bytes.Buffer
+	assert.Nil(t, r.WriteMetrics(&buf))
+	assert.Equal(t, `# HELP tiup_mirror_downloads_total The number of downloads of the component tarballs.
+# TYPE tiup_mirror_downloads_total counter
+tiup_mirror_downloads_total{component="tidb",version="v6.1.0",platform="linux/amd64"} 2
+tiup_mirror_downloads_total{component="tikv",version="v6.1.0",platform="linux/amd64"} 1
+# HELP tiup_mirror_last_download_timestamp_seconds The unix time of the last download of the component tarballs.
+# TYPE tiup_mirror_last_download_timestamp_seconds gauge
+tiup_mirror_last_download_timestamp_seconds{component="tidb",version="v6.1.0",platform="linux/amd64"} 1665381336
+tiup_mirror_last_download_timestamp_seconds{component="tikv",version="v6.1.0",platform="linux/amd64"} 0
+`, buf.String())
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stats.json")
+	r, err := Load(path)
+	require.Nil(t, err)
+
+	// nothing is written without changes
+	assert.Nil(t, r.Save())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// the changes are kept if the write fails
+	r.Record("tidb-v6.1.0-linux-amd64.tar.gz", time.Time{})
+	r.path = filepath.Join(dir, "not-exist", "stats.json")
+	assert.NotNil(t, r.Save())
+	r.path = path
+	assert.Nil(t, r.Save())
+
+	loaded, err := Load(path)
+	require.Nil(t, err)
+	assert.Equal(t, r.Entries(), loaded.Entries())
+
+	// saved already
+	require.Nil(t, os.Remove(path))
+	assert.Nil(t, r.Save())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/server/stats"
+	"github.com/spf13/cobra"
+)
+
+func newStatsCmd() *cobra.Command {
+	var (
+		statsFile  string
+		importLogs []string
+		idle       time.Duration
+		format     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stats <root-dir>",
+		Short: "Show the download statistics of the mirror",
+		Long: `Show the download counts and last access time of the component tarballs
+served by the mirror. The access logs of tiup-server or other web servers serving
+the mirror can be imported to the statistics with --import-log.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			if statsFile == "" {
+				statsFile = defaultStatsFile(args[0])
+			}
+
+			recorder, err := stats.Load(statsFile)
+			if err != nil {
+				return err
+			}
+
+			for _, fname := range importLogs {
+				f, err := os.Open(fname)
+				if err != nil {
+					return errors.AddStack(err)
+				}
+				count, err := recorder.ImportLog(f)
+				f.Close()
+				if err != nil {
+					return errors.Annotatef(err, "import log %s", fname)
+				}
+				fmt.Fprintf(os.Stderr, "Imported %d downloads from %s\n", count, fname)
+			}
+			if err := recorder.Save(); err != nil {
+				return err
+			}
+
+			entries := recorder.Entries()
+			// the tarballs never downloaded are listed too, they're the first
+			// candidates to be removed
+			if files, err := os.ReadDir(args[0]); err == nil {
+				seen := make(map[stats.Key]bool)
+				for _, e := range entries {
+					seen[e.Key] = true
+				}
+				for _, f := range files {
+					if key, ok := stats.ParseTarball(f.Name()); ok && !seen[key] {
+						entries = append(entries, stats.Entry{Key: key})
+					}
+				}
+			}
+			if idle > 0 {
+				deadline := time.Now().Add(-idle)
+				filtered := entries[:0]
+				for _, e := range entries {
+					if e.LastAccess.Before(deadline) {
+						filtered = append(filtered, e)
+					}
+				}
+				entries = filtered
+			}
+
+			if format == "json" {
+				data, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			table := [][]string{{"Component", "Version", "Platform", "Downloads", "Last Access"}}
+			for _, e := range entries {
+				lastAccess := "-"
+				if !e.LastAccess.IsZero() {
+					lastAccess = e.LastAccess.Format(time.RFC3339)
+				}
+				table = append(table, []string{
+					e.Component,
+					e.Version,
+					e.Platform,
+					strconv.FormatUint(e.Downloads, 10),
+					lastAccess,
+				})
+			}
+			tui.PrintTable(table, true)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&statsFile, "stats-file", "", "specify the file of the download statistics, <root-dir>.stats.json by default")
+	cmd.Flags().StringSliceVar(&importLogs, "import-log", nil, "import the downloads in the access logs to the statistics")
+	cmd.Flags().DurationVar(&idle, "idle", 0, "only show the tarballs not downloaded in the duration")
+	cmd.Flags().StringVar(&format, "format", "default", "the format of output, available values are [default, json]")
+
+	return cmd
+}